@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,30 +17,82 @@ import (
 	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
 	"github.com/wyfcoding/financialtrading/internal/account/application"
 	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/client"
+	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/gateway"
 	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/persistence/mysql"
 	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/persistence/redis"
+	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/policy"
+	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/statement"
 	accountconsumer "github.com/wyfcoding/financialtrading/internal/account/interfaces/consumer"
 	grpcserver "github.com/wyfcoding/financialtrading/internal/account/interfaces/grpc"
 	httpserver "github.com/wyfcoding/financialtrading/internal/account/interfaces/http"
 	"github.com/wyfcoding/pkg/cache"
 	"github.com/wyfcoding/pkg/config"
 	"github.com/wyfcoding/pkg/database"
+	"github.com/wyfcoding/pkg/idgen"
 	"github.com/wyfcoding/pkg/logging"
 	"github.com/wyfcoding/pkg/messagequeue/kafka"
 	"github.com/wyfcoding/pkg/messagequeue/outbox"
 	"github.com/wyfcoding/pkg/metrics"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
 var configPath = flag.String("config", "configs/account/config.toml", "config file path")
 
+// AccountConfig 账户服务配置
+type AccountConfig struct {
+	config.Config `mapstructure:",squash"`
+	Ledger        struct {
+		// CheckInterval 总账完整性核对周期，0 表示不核对
+		CheckInterval time.Duration `mapstructure:"check_interval" toml:"check_interval"`
+		// PostOpening 启动时为尚无分录的账户补记期初分录
+		PostOpening bool `mapstructure:"post_opening" toml:"post_opening"`
+	} `mapstructure:"ledger" toml:"ledger"`
+	Statement struct {
+		// Dir 对账单归档目录
+		Dir string `mapstructure:"dir" toml:"dir"`
+		// Schedule 定期生成的对账单频率（daily、monthly），为空表示不生成
+		Schedule []string `mapstructure:"schedule" toml:"schedule"`
+		// Timezone 日结与月结的切日时区
+		Timezone string `mapstructure:"timezone" toml:"timezone"`
+	} `mapstructure:"statement" toml:"statement"`
+	Payment    PaymentConfig `mapstructure:"payment" toml:"payment"`
+	Withdrawal struct {
+		// SLAInterval 提现人工审核超时升级的检查周期，0 表示不检查
+		SLAInterval time.Duration `mapstructure:"sla_interval" toml:"sla_interval"`
+		// Policy 提现风控策略，缺失时所有提现均需人工审核
+		Policy map[string]any `mapstructure:"policy" toml:"policy"`
+	} `mapstructure:"withdrawal" toml:"withdrawal"`
+}
+
+// PaymentConfig 支付通道配置，未配置通道时使用模拟网关
+type PaymentConfig struct {
+	// Rails 支付通道列表
+	Rails []PaymentRailConfig `mapstructure:"rails" toml:"rails"`
+	// PayoutRail 提现出款使用的通道类型，为空时取第一个通道
+	PayoutRail string `mapstructure:"payout_rail" toml:"payout_rail"`
+	// Secret 默认的请求与回调签名密钥，通道未单独配置时使用
+	Secret string `mapstructure:"secret" toml:"secret"`
+	// ReconInterval 检查前一日通道对账是否到期的周期，0 表示不对账
+	ReconInterval time.Duration `mapstructure:"recon_interval" toml:"recon_interval"`
+}
+
+// PaymentRailConfig 单个支付通道
+type PaymentRailConfig struct {
+	Type    string `mapstructure:"type"     toml:"type"`
+	BaseURL string `mapstructure:"base_url" toml:"base_url"`
+	// Secret 通道签名密钥，优先取环境变量 PAYMENT_RAIL_<TYPE>_SECRET
+	Secret string `mapstructure:"secret" toml:"secret"`
+}
+
 func main() {
 	flag.Parse()
 
 	// 1. 初始化配置
-	var cfg config.Config
+	var cfg AccountConfig
 	if err := config.Load(*configPath, &cfg); err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -60,7 +113,10 @@ func main() {
 	}
 
 	if cfg.Server.Environment == "dev" {
-		if err := db.RawDB().AutoMigrate(&mysql.AccountModel{}, &mysql.EventPO{}, &mysql.TransactionPO{}, &outbox.Message{}); err != nil {
+		if err := db.RawDB().AutoMigrate(&mysql.AccountModel{}, &mysql.EventPO{}, &mysql.TransactionPO{},
+			&mysql.JournalEntryModel{}, &mysql.JournalLineModel{}, &mysql.StatementModel{},
+			&mysql.DepositOrderModel{}, &mysql.WithdrawalOrderModel{},
+			&mysql.SubAccountModel{}, &mysql.InternalTransferModel{}, &outbox.Message{}); err != nil {
 			slog.Error("failed to migrate database", "error", err)
 		}
 	}
@@ -83,13 +139,117 @@ func main() {
 	redisRepo := redis.NewAccountRedisRepository(redisCache.GetClient())
 	eventStore := mysql.NewEventStore(db.RawDB())
 	publisher := outbox.NewPublisher(outboxMgr)
+	ledgerRepo := mysql.NewLedgerRepository(db.RawDB())
 
 	// 7. 初始化应用服务
 	commandSvc := application.NewAccountCommandService(mysqlRepo, eventStore, publisher, logger.Logger)
+	commandSvc.SetLedgerRepository(ledgerRepo)
+	// 主子账户：子账户交易权限与限额、合并保证金补足、内部划转
+	subAccountRepo := mysql.NewSubAccountRepository(db.RawDB())
+	commandSvc.SetSubAccountRepository(subAccountRepo)
+	subAccountSvc := application.NewSubAccountService(mysqlRepo, subAccountRepo, eventStore, publisher, logger.Logger)
+	subAccountSvc.SetLedgerRepository(ledgerRepo)
+	ledgerSvc := application.NewLedgerService(mysqlRepo, ledgerRepo, logger.Logger)
+	if cfg.Ledger.PostOpening {
+		posted, err := ledgerSvc.PostOpeningBalances(context.Background())
+		if err != nil {
+			slog.Error("failed to post opening ledger entries", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("opening ledger entries posted", "accounts", posted)
+	}
 	queryService := application.NewAccountQueryService(mysqlRepo, redisRepo)
 	interestJob := application.NewInterestAccrualJob(commandSvc, queryService, logger.Logger)
 
-	// 7.1 Projection Consumers (Account Events -> Redis)
+	// 7.1 出入金：配置支付通道时由通道回调确认充值并做日终对账，否则使用模拟网关
+	depositRepo := mysql.NewDepositOrderRepository(db.RawDB())
+	withdrawalRepo := mysql.NewWithdrawalOrderRepository(db.RawDB())
+	fundingIDs, err := idgen.NewGenerator(cfg.Snowflake)
+	if err != nil {
+		slog.Error("failed to init id generator", "error", err)
+		os.Exit(1)
+	}
+	fundingSvc := application.NewDepositWithdrawalCommandService(mysqlRepo, depositRepo, withdrawalRepo, publisher, fundingIDs, logger.Logger)
+	fundingSvc.SetLedgerRepository(ledgerRepo)
+	fundingSvc.SetSubAccountRepository(subAccountRepo)
+	var gatewayRecon *application.GatewayReconciliationService
+	if len(cfg.Payment.Rails) > 0 {
+		rails, err := buildPaymentRails(cfg.Payment)
+		if err != nil {
+			slog.Error("failed to configure payment rails", "error", err)
+			os.Exit(1)
+		}
+		fundingSvc.SetPaymentRails(rails)
+		gatewayRecon = application.NewGatewayReconciliationService(fundingSvc, rails, depositRepo, withdrawalRepo, logger.Logger)
+	}
+
+	// 7.1.1 提现风控：限额、冷静期与多人复核按配置策略，出款前经 AML 筛查
+	if len(cfg.Withdrawal.Policy) > 0 {
+		policies, err := policy.NewConfigWithdrawalPolicyLoader(cfg.Withdrawal.Policy).Load()
+		if err != nil {
+			slog.Error("failed to load withdrawal policy", "error", err)
+			os.Exit(1)
+		}
+		fundingSvc.SetWithdrawalPolicies(policies)
+	} else {
+		slog.Warn("withdrawal policy not configured, all withdrawals require manual review")
+	}
+	if addr := cfg.GetGRPCAddr("aml"); addr != "" {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			slog.Error("failed to connect aml service", "error", err)
+			os.Exit(1)
+		}
+		fundingSvc.SetWithdrawalScreener(client.NewAMLWithdrawalScreener(conn))
+	}
+
+	// 7.2 对账单：出入金与利息取自本服务，成交、手续费与公司行动按配置接入
+	statementStore, err := statement.NewFileStorage(cfg.Statement.Dir)
+	if err != nil {
+		slog.Error("failed to init statement storage", "error", err)
+		os.Exit(1)
+	}
+	statementLoc, err := time.LoadLocation(cfg.Statement.Timezone)
+	if err != nil {
+		slog.Error("invalid statement time zone", "tz", cfg.Statement.Timezone, "error", err)
+		os.Exit(1)
+	}
+	statementSvc := application.NewStatementService(mysqlRepo,
+		depositRepo, withdrawalRepo,
+		mysql.NewStatementRepository(db.RawDB()), statementStore, publisher, logger.Logger,
+		statement.NewPDFRenderer(), statement.NewCSVRenderer())
+	statementSvc.SetLedgerRepository(ledgerRepo)
+	statementSvc.SetLocation(statementLoc)
+	if gatewayRecon != nil {
+		gatewayRecon.SetLocation(statementLoc)
+	}
+	if addr := cfg.GetGRPCAddr("execution"); addr != "" {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			slog.Error("failed to connect execution service", "error", err)
+			os.Exit(1)
+		}
+		statementSvc.AddActivitySource(client.NewExecutionActivitySource(conn, "USDT"))
+	}
+	if addr := cfg.GetGRPCAddr("feemanagement"); addr != "" {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			slog.Error("failed to connect feemanagement service", "error", err)
+			os.Exit(1)
+		}
+		statementSvc.AddActivitySource(client.NewFeeActivitySource(conn))
+	}
+	if addr := cfg.GetHTTPAddr("corporateaction"); addr != "" {
+		statementSvc.AddActivitySource(client.NewCorporateActionActivitySource(addr, 5*time.Second))
+	}
+	var statementFreqs []domain.StatementFrequency
+	for _, f := range cfg.Statement.Schedule {
+		if f = strings.TrimSpace(f); f != "" {
+			statementFreqs = append(statementFreqs, domain.StatementFrequency(strings.ToUpper(f)))
+		}
+	}
+
+	// 7.3 Projection Consumers (Account Events -> Redis)
 	projectionSvc := application.NewAccountProjectionService(mysqlRepo, redisRepo, logger.Logger)
 	projectionHandler := accountconsumer.NewAccountProjectionHandler(projectionSvc, logger.Logger)
 	projectionTopics := []string{
@@ -115,6 +275,7 @@ func main() {
 	// 8. 初始化接口层
 	grpcSrv := grpc.NewServer()
 	accountSrv := grpcserver.NewHandler(commandSvc, queryService)
+	accountSrv.SetSubAccountService(subAccountSvc)
 	accountv1.RegisterAccountServiceServer(grpcSrv, accountSrv)
 	reflection.Register(grpcSrv)
 
@@ -122,7 +283,14 @@ func main() {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	httpHandler := httpserver.NewAccountHandler(commandSvc, queryService)
+	httpHandler.SetStatementService(statementSvc)
 	httpHandler.RegisterRoutes(r.Group("/api"))
+	fundingHandler := httpserver.NewFundingHandler(fundingSvc)
+	if gatewayRecon != nil {
+		fundingHandler.SetReconciliationService(gatewayRecon)
+	}
+	fundingHandler.RegisterRoutes(r.Group("/api"))
+	httpserver.NewSubAccountHandler(subAccountSvc).RegisterRoutes(r.Group("/api"))
 
 	// 9. 启动服务
 	g, ctx := errgroup.WithContext(context.Background())
@@ -140,6 +308,38 @@ func main() {
 		return nil
 	})
 
+	// Ledger Integrity Check
+	if cfg.Ledger.CheckInterval > 0 {
+		g.Go(func() error {
+			ledgerSvc.Start(ctx, cfg.Ledger.CheckInterval)
+			return nil
+		})
+	}
+
+	// Scheduled Statements
+	if len(statementFreqs) > 0 {
+		g.Go(func() error {
+			statementSvc.Start(ctx, time.Hour, statementFreqs...)
+			return nil
+		})
+	}
+
+	// Gateway Reconciliation
+	if gatewayRecon != nil && cfg.Payment.ReconInterval > 0 {
+		g.Go(func() error {
+			gatewayRecon.Start(ctx, cfg.Payment.ReconInterval)
+			return nil
+		})
+	}
+
+	// Withdrawal Review SLA
+	if cfg.Withdrawal.SLAInterval > 0 {
+		g.Go(func() error {
+			fundingSvc.StartReviewSLAMonitor(ctx, cfg.Withdrawal.SLAInterval)
+			return nil
+		})
+	}
+
 	g.Go(func() error {
 		addr := fmt.Sprintf(":%d", cfg.Server.GRPC.Port)
 		lis, err := net.Listen("tcp", addr)
@@ -183,3 +383,25 @@ func main() {
 		slog.Error("server exited with error", "error", err)
 	}
 }
+
+// buildPaymentRails 按配置构建通道路由，密钥优先取 PAYMENT_RAIL_<TYPE>_SECRET，其次通道配置，最后默认密钥
+func buildPaymentRails(cfg PaymentConfig) (*gateway.Router, error) {
+	rails := make([]domain.PaymentRail, 0, len(cfg.Rails))
+	for _, rc := range cfg.Rails {
+		if rc.Type == "" || rc.BaseURL == "" {
+			return nil, fmt.Errorf("invalid payment rail %q: type and base_url are required", rc.Type)
+		}
+		secret := os.Getenv("PAYMENT_RAIL_" + strings.ToUpper(rc.Type) + "_SECRET")
+		if secret == "" {
+			secret = rc.Secret
+		}
+		if secret == "" {
+			secret = cfg.Secret
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("no signing secret for payment rail %s", rc.Type)
+		}
+		rails = append(rails, gateway.NewHTTPRail(domain.GatewayType(rc.Type), rc.BaseURL, secret, 10*time.Second))
+	}
+	return gateway.NewRouter(domain.GatewayType(cfg.PayoutRail), rails...)
+}
@@ -0,0 +1,33 @@
+// 变更说明：本地桩支付通道，供账户服务联调支付通道适配层。
+// 假设：账户服务以 -payment-rails bank=http://127.0.0.1:8190 -payment-rail-secret <secret> 启动，
+// 本进程以相同密钥签名并回调 /api/v1/account/gateways/<type>/webhook。
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/wyfcoding/financialtrading/internal/account/infrastructure/gateway"
+)
+
+var (
+	addr       = flag.String("addr", ":8190", "listen address")
+	secret     = flag.String("secret", "stub-secret", "shared signing secret")
+	webhookURL = flag.String("webhook-url", "http://127.0.0.1:8103/api/v1/account/gateways/bank/webhook", "account service webhook url")
+	autoSettle = flag.Duration("auto-settle", 0, "settle deposits automatically after this delay, 0 to settle manually via /v1/stub/deposits/{reference}/settle")
+)
+
+func main() {
+	flag.Parse()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	provider := gateway.NewStubProvider(*secret, *webhookURL, *autoSettle, logger)
+	logger.Info("stub payment provider starting", "addr", *addr, "webhook_url", *webhookURL, "auto_settle", *autoSettle)
+	if err := http.ListenAndServe(*addr, provider); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("stub payment provider exited", "error", err)
+		os.Exit(1)
+	}
+}
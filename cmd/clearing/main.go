@@ -13,13 +13,20 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
 	clearingv1 "github.com/wyfcoding/financialtrading/go-api/clearing/v1"
+	custodyv1 "github.com/wyfcoding/financialtrading/go-api/custody/v1"
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
 	"github.com/wyfcoding/financialtrading/internal/clearing/application"
 	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	clearingclient "github.com/wyfcoding/financialtrading/internal/clearing/infrastructure/client"
 	"github.com/wyfcoding/financialtrading/internal/clearing/infrastructure/persistence/elasticsearch"
 	"github.com/wyfcoding/financialtrading/internal/clearing/infrastructure/persistence/mysql"
 	clearingredis "github.com/wyfcoding/financialtrading/internal/clearing/infrastructure/persistence/redis"
+	clearingspan "github.com/wyfcoding/financialtrading/internal/clearing/infrastructure/span"
 	clearingconsumer "github.com/wyfcoding/financialtrading/internal/clearing/interfaces/consumer"
 	grpcserver "github.com/wyfcoding/financialtrading/internal/clearing/interfaces/grpc"
 	httpserver "github.com/wyfcoding/financialtrading/internal/clearing/interfaces/http"
@@ -39,11 +46,29 @@ import (
 
 var configPath = flag.String("config", "configs/clearing/config.toml", "config file path")
 
+// ClearingConfig 清算服务配置
+type ClearingConfig struct {
+	config.Config `mapstructure:",squash"`
+	Span          clearingspan.Config `mapstructure:"span" toml:"span"`
+	Settlement    struct {
+		// Market 结算日历使用的市场代码，为空时只按结算币种日历
+		Market string `mapstructure:"market" toml:"market"`
+		// Days 结算周期（T+N 工作日）
+		Days int `mapstructure:"days" toml:"days" validate:"min=0"`
+	} `mapstructure:"settlement" toml:"settlement"`
+	DVP struct {
+		// Enabled 启用银货对付交收：托管锁券与账户冻款
+		Enabled bool `mapstructure:"enabled" toml:"enabled"`
+		// LockTimeout 券款两腿锁定的最长时间，未配置时使用默认值
+		LockTimeout time.Duration `mapstructure:"lock_timeout" toml:"lock_timeout"`
+	} `mapstructure:"dvp" toml:"dvp"`
+}
+
 func main() {
 	flag.Parse()
 
 	// 1. Config
-	var cfg config.Config
+	var cfg ClearingConfig
 	if err := config.Load(*configPath, &cfg); err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -63,7 +88,8 @@ func main() {
 		os.Exit(1)
 	}
 	if cfg.Server.Environment == "dev" {
-		if err := db.RawDB().AutoMigrate(&mysql.SettlementModel{}, &outbox.Message{}); err != nil {
+		if err := db.RawDB().AutoMigrate(&mysql.SettlementModel{}, &mysql.ClearingRunModel{}, &mysql.ClearingPostingModel{}, &mysql.MemberStatementModel{},
+			&mysql.CCPContractModel{}, &mysql.MemberCollateralModel{}, &mysql.DefaultCaseModel{}, &mysql.AuctionBidModel{}, &mysql.DVPSettlementModel{}, &outbox.Message{}); err != nil {
 			slog.Error("failed to migrate database", "error", err)
 		}
 	}
@@ -88,8 +114,55 @@ func main() {
 	}
 	accountClient := accountv1.NewAccountServiceClient(accountConn)
 
+	positionAddr := cfg.GetGRPCAddr("position")
+	if positionAddr == "" {
+		positionAddr = "localhost:9104"
+	}
+	positionConn, err := grpc.NewClient(positionAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Error("failed to connect position service", "error", err)
+		os.Exit(1)
+	}
+	positionClient := positionv1.NewPositionServiceClient(positionConn)
+
+	marketdataAddr := cfg.GetGRPCAddr("marketdata")
+	if marketdataAddr == "" {
+		marketdataAddr = "localhost:9112"
+	}
+	marketdataConn, err := grpc.NewClient(marketdataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Error("failed to connect marketdata service", "error", err)
+		os.Exit(1)
+	}
+	marketdataClient := marketdatav1.NewMarketDataServiceClient(marketdataConn)
+
+	refdataAddr := cfg.GetGRPCAddr("referencedata")
+	if refdataAddr == "" {
+		refdataAddr = "localhost:9092"
+	}
+	refdataConn, err := grpc.NewClient(refdataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Error("failed to connect referencedata service", "error", err)
+		os.Exit(1)
+	}
+	refdataClient := referencedatav1.NewReferenceDataServiceClient(refdataConn)
+
+	custodyAddr := cfg.GetGRPCAddr("custody")
+	if custodyAddr == "" {
+		custodyAddr = "localhost:9097"
+	}
+	custodyConn, err := grpc.NewClient(custodyAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Error("failed to connect custody service", "error", err)
+		os.Exit(1)
+	}
+	custodyClient := custodyv1.NewCustodyServiceClient(custodyConn)
+
 	// 7. Repositories
 	repo := mysql.NewSettlementRepository(db.RawDB())
+	runRepo := mysql.NewClearingRunRepository(db.RawDB())
+	contractRepo := mysql.NewCCPContractRepository(db.RawDB())
+	defaultRepo := mysql.NewDefaultManagementRepository(db.RawDB())
 	publisher := outbox.NewPublisher(outboxMgr)
 
 	esCfg := &search_pkg.Config{
@@ -113,6 +186,33 @@ func main() {
 	// 8. Application
 	commandSvc := application.NewClearingCommandService(repo, marginRepo, publisher, accountClient)
 	querySvc := application.NewClearingQueryService(repo, searchRepo, settlementReadRepo, marginRepo)
+	querySvc.SetClearingRunRepository(runRepo)
+
+	eodSvc := application.NewEODClearingService(repo, runRepo, clearingclient.NewGRPCClearingPoster(accountClient, positionClient), publisher, logger.Logger)
+	eodSvc.SetCCPContractRepository(contractRepo)
+	commandSvc.SetEODClearingService(eodSvc)
+	commandSvc.SetCCPContractRepository(contractRepo)
+	commandSvc.SetSettlementCalendar(clearingclient.NewGRPCSettlementCalendar(refdataClient), cfg.Settlement.Market, cfg.Settlement.Days)
+
+	var dvpSvc *application.DVPService
+	if cfg.DVP.Enabled {
+		dvpSvc = application.NewDVPService(mysql.NewDVPRepository(db.RawDB()),
+			clearingclient.NewGRPCCustodyAssetLeg(custodyClient),
+			clearingclient.NewGRPCAccountCashLeg(accountClient),
+			cfg.DVP.LockTimeout, logger.Logger)
+		commandSvc.SetDVPService(dvpSvc)
+	}
+
+	// 违约头寸对冲计入 1% 滑点
+	closePrices := clearingclient.NewGRPCClosePriceSource(marketdataClient, decimal.NewFromFloat(0.01))
+	defaultSvc := application.NewDefaultManagementService(defaultRepo, contractRepo, closePrices, publisher, logger.Logger)
+
+	var spanLoader domain.SpanParameterLoader = clearingspan.NewConfigParameterLoader(&cfg.Span)
+	if cfg.Span.ParamsDir != "" {
+		spanLoader = clearingspan.NewFileParameterLoader(cfg.Span.ParamsDir)
+	}
+	spanSvc := application.NewSpanMarginService(spanLoader, logger.Logger)
+	spanSvc.SetPriceSource(clearingclient.NewGRPCSpanPriceSource(marketdataClient))
 
 	projectionSvc := application.NewClearingProjectionService(repo, settlementReadRepo, searchRepo, logger.Logger)
 	projectionHandler := clearingconsumer.NewSettlementProjectionHandler(projectionSvc, logger.Logger)
@@ -153,6 +253,9 @@ func main() {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	httpHandler := httpserver.NewClearingHandler(commandSvc, querySvc)
+	httpHandler.SetEODClearingService(eodSvc)
+	httpHandler.SetDefaultManagementService(defaultSvc)
+	httpHandler.SetSpanMarginService(spanSvc)
 	httpHandler.RegisterRoutes(r.Group("/api"))
 
 	// 10. Start
@@ -165,6 +268,28 @@ func main() {
 		return nil
 	})
 
+	g.Go(func() error {
+		return defaultSvc.Start(ctx)
+	})
+
+	g.Go(func() error {
+		return spanSvc.Start(ctx)
+	})
+
+	if dvpSvc != nil {
+		g.Go(func() error {
+			return dvpSvc.Start(ctx)
+		})
+	}
+
+	// 恢复进程退出时尚未完成的日终清算任务
+	g.Go(func() error {
+		if err := eodSvc.Resume(ctx); err != nil {
+			slog.Error("failed to resume EOD clearing runs", "error", err)
+		}
+		return nil
+	})
+
 	g.Go(func() error {
 		addr := fmt.Sprintf(":%d", cfg.Server.GRPC.Port)
 		lis, err := net.Listen("tcp", addr)
@@ -40,6 +40,7 @@ func main() {
 		&persistence_mysql.CustodyTransferModel{},
 		&persistence_mysql.CorpActionModel{},
 		&persistence_mysql.CorpActionExecutionModel{},
+		&persistence_mysql.AssetLockModel{},
 	)
 	if err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
@@ -49,6 +50,7 @@ func main() {
 	cRepo := persistence_mysql.NewCustodyRepo(db)
 	caRepo := persistence_mysql.NewCorpActionRepo(db)
 	app := application.NewCustodyAppService(cRepo, caRepo, logger)
+	app.SetAssetLockRepository(persistence_mysql.NewAssetLockRepo(db))
 	svc := grpc_server.NewServer(app)
 
 	// 5. Server
@@ -18,6 +18,7 @@ import (
 	"github.com/wyfcoding/financialtrading/internal/marketdata/application"
 	"github.com/wyfcoding/financialtrading/internal/marketdata/domain"
 	"github.com/wyfcoding/financialtrading/internal/marketdata/infrastructure/analysis"
+	"github.com/wyfcoding/financialtrading/internal/marketdata/infrastructure/broadcast"
 	"github.com/wyfcoding/financialtrading/internal/marketdata/infrastructure/persistence/elasticsearch"
 	"github.com/wyfcoding/financialtrading/internal/marketdata/infrastructure/persistence/mysql"
 	redisrepo "github.com/wyfcoding/financialtrading/internal/marketdata/infrastructure/persistence/redis"
@@ -100,6 +101,7 @@ func main() {
 	klineReadRepo := redisrepo.NewKlineRedisRepository(redisClient)
 	tradeReadRepo := redisrepo.NewTradeRedisRepository(redisClient)
 	orderBookReadRepo := redisrepo.NewOrderBookRedisRepository(redisClient)
+	analyticsReadRepo := redisrepo.NewAnalyticsRedisRepository(redisClient)
 
 	publisher := outbox.NewPublisher(outboxMgr)
 	var searchRepo domain.MarketDataSearchRepository
@@ -121,9 +123,13 @@ func main() {
 	commandSvc := application.NewMarketDataCommandService(mysqlRepo, logger.Logger, publisher, historySvc)
 	querySvc := application.NewMarketDataQueryService(mysqlRepo, quoteReadRepo, klineReadRepo, tradeReadRepo, orderBookReadRepo, searchRepo, historySvc)
 	projectionSvc := application.NewMarketDataProjectionService(quoteReadRepo, klineReadRepo, tradeReadRepo, orderBookReadRepo, searchRepo, logger.Logger)
+	analyticsSvc := application.NewMarketAnalyticsService(domain.DefaultAnalyticsConfig(), analyticsReadRepo, publisher, logger.Logger)
+	analyticsSvc.SetBroadcaster(broadcast.NewRedisBroadcaster(redisClient))
+	querySvc.SetAnalytics(analyticsSvc)
 
 	// 9. Kafka Consumers (Projection)
 	projectionHandler := mdconsumer.NewMarketDataProjectionHandler(projectionSvc, logger.Logger)
+	projectionHandler.SetAnalytics(analyticsSvc)
 	projectionTopics := []string{
 		domain.QuoteUpdatedEventType,
 		domain.KlineUpdatedEventType,
@@ -1,19 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	pb "github.com/wyfcoding/financialtrading/go-api/portfolio/v1"
+	benchmark_domain "github.com/wyfcoding/financialtrading/internal/benchmark/domain"
+	benchmark_mysql "github.com/wyfcoding/financialtrading/internal/benchmark/infrastructure/persistence/mysql"
 	"github.com/wyfcoding/financialtrading/internal/portfolio/application"
 	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/infrastructure/client"
 	persistence_mysql "github.com/wyfcoding/financialtrading/internal/portfolio/infrastructure/persistence/mysql"
 	grpc_server "github.com/wyfcoding/financialtrading/internal/portfolio/interfaces/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -36,16 +42,85 @@ func main() {
 	}
 
 	// Auto Migrate
-	err = db.AutoMigrate(&domain.PortfolioSnapshot{}, &domain.UserPerformance{})
+	err = db.AutoMigrate(&domain.PortfolioSnapshot{}, &domain.UserPerformance{}, &domain.CashFlow{}, &domain.AssetReturn{},
+		&domain.ModelPortfolio{}, &domain.ModelComponent{}, &domain.ModelAssignment{},
+		&domain.RebalanceProposal{}, &domain.RebalanceLine{},
+		&domain.BlockOrder{}, &domain.BlockAllocation{}, &domain.BlockFill{}, &domain.AllocationBooking{})
 	if err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 
+	// 基准指数点位默认与组合共库，可通过 BENCHMARK_DSN 指向独立的基准库
+	benchmarkDB := db
+	if benchmarkDSN := os.Getenv("BENCHMARK_DSN"); benchmarkDSN != "" {
+		if benchmarkDB, err = gorm.Open(mysql.Open(benchmarkDSN), &gorm.Config{}); err != nil {
+			log.Fatalf("failed to connect benchmark database: %v", err)
+		}
+	} else if err := db.AutoMigrate(&benchmark_domain.BenchmarkIndex{}, &benchmark_domain.IndexConstituent{}, &benchmark_domain.IndexLevel{}); err != nil {
+		log.Fatalf("failed to migrate benchmark tables: %v", err)
+	}
+
 	// 4. Layers
 	repo := persistence_mysql.NewPortfolioRepo(db)
 	app := application.NewPortfolioAppService(repo, logger)
+	app.SetCashFlowRepository(repo)
+	app.SetBenchmarkSource(benchmark_mysql.NewBenchmarkRepository(benchmarkDB))
+	app.SetModelPortfolioRepository(persistence_mysql.NewModelPortfolioRepo(db))
+	// 主子账户层级合并查询依赖账户服务
+	var accountConn *grpc.ClientConn
+	if accountAddr := os.Getenv("ACCOUNT_GRPC_ADDR"); accountAddr != "" {
+		accountConn, err = grpc.NewClient(accountAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("failed to connect account service: %v", err)
+		}
+		defer accountConn.Close()
+		app.SetAccountHierarchyResolver(client.NewAccountHierarchyClient(accountConn))
+	}
+	// 组合优化与模型调仓依赖持仓与行情服务，参考数据用于按最小交易单位取整，执行服务用于下发算法单
+	positionAddr, marketDataAddr := os.Getenv("POSITION_GRPC_ADDR"), os.Getenv("MARKETDATA_GRPC_ADDR")
+	if positionAddr != "" && marketDataAddr != "" {
+		positionConn, err := grpc.NewClient(positionAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("failed to connect position service: %v", err)
+		}
+		defer positionConn.Close()
+		marketDataConn, err := grpc.NewClient(marketDataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("failed to connect marketdata service: %v", err)
+		}
+		defer marketDataConn.Close()
+		var referenceConn grpc.ClientConnInterface
+		if referenceAddr := os.Getenv("REFERENCEDATA_GRPC_ADDR"); referenceAddr != "" {
+			conn, err := grpc.NewClient(referenceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				log.Fatalf("failed to connect referencedata service: %v", err)
+			}
+			defer conn.Close()
+			referenceConn = conn
+		}
+		app.SetAssetReturnRepository(repo)
+		app.SetOptimizerSources(client.NewPositionHoldingsClient(positionConn), client.NewOptimizerMarketDataClient(marketDataConn, referenceConn))
+		// 模型调仓需要账户可用现金与持仓计税批次，大宗单成交回分过账到账户与持仓服务
+		if accountConn != nil {
+			app.SetAccountStateSource(client.NewAccountStateClient(accountConn, positionConn))
+			app.SetAllocationPoster(client.NewAllocationPosterClient(accountConn, positionConn))
+		}
+		if executionAddr := os.Getenv("EXECUTION_GRPC_ADDR"); executionAddr != "" {
+			executionConn, err := grpc.NewClient(executionAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				log.Fatalf("failed to connect execution service: %v", err)
+			}
+			defer executionConn.Close()
+			app.SetAlgoOrderSubmitter(client.NewExecutionAlgoClient(executionConn))
+		}
+	}
 	svc := grpc_server.NewServer(app)
 
+	// 未过账的成交回分每分钟重试一次
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.StartAllocationPosting(ctx, time.Minute)
+
 	// 5. Server
 	lis, err := net.Listen("tcp", ":9100")
 	if err != nil {
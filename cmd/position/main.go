@@ -15,8 +15,10 @@ import (
 	"github.com/gin-gonic/gin"
 	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
 	"github.com/wyfcoding/financialtrading/internal/position/application"
+	"github.com/wyfcoding/financialtrading/internal/position/infrastructure/client"
 	"github.com/wyfcoding/financialtrading/internal/position/infrastructure/persistence/mysql"
 	redisrepo "github.com/wyfcoding/financialtrading/internal/position/infrastructure/persistence/redis"
+	positionconsumer "github.com/wyfcoding/financialtrading/internal/position/interfaces/consumer"
 	grpc_server "github.com/wyfcoding/financialtrading/internal/position/interfaces/grpc"
 	http_server "github.com/wyfcoding/financialtrading/internal/position/interfaces/http"
 	"github.com/wyfcoding/pkg/cache"
@@ -28,16 +30,30 @@ import (
 	"github.com/wyfcoding/pkg/metrics"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 )
 
 var configPath = flag.String("config", "configs/position/config.toml", "config file path")
 
+// PositionConfig 持仓服务配置
+type PositionConfig struct {
+	config.Config `mapstructure:",squash"`
+	PnL           struct {
+		// ReportingCurrency 日内盈亏归因的报告币种
+		ReportingCurrency string `mapstructure:"reporting_currency" toml:"reporting_currency"`
+		// Timezone 盈亏业务日的切日时区
+		Timezone string `mapstructure:"timezone" toml:"timezone"`
+		// SnapshotInterval 检查日初快照是否到期的周期，0 表示不生成
+		SnapshotInterval time.Duration `mapstructure:"snapshot_interval" toml:"snapshot_interval"`
+	} `mapstructure:"pnl" toml:"pnl"`
+}
+
 func main() {
 	flag.Parse()
 
 	// 1. Config
-	var cfg config.Config
+	var cfg PositionConfig
 	if err := config.Load(*configPath, &cfg); err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -61,7 +77,7 @@ func main() {
 	}
 
 	if cfg.Server.Environment == "dev" {
-		if err := db.RawDB().AutoMigrate(&mysql.PositionModel{}, &mysql.PositionLotModel{}, &outbox.Message{}); err != nil {
+		if err := db.RawDB().AutoMigrate(&mysql.PositionModel{}, &mysql.PositionLotModel{}, &mysql.RealizedLotModel{}, &mysql.CorporateActionApplicationModel{}, &mysql.PostingApplicationModel{}, &mysql.PnLEventModel{}, &mysql.StartOfDaySnapshotModel{}, &outbox.Message{}); err != nil {
 			slog.Error("failed to migrate database", "error", err)
 		}
 	}
@@ -85,11 +101,58 @@ func main() {
 	// 7. Repositories
 	repo := mysql.NewPositionRepository(db.RawDB())
 	readRepo := redisrepo.NewPositionRedisRepository(redisClient)
+	taxLotRepo := mysql.NewTaxLotRepository(db.RawDB())
 	publisher := outbox.NewPublisher(outboxMgr)
 
 	// 8. Application
 	commandSvc := application.NewPositionCommandService(repo, publisher)
+	commandSvc.SetTaxLotRepository(taxLotRepo)
 	querySvc := application.NewPositionQueryService(repo, readRepo)
+	querySvc.SetTaxLotRepository(taxLotRepo)
+	if addr := cfg.GetGRPCAddr("account"); addr != "" {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			slog.Error("failed to connect account service", "error", err)
+			os.Exit(1)
+		}
+		querySvc.SetAccountHierarchyResolver(client.NewAccountHierarchyClient(conn))
+	}
+
+	// 日内损益归因：依赖行情服务取标记价与汇率
+	var pnlSvc *application.PnLExplainService
+	if addr := cfg.GetGRPCAddr("marketdata"); addr != "" {
+		location, err := time.LoadLocation(cfg.PnL.Timezone)
+		if err != nil {
+			slog.Error("invalid pnl timezone", "timezone", cfg.PnL.Timezone, "error", err)
+			os.Exit(1)
+		}
+		marketConn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			slog.Error("failed to connect marketdata service", "error", err)
+			os.Exit(1)
+		}
+		var referenceConn grpc.ClientConnInterface
+		if refAddr := cfg.GetGRPCAddr("referencedata"); refAddr != "" {
+			conn, err := grpc.NewClient(refAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				slog.Error("failed to connect referencedata service", "error", err)
+				os.Exit(1)
+			}
+			referenceConn = conn
+		}
+		pnlSvc = application.NewPnLExplainService(repo, mysql.NewPnLRepository(db.RawDB()),
+			client.NewPnLMarketDataClient(marketConn, referenceConn), cfg.PnL.ReportingCurrency, location, logger.Logger)
+		querySvc.SetPnLExplainService(pnlSvc)
+
+		pnlHandler := positionconsumer.NewPnLHandler(pnlSvc, logger.Logger)
+		for _, topic := range positionconsumer.PnLTopics {
+			consumerCfg := cfg.MessageQueue.Kafka
+			consumerCfg.Topic = topic
+			consumerCfg.GroupID = "position-pnl-group"
+			consumer := kafka.NewConsumer(&consumerCfg, logger, metricsImpl)
+			consumer.Start(context.Background(), 1, pnlHandler.Handle)
+		}
+	}
 
 	// 9. Interfaces
 	grpcSrv := grpc.NewServer()
@@ -101,6 +164,9 @@ func main() {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	hHandler := http_server.NewPositionHandler(commandSvc, querySvc)
+	if pnlSvc != nil {
+		hHandler.SetPnLExplainService(pnlSvc)
+	}
 	hHandler.RegisterRoutes(r.Group("/api"))
 
 	// 10. Start
@@ -113,6 +179,13 @@ func main() {
 		return nil
 	})
 
+	if pnlSvc != nil && cfg.PnL.SnapshotInterval > 0 {
+		g.Go(func() error {
+			pnlSvc.Start(ctx, cfg.PnL.SnapshotInterval)
+			return nil
+		})
+	}
+
 	g.Go(func() error {
 		addr := fmt.Sprintf(":%d", cfg.Server.GRPC.Port)
 		lis, err := net.Listen("tcp", addr)
@@ -37,11 +37,20 @@ import (
 
 var configPath = flag.String("config", "configs/referencedata/config.toml", "config file path")
 
+// ReferenceDataConfig 参考数据服务配置
+type ReferenceDataConfig struct {
+	config.Config `mapstructure:",squash"`
+	Calendar      struct {
+		// HolidaysDir 节假日日历种子目录（CSV/ICS），启动时导入
+		HolidaysDir string `mapstructure:"holidays_dir" toml:"holidays_dir"`
+	} `mapstructure:"calendar" toml:"calendar"`
+}
+
 func main() {
 	flag.Parse()
 
 	// 1. Config
-	var cfg config.Config
+	var cfg ReferenceDataConfig
 	if err := config.Load(*configPath, &cfg); err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
@@ -69,6 +78,8 @@ func main() {
 			&mysql.SymbolModel{},
 			&mysql.ExchangeModel{},
 			&mysql.InstrumentModel{},
+			&mysql.HolidayCalendarModel{},
+			&mysql.HolidayModel{},
 			&outbox.Message{},
 		); err != nil {
 			slog.Error("failed to migrate database", "error", err)
@@ -116,6 +127,10 @@ func main() {
 	cmdSvc := application.NewReferenceDataCommandService(mysqlRepo, publisher)
 	querySvc := application.NewReferenceDataQueryService(mysqlRepo, symbolReadRepo, exchangeReadRepo, instrumentReadRepo, searchRepo)
 	projectionSvc := application.NewReferenceDataProjectionService(mysqlRepo, symbolReadRepo, exchangeReadRepo, instrumentReadRepo, searchRepo, logger.Logger)
+	calendarSvc := application.NewCalendarService(mysql.NewCalendarRepository(db.RawDB()), mysqlRepo, logger.Logger)
+	if err := calendarSvc.Seed(context.Background(), cfg.Calendar.HolidaysDir); err != nil {
+		slog.Error("failed to seed holiday calendars", "error", err)
+	}
 
 	// 9. Kafka Consumers (Projection)
 	projectionHandler := refconsumer.NewProjectionHandler(projectionSvc, logger.Logger)
@@ -142,6 +157,7 @@ func main() {
 	// 10. Interfaces
 	grpcSrv := grpc.NewServer()
 	refHandler := grpcserver.NewHandler(cmdSvc, querySvc)
+	refHandler.SetCalendarService(calendarSvc)
 	referencedatav1.RegisterReferenceDataServiceServer(grpcSrv, refHandler)
 	reflection.Register(grpcSrv)
 
@@ -150,6 +166,7 @@ func main() {
 	r.Use(gin.Recovery())
 
 	httpHandler := httpserver.NewReferenceDataHandler(querySvc)
+	httpHandler.SetCalendarService(calendarSvc)
 	httpHandler.RegisterRoutes(r.Group("/api"))
 
 	// 11. Start
@@ -17,7 +17,10 @@ import (
 	"github.com/shopspring/decimal"
 	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
 	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	orderv1 "github.com/wyfcoding/financialtrading/go-api/order/v1"
 	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	pricingv1 "github.com/wyfcoding/financialtrading/go-api/pricing/v1"
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
 	riskv1 "github.com/wyfcoding/financialtrading/go-api/risk/v1"
 	"github.com/wyfcoding/financialtrading/internal/risk/application"
 	"github.com/wyfcoding/financialtrading/internal/risk/domain"
@@ -25,6 +28,7 @@ import (
 	"github.com/wyfcoding/financialtrading/internal/risk/infrastructure/persistence/elasticsearch"
 	"github.com/wyfcoding/financialtrading/internal/risk/infrastructure/persistence/mysql"
 	redisrepo "github.com/wyfcoding/financialtrading/internal/risk/infrastructure/persistence/redis"
+	"github.com/wyfcoding/financialtrading/internal/risk/infrastructure/telemetry"
 	riskconsumer "github.com/wyfcoding/financialtrading/internal/risk/interfaces/consumer"
 	grpcserver "github.com/wyfcoding/financialtrading/internal/risk/interfaces/grpc"
 	httpserver "github.com/wyfcoding/financialtrading/internal/risk/interfaces/http"
@@ -78,6 +82,16 @@ func main() {
 			&mysql.RiskMetricsModel{},
 			&mysql.RiskAlertModel{},
 			&mysql.CircuitBreakerModel{},
+			&mysql.StressScenarioModel{},
+			&mysql.StressTestRunModel{},
+			&mysql.LiquidationCaseModel{},
+			&mysql.LiquidationStepModel{},
+			&mysql.InsuranceFundModel{},
+			&mysql.RiskRuleModel{},
+			&mysql.PreTradeLimitsModel{},
+			&mysql.DeskMemberModel{},
+			&mysql.KillSwitchModel{},
+			&mysql.ControlAuditModel{},
 			&outbox.Message{},
 		); err != nil {
 			slog.Error("failed to migrate database", "error", err)
@@ -144,6 +158,36 @@ func main() {
 	}
 	mdClient := riskclient.NewGRPCMarketDataClient(marketdatav1.NewMarketDataServiceClient(mdConn))
 
+	// 期权重估优先使用定价服务；Dial 不阻塞建连，服务不可达时由 GRPCOptionPricer 在调用时回退到本地 Black-Scholes
+	var optionPricer domain.OptionPricer
+	pricingAddr := cfg.GetGRPCAddr("pricing")
+	pricingConn, err := grpc.Dial(pricingAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Warn("failed to connect to pricing, falling back to local option pricer", "error", err)
+	} else {
+		optionPricer = riskclient.NewGRPCOptionPricer(pricingv1.NewPricingServiceClient(pricingConn))
+	}
+
+	// 压力测试的行业/币种分类取自参考数据，连接失败时按代码解析计价货币
+	var classifier *riskclient.ReferenceDataClassifier
+	refdataAddr := cfg.GetGRPCAddr("referencedata")
+	refdataConn, err := grpc.Dial(refdataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Warn("failed to connect to referencedata, stress tests will not apply sector shocks", "error", err)
+	} else {
+		classifier = riskclient.NewReferenceDataClassifier(referencedatav1.NewReferenceDataServiceClient(refdataConn), logger.Logger)
+	}
+
+	// 撤单阶段依赖订单服务，连接失败时强平直接进入部分平仓
+	var orderCanceller *riskclient.GRPCOrderCanceller
+	orderAddr := cfg.GetGRPCAddr("order")
+	orderConn, err := grpc.Dial(orderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		slog.Warn("failed to connect to order, liquidation will skip order cancellation", "error", err)
+	} else {
+		orderCanceller = riskclient.NewGRPCOrderCanceller(orderv1.NewOrderServiceClient(orderConn))
+	}
+
 	marginCalc := domain.NewVolatilityAdjustedMarginCalculator(
 		decimal.NewFromFloat(0.05),
 		decimal.NewFromFloat(2.0),
@@ -153,8 +197,18 @@ func main() {
 	// 9. Application
 	commandSvc := application.NewRiskCommandService(repo, readRepo, accClient, posClient, publisher, marginCalc)
 	querySvc := application.NewRiskQueryService(repo, readRepo, searchRepo)
+	querySvc.SetVaREngine(domain.NewVaREngine(mdClient, optionPricer))
 	projectionSvc := application.NewRiskProjectionService(repo, readRepo, searchRepo, logger.Logger)
 
+	// 事前风控表达式规则，规则集从仓储热加载
+	ruleEngine := domain.NewRealTimeRiskEngine()
+	ruleEngine.SetObserver(telemetry.NewRuleMetrics(metricsImpl))
+	ruleSvc := application.NewPreTradeRuleService(ruleEngine, mysql.NewRiskRuleRepository(db.RawDB()), logger.Logger)
+	commandSvc.SetRuleEngine(ruleEngine)
+	controlEngine := domain.NewPreTradeControlEngine()
+	ruleEngine.SetControls(controlEngine)
+	controlSvc := application.NewPreTradeControlService(controlEngine, mysql.NewPreTradeControlRepository(db.RawDB()), repo, publisher, logger.Logger)
+
 	// 10. Kafka Consumers (Projection)
 	projectionHandler := riskconsumer.NewProjectionHandler(projectionSvc, logger.Logger)
 	projectionTopics := []string{
@@ -178,10 +232,41 @@ func main() {
 
 	// Liquidation Engine
 	liqEngine := application.NewLiquidationEngine(accClient, posClient, publisher, logger.Logger)
+	liqEngine.SetRepository(repo, mysql.NewLiquidationRepository(db.RawDB()))
+	liqEngine.SetOrderBookProvider(mdClient)
+	if orderCanceller != nil {
+		liqEngine.SetOrderCanceller(orderCanceller)
+		controlSvc.SetOrderCanceller(orderCanceller)
+	}
+
+	// 事件驱动保证金监控：替代对全部杠杆账户的轮询
+	marginMonitor := application.NewMarginMonitor(liqEngine, accClient, posClient, logger.Logger)
+	marginMonitor.SetSnapshotRepository(redisrepo.NewMarginSnapshotRepository(redisClient))
+	marginMonitor.SetRuleEngine(ruleEngine)
+	liqEngine.SetMarginState(marginMonitor.State())
+	marginHandler := riskconsumer.NewMarginHandler(marginMonitor, logger.Logger)
+	marginHandler.SetControlService(controlSvc)
+	for _, topic := range riskconsumer.MarginTopics {
+		consumerCfg := cfg.MessageQueue.Kafka
+		consumerCfg.Topic = topic
+		consumerCfg.GroupID = "risk-margin-group"
+		consumer := kafka.NewConsumer(&consumerCfg, logger, metricsImpl)
+		consumer.Start(context.Background(), 1, marginHandler.Handle)
+	}
+
+	// Stress Scenario Library
+	stressSvc := application.NewStressTestService(mysql.NewStressScenarioRepository(db.RawDB()), repo, accClient, posClient, publisher, logger.Logger)
+	stressSvc.SetHistoryProvider(mdClient)
+	stressSvc.SetOptionPricer(optionPricer)
+	if classifier != nil {
+		stressSvc.SetInstrumentClassifier(classifier)
+	}
+	querySvc.SetStressTestService(stressSvc)
 
 	// 11. Interfaces
 	grpcSrv := grpc.NewServer()
 	riskHandler := grpcserver.NewHandler(commandSvc, querySvc)
+	riskHandler.SetStressTestService(stressSvc)
 	riskv1.RegisterRiskServiceServer(grpcSrv, riskHandler)
 	reflection.Register(grpcSrv)
 
@@ -190,6 +275,11 @@ func main() {
 	r.Use(gin.Recovery())
 
 	httpHandler := httpserver.NewRiskHandler(commandSvc, querySvc)
+	httpHandler.SetStressTestService(stressSvc)
+	httpHandler.SetLiquidationEngine(liqEngine)
+	httpHandler.SetMarginMonitor(marginMonitor)
+	httpHandler.SetPreTradeRuleService(ruleSvc)
+	httpHandler.SetPreTradeControlService(controlSvc)
 	httpHandler.RegisterRoutes(r.Group("/api"))
 
 	// Health/pprof
@@ -218,7 +308,23 @@ func main() {
 	})
 
 	g.Go(func() error {
-		liqEngine.Start(ctx)
+		return marginMonitor.Start(ctx)
+	})
+	g.Go(func() error {
+		return ruleSvc.Start(ctx)
+	})
+	g.Go(func() error {
+		return controlSvc.Start(ctx)
+	})
+
+	if classifier != nil {
+		g.Go(func() error {
+			classifier.Start(ctx, 10*time.Minute)
+			return nil
+		})
+	}
+	g.Go(func() error {
+		stressSvc.Start(ctx)
 		return nil
 	})
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"net"
@@ -8,12 +9,17 @@ import (
 	"os/signal"
 	"syscall"
 
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
 	pb "github.com/wyfcoding/financialtrading/go-api/settlement/v1"
 	"github.com/wyfcoding/financialtrading/internal/settlement/application"
 	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"github.com/wyfcoding/financialtrading/internal/settlement/infrastructure/adapter"
 	persistence_mysql "github.com/wyfcoding/financialtrading/internal/settlement/infrastructure/persistence/mysql"
+	"github.com/wyfcoding/financialtrading/internal/settlement/infrastructure/swift"
 	grpc_server "github.com/wyfcoding/financialtrading/internal/settlement/interfaces/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -36,7 +42,8 @@ func main() {
 	}
 
 	// Auto Migrate
-	err = db.AutoMigrate(&domain.SettlementInstruction{})
+	err = db.AutoMigrate(&domain.SettlementInstruction{}, &domain.SettlementPenalty{}, &domain.BuyIn{}, &domain.SettlementMessage{},
+		&domain.NettingResult{}, &domain.FXRate{}, &domain.FXInstruction{})
 	if err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
@@ -48,7 +55,54 @@ func main() {
 	fxRateRepo := persistence_mysql.NewFXRateRepo(db)
 	domainSvc := domain.NewSettlementDomainService(nil, nil, nil)
 	app := application.NewSettlementAppService(repo, nettingRepo, batchRepo, fxRateRepo, domainSvc, logger)
+	app.SetFXInstructionRepository(persistence_mysql.NewFXInstructionRepo(db))
+
+	marketdataAddr := os.Getenv("MARKETDATA_GRPC_ADDR")
+	if marketdataAddr == "" {
+		marketdataAddr = "127.0.0.1:9112"
+	}
+	marketdataConn, err := grpc.NewClient(marketdataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("failed to connect marketdata service: %v", err)
+	}
+	defer marketdataConn.Close()
+	marketdataClient := marketdatav1.NewMarketDataServiceClient(marketdataConn)
+
+	refdataAddr := os.Getenv("REFDATA_GRPC_ADDR")
+	if refdataAddr == "" {
+		refdataAddr = "127.0.0.1:9092"
+	}
+	refdataConn, err := grpc.NewClient(refdataAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("failed to connect referencedata service: %v", err)
+	}
+	defer refdataConn.Close()
+	calendar := adapter.NewReferenceDataCalendarAdapter(referencedatav1.NewReferenceDataServiceClient(refdataConn))
+	app.SetCalendar(calendar, os.Getenv("SETTLEMENT_DEFAULT_MARKET"))
+
+	failsSvc := application.NewFailsService(repo, persistence_mysql.NewFailsRepo(db), app, domainSvc, logger)
+	failsSvc.SetCalendar(calendar, os.Getenv("SETTLEMENT_DEFAULT_MARKET"))
+	failsSvc.SetReferencePriceSource(adapter.NewMarketReferencePriceAdapter(marketdataClient))
+	failsSvc.SetBuyInExecutor(adapter.NewQuoteBuyInExecutor(marketdataClient, logger))
+
+	// 托管商报文：未接入 SWIFT 网络时以目录投递联调
+	swiftDir := os.Getenv("SWIFT_DROP_DIR")
+	if swiftDir == "" {
+		swiftDir = "var/settlement/swift"
+	}
+	senderBIC := os.Getenv("SETTLEMENT_SENDER_BIC")
+	if senderBIC == "" {
+		senderBIC = "FTRDCNSHXXX"
+	}
+	gateway, err := swift.NewFileDropGateway(swiftDir)
+	if err != nil {
+		log.Fatalf("failed to init swift file drop: %v", err)
+	}
+	messagingSvc := application.NewMessagingService(repo, persistence_mysql.NewMessageRepo(db), swift.NewCodec(), gateway, senderBIC, logger)
+
 	svc := grpc_server.NewServer(app)
+	svc.SetFailsService(failsSvc)
+	svc.SetMessagingService(messagingSvc)
 
 	// 5. Server
 	lis, err := net.Listen("tcp", ":9094")
@@ -66,10 +120,19 @@ func main() {
 		}
 	}()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = failsSvc.Start(ctx)
+	}()
+	go func() {
+		_ = messagingSvc.Start(ctx)
+	}()
+
 	// 6. Graceful Shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Info("shutting down server...")
+	cancel()
 	s.GracefulStop()
 }
@@ -67,6 +67,8 @@ type AccountCommandService struct {
 	eventStore domain.EventStore
 	publisher  messagequeue.EventPublisher
 	logger     *slog.Logger
+	ledger     domain.LedgerRepository
+	subs       domain.SubAccountRepository
 }
 
 func NewAccountCommandService(
@@ -127,8 +129,11 @@ func (s *AccountCommandService) Deposit(ctx context.Context, cmd DepositCommand)
 			return fmt.Errorf("account not found: %s", cmd.AccountID)
 		}
 
-		account.Deposit(cmd.Amount)
-
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindDeposit, "", "deposit",
+			domain.MovementLines(account, domain.LedgerHouseCash, domain.LedgerCustomerAvailable, cmd.Amount), account)
+		if err != nil || !applied {
+			return err
+		}
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -160,10 +165,15 @@ func (s *AccountCommandService) BorrowFunds(ctx context.Context, cmd BorrowFunds
 			return fmt.Errorf("account not found: %s", cmd.AccountID)
 		}
 
-		if success := account.Borrow(cmd.Amount); !success {
+		if account.AccountType != domain.AccountTypeMargin {
 			return fmt.Errorf("borrowing failed (only margin accounts can borrow)")
 		}
 
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindBorrow, "", "margin borrow",
+			domain.MovementLines(account, domain.LedgerMarginLoans, domain.LedgerCustomerAvailable, cmd.Amount), account)
+		if err != nil || !applied {
+			return err
+		}
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -195,10 +205,11 @@ func (s *AccountCommandService) RepayFunds(ctx context.Context, cmd RepayFundsCo
 			return fmt.Errorf("account not found: %s", cmd.AccountID)
 		}
 
-		if success := account.Repay(cmd.Amount); !success {
-			return fmt.Errorf("insufficient balance for repayment")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindRepay, "", "margin repay",
+			domain.MovementLines(account, domain.LedgerCustomerAvailable, domain.LedgerMarginLoans, cmd.Amount), account)
+		if err != nil || !applied {
+			return err
 		}
-
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -230,8 +241,11 @@ func (s *AccountCommandService) AccrueInterest(ctx context.Context, cmd AccrueIn
 			return fmt.Errorf("account not found: %s", cmd.AccountID)
 		}
 
-		account.AccrueInterest(cmd.Rate)
-
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindInterestAccrual, "", "interest accrual",
+			domain.InterestAccrualLines(account, cmd.Rate), account)
+		if err != nil || !applied {
+			return err
+		}
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -317,8 +331,11 @@ func (s *AccountCommandService) SettleInterest(ctx context.Context, cmd SettleIn
 
 		settledAmount := account.AccruedInterest
 
-		account.SettleInterest()
-
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindInterestSettlement, "", "interest settlement",
+			domain.InterestSettlementLines(account), account)
+		if err != nil || !applied {
+			return err
+		}
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -350,10 +367,11 @@ func (s *AccountCommandService) Freeze(ctx context.Context, cmd FreezeCommand) e
 			return fmt.Errorf("account not found")
 		}
 
-		if success := account.Freeze(cmd.Amount, cmd.Reason); !success {
-			return fmt.Errorf("insufficient available balance")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindFreeze, "", cmd.Reason,
+			domain.MovementLines(account, domain.LedgerCustomerAvailable, domain.LedgerCustomerFrozen, cmd.Amount), account)
+		if err != nil || !applied {
+			return err
 		}
-
 		if err := s.repo.Save(txCtx, account); err != nil {
 			return err
 		}
@@ -393,10 +411,11 @@ func (s *AccountCommandService) SagaDeductFrozen(ctx context.Context, barrier an
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		if success := targetAccount.DeductFrozen(amount); !success {
-			return fmt.Errorf("insufficient frozen balance")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindTradeDebit, barrierReference(barrier, targetAccount.AccountID), "saga deduct frozen",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerFrozen, domain.LedgerSettlementClearing, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
-
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
 		}
@@ -433,10 +452,11 @@ func (s *AccountCommandService) SagaRefundFrozen(ctx context.Context, barrier an
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		if success := targetAccount.Unfreeze(amount); !success {
-			return fmt.Errorf("failed to unfreeze for refund")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindUnfreeze, barrierReference(barrier, targetAccount.AccountID), "saga refund frozen",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerFrozen, domain.LedgerCustomerAvailable, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
-
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
 		}
@@ -473,7 +493,11 @@ func (s *AccountCommandService) SagaAddBalance(ctx context.Context, barrier any,
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		targetAccount.Deposit(amount)
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindTradeCredit, barrierReference(barrier, targetAccount.AccountID), "saga add balance",
+			domain.MovementLines(targetAccount, domain.LedgerSettlementClearing, domain.LedgerCustomerAvailable, amount), targetAccount)
+		if err != nil || !applied {
+			return err
+		}
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
 		}
@@ -510,8 +534,10 @@ func (s *AccountCommandService) SagaSubBalance(ctx context.Context, barrier any,
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		if success := targetAccount.Withdraw(amount); !success {
-			return fmt.Errorf("insufficient balance for compensation")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindTradeDebit, barrierReference(barrier, targetAccount.AccountID), "saga sub balance",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerAvailable, domain.LedgerSettlementClearing, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
@@ -548,9 +574,14 @@ func (s *AccountCommandService) TccTryFreeze(ctx context.Context, barrier any, u
 		if targetAccount == nil {
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
+		if err := s.applySubAccountControls(ctx, txCtx, targetAccount, amount); err != nil {
+			return err
+		}
 
-		if success := targetAccount.Freeze(amount, "TCC Freeze"); !success {
-			return fmt.Errorf("insufficient balance for TCC freeze")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindFreeze, barrierReference(barrier, targetAccount.AccountID), "TCC Freeze",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerAvailable, domain.LedgerCustomerFrozen, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
@@ -588,8 +619,10 @@ func (s *AccountCommandService) TccConfirmFreeze(ctx context.Context, barrier an
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		if success := targetAccount.DeductFrozen(amount); !success {
-			return fmt.Errorf("insufficient frozen balance for TCC confirm")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindTradeDebit, barrierReference(barrier, targetAccount.AccountID), "tcc confirm freeze",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerFrozen, domain.LedgerSettlementClearing, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
@@ -627,8 +660,10 @@ func (s *AccountCommandService) TccCancelFreeze(ctx context.Context, barrier any
 			return fmt.Errorf("account not found for user %s currency %s", userID, currency)
 		}
 
-		if success := targetAccount.Unfreeze(amount); !success {
-			return fmt.Errorf("failed to unfreeze for TCC cancel")
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindUnfreeze, barrierReference(barrier, targetAccount.AccountID), "tcc cancel freeze",
+			domain.MovementLines(targetAccount, domain.LedgerCustomerFrozen, domain.LedgerCustomerAvailable, amount), targetAccount)
+		if err != nil || !applied {
+			return err
 		}
 		if err := s.repo.Save(txCtx, targetAccount); err != nil {
 			return err
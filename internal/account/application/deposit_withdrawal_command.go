@@ -23,9 +23,14 @@ type DepositWithdrawalCommandService struct {
 	depositOrderRepo    domain.DepositOrderRepository
 	withdrawalOrderRepo domain.WithdrawalOrderRepository
 	gateway             domain.FundsGateway
+	rails               domain.PaymentRails
 	publisher           messagequeue.EventPublisher
 	idGenerator         idgen.Generator
 	logger              *slog.Logger
+	ledger              domain.LedgerRepository
+	policies            *domain.WithdrawalPolicySet
+	screener            domain.WithdrawalScreener
+	subs                domain.SubAccountRepository
 }
 
 // NewDepositWithdrawalCommandService 创建充值提现命令服务
@@ -173,9 +178,15 @@ func (s *DepositWithdrawalCommandService) ConfirmDeposit(ctx context.Context, re
 			return errors.New("account not found")
 		}
 
-		account.Deposit(depositOrder.Amount)
-		if err := s.accountRepo.Save(txCtx, account); err != nil {
-			return fmt.Errorf("failed to save account: %w", err)
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindDeposit, "DEP:"+depositOrder.DepositNo, "deposit confirmed",
+			domain.MovementLines(account, domain.LedgerHouseCash, domain.LedgerCustomerAvailable, depositOrder.Amount), account)
+		if err != nil {
+			return err
+		}
+		if applied {
+			if err := s.accountRepo.Save(txCtx, account); err != nil {
+				return fmt.Errorf("failed to save account: %w", err)
+			}
 		}
 
 		// 3. 完成充值订单
@@ -218,6 +229,12 @@ func (s *DepositWithdrawalCommandService) CreateWithdrawal(ctx context.Context,
 		return nil, errors.New("amount must be greater than fee")
 	}
 
+	// 风控：限额与审核要求
+	review, err := s.evaluateWithdrawal(ctx, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// 1. 获取账户并检查余额
 	account, err := s.accountRepo.Get(ctx, req.AccountID)
 	if err != nil {
@@ -226,9 +243,11 @@ func (s *DepositWithdrawalCommandService) CreateWithdrawal(ctx context.Context,
 	if account == nil {
 		return nil, errors.New("account not found")
 	}
+	if err := s.checkWithdrawPermission(ctx, account.UserID); err != nil {
+		return nil, err
+	}
 
-	// 2. 冻结资金
-	if !account.Freeze(req.Amount, "withdrawal") {
+	if account.AvailableBalance.LessThan(req.Amount) {
 		return nil, errors.New("insufficient available balance")
 	}
 
@@ -236,9 +255,16 @@ func (s *DepositWithdrawalCommandService) CreateWithdrawal(ctx context.Context,
 
 	var response *CreateWithdrawalResponse
 	err = s.withdrawalOrderRepo.WithTx(ctx, func(txCtx context.Context) error {
-		// 保存账户冻结状态
-		if err := s.accountRepo.Save(txCtx, account); err != nil {
-			return fmt.Errorf("failed to save account: %w", err)
+		// 2. 冻结资金并保存账户冻结状态
+		applied, err := postJournal(txCtx, s.ledger, domain.JournalKindFreeze, "WDR:"+withdrawalOrder.WithdrawalNo+":FREEZE", "withdrawal",
+			domain.MovementLines(account, domain.LedgerCustomerAvailable, domain.LedgerCustomerFrozen, req.Amount), account)
+		if err != nil {
+			return err
+		}
+		if applied {
+			if err := s.accountRepo.Save(txCtx, account); err != nil {
+				return fmt.Errorf("failed to save account: %w", err)
+			}
 		}
 
 		// 保存提现订单
@@ -250,6 +276,14 @@ func (s *DepositWithdrawalCommandService) CreateWithdrawal(ctx context.Context,
 		if err := withdrawalOrder.StartAudit(txCtx); err != nil {
 			return fmt.Errorf("failed to start audit: %w", err)
 		}
+		if review != nil {
+			withdrawalOrder.ApplyReview(*review)
+			if review.RequiredApprovals == 0 {
+				if err := withdrawalOrder.Approve(txCtx, "policy", "auto-approved within policy limits"); err != nil {
+					return fmt.Errorf("failed to auto-approve withdrawal: %w", err)
+				}
+			}
+		}
 		if err := s.withdrawalOrderRepo.Update(txCtx, withdrawalOrder); err != nil {
 			return fmt.Errorf("failed to update withdrawal order: %w", err)
 		}
@@ -285,24 +319,24 @@ func (s *DepositWithdrawalCommandService) AuditWithdrawal(ctx context.Context, r
 		return errors.New("withdrawal order not found")
 	}
 
+	if s.policies != nil && !s.policies.CanApprove(req.Auditor) {
+		return domain.ErrApproverNotAllowed
+	}
+
 	return s.withdrawalOrderRepo.WithTx(ctx, func(txCtx context.Context) error {
 		if req.Approved {
-			if err := withdrawalOrder.Approve(txCtx, req.Auditor, req.Remark); err != nil {
+			// 达到策略要求的审批人数后才审核通过（maker-checker）
+			if _, err := withdrawalOrder.RecordApproval(txCtx, req.Auditor, req.Remark, time.Now()); err != nil {
 				return fmt.Errorf("failed to approve withdrawal: %w", err)
 			}
 		} else {
 			// 拒绝时解冻资金
-			account, err := s.accountRepo.Get(txCtx, withdrawalOrder.AccountID)
-			if err != nil {
-				return fmt.Errorf("failed to get account: %w", err)
+			if err := s.unfreezeWithdrawal(txCtx, withdrawalOrder, "withdrawal rejected"); err != nil {
+				return err
 			}
-			if account != nil {
-				account.Unfreeze(withdrawalOrder.Amount)
-				if err := s.accountRepo.Save(txCtx, account); err != nil {
-					return fmt.Errorf("failed to save account: %w", err)
-				}
-			}
-
+			withdrawalOrder.Approvals = append(withdrawalOrder.Approvals, domain.WithdrawalApproval{
+				Approver: req.Auditor, Stage: domain.ApprovalStageReview, Remark: req.Remark, At: time.Now(),
+			})
 			if err := withdrawalOrder.Reject(txCtx, req.Auditor, req.Remark); err != nil {
 				return fmt.Errorf("failed to reject withdrawal: %w", err)
 			}
@@ -312,7 +346,8 @@ func (s *DepositWithdrawalCommandService) AuditWithdrawal(ctx context.Context, r
 			return fmt.Errorf("failed to update withdrawal order: %w", err)
 		}
 
-		s.logger.Info("withdrawal audited", "withdrawal_no", req.WithdrawalNo, "approved", req.Approved, "auditor", req.Auditor)
+		s.logger.Info("withdrawal audited", "withdrawal_no", req.WithdrawalNo, "approved", req.Approved, "auditor", req.Auditor,
+			"status", withdrawalOrder.Status, "approvals_remaining", withdrawalOrder.ApprovalsRemaining())
 		return nil
 	})
 }
@@ -330,6 +365,10 @@ func (s *DepositWithdrawalCommandService) ProcessWithdrawal(ctx context.Context,
 	if withdrawalOrder.Status != domain.WithdrawalStatusApproved {
 		return errors.New("withdrawal is not approved")
 	}
+	// 冷静期与出款前 AML 筛查
+	if err := s.screenBeforePayout(ctx, withdrawalOrder, time.Now()); err != nil {
+		return err
+	}
 
 	return s.withdrawalOrderRepo.WithTx(ctx, func(txCtx context.Context) error {
 		if err := withdrawalOrder.StartProcessing(txCtx); err != nil {
@@ -360,9 +399,18 @@ func (s *DepositWithdrawalCommandService) ProcessWithdrawal(ctx context.Context,
 			return fmt.Errorf("failed to get account: %w", err)
 		}
 		if account != nil {
-			account.DeductFrozen(withdrawalOrder.Amount)
-			if err := s.accountRepo.Save(txCtx, account); err != nil {
-				return fmt.Errorf("failed to save account: %w", err)
+			// 手续费从对外划付款中留存，公司存款实际减少净额
+			lines := append(domain.MovementLines(account, domain.LedgerCustomerFrozen, domain.LedgerHouseCash, withdrawalOrder.Amount),
+				domain.FeeLines(account.Currency, withdrawalOrder.Fee)...)
+			applied, err := postJournal(txCtx, s.ledger, domain.JournalKindWithdrawal, "WDR:"+withdrawalOrder.WithdrawalNo+":PAYOUT",
+				"withdrawal payout", lines, account)
+			if err != nil {
+				return err
+			}
+			if applied {
+				if err := s.accountRepo.Save(txCtx, account); err != nil {
+					return fmt.Errorf("failed to save account: %w", err)
+				}
 			}
 		}
 
@@ -0,0 +1,222 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/dtm-labs/client/dtmcli"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+const ledgerCheckPageSize = 500
+
+// SetLedgerRepository 启用复式记账：账户余额变动先在同一事务内记入总账，再按分录变动账户
+func (s *AccountCommandService) SetLedgerRepository(ledger domain.LedgerRepository) {
+	s.ledger = ledger
+}
+
+// SetLedgerRepository 启用复式记账：出入金与提现冻结先在同一事务内记入总账，再按分录变动账户
+func (s *DepositWithdrawalCommandService) SetLedgerRepository(ledger domain.LedgerRepository) {
+	s.ledger = ledger
+}
+
+// postJournal 按业务意图生成分录并先行记账，再以分录为准变动涉及的账户：账户各余额字段由分录推导，总账是账户余额的来源。
+// 须在账户保存的同一事务内调用，返回错误时调用方应中止事务。返回 false 表示无需变动账户：没有分录行，
+// 或同一 reference 已记账、对应变动已生效，此时调用方不得再保存账户。
+// reference 为空时以分录 ID 作为幂等键；未启用总账时只按分录变动账户。
+func postJournal(ctx context.Context, ledger domain.LedgerRepository, kind domain.JournalKind,
+	reference, memo string, lines []domain.JournalLine, accounts ...*domain.Account,
+) (bool, error) {
+	if len(lines) == 0 {
+		return false, nil
+	}
+	entryID := "JE-" + strconv.FormatUint(idgen.GenID(), 10)
+	if reference == "" {
+		reference = entryID
+	}
+	entry, err := domain.NewJournalEntry(entryID, reference, kind, memo, lines)
+	if err != nil {
+		return false, err
+	}
+	if ledger != nil {
+		err := ledger.Append(ctx, entry)
+		if errors.Is(err, domain.ErrJournalPosted) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to post journal entry %s: %w", reference, err)
+		}
+	}
+	for _, acc := range accounts {
+		if err := acc.PostEntry(entry); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// barrierReference 以 DTM 分支标识作为分录幂等键，重试的分支不会重复记账
+func barrierReference(barrier any, accountID string) string {
+	b, ok := barrier.(*dtmcli.BranchBarrier)
+	if !ok || b == nil || b.Gid == "" {
+		return ""
+	}
+	return fmt.Sprintf("DTM:%s:%s:%s:%s", b.Gid, b.BranchID, b.Op, accountID)
+}
+
+// LedgerIntegrityReport 总账完整性核对结果
+type LedgerIntegrityReport struct {
+	TrialBalance    *domain.TrialBalance
+	Unbalanced      []string
+	Discrepancies   []domain.LedgerDiscrepancy
+	AccountsChecked int
+}
+
+// Healthy 试算平衡且所有账户余额与总账一致
+func (r *LedgerIntegrityReport) Healthy() bool {
+	return len(r.Unbalanced) == 0 && len(r.Discrepancies) == 0
+}
+
+// LedgerService 总账查询与完整性核对
+type LedgerService struct {
+	accounts domain.AccountRepository
+	ledger   domain.LedgerRepository
+	logger   *slog.Logger
+}
+
+// NewLedgerService 创建总账服务
+func NewLedgerService(accounts domain.AccountRepository, ledger domain.LedgerRepository, logger *slog.Logger) *LedgerService {
+	return &LedgerService{accounts: accounts, ledger: ledger, logger: logger}
+}
+
+// TrialBalance 生成当前试算平衡表
+func (s *LedgerService) TrialBalance(ctx context.Context) (*domain.TrialBalance, error) {
+	balances, err := s.ledger.Balances(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewTrialBalance(balances, time.Now()), nil
+}
+
+// AccountEntries 查询涉及某账户的分录
+func (s *LedgerService) AccountEntries(ctx context.Context, accountID string, limit, offset int) ([]*domain.JournalEntry, error) {
+	if accountID == "" {
+		return nil, errors.New("account id is required")
+	}
+	return s.ledger.ListEntries(ctx, accountID, limit, offset)
+}
+
+// Check 试算平衡并逐户核对账户余额与总账余额
+func (s *LedgerService) Check(ctx context.Context) (*LedgerIntegrityReport, error) {
+	balances, err := s.ledger.Balances(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ledger balances: %w", err)
+	}
+	report := &LedgerIntegrityReport{TrialBalance: domain.NewTrialBalance(balances, time.Now())}
+	report.Unbalanced = report.TrialBalance.UnbalancedCurrencies()
+
+	for offset := 0; ; offset += ledgerCheckPageSize {
+		accounts, err := s.accounts.List(ctx, "", ledgerCheckPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		for _, acc := range accounts {
+			report.Discrepancies = append(report.Discrepancies, domain.ReconcileAccount(acc, balances)...)
+		}
+		report.AccountsChecked += len(accounts)
+		if len(accounts) < ledgerCheckPageSize {
+			break
+		}
+	}
+	return report, nil
+}
+
+// PostOpeningBalances 为尚无分录的账户按当前余额补记期初分录，用于启用总账前的存量账户
+func (s *LedgerService) PostOpeningBalances(ctx context.Context) (int, error) {
+	posted := 0
+	for offset := 0; ; offset += ledgerCheckPageSize {
+		accounts, err := s.accounts.List(ctx, "", ledgerCheckPageSize, offset)
+		if err != nil {
+			return posted, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		for _, acc := range accounts {
+			ok, err := s.postOpening(ctx, acc)
+			if err != nil {
+				return posted, err
+			}
+			if ok {
+				posted++
+			}
+		}
+		if len(accounts) < ledgerCheckPageSize {
+			return posted, nil
+		}
+	}
+}
+
+func (s *LedgerService) postOpening(ctx context.Context, acc *domain.Account) (bool, error) {
+	existing, err := s.ledger.ListEntries(ctx, acc.AccountID, 1, 0)
+	if err != nil {
+		return false, err
+	}
+	lines := domain.OpeningBalanceLines(acc)
+	if len(existing) > 0 || len(lines) == 0 {
+		return false, nil
+	}
+	entry, err := domain.NewJournalEntry("JE-"+strconv.FormatUint(idgen.GenID(), 10), "OPEN:"+acc.AccountID,
+		domain.JournalKindOpening, "opening balance", lines)
+	if err != nil {
+		return false, err
+	}
+	if err := s.ledger.Append(ctx, entry); err != nil {
+		if errors.Is(err, domain.ErrJournalPosted) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Start 周期性执行完整性核对，发现不平衡或不一致时记录错误日志
+func (s *LedgerService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("ledger integrity check started", "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runCheck(ctx)
+		}
+	}
+}
+
+func (s *LedgerService) runCheck(ctx context.Context) {
+	report, err := s.Check(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "ledger integrity check failed", "error", err)
+		return
+	}
+	if report.Healthy() {
+		s.logger.InfoContext(ctx, "ledger integrity check passed", "accounts", report.AccountsChecked)
+		return
+	}
+	for _, ccy := range report.Unbalanced {
+		s.logger.ErrorContext(ctx, "trial balance does not balance", "currency", ccy,
+			"debits", report.TrialBalance.TotalDebits[ccy].String(),
+			"credits", report.TrialBalance.TotalCredits[ccy].String())
+	}
+	for _, d := range report.Discrepancies {
+		s.logger.ErrorContext(ctx, "account balance differs from ledger",
+			"account_id", d.AccountID, "currency", d.Currency, "field", d.Field,
+			"account_value", d.AccountValue.String(), "ledger_value", d.LedgerValue.String(),
+			"diff", d.AccountValue.Sub(d.LedgerValue).String())
+	}
+}
@@ -0,0 +1,367 @@
+// 变更说明：支付通道接入：以 PaymentRails 替代默认的模拟网关，充值由通道签名回调驱动确认/失败，
+// 并按日拉取各通道对账单与本系统已完成的出入金逐笔核对，补齐漏收的充值回调。
+// 假设：通道对账单的 Reference 为本系统充值单号/提现单号；出款金额为提现净额；
+// 通道已结算而本系统仍待确认的充值视为回调丢失，由对账任务自动确认入账。
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// ErrGatewayNotificationMismatch 回调金额或币种与充值单不一致
+var ErrGatewayNotificationMismatch = errors.New("gateway notification does not match deposit order")
+
+// SetPaymentRails 接入真实支付通道：充值按网关类型路由，出款走通道集合指定的出款通道
+func (s *DepositWithdrawalCommandService) SetPaymentRails(rails domain.PaymentRails) {
+	s.rails = rails
+	s.gateway = rails
+}
+
+// HandleGatewayWebhook 校验并处理通道回调；重复投递的回调按充值单状态幂等处理
+func (s *DepositWithdrawalCommandService) HandleGatewayWebhook(ctx context.Context, gatewayType domain.GatewayType, signature, timestamp string, body []byte) error {
+	if s.rails == nil {
+		return fmt.Errorf("%w: %s", domain.ErrUnknownPaymentRail, gatewayType)
+	}
+	rail, ok := s.rails.Rail(gatewayType)
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrUnknownPaymentRail, gatewayType)
+	}
+	n, err := rail.ParseWebhook(signature, timestamp, body)
+	if err != nil {
+		return err
+	}
+
+	order, err := s.depositOrderRepo.FindByDepositNo(ctx, n.DepositNo)
+	if err != nil {
+		return fmt.Errorf("failed to find deposit order: %w", err)
+	}
+	if order == nil {
+		return fmt.Errorf("deposit order %s not found", n.DepositNo)
+	}
+	if order.GatewayType != gatewayType {
+		return fmt.Errorf("%w: deposit %s belongs to gateway %s", ErrGatewayNotificationMismatch, n.DepositNo, order.GatewayType)
+	}
+
+	switch n.Type {
+	case domain.GatewayEventDepositSucceeded:
+		if !n.Amount.Equal(order.Amount) || !strings.EqualFold(n.Currency, order.Currency) {
+			s.logger.ErrorContext(ctx, "gateway notification amount mismatch", "deposit_no", n.DepositNo, "event_id", n.EventID,
+				"order_amount", order.Amount.String(), "order_currency", order.Currency,
+				"notified_amount", n.Amount.String(), "notified_currency", n.Currency)
+			return fmt.Errorf("%w: %s %s notified for %s %s", ErrGatewayNotificationMismatch,
+				n.Amount, n.Currency, order.Amount, order.Currency)
+		}
+		if order.Status == domain.DepositStatusFailed || order.Status == domain.DepositStatusCancelled {
+			// 通道已收款而本地已关单，不自动入账，交由日终对账人工处理
+			s.logger.ErrorContext(ctx, "gateway settled a closed deposit", "deposit_no", n.DepositNo, "status", order.Status, "event_id", n.EventID)
+			return nil
+		}
+		return s.ConfirmDeposit(ctx, &ConfirmDepositRequest{
+			DepositNo:     n.DepositNo,
+			TransactionID: n.TransactionID,
+			ThirdPartyNo:  n.ThirdPartyNo,
+		})
+	case domain.GatewayEventDepositFailed:
+		return s.FailDeposit(ctx, n.DepositNo, n.Reason)
+	default:
+		s.logger.WarnContext(ctx, "ignoring unsupported gateway event", "gateway", gatewayType, "type", n.Type, "event_id", n.EventID)
+		return nil
+	}
+}
+
+// FailDeposit 通道通知充值失败；已失败的订单直接返回，已入账的订单不可失败
+func (s *DepositWithdrawalCommandService) FailDeposit(ctx context.Context, depositNo, reason string) error {
+	order, err := s.depositOrderRepo.FindByDepositNo(ctx, depositNo)
+	if err != nil {
+		return fmt.Errorf("failed to find deposit order: %w", err)
+	}
+	if order == nil {
+		return errors.New("deposit order not found")
+	}
+	switch order.Status {
+	case domain.DepositStatusFailed:
+		return nil
+	case domain.DepositStatusCompleted:
+		return fmt.Errorf("deposit %s already completed", depositNo)
+	}
+	if err := order.Fail(ctx, reason); err != nil {
+		return fmt.Errorf("failed to fail deposit: %w", err)
+	}
+	if err := s.depositOrderRepo.Update(ctx, order); err != nil {
+		return fmt.Errorf("failed to update deposit order: %w", err)
+	}
+	s.logger.Info("deposit failed by gateway", "deposit_no", depositNo, "reason", reason)
+	return nil
+}
+
+// GatewayBreakKind 通道对账差异类型
+type GatewayBreakKind string
+
+const (
+	// GatewayBreakMissingInternal 通道已结算，本系统无对应完成记录
+	GatewayBreakMissingInternal GatewayBreakKind = "MISSING_INTERNAL"
+	// GatewayBreakMissingAtGateway 本系统已完成，通道对账单无对应记录
+	GatewayBreakMissingAtGateway GatewayBreakKind = "MISSING_AT_GATEWAY"
+	// GatewayBreakAmountMismatch 双方金额或币种不一致
+	GatewayBreakAmountMismatch GatewayBreakKind = "AMOUNT_MISMATCH"
+)
+
+// GatewayBreak 一条通道对账差异
+type GatewayBreak struct {
+	Kind          GatewayBreakKind            `json:"kind"`
+	LineKind      domain.GatewayStatementKind `json:"line_kind"`
+	Reference     string                      `json:"reference"`
+	ProviderRef   string                      `json:"provider_ref"`
+	Currency      string                      `json:"currency"`
+	InternalValue decimal.Decimal             `json:"internal_value"`
+	GatewayValue  decimal.Decimal             `json:"gateway_value"`
+	Detail        string                      `json:"detail"`
+}
+
+// GatewayReconciliationReport 单一通道某日的对账结果
+type GatewayReconciliationReport struct {
+	Gateway domain.GatewayType `json:"gateway"`
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Matched int                `json:"matched"`
+	// Recovered 回调丢失、由对账补确认入账的充值单号
+	Recovered []string       `json:"recovered"`
+	Breaks    []GatewayBreak `json:"breaks"`
+}
+
+// GatewayReconciliationService 支付通道日终对账
+type GatewayReconciliationService struct {
+	funding     *DepositWithdrawalCommandService
+	rails       domain.PaymentRails
+	deposits    domain.DepositOrderRepository
+	withdrawals domain.WithdrawalOrderRepository
+	loc         *time.Location
+	logger      *slog.Logger
+}
+
+// NewGatewayReconciliationService 创建通道对账服务，funding 用于补确认回调丢失的充值
+func NewGatewayReconciliationService(funding *DepositWithdrawalCommandService, rails domain.PaymentRails,
+	deposits domain.DepositOrderRepository, withdrawals domain.WithdrawalOrderRepository, logger *slog.Logger,
+) *GatewayReconciliationService {
+	return &GatewayReconciliationService{
+		funding:     funding,
+		rails:       rails,
+		deposits:    deposits,
+		withdrawals: withdrawals,
+		loc:         time.UTC,
+		logger:      logger,
+	}
+}
+
+// SetLocation 设置日切时区
+func (s *GatewayReconciliationService) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.loc = loc
+	}
+}
+
+// Location 日切时区
+func (s *GatewayReconciliationService) Location() *time.Location {
+	return s.loc
+}
+
+// ReconcileDay 核对 day 所在自然日的全部通道
+func (s *GatewayReconciliationService) ReconcileDay(ctx context.Context, day time.Time) ([]*GatewayReconciliationReport, error) {
+	t := day.In(s.loc)
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+	to := from.AddDate(0, 0, 1)
+
+	var reports []*GatewayReconciliationReport
+	var errs []error
+	for _, rail := range s.rails.List() {
+		report, err := s.Reconcile(ctx, rail, from, to)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rail.Type(), err))
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Reconcile 以通道对账单为准核对 [from, to) 内的出入金
+func (s *GatewayReconciliationService) Reconcile(ctx context.Context, rail domain.PaymentRail, from, to time.Time) (*GatewayReconciliationReport, error) {
+	lines, err := rail.FetchStatement(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch gateway statement: %w", err)
+	}
+	deposits, err := s.deposits.FindCompletedByGatewayBetween(ctx, rail.Type(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deposits: %w", err)
+	}
+	var withdrawals []*domain.WithdrawalOrder
+	if s.rails.PayoutRail() != nil && s.rails.PayoutRail().Type() == rail.Type() {
+		if withdrawals, err = s.withdrawals.FindCompletedBetween(ctx, from, to); err != nil {
+			return nil, fmt.Errorf("failed to list withdrawals: %w", err)
+		}
+	}
+
+	report := &GatewayReconciliationReport{Gateway: rail.Type(), From: from, To: to}
+	depositByNo := make(map[string]*domain.DepositOrder, len(deposits))
+	for _, d := range deposits {
+		depositByNo[d.DepositNo] = d
+	}
+	withdrawalByNo := make(map[string]*domain.WithdrawalOrder, len(withdrawals))
+	for _, w := range withdrawals {
+		withdrawalByNo[w.WithdrawalNo] = w
+	}
+
+	for _, line := range lines {
+		switch line.Kind {
+		case domain.GatewayStatementDeposit:
+			if d, ok := depositByNo[line.Reference]; ok {
+				delete(depositByNo, line.Reference)
+				report.compare(line, d.Amount, d.Currency)
+				continue
+			}
+			s.unmatchedDeposit(ctx, report, line)
+		case domain.GatewayStatementPayout:
+			if w, ok := withdrawalByNo[line.Reference]; ok {
+				delete(withdrawalByNo, line.Reference)
+				report.compare(line, w.NetAmount, w.Currency)
+				continue
+			}
+			s.unmatchedPayout(ctx, report, line)
+		default:
+			report.Breaks = append(report.Breaks, GatewayBreak{Kind: GatewayBreakMissingInternal, LineKind: line.Kind,
+				Reference: line.Reference, ProviderRef: line.ProviderRef, Currency: line.Currency,
+				GatewayValue: line.Amount, Detail: "unknown statement line kind"})
+		}
+	}
+
+	for _, d := range deposits {
+		if _, open := depositByNo[d.DepositNo]; open {
+			report.Breaks = append(report.Breaks, GatewayBreak{Kind: GatewayBreakMissingAtGateway, LineKind: domain.GatewayStatementDeposit,
+				Reference: d.DepositNo, ProviderRef: d.ThirdPartyNo, Currency: d.Currency, InternalValue: d.Amount,
+				Detail: "deposit credited but not on gateway statement"})
+		}
+	}
+	for _, w := range withdrawals {
+		if _, open := withdrawalByNo[w.WithdrawalNo]; open {
+			report.Breaks = append(report.Breaks, GatewayBreak{Kind: GatewayBreakMissingAtGateway, LineKind: domain.GatewayStatementPayout,
+				Reference: w.WithdrawalNo, ProviderRef: w.GatewayReference, Currency: w.Currency, InternalValue: w.NetAmount,
+				Detail: "withdrawal completed but not on gateway statement"})
+		}
+	}
+	return report, nil
+}
+
+// compare 逐笔比对金额与币种
+func (r *GatewayReconciliationReport) compare(line domain.GatewayStatementLine, amount decimal.Decimal, currency string) {
+	if line.Amount.Equal(amount) && strings.EqualFold(line.Currency, currency) {
+		r.Matched++
+		return
+	}
+	r.Breaks = append(r.Breaks, GatewayBreak{Kind: GatewayBreakAmountMismatch, LineKind: line.Kind,
+		Reference: line.Reference, ProviderRef: line.ProviderRef, Currency: currency,
+		InternalValue: amount, GatewayValue: line.Amount,
+		Detail: fmt.Sprintf("internal %s %s, gateway %s %s", amount, currency, line.Amount, line.Currency)})
+}
+
+// unmatchedDeposit 通道已结算的充值在当日完成记录中找不到：跨日完成的视为匹配，回调丢失的补确认
+func (s *GatewayReconciliationService) unmatchedDeposit(ctx context.Context, report *GatewayReconciliationReport, line domain.GatewayStatementLine) {
+	missing := GatewayBreak{Kind: GatewayBreakMissingInternal, LineKind: line.Kind, Reference: line.Reference,
+		ProviderRef: line.ProviderRef, Currency: line.Currency, GatewayValue: line.Amount}
+
+	order, err := s.deposits.FindByDepositNo(ctx, line.Reference)
+	if err != nil || order == nil {
+		missing.Detail = "no deposit order for gateway settlement"
+		report.Breaks = append(report.Breaks, missing)
+		return
+	}
+	missing.InternalValue = order.Amount
+	switch order.Status {
+	case domain.DepositStatusCompleted:
+		report.compare(line, order.Amount, order.Currency)
+	case domain.DepositStatusPending, domain.DepositStatusConfirmed:
+		if !line.Amount.Equal(order.Amount) || !strings.EqualFold(line.Currency, order.Currency) {
+			report.compare(line, order.Amount, order.Currency)
+			return
+		}
+		if err := s.funding.ConfirmDeposit(ctx, &ConfirmDepositRequest{
+			DepositNo:     order.DepositNo,
+			TransactionID: line.ProviderRef,
+			ThirdPartyNo:  line.ProviderRef,
+		}); err != nil {
+			missing.Detail = "failed to confirm deposit with missed callback: " + err.Error()
+			report.Breaks = append(report.Breaks, missing)
+			return
+		}
+		report.Matched++
+		report.Recovered = append(report.Recovered, order.DepositNo)
+	default:
+		missing.Detail = fmt.Sprintf("gateway settled deposit in status %s", order.Status)
+		report.Breaks = append(report.Breaks, missing)
+	}
+}
+
+// unmatchedPayout 通道已出款的提现在当日完成记录中找不到：跨日完成的视为匹配
+func (s *GatewayReconciliationService) unmatchedPayout(ctx context.Context, report *GatewayReconciliationReport, line domain.GatewayStatementLine) {
+	order, err := s.withdrawals.FindByWithdrawalNo(ctx, line.Reference)
+	if err == nil && order != nil && order.Status == domain.WithdrawalStatusCompleted {
+		report.compare(line, order.NetAmount, order.Currency)
+		return
+	}
+	detail := "no withdrawal order for gateway payout"
+	if err == nil && order != nil {
+		detail = fmt.Sprintf("gateway paid out withdrawal in status %s", order.Status)
+	}
+	report.Breaks = append(report.Breaks, GatewayBreak{Kind: GatewayBreakMissingInternal, LineKind: line.Kind,
+		Reference: line.Reference, ProviderRef: line.ProviderRef, Currency: line.Currency,
+		GatewayValue: line.Amount, Detail: detail})
+}
+
+// Start 每日日切后核对前一自然日，interval 为检查是否到期的间隔
+func (s *GatewayReconciliationService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("gateway reconciliation started", "interval", interval, "location", s.loc.String())
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			yesterday := now.In(s.loc).AddDate(0, 0, -1)
+			day := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, s.loc)
+			if day.Equal(last) {
+				continue
+			}
+			if s.runDay(ctx, day) {
+				last = day
+			}
+		}
+	}
+}
+
+// runDay 执行并记录一日对账，全部通道完成时返回 true
+func (s *GatewayReconciliationService) runDay(ctx context.Context, day time.Time) bool {
+	reports, err := s.ReconcileDay(ctx, day)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "gateway reconciliation failed", "day", day.Format("2006-01-02"), "error", err)
+	}
+	for _, r := range reports {
+		for _, b := range r.Breaks {
+			s.logger.ErrorContext(ctx, "gateway reconciliation break", "gateway", r.Gateway, "day", day.Format("2006-01-02"),
+				"kind", b.Kind, "line_kind", b.LineKind, "reference", b.Reference, "provider_ref", b.ProviderRef,
+				"internal", b.InternalValue.String(), "gateway_value", b.GatewayValue.String(), "detail", b.Detail)
+		}
+		s.logger.InfoContext(ctx, "gateway reconciliation completed", "gateway", r.Gateway, "day", day.Format("2006-01-02"),
+			"matched", r.Matched, "recovered", len(r.Recovered), "breaks", len(r.Breaks))
+	}
+	return err == nil
+}
@@ -0,0 +1,379 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+const statementUserPageSize = 500
+
+// GenerateStatementCommand 生成对账单命令，期间为 [PeriodStart, PeriodEnd)
+type GenerateStatementCommand struct {
+	UserID      string
+	Frequency   domain.StatementFrequency
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+type statementSource struct {
+	source domain.StatementActivitySource
+	// required 为真时来源失败则整份对账单失败，否则记为对账单警告
+	required bool
+}
+
+// StatementService 客户对账单生成、存档、下载与定期调度
+type StatementService struct {
+	accounts  domain.AccountRepository
+	ledger    domain.LedgerRepository
+	repo      domain.StatementRepository
+	storage   domain.StatementStorage
+	renderers []domain.StatementRenderer
+	sources   []statementSource
+	publisher messagequeue.EventPublisher
+	logger    *slog.Logger
+	location  *time.Location
+	lastRun   map[domain.StatementFrequency]time.Time
+}
+
+// NewStatementService 创建对账单服务；deposits/withdrawals 为账户服务自有的出入金记录来源
+func NewStatementService(
+	accounts domain.AccountRepository,
+	deposits domain.DepositOrderRepository,
+	withdrawals domain.WithdrawalOrderRepository,
+	repo domain.StatementRepository,
+	storage domain.StatementStorage,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+	renderers ...domain.StatementRenderer,
+) *StatementService {
+	s := &StatementService{
+		accounts:  accounts,
+		repo:      repo,
+		storage:   storage,
+		renderers: renderers,
+		publisher: publisher,
+		logger:    logger,
+		location:  time.UTC,
+		lastRun:   make(map[domain.StatementFrequency]time.Time),
+	}
+	s.sources = append(s.sources, statementSource{source: &fundingActivitySource{deposits: deposits, withdrawals: withdrawals}, required: true})
+	return s
+}
+
+// SetLedgerRepository 以总账余额作为期初/期末余额，并从总账读取计息与结息记录
+func (s *StatementService) SetLedgerRepository(ledger domain.LedgerRepository) {
+	s.ledger = ledger
+	s.sources = append(s.sources, statementSource{source: &interestActivitySource{accounts: s.accounts, ledger: ledger}, required: true})
+}
+
+// SetLocation 设置日/月对账单的切日时区，默认 UTC
+func (s *StatementService) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.location = loc
+	}
+}
+
+// AddActivitySource 接入外部活动来源（成交、手续费、公司行动），来源不可用时对账单照常生成并注明缺失
+func (s *StatementService) AddActivitySource(source domain.StatementActivitySource) {
+	s.sources = append(s.sources, statementSource{source: source})
+}
+
+// Generate 生成对账单：汇总余额与活动、渲染全部格式并存档，保存后发布对账单就绪事件。
+// 定期对账单按 (用户, 周期, 期间起点) 幂等，已就绪的直接返回。
+func (s *StatementService) Generate(ctx context.Context, cmd GenerateStatementCommand) (*domain.Statement, error) {
+	if cmd.Frequency == "" {
+		cmd.Frequency = domain.StatementFrequencyAdhoc
+	}
+	statementID := "STM-" + strconv.FormatUint(idgen.GenID(), 10)
+	if cmd.Frequency != domain.StatementFrequencyAdhoc {
+		existing, err := s.repo.FindByPeriod(ctx, cmd.UserID, cmd.Frequency, cmd.PeriodStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load statement: %w", err)
+		}
+		if existing != nil && existing.Status == domain.StatementStatusReady {
+			return existing, nil
+		}
+		if existing != nil {
+			statementID = existing.StatementID
+		}
+	}
+
+	stmt, err := domain.NewStatement(statementID, cmd.UserID, cmd.Frequency, cmd.PeriodStart, cmd.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.build(ctx, stmt); err != nil {
+		return nil, s.fail(ctx, stmt, err)
+	}
+	if err := s.archive(ctx, stmt); err != nil {
+		return nil, s.fail(ctx, stmt, err)
+	}
+	stmt.MarkReady(time.Now())
+
+	err = s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.Save(txCtx, stmt); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		formats := make([]string, 0, len(stmt.Files))
+		for _, r := range s.renderers {
+			formats = append(formats, string(r.Format()))
+		}
+		return s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.AccountStatementReadyEventType, stmt.StatementID, map[string]any{
+			"statement_id": stmt.StatementID,
+			"user_id":      stmt.UserID,
+			"frequency":    string(stmt.Frequency),
+			"period_start": stmt.PeriodStart.Format(time.RFC3339),
+			"period_end":   stmt.PeriodEnd.Format(time.RFC3339),
+			"formats":      formats,
+			"warnings":     len(stmt.Warnings),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save statement %s: %w", stmt.StatementID, err)
+	}
+	s.logger.InfoContext(ctx, "statement generated", "statement_id", stmt.StatementID, "user_id", stmt.UserID,
+		"frequency", stmt.Frequency, "period_start", stmt.PeriodStart, "activities", len(stmt.Activities))
+	return stmt, nil
+}
+
+// build 汇总期初/期末余额与各来源活动
+func (s *StatementService) build(ctx context.Context, stmt *domain.Statement) error {
+	accounts, err := s.accounts.GetByUserID(ctx, stmt.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	var activities []domain.StatementActivity
+	for _, src := range s.sources {
+		list, err := src.source.ListActivity(ctx, stmt.UserID, stmt.PeriodStart, stmt.PeriodEnd)
+		if err != nil {
+			if src.required {
+				return fmt.Errorf("activity source %s: %w", src.source.Name(), err)
+			}
+			s.logger.WarnContext(ctx, "statement activity source unavailable", "source", src.source.Name(),
+				"user_id", stmt.UserID, "error", err)
+			stmt.Warnings = append(stmt.Warnings, fmt.Sprintf("%s activity unavailable: %v", src.source.Name(), err))
+			continue
+		}
+		activities = append(activities, list...)
+	}
+
+	opening, closing, err := s.balances(ctx, accounts, stmt, activities)
+	if err != nil {
+		return err
+	}
+	stmt.Build(opening, closing, activities)
+	return nil
+}
+
+// balances 启用总账时取期初/期末的客户资金余额；否则以当前余额为期末并按期间现金活动倒推期初
+func (s *StatementService) balances(ctx context.Context, accounts []*domain.Account, stmt *domain.Statement,
+	activities []domain.StatementActivity,
+) (map[string]decimal.Decimal, map[string]decimal.Decimal, error) {
+	opening := make(map[string]decimal.Decimal)
+	closing := make(map[string]decimal.Decimal)
+	if s.ledger == nil {
+		for _, acc := range accounts {
+			closing[acc.Currency] = closing[acc.Currency].Add(acc.Balance)
+		}
+		for ccy, v := range closing {
+			opening[ccy] = v
+		}
+		for _, a := range activities {
+			if a.Category.AffectsCash() {
+				opening[a.Currency] = opening[a.Currency].Sub(a.Amount)
+			}
+		}
+		stmt.Warnings = append(stmt.Warnings, "ledger disabled: opening balance derived from current balance")
+		return opening, closing, nil
+	}
+
+	for _, acc := range accounts {
+		for _, point := range []struct {
+			at  time.Time
+			out map[string]decimal.Decimal
+		}{{stmt.PeriodStart, opening}, {stmt.PeriodEnd, closing}} {
+			balances, err := s.ledger.BalancesAt(ctx, acc.AccountID, point.at)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load ledger balances: %w", err)
+			}
+			cash := balances.Net(domain.LedgerCustomerAvailable, acc.AccountID, acc.Currency).
+				Add(balances.Net(domain.LedgerCustomerFrozen, acc.AccountID, acc.Currency))
+			point.out[acc.Currency] = point.out[acc.Currency].Add(cash)
+		}
+	}
+	return opening, closing, nil
+}
+
+// archive 渲染全部格式并写入文件存储
+func (s *StatementService) archive(ctx context.Context, stmt *domain.Statement) error {
+	if len(s.renderers) == 0 {
+		return errors.New("no statement renderer configured")
+	}
+	for _, r := range s.renderers {
+		data, err := r.Render(stmt)
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", r.Format(), err)
+		}
+		key := fmt.Sprintf("%s/%s/%s.%s", stmt.UserID, stmt.PeriodStart.Format("2006"), stmt.StatementID, strings.ToLower(string(r.Format())))
+		if err := s.storage.Put(ctx, key, data); err != nil {
+			return fmt.Errorf("failed to store %s: %w", key, err)
+		}
+		stmt.Files[r.Format()] = key
+	}
+	return nil
+}
+
+// fail 记录失败的对账单，便于调度重试与排查
+func (s *StatementService) fail(ctx context.Context, stmt *domain.Statement, cause error) error {
+	stmt.MarkFailed(cause.Error(), time.Now())
+	if err := s.repo.Save(ctx, stmt); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save failed statement", "statement_id", stmt.StatementID, "error", err)
+	}
+	s.logger.ErrorContext(ctx, "statement generation failed", "statement_id", stmt.StatementID,
+		"user_id", stmt.UserID, "error", cause)
+	return fmt.Errorf("statement %s failed: %w", stmt.StatementID, cause)
+}
+
+// Get 查询对账单，userID 非空时校验归属
+func (s *StatementService) Get(ctx context.Context, statementID, userID string) (*domain.Statement, error) {
+	stmt, err := s.repo.Get(ctx, statementID)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" && stmt.UserID != userID {
+		return nil, domain.ErrStatementNotFound
+	}
+	return stmt, nil
+}
+
+// List 按期间倒序列出用户对账单
+func (s *StatementService) List(ctx context.Context, userID string, limit, offset int) ([]*domain.Statement, error) {
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.repo.ListByUser(ctx, userID, limit, offset)
+}
+
+// Download 读取对账单文件，返回内容与 Content-Type
+func (s *StatementService) Download(ctx context.Context, statementID, userID string, format domain.StatementFormat) ([]byte, string, error) {
+	stmt, err := s.Get(ctx, statementID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if stmt.Status != domain.StatementStatusReady {
+		return nil, "", fmt.Errorf("statement %s is %s", statementID, stmt.Status)
+	}
+	key, ok := stmt.Files[format]
+	if !ok {
+		return nil, "", fmt.Errorf("statement %s has no %s file", statementID, format)
+	}
+	data, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := "application/octet-stream"
+	for _, r := range s.renderers {
+		if r.Format() == format {
+			contentType = r.ContentType()
+		}
+	}
+	return data, contentType, nil
+}
+
+// RunScheduled 为所有有账户的用户生成 at 之前最近一个完整周期的对账单，返回成功生成的份数
+func (s *StatementService) RunScheduled(ctx context.Context, freq domain.StatementFrequency, at time.Time) (int, error) {
+	start, end, err := domain.StatementPeriod(freq, at, s.location)
+	if err != nil {
+		return 0, err
+	}
+	users, err := s.listUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	generated, failed := 0, 0
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return generated, ctx.Err()
+		}
+		if _, err := s.Generate(ctx, GenerateStatementCommand{UserID: userID, Frequency: freq, PeriodStart: start, PeriodEnd: end}); err != nil {
+			failed++
+			continue
+		}
+		generated++
+	}
+	s.logger.InfoContext(ctx, "scheduled statements finished", "frequency", freq, "period_start", start,
+		"users", len(users), "generated", generated, "failed", failed)
+	return generated, nil
+}
+
+func (s *StatementService) listUsers(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+	var users []string
+	for offset := 0; ; offset += statementUserPageSize {
+		accounts, err := s.accounts.List(ctx, "", statementUserPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		for _, acc := range accounts {
+			if _, ok := seen[acc.UserID]; !ok {
+				seen[acc.UserID] = struct{}{}
+				users = append(users, acc.UserID)
+			}
+		}
+		if len(accounts) < statementUserPageSize {
+			return users, nil
+		}
+	}
+}
+
+// Start 周期性检查是否进入新的日/月周期，进入后为所有用户生成上一周期对账单
+func (s *StatementService) Start(ctx context.Context, interval time.Duration, freqs ...domain.StatementFrequency) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("statement scheduler started", "interval", interval, "frequencies", freqs)
+	for {
+		for _, freq := range freqs {
+			s.runDue(ctx, freq, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *StatementService) runDue(ctx context.Context, freq domain.StatementFrequency, now time.Time) {
+	start, _, err := domain.StatementPeriod(freq, now, s.location)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "invalid statement schedule", "frequency", freq, "error", err)
+		return
+	}
+	if last, ok := s.lastRun[freq]; ok && last.Equal(start) {
+		return
+	}
+	if _, err := s.RunScheduled(ctx, freq, now); err != nil {
+		s.logger.ErrorContext(ctx, "scheduled statements failed", "frequency", freq, "error", err)
+		return
+	}
+	s.lastRun[freq] = start
+}
@@ -0,0 +1,146 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// fundingActivitySource 账户服务自有的出入金记录：按入账/出款完成时间计入对账单
+type fundingActivitySource struct {
+	deposits    domain.DepositOrderRepository
+	withdrawals domain.WithdrawalOrderRepository
+}
+
+func (s *fundingActivitySource) Name() string { return "funding" }
+
+func (s *fundingActivitySource) ListActivity(ctx context.Context, userID string, from, to time.Time) ([]domain.StatementActivity, error) {
+	var list []domain.StatementActivity
+	if s.deposits != nil {
+		deposits, err := s.deposits.FindCompletedByUserBetween(ctx, userID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deposits: %w", err)
+		}
+		for _, d := range deposits {
+			list = append(list, domain.StatementActivity{
+				Time:        *d.CompletedAt,
+				Category:    domain.ActivityDeposit,
+				Currency:    d.Currency,
+				Amount:      d.Amount,
+				Reference:   d.DepositNo,
+				Description: fmt.Sprintf("Deposit via %s", d.GatewayType),
+			})
+		}
+	}
+	if s.withdrawals != nil {
+		withdrawals, err := s.withdrawals.FindCompletedByUserBetween(ctx, userID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list withdrawals: %w", err)
+		}
+		for _, w := range withdrawals {
+			list = append(list, domain.StatementActivity{
+				Time:        *w.CompletedAt,
+				Category:    domain.ActivityWithdrawal,
+				Currency:    w.Currency,
+				Amount:      w.NetAmount.Neg(),
+				Reference:   w.WithdrawalNo,
+				Description: fmt.Sprintf("Withdrawal to %s", w.BankName),
+			})
+			if w.Fee.IsPositive() {
+				list = append(list, domain.StatementActivity{
+					Time:        *w.CompletedAt,
+					Category:    domain.ActivityFee,
+					Currency:    w.Currency,
+					Amount:      w.Fee.Neg(),
+					Reference:   w.WithdrawalNo,
+					Description: "Withdrawal fee",
+				})
+			}
+		}
+	}
+	return list, nil
+}
+
+// interestActivitySource 从总账读取计息与结息分录：计提按日汇总，结息按对客户资金的实际影响计入
+type interestActivitySource struct {
+	accounts domain.AccountRepository
+	ledger   domain.LedgerRepository
+}
+
+func (s *interestActivitySource) Name() string { return "interest" }
+
+func (s *interestActivitySource) ListActivity(ctx context.Context, userID string, from, to time.Time) ([]domain.StatementActivity, error) {
+	accounts, err := s.accounts.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var list []domain.StatementActivity
+	for _, acc := range accounts {
+		entries, err := s.ledger.ListEntriesBetween(ctx, acc.AccountID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+		}
+
+		accrued := make(map[time.Time]decimal.Decimal)
+		var days []time.Time
+		for _, e := range entries {
+			switch e.Kind {
+			case domain.JournalKindInterestAccrual:
+				day := time.Date(e.PostedAt.Year(), e.PostedAt.Month(), e.PostedAt.Day(), 0, 0, 0, 0, e.PostedAt.Location())
+				if day.Before(from) {
+					day = from
+				}
+				if _, ok := accrued[day]; !ok {
+					days = append(days, day)
+				}
+				accrued[day] = accrued[day].Add(lineTotal(e, domain.LedgerInterestReceivable, acc.AccountID, true))
+			case domain.JournalKindInterestSettlement:
+				charged := lineTotal(e, domain.LedgerInterestReceivable, acc.AccountID, false)
+				cash := lineTotal(e, domain.LedgerCustomerAvailable, acc.AccountID, false).
+					Sub(lineTotal(e, domain.LedgerCustomerAvailable, acc.AccountID, true))
+				desc := "Interest charged"
+				if cash.IsZero() {
+					desc = fmt.Sprintf("Interest %s capitalised into margin loan", charged)
+				}
+				list = append(list, domain.StatementActivity{
+					Time:        e.PostedAt,
+					Category:    domain.ActivityInterest,
+					Currency:    acc.Currency,
+					Amount:      cash,
+					Reference:   e.Reference,
+					Description: desc,
+				})
+			}
+		}
+		for _, day := range days {
+			list = append(list, domain.StatementActivity{
+				Time:        day,
+				Category:    domain.ActivityInterestAccrual,
+				Currency:    acc.Currency,
+				Amount:      accrued[day],
+				Reference:   acc.AccountID,
+				Description: "Margin interest accrued",
+			})
+		}
+	}
+	return list, nil
+}
+
+// lineTotal 汇总分录中某客户科目的借方或贷方金额
+func lineTotal(e *domain.JournalEntry, code domain.LedgerAccountCode, ownerID string, debit bool) decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range e.Lines {
+		if l.AccountCode != code || l.OwnerID != ownerID {
+			continue
+		}
+		if debit {
+			total = total.Add(l.Debit)
+		} else {
+			total = total.Add(l.Credit)
+		}
+	}
+	return total
+}
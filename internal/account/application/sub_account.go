@@ -0,0 +1,415 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+// CreateSubAccountCommand 创建子账户命令
+type CreateSubAccountCommand struct {
+	MasterUserID          string
+	SubUserID             string
+	Name                  string
+	Permissions           []domain.SubAccountPermission
+	MarginMode            domain.MarginMode
+	MaxOrderAmount        decimal.Decimal
+	DailyTransferOutLimit decimal.Decimal
+	// Currencies 为子账户开立的资金账户币种
+	Currencies  []string
+	AccountType domain.AccountType
+}
+
+// UpdateSubAccountCommand 更新子账户权限与限额命令，Status 为空时不变
+type UpdateSubAccountCommand struct {
+	SubUserID             string
+	Permissions           []domain.SubAccountPermission
+	MarginMode            domain.MarginMode
+	MaxOrderAmount        decimal.Decimal
+	DailyTransferOutLimit decimal.Decimal
+	Status                domain.SubAccountStatus
+}
+
+// TransferCommand 内部划转命令
+type TransferCommand struct {
+	FromUserID string
+	ToUserID   string
+	Currency   string
+	Amount     decimal.Decimal
+	Operator   string
+	Memo       string
+}
+
+// SubAccountService 主子账户管理、内部划转与合并资金查询
+type SubAccountService struct {
+	accounts   domain.AccountRepository
+	subs       domain.SubAccountRepository
+	eventStore domain.EventStore
+	publisher  messagequeue.EventPublisher
+	ledger     domain.LedgerRepository
+	logger     *slog.Logger
+}
+
+func NewSubAccountService(
+	accounts domain.AccountRepository,
+	subs domain.SubAccountRepository,
+	eventStore domain.EventStore,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+) *SubAccountService {
+	return &SubAccountService{
+		accounts:   accounts,
+		subs:       subs,
+		eventStore: eventStore,
+		publisher:  publisher,
+		logger:     logger,
+	}
+}
+
+// SetLedgerRepository 启用总账记账
+func (s *SubAccountService) SetLedgerRepository(ledger domain.LedgerRepository) {
+	s.ledger = ledger
+}
+
+// CreateSubAccount 在主账户下创建子账户并开立资金账户
+func (s *SubAccountService) CreateSubAccount(ctx context.Context, cmd CreateSubAccountCommand) (*domain.SubAccount, error) {
+	sub, err := domain.NewSubAccount(cmd.MasterUserID, cmd.SubUserID, cmd.Name, cmd.Permissions, cmd.MarginMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.Configure(sub.Permissions, sub.MarginMode, cmd.MaxOrderAmount, cmd.DailyTransferOutLimit); err != nil {
+		return nil, err
+	}
+	// 层级只有一级：主账户不能是子账户，子账户不能已挂子账户或已属于其他主账户
+	if parent, err := s.subs.Get(ctx, cmd.MasterUserID); err != nil {
+		return nil, err
+	} else if parent != nil {
+		return nil, fmt.Errorf("%w: %s is a sub-account of %s", domain.ErrInvalidHierarchy, cmd.MasterUserID, parent.MasterUserID)
+	}
+	if existing, err := s.subs.Get(ctx, cmd.SubUserID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, fmt.Errorf("%w: %s already belongs to %s", domain.ErrInvalidHierarchy, cmd.SubUserID, existing.MasterUserID)
+	}
+	if children, err := s.subs.ListByMaster(ctx, cmd.SubUserID); err != nil {
+		return nil, err
+	} else if len(children) > 0 {
+		return nil, fmt.Errorf("%w: %s is a master account", domain.ErrInvalidHierarchy, cmd.SubUserID)
+	}
+
+	accType := cmd.AccountType
+	if accType == "" {
+		accType = domain.AccountTypeSpot
+	}
+	err = s.accounts.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.subs.Save(txCtx, sub); err != nil {
+			return fmt.Errorf("failed to save sub-account: %w", err)
+		}
+		for _, ccy := range cmd.Currencies {
+			existing, err := findUserAccount(txCtx, s.accounts, sub.SubUserID, ccy)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				continue
+			}
+			accountID := fmt.Sprintf("ACC-%d", idgen.GenID())
+			account := domain.NewAccount(accountID, sub.SubUserID, ccy, accType)
+			if err := s.accounts.Save(txCtx, account); err != nil {
+				return err
+			}
+			if err := s.eventStore.Save(txCtx, account.AccountID, account.GetUncommittedEvents(), account.Version()); err != nil {
+				return err
+			}
+			account.MarkCommitted()
+			if s.publisher != nil {
+				if err := s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.AccountCreatedEventType, accountID, map[string]any{
+					"account_id": accountID,
+					"user_id":    sub.SubUserID,
+					"currency":   ccy,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create sub-account", "master_user_id", cmd.MasterUserID, "sub_user_id", cmd.SubUserID, "error", err)
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "sub-account created", "master_user_id", sub.MasterUserID, "sub_user_id", sub.SubUserID,
+		"margin_mode", sub.MarginMode, "permissions", sub.Permissions)
+	return sub, nil
+}
+
+// UpdateSubAccount 更新子账户权限、保证金模式、限额与状态
+func (s *SubAccountService) UpdateSubAccount(ctx context.Context, cmd UpdateSubAccountCommand) (*domain.SubAccount, error) {
+	sub, err := s.subs.Get(ctx, cmd.SubUserID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, domain.ErrSubAccountNotFound
+	}
+	if err := sub.Configure(cmd.Permissions, cmd.MarginMode, cmd.MaxOrderAmount, cmd.DailyTransferOutLimit); err != nil {
+		return nil, err
+	}
+	switch cmd.Status {
+	case "":
+	case domain.SubAccountStatusActive, domain.SubAccountStatusSuspended:
+		sub.Status = cmd.Status
+	default:
+		return nil, fmt.Errorf("unknown sub-account status %q", cmd.Status)
+	}
+	if err := s.subs.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "sub-account updated", "sub_user_id", sub.SubUserID, "status", sub.Status, "margin_mode", sub.MarginMode)
+	return sub, nil
+}
+
+// GetHierarchy 返回用户所在层级，userID 可以是主账户或任一子账户；没有子账户的用户自成一级
+func (s *SubAccountService) GetHierarchy(ctx context.Context, userID string) (*domain.AccountHierarchy, error) {
+	return resolveHierarchy(ctx, s.subs, userID)
+}
+
+// Transfer 同一主账户下账户之间即时划转
+func (s *SubAccountService) Transfer(ctx context.Context, cmd TransferCommand) (*domain.InternalTransfer, error) {
+	if !cmd.Amount.IsPositive() {
+		return nil, errors.New("transfer amount must be positive")
+	}
+	if cmd.FromUserID == cmd.ToUserID {
+		return nil, errors.New("cannot transfer to the same account")
+	}
+	h, err := s.GetHierarchy(ctx, cmd.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !h.Contains(cmd.ToUserID) {
+		return nil, fmt.Errorf("%w: %s and %s", domain.ErrNotInSameHierarchy, cmd.FromUserID, cmd.ToUserID)
+	}
+	now := time.Now()
+
+	transfer := &domain.InternalTransfer{
+		TransferNo:   fmt.Sprintf("TRF-%d", idgen.GenID()),
+		MasterUserID: h.MasterUserID,
+		FromUserID:   cmd.FromUserID,
+		ToUserID:     cmd.ToUserID,
+		Currency:     cmd.Currency,
+		Amount:       cmd.Amount,
+		Kind:         domain.TransferKindManual,
+		Operator:     cmd.Operator,
+		Memo:         cmd.Memo,
+		CreatedAt:    now,
+	}
+	err = s.accounts.WithTx(ctx, func(txCtx context.Context) error {
+		if h.SubAccount(cmd.FromUserID) != nil {
+			if err := s.checkTransferOut(txCtx, cmd, now); err != nil {
+				return err
+			}
+		}
+		from, err := findUserAccount(txCtx, s.accounts, cmd.FromUserID, cmd.Currency)
+		if err != nil {
+			return err
+		}
+		to, err := findUserAccount(txCtx, s.accounts, cmd.ToUserID, cmd.Currency)
+		if err != nil {
+			return err
+		}
+		if from == nil || to == nil {
+			return fmt.Errorf("account not found for transfer %s -> %s currency %s", cmd.FromUserID, cmd.ToUserID, cmd.Currency)
+		}
+		return executeTransfer(ctx, txCtx, s.accounts, s.subs, s.eventStore, s.ledger, s.publisher, from, to, transfer)
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "internal transfer failed", "from", cmd.FromUserID, "to", cmd.ToUserID, "error", err)
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "internal transfer completed", "transfer_no", transfer.TransferNo, "from", cmd.FromUserID,
+		"to", cmd.ToUserID, "amount", cmd.Amount.String(), "currency", cmd.Currency)
+	return transfer, nil
+}
+
+// checkTransferOut 校验子账户当日划出限额。先锁定子账户行，使同一子账户的并发划转串行执行，
+// 当日已用额度在锁内统计，本笔划转记录与之同事务提交
+func (s *SubAccountService) checkTransferOut(txCtx context.Context, cmd TransferCommand, now time.Time) error {
+	sub, err := s.subs.GetForUpdate(txCtx, cmd.FromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to lock sub-account: %w", err)
+	}
+	if sub == nil {
+		return nil
+	}
+	y, m, d := now.UTC().Date()
+	used, err := s.subs.SumTransfersOut(txCtx, cmd.FromUserID, cmd.Currency, time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return fmt.Errorf("failed to load transfer usage: %w", err)
+	}
+	return sub.CheckTransferOut(used, cmd.Amount)
+}
+
+// ConsolidatedBalance 主账户层面的合并资金，userID 可以是层级内任一账户
+func (s *SubAccountService) ConsolidatedBalance(ctx context.Context, userID, currency string) (*domain.ConsolidatedBalance, error) {
+	h, err := s.GetHierarchy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []*domain.Account
+	for _, id := range h.UserIDs() {
+		list, err := s.accounts.GetByUserID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, list...)
+	}
+	return h.Consolidate(currency, accounts), nil
+}
+
+// ListTransfers 层级内的划转记录
+func (s *SubAccountService) ListTransfers(ctx context.Context, userID string, limit, offset int) ([]*domain.InternalTransfer, error) {
+	h, err := s.GetHierarchy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.subs.ListTransfers(ctx, h.MasterUserID, limit, offset)
+}
+
+// SetSubAccountRepository 启用子账户交易权限、限额与合并保证金
+func (s *AccountCommandService) SetSubAccountRepository(subs domain.SubAccountRepository) {
+	s.subs = subs
+}
+
+// applySubAccountControls 子账户冻结交易资金前校验权限与单笔上限；合并保证金模式下可用资金的缺口由主账户补足，
+// 主账户资金也不足时不补足，由后续冻结按余额不足失败
+func (s *AccountCommandService) applySubAccountControls(ctx, txCtx context.Context, acc *domain.Account, amount decimal.Decimal) error {
+	if s.subs == nil {
+		return nil
+	}
+	sub, err := s.subs.Get(txCtx, acc.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load sub-account: %w", err)
+	}
+	if sub == nil {
+		return nil
+	}
+	if err := sub.CheckTrade(amount); err != nil {
+		return err
+	}
+	shortfall := amount.Sub(acc.AvailableBalance)
+	if sub.MarginMode != domain.MarginModeConsolidated || !shortfall.IsPositive() {
+		return nil
+	}
+	master, err := findUserAccount(txCtx, s.repo, sub.MasterUserID, acc.Currency)
+	if err != nil {
+		return err
+	}
+	if master == nil || master.AvailableBalance.LessThan(shortfall) {
+		return nil
+	}
+	transfer := &domain.InternalTransfer{
+		TransferNo:   fmt.Sprintf("TRF-%d", idgen.GenID()),
+		MasterUserID: sub.MasterUserID,
+		FromUserID:   sub.MasterUserID,
+		ToUserID:     acc.UserID,
+		Currency:     acc.Currency,
+		Amount:       shortfall,
+		Kind:         domain.TransferKindMarginSweep,
+		Operator:     "system",
+		Memo:         "consolidated margin sweep",
+		CreatedAt:    time.Now(),
+	}
+	if err := executeTransfer(ctx, txCtx, s.repo, s.subs, s.eventStore, s.ledger, s.publisher, master, acc, transfer); err != nil {
+		return fmt.Errorf("margin sweep failed: %w", err)
+	}
+	s.logger.InfoContext(ctx, "margin swept from master account", "master_user_id", sub.MasterUserID,
+		"sub_user_id", acc.UserID, "amount", shortfall.String(), "currency", acc.Currency)
+	return nil
+}
+
+// executeTransfer 在事务内完成划转：两边记账、保存快照与事件、落划转记录并发布事件
+func executeTransfer(ctx, txCtx context.Context, accounts domain.AccountRepository, subs domain.SubAccountRepository,
+	eventStore domain.EventStore, ledger domain.LedgerRepository, publisher messagequeue.EventPublisher,
+	from, to *domain.Account, t *domain.InternalTransfer,
+) error {
+	if from.AvailableBalance.LessThan(t.Amount) {
+		return fmt.Errorf("insufficient available balance in %s for transfer", from.AccountID)
+	}
+	// 一笔分录：划出方与划入方的客户科目经内部划转往来相抵
+	lines := append(domain.MovementLines(from, domain.LedgerCustomerAvailable, domain.LedgerInternalTransfer, t.Amount),
+		domain.MovementLines(to, domain.LedgerInternalTransfer, domain.LedgerCustomerAvailable, t.Amount)...)
+	applied, err := postJournal(txCtx, ledger, domain.JournalKindInternalTransfer, t.TransferNo, t.Memo, lines, from, to)
+	if err != nil || !applied {
+		return err
+	}
+	for _, acc := range []*domain.Account{from, to} {
+		if err := accounts.Save(txCtx, acc); err != nil {
+			return err
+		}
+	}
+	for _, acc := range []*domain.Account{from, to} {
+		if eventStore != nil {
+			if err := eventStore.Save(txCtx, acc.AccountID, acc.GetUncommittedEvents(), acc.Version()); err != nil {
+				return err
+			}
+		}
+		acc.MarkCommitted()
+	}
+	if err := subs.SaveTransfer(txCtx, t); err != nil {
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	if publisher == nil {
+		return nil
+	}
+	return publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.AccountTransferredEventType, t.TransferNo, map[string]any{
+		"transfer_no":     t.TransferNo,
+		"master_user_id":  t.MasterUserID,
+		"from_user_id":    t.FromUserID,
+		"to_user_id":      t.ToUserID,
+		"from_account_id": from.AccountID,
+		"to_account_id":   to.AccountID,
+		"currency":        t.Currency,
+		"amount":          t.Amount.String(),
+		"kind":            string(t.Kind),
+	})
+}
+
+// resolveHierarchy 按子账户关系解析用户所在层级
+func resolveHierarchy(ctx context.Context, subs domain.SubAccountRepository, userID string) (*domain.AccountHierarchy, error) {
+	master := userID
+	sub, err := subs.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sub != nil {
+		master = sub.MasterUserID
+	}
+	members, err := subs.ListByMaster(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AccountHierarchy{MasterUserID: master, SubAccounts: members}, nil
+}
+
+// findUserAccount 按用户与币种查找资金账户，不存在时返回 nil
+func findUserAccount(ctx context.Context, repo domain.AccountRepository, userID, currency string) (*domain.Account, error) {
+	accounts, err := repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, acc := range accounts {
+		if acc.Currency == currency {
+			return acc, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,282 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+)
+
+const withdrawalQueuePageSize = 200
+
+// SetWithdrawalPolicies 启用提现风控策略：限额、冷静期、自动放行与多人复核
+func (s *DepositWithdrawalCommandService) SetWithdrawalPolicies(policies *domain.WithdrawalPolicySet) {
+	s.policies = policies
+}
+
+// SetWithdrawalScreener 启用出款前 AML 筛查
+func (s *DepositWithdrawalCommandService) SetWithdrawalScreener(screener domain.WithdrawalScreener) {
+	s.screener = screener
+}
+
+// SetSubAccountRepository 启用子账户出金权限校验
+func (s *DepositWithdrawalCommandService) SetSubAccountRepository(subs domain.SubAccountRepository) {
+	s.subs = subs
+}
+
+// checkWithdrawPermission 子账户需具备出金权限，主账户与普通账户不受限
+func (s *DepositWithdrawalCommandService) checkWithdrawPermission(ctx context.Context, userID string) error {
+	if s.subs == nil {
+		return nil
+	}
+	sub, err := s.subs.Get(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load sub-account: %w", err)
+	}
+	if sub != nil && !sub.Allows(domain.PermissionWithdraw) {
+		return fmt.Errorf("%w: %s cannot withdraw", domain.ErrSubAccountPermissionDenied, userID)
+	}
+	return nil
+}
+
+// evaluateWithdrawal 校验限额并评估审核要求，未配置策略时返回 nil（沿用单人人工审核）
+func (s *DepositWithdrawalCommandService) evaluateWithdrawal(ctx context.Context, req *CreateWithdrawalRequest, now time.Time) (*domain.WithdrawalReview, error) {
+	if s.policies == nil {
+		return nil, nil
+	}
+	policy := s.policies.For(req.Currency)
+
+	recent, err := s.withdrawalOrderRepo.FindByUserSince(ctx, req.UserID, req.Currency, now.Add(-domain.VelocityWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recent withdrawals: %w", err)
+	}
+	usage := domain.WithdrawalUsage{Count: len(recent)}
+	for _, w := range recent {
+		usage.Amount = usage.Amount.Add(w.Amount)
+	}
+	if err := policy.CheckVelocity(usage, req.Amount); err != nil {
+		return nil, err
+	}
+
+	var firstUse *time.Time
+	first, err := s.withdrawalOrderRepo.FindFirstByBeneficiary(ctx, req.UserID, req.BankAccountNo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load beneficiary history: %w", err)
+	}
+	if first != nil {
+		firstUse = &first.CreatedAt
+	}
+	review := policy.Review(req.Amount, firstUse, now)
+	return &review, nil
+}
+
+// screenBeforePayout 出款前校验冷静期并执行 AML 筛查；命中时挂起并持久化，返回 ErrWithdrawalAMLHold
+func (s *DepositWithdrawalCommandService) screenBeforePayout(ctx context.Context, w *domain.WithdrawalOrder, now time.Time) error {
+	if err := w.CheckReleasable(now); err != nil {
+		return err
+	}
+	if s.screener == nil || w.AMLStatus == domain.AMLStatusClear {
+		return nil
+	}
+	result, err := s.screener.ScreenWithdrawal(ctx, w)
+	if err != nil {
+		// 筛查不可用时不出款
+		return fmt.Errorf("aml screening unavailable: %w", err)
+	}
+	if !result.Hold {
+		w.ClearAML("", "", now)
+		return nil
+	}
+
+	var due *time.Time
+	if s.policies != nil {
+		if sla := s.policies.For(w.Currency).ReviewSLA(); sla > 0 {
+			t := now.Add(sla)
+			due = &t
+		}
+	}
+	w.HoldForAML(result, due)
+	if err := s.withdrawalOrderRepo.Update(ctx, w); err != nil {
+		return fmt.Errorf("failed to update withdrawal order: %w", err)
+	}
+	s.logger.Warn("withdrawal held by aml screening", "withdrawal_no", w.WithdrawalNo, "user_id", w.UserID,
+		"risk_level", result.RiskLevel, "aml_reference", result.Reference)
+	return domain.ErrWithdrawalAMLHold
+}
+
+// unfreezeWithdrawal 拒绝提现时解冻资金并记账
+func (s *DepositWithdrawalCommandService) unfreezeWithdrawal(txCtx context.Context, w *domain.WithdrawalOrder, memo string) error {
+	account, err := s.accountRepo.Get(txCtx, w.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		return nil
+	}
+	applied, err := postJournal(txCtx, s.ledger, domain.JournalKindUnfreeze, "WDR:"+w.WithdrawalNo+":UNFREEZE", memo,
+		domain.MovementLines(account, domain.LedgerCustomerFrozen, domain.LedgerCustomerAvailable, w.Amount), account)
+	if err != nil || !applied {
+		return err
+	}
+	if err := s.accountRepo.Save(txCtx, account); err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
+	return nil
+}
+
+// ReviewAMLHoldRequest 合规复核 AML 挂起
+type ReviewAMLHoldRequest struct {
+	WithdrawalNo string
+	Reviewer     string
+	Release      bool
+	Remark       string
+}
+
+// ReviewAMLHold 合规复核：放行后可再次发起出款，拒绝则解冻资金
+func (s *DepositWithdrawalCommandService) ReviewAMLHold(ctx context.Context, req *ReviewAMLHoldRequest) error {
+	w, err := s.withdrawalOrderRepo.FindByWithdrawalNo(ctx, req.WithdrawalNo)
+	if err != nil {
+		return fmt.Errorf("failed to find withdrawal order: %w", err)
+	}
+	if w == nil {
+		return errors.New("withdrawal order not found")
+	}
+	if w.Status != domain.WithdrawalStatusApproved || w.AMLStatus != domain.AMLStatusHold {
+		return fmt.Errorf("withdrawal %s is not held by aml screening", req.WithdrawalNo)
+	}
+	if req.Reviewer == "" || req.Reviewer == w.UserID || (s.policies != nil && !s.policies.CanReviewAML(req.Reviewer)) {
+		return domain.ErrApproverNotAllowed
+	}
+
+	now := time.Now()
+	return s.withdrawalOrderRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if req.Release {
+			w.ClearAML(req.Reviewer, req.Remark, now)
+		} else {
+			if err := s.unfreezeWithdrawal(txCtx, w, "withdrawal rejected by aml review"); err != nil {
+				return err
+			}
+			w.Approvals = append(w.Approvals, domain.WithdrawalApproval{Approver: req.Reviewer, Stage: domain.ApprovalStageAML, Remark: req.Remark, At: now})
+			if err := w.Reject(txCtx, req.Reviewer, req.Remark); err != nil {
+				return fmt.Errorf("failed to reject withdrawal: %w", err)
+			}
+		}
+		if err := s.withdrawalOrderRepo.Update(txCtx, w); err != nil {
+			return fmt.Errorf("failed to update withdrawal order: %w", err)
+		}
+		s.logger.Info("withdrawal aml hold reviewed", "withdrawal_no", req.WithdrawalNo, "released", req.Release, "reviewer", req.Reviewer)
+		return nil
+	})
+}
+
+// WithdrawalQueueItem 审批队列条目
+type WithdrawalQueueItem struct {
+	*domain.WithdrawalOrder
+	// Stage REVIEW 为人工审核，AML 为合规复核
+	Stage              domain.ApprovalStage `json:"stage"`
+	ApprovalsRemaining int                  `json:"approvals_remaining"`
+	Overdue            bool                 `json:"overdue"`
+	// SLARemainingSeconds 距审核时限的秒数，超时为负，未设时限为 0
+	SLARemainingSeconds int64 `json:"sla_remaining_seconds"`
+}
+
+// ListApprovalQueue 审批队列：人工审核中与 AML 挂起的提现，按审核时限升序
+func (s *DepositWithdrawalCommandService) ListApprovalQueue(ctx context.Context, offset, limit int) ([]*WithdrawalQueueItem, int64, error) {
+	if limit <= 0 || limit > withdrawalQueuePageSize {
+		limit = withdrawalQueuePageSize
+	}
+	orders, total, err := s.withdrawalOrderRepo.FindAwaitingReview(ctx, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	items := make([]*WithdrawalQueueItem, 0, len(orders))
+	for _, w := range orders {
+		item := &WithdrawalQueueItem{WithdrawalOrder: w, Stage: domain.ApprovalStageReview, Overdue: w.ReviewOverdue(now)}
+		if w.Status == domain.WithdrawalStatusAuditing {
+			item.ApprovalsRemaining = w.ApprovalsRemaining()
+		} else {
+			item.Stage = domain.ApprovalStageAML
+			item.ApprovalsRemaining = 1
+		}
+		if w.ReviewDueAt != nil {
+			item.SLARemainingSeconds = int64(w.ReviewDueAt.Sub(now) / time.Second)
+		}
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// StartReviewSLAMonitor 周期性扫描审批队列，超出审核时限的提现升级一次并发布事件
+func (s *DepositWithdrawalCommandService) StartReviewSLAMonitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("withdrawal review sla monitor started", "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.EscalateOverdueReviews(ctx); err != nil {
+				s.logger.ErrorContext(ctx, "withdrawal review sla check failed", "error", err)
+			} else if n > 0 {
+				s.logger.WarnContext(ctx, "withdrawal reviews escalated", "count", n)
+			}
+		}
+	}
+}
+
+// EscalateOverdueReviews 升级超时未审的提现，返回本次升级数量
+func (s *DepositWithdrawalCommandService) EscalateOverdueReviews(ctx context.Context) (int, error) {
+	now := time.Now()
+	var overdue []*domain.WithdrawalOrder
+	for offset := 0; ; offset += withdrawalQueuePageSize {
+		orders, _, err := s.withdrawalOrderRepo.FindAwaitingReview(ctx, offset, withdrawalQueuePageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, w := range orders {
+			if w.ReviewOverdue(now) && !w.ReviewEscalated {
+				overdue = append(overdue, w)
+			}
+		}
+		if len(orders) < withdrawalQueuePageSize {
+			break
+		}
+	}
+
+	escalated := 0
+	for _, w := range overdue {
+		err := s.withdrawalOrderRepo.WithTx(ctx, func(txCtx context.Context) error {
+			w.ReviewEscalated = true
+			if err := s.withdrawalOrderRepo.Update(txCtx, w); err != nil {
+				return err
+			}
+			if s.publisher == nil {
+				return nil
+			}
+			return s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.WithdrawalReviewOverdueEventType, w.WithdrawalNo, map[string]any{
+				"withdrawal_no":       w.WithdrawalNo,
+				"user_id":             w.UserID,
+				"amount":              w.Amount.String(),
+				"currency":            w.Currency,
+				"status":              string(w.Status),
+				"aml_status":          string(w.AMLStatus),
+				"approvals_remaining": w.ApprovalsRemaining(),
+				"review_due_at":       w.ReviewDueAt.Format(time.RFC3339),
+				"overdue_seconds":     int64(now.Sub(*w.ReviewDueAt) / time.Second),
+			})
+		})
+		if err != nil {
+			return escalated, fmt.Errorf("failed to escalate withdrawal %s: %w", w.WithdrawalNo, err)
+		}
+		s.logger.WarnContext(ctx, "withdrawal review overdue", "withdrawal_no", w.WithdrawalNo,
+			"due_at", w.ReviewDueAt.Format(time.RFC3339), "amount", w.Amount.String(), "currency", w.Currency)
+		escalated++
+	}
+	return escalated, nil
+}
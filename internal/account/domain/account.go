@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -64,6 +65,12 @@ func NewAccount(accountID, userID, currency string, accType AccountType) *Accoun
 	return a
 }
 
+// ApplyChange 先把事件应用到聚合状态，再记录为未提交事件；基类只记录事件不修改状态
+func (a *Account) ApplyChange(event eventsourcing.DomainEvent) {
+	a.Apply(event)
+	a.AggregateRoot.ApplyChange(event)
+}
+
 // Apply 实现了 eventsourcing.EventApplier 接口
 func (a *Account) Apply(event eventsourcing.DomainEvent) {
 	switch e := event.(type) {
@@ -99,6 +106,8 @@ func (a *Account) Apply(event eventsourcing.DomainEvent) {
 	case *InterestAccruedEvent:
 		a.AccruedInterest = e.Total
 	case *InterestSettledEvent:
+		// 普通账户从余额扣收利息，可用资金同步减少
+		a.AvailableBalance = a.AvailableBalance.Add(e.Balance.Sub(a.Balance))
 		a.Balance = e.Balance
 		a.BorrowedAmount = a.BorrowedAmount.Add(e.PrincipalDelta)
 		a.AccruedInterest = decimal.Zero
@@ -229,6 +238,52 @@ func (a *Account) SettleInterest() {
 	})
 }
 
+// PostEntry 以已记账的分录为准变动账户：由分录对本账户分户科目的影响确定资金变动并生成对应事件。
+// 影响不对应任何资金变动或余额不足时返回 ErrEntryRejected，账户保持不变
+func (a *Account) PostEntry(entry *JournalEntry) error {
+	eff := entry.EffectOn(a)
+	x := eff.magnitude()
+	if x.IsZero() {
+		return nil
+	}
+	settle := AccountEffect{Available: x.Neg(), AccruedInterest: x.Neg()}
+	if a.AccountType == AccountTypeMargin {
+		settle = AccountEffect{Borrowed: x, AccruedInterest: x.Neg()}
+	}
+
+	applied := true
+	switch {
+	case eff.equal(AccountEffect{Available: x}):
+		a.Deposit(x)
+	case eff.equal(AccountEffect{Available: x.Neg()}):
+		applied = a.Withdraw(x)
+	case eff.equal(AccountEffect{Available: x.Neg(), Frozen: x}):
+		applied = a.Freeze(x, entry.Memo)
+	case eff.equal(AccountEffect{Available: x, Frozen: x.Neg()}):
+		applied = a.Unfreeze(x)
+	case eff.equal(AccountEffect{Frozen: x.Neg()}):
+		applied = a.DeductFrozen(x)
+	case eff.equal(AccountEffect{Available: x, Borrowed: x}):
+		applied = a.Borrow(x)
+	case eff.equal(AccountEffect{Available: x.Neg(), Borrowed: x.Neg()}):
+		applied = a.Repay(x)
+	case eff.equal(AccountEffect{AccruedInterest: x}):
+		a.ApplyChange(&InterestAccruedEvent{
+			AccountID: a.AccountID,
+			Amount:    x,
+			Total:     a.AccruedInterest.Add(x),
+		})
+	case eff.equal(settle) && x.Equal(a.AccruedInterest):
+		a.SettleInterest()
+	default:
+		applied = false
+	}
+	if !applied {
+		return fmt.Errorf("%w: entry %s on account %s", ErrEntryRejected, entry.Reference, a.AccountID)
+	}
+	return nil
+}
+
 // UpdateVIPLevel 更新 VIP 等级
 func (a *Account) UpdateVIPLevel(level int) {
 	if a.VIPLevel != level {
@@ -174,7 +174,16 @@ type WithdrawalOrder struct {
 	GatewayReference string           `json:"gateway_reference"`
 	FailureReason    string           `json:"failure_reason"`
 	CompletedAt      *time.Time       `json:"completed_at"`
-	fsm              *fsm.Machine[string, string]
+	// 提现风控：所需审批人数、审批记录、触发审核的原因、冷静期与 AML 筛查
+	RequiredApprovals int                  `json:"required_approvals"`
+	Approvals         []WithdrawalApproval `json:"approvals"`
+	ReviewReasons     []string             `json:"review_reasons"`
+	ReviewDueAt       *time.Time           `json:"review_due_at"`
+	ReviewEscalated   bool                 `json:"review_escalated"`
+	EarliestPayoutAt  *time.Time           `json:"earliest_payout_at"`
+	AMLStatus         AMLStatus            `json:"aml_status"`
+	AMLReference      string               `json:"aml_reference"`
+	fsm               *fsm.Machine[string, string]
 }
 
 func (WithdrawalOrder) TableName() string {
@@ -206,6 +215,8 @@ func (w *WithdrawalOrder) initFSM() {
 	m.AddTransition(string(WithdrawalStatusPending), "AUDIT", string(WithdrawalStatusAuditing))
 	m.AddTransition(string(WithdrawalStatusAuditing), "APPROVE", string(WithdrawalStatusApproved))
 	m.AddTransition(string(WithdrawalStatusAuditing), "REJECT", string(WithdrawalStatusRejected))
+	// AML 挂起的已审批提现可由合规复核拒绝
+	m.AddTransition(string(WithdrawalStatusApproved), "REJECT", string(WithdrawalStatusRejected))
 	m.AddTransition(string(WithdrawalStatusApproved), "PROCESS", string(WithdrawalStatusProcessing))
 	m.AddTransition(string(WithdrawalStatusProcessing), "COMPLETE", string(WithdrawalStatusCompleted))
 	m.AddTransition(string(WithdrawalStatusProcessing), "FAIL", string(WithdrawalStatusFailed))
@@ -1,7 +1,10 @@
 // 生成摘要：充值和提现仓储接口定义。
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // DepositOrderRepository 充值订单仓储接口
 type DepositOrderRepository interface {
@@ -15,6 +18,10 @@ type DepositOrderRepository interface {
 	FindByUserID(ctx context.Context, userID string, offset, limit int) ([]*DepositOrder, int64, error)
 	// FindByAccountID 根据账户ID查询列表
 	FindByAccountID(ctx context.Context, accountID string, offset, limit int) ([]*DepositOrder, int64, error)
+	// FindCompletedByUserBetween 查询用户在 [from, to) 内入账完成的充值订单，按完成时间升序
+	FindCompletedByUserBetween(ctx context.Context, userID string, from, to time.Time) ([]*DepositOrder, error)
+	// FindCompletedByGatewayBetween 查询某网关在 [from, to) 内入账完成的充值订单，供网关日终对账
+	FindCompletedByGatewayBetween(ctx context.Context, gatewayType GatewayType, from, to time.Time) ([]*DepositOrder, error)
 	// Update 更新充值订单
 	Update(ctx context.Context, deposit *DepositOrder) error
 	// WithTx 事务执行
@@ -33,8 +40,18 @@ type WithdrawalOrderRepository interface {
 	FindByUserID(ctx context.Context, userID string, offset, limit int) ([]*WithdrawalOrder, int64, error)
 	// FindByAccountID 根据账户ID查询列表
 	FindByAccountID(ctx context.Context, accountID string, offset, limit int) ([]*WithdrawalOrder, int64, error)
+	// FindCompletedByUserBetween 查询用户在 [from, to) 内完成出款的提现订单，按完成时间升序
+	FindCompletedByUserBetween(ctx context.Context, userID string, from, to time.Time) ([]*WithdrawalOrder, error)
+	// FindCompletedBetween 查询 [from, to) 内完成出款的全部提现订单，供网关日终对账
+	FindCompletedBetween(ctx context.Context, from, to time.Time) ([]*WithdrawalOrder, error)
 	// FindPendingForAudit 查询待审核列表
 	FindPendingForAudit(ctx context.Context, offset, limit int) ([]*WithdrawalOrder, int64, error)
+	// FindAwaitingReview 查询审批队列（审核中或 AML 挂起），按审核时限升序
+	FindAwaitingReview(ctx context.Context, offset, limit int) ([]*WithdrawalOrder, int64, error)
+	// FindByUserSince 查询用户某币种自 since 起发起且未被拒绝/失败的提现，用于限额统计
+	FindByUserSince(ctx context.Context, userID, currency string, since time.Time) ([]*WithdrawalOrder, error)
+	// FindFirstByBeneficiary 查询用户向某收款账户发起的最早一笔未被拒绝的提现，不存在时返回 nil
+	FindFirstByBeneficiary(ctx context.Context, userID, bankAccountNo string) (*WithdrawalOrder, error)
 	// Update 更新提现订单
 	Update(ctx context.Context, withdrawal *WithdrawalOrder) error
 	// WithTx 事务执行
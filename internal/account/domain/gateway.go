@@ -2,10 +2,19 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+var (
+	// ErrInvalidWebhookSignature 网关回调签名校验失败或已过期
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrUnknownPaymentRail 未接入的支付通道
+	ErrUnknownPaymentRail = errors.New("unknown payment rail")
+)
+
 // DepositGatewayRequest 表示充值网关请求参数。
 type DepositGatewayRequest struct {
 	DepositNo   string
@@ -17,6 +26,7 @@ type DepositGatewayRequest struct {
 }
 
 // WithdrawalGatewayRequest 表示提现网关请求参数。
+// 网关以 WithdrawalNo 作为幂等键，同一提现单重复请求只打款一次并返回首次的网关流水号。
 type WithdrawalGatewayRequest struct {
 	WithdrawalNo  string
 	UserID        string
@@ -34,3 +44,62 @@ type FundsGateway interface {
 	CreateDepositPayment(ctx context.Context, req *DepositGatewayRequest) (string, error)
 	Payout(ctx context.Context, req *WithdrawalGatewayRequest) (string, error)
 }
+
+// GatewayEventType 网关异步通知类型
+type GatewayEventType string
+
+const (
+	GatewayEventDepositSucceeded GatewayEventType = "DEPOSIT_SUCCEEDED"
+	GatewayEventDepositFailed    GatewayEventType = "DEPOSIT_FAILED"
+)
+
+// GatewayNotification 验签后的网关异步通知
+type GatewayNotification struct {
+	EventID       string
+	Type          GatewayEventType
+	DepositNo     string
+	TransactionID string
+	ThirdPartyNo  string
+	Amount        decimal.Decimal
+	Currency      string
+	Reason        string
+	OccurredAt    time.Time
+}
+
+// GatewayStatementKind 网关对账单条目类型
+type GatewayStatementKind string
+
+const (
+	GatewayStatementDeposit GatewayStatementKind = "DEPOSIT"
+	GatewayStatementPayout  GatewayStatementKind = "PAYOUT"
+)
+
+// GatewayStatementLine 网关日终对账单中的一笔已结算资金往来
+type GatewayStatementLine struct {
+	Kind GatewayStatementKind
+	// Reference 本系统单号（充值单号或提现单号）
+	Reference   string
+	ProviderRef string
+	Amount      decimal.Decimal
+	Currency    string
+	SettledAt   time.Time
+}
+
+// PaymentRail 支付通道适配器：发起收付款、校验并解析回调、拉取日终对账单
+type PaymentRail interface {
+	FundsGateway
+	Type() GatewayType
+	// ParseWebhook 校验回调签名与时间戳并解析通知，签名不符时返回 ErrInvalidWebhookSignature
+	ParseWebhook(signature, timestamp string, body []byte) (*GatewayNotification, error)
+	// FetchStatement 拉取 [from, to) 内已结算的资金往来
+	FetchStatement(ctx context.Context, from, to time.Time) ([]GatewayStatementLine, error)
+}
+
+// PaymentRails 已接入的支付通道集合
+type PaymentRails interface {
+	FundsGateway
+	Rail(gatewayType GatewayType) (PaymentRail, bool)
+	// PayoutRail 出款所用通道
+	PayoutRail() PaymentRail
+	List() []PaymentRail
+}
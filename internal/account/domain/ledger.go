@@ -0,0 +1,483 @@
+// 变更说明：在账户余额之下引入复式记账总账：科目表、借贷必须平衡且只追加的会计分录、由分录推导的科目余额，
+// 以及试算平衡与总账-账户余额一致性核对。
+// 假设：分录按业务意图生成并先于账户变动记账，账户各余额字段由分录对该账户分户科目的发生额推导；
+// 入金、出金与冻结扣划的对手科目由调用方按业务场景指定（外部出入金为公司银行存款，交易交收为清算往来）；
+// 客户类科目按账户 ID 分户核算。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnbalancedEntry 分录借贷不平衡
+	ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+	// ErrInvalidJournalLine 分录行不合法
+	ErrInvalidJournalLine = errors.New("invalid journal line")
+	// ErrJournalPosted 同一幂等键的分录已记账，对应的账户变动已生效
+	ErrJournalPosted = errors.New("journal entry already posted")
+	// ErrEntryRejected 分录对账户的影响不对应任何资金变动，或账户余额不足以承接
+	ErrEntryRejected = errors.New("journal entry rejected by account")
+)
+
+// LedgerAccountType 科目类别
+type LedgerAccountType string
+
+const (
+	LedgerAccountAsset     LedgerAccountType = "ASSET"
+	LedgerAccountLiability LedgerAccountType = "LIABILITY"
+	LedgerAccountIncome    LedgerAccountType = "INCOME"
+)
+
+// LedgerAccountCode 科目代码
+type LedgerAccountCode string
+
+const (
+	// LedgerHouseCash 公司银行存款（客户资金存管户）
+	LedgerHouseCash LedgerAccountCode = "HOUSE_CASH"
+	// LedgerSettlementClearing 清算往来：交易交收中与清算机构之间的应收应付
+	LedgerSettlementClearing LedgerAccountCode = "SETTLEMENT_CLEARING"
+	// LedgerInternalTransfer 内部划转往来：同一主账户下账户间划转的过渡科目，每笔划转内借贷相抵
+	LedgerInternalTransfer LedgerAccountCode = "INTERNAL_TRANSFER"
+	// LedgerCustomerAvailable 客户可用资金
+	LedgerCustomerAvailable LedgerAccountCode = "CUSTOMER_AVAILABLE"
+	// LedgerCustomerFrozen 客户冻结资金
+	LedgerCustomerFrozen LedgerAccountCode = "CUSTOMER_FROZEN"
+	// LedgerMarginLoans 融资借款本金
+	LedgerMarginLoans LedgerAccountCode = "MARGIN_LOANS"
+	// LedgerInterestReceivable 应收利息
+	LedgerInterestReceivable LedgerAccountCode = "INTEREST_RECEIVABLE"
+	// LedgerFeeIncome 手续费收入
+	LedgerFeeIncome LedgerAccountCode = "FEE_INCOME"
+	// LedgerInterestIncome 利息收入
+	LedgerInterestIncome LedgerAccountCode = "INTEREST_INCOME"
+)
+
+// LedgerAccount 科目
+type LedgerAccount struct {
+	Code LedgerAccountCode
+	Name string
+	Type LedgerAccountType
+	// PerCustomer 是否按客户账户分户核算
+	PerCustomer bool
+}
+
+// DebitNormal 余额方向是否在借方
+func (a LedgerAccount) DebitNormal() bool {
+	return a.Type == LedgerAccountAsset
+}
+
+var chartOfAccounts = map[LedgerAccountCode]LedgerAccount{
+	LedgerHouseCash:          {Code: LedgerHouseCash, Name: "公司银行存款", Type: LedgerAccountAsset},
+	LedgerSettlementClearing: {Code: LedgerSettlementClearing, Name: "清算往来", Type: LedgerAccountAsset},
+	LedgerInternalTransfer:   {Code: LedgerInternalTransfer, Name: "内部划转往来", Type: LedgerAccountAsset},
+	LedgerCustomerAvailable:  {Code: LedgerCustomerAvailable, Name: "客户可用资金", Type: LedgerAccountLiability, PerCustomer: true},
+	LedgerCustomerFrozen:     {Code: LedgerCustomerFrozen, Name: "客户冻结资金", Type: LedgerAccountLiability, PerCustomer: true},
+	LedgerMarginLoans:        {Code: LedgerMarginLoans, Name: "融资借款", Type: LedgerAccountAsset, PerCustomer: true},
+	LedgerInterestReceivable: {Code: LedgerInterestReceivable, Name: "应收利息", Type: LedgerAccountAsset, PerCustomer: true},
+	LedgerFeeIncome:          {Code: LedgerFeeIncome, Name: "手续费收入", Type: LedgerAccountIncome},
+	LedgerInterestIncome:     {Code: LedgerInterestIncome, Name: "利息收入", Type: LedgerAccountIncome},
+}
+
+// ChartOfAccounts 科目表，按科目代码排序
+func ChartOfAccounts() []LedgerAccount {
+	list := make([]LedgerAccount, 0, len(chartOfAccounts))
+	for _, a := range chartOfAccounts {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// LookupLedgerAccount 查询科目
+func LookupLedgerAccount(code LedgerAccountCode) (LedgerAccount, bool) {
+	a, ok := chartOfAccounts[code]
+	return a, ok
+}
+
+// JournalKind 分录业务类型
+type JournalKind string
+
+const (
+	JournalKindOpening            JournalKind = "OPENING"
+	JournalKindDeposit            JournalKind = "DEPOSIT"
+	JournalKindWithdrawal         JournalKind = "WITHDRAWAL"
+	JournalKindFreeze             JournalKind = "FREEZE"
+	JournalKindUnfreeze           JournalKind = "UNFREEZE"
+	JournalKindTradeDebit         JournalKind = "TRADE_DEBIT"
+	JournalKindTradeCredit        JournalKind = "TRADE_CREDIT"
+	JournalKindBorrow             JournalKind = "BORROW"
+	JournalKindRepay              JournalKind = "REPAY"
+	JournalKindInterestAccrual    JournalKind = "INTEREST_ACCRUAL"
+	JournalKindInterestSettlement JournalKind = "INTEREST_SETTLEMENT"
+	JournalKindInternalTransfer   JournalKind = "INTERNAL_TRANSFER"
+)
+
+// JournalLine 分录行：借贷方恰有一方为正数
+type JournalLine struct {
+	AccountCode LedgerAccountCode
+	// OwnerID 分户核算科目的客户账户 ID，公司科目为空
+	OwnerID  string
+	Currency string
+	Debit    decimal.Decimal
+	Credit   decimal.Decimal
+}
+
+// JournalEntry 会计分录，创建后不可修改，更正通过反向分录完成
+type JournalEntry struct {
+	EntryID string
+	// Reference 业务幂等键，同一 Reference 只记账一次
+	Reference string
+	Kind      JournalKind
+	Memo      string
+	PostedAt  time.Time
+	Lines     []JournalLine
+}
+
+// NewJournalEntry 创建分录并校验：至少两行、科目存在且分户正确、每个币种借贷相等
+func NewJournalEntry(entryID, reference string, kind JournalKind, memo string, lines []JournalLine) (*JournalEntry, error) {
+	if reference == "" {
+		return nil, errors.New("journal entry reference is required")
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: entry %s has %d lines", ErrUnbalancedEntry, reference, len(lines))
+	}
+	totals := make(map[string]decimal.Decimal)
+	for i, l := range lines {
+		if err := l.validate(); err != nil {
+			return nil, fmt.Errorf("entry %s line %d: %w", reference, i+1, err)
+		}
+		totals[l.Currency] = totals[l.Currency].Add(l.Debit).Sub(l.Credit)
+	}
+	for ccy, diff := range totals {
+		if !diff.IsZero() {
+			return nil, fmt.Errorf("%w: entry %s %s debit-credit=%s", ErrUnbalancedEntry, reference, ccy, diff)
+		}
+	}
+	return &JournalEntry{
+		EntryID:   entryID,
+		Reference: reference,
+		Kind:      kind,
+		Memo:      memo,
+		PostedAt:  time.Now(),
+		Lines:     lines,
+	}, nil
+}
+
+func (l JournalLine) validate() error {
+	acc, ok := LookupLedgerAccount(l.AccountCode)
+	if !ok {
+		return fmt.Errorf("%w: unknown account %s", ErrInvalidJournalLine, l.AccountCode)
+	}
+	if acc.PerCustomer != (l.OwnerID != "") {
+		return fmt.Errorf("%w: account %s owner %q", ErrInvalidJournalLine, l.AccountCode, l.OwnerID)
+	}
+	if l.Currency == "" {
+		return fmt.Errorf("%w: currency is required", ErrInvalidJournalLine)
+	}
+	if l.Debit.IsNegative() || l.Credit.IsNegative() || l.Debit.IsPositive() == l.Credit.IsPositive() {
+		return fmt.Errorf("%w: exactly one positive side required, debit=%s credit=%s", ErrInvalidJournalLine, l.Debit, l.Credit)
+	}
+	return nil
+}
+
+func debitLine(code LedgerAccountCode, owner, currency string, amount decimal.Decimal) JournalLine {
+	return JournalLine{AccountCode: code, OwnerID: owner, Currency: currency, Debit: amount}
+}
+
+func creditLine(code LedgerAccountCode, owner, currency string, amount decimal.Decimal) JournalLine {
+	return JournalLine{AccountCode: code, OwnerID: owner, Currency: currency, Credit: amount}
+}
+
+// ownerOf 公司科目不分户
+func ownerOf(code LedgerAccountCode, accountID string) string {
+	if acc, ok := LookupLedgerAccount(code); ok && acc.PerCustomer {
+		return accountID
+	}
+	return ""
+}
+
+// MovementLines 按业务意图生成一笔客户资金变动的分录行：借 dr、贷 cr，分户科目记在该账户名下。
+// 入金、出金与冻结扣划的对手科目由调用方按业务场景指定：外部出入金使用 LedgerHouseCash，交易交收使用 LedgerSettlementClearing。
+// 金额非正时不产生分录行。
+func MovementLines(acc *Account, dr, cr LedgerAccountCode, amount decimal.Decimal) []JournalLine {
+	if !amount.IsPositive() {
+		return nil
+	}
+	id, ccy := acc.AccountID, acc.Currency
+	return []JournalLine{
+		debitLine(dr, ownerOf(dr, id), ccy, amount),
+		creditLine(cr, ownerOf(cr, id), ccy, amount),
+	}
+}
+
+// InterestAccrualLines 按借款本金与利率计提应收利息，无借款时不产生分录行
+func InterestAccrualLines(acc *Account, rate decimal.Decimal) []JournalLine {
+	if !acc.BorrowedAmount.IsPositive() {
+		return nil
+	}
+	return MovementLines(acc, LedgerInterestReceivable, LedgerInterestIncome, acc.BorrowedAmount.Mul(rate))
+}
+
+// InterestSettlementLines 结转全部应计利息：杠杆账户资本化为借款本金，普通账户从可用资金扣收
+func InterestSettlementLines(acc *Account) []JournalLine {
+	if acc.AccountType == AccountTypeMargin {
+		return MovementLines(acc, LedgerMarginLoans, LedgerInterestReceivable, acc.AccruedInterest)
+	}
+	return MovementLines(acc, LedgerCustomerAvailable, LedgerInterestReceivable, acc.AccruedInterest)
+}
+
+// FeeLines 出金手续费：从对外划付的款项中留存为手续费收入
+func FeeLines(currency string, fee decimal.Decimal) []JournalLine {
+	if !fee.IsPositive() {
+		return nil
+	}
+	return []JournalLine{
+		debitLine(LedgerHouseCash, "", currency, fee),
+		creditLine(LedgerFeeIncome, "", currency, fee),
+	}
+}
+
+// OpeningBalanceLines 为启用总账前已存在的账户生成期初分录行，使总账余额与账户当前余额一致
+func OpeningBalanceLines(acc *Account) []JournalLine {
+	id, ccy := acc.AccountID, acc.Currency
+	var lines []JournalLine
+	cash := acc.AvailableBalance.Add(acc.FrozenBalance).Sub(acc.BorrowedAmount)
+	switch {
+	case cash.IsPositive():
+		lines = append(lines, debitLine(LedgerHouseCash, "", ccy, cash))
+	case cash.IsNegative():
+		lines = append(lines, creditLine(LedgerHouseCash, "", ccy, cash.Neg()))
+	}
+	if acc.BorrowedAmount.IsPositive() {
+		lines = append(lines, debitLine(LedgerMarginLoans, id, ccy, acc.BorrowedAmount))
+	}
+	if acc.AvailableBalance.IsPositive() {
+		lines = append(lines, creditLine(LedgerCustomerAvailable, id, ccy, acc.AvailableBalance))
+	}
+	if acc.FrozenBalance.IsPositive() {
+		lines = append(lines, creditLine(LedgerCustomerFrozen, id, ccy, acc.FrozenBalance))
+	}
+	if acc.AccruedInterest.IsPositive() {
+		lines = append(lines,
+			debitLine(LedgerInterestReceivable, id, ccy, acc.AccruedInterest),
+			creditLine(LedgerInterestIncome, "", ccy, acc.AccruedInterest))
+	}
+	return lines
+}
+
+// LedgerBalanceKey 科目余额维度
+type LedgerBalanceKey struct {
+	AccountCode LedgerAccountCode
+	OwnerID     string
+	Currency    string
+}
+
+// LedgerBalance 由分录累计得到的科目发生额
+type LedgerBalance struct {
+	LedgerBalanceKey
+	Debit  decimal.Decimal
+	Credit decimal.Decimal
+}
+
+// Net 按科目余额方向计算的余额
+func (b *LedgerBalance) Net() decimal.Decimal {
+	if acc, ok := LookupLedgerAccount(b.AccountCode); ok && !acc.DebitNormal() {
+		return b.Credit.Sub(b.Debit)
+	}
+	return b.Debit.Sub(b.Credit)
+}
+
+// LedgerBalances 科目余额表
+type LedgerBalances map[LedgerBalanceKey]*LedgerBalance
+
+// Post 累计一笔分录
+func (b LedgerBalances) Post(entry *JournalEntry) {
+	for _, l := range entry.Lines {
+		b.AddLine(l)
+	}
+}
+
+// AddLine 累计一行发生额
+func (b LedgerBalances) AddLine(l JournalLine) {
+	key := LedgerBalanceKey{AccountCode: l.AccountCode, OwnerID: l.OwnerID, Currency: l.Currency}
+	bal, ok := b[key]
+	if !ok {
+		bal = &LedgerBalance{LedgerBalanceKey: key}
+		b[key] = bal
+	}
+	bal.Debit = bal.Debit.Add(l.Debit)
+	bal.Credit = bal.Credit.Add(l.Credit)
+}
+
+// Net 指定科目、客户与币种的余额，无发生额时为零
+func (b LedgerBalances) Net(code LedgerAccountCode, ownerID, currency string) decimal.Decimal {
+	if bal, ok := b[LedgerBalanceKey{AccountCode: code, OwnerID: ownerID, Currency: currency}]; ok {
+		return bal.Net()
+	}
+	return decimal.Zero
+}
+
+// AccountEffect 一笔分录对某个账户各余额字段的影响
+type AccountEffect struct {
+	Available       decimal.Decimal
+	Frozen          decimal.Decimal
+	Borrowed        decimal.Decimal
+	AccruedInterest decimal.Decimal
+}
+
+// EffectOn 按科目余额方向汇总分录中属于该账户、同币种的分户科目发生额
+func (e *JournalEntry) EffectOn(acc *Account) AccountEffect {
+	balances := make(LedgerBalances)
+	for _, l := range e.Lines {
+		if l.OwnerID == acc.AccountID && l.Currency == acc.Currency {
+			balances.AddLine(l)
+		}
+	}
+	id, ccy := acc.AccountID, acc.Currency
+	return AccountEffect{
+		Available:       balances.Net(LedgerCustomerAvailable, id, ccy),
+		Frozen:          balances.Net(LedgerCustomerFrozen, id, ccy),
+		Borrowed:        balances.Net(LedgerMarginLoans, id, ccy),
+		AccruedInterest: balances.Net(LedgerInterestReceivable, id, ccy),
+	}
+}
+
+// magnitude 首个非零字段的绝对值，单笔资金变动涉及的各字段变动额相同
+func (e AccountEffect) magnitude() decimal.Decimal {
+	for _, v := range []decimal.Decimal{e.Available, e.Frozen, e.Borrowed, e.AccruedInterest} {
+		if !v.IsZero() {
+			return v.Abs()
+		}
+	}
+	return decimal.Zero
+}
+
+func (e AccountEffect) equal(o AccountEffect) bool {
+	return e.Available.Equal(o.Available) && e.Frozen.Equal(o.Frozen) &&
+		e.Borrowed.Equal(o.Borrowed) && e.AccruedInterest.Equal(o.AccruedInterest)
+}
+
+// TrialBalanceRow 试算平衡表行：科目在某币种下跨客户汇总
+type TrialBalanceRow struct {
+	AccountCode LedgerAccountCode
+	Currency    string
+	Debit       decimal.Decimal
+	Credit      decimal.Decimal
+}
+
+// TrialBalance 试算平衡表
+type TrialBalance struct {
+	AsOf         time.Time
+	Rows         []TrialBalanceRow
+	TotalDebits  map[string]decimal.Decimal
+	TotalCredits map[string]decimal.Decimal
+}
+
+// NewTrialBalance 由科目余额生成试算平衡表
+func NewTrialBalance(balances LedgerBalances, asOf time.Time) *TrialBalance {
+	type rowKey struct {
+		code LedgerAccountCode
+		ccy  string
+	}
+	rows := make(map[rowKey]*TrialBalanceRow)
+	tb := &TrialBalance{
+		AsOf:         asOf,
+		TotalDebits:  make(map[string]decimal.Decimal),
+		TotalCredits: make(map[string]decimal.Decimal),
+	}
+	for key, bal := range balances {
+		rk := rowKey{code: key.AccountCode, ccy: key.Currency}
+		row, ok := rows[rk]
+		if !ok {
+			row = &TrialBalanceRow{AccountCode: key.AccountCode, Currency: key.Currency}
+			rows[rk] = row
+		}
+		row.Debit = row.Debit.Add(bal.Debit)
+		row.Credit = row.Credit.Add(bal.Credit)
+		tb.TotalDebits[key.Currency] = tb.TotalDebits[key.Currency].Add(bal.Debit)
+		tb.TotalCredits[key.Currency] = tb.TotalCredits[key.Currency].Add(bal.Credit)
+	}
+	for _, row := range rows {
+		tb.Rows = append(tb.Rows, *row)
+	}
+	sort.Slice(tb.Rows, func(i, j int) bool {
+		if tb.Rows[i].Currency != tb.Rows[j].Currency {
+			return tb.Rows[i].Currency < tb.Rows[j].Currency
+		}
+		return tb.Rows[i].AccountCode < tb.Rows[j].AccountCode
+	})
+	return tb
+}
+
+// UnbalancedCurrencies 借贷合计不相等的币种
+func (t *TrialBalance) UnbalancedCurrencies() []string {
+	var list []string
+	for ccy, dr := range t.TotalDebits {
+		if !dr.Equal(t.TotalCredits[ccy]) {
+			list = append(list, ccy)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// LedgerDiscrepancy 账户余额与总账余额不一致项
+type LedgerDiscrepancy struct {
+	AccountID    string
+	Currency     string
+	Field        string
+	AccountValue decimal.Decimal
+	LedgerValue  decimal.Decimal
+}
+
+// ReconcileAccount 核对账户各余额字段与总账分户余额
+func ReconcileAccount(acc *Account, balances LedgerBalances) []LedgerDiscrepancy {
+	id, ccy := acc.AccountID, acc.Currency
+	available := balances.Net(LedgerCustomerAvailable, id, ccy)
+	frozen := balances.Net(LedgerCustomerFrozen, id, ccy)
+	checks := []struct {
+		field   string
+		account decimal.Decimal
+		ledger  decimal.Decimal
+	}{
+		{"available_balance", acc.AvailableBalance, available},
+		{"frozen_balance", acc.FrozenBalance, frozen},
+		{"balance", acc.Balance, available.Add(frozen)},
+		{"borrowed_amount", acc.BorrowedAmount, balances.Net(LedgerMarginLoans, id, ccy)},
+		{"accrued_interest", acc.AccruedInterest, balances.Net(LedgerInterestReceivable, id, ccy)},
+	}
+	var list []LedgerDiscrepancy
+	for _, c := range checks {
+		if !c.account.Equal(c.ledger) {
+			list = append(list, LedgerDiscrepancy{
+				AccountID:    id,
+				Currency:     ccy,
+				Field:        c.field,
+				AccountValue: c.account,
+				LedgerValue:  c.ledger,
+			})
+		}
+	}
+	return list
+}
+
+// LedgerRepository 总账仓储：分录只追加，余额由分录汇总得到
+type LedgerRepository interface {
+	// Append 追加分录，Reference 已记账时返回 ErrJournalPosted
+	Append(ctx context.Context, entry *JournalEntry) error
+	// ListEntries 按记账顺序列出分录，ownerID 非空时只返回涉及该客户账户的分录
+	ListEntries(ctx context.Context, ownerID string, limit, offset int) ([]*JournalEntry, error)
+	// Balances 汇总科目余额，ownerID 非空时只汇总该客户账户的分户科目
+	Balances(ctx context.Context, ownerID string) (LedgerBalances, error)
+	// BalancesAt 汇总记账时间早于 at 的分录形成的科目余额
+	BalancesAt(ctx context.Context, ownerID string, at time.Time) (LedgerBalances, error)
+	// ListEntriesBetween 列出记账时间在 [from, to) 内且涉及该客户账户的分录
+	ListEntriesBetween(ctx context.Context, ownerID string, from, to time.Time) ([]*JournalEntry, error)
+}
@@ -0,0 +1,253 @@
+// 变更说明：客户对账单：按用户与期间汇总各币种期初/期末余额与期间资金活动（出入金、成交、手续费、利息、公司行动），
+// 渲染为 CSV 与 PDF 存档，并在生成后通知用户。
+// 假设：对账单的现金口径为账户总余额（可用 + 冻结），期初/期末余额取自总账；成交按计价币种计算现金影响，
+// 无法归类的差额单列为"其他变动"，保证每个币种的期初 + 期间变动 = 期末。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountStatementReadyEventType 对账单生成完成事件主题
+const AccountStatementReadyEventType = "account.statement_ready"
+
+var (
+	// ErrInvalidStatementPeriod 对账单期间不合法
+	ErrInvalidStatementPeriod = errors.New("invalid statement period")
+	// ErrStatementNotFound 对账单不存在
+	ErrStatementNotFound = errors.New("statement not found")
+)
+
+// StatementFrequency 对账单周期
+type StatementFrequency string
+
+const (
+	StatementFrequencyDaily   StatementFrequency = "DAILY"
+	StatementFrequencyMonthly StatementFrequency = "MONTHLY"
+	StatementFrequencyAdhoc   StatementFrequency = "ADHOC"
+)
+
+// StatementStatus 对账单状态
+type StatementStatus string
+
+const (
+	StatementStatusReady  StatementStatus = "READY"
+	StatementStatusFailed StatementStatus = "FAILED"
+)
+
+// StatementFormat 对账单文件格式
+type StatementFormat string
+
+const (
+	StatementFormatCSV StatementFormat = "CSV"
+	StatementFormatPDF StatementFormat = "PDF"
+)
+
+// ActivityCategory 对账单活动类别
+type ActivityCategory string
+
+const (
+	ActivityDeposit         ActivityCategory = "DEPOSIT"
+	ActivityWithdrawal      ActivityCategory = "WITHDRAWAL"
+	ActivityTrade           ActivityCategory = "TRADE"
+	ActivityFee             ActivityCategory = "FEE"
+	ActivityInterest        ActivityCategory = "INTEREST"
+	ActivityInterestAccrual ActivityCategory = "INTEREST_ACCRUAL"
+	ActivityCorporateAction ActivityCategory = "CORPORATE_ACTION"
+)
+
+// AffectsCash 是否计入现金余额变动；利息计提只形成应收，不影响现金
+func (c ActivityCategory) AffectsCash() bool {
+	return c != ActivityInterestAccrual
+}
+
+// StatementActivity 对账单期间的一条活动
+type StatementActivity struct {
+	Time     time.Time        `json:"time"`
+	Category ActivityCategory `json:"category"`
+	Currency string           `json:"currency"`
+	// Amount 对现金余额的影响，流入为正、流出为负；利息计提为计提金额
+	Amount decimal.Decimal `json:"amount"`
+	// Symbol/Quantity 证券类活动（成交、送股）的标的与数量变动
+	Symbol      string          `json:"symbol"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Reference   string          `json:"reference"`
+	Description string          `json:"description"`
+}
+
+// CurrencySummary 单一币种的对账汇总
+type CurrencySummary struct {
+	Currency         string          `json:"currency"`
+	Opening          decimal.Decimal `json:"opening"`
+	Deposits         decimal.Decimal `json:"deposits"`
+	Withdrawals      decimal.Decimal `json:"withdrawals"`
+	Trades           decimal.Decimal `json:"trades"`
+	Fees             decimal.Decimal `json:"fees"`
+	Interest         decimal.Decimal `json:"interest"`
+	CorporateActions decimal.Decimal `json:"corporate_actions"`
+	// Other 期末与期初加期间活动之间无法归类的差额（如内部划转、交收调整）
+	Other           decimal.Decimal `json:"other"`
+	Closing         decimal.Decimal `json:"closing"`
+	InterestAccrued decimal.Decimal `json:"interest_accrued"`
+}
+
+// Statement 客户对账单
+type Statement struct {
+	StatementID   string                     `json:"statement_id"`
+	UserID        string                     `json:"user_id"`
+	Frequency     StatementFrequency         `json:"frequency"`
+	PeriodStart   time.Time                  `json:"period_start"`
+	PeriodEnd     time.Time                  `json:"period_end"`
+	Status        StatementStatus            `json:"status"`
+	Summaries     []CurrencySummary          `json:"summaries"`
+	Activities    []StatementActivity        `json:"activities"`
+	Files         map[StatementFormat]string `json:"files"`
+	FailureReason string                     `json:"failure_reason"`
+	GeneratedAt   time.Time                  `json:"generated_at"`
+	// Warnings 部分活动来源不可用时的说明，同时写入对账单正文
+	Warnings []string `json:"warnings"`
+}
+
+// NewStatement 创建对账单，期间为 [start, end)
+func NewStatement(statementID, userID string, freq StatementFrequency, start, end time.Time) (*Statement, error) {
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: %s - %s", ErrInvalidStatementPeriod, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return &Statement{
+		StatementID: statementID,
+		UserID:      userID,
+		Frequency:   freq,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Files:       make(map[StatementFormat]string),
+	}, nil
+}
+
+// Build 按期初/期末余额与期间活动生成各币种汇总，活动按时间排序
+func (s *Statement) Build(opening, closing map[string]decimal.Decimal, activities []StatementActivity) {
+	sort.SliceStable(activities, func(i, j int) bool { return activities[i].Time.Before(activities[j].Time) })
+	s.Activities = activities
+
+	summaries := make(map[string]*CurrencySummary)
+	get := func(ccy string) *CurrencySummary {
+		sum, ok := summaries[ccy]
+		if !ok {
+			sum = &CurrencySummary{Currency: ccy}
+			summaries[ccy] = sum
+		}
+		return sum
+	}
+	for ccy, v := range opening {
+		get(ccy).Opening = v
+	}
+	for ccy, v := range closing {
+		get(ccy).Closing = v
+	}
+	for _, a := range activities {
+		if a.Currency == "" {
+			// 纯证券类活动（如送股）无现金影响
+			continue
+		}
+		sum := get(a.Currency)
+		switch a.Category {
+		case ActivityDeposit:
+			sum.Deposits = sum.Deposits.Add(a.Amount)
+		case ActivityWithdrawal:
+			sum.Withdrawals = sum.Withdrawals.Add(a.Amount)
+		case ActivityTrade:
+			sum.Trades = sum.Trades.Add(a.Amount)
+		case ActivityFee:
+			sum.Fees = sum.Fees.Add(a.Amount)
+		case ActivityInterest:
+			sum.Interest = sum.Interest.Add(a.Amount)
+		case ActivityInterestAccrual:
+			sum.InterestAccrued = sum.InterestAccrued.Add(a.Amount)
+		case ActivityCorporateAction:
+			sum.CorporateActions = sum.CorporateActions.Add(a.Amount)
+		}
+	}
+
+	s.Summaries = make([]CurrencySummary, 0, len(summaries))
+	for _, sum := range summaries {
+		sum.Other = sum.Closing.Sub(sum.Opening).Sub(sum.Movement())
+		s.Summaries = append(s.Summaries, *sum)
+	}
+	sort.Slice(s.Summaries, func(i, j int) bool { return s.Summaries[i].Currency < s.Summaries[j].Currency })
+}
+
+// Movement 已归类的期间现金变动合计
+func (c CurrencySummary) Movement() decimal.Decimal {
+	return c.Deposits.Add(c.Withdrawals).Add(c.Trades).Add(c.Fees).Add(c.Interest).Add(c.CorporateActions)
+}
+
+// MarkReady 文件已存档，对账单可供下载
+func (s *Statement) MarkReady(at time.Time) {
+	s.Status = StatementStatusReady
+	s.FailureReason = ""
+	s.GeneratedAt = at
+}
+
+// MarkFailed 生成失败
+func (s *Statement) MarkFailed(reason string, at time.Time) {
+	s.Status = StatementStatusFailed
+	s.FailureReason = reason
+	s.GeneratedAt = at
+}
+
+// StatementPeriod 返回 at 所在时刻之前最近一个完整周期 [start, end)，按 loc 的自然日/自然月切分
+func StatementPeriod(freq StatementFrequency, at time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := at.In(loc)
+	switch freq {
+	case StatementFrequencyDaily:
+		end := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return end.AddDate(0, 0, -1), end, nil
+	case StatementFrequencyMonthly:
+		end := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		return end.AddDate(0, -1, 0), end, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: unsupported frequency %s", ErrInvalidStatementPeriod, freq)
+	}
+}
+
+// StatementRepository 对账单仓储
+type StatementRepository interface {
+	Save(ctx context.Context, statement *Statement) error
+	// Get 不存在时返回 ErrStatementNotFound
+	Get(ctx context.Context, statementID string) (*Statement, error)
+	// FindByPeriod 查询用户某周期的对账单，不存在时返回 nil
+	FindByPeriod(ctx context.Context, userID string, freq StatementFrequency, periodStart time.Time) (*Statement, error)
+	// ListByUser 按期间倒序列出用户对账单
+	ListByUser(ctx context.Context, userID string, limit, offset int) ([]*Statement, error)
+	WithTx(ctx context.Context, fn func(txCtx context.Context) error) error
+}
+
+// StatementStorage 对账单文件存储
+type StatementStorage interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// StatementActivitySource 对账单活动来源（出入金、成交、手续费、公司行动等）
+type StatementActivitySource interface {
+	Name() string
+	ListActivity(ctx context.Context, userID string, from, to time.Time) ([]StatementActivity, error)
+}
+
+// StatementRenderer 对账单渲染器
+type StatementRenderer interface {
+	Format() StatementFormat
+	ContentType() string
+	Render(statement *Statement) ([]byte, error)
+}
@@ -0,0 +1,276 @@
+// 变更说明：机构客户主子账户：主账户下挂多个交易子账户，子账户按权限与限额独立交易和出金，
+// 同一主账户下的账户之间可即时内部划转；合并保证金模式下子账户资金不足时由主账户同币种资金自动补足。
+// 假设：主、子账户各以独立 user_id 标识，沿用按用户 + 币种定位资金账户的既有约定；层级只有一级，
+// 子账户不能再挂子账户，主账户不受子账户权限与限额约束。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountTransferredEventType 内部划转完成事件主题
+const AccountTransferredEventType = "account.transferred"
+
+var (
+	// ErrSubAccountNotFound 子账户不存在
+	ErrSubAccountNotFound = errors.New("sub-account not found")
+	// ErrInvalidHierarchy 层级关系不合法
+	ErrInvalidHierarchy = errors.New("invalid account hierarchy")
+	// ErrNotInSameHierarchy 划转双方不属于同一主账户
+	ErrNotInSameHierarchy = errors.New("accounts are not in the same hierarchy")
+	// ErrSubAccountPermissionDenied 子账户无此权限或已停用
+	ErrSubAccountPermissionDenied = errors.New("sub-account permission denied")
+	// ErrSubAccountLimitExceeded 超出子账户限额
+	ErrSubAccountLimitExceeded = errors.New("sub-account limit exceeded")
+)
+
+// SubAccountPermission 子账户权限
+type SubAccountPermission string
+
+const (
+	// PermissionTrade 交易（冻结资金下单）
+	PermissionTrade SubAccountPermission = "TRADE"
+	// PermissionTransferOut 向同层级账户划出资金
+	PermissionTransferOut SubAccountPermission = "TRANSFER_OUT"
+	// PermissionWithdraw 对外出金
+	PermissionWithdraw SubAccountPermission = "WITHDRAW"
+)
+
+// MarginMode 子账户保证金模式
+type MarginMode string
+
+const (
+	// MarginModeIsolated 逐仓：仅使用子账户自有资金
+	MarginModeIsolated MarginMode = "ISOLATED"
+	// MarginModeConsolidated 合并：资金不足部分由主账户补足
+	MarginModeConsolidated MarginMode = "CONSOLIDATED"
+)
+
+// SubAccountStatus 子账户状态
+type SubAccountStatus string
+
+const (
+	SubAccountStatusActive    SubAccountStatus = "ACTIVE"
+	SubAccountStatusSuspended SubAccountStatus = "SUSPENDED"
+)
+
+// SubAccount 子账户：挂在主账户下的交易主体
+type SubAccount struct {
+	SubUserID    string                 `json:"sub_user_id"`
+	MasterUserID string                 `json:"master_user_id"`
+	Name         string                 `json:"name"`
+	Permissions  []SubAccountPermission `json:"permissions"`
+	MarginMode   MarginMode             `json:"margin_mode"`
+	Status       SubAccountStatus       `json:"status"`
+	// MaxOrderAmount 单笔交易冻结上限，0 表示不限
+	MaxOrderAmount decimal.Decimal `json:"max_order_amount"`
+	// DailyTransferOutLimit 每日划出上限（各币种分别计算），0 表示不限
+	DailyTransferOutLimit decimal.Decimal `json:"daily_transfer_out_limit"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+// NewSubAccount 创建子账户，未指定保证金模式时为逐仓
+func NewSubAccount(masterUserID, subUserID, name string, permissions []SubAccountPermission, mode MarginMode) (*SubAccount, error) {
+	if masterUserID == "" || subUserID == "" || masterUserID == subUserID {
+		return nil, fmt.Errorf("%w: master %q, sub %q", ErrInvalidHierarchy, masterUserID, subUserID)
+	}
+	if mode == "" {
+		mode = MarginModeIsolated
+	}
+	s := &SubAccount{
+		SubUserID:    subUserID,
+		MasterUserID: masterUserID,
+		Name:         name,
+		Status:       SubAccountStatusActive,
+	}
+	if err := s.Configure(permissions, mode, decimal.Zero, decimal.Zero); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Configure 更新权限、保证金模式与限额
+func (s *SubAccount) Configure(permissions []SubAccountPermission, mode MarginMode, maxOrder, dailyTransferOut decimal.Decimal) error {
+	for _, p := range permissions {
+		switch p {
+		case PermissionTrade, PermissionTransferOut, PermissionWithdraw:
+		default:
+			return fmt.Errorf("unknown sub-account permission %q", p)
+		}
+	}
+	switch mode {
+	case MarginModeIsolated, MarginModeConsolidated:
+	default:
+		return fmt.Errorf("unknown margin mode %q", mode)
+	}
+	if maxOrder.IsNegative() || dailyTransferOut.IsNegative() {
+		return fmt.Errorf("%w: limits must not be negative", ErrSubAccountLimitExceeded)
+	}
+	s.Permissions = slices.Compact(slices.Sorted(slices.Values(permissions)))
+	s.MarginMode = mode
+	s.MaxOrderAmount = maxOrder
+	s.DailyTransferOutLimit = dailyTransferOut
+	return nil
+}
+
+// Allows 是否具备某项权限；停用的子账户不具备任何权限
+func (s *SubAccount) Allows(p SubAccountPermission) bool {
+	return s.Status == SubAccountStatusActive && slices.Contains(s.Permissions, p)
+}
+
+// CheckTrade 校验交易权限与单笔冻结上限
+func (s *SubAccount) CheckTrade(amount decimal.Decimal) error {
+	if !s.Allows(PermissionTrade) {
+		return fmt.Errorf("%w: %s cannot trade", ErrSubAccountPermissionDenied, s.SubUserID)
+	}
+	if s.MaxOrderAmount.IsPositive() && amount.GreaterThan(s.MaxOrderAmount) {
+		return fmt.Errorf("%w: order amount %s above %s", ErrSubAccountLimitExceeded, amount, s.MaxOrderAmount)
+	}
+	return nil
+}
+
+// CheckTransferOut 校验划出权限与当日划出额度，usedToday 为当日已划出金额
+func (s *SubAccount) CheckTransferOut(usedToday, amount decimal.Decimal) error {
+	if !s.Allows(PermissionTransferOut) {
+		return fmt.Errorf("%w: %s cannot transfer out", ErrSubAccountPermissionDenied, s.SubUserID)
+	}
+	if s.DailyTransferOutLimit.IsPositive() && usedToday.Add(amount).GreaterThan(s.DailyTransferOutLimit) {
+		return fmt.Errorf("%w: daily transfer out %s above %s", ErrSubAccountLimitExceeded, usedToday.Add(amount), s.DailyTransferOutLimit)
+	}
+	return nil
+}
+
+// AccountHierarchy 主账户及其全部子账户
+type AccountHierarchy struct {
+	MasterUserID string        `json:"master_user_id"`
+	SubAccounts  []*SubAccount `json:"sub_accounts"`
+}
+
+// UserIDs 层级内全部用户，主账户在前
+func (h *AccountHierarchy) UserIDs() []string {
+	ids := make([]string, 0, len(h.SubAccounts)+1)
+	ids = append(ids, h.MasterUserID)
+	for _, s := range h.SubAccounts {
+		ids = append(ids, s.SubUserID)
+	}
+	return ids
+}
+
+// Contains 用户是否属于该层级
+func (h *AccountHierarchy) Contains(userID string) bool {
+	return userID == h.MasterUserID || h.SubAccount(userID) != nil
+}
+
+// SubAccount 查找子账户，主账户或不属于该层级时返回 nil
+func (h *AccountHierarchy) SubAccount(userID string) *SubAccount {
+	for _, s := range h.SubAccounts {
+		if s.SubUserID == userID {
+			return s
+		}
+	}
+	return nil
+}
+
+// TransferKind 内部划转类型
+type TransferKind string
+
+const (
+	// TransferKindManual 手工划转
+	TransferKindManual TransferKind = "MANUAL"
+	// TransferKindMarginSweep 合并保证金下主账户自动补足子账户
+	TransferKindMarginSweep TransferKind = "MARGIN_SWEEP"
+)
+
+// InternalTransfer 同一主账户下账户之间的资金划转记录
+type InternalTransfer struct {
+	TransferNo   string          `json:"transfer_no"`
+	MasterUserID string          `json:"master_user_id"`
+	FromUserID   string          `json:"from_user_id"`
+	ToUserID     string          `json:"to_user_id"`
+	Currency     string          `json:"currency"`
+	Amount       decimal.Decimal `json:"amount"`
+	Kind         TransferKind    `json:"kind"`
+	Operator     string          `json:"operator"`
+	Memo         string          `json:"memo"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// MemberBalance 层级内单个账户在某币种上的资金
+type MemberBalance struct {
+	UserID     string          `json:"user_id"`
+	AccountID  string          `json:"account_id"`
+	Name       string          `json:"name,omitempty"`
+	MarginMode MarginMode      `json:"margin_mode,omitempty"`
+	Balance    decimal.Decimal `json:"balance"`
+	Available  decimal.Decimal `json:"available"`
+	Frozen     decimal.Decimal `json:"frozen"`
+	Borrowed   decimal.Decimal `json:"borrowed"`
+}
+
+// ConsolidatedBalance 主账户层面的合并资金与保证金
+type ConsolidatedBalance struct {
+	MasterUserID string          `json:"master_user_id"`
+	Currency     string          `json:"currency"`
+	Balance      decimal.Decimal `json:"balance"`
+	Available    decimal.Decimal `json:"available"`
+	Frozen       decimal.Decimal `json:"frozen"`
+	Borrowed     decimal.Decimal `json:"borrowed"`
+	// Equity 合并权益：总余额扣除借款
+	Equity decimal.Decimal `json:"equity"`
+	// SharedMargin 主账户可为合并保证金子账户补足的可用资金
+	SharedMargin decimal.Decimal `json:"shared_margin"`
+	Members      []MemberBalance `json:"members"`
+}
+
+// Consolidate 按层级汇总某币种资金，accounts 为层级内全部用户的账户
+func (h *AccountHierarchy) Consolidate(currency string, accounts []*Account) *ConsolidatedBalance {
+	cb := &ConsolidatedBalance{MasterUserID: h.MasterUserID, Currency: currency, Members: make([]MemberBalance, 0, len(accounts))}
+	for _, acc := range accounts {
+		if acc.Currency != currency || !h.Contains(acc.UserID) {
+			continue
+		}
+		m := MemberBalance{
+			UserID:    acc.UserID,
+			AccountID: acc.AccountID,
+			Balance:   acc.Balance,
+			Available: acc.AvailableBalance,
+			Frozen:    acc.FrozenBalance,
+			Borrowed:  acc.BorrowedAmount,
+		}
+		if sub := h.SubAccount(acc.UserID); sub != nil {
+			m.Name = sub.Name
+			m.MarginMode = sub.MarginMode
+		} else {
+			cb.SharedMargin = cb.SharedMargin.Add(acc.AvailableBalance)
+		}
+		cb.Balance = cb.Balance.Add(m.Balance)
+		cb.Available = cb.Available.Add(m.Available)
+		cb.Frozen = cb.Frozen.Add(m.Frozen)
+		cb.Borrowed = cb.Borrowed.Add(m.Borrowed)
+		cb.Members = append(cb.Members, m)
+	}
+	cb.Equity = cb.Balance.Sub(cb.Borrowed)
+	return cb
+}
+
+// SubAccountRepository 子账户与内部划转仓储
+type SubAccountRepository interface {
+	Save(ctx context.Context, s *SubAccount) error
+	// Get 查询子账户，不存在时返回 nil, nil
+	Get(ctx context.Context, subUserID string) (*SubAccount, error)
+	// GetForUpdate 查询并锁定子账户行直至事务结束，须在事务内调用；不存在时返回 nil, nil
+	GetForUpdate(ctx context.Context, subUserID string) (*SubAccount, error)
+	ListByMaster(ctx context.Context, masterUserID string) ([]*SubAccount, error)
+
+	SaveTransfer(ctx context.Context, t *InternalTransfer) error
+	// SumTransfersOut 统计用户自 since 起某币种的手工划出金额
+	SumTransfersOut(ctx context.Context, fromUserID, currency string, since time.Time) (decimal.Decimal, error)
+	ListTransfers(ctx context.Context, masterUserID string, limit, offset int) ([]*InternalTransfer, error)
+}
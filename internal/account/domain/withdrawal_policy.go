@@ -0,0 +1,268 @@
+// 变更说明：提现风控策略：按币种配置自动放行额度、大额多人复核（maker-checker）、
+// 用户维度 24 小时滚动的金额/笔数限制、新收款账户冷静期与审核 SLA，出款前经 AML 筛查。
+// 假设：收款账户以用户 + 银行账号识别，首次出现即视为新收款账户；提交人即 maker，不得审批自己的提现；
+// 被拒绝或失败的提现不计入限额。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// WithdrawalReviewOverdueEventType 提现审核超出 SLA 事件主题
+const WithdrawalReviewOverdueEventType = "account.withdrawal_review_overdue"
+
+// VelocityWindow 提现限额的滚动统计窗口
+const VelocityWindow = 24 * time.Hour
+
+var (
+	// ErrWithdrawalVelocityExceeded 超出提现金额或笔数限制
+	ErrWithdrawalVelocityExceeded = errors.New("withdrawal velocity limit exceeded")
+	// ErrWithdrawalCoolingOff 新收款账户仍在冷静期内
+	ErrWithdrawalCoolingOff = errors.New("beneficiary is in cooling-off period")
+	// ErrWithdrawalAMLHold 提现被 AML 筛查挂起，待合规复核
+	ErrWithdrawalAMLHold = errors.New("withdrawal held by AML screening")
+	// ErrApproverNotAllowed 审批人无权审批
+	ErrApproverNotAllowed = errors.New("approver is not allowed to review this withdrawal")
+	// ErrDuplicateApproval 同一审批人重复审批
+	ErrDuplicateApproval = errors.New("approver has already approved this withdrawal")
+)
+
+// WithdrawalPolicy 单一币种的提现策略，金额类阈值为 0 表示不限制
+type WithdrawalPolicy struct {
+	// AutoApproveLimit 不超过该金额且无其他风险因素时自动审核通过；为 0 时全部人工审核
+	AutoApproveLimit decimal.Decimal `json:"auto_approve_limit"`
+	// LargeAmountThreshold 达到该金额需 LargeAmountApprovers 名不同审批人复核
+	LargeAmountThreshold decimal.Decimal `json:"large_amount_threshold"`
+	LargeAmountApprovers int             `json:"large_amount_approvers"`
+	// DailyAmountLimit/DailyCountLimit 用户在该币种 24 小时滚动窗口内的提现金额与笔数上限
+	DailyAmountLimit decimal.Decimal `json:"daily_amount_limit"`
+	DailyCountLimit  int             `json:"daily_count_limit"`
+	// BeneficiaryCoolingOffHours 新收款账户首次使用后的冷静期，期内需人工审核且不出款
+	BeneficiaryCoolingOffHours int `json:"beneficiary_cooling_off_hours"`
+	// ReviewSLAMinutes 人工审核时限，超时升级
+	ReviewSLAMinutes int `json:"review_sla_minutes"`
+}
+
+// WithdrawalPolicySet 提现策略集合：按币种覆盖默认策略，并限定审批人
+type WithdrawalPolicySet struct {
+	Default    WithdrawalPolicy            `json:"default"`
+	Currencies map[string]WithdrawalPolicy `json:"currencies"`
+	// Approvers 可审批提现的人员，为空时除提交人外均可审批
+	Approvers []string `json:"approvers"`
+	// ComplianceReviewers 可复核 AML 挂起的人员，为空时沿用 Approvers
+	ComplianceReviewers []string `json:"compliance_reviewers"`
+}
+
+// For 返回币种适用的策略
+func (s *WithdrawalPolicySet) For(currency string) WithdrawalPolicy {
+	if p, ok := s.Currencies[strings.ToUpper(currency)]; ok {
+		return p
+	}
+	return s.Default
+}
+
+// CanApprove 是否可作为审批人
+func (s *WithdrawalPolicySet) CanApprove(approver string) bool {
+	return len(s.Approvers) == 0 || slices.Contains(s.Approvers, approver)
+}
+
+// CanReviewAML 是否可复核 AML 挂起
+func (s *WithdrawalPolicySet) CanReviewAML(reviewer string) bool {
+	if len(s.ComplianceReviewers) == 0 {
+		return s.CanApprove(reviewer)
+	}
+	return slices.Contains(s.ComplianceReviewers, reviewer)
+}
+
+// WithdrawalUsage 用户在统计窗口内已发起的提现
+type WithdrawalUsage struct {
+	Amount decimal.Decimal
+	Count  int
+}
+
+// CheckVelocity 校验加上本笔后是否超出限额
+func (p WithdrawalPolicy) CheckVelocity(usage WithdrawalUsage, amount decimal.Decimal) error {
+	if p.DailyCountLimit > 0 && usage.Count+1 > p.DailyCountLimit {
+		return fmt.Errorf("%w: %d withdrawals in %s, limit %d", ErrWithdrawalVelocityExceeded, usage.Count+1, VelocityWindow, p.DailyCountLimit)
+	}
+	if p.DailyAmountLimit.IsPositive() && usage.Amount.Add(amount).GreaterThan(p.DailyAmountLimit) {
+		return fmt.Errorf("%w: %s in %s, limit %s", ErrWithdrawalVelocityExceeded, usage.Amount.Add(amount), VelocityWindow, p.DailyAmountLimit)
+	}
+	return nil
+}
+
+// WithdrawalReview 策略评估得出的审核要求
+type WithdrawalReview struct {
+	RequiredApprovals int
+	Reasons           []string
+	// EarliestPayoutAt 冷静期结束前不得出款
+	EarliestPayoutAt *time.Time
+	DueAt            *time.Time
+}
+
+// Review 评估本笔提现的审核要求；firstUse 为收款账户首次使用时间，nil 表示首次使用
+func (p WithdrawalPolicy) Review(amount decimal.Decimal, firstUse *time.Time, now time.Time) WithdrawalReview {
+	var r WithdrawalReview
+	switch {
+	case p.LargeAmountThreshold.IsPositive() && amount.GreaterThanOrEqual(p.LargeAmountThreshold):
+		r.RequiredApprovals = max(p.LargeAmountApprovers, 2)
+		r.Reasons = append(r.Reasons, fmt.Sprintf("amount %s at or above large amount threshold %s", amount, p.LargeAmountThreshold))
+	case amount.GreaterThan(p.AutoApproveLimit):
+		r.RequiredApprovals = 1
+		r.Reasons = append(r.Reasons, fmt.Sprintf("amount %s above auto-approve limit %s", amount, p.AutoApproveLimit))
+	}
+
+	if p.BeneficiaryCoolingOffHours > 0 {
+		since := now
+		if firstUse != nil {
+			since = *firstUse
+		}
+		release := since.Add(time.Duration(p.BeneficiaryCoolingOffHours) * time.Hour)
+		if now.Before(release) {
+			r.EarliestPayoutAt = &release
+			r.RequiredApprovals = max(r.RequiredApprovals, 1)
+			r.Reasons = append(r.Reasons, fmt.Sprintf("new beneficiary in cooling-off until %s", release.Format(time.RFC3339)))
+		}
+	}
+
+	if r.RequiredApprovals > 0 && p.ReviewSLAMinutes > 0 {
+		due := now.Add(time.Duration(p.ReviewSLAMinutes) * time.Minute)
+		r.DueAt = &due
+	}
+	return r
+}
+
+// ReviewSLA 审核时限
+func (p WithdrawalPolicy) ReviewSLA() time.Duration {
+	return time.Duration(p.ReviewSLAMinutes) * time.Minute
+}
+
+// ApprovalStage 审批环节
+type ApprovalStage string
+
+const (
+	ApprovalStageReview ApprovalStage = "REVIEW"
+	ApprovalStageAML    ApprovalStage = "AML"
+)
+
+// WithdrawalApproval 一条审批记录
+type WithdrawalApproval struct {
+	Approver string        `json:"approver"`
+	Stage    ApprovalStage `json:"stage"`
+	Approved bool          `json:"approved"`
+	Remark   string        `json:"remark"`
+	At       time.Time     `json:"at"`
+}
+
+// AMLStatus 出款前 AML 筛查状态
+type AMLStatus string
+
+const (
+	AMLStatusClear AMLStatus = "CLEAR"
+	AMLStatusHold  AMLStatus = "HOLD"
+)
+
+// WithdrawalScreening AML 筛查结果
+type WithdrawalScreening struct {
+	Hold      bool
+	RiskLevel string
+	Reference string
+	Reasons   []string
+}
+
+// WithdrawalScreener 出款前 AML 筛查
+type WithdrawalScreener interface {
+	ScreenWithdrawal(ctx context.Context, w *WithdrawalOrder) (*WithdrawalScreening, error)
+}
+
+// ApplyReview 记录策略评估结果
+func (w *WithdrawalOrder) ApplyReview(r WithdrawalReview) {
+	w.RequiredApprovals = r.RequiredApprovals
+	w.ReviewReasons = r.Reasons
+	w.EarliestPayoutAt = r.EarliestPayoutAt
+	w.ReviewDueAt = r.DueAt
+}
+
+// RecordApproval 记录一名审批人的同意意见，达到所需人数时审核通过并返回 true。
+// 提交人不得审批自己的提现，同一审批人只计一次。
+func (w *WithdrawalOrder) RecordApproval(ctx context.Context, approver, remark string, at time.Time) (bool, error) {
+	if w.Status != WithdrawalStatusAuditing {
+		return false, fmt.Errorf("withdrawal %s is %s, not under review", w.WithdrawalNo, w.Status)
+	}
+	if approver == "" || approver == w.UserID {
+		return false, fmt.Errorf("%w: maker cannot approve own withdrawal", ErrApproverNotAllowed)
+	}
+	for _, a := range w.Approvals {
+		if a.Stage == ApprovalStageReview && a.Approver == approver {
+			return false, ErrDuplicateApproval
+		}
+	}
+	w.Approvals = append(w.Approvals, WithdrawalApproval{Approver: approver, Stage: ApprovalStageReview, Approved: true, Remark: remark, At: at})
+	if w.ApprovalsRemaining() > 0 {
+		return false, nil
+	}
+	approvers := make([]string, 0, len(w.Approvals))
+	for _, a := range w.Approvals {
+		if a.Stage == ApprovalStageReview {
+			approvers = append(approvers, a.Approver)
+		}
+	}
+	return true, w.Approve(ctx, strings.Join(approvers, ","), remark)
+}
+
+// ApprovalsRemaining 还需的审批人数，至少需要一人
+func (w *WithdrawalOrder) ApprovalsRemaining() int {
+	got := 0
+	for _, a := range w.Approvals {
+		if a.Stage == ApprovalStageReview && a.Approved {
+			got++
+		}
+	}
+	return max(max(w.RequiredApprovals, 1)-got, 0)
+}
+
+// HoldForAML AML 筛查命中，挂起待合规复核
+func (w *WithdrawalOrder) HoldForAML(s *WithdrawalScreening, dueAt *time.Time) {
+	w.AMLStatus = AMLStatusHold
+	w.AMLReference = s.Reference
+	w.ReviewReasons = append(w.ReviewReasons, fmt.Sprintf("AML hold (%s): %s", s.RiskLevel, strings.Join(s.Reasons, "; ")))
+	w.ReviewDueAt = dueAt
+	w.ReviewEscalated = false
+}
+
+// ClearAML 筛查通过或合规复核放行
+func (w *WithdrawalOrder) ClearAML(reviewer, remark string, at time.Time) {
+	if reviewer != "" {
+		w.Approvals = append(w.Approvals, WithdrawalApproval{Approver: reviewer, Stage: ApprovalStageAML, Approved: true, Remark: remark, At: at})
+	}
+	w.AMLStatus = AMLStatusClear
+	w.ReviewDueAt = nil
+}
+
+// CheckReleasable 校验冷静期与 AML 挂起，可出款时返回 nil
+func (w *WithdrawalOrder) CheckReleasable(now time.Time) error {
+	if w.AMLStatus == AMLStatusHold {
+		return ErrWithdrawalAMLHold
+	}
+	if w.EarliestPayoutAt != nil && now.Before(*w.EarliestPayoutAt) {
+		return fmt.Errorf("%w: payout allowed after %s", ErrWithdrawalCoolingOff, w.EarliestPayoutAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// AwaitingReview 是否在审批队列中：人工审核中或 AML 挂起
+func (w *WithdrawalOrder) AwaitingReview() bool {
+	return w.Status == WithdrawalStatusAuditing || (w.Status == WithdrawalStatusApproved && w.AMLStatus == AMLStatusHold)
+}
+
+// ReviewOverdue 是否已超出审核 SLA
+func (w *WithdrawalOrder) ReviewOverdue(now time.Time) bool {
+	return w.AwaitingReview() && w.ReviewDueAt != nil && now.After(*w.ReviewDueAt)
+}
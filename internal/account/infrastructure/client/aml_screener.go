@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+
+	amlv1 "github.com/wyfcoding/financialtrading/go-api/aml/v1"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"google.golang.org/grpc"
+)
+
+// AMLWithdrawalScreener 出款前经 AML 服务监控交易：判定可疑或风险等级达到 holdLevel 时挂起
+type AMLWithdrawalScreener struct {
+	client    amlv1.AMLServiceClient
+	holdLevel amlv1.RiskLevel
+}
+
+// NewAMLWithdrawalScreener 创建 AML 提现筛查，风险等级达到 HIGH 即挂起
+func NewAMLWithdrawalScreener(conn grpc.ClientConnInterface) *AMLWithdrawalScreener {
+	return &AMLWithdrawalScreener{client: amlv1.NewAMLServiceClient(conn), holdLevel: amlv1.RiskLevel_RISK_LEVEL_HIGH}
+}
+
+func (s *AMLWithdrawalScreener) ScreenWithdrawal(ctx context.Context, w *domain.WithdrawalOrder) (*domain.WithdrawalScreening, error) {
+	resp, err := s.client.MonitorTransaction(ctx, &amlv1.MonitorTransactionRequest{
+		TransactionId:   w.WithdrawalNo,
+		UserId:          w.UserID,
+		Amount:          w.Amount.String(),
+		Currency:        w.Currency,
+		CounterpartyId:  w.BankAccountNo,
+		TransactionType: "WITHDRAWAL",
+		Metadata: map[string]string{
+			"account_id":  w.AccountID,
+			"bank_name":   w.BankName,
+			"bank_holder": w.BankHolder,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.WithdrawalScreening{
+		Hold:      resp.IsSuspicious || resp.RiskLevel >= s.holdLevel,
+		RiskLevel: resp.RiskLevel.String(),
+		Reference: resp.AlertId,
+		Reasons:   resp.TriggeredRules,
+	}
+	if result.Hold && len(result.Reasons) == 0 {
+		result.Reasons = []string{"flagged by transaction monitoring"}
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// CorporateActionActivitySource 通过公司行动服务的 HTTP 接口读取已支付权益：现金分红计为流入，送股/拆股只记数量
+type CorporateActionActivitySource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCorporateActionActivitySource 创建公司行动活动来源，baseURL 形如 http://host:port/api
+func NewCorporateActionActivitySource(baseURL string, timeout time.Duration) *CorporateActionActivitySource {
+	return &CorporateActionActivitySource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *CorporateActionActivitySource) Name() string { return "corporateaction" }
+
+type entitlementView struct {
+	EntitlementID string          `json:"EntitlementID"`
+	PayoutCash    decimal.Decimal `json:"PayoutCash"`
+	PayoutStock   decimal.Decimal `json:"PayoutStock"`
+	StockSymbol   string          `json:"StockSymbol"`
+	Status        string          `json:"Status"`
+	EventID       string          `json:"event_id"`
+	Symbol        string          `json:"symbol"`
+	ActionType    string          `json:"action_type"`
+	Currency      string          `json:"currency"`
+	PaymentDate   time.Time       `json:"payment_date"`
+}
+
+func (s *CorporateActionActivitySource) ListActivity(ctx context.Context, userID string, from, to time.Time) ([]domain.StatementActivity, error) {
+	q := url.Values{}
+	q.Set("account_id", userID)
+	q.Set("from", from.Format(time.RFC3339))
+	q.Set("to", to.Format(time.RFC3339))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/corporate-actions/entitlements?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("corporate action service returned %s", resp.Status)
+	}
+	var body struct {
+		Entitlements []entitlementView `json:"entitlements"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode entitlements: %w", err)
+	}
+
+	var list []domain.StatementActivity
+	for _, e := range body.Entitlements {
+		if e.Status != "PAID" {
+			continue
+		}
+		a := domain.StatementActivity{
+			Time:        e.PaymentDate,
+			Category:    domain.ActivityCorporateAction,
+			Currency:    e.Currency,
+			Amount:      e.PayoutCash,
+			Reference:   e.EntitlementID,
+			Description: fmt.Sprintf("%s on %s (%s)", e.ActionType, e.Symbol, e.EventID),
+		}
+		if e.PayoutStock.IsPositive() {
+			a.Symbol = e.StockSymbol
+			a.Quantity = e.PayoutStock
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
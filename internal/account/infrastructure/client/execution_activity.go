@@ -0,0 +1,79 @@
+// Package client 账户服务依赖的下游服务客户端
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	executionv1 "github.com/wyfcoding/financialtrading/go-api/execution/v1"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"google.golang.org/grpc"
+)
+
+// statementHistoryLimit 单份对账单最多拉取的成交笔数
+const statementHistoryLimit = 5000
+
+// ExecutionActivitySource 从执行服务读取成交，按计价币种计算现金影响：买入为流出、卖出为流入
+type ExecutionActivitySource struct {
+	client          executionv1.ExecutionServiceClient
+	defaultCurrency string
+}
+
+// NewExecutionActivitySource 创建成交活动来源；无法从标的解析计价币种时使用 defaultCurrency
+func NewExecutionActivitySource(conn grpc.ClientConnInterface, defaultCurrency string) *ExecutionActivitySource {
+	return &ExecutionActivitySource{client: executionv1.NewExecutionServiceClient(conn), defaultCurrency: defaultCurrency}
+}
+
+func (s *ExecutionActivitySource) Name() string { return "execution" }
+
+func (s *ExecutionActivitySource) ListActivity(ctx context.Context, userID string, from, to time.Time) ([]domain.StatementActivity, error) {
+	resp, err := s.client.GetExecutionHistory(ctx, &executionv1.GetExecutionHistoryRequest{
+		UserId:    userID,
+		Limit:     statementHistoryLimit,
+		StartTime: from.Unix(),
+		EndTime:   to.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]domain.StatementActivity, 0, len(resp.Executions))
+	for _, e := range resp.Executions {
+		qty, err := decimal.NewFromString(e.ExecutedQuantity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity of execution %s: %w", e.ExecutionId, err)
+		}
+		price, err := decimal.NewFromString(e.ExecutedPrice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price of execution %s: %w", e.ExecutionId, err)
+		}
+		cash := qty.Mul(price)
+		if strings.EqualFold(e.Side, "BUY") {
+			cash = cash.Neg()
+			qty = qty.Abs()
+		} else {
+			qty = qty.Neg()
+		}
+		list = append(list, domain.StatementActivity{
+			Time:        time.Unix(e.Timestamp, 0),
+			Category:    domain.ActivityTrade,
+			Currency:    s.quoteCurrency(e.Symbol),
+			Amount:      cash,
+			Symbol:      e.Symbol,
+			Quantity:    qty,
+			Reference:   e.ExecutionId,
+			Description: fmt.Sprintf("%s %s @ %s (order %s)", strings.ToUpper(e.Side), e.ExecutedQuantity, e.ExecutedPrice, e.OrderId),
+		})
+	}
+	return list, nil
+}
+
+// quoteCurrency 从 BTC-USDT、EUR/USD 等标的解析计价币种
+func (s *ExecutionActivitySource) quoteCurrency(symbol string) string {
+	if i := strings.LastIndexAny(symbol, "-/"); i >= 0 && i < len(symbol)-1 {
+		return strings.ToUpper(symbol[i+1:])
+	}
+	return s.defaultCurrency
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	feemanagementv1 "github.com/wyfcoding/financialtrading/go-api/feemanagement/v1"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FeeActivitySource 从手续费服务读取交易手续费，计为现金流出
+type FeeActivitySource struct {
+	client feemanagementv1.FeeManagementServiceClient
+}
+
+// NewFeeActivitySource 创建手续费活动来源
+func NewFeeActivitySource(conn grpc.ClientConnInterface) *FeeActivitySource {
+	return &FeeActivitySource{client: feemanagementv1.NewFeeManagementServiceClient(conn)}
+}
+
+func (s *FeeActivitySource) Name() string { return "feemanagement" }
+
+func (s *FeeActivitySource) ListActivity(ctx context.Context, userID string, from, to time.Time) ([]domain.StatementActivity, error) {
+	resp, err := s.client.ListTradeFees(ctx, &feemanagementv1.ListTradeFeesRequest{
+		UserId:    userID,
+		StartTime: timestamppb.New(from),
+		EndTime:   timestamppb.New(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]domain.StatementActivity, 0, len(resp.Fees))
+	for _, f := range resp.Fees {
+		if f.TotalFee == 0 {
+			continue
+		}
+		list = append(list, domain.StatementActivity{
+			Time:        f.CalculatedAt.AsTime(),
+			Category:    domain.ActivityFee,
+			Currency:    f.Currency,
+			Amount:      decimal.NewFromFloat(f.TotalFee).Neg(),
+			Reference:   f.TradeId,
+			Description: "Trading fee (order " + f.OrderId + ")",
+		})
+	}
+	return list, nil
+}
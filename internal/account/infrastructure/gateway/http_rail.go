@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// HTTPRail 通用 HTTP/JSON 支付通道适配器：
+//
+//	POST {base}/v1/deposits   发起充值，返回支付链接
+//	POST {base}/v1/payouts    发起出款，Idempotency-Key 为提现单号
+//	GET  {base}/v1/statements 拉取 [from, to) 已结算流水
+//
+// 对外请求与回调均使用同一共享密钥签名。
+type HTTPRail struct {
+	gatewayType domain.GatewayType
+	baseURL     string
+	signer      *Signer
+	client      *http.Client
+}
+
+// NewHTTPRail 创建 HTTP 通道适配器
+func NewHTTPRail(gatewayType domain.GatewayType, baseURL, secret string, timeout time.Duration) *HTTPRail {
+	return &HTTPRail{
+		gatewayType: gatewayType,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		signer:      NewSigner(secret, DefaultSignatureTolerance),
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+func (r *HTTPRail) Type() domain.GatewayType { return r.gatewayType }
+
+func (r *HTTPRail) CreateDepositPayment(ctx context.Context, req *domain.DepositGatewayRequest) (string, error) {
+	if req == nil || strings.TrimSpace(req.DepositNo) == "" {
+		return "", errors.New("deposit_no is required")
+	}
+	var resp depositPaymentResponse
+	err := r.do(ctx, http.MethodPost, "/v1/deposits", nil, &depositPaymentRequest{
+		Reference: req.DepositNo,
+		UserID:    req.UserID,
+		AccountID: req.AccountID,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.PaymentURL, nil
+}
+
+func (r *HTTPRail) Payout(ctx context.Context, req *domain.WithdrawalGatewayRequest) (string, error) {
+	if req == nil || strings.TrimSpace(req.WithdrawalNo) == "" {
+		return "", errors.New("withdrawal_no is required")
+	}
+	var resp payoutResponse
+	headers := map[string]string{HeaderIdempotencyKey: req.WithdrawalNo}
+	err := r.do(ctx, http.MethodPost, "/v1/payouts", headers, &payoutRequest{
+		Reference:     req.WithdrawalNo,
+		UserID:        req.UserID,
+		Amount:        req.NetAmount,
+		Currency:      req.Currency,
+		BankAccountNo: req.BankAccountNo,
+		BankName:      req.BankName,
+		BankHolder:    req.BankHolder,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.PayoutID == "" {
+		return "", fmt.Errorf("%s rail returned empty payout id", r.gatewayType)
+	}
+	return resp.PayoutID, nil
+}
+
+func (r *HTTPRail) ParseWebhook(signature, timestamp string, body []byte) (*domain.GatewayNotification, error) {
+	if err := r.signer.Verify(signature, timestamp, body); err != nil {
+		return nil, err
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook: %w", err)
+	}
+	if p.EventID == "" || p.Reference == "" {
+		return nil, errors.New("webhook missing event_id or reference")
+	}
+	return &domain.GatewayNotification{
+		EventID:       p.EventID,
+		Type:          domain.GatewayEventType(p.Type),
+		DepositNo:     p.Reference,
+		TransactionID: p.TransactionID,
+		ThirdPartyNo:  p.ProviderRef,
+		Amount:        p.Amount,
+		Currency:      p.Currency,
+		Reason:        p.Reason,
+		OccurredAt:    p.OccurredAt,
+	}, nil
+}
+
+func (r *HTTPRail) FetchStatement(ctx context.Context, from, to time.Time) ([]domain.GatewayStatementLine, error) {
+	q := url.Values{}
+	q.Set("from", from.UTC().Format(time.RFC3339))
+	q.Set("to", to.UTC().Format(time.RFC3339))
+	var resp statementResponse
+	if err := r.do(ctx, http.MethodGet, "/v1/statements?"+q.Encode(), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	lines := make([]domain.GatewayStatementLine, 0, len(resp.Lines))
+	for _, l := range resp.Lines {
+		lines = append(lines, domain.GatewayStatementLine{
+			Kind:        domain.GatewayStatementKind(l.Kind),
+			Reference:   l.Reference,
+			ProviderRef: l.ProviderRef,
+			Amount:      l.Amount,
+			Currency:    l.Currency,
+			SettledAt:   l.SettledAt,
+		})
+	}
+	return lines, nil
+}
+
+// do 发送签名请求并解码 JSON 响应，非 2xx 视为失败
+func (r *HTTPRail) do(ctx context.Context, method, path string, headers map[string]string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signature, timestamp := r.signer.Sign(body)
+	req.Header.Set(HeaderSignature, signature)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s rail request failed: %w", r.gatewayType, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s rail returned %s: %s", r.gatewayType, resp.Status, e.Error)
+		}
+		return fmt.Errorf("%s rail returned %s", r.gatewayType, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s rail response: %w", r.gatewayType, err)
+	}
+	return nil
+}
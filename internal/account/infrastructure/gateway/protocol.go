@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 通道协议报文：HTTPRail 与 StubProvider 共用
+
+type depositPaymentRequest struct {
+	Reference string          `json:"reference"`
+	UserID    string          `json:"user_id"`
+	AccountID string          `json:"account_id"`
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency"`
+}
+
+type depositPaymentResponse struct {
+	PaymentURL string `json:"payment_url"`
+}
+
+type payoutRequest struct {
+	Reference     string          `json:"reference"`
+	UserID        string          `json:"user_id"`
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency"`
+	BankAccountNo string          `json:"bank_account_no"`
+	BankName      string          `json:"bank_name"`
+	BankHolder    string          `json:"bank_holder"`
+}
+
+type payoutResponse struct {
+	PayoutID string `json:"payout_id"`
+	Status   string `json:"status"`
+}
+
+// webhookPayload 通道异步通知报文
+type webhookPayload struct {
+	EventID       string          `json:"event_id"`
+	Type          string          `json:"type"`
+	Reference     string          `json:"reference"`
+	TransactionID string          `json:"transaction_id"`
+	ProviderRef   string          `json:"provider_ref"`
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency"`
+	Reason        string          `json:"reason"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+}
+
+type statementLine struct {
+	Kind        string          `json:"kind"`
+	Reference   string          `json:"reference"`
+	ProviderRef string          `json:"provider_ref"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	SettledAt   time.Time       `json:"settled_at"`
+}
+
+type statementResponse struct {
+	Lines []statementLine `json:"lines"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
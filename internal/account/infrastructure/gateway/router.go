@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// Router 按网关类型分发充值、固定通道出款的通道集合，实现 domain.PaymentRails
+type Router struct {
+	rails  map[domain.GatewayType]domain.PaymentRail
+	payout domain.PaymentRail
+}
+
+// NewRouter 创建通道路由，payoutType 为空时取第一个通道出款
+func NewRouter(payoutType domain.GatewayType, rails ...domain.PaymentRail) (*Router, error) {
+	if len(rails) == 0 {
+		return nil, fmt.Errorf("%w: no rails configured", domain.ErrUnknownPaymentRail)
+	}
+	r := &Router{rails: make(map[domain.GatewayType]domain.PaymentRail, len(rails))}
+	for _, rail := range rails {
+		if _, dup := r.rails[rail.Type()]; dup {
+			return nil, fmt.Errorf("duplicate payment rail %s", rail.Type())
+		}
+		r.rails[rail.Type()] = rail
+	}
+	if payoutType == "" {
+		payoutType = rails[0].Type()
+	}
+	payout, ok := r.rails[payoutType]
+	if !ok {
+		return nil, fmt.Errorf("%w: payout rail %s", domain.ErrUnknownPaymentRail, payoutType)
+	}
+	r.payout = payout
+	return r, nil
+}
+
+func (r *Router) Rail(gatewayType domain.GatewayType) (domain.PaymentRail, bool) {
+	rail, ok := r.rails[gatewayType]
+	return rail, ok
+}
+
+func (r *Router) PayoutRail() domain.PaymentRail { return r.payout }
+
+// List 按网关类型排序返回全部通道
+func (r *Router) List() []domain.PaymentRail {
+	list := make([]domain.PaymentRail, 0, len(r.rails))
+	for _, rail := range r.rails {
+		list = append(list, rail)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type() < list[j].Type() })
+	return list
+}
+
+func (r *Router) CreateDepositPayment(ctx context.Context, req *domain.DepositGatewayRequest) (string, error) {
+	rail, ok := r.rails[req.GatewayType]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownPaymentRail, req.GatewayType)
+	}
+	return rail.CreateDepositPayment(ctx, req)
+}
+
+func (r *Router) Payout(ctx context.Context, req *domain.WithdrawalGatewayRequest) (string, error) {
+	return r.payout.Payout(ctx, req)
+}
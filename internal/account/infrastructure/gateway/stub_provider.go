@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StubProvider 本地桩支付通道：实现 HTTPRail 所用协议的内存版服务端，供联调与测试。
+// 充值在结算后（手动触发或 autoSettle 延迟后自动）向回调地址推送签名通知；
+// 出款按幂等键去重，同一键的不同请求体返回 409。
+type StubProvider struct {
+	signer     *Signer
+	webhookURL string
+	autoSettle time.Duration
+	client     *http.Client
+	logger     *slog.Logger
+	mux        *http.ServeMux
+
+	mu       sync.Mutex
+	seq      int64
+	deposits map[string]*stubDeposit
+	payouts  map[string]*stubPayout
+}
+
+type stubDeposit struct {
+	req         depositPaymentRequest
+	providerRef string
+	status      string
+	settledAt   time.Time
+}
+
+type stubPayout struct {
+	req       payoutRequest
+	body      []byte
+	payoutID  string
+	settledAt time.Time
+}
+
+// NewStubProvider 创建桩通道，webhookURL 为账户服务的回调地址，autoSettle 为 0 时只能手动结算
+func NewStubProvider(secret, webhookURL string, autoSettle time.Duration, logger *slog.Logger) *StubProvider {
+	p := &StubProvider{
+		signer:     NewSigner(secret, DefaultSignatureTolerance),
+		webhookURL: webhookURL,
+		autoSettle: autoSettle,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		logger:     logger,
+		mux:        http.NewServeMux(),
+		deposits:   make(map[string]*stubDeposit),
+		payouts:    make(map[string]*stubPayout),
+	}
+	p.mux.HandleFunc("POST /v1/deposits", p.createDeposit)
+	p.mux.HandleFunc("POST /v1/payouts", p.createPayout)
+	p.mux.HandleFunc("GET /v1/statements", p.statement)
+	p.mux.HandleFunc("POST /v1/stub/deposits/{reference}/settle", p.settleDeposit)
+	p.mux.HandleFunc("POST /v1/stub/deposits/{reference}/fail", p.failDeposit)
+	return p
+}
+
+func (p *StubProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// Settle 结算充值并推送成功通知
+func (p *StubProvider) Settle(ctx context.Context, reference string) error {
+	p.mu.Lock()
+	d, ok := p.deposits[reference]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("deposit %s not found", reference)
+	}
+	switch d.status {
+	case "FAILED":
+		p.mu.Unlock()
+		return fmt.Errorf("deposit %s is FAILED", reference)
+	case "PENDING":
+		d.status = "SETTLED"
+		d.settledAt = time.Now().UTC()
+	}
+	// 已结算的充值重复结算时重发通知，模拟通道重推
+	payload := p.depositEvent(d, "DEPOSIT_SUCCEEDED", "")
+	p.mu.Unlock()
+	return p.deliver(ctx, payload)
+}
+
+// Fail 标记充值失败并推送失败通知
+func (p *StubProvider) Fail(ctx context.Context, reference, reason string) error {
+	p.mu.Lock()
+	d, ok := p.deposits[reference]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("deposit %s not found", reference)
+	}
+	if d.status != "PENDING" {
+		p.mu.Unlock()
+		return fmt.Errorf("deposit %s is %s", reference, d.status)
+	}
+	d.status = "FAILED"
+	payload := p.depositEvent(d, "DEPOSIT_FAILED", reason)
+	p.mu.Unlock()
+	return p.deliver(ctx, payload)
+}
+
+func (p *StubProvider) createDeposit(w http.ResponseWriter, r *http.Request) {
+	body, ok := p.readSigned(w, r)
+	if !ok {
+		return
+	}
+	var req depositPaymentRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Reference == "" || !req.Amount.IsPositive() {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid deposit request"})
+		return
+	}
+
+	p.mu.Lock()
+	if _, exists := p.deposits[req.Reference]; !exists {
+		p.seq++
+		p.deposits[req.Reference] = &stubDeposit{req: req, providerRef: fmt.Sprintf("STUB-D%06d", p.seq), status: "PENDING"}
+	}
+	p.mu.Unlock()
+
+	if p.autoSettle > 0 {
+		ref := req.Reference
+		time.AfterFunc(p.autoSettle, func() {
+			if err := p.Settle(context.Background(), ref); err != nil {
+				p.logger.Warn("stub auto settle failed", "reference", ref, "error", err)
+			}
+		})
+	}
+	writeJSON(w, http.StatusOK, depositPaymentResponse{
+		PaymentURL: fmt.Sprintf("http://%s/v1/stub/deposits/%s/settle", r.Host, req.Reference),
+	})
+}
+
+func (p *StubProvider) createPayout(w http.ResponseWriter, r *http.Request) {
+	body, ok := p.readSigned(w, r)
+	if !ok {
+		return
+	}
+	key := r.Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "Idempotency-Key header is required"})
+		return
+	}
+	var req payoutRequest
+	if err := json.Unmarshal(body, &req); err != nil || !req.Amount.IsPositive() {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid payout request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.payouts[key]; ok {
+		if !bytes.Equal(existing.body, body) {
+			writeJSON(w, http.StatusConflict, errorResponse{Error: "idempotency key reused with different request"})
+			return
+		}
+		writeJSON(w, http.StatusOK, payoutResponse{PayoutID: existing.payoutID, Status: "SETTLED"})
+		return
+	}
+	p.seq++
+	payout := &stubPayout{req: req, body: body, payoutID: fmt.Sprintf("STUB-P%06d", p.seq), settledAt: time.Now().UTC()}
+	p.payouts[key] = payout
+	p.logger.Info("stub payout settled", "reference", req.Reference, "payout_id", payout.payoutID, "amount", req.Amount)
+	writeJSON(w, http.StatusOK, payoutResponse{PayoutID: payout.payoutID, Status: "SETTLED"})
+}
+
+func (p *StubProvider) statement(w http.ResponseWriter, r *http.Request) {
+	if _, ok := p.readSigned(w, r); !ok {
+		return
+	}
+	from, err1 := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	to, err2 := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "from and to must be RFC3339"})
+		return
+	}
+	in := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+
+	p.mu.Lock()
+	lines := make([]statementLine, 0)
+	for _, d := range p.deposits {
+		if d.status == "SETTLED" && in(d.settledAt) {
+			lines = append(lines, statementLine{Kind: "DEPOSIT", Reference: d.req.Reference, ProviderRef: d.providerRef,
+				Amount: d.req.Amount, Currency: d.req.Currency, SettledAt: d.settledAt})
+		}
+	}
+	for _, po := range p.payouts {
+		if in(po.settledAt) {
+			lines = append(lines, statementLine{Kind: "PAYOUT", Reference: po.req.Reference, ProviderRef: po.payoutID,
+				Amount: po.req.Amount, Currency: po.req.Currency, SettledAt: po.settledAt})
+		}
+	}
+	p.mu.Unlock()
+	sort.Slice(lines, func(i, j int) bool { return lines[i].SettledAt.Before(lines[j].SettledAt) })
+	writeJSON(w, http.StatusOK, statementResponse{Lines: lines})
+}
+
+func (p *StubProvider) settleDeposit(w http.ResponseWriter, r *http.Request) {
+	if err := p.Settle(r.Context(), r.PathValue("reference")); err != nil {
+		writeJSON(w, http.StatusBadGateway, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "SETTLED"})
+}
+
+func (p *StubProvider) failDeposit(w http.ResponseWriter, r *http.Request) {
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "declined by stub provider"
+	}
+	if err := p.Fail(r.Context(), r.PathValue("reference"), reason); err != nil {
+		writeJSON(w, http.StatusBadGateway, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "FAILED"})
+}
+
+// depositEvent 构造充值通知，调用方需持有锁
+func (p *StubProvider) depositEvent(d *stubDeposit, eventType, reason string) webhookPayload {
+	p.seq++
+	return webhookPayload{
+		EventID:       fmt.Sprintf("STUB-E%06d", p.seq),
+		Type:          eventType,
+		Reference:     d.req.Reference,
+		TransactionID: d.providerRef,
+		ProviderRef:   d.providerRef,
+		Amount:        d.req.Amount,
+		Currency:      d.req.Currency,
+		Reason:        reason,
+		OccurredAt:    time.Now().UTC(),
+	}
+}
+
+// deliver 推送签名通知，非 2xx 时按指数退避重试
+func (p *StubProvider) deliver(ctx context.Context, payload webhookPayload) error {
+	if p.webhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	backoff := 200 * time.Millisecond
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		signature, timestamp := p.signer.Sign(body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderSignature, signature)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		resp, err := p.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			p.logger.Info("stub webhook delivered", "event_id", payload.EventID, "type", payload.Type, "reference", payload.Reference)
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return fmt.Errorf("failed to deliver webhook %s: %w", payload.EventID, lastErr)
+}
+
+// readSigned 读取请求体并校验调用方签名
+func (p *StubProvider) readSigned(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return nil, false
+	}
+	if err := p.signer.Verify(r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body); err != nil {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+		return nil, false
+	}
+	return body, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
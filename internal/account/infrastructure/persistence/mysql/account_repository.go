@@ -68,9 +68,14 @@ func (r *accountRepository) Save(ctx context.Context, account *domain.Account) e
 		return nil
 	}
 
+	// 乐观锁以加载时的版本为准，每个未提交事件使版本加一
 	currentVersion := account.Version()
+	loadedVersion := currentVersion - int64(len(account.GetUncommittedEvents()))
+	if loadedVersion == currentVersion {
+		currentVersion++
+	}
 	result := db.WithContext(ctx).Model(&AccountModel{}).
-		Where("account_id = ? AND version = ?", account.AccountID, currentVersion).
+		Where("account_id = ? AND version = ?", account.AccountID, loadedVersion).
 		Updates(map[string]any{
 			"balance":           account.Balance,
 			"available_balance": account.AvailableBalance,
@@ -78,7 +83,7 @@ func (r *accountRepository) Save(ctx context.Context, account *domain.Account) e
 			"borrowed_amount":   account.BorrowedAmount,
 			"locked_collateral": account.LockedCollateral,
 			"accrued_interest":  account.AccruedInterest,
-			"version":           currentVersion + 1,
+			"version":           currentVersion,
 		})
 
 	if result.Error != nil {
@@ -88,7 +93,7 @@ func (r *accountRepository) Save(ctx context.Context, account *domain.Account) e
 		return errors.New("optimistic lock failed: account modified by another transaction")
 	}
 
-	account.SetVersion(currentVersion + 1)
+	account.SetVersion(currentVersion)
 	account.UpdatedAt = time.Now()
 	return nil
 }
@@ -3,6 +3,7 @@ package mysql
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -54,6 +55,15 @@ type WithdrawalOrderModel struct {
 	GatewayReference string          `gorm:"column:gateway_reference;type:varchar(128)"`
 	FailureReason    string          `gorm:"column:failure_reason;type:text"`
 	CompletedAt      *time.Time      `gorm:"column:completed_at"`
+	// 提现风控
+	RequiredApprovals int        `gorm:"column:required_approvals;not null;default:0"`
+	ApprovalsJSON     string     `gorm:"column:approvals_json;type:text;comment:审批记录"`
+	ReviewReasonsJSON string     `gorm:"column:review_reasons_json;type:text;comment:审核原因"`
+	ReviewDueAt       *time.Time `gorm:"column:review_due_at;index"`
+	ReviewEscalated   bool       `gorm:"column:review_escalated;not null;default:false"`
+	EarliestPayoutAt  *time.Time `gorm:"column:earliest_payout_at"`
+	AMLStatus         string     `gorm:"column:aml_status;type:varchar(16);index"`
+	AMLReference      string     `gorm:"column:aml_reference;type:varchar(64)"`
 }
 
 func (WithdrawalOrderModel) TableName() string {
@@ -141,6 +151,34 @@ func (r *DepositOrderMySQLRepository) FindByAccountID(ctx context.Context, accou
 	return result, total, nil
 }
 
+func (r *DepositOrderMySQLRepository) FindCompletedByUserBetween(ctx context.Context, userID string, from, to time.Time) ([]*domain.DepositOrder, error) {
+	var models []DepositOrderModel
+	if err := r.getDB(ctx).Where("user_id = ? AND status = ? AND completed_at >= ? AND completed_at < ?",
+		userID, string(domain.DepositStatusCompleted), from, to).
+		Order("completed_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.DepositOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, nil
+}
+
+func (r *DepositOrderMySQLRepository) FindCompletedByGatewayBetween(ctx context.Context, gatewayType domain.GatewayType, from, to time.Time) ([]*domain.DepositOrder, error) {
+	var models []DepositOrderModel
+	if err := r.getDB(ctx).Where("gateway_type = ? AND status = ? AND completed_at >= ? AND completed_at < ?",
+		string(gatewayType), string(domain.DepositStatusCompleted), from, to).
+		Order("completed_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.DepositOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, nil
+}
+
 func (r *DepositOrderMySQLRepository) Update(ctx context.Context, d *domain.DepositOrder) error {
 	model := r.toModel(d)
 	return r.getDB(ctx).Model(&DepositOrderModel{}).Where("id = ?", d.ID).Updates(model).Error
@@ -293,9 +331,84 @@ func (r *WithdrawalOrderMySQLRepository) FindPendingForAudit(ctx context.Context
 	return result, total, nil
 }
 
+func (r *WithdrawalOrderMySQLRepository) FindCompletedByUserBetween(ctx context.Context, userID string, from, to time.Time) ([]*domain.WithdrawalOrder, error) {
+	var models []WithdrawalOrderModel
+	if err := r.getDB(ctx).Where("user_id = ? AND status = ? AND completed_at >= ? AND completed_at < ?",
+		userID, string(domain.WithdrawalStatusCompleted), from, to).
+		Order("completed_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.WithdrawalOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, nil
+}
+
+func (r *WithdrawalOrderMySQLRepository) FindAwaitingReview(ctx context.Context, offset, limit int) ([]*domain.WithdrawalOrder, int64, error) {
+	var models []WithdrawalOrderModel
+	var total int64
+	db := r.getDB(ctx).Model(&WithdrawalOrderModel{}).Where("status = ? OR (status = ? AND aml_status = ?)",
+		string(domain.WithdrawalStatusAuditing), string(domain.WithdrawalStatusApproved), string(domain.AMLStatusHold))
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("review_due_at IS NULL, review_due_at ASC, created_at ASC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+	result := make([]*domain.WithdrawalOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, total, nil
+}
+
+func (r *WithdrawalOrderMySQLRepository) FindByUserSince(ctx context.Context, userID, currency string, since time.Time) ([]*domain.WithdrawalOrder, error) {
+	var models []WithdrawalOrderModel
+	if err := r.getDB(ctx).Where("user_id = ? AND currency = ? AND created_at >= ? AND status NOT IN ?",
+		userID, currency, since, []string{string(domain.WithdrawalStatusRejected), string(domain.WithdrawalStatusFailed)}).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.WithdrawalOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, nil
+}
+
+func (r *WithdrawalOrderMySQLRepository) FindFirstByBeneficiary(ctx context.Context, userID, bankAccountNo string) (*domain.WithdrawalOrder, error) {
+	var models []WithdrawalOrderModel
+	if err := r.getDB(ctx).Where("user_id = ? AND bank_account_no = ? AND status <> ?",
+		userID, bankAccountNo, string(domain.WithdrawalStatusRejected)).
+		Order("created_at ASC").Limit(1).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, nil
+	}
+	return r.toDomain(&models[0]), nil
+}
+
+func (r *WithdrawalOrderMySQLRepository) FindCompletedBetween(ctx context.Context, from, to time.Time) ([]*domain.WithdrawalOrder, error) {
+	var models []WithdrawalOrderModel
+	if err := r.getDB(ctx).Where("status = ? AND completed_at >= ? AND completed_at < ?",
+		string(domain.WithdrawalStatusCompleted), from, to).
+		Order("completed_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.WithdrawalOrder, len(models))
+	for i, m := range models {
+		result[i] = r.toDomain(&m)
+	}
+	return result, nil
+}
+
 func (r *WithdrawalOrderMySQLRepository) Update(ctx context.Context, w *domain.WithdrawalOrder) error {
 	model := r.toModel(w)
-	return r.getDB(ctx).Model(&WithdrawalOrderModel{}).Where("id = ?", w.ID).Updates(model).Error
+	// 审核时限、AML 状态等字段会被清空，需写入零值
+	return r.getDB(ctx).Model(&WithdrawalOrderModel{}).Where("id = ?", w.ID).
+		Select("*").Omit("id", "created_at", "deleted_at").Updates(model).Error
 }
 
 func (r *WithdrawalOrderMySQLRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
@@ -325,6 +438,15 @@ func (r *WithdrawalOrderMySQLRepository) toModel(w *domain.WithdrawalOrder) *Wit
 		GatewayReference: w.GatewayReference,
 		FailureReason:    w.FailureReason,
 		CompletedAt:      w.CompletedAt,
+
+		RequiredApprovals: w.RequiredApprovals,
+		ApprovalsJSON:     marshalJSON(w.Approvals),
+		ReviewReasonsJSON: marshalJSON(w.ReviewReasons),
+		ReviewDueAt:       w.ReviewDueAt,
+		ReviewEscalated:   w.ReviewEscalated,
+		EarliestPayoutAt:  w.EarliestPayoutAt,
+		AMLStatus:         string(w.AMLStatus),
+		AMLReference:      w.AMLReference,
 	}
 }
 
@@ -350,7 +472,28 @@ func (r *WithdrawalOrderMySQLRepository) toDomain(m *WithdrawalOrderModel) *doma
 		GatewayReference: m.GatewayReference,
 		FailureReason:    m.FailureReason,
 		CompletedAt:      m.CompletedAt,
+
+		RequiredApprovals: m.RequiredApprovals,
+		ReviewDueAt:       m.ReviewDueAt,
+		ReviewEscalated:   m.ReviewEscalated,
+		EarliestPayoutAt:  m.EarliestPayoutAt,
+		AMLStatus:         domain.AMLStatus(m.AMLStatus),
+		AMLReference:      m.AMLReference,
+	}
+	if m.ApprovalsJSON != "" {
+		_ = json.Unmarshal([]byte(m.ApprovalsJSON), &w.Approvals)
+	}
+	if m.ReviewReasonsJSON != "" {
+		_ = json.Unmarshal([]byte(m.ReviewReasonsJSON), &w.ReviewReasons)
 	}
 	w.InitFSM()
 	return w
 }
+
+func marshalJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
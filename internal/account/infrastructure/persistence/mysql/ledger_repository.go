@@ -0,0 +1,200 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+)
+
+// JournalEntryModel 会计分录头
+type JournalEntryModel struct {
+	gorm.Model
+	EntryID   string    `gorm:"column:entry_id;type:varchar(32);uniqueIndex;not null;comment:分录ID"`
+	Reference string    `gorm:"column:reference;type:varchar(128);uniqueIndex;not null;comment:业务幂等键"`
+	Kind      string    `gorm:"column:kind;type:varchar(32);index;not null;comment:业务类型"`
+	Memo      string    `gorm:"column:memo;type:varchar(255);comment:摘要"`
+	PostedAt  time.Time `gorm:"column:posted_at;not null;comment:记账时间"`
+}
+
+func (JournalEntryModel) TableName() string { return "ledger_journal_entries" }
+
+// JournalLineModel 会计分录行
+type JournalLineModel struct {
+	gorm.Model
+	EntryID     string          `gorm:"column:entry_id;type:varchar(32);index;not null;comment:分录ID"`
+	LineNo      int             `gorm:"column:line_no;not null;comment:行号"`
+	AccountCode string          `gorm:"column:account_code;type:varchar(32);index:idx_ledger_line_owner,priority:2;not null;comment:科目代码"`
+	OwnerID     string          `gorm:"column:owner_id;type:varchar(32);index:idx_ledger_line_owner,priority:1;not null;default:'';comment:客户账户ID"`
+	Currency    string          `gorm:"column:currency;type:varchar(10);not null;comment:币种"`
+	Debit       decimal.Decimal `gorm:"column:debit;type:decimal(32,18);default:0;not null;comment:借方金额"`
+	Credit      decimal.Decimal `gorm:"column:credit;type:decimal(32,18);default:0;not null;comment:贷方金额"`
+}
+
+func (JournalLineModel) TableName() string { return "ledger_journal_lines" }
+
+// ledgerRepository 总账仓储实现，分录与分录行只插入不更新
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository 创建总账仓储
+func NewLedgerRepository(db *gorm.DB) domain.LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) Append(ctx context.Context, entry *domain.JournalEntry) error {
+	db := r.getDB(ctx).WithContext(ctx)
+
+	var existing JournalEntryModel
+	err := db.Where("reference = ?", entry.Reference).First(&existing).Error
+	if err == nil {
+		return domain.ErrJournalPosted
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := db.Create(&JournalEntryModel{
+		EntryID:   entry.EntryID,
+		Reference: entry.Reference,
+		Kind:      string(entry.Kind),
+		Memo:      entry.Memo,
+		PostedAt:  entry.PostedAt,
+	}).Error; err != nil {
+		return err
+	}
+	lines := make([]*JournalLineModel, len(entry.Lines))
+	for i, l := range entry.Lines {
+		lines[i] = &JournalLineModel{
+			EntryID:     entry.EntryID,
+			LineNo:      i + 1,
+			AccountCode: string(l.AccountCode),
+			OwnerID:     l.OwnerID,
+			Currency:    l.Currency,
+			Debit:       l.Debit,
+			Credit:      l.Credit,
+		}
+	}
+	return db.Create(&lines).Error
+}
+
+func (r *ledgerRepository) ListEntries(ctx context.Context, ownerID string, limit, offset int) ([]*domain.JournalEntry, error) {
+	db := r.getDB(ctx).WithContext(ctx)
+	query := db.Model(&JournalEntryModel{})
+	if ownerID != "" {
+		query = query.Where("entry_id IN (?)",
+			db.Model(&JournalLineModel{}).Select("entry_id").Where("owner_id = ?", ownerID))
+	}
+	var heads []*JournalEntryModel
+	if err := query.Order("id ASC").Limit(limit).Offset(offset).Find(&heads).Error; err != nil {
+		return nil, err
+	}
+	return r.withLines(db, heads)
+}
+
+func (r *ledgerRepository) ListEntriesBetween(ctx context.Context, ownerID string, from, to time.Time) ([]*domain.JournalEntry, error) {
+	db := r.getDB(ctx).WithContext(ctx)
+	var heads []*JournalEntryModel
+	if err := db.Model(&JournalEntryModel{}).
+		Where("entry_id IN (?)", db.Model(&JournalLineModel{}).Select("entry_id").Where("owner_id = ?", ownerID)).
+		Where("posted_at >= ? AND posted_at < ?", from, to).
+		Order("posted_at ASC, id ASC").Find(&heads).Error; err != nil {
+		return nil, err
+	}
+	return r.withLines(db, heads)
+}
+
+// withLines 为分录头加载分录行
+func (r *ledgerRepository) withLines(db *gorm.DB, heads []*JournalEntryModel) ([]*domain.JournalEntry, error) {
+	if len(heads) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(heads))
+	for i, h := range heads {
+		ids[i] = h.EntryID
+	}
+	var lines []*JournalLineModel
+	if err := db.Where("entry_id IN ?", ids).Order("entry_id ASC, line_no ASC").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	byEntry := make(map[string][]domain.JournalLine, len(heads))
+	for _, l := range lines {
+		byEntry[l.EntryID] = append(byEntry[l.EntryID], domain.JournalLine{
+			AccountCode: domain.LedgerAccountCode(l.AccountCode),
+			OwnerID:     l.OwnerID,
+			Currency:    l.Currency,
+			Debit:       l.Debit,
+			Credit:      l.Credit,
+		})
+	}
+
+	entries := make([]*domain.JournalEntry, len(heads))
+	for i, h := range heads {
+		entries[i] = &domain.JournalEntry{
+			EntryID:   h.EntryID,
+			Reference: h.Reference,
+			Kind:      domain.JournalKind(h.Kind),
+			Memo:      h.Memo,
+			PostedAt:  h.PostedAt,
+			Lines:     byEntry[h.EntryID],
+		}
+	}
+	return entries, nil
+}
+
+func (r *ledgerRepository) Balances(ctx context.Context, ownerID string) (domain.LedgerBalances, error) {
+	query := r.getDB(ctx).WithContext(ctx).Model(&JournalLineModel{}).
+		Select("account_code, owner_id, currency, SUM(debit) AS debit, SUM(credit) AS credit")
+	if ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	return sumBalances(query.Group("account_code, owner_id, currency"))
+}
+
+func (r *ledgerRepository) BalancesAt(ctx context.Context, ownerID string, at time.Time) (domain.LedgerBalances, error) {
+	query := r.getDB(ctx).WithContext(ctx).Table("ledger_journal_lines AS l").
+		Select("l.account_code, l.owner_id, l.currency, SUM(l.debit) AS debit, SUM(l.credit) AS credit").
+		Joins("JOIN ledger_journal_entries AS e ON e.entry_id = l.entry_id").
+		Where("e.posted_at < ?", at)
+	if ownerID != "" {
+		query = query.Where("l.owner_id = ?", ownerID)
+	}
+	return sumBalances(query.Group("l.account_code, l.owner_id, l.currency"))
+}
+
+func sumBalances(query *gorm.DB) (domain.LedgerBalances, error) {
+	var rows []struct {
+		AccountCode string
+		OwnerID     string
+		Currency    string
+		Debit       decimal.Decimal
+		Credit      decimal.Decimal
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make(domain.LedgerBalances, len(rows))
+	for _, row := range rows {
+		key := domain.LedgerBalanceKey{
+			AccountCode: domain.LedgerAccountCode(row.AccountCode),
+			OwnerID:     row.OwnerID,
+			Currency:    row.Currency,
+		}
+		balances[key] = &domain.LedgerBalance{LedgerBalanceKey: key, Debit: row.Debit, Credit: row.Credit}
+	}
+	return balances, nil
+}
+
+func (r *ledgerRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
@@ -0,0 +1,157 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+)
+
+// StatementModel 对账单记录；活动明细只存于归档文件，表内保存汇总与文件位置
+type StatementModel struct {
+	gorm.Model
+	StatementID   string    `gorm:"column:statement_id;type:varchar(32);uniqueIndex;not null;comment:对账单ID"`
+	UserID        string    `gorm:"column:user_id;type:varchar(32);index:idx_statement_period,priority:1;not null;comment:用户ID"`
+	Frequency     string    `gorm:"column:frequency;type:varchar(16);index:idx_statement_period,priority:2;not null;comment:周期"`
+	PeriodStart   time.Time `gorm:"column:period_start;index:idx_statement_period,priority:3;not null;comment:期间起点"`
+	PeriodEnd     time.Time `gorm:"column:period_end;not null;comment:期间终点(不含)"`
+	Status        string    `gorm:"column:status;type:varchar(16);not null;comment:状态"`
+	SummaryJSON   string    `gorm:"column:summary_json;type:text;comment:各币种汇总"`
+	FilesJSON     string    `gorm:"column:files_json;type:text;comment:归档文件"`
+	WarningsJSON  string    `gorm:"column:warnings_json;type:text;comment:生成警告"`
+	FailureReason string    `gorm:"column:failure_reason;type:text;comment:失败原因"`
+	GeneratedAt   time.Time `gorm:"column:generated_at;comment:生成时间"`
+}
+
+func (StatementModel) TableName() string { return "account_statements" }
+
+type statementRepository struct {
+	db *gorm.DB
+}
+
+// NewStatementRepository 创建对账单仓储
+func NewStatementRepository(db *gorm.DB) domain.StatementRepository {
+	return &statementRepository{db: db}
+}
+
+// Save 按 StatementID 插入或覆盖（失败后重新生成沿用原 ID）
+func (r *statementRepository) Save(ctx context.Context, s *domain.Statement) error {
+	model, err := toStatementModel(s)
+	if err != nil {
+		return err
+	}
+	db := r.getDB(ctx).WithContext(ctx)
+	var existing StatementModel
+	err = db.Where("statement_id = ?", s.StatementID).First(&existing).Error
+	switch {
+	case err == nil:
+		model.ID = existing.ID
+		model.CreatedAt = existing.CreatedAt
+		return db.Save(model).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return db.Create(model).Error
+	default:
+		return err
+	}
+}
+
+func (r *statementRepository) Get(ctx context.Context, statementID string) (*domain.Statement, error) {
+	var m StatementModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("statement_id = ?", statementID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrStatementNotFound
+		}
+		return nil, err
+	}
+	return toStatementDomain(&m), nil
+}
+
+func (r *statementRepository) FindByPeriod(ctx context.Context, userID string, freq domain.StatementFrequency, periodStart time.Time) (*domain.Statement, error) {
+	var m StatementModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND frequency = ? AND period_start = ?", userID, string(freq), periodStart).
+		Order("id DESC").First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toStatementDomain(&m), nil
+}
+
+func (r *statementRepository) ListByUser(ctx context.Context, userID string, limit, offset int) ([]*domain.Statement, error) {
+	var models []*StatementModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).
+		Order("period_start DESC, id DESC").Limit(limit).Offset(offset).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*domain.Statement, len(models))
+	for i, m := range models {
+		list[i] = toStatementDomain(m)
+	}
+	return list, nil
+}
+
+func (r *statementRepository) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(contextx.WithTx(ctx, tx))
+	})
+}
+
+func (r *statementRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func toStatementModel(s *domain.Statement) (*StatementModel, error) {
+	summary, err := json.Marshal(s.Summaries)
+	if err != nil {
+		return nil, err
+	}
+	files, err := json.Marshal(s.Files)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := json.Marshal(s.Warnings)
+	if err != nil {
+		return nil, err
+	}
+	return &StatementModel{
+		StatementID:   s.StatementID,
+		UserID:        s.UserID,
+		Frequency:     string(s.Frequency),
+		PeriodStart:   s.PeriodStart,
+		PeriodEnd:     s.PeriodEnd,
+		Status:        string(s.Status),
+		SummaryJSON:   string(summary),
+		FilesJSON:     string(files),
+		WarningsJSON:  string(warnings),
+		FailureReason: s.FailureReason,
+		GeneratedAt:   s.GeneratedAt,
+	}, nil
+}
+
+func toStatementDomain(m *StatementModel) *domain.Statement {
+	s := &domain.Statement{
+		StatementID:   m.StatementID,
+		UserID:        m.UserID,
+		Frequency:     domain.StatementFrequency(m.Frequency),
+		PeriodStart:   m.PeriodStart,
+		PeriodEnd:     m.PeriodEnd,
+		Status:        domain.StatementStatus(m.Status),
+		FailureReason: m.FailureReason,
+		GeneratedAt:   m.GeneratedAt,
+		Files:         make(map[domain.StatementFormat]string),
+	}
+	_ = json.Unmarshal([]byte(m.SummaryJSON), &s.Summaries)
+	_ = json.Unmarshal([]byte(m.FilesJSON), &s.Files)
+	_ = json.Unmarshal([]byte(m.WarningsJSON), &s.Warnings)
+	return s
+}
@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubAccountModel 子账户
+type SubAccountModel struct {
+	gorm.Model
+	SubUserID             string          `gorm:"column:sub_user_id;type:varchar(32);uniqueIndex;not null;comment:子账户用户ID"`
+	MasterUserID          string          `gorm:"column:master_user_id;type:varchar(32);index;not null;comment:主账户用户ID"`
+	Name                  string          `gorm:"column:name;type:varchar(64);comment:名称"`
+	Permissions           string          `gorm:"column:permissions;type:varchar(128);comment:权限(逗号分隔)"`
+	MarginMode            string          `gorm:"column:margin_mode;type:varchar(16);not null;comment:保证金模式"`
+	Status                string          `gorm:"column:status;type:varchar(16);not null;comment:状态"`
+	MaxOrderAmount        decimal.Decimal `gorm:"column:max_order_amount;type:decimal(32,18);default:0;not null;comment:单笔交易冻结上限"`
+	DailyTransferOutLimit decimal.Decimal `gorm:"column:daily_transfer_out_limit;type:decimal(32,18);default:0;not null;comment:每日划出上限"`
+}
+
+func (SubAccountModel) TableName() string { return "account_sub_accounts" }
+
+// InternalTransferModel 内部划转记录
+type InternalTransferModel struct {
+	gorm.Model
+	TransferNo   string          `gorm:"column:transfer_no;type:varchar(32);uniqueIndex;not null;comment:划转单号"`
+	MasterUserID string          `gorm:"column:master_user_id;type:varchar(32);index;not null;comment:主账户用户ID"`
+	FromUserID   string          `gorm:"column:from_user_id;type:varchar(32);index:idx_transfer_from,priority:1;not null;comment:划出用户ID"`
+	ToUserID     string          `gorm:"column:to_user_id;type:varchar(32);index;not null;comment:划入用户ID"`
+	Currency     string          `gorm:"column:currency;type:varchar(10);index:idx_transfer_from,priority:2;not null;comment:币种"`
+	Amount       decimal.Decimal `gorm:"column:amount;type:decimal(32,18);not null;comment:金额"`
+	Kind         string          `gorm:"column:kind;type:varchar(16);not null;comment:划转类型"`
+	Operator     string          `gorm:"column:operator;type:varchar(64);comment:操作人"`
+	Memo         string          `gorm:"column:memo;type:varchar(255);comment:备注"`
+	TransferAt   time.Time       `gorm:"column:transfer_at;index:idx_transfer_from,priority:3;not null;comment:划转时间"`
+}
+
+func (InternalTransferModel) TableName() string { return "account_internal_transfers" }
+
+type subAccountRepository struct {
+	db *gorm.DB
+}
+
+// NewSubAccountRepository 创建子账户仓储
+func NewSubAccountRepository(db *gorm.DB) domain.SubAccountRepository {
+	return &subAccountRepository{db: db}
+}
+
+// Save 按 SubUserID 插入或更新
+func (r *subAccountRepository) Save(ctx context.Context, s *domain.SubAccount) error {
+	model := toSubAccountModel(s)
+	db := r.getDB(ctx).WithContext(ctx)
+	var existing SubAccountModel
+	err := db.Where("sub_user_id = ?", s.SubUserID).First(&existing).Error
+	switch {
+	case err == nil:
+		model.ID = existing.ID
+		model.CreatedAt = existing.CreatedAt
+		return db.Save(model).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := db.Create(model).Error; err != nil {
+			return err
+		}
+		s.CreatedAt, s.UpdatedAt = model.CreatedAt, model.UpdatedAt
+		return nil
+	default:
+		return err
+	}
+}
+
+func (r *subAccountRepository) Get(ctx context.Context, subUserID string) (*domain.SubAccount, error) {
+	var m SubAccountModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("sub_user_id = ?", subUserID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toSubAccount(&m), nil
+}
+
+func (r *subAccountRepository) GetForUpdate(ctx context.Context, subUserID string) (*domain.SubAccount, error) {
+	var m SubAccountModel
+	err := r.getDB(ctx).WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sub_user_id = ?", subUserID).First(&m).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toSubAccount(&m), nil
+}
+
+func (r *subAccountRepository) ListByMaster(ctx context.Context, masterUserID string) ([]*domain.SubAccount, error) {
+	var models []*SubAccountModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("master_user_id = ?", masterUserID).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*domain.SubAccount, len(models))
+	for i, m := range models {
+		list[i] = toSubAccount(m)
+	}
+	return list, nil
+}
+
+func (r *subAccountRepository) SaveTransfer(ctx context.Context, t *domain.InternalTransfer) error {
+	return r.getDB(ctx).WithContext(ctx).Create(&InternalTransferModel{
+		TransferNo:   t.TransferNo,
+		MasterUserID: t.MasterUserID,
+		FromUserID:   t.FromUserID,
+		ToUserID:     t.ToUserID,
+		Currency:     t.Currency,
+		Amount:       t.Amount,
+		Kind:         string(t.Kind),
+		Operator:     t.Operator,
+		Memo:         t.Memo,
+		TransferAt:   t.CreatedAt,
+	}).Error
+}
+
+func (r *subAccountRepository) SumTransfersOut(ctx context.Context, fromUserID, currency string, since time.Time) (decimal.Decimal, error) {
+	var total decimal.NullDecimal
+	err := r.getDB(ctx).WithContext(ctx).Model(&InternalTransferModel{}).
+		Select("SUM(amount)").
+		Where("from_user_id = ? AND currency = ? AND kind = ? AND transfer_at >= ?", fromUserID, currency, string(domain.TransferKindManual), since).
+		Scan(&total).Error
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !total.Valid {
+		return decimal.Zero, nil
+	}
+	return total.Decimal, nil
+}
+
+func (r *subAccountRepository) ListTransfers(ctx context.Context, masterUserID string, limit, offset int) ([]*domain.InternalTransfer, error) {
+	var models []*InternalTransferModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("master_user_id = ?", masterUserID).
+		Order("transfer_at DESC, id DESC").Limit(limit).Offset(offset).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*domain.InternalTransfer, len(models))
+	for i, m := range models {
+		list[i] = &domain.InternalTransfer{
+			TransferNo:   m.TransferNo,
+			MasterUserID: m.MasterUserID,
+			FromUserID:   m.FromUserID,
+			ToUserID:     m.ToUserID,
+			Currency:     m.Currency,
+			Amount:       m.Amount,
+			Kind:         domain.TransferKind(m.Kind),
+			Operator:     m.Operator,
+			Memo:         m.Memo,
+			CreatedAt:    m.TransferAt,
+		}
+	}
+	return list, nil
+}
+
+func (r *subAccountRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func toSubAccountModel(s *domain.SubAccount) *SubAccountModel {
+	perms := make([]string, len(s.Permissions))
+	for i, p := range s.Permissions {
+		perms[i] = string(p)
+	}
+	return &SubAccountModel{
+		SubUserID:             s.SubUserID,
+		MasterUserID:          s.MasterUserID,
+		Name:                  s.Name,
+		Permissions:           strings.Join(perms, ","),
+		MarginMode:            string(s.MarginMode),
+		Status:                string(s.Status),
+		MaxOrderAmount:        s.MaxOrderAmount,
+		DailyTransferOutLimit: s.DailyTransferOutLimit,
+	}
+}
+
+func toSubAccount(m *SubAccountModel) *domain.SubAccount {
+	s := &domain.SubAccount{
+		SubUserID:             m.SubUserID,
+		MasterUserID:          m.MasterUserID,
+		Name:                  m.Name,
+		MarginMode:            domain.MarginMode(m.MarginMode),
+		Status:                domain.SubAccountStatus(m.Status),
+		MaxOrderAmount:        m.MaxOrderAmount,
+		DailyTransferOutLimit: m.DailyTransferOutLimit,
+		CreatedAt:             m.CreatedAt,
+		UpdatedAt:             m.UpdatedAt,
+	}
+	for _, p := range strings.Split(m.Permissions, ",") {
+		if p != "" {
+			s.Permissions = append(s.Permissions, domain.SubAccountPermission(p))
+		}
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// ConfigWithdrawalPolicyLoader 从服务配置文件 [withdrawal.policy] 段加载提现风控策略，
+// 字段名与策略的 JSON 字段一致
+type ConfigWithdrawalPolicyLoader struct {
+	raw map[string]any
+}
+
+// NewConfigWithdrawalPolicyLoader 创建配置策略加载器
+func NewConfigWithdrawalPolicyLoader(raw map[string]any) *ConfigWithdrawalPolicyLoader {
+	return &ConfigWithdrawalPolicyLoader{raw: raw}
+}
+
+// Load 转换并校验策略，币种键统一转为大写（配置加载会将键名转为小写）
+func (l *ConfigWithdrawalPolicyLoader) Load() (*domain.WithdrawalPolicySet, error) {
+	data, err := json.Marshal(l.raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid withdrawal policy: %w", err)
+	}
+	set := &domain.WithdrawalPolicySet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("invalid withdrawal policy: %w", err)
+	}
+	currencies := make(map[string]domain.WithdrawalPolicy, len(set.Currencies))
+	for currency, p := range set.Currencies {
+		currencies[strings.ToUpper(currency)] = p
+	}
+	set.Currencies = currencies
+
+	for name, p := range currencies {
+		if err := validate(p); err != nil {
+			return nil, fmt.Errorf("invalid withdrawal policy for %s: %w", name, err)
+		}
+	}
+	if err := validate(set.Default); err != nil {
+		return nil, fmt.Errorf("invalid withdrawal policy default: %w", err)
+	}
+	return set, nil
+}
+
+func validate(p domain.WithdrawalPolicy) error {
+	if p.AutoApproveLimit.IsNegative() || p.LargeAmountThreshold.IsNegative() || p.DailyAmountLimit.IsNegative() {
+		return fmt.Errorf("amount thresholds must not be negative")
+	}
+	if p.DailyCountLimit < 0 || p.BeneficiaryCoolingOffHours < 0 || p.ReviewSLAMinutes < 0 || p.LargeAmountApprovers < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if p.LargeAmountThreshold.IsPositive() && p.AutoApproveLimit.GreaterThanOrEqual(p.LargeAmountThreshold) {
+		return fmt.Errorf("auto_approve_limit %s must be below large_amount_threshold %s", p.AutoApproveLimit, p.LargeAmountThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package statement 对账单渲染与文件存储
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// CSVRenderer 以 CSV 输出对账单：抬头、各币种汇总、活动明细与警告依次成段，段间空行分隔
+type CSVRenderer struct{}
+
+// NewCSVRenderer 创建 CSV 渲染器
+func NewCSVRenderer() *CSVRenderer { return &CSVRenderer{} }
+
+func (CSVRenderer) Format() domain.StatementFormat { return domain.StatementFormatCSV }
+
+func (CSVRenderer) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (CSVRenderer) Render(s *domain.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		{"Statement ID", s.StatementID},
+		{"User ID", s.UserID},
+		{"Frequency", string(s.Frequency)},
+		{"Period Start", s.PeriodStart.Format(time.RFC3339)},
+		{"Period End", s.PeriodEnd.Format(time.RFC3339)},
+		{},
+		{"Currency", "Opening", "Deposits", "Withdrawals", "Trades", "Fees", "Interest", "Corporate Actions", "Other", "Closing", "Interest Accrued"},
+	}
+	for _, c := range s.Summaries {
+		rows = append(rows, []string{c.Currency, c.Opening.String(), c.Deposits.String(), c.Withdrawals.String(),
+			c.Trades.String(), c.Fees.String(), c.Interest.String(), c.CorporateActions.String(), c.Other.String(),
+			c.Closing.String(), c.InterestAccrued.String()})
+	}
+	rows = append(rows, []string{}, []string{"Time", "Category", "Currency", "Amount", "Symbol", "Quantity", "Reference", "Description"})
+	for _, a := range s.Activities {
+		qty := ""
+		if !a.Quantity.IsZero() {
+			qty = a.Quantity.String()
+		}
+		rows = append(rows, []string{a.Time.Format(time.RFC3339), string(a.Category), a.Currency, a.Amount.String(),
+			a.Symbol, qty, a.Reference, a.Description})
+	}
+	if len(s.Warnings) > 0 {
+		rows = append(rows, []string{})
+		for _, warning := range s.Warnings {
+			rows = append(rows, []string{"Warning", warning})
+		}
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
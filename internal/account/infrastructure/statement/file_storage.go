@@ -0,0 +1,55 @@
+package statement
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStorage 把对账单文件存放在本地目录，key 为相对路径
+type FileStorage struct {
+	dir string
+}
+
+// NewFileStorage 创建本地文件存储，目录不存在时自动创建
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create statement dir %s: %w", dir, err)
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+func (s *FileStorage) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免下载到写了一半的文件
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *FileStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// path 拒绝越出存储目录的 key
+func (s *FileStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid statement key: %s", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
@@ -0,0 +1,254 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+const (
+	pdfPageWidth    = 595 // A4, 单位 pt
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFontSize     = 8
+	pdfLeading      = 11
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	pdfLineWidth    = 110 // Courier 8pt 每行可容纳的列数
+	// pdfCJKAdvance 中日韩字符的字宽（千分之一字号），取两列 Courier（2 x 600），保持列对齐
+	pdfCJKAdvance = 1200
+)
+
+// PDFRenderer 输出分页 PDF 对账单，不依赖外部库。ASCII 使用 Courier，其余字符使用
+// Adobe-GB1 标准中文字体 STSong-Light（Type0/CIDFont，UniGB-UCS2-H 编码），由阅读器提供字形，
+// 并附 ToUnicode 映射保证文本可复制与检索；中文字符按两列宽度排版
+type PDFRenderer struct{}
+
+// NewPDFRenderer 创建 PDF 渲染器
+func NewPDFRenderer() *PDFRenderer { return &PDFRenderer{} }
+
+func (PDFRenderer) Format() domain.StatementFormat { return domain.StatementFormatPDF }
+
+func (PDFRenderer) ContentType() string { return "application/pdf" }
+
+func (PDFRenderer) Render(s *domain.Statement) ([]byte, error) {
+	return writePDF(statementLines(s)), nil
+}
+
+// statementLines 排版对账单正文
+func statementLines(s *domain.Statement) []string {
+	lines := []string{
+		"ACCOUNT STATEMENT",
+		"",
+		fmt.Sprintf("Statement ID : %s", s.StatementID),
+		fmt.Sprintf("User ID      : %s", s.UserID),
+		fmt.Sprintf("Frequency    : %s", s.Frequency),
+		fmt.Sprintf("Period       : %s - %s", s.PeriodStart.Format(time.RFC3339), s.PeriodEnd.Format(time.RFC3339)),
+		"",
+		"SUMMARY",
+	}
+	for _, c := range s.Summaries {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("  Currency %s", c.Currency),
+			fmt.Sprintf("    %-28s %24s", "Opening balance", c.Opening.String()),
+			fmt.Sprintf("    %-28s %24s", "Deposits", c.Deposits.String()),
+			fmt.Sprintf("    %-28s %24s", "Withdrawals", c.Withdrawals.String()),
+			fmt.Sprintf("    %-28s %24s", "Trades", c.Trades.String()),
+			fmt.Sprintf("    %-28s %24s", "Fees", c.Fees.String()),
+			fmt.Sprintf("    %-28s %24s", "Interest", c.Interest.String()),
+			fmt.Sprintf("    %-28s %24s", "Corporate actions", c.CorporateActions.String()),
+			fmt.Sprintf("    %-28s %24s", "Other movements", c.Other.String()),
+			fmt.Sprintf("    %-28s %24s", "Closing balance", c.Closing.String()),
+		)
+		if !c.InterestAccrued.IsZero() {
+			lines = append(lines, fmt.Sprintf("    %-28s %24s", "Interest accrued (unsettled)", c.InterestAccrued.String()))
+		}
+	}
+
+	lines = append(lines, "", "ACTIVITY", "",
+		fmt.Sprintf("%-20s %-17s %-5s %20s %-12s %s", "Time", "Category", "Ccy", "Amount", "Symbol", "Reference / Description"))
+	if len(s.Activities) == 0 {
+		lines = append(lines, "  No activity in this period.")
+	}
+	for _, a := range s.Activities {
+		symbol := a.Symbol
+		if !a.Quantity.IsZero() {
+			symbol = fmt.Sprintf("%s %s", a.Symbol, a.Quantity.String())
+		}
+		lines = append(lines, fmt.Sprintf("%-20s %-17s %-5s %20s %-12s %s %s",
+			a.Time.UTC().Format("2006-01-02 15:04:05"), a.Category, a.Currency, a.Amount.String(), symbol, a.Reference, a.Description))
+	}
+	if len(s.Warnings) > 0 {
+		lines = append(lines, "", "NOTES")
+		for _, w := range s.Warnings {
+			lines = append(lines, "  * "+w)
+		}
+	}
+
+	wrapped := make([]string, 0, len(lines))
+	for _, l := range lines {
+		wrapped = append(wrapped, wrapLine(l)...)
+	}
+	return wrapped
+}
+
+// wrapLine 按显示列宽折行，续行缩进四列
+func wrapLine(l string) []string {
+	var out []string
+	var b strings.Builder
+	width := 0
+	for _, r := range l {
+		w := runeColumns(r)
+		if width+w > pdfLineWidth {
+			out = append(out, b.String())
+			b.Reset()
+			b.WriteString("    ")
+			width = 4
+		}
+		b.WriteRune(r)
+		width += w
+	}
+	return append(out, b.String())
+}
+
+// runeColumns 字符占用的列数：ASCII 一列，其余按全角两列
+func runeColumns(r rune) int {
+	if r < 0x80 {
+		return 1
+	}
+	return 2
+}
+
+// writePDF 生成最小的 PDF 1.4 文档：目录、页树、Courier 与中文 CID 字体以及每页一个内容流
+func writePDF(lines []string) []byte {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// 对象编号：1 目录，2 页树，3 Courier，4-7 中文字体（Type0、CIDFont、字体描述、ToUnicode），
+	// 之后每页占两个对象（页面与内容流）
+	const firstPage = 8
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	toUnicode := pdfToUnicodeCMap()
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-UniGB-UCS2-H /Encoding /UniGB-UCS2-H /DescendantFonts [5 0 R] /ToUnicode 7 0 R >>",
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> /FontDescriptor 6 0 R /DW %d >>", pdfCJKAdvance),
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 752 /Descent -271 /CapHeight 737 /StemV 58 >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(toUnicode), toUnicode),
+	)
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n%d TL\n%d %d Td\n", pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, l := range page {
+			writePDFText(&content, l)
+			content.WriteString("T*\n")
+		}
+		fmt.Fprintf(&content, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(Page %d of %d) Tj\nET\n",
+			pdfFontSize, pdfPageWidth-pdfMargin-80, pdfMargin/2, i+1, len(pages))
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, firstPage+1+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// writePDFText 输出一行文本：ASCII 片段以 Courier 字面串输出，其余片段切换到中文字体并以 UCS-2 十六进制串输出
+func writePDFText(w *bytes.Buffer, line string) {
+	runes := []rune(line)
+	for start := 0; start < len(runes); {
+		ascii := runes[start] < 0x80
+		end := start
+		for end < len(runes) && (runes[end] < 0x80) == ascii {
+			end++
+		}
+		if ascii {
+			fmt.Fprintf(w, "/F1 %d Tf (%s) Tj\n", pdfFontSize, pdfEscape(runes[start:end]))
+		} else {
+			fmt.Fprintf(w, "/F2 %d Tf <%s> Tj\n", pdfFontSize, pdfUCS2(runes[start:end]))
+		}
+		start = end
+	}
+}
+
+// pdfEscape 转义 ASCII 片段中的括号与反斜杠，控制字符替换为 ?
+func pdfEscape(runes []rune) string {
+	var b strings.Builder
+	for _, r := range runes {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfUCS2 将非 ASCII 片段编码为 UCS-2 十六进制串；基本多文种平面之外的字符无法编码，以全角问号代替
+func pdfUCS2(runes []rune) string {
+	var b strings.Builder
+	for _, r := range runes {
+		if r > 0xffff || (r >= 0xd800 && r <= 0xdfff) {
+			r = 0xff1f
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// pdfToUnicodeCMap UniGB-UCS2-H 的字符码即 UCS-2 码位，逐高字节按恒等区间映射回 Unicode（跳过代理区）
+func pdfToUnicodeCMap() string {
+	var ranges []string
+	for hi := 0; hi <= 0xff; hi++ {
+		if hi >= 0xd8 && hi <= 0xdf {
+			continue
+		}
+		ranges = append(ranges, fmt.Sprintf("<%02X00> <%02XFF> <%02X00>", hi, hi, hi))
+	}
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// 单个 bfrange 段最多 100 项
+	for len(ranges) > 0 {
+		n := min(len(ranges), 100)
+		fmt.Fprintf(&b, "%d beginbfrange\n%s\nendbfrange\n", n, strings.Join(ranges[:n], "\n"))
+		ranges = ranges[n:]
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.String()
+}
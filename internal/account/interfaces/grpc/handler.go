@@ -19,6 +19,7 @@ type Handler struct {
 	pb.UnimplementedAccountServiceServer
 	cmd   *application.AccountCommandService
 	query *application.AccountQueryService
+	subs  *application.SubAccountService
 }
 
 // NewHandler 构造一个新的账户 gRPC 处理器实例。
@@ -26,6 +27,11 @@ func NewHandler(cmd *application.AccountCommandService, query *application.Accou
 	return &Handler{cmd: cmd, query: query}
 }
 
+// SetSubAccountService 启用主子账户层级查询
+func (h *Handler) SetSubAccountService(subs *application.SubAccountService) {
+	h.subs = subs
+}
+
 // CreateAccount 处理创建新账户的请求。
 func (h *Handler) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
 	start := time.Now()
@@ -81,12 +87,18 @@ func (h *Handler) Deposit(ctx context.Context, req *pb.DepositRequest) (*pb.Depo
 	return &pb.DepositResponse{AccountId: req.AccountId, Status: "SUCCESS", Amount: req.Amount, Timestamp: 0}, nil
 }
 
-// GetBalance 获取指定账户的余额详情快照。
+// GetBalance 获取指定账户的余额详情快照，未传 account_id 时按用户与币种定位账户。
 func (h *Handler) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
 	start := time.Now()
 	slog.DebugContext(ctx, "grpc get_balance received", "account_id", req.AccountId)
 
-	dto, err := h.query.GetAccount(ctx, req.AccountId)
+	var dto *application.AccountDTO
+	var err error
+	if req.AccountId == "" && req.UserId != "" && req.Currency != "" {
+		dto, err = h.query.GetBalance(ctx, req.UserId, req.Currency)
+	} else {
+		dto, err = h.query.GetAccount(ctx, req.AccountId)
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "grpc get_balance failed", "account_id", req.AccountId, "error", err, "duration", time.Since(start))
 		return nil, status.Errorf(codes.Internal, "failed to get balance: %v", err)
@@ -293,6 +305,36 @@ func (h *Handler) SettleInterest(ctx context.Context, req *pb.SettleInterestRequ
 	return &pb.SettleInterestResponse{Success: true, SettledAmount: settledAmount}, nil
 }
 
+// GetAccountHierarchy 查询用户所在的主子账户层级；未启用子账户时每个用户自成一级。
+func (h *Handler) GetAccountHierarchy(ctx context.Context, req *pb.GetAccountHierarchyRequest) (*pb.GetAccountHierarchyResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if h.subs == nil {
+		return &pb.GetAccountHierarchyResponse{MasterUserId: req.UserId}, nil
+	}
+	hierarchy, err := h.subs.GetHierarchy(ctx, req.UserId)
+	if err != nil {
+		slog.ErrorContext(ctx, "grpc get_account_hierarchy failed", "user_id", req.UserId, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get account hierarchy: %v", err)
+	}
+	resp := &pb.GetAccountHierarchyResponse{MasterUserId: hierarchy.MasterUserID}
+	for _, sub := range hierarchy.SubAccounts {
+		perms := make([]string, len(sub.Permissions))
+		for i, p := range sub.Permissions {
+			perms[i] = string(p)
+		}
+		resp.SubAccounts = append(resp.SubAccounts, &pb.SubAccountItem{
+			SubUserId:   sub.SubUserID,
+			Name:        sub.Name,
+			Permissions: perms,
+			MarginMode:  string(sub.MarginMode),
+			Status:      string(sub.Status),
+		})
+	}
+	return resp, nil
+}
+
 func (h *Handler) toProto(dto *application.AccountDTO) *pb.AccountResponse {
 	return &pb.AccountResponse{
 		AccountId:        dto.AccountID,
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/application"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// FundingHandler 出入金与支付通道回调接口
+type FundingHandler struct {
+	funding *application.DepositWithdrawalCommandService
+	recon   *application.GatewayReconciliationService
+}
+
+func NewFundingHandler(funding *application.DepositWithdrawalCommandService) *FundingHandler {
+	return &FundingHandler{funding: funding}
+}
+
+// SetReconciliationService 启用通道对账手动触发接口
+func (h *FundingHandler) SetReconciliationService(recon *application.GatewayReconciliationService) {
+	h.recon = recon
+}
+
+func (h *FundingHandler) RegisterRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/v1/account")
+	{
+		v1.POST("/funding/deposits", h.CreateDeposit)
+		v1.POST("/funding/withdrawals", h.CreateWithdrawal)
+		v1.GET("/funding/withdrawals/queue", h.ListApprovalQueue)
+		v1.POST("/funding/withdrawals/:withdrawal_no/audit", h.AuditWithdrawal)
+		v1.POST("/funding/withdrawals/:withdrawal_no/process", h.ProcessWithdrawal)
+		v1.POST("/funding/withdrawals/:withdrawal_no/aml-review", h.ReviewAMLHold)
+		v1.POST("/gateways/:gateway/webhook", h.GatewayWebhook)
+		if h.recon != nil {
+			v1.POST("/gateways/reconcile", h.Reconcile)
+		}
+	}
+}
+
+type createDepositRequest struct {
+	UserID      string `json:"user_id" binding:"required"`
+	AccountID   string `json:"account_id" binding:"required"`
+	Amount      string `json:"amount" binding:"required"`
+	Currency    string `json:"currency" binding:"required"`
+	GatewayType string `json:"gateway_type" binding:"required"`
+}
+
+// CreateDeposit 创建充值单并返回通道支付链接
+func (h *FundingHandler) CreateDeposit(c *gin.Context) {
+	var req createDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	resp, err := h.funding.CreateDeposit(c.Request.Context(), &application.CreateDepositRequest{
+		UserID:      req.UserID,
+		AccountID:   req.AccountID,
+		Amount:      amount,
+		Currency:    req.Currency,
+		GatewayType: domain.GatewayType(req.GatewayType),
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrUnknownPaymentRail) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type createWithdrawalRequest struct {
+	UserID        string `json:"user_id" binding:"required"`
+	AccountID     string `json:"account_id" binding:"required"`
+	Amount        string `json:"amount" binding:"required"`
+	Fee           string `json:"fee"`
+	Currency      string `json:"currency" binding:"required"`
+	BankAccountNo string `json:"bank_account_no" binding:"required"`
+	BankName      string `json:"bank_name"`
+	BankHolder    string `json:"bank_holder"`
+}
+
+// CreateWithdrawal 冻结资金并创建待审核提现单
+func (h *FundingHandler) CreateWithdrawal(c *gin.Context) {
+	var req createWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	fee := decimal.Zero
+	if req.Fee != "" {
+		if fee, err = decimal.NewFromString(req.Fee); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fee"})
+			return
+		}
+	}
+	resp, err := h.funding.CreateWithdrawal(c.Request.Context(), &application.CreateWithdrawalRequest{
+		UserID:        req.UserID,
+		AccountID:     req.AccountID,
+		Amount:        amount,
+		Fee:           fee,
+		Currency:      req.Currency,
+		BankAccountNo: req.BankAccountNo,
+		BankName:      req.BankName,
+		BankHolder:    req.BankHolder,
+	})
+	if err != nil {
+		c.JSON(withdrawalErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type auditWithdrawalRequest struct {
+	Approved bool   `json:"approved"`
+	Remark   string `json:"remark"`
+}
+
+// AuditWithdrawal 审核提现，审核人取认证身份
+func (h *FundingHandler) AuditWithdrawal(c *gin.Context) {
+	auditor := principal(c)
+	if auditor == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	var req auditWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.funding.AuditWithdrawal(c.Request.Context(), &application.AuditWithdrawalRequest{
+		WithdrawalNo: c.Param("withdrawal_no"),
+		Approved:     req.Approved,
+		Auditor:      auditor,
+		Remark:       req.Remark,
+	}); err != nil {
+		c.JSON(withdrawalErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ProcessWithdrawal 经出款通道打款；冷静期内或被 AML 挂起时返回 409
+func (h *FundingHandler) ProcessWithdrawal(c *gin.Context) {
+	if err := h.funding.ProcessWithdrawal(c.Request.Context(), c.Param("withdrawal_no")); err != nil {
+		c.JSON(withdrawalErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type reviewAMLHoldRequest struct {
+	Release bool   `json:"release"`
+	Remark  string `json:"remark"`
+}
+
+// ReviewAMLHold 合规复核 AML 挂起的提现，复核人取认证身份
+func (h *FundingHandler) ReviewAMLHold(c *gin.Context) {
+	reviewer := principal(c)
+	if reviewer == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	var req reviewAMLHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.funding.ReviewAMLHold(c.Request.Context(), &application.ReviewAMLHoldRequest{
+		WithdrawalNo: c.Param("withdrawal_no"),
+		Reviewer:     reviewer,
+		Release:      req.Release,
+		Remark:       req.Remark,
+	}); err != nil {
+		c.JSON(withdrawalErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ListApprovalQueue 提现审批队列（offset/limit 分页）
+func (h *FundingHandler) ListApprovalQueue(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	items, total, err := h.funding.ListApprovalQueue(c.Request.Context(), max(offset, 0), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+}
+
+// withdrawalErrorStatus 提现风控错误映射为 HTTP 状态码
+func withdrawalErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, domain.ErrWithdrawalVelocityExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrApproverNotAllowed),
+		errors.Is(err, domain.ErrSubAccountPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrDuplicateApproval),
+		errors.Is(err, domain.ErrWithdrawalAMLHold),
+		errors.Is(err, domain.ErrWithdrawalCoolingOff):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+// GatewayWebhook 支付通道异步回调；返回非 2xx 时通道会重推
+func (h *FundingHandler) GatewayWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = h.funding.HandleGatewayWebhook(c.Request.Context(), domain.GatewayType(c.Param("gateway")),
+		c.GetHeader("X-Signature"), c.GetHeader("X-Timestamp"), body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrInvalidWebhookSignature):
+			status = http.StatusUnauthorized
+		case errors.Is(err, domain.ErrUnknownPaymentRail):
+			status = http.StatusNotFound
+		case errors.Is(err, application.ErrGatewayNotificationMismatch):
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Reconcile 手动触发某日（date=2006-01-02，默认昨日）的通道对账
+func (h *FundingHandler) Reconcile(c *gin.Context) {
+	day := time.Now().AddDate(0, 0, -1)
+	if v := c.Query("date"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, h.recon.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		day = d
+	}
+	reports, err := h.recon.ReconcileDay(c.Request.Context(), day)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "reports": reports})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
@@ -1,29 +1,45 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
 	"github.com/wyfcoding/financialtrading/internal/account/application"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
 )
 
 type AccountHandler struct {
-	cmd   *application.AccountCommandService
-	query *application.AccountQueryService
+	cmd        *application.AccountCommandService
+	query      *application.AccountQueryService
+	statements *application.StatementService
 }
 
 func NewAccountHandler(cmd *application.AccountCommandService, query *application.AccountQueryService) *AccountHandler {
 	return &AccountHandler{cmd: cmd, query: query}
 }
 
+// SetStatementService 启用对账单接口
+func (h *AccountHandler) SetStatementService(statements *application.StatementService) {
+	h.statements = statements
+}
+
 func (h *AccountHandler) RegisterRoutes(r *gin.RouterGroup) {
 	v1 := r.Group("/v1/account")
 	{
 		v1.POST("", h.CreateAccount)
 		v1.GET("/:id", h.GetAccount)
 		v1.POST("/deposit", h.Deposit)
+		if h.statements != nil {
+			v1.POST("/statements", h.GenerateStatement)
+			v1.GET("/statements", h.ListStatements)
+			v1.GET("/statements/:statement_id/download", h.DownloadStatement)
+		}
 	}
 }
 
@@ -82,3 +98,81 @@ func (h *AccountHandler) Deposit(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
+
+type generateStatementRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	// From/To 为 RFC3339 或 2006-01-02，To 不含
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// GenerateStatement 按指定期间即时生成对账单
+func (h *AccountHandler) GenerateStatement(c *gin.Context) {
+	var req generateStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, err := parseStatementTime(req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	to, err := parseStatementTime(req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+
+	stmt, err := h.statements.Generate(c.Request.Context(), application.GenerateStatementCommand{
+		UserID:      req.UserID,
+		Frequency:   domain.StatementFrequencyAdhoc,
+		PeriodStart: from,
+		PeriodEnd:   to,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidStatementPeriod) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stmt)
+}
+
+// ListStatements 列出用户对账单
+func (h *AccountHandler) ListStatements(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	list, err := h.statements.List(c.Request.Context(), c.Query("user_id"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"statements": list})
+}
+
+// DownloadStatement 下载对账单文件，format 为 pdf 或 csv
+func (h *AccountHandler) DownloadStatement(c *gin.Context) {
+	id := c.Param("statement_id")
+	format := domain.StatementFormat(strings.ToUpper(c.DefaultQuery("format", "pdf")))
+	data, contentType, err := h.statements.Download(c.Request.Context(), id, c.Query("user_id"), format)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrStatementNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+id+"."+strings.ToLower(string(format)))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func parseStatementTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/middleware"
+)
+
+// principalHeader 网关完成认证后透传的用户标识
+const principalHeader = "X-User-ID"
+
+// principal 返回已认证的操作人：依次取 JWT 中间件注入的用户、请求上下文中的用户与网关透传头，均缺失时返回空串。
+// 审批类接口的操作人只取自认证身份，不接受请求体中的自报值
+func principal(c *gin.Context) string {
+	if id, ok := middleware.GetUserID(c); ok && id != 0 {
+		return strconv.FormatUint(id, 10)
+	}
+	if id := contextx.GetUserID(c.Request.Context()); id != "" {
+		return id
+	}
+	return c.GetHeader(principalHeader)
+}
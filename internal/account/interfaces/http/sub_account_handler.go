@@ -0,0 +1,225 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/account/application"
+	"github.com/wyfcoding/financialtrading/internal/account/domain"
+)
+
+// SubAccountHandler 主子账户、内部划转与合并资金接口
+type SubAccountHandler struct {
+	subs *application.SubAccountService
+}
+
+func NewSubAccountHandler(subs *application.SubAccountService) *SubAccountHandler {
+	return &SubAccountHandler{subs: subs}
+}
+
+func (h *SubAccountHandler) RegisterRoutes(r *gin.RouterGroup) {
+	v1 := r.Group("/v1/account")
+	{
+		v1.POST("/sub-accounts", h.CreateSubAccount)
+		v1.PUT("/sub-accounts/:sub_user_id", h.UpdateSubAccount)
+		v1.POST("/transfers", h.Transfer)
+		v1.GET("/hierarchy/:user_id", h.GetHierarchy)
+		v1.GET("/hierarchy/:user_id/balances", h.ConsolidatedBalance)
+		v1.GET("/hierarchy/:user_id/transfers", h.ListTransfers)
+	}
+}
+
+type subAccountSettings struct {
+	Permissions           []string `json:"permissions"`
+	MarginMode            string   `json:"margin_mode"`
+	MaxOrderAmount        string   `json:"max_order_amount"`
+	DailyTransferOutLimit string   `json:"daily_transfer_out_limit"`
+}
+
+func (s subAccountSettings) parse() ([]domain.SubAccountPermission, decimal.Decimal, decimal.Decimal, error) {
+	perms := make([]domain.SubAccountPermission, len(s.Permissions))
+	for i, p := range s.Permissions {
+		perms[i] = domain.SubAccountPermission(p)
+	}
+	maxOrder, err := optionalDecimal(s.MaxOrderAmount)
+	if err != nil {
+		return nil, decimal.Zero, decimal.Zero, errors.New("invalid max_order_amount")
+	}
+	dailyOut, err := optionalDecimal(s.DailyTransferOutLimit)
+	if err != nil {
+		return nil, decimal.Zero, decimal.Zero, errors.New("invalid daily_transfer_out_limit")
+	}
+	return perms, maxOrder, dailyOut, nil
+}
+
+type createSubAccountRequest struct {
+	MasterUserID string   `json:"master_user_id" binding:"required"`
+	SubUserID    string   `json:"sub_user_id" binding:"required"`
+	Name         string   `json:"name"`
+	Currencies   []string `json:"currencies"`
+	AccountType  string   `json:"account_type"`
+	subAccountSettings
+}
+
+// CreateSubAccount 在主账户下创建子账户
+func (h *SubAccountHandler) CreateSubAccount(c *gin.Context) {
+	var req createSubAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	perms, maxOrder, dailyOut, err := req.parse()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := h.subs.CreateSubAccount(c.Request.Context(), application.CreateSubAccountCommand{
+		MasterUserID:          req.MasterUserID,
+		SubUserID:             req.SubUserID,
+		Name:                  req.Name,
+		Permissions:           perms,
+		MarginMode:            domain.MarginMode(req.MarginMode),
+		MaxOrderAmount:        maxOrder,
+		DailyTransferOutLimit: dailyOut,
+		Currencies:            req.Currencies,
+		AccountType:           domain.AccountType(req.AccountType),
+	})
+	if err != nil {
+		c.JSON(subAccountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+type updateSubAccountRequest struct {
+	Status string `json:"status"`
+	subAccountSettings
+}
+
+// UpdateSubAccount 更新子账户权限、保证金模式、限额与状态
+func (h *SubAccountHandler) UpdateSubAccount(c *gin.Context) {
+	var req updateSubAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	perms, maxOrder, dailyOut, err := req.parse()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := h.subs.UpdateSubAccount(c.Request.Context(), application.UpdateSubAccountCommand{
+		SubUserID:             c.Param("sub_user_id"),
+		Permissions:           perms,
+		MarginMode:            domain.MarginMode(req.MarginMode),
+		MaxOrderAmount:        maxOrder,
+		DailyTransferOutLimit: dailyOut,
+		Status:                domain.SubAccountStatus(req.Status),
+	})
+	if err != nil {
+		c.JSON(subAccountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+type transferRequest struct {
+	FromUserID string `json:"from_user_id" binding:"required"`
+	ToUserID   string `json:"to_user_id" binding:"required"`
+	Currency   string `json:"currency" binding:"required"`
+	Amount     string `json:"amount" binding:"required"`
+	Operator   string `json:"operator"`
+	Memo       string `json:"memo"`
+}
+
+// Transfer 同一主账户下账户之间即时划转
+func (h *SubAccountHandler) Transfer(c *gin.Context) {
+	var req transferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+		return
+	}
+	transfer, err := h.subs.Transfer(c.Request.Context(), application.TransferCommand{
+		FromUserID: req.FromUserID,
+		ToUserID:   req.ToUserID,
+		Currency:   req.Currency,
+		Amount:     amount,
+		Operator:   req.Operator,
+		Memo:       req.Memo,
+	})
+	if err != nil {
+		c.JSON(subAccountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, transfer)
+}
+
+// GetHierarchy 查询用户所在层级
+func (h *SubAccountHandler) GetHierarchy(c *gin.Context) {
+	hierarchy, err := h.subs.GetHierarchy(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hierarchy)
+}
+
+// ConsolidatedBalance 主账户层面的合并资金（currency 必填）
+func (h *SubAccountHandler) ConsolidatedBalance(c *gin.Context) {
+	currency := c.Query("currency")
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency is required"})
+		return
+	}
+	balance, err := h.subs.ConsolidatedBalance(c.Request.Context(), c.Param("user_id"), currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+// ListTransfers 层级内的划转记录
+func (h *SubAccountHandler) ListTransfers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	transfers, err := h.subs.ListTransfers(c.Request.Context(), c.Param("user_id"), limit, max(offset, 0))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+// subAccountErrorStatus 子账户错误映射为 HTTP 状态码
+func subAccountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSubAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrSubAccountPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrSubAccountLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrInvalidHierarchy), errors.Is(err, domain.ErrNotInSameHierarchy):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func optionalDecimal(v string) (decimal.Decimal, error) {
+	if v == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(v)
+}
@@ -52,7 +52,7 @@ func (s *AMLService) MonitorTransaction(ctx context.Context, req *pb.MonitorTran
 	return &pb.MonitorTransactionResponse{
 		IsSuspicious: isSuspicious,
 		AlertId:      alertID,
-		RiskLevel:    riskLevel,
+		RiskLevel:    toPBRiskLevel(riskLevel),
 	}, nil
 }
 
@@ -65,13 +65,13 @@ func (s *AMLService) GetRiskScore(ctx context.Context, userID string) (*pb.GetRi
 		return &pb.GetRiskScoreResponse{
 			UserId:    userID,
 			Score:     0,
-			RiskLevel: "LOW",
+			RiskLevel: pb.RiskLevel_RISK_LEVEL_LOW,
 		}, nil
 	}
 	return &pb.GetRiskScoreResponse{
 		UserId:    score.UserID,
 		Score:     score.Score,
-		RiskLevel: normalizeRiskLevel(score.RiskLevel),
+		RiskLevel: toPBRiskLevel(normalizeRiskLevel(score.RiskLevel)),
 	}, nil
 }
 
@@ -81,22 +81,24 @@ func (s *AMLService) ListAlerts(ctx context.Context, status string) (*pb.ListAle
 		return nil, err
 	}
 
-	items := make([]*pb.AlertItem, 0, len(alerts))
+	items := make([]*pb.AMLAlert, 0, len(alerts))
 	for _, a := range alerts {
 		if a == nil {
 			continue
 		}
-		items = append(items, &pb.AlertItem{
+		items = append(items, &pb.AMLAlert{
 			AlertId:     a.AlertID,
 			UserId:      a.UserID,
-			Type:        a.Type,
+			Title:       a.Title,
 			Description: a.Description,
-			Status:      a.Status,
+			Status:      pb.AlertStatus(pb.AlertStatus_value["ALERT_STATUS_"+strings.ToUpper(a.Status)]),
+			RiskLevel:   toPBRiskLevel(normalizeRiskLevel(a.RiskLevel)),
 			CreatedAt:   timestamppb.New(a.CreatedAt),
+			UpdatedAt:   timestamppb.New(a.UpdatedAt),
 		})
 	}
 
-	return &pb.ListAlertsResponse{Alerts: items}, nil
+	return &pb.ListAlertsResponse{Alerts: items, Total: int32(len(items))}, nil
 }
 
 func classifyRisk(amount string) string {
@@ -124,3 +126,11 @@ func normalizeRiskLevel(level string) string {
 		return "LOW"
 	}
 }
+
+// toPBRiskLevel 把内部风险等级（LOW/MEDIUM/HIGH/CRITICAL）映射为协议枚举
+func toPBRiskLevel(level string) pb.RiskLevel {
+	if v, ok := pb.RiskLevel_value["RISK_LEVEL_"+level]; ok {
+		return pb.RiskLevel(v)
+	}
+	return pb.RiskLevel_RISK_LEVEL_UNSPECIFIED
+}
@@ -24,18 +24,33 @@ func (BenchmarkIndex) TableName() string { return "bm_indices" }
 // IndexConstituent 指数成分股
 type IndexConstituent struct {
 	gorm.Model
-	IndexSymbol string          `gorm:"column:index_symbol;index;not null"`
-	StockSymbol string          `gorm:"column:stock_symbol;index;not null"`
-	Weight      decimal.Decimal `gorm:"column:weight;type:decimal(10,8);not null"` // 权重
-	EffectiveDate time.Time     `gorm:"column:effective_date;index;not null"`      // 生效日期
+	IndexSymbol   string          `gorm:"column:index_symbol;index;not null"`
+	StockSymbol   string          `gorm:"column:stock_symbol;index;not null"`
+	Weight        decimal.Decimal `gorm:"column:weight;type:decimal(10,8);not null"` // 权重
+	EffectiveDate time.Time       `gorm:"column:effective_date;index;not null"`      // 生效日期
 }
 
 func (IndexConstituent) TableName() string { return "bm_constituents" }
 
+// IndexLevel 指数日收盘点位（总收益口径）
+type IndexLevel struct {
+	gorm.Model
+	IndexSymbol string          `gorm:"column:index_symbol;type:varchar(32);uniqueIndex:idx_bm_level_symbol_date;not null"`
+	LevelDate   time.Time       `gorm:"column:level_date;type:date;uniqueIndex:idx_bm_level_symbol_date;not null"`
+	Close       decimal.Decimal `gorm:"column:close;type:decimal(20,8);not null"`
+}
+
+func (IndexLevel) TableName() string { return "bm_index_levels" }
+
 type BenchmarkRepository interface {
 	SaveIndex(ctx context.Context, idx *BenchmarkIndex) error
 	GetIndex(ctx context.Context, symbol string) (*BenchmarkIndex, error)
-	
+
 	SaveConstituents(ctx context.Context, constituents []IndexConstituent) error
 	GetConstituents(ctx context.Context, indexSymbol string, date time.Time) ([]IndexConstituent, error)
+
+	// SaveLevels 写入指数点位，同一日期已存在时覆盖
+	SaveLevels(ctx context.Context, levels []IndexLevel) error
+	// GetLevels 按日期升序返回 [start, end] 内的指数点位
+	GetLevels(ctx context.Context, indexSymbol string, start, end time.Time) ([]IndexLevel, error)
 }
@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/benchmark/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type benchmarkRepository struct {
+	db *gorm.DB
+}
+
+// NewBenchmarkRepository 创建基准指数仓储
+func NewBenchmarkRepository(db *gorm.DB) domain.BenchmarkRepository {
+	return &benchmarkRepository{db: db}
+}
+
+func (r *benchmarkRepository) SaveIndex(ctx context.Context, idx *domain.BenchmarkIndex) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "currency", "description", "source", "updated_at"}),
+	}).Create(idx).Error
+}
+
+func (r *benchmarkRepository) GetIndex(ctx context.Context, symbol string) (*domain.BenchmarkIndex, error) {
+	var idx domain.BenchmarkIndex
+	if err := r.db.WithContext(ctx).Where("symbol = ?", symbol).First(&idx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &idx, nil
+}
+
+func (r *benchmarkRepository) SaveConstituents(ctx context.Context, constituents []domain.IndexConstituent) error {
+	if len(constituents) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&constituents).Error
+}
+
+// GetConstituents 返回 date 当日生效的成分股，即生效日期不晚于 date 的最近一期
+func (r *benchmarkRepository) GetConstituents(ctx context.Context, indexSymbol string, date time.Time) ([]domain.IndexConstituent, error) {
+	row := r.db.WithContext(ctx).Model(&domain.IndexConstituent{}).
+		Where("index_symbol = ? AND effective_date <= ?", indexSymbol, date).
+		Select("MAX(effective_date)").Row()
+	var latest *time.Time
+	if err := row.Scan(&latest); err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	var constituents []domain.IndexConstituent
+	err := r.db.WithContext(ctx).
+		Where("index_symbol = ? AND effective_date = ?", indexSymbol, *latest).
+		Order("weight DESC").
+		Find(&constituents).Error
+	return constituents, err
+}
+
+func (r *benchmarkRepository) SaveLevels(ctx context.Context, levels []domain.IndexLevel) error {
+	if len(levels) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "index_symbol"}, {Name: "level_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"close", "updated_at"}),
+	}).CreateInBatches(&levels, 500).Error
+}
+
+func (r *benchmarkRepository) GetLevels(ctx context.Context, indexSymbol string, start, end time.Time) ([]domain.IndexLevel, error) {
+	var levels []domain.IndexLevel
+	err := r.db.WithContext(ctx).
+		Where("index_symbol = ? AND level_date >= ? AND level_date <= ?", indexSymbol, start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Order("level_date ASC").
+		Find(&levels).Error
+	return levels, err
+}
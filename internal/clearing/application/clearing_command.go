@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -82,12 +83,26 @@ func (s *AddSellStep) Compensate(ctx context.Context) error {
 	return nil
 }
 
+const (
+	// settlementCalendarAttempts 结算日历调用的最大尝试次数
+	settlementCalendarAttempts = 3
+	// settlementCalendarBackoff 结算日历重试的退避基数，第 n 次重试等待 n 倍
+	settlementCalendarBackoff = 200 * time.Millisecond
+)
+
 // ClearingCommandService 处理清算相关的写操作。
 type ClearingCommandService struct {
 	repo          domain.SettlementRepository
 	redisRepo     domain.MarginRedisRepository
 	publisher     messagequeue.EventPublisher
 	accountClient accountv1.AccountServiceClient
+	eod           *EODClearingService
+	contracts     domain.CCPContractRepository
+	dvp           *DVPService
+
+	calendar       domain.SettlementCalendar
+	calendarMarket string
+	settleDays     int
 }
 
 func NewClearingCommandService(
@@ -114,12 +129,23 @@ func (s *ClearingCommandService) SettleTrade(ctx context.Context, req *SettleTra
 
 	settlementID := fmt.Sprintf("SET-%d", idgen.GenID())
 	settlement := domain.NewSettlement(settlementID, req.TradeID, req.BuyUserID, req.SellUserID, req.Symbol, req.Currency, req.Quantity, req.Price)
+	settlement.SettlementDate = s.settlementDate(ctx, req.TradeID, req.Currency, settlement.SettlementDate)
 
 	// 本地事务：保存 Settlement 并发送 Saga 开始事件
 	err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
 		if err := s.repo.Save(txCtx, settlement); err != nil {
 			return err
 		}
+		if s.contracts != nil {
+			// 合同替代：清算所成为买卖双方的共同对手方
+			contracts, err := domain.NewClearingHouse(req.Symbol).NovateContracts(settlement)
+			if err != nil {
+				return err
+			}
+			if err := s.contracts.SaveContracts(txCtx, contracts); err != nil {
+				return err
+			}
+		}
 
 		if s.publisher == nil {
 			return nil
@@ -145,6 +171,23 @@ func (s *ClearingCommandService) SettleTrade(ctx context.Context, req *SettleTra
 
 // executeSaga 使用自定义 Saga 协调器执行清算流程
 func (s *ClearingCommandService) executeSaga(ctx context.Context, settlement *domain.Settlement) {
+	if s.dvp != nil {
+		// 先认领结算单，避免日终清算同时将其纳入净额交收；DVP 终态由 SetDVPService 注册的回调回写
+		claimed, err := s.repo.ClaimForDVP(ctx, settlement.SettlementID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to claim settlement for dvp", "settlement_id", settlement.SettlementID, "error", err)
+			return
+		}
+		if !claimed {
+			slog.InfoContext(ctx, "settlement already claimed, skipping dvp", "settlement_id", settlement.SettlementID)
+			return
+		}
+		settlement.Status = domain.StatusDVPInProgress
+		if _, err := s.dvp.Settle(ctx, settlement); err != nil {
+			slog.WarnContext(ctx, "dvp settlement not completed", "settlement_id", settlement.SettlementID, "error", err)
+		}
+		return
+	}
 	slog.InfoContext(ctx, "starting settlement saga with coordinator", "settlement_id", settlement.SettlementID)
 
 	saga := transaction.NewSagaCoordinator()
@@ -177,6 +220,11 @@ func (s *ClearingCommandService) markCompleted(ctx context.Context, id string) {
 		if err := s.repo.Save(txCtx, settlement); err != nil {
 			return err
 		}
+		if s.contracts != nil {
+			if err := s.contracts.UpdateStatusByOriginal(txCtx, settlement.SettlementID, domain.ContractSettled); err != nil {
+				return err
+			}
+		}
 		if s.publisher == nil {
 			return nil
 		}
@@ -225,35 +273,69 @@ func (s *ClearingCommandService) SagaMarkSettlementFailed(ctx context.Context, s
 	return nil
 }
 
-// ExecuteEODClearing 执行日终清算
-func (s *ClearingCommandService) ExecuteEODClearing(ctx context.Context, clearingDate string) (string, error) {
-	clearingID := fmt.Sprintf("EOD-%s-%d", clearingDate, idgen.GenID())
-	slog.InfoContext(ctx, "EOD clearing started", "clearing_id", clearingID, "date", clearingDate)
+// SetCCPContractRepository 注入 CCP 合同仓储，注入后结算单创建时执行合同替代
+func (s *ClearingCommandService) SetCCPContractRepository(contracts domain.CCPContractRepository) {
+	s.contracts = contracts
+}
 
-	settlements, err := s.repo.List(ctx, 1000)
-	if err != nil {
-		return "", err
+// settlementDate 按结算日历计算 T+N 预定结算日，日历服务调用失败时重试；
+// 仍失败或未配置日历时回退为跳过周末的 T+N，不会退化为成交日
+func (s *ClearingCommandService) settlementDate(ctx context.Context, tradeID, currency string, tradeDate time.Time) time.Time {
+	if s.calendar == nil {
+		return domain.WeekdaySettlementDate(tradeDate, s.settleDays)
 	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		var date time.Time
+		if date, err = s.calendar.SettlementDate(ctx, s.calendarMarket, currency, tradeDate, s.settleDays); err == nil {
+			return date
+		}
+		if attempt == settlementCalendarAttempts || ctx.Err() != nil {
+			break
+		}
+		timer := time.NewTimer(time.Duration(attempt) * settlementCalendarBackoff)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	fallback := domain.WeekdaySettlementDate(tradeDate, s.settleDays)
+	slog.WarnContext(ctx, "settlement calendar unavailable, using weekday-only settlement date",
+		"trade_id", tradeID, "settlement_date", fallback.Format(domain.ClearingDateLayout), "error", err)
+	return fallback
+}
 
-	var totalVolume decimal.Decimal
-	var totalFee decimal.Decimal
-	userNetting := make(map[string]decimal.Decimal)
-
-	for _, st := range settlements {
-		totalVolume = totalVolume.Add(st.TotalAmount)
-		totalFee = totalFee.Add(st.Fee)
+// SetSettlementCalendar 注入结算日历，结算单按 market 与结算币种的工作日计算 T+days 预定结算日
+func (s *ClearingCommandService) SetSettlementCalendar(calendar domain.SettlementCalendar, market string, days int) {
+	s.calendar = calendar
+	s.calendarMarket = market
+	s.settleDays = days
+}
 
-		userNetting[st.BuyUserID] = userNetting[st.BuyUserID].Sub(st.TotalAmount)
-		userNetting[st.SellUserID] = userNetting[st.SellUserID].Add(st.TotalAmount)
-	}
+// SetDVPService 启用银货对付交收：结算单改由托管锁券与账户冻款的 DVP 流程完成，交收终态回写结算单
+func (s *ClearingCommandService) SetDVPService(dvp *DVPService) {
+	s.dvp = dvp
+	dvp.SetFinalHandler(func(ctx context.Context, d *domain.DVPSettlement) {
+		if d.Status == domain.DVPStatusSettled {
+			s.markCompleted(ctx, d.SettlementID)
+			return
+		}
+		s.markFailed(ctx, d.SettlementID, d.FailReason)
+	})
+}
 
-	slog.InfoContext(ctx, "EOD Summary",
-		"clearing_id", clearingID,
-		"total_volume", totalVolume.String(),
-		"total_fee", totalFee.String(),
-		"users_count", len(userNetting))
+// SetEODClearingService 注入日终清算服务
+func (s *ClearingCommandService) SetEODClearingService(eod *EODClearingService) {
+	s.eod = eod
+}
 
-	return clearingID, nil
+// ExecuteEODClearing 触发日终清算，同一清算日重复触发返回同一任务
+func (s *ClearingCommandService) ExecuteEODClearing(ctx context.Context, clearingDate string) (*ClearingRunDTO, error) {
+	if s.eod == nil {
+		return nil, errors.New("eod clearing is not configured")
+	}
+	return s.eod.Trigger(ctx, clearingDate)
 }
 
 // RunLiquidationCheck 对指定用户执行强平核查
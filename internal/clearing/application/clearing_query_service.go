@@ -35,6 +35,7 @@ type ClearingQueryService struct {
 	searchRepo     domain.SettlementSearchRepository
 	marginRepo     domain.MarginRedisRepository
 	settleReadRepo domain.SettlementReadRepository
+	runRepo        domain.ClearingRunRepository
 }
 
 func NewClearingQueryService(
@@ -70,8 +71,21 @@ func (q *ClearingQueryService) GetSettlement(ctx context.Context, id string) (*S
 	return q.toDTO(agg), nil
 }
 
-func (q *ClearingQueryService) GetClearingStatus(ctx context.Context, id string) (*SettlementDTO, error) {
-	return q.GetSettlement(ctx, id)
+// SetClearingRunRepository 注入日终清算任务仓储
+func (q *ClearingQueryService) SetClearingRunRepository(runs domain.ClearingRunRepository) {
+	q.runRepo = runs
+}
+
+// GetClearingStatus 查询日终清算任务状态，不存在时返回 nil
+func (q *ClearingQueryService) GetClearingStatus(ctx context.Context, id string) (*ClearingRunDTO, error) {
+	if q.runRepo == nil {
+		return nil, nil
+	}
+	run, err := q.runRepo.GetRun(ctx, id)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	return toClearingRunDTO(run), nil
 }
 
 func (q *ClearingQueryService) ListSettlements(ctx context.Context, userID, symbol string, limit, offset int) ([]*SettlementDTO, int64, error) {
@@ -0,0 +1,427 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+const (
+	defaultAuctionWindow   = 30 * time.Minute
+	defaultSweepInterval   = 30 * time.Second
+	defaultCaseSweepLimit  = 50
+	defaultReportCaseLimit = 100
+)
+
+// DeclareDefaultCommand 宣告会员违约
+type DeclareDefaultCommand struct {
+	UserID string                `json:"user_id" binding:"required"`
+	Reason string                `json:"reason"`
+	Amount decimal.Decimal       `json:"amount"`
+	Method domain.CloseOutMethod `json:"method"`
+}
+
+// SubmitAuctionBidCommand 提交违约拍卖报价
+type SubmitAuctionBidCommand struct {
+	LotID  string          `json:"lot_id" binding:"required"`
+	Bidder string          `json:"bidder" binding:"required"`
+	Price  decimal.Decimal `json:"price"`
+}
+
+// DefaultManagementService 违约管理：会员违约后了结其 CCP 合同（拍卖或对冲），
+// 再按瀑布顺序分摊损失并向清算会员发送报告。
+type DefaultManagementService struct {
+	repo      domain.DefaultManagementRepository
+	contracts domain.CCPContractRepository
+	prices    domain.CloseOutPriceSource
+	publisher messagequeue.EventPublisher
+	logger    *slog.Logger
+
+	auctionWindow time.Duration
+	mu            sync.Mutex
+}
+
+// NewDefaultManagementService 创建违约管理服务
+func NewDefaultManagementService(
+	repo domain.DefaultManagementRepository,
+	contracts domain.CCPContractRepository,
+	prices domain.CloseOutPriceSource,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+) *DefaultManagementService {
+	return &DefaultManagementService{
+		repo:          repo,
+		contracts:     contracts,
+		prices:        prices,
+		publisher:     publisher,
+		logger:        logger,
+		auctionWindow: defaultAuctionWindow,
+	}
+}
+
+// SetAuctionWindow 设置违约拍卖窗口
+func (s *DefaultManagementService) SetAuctionWindow(window time.Duration) {
+	if window > 0 {
+		s.auctionWindow = window
+	}
+}
+
+// Start 周期性了结拍卖到期的违约案例，直到 ctx 结束
+func (s *DefaultManagementService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(defaultSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.ProcessDueCases(ctx)
+		}
+	}
+}
+
+// DeclareDefault 宣告会员违约：冻结其未了结的 CCP 合同并开启处置。
+// record 可直接来自 SettlementRiskService.HandleDefault；对冲方式立即平仓并分摊损失。
+func (s *DefaultManagementService) DeclareDefault(ctx context.Context, record *domain.DefaultRecord, method domain.CloseOutMethod) (*domain.DefaultCase, error) {
+	if record == nil || record.UserID == "" {
+		return nil, errors.New("default record is required")
+	}
+	if record.UserID == domain.ClearingHouseUserID {
+		return nil, errors.New("clearing house cannot be declared in default")
+	}
+	switch method {
+	case "":
+		method = domain.CloseOutAuction
+	case domain.CloseOutAuction, domain.CloseOutHedge:
+	default:
+		return nil, fmt.Errorf("unsupported close-out method %q", method)
+	}
+
+	var dc *domain.DefaultCase
+	err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		active, err := s.repo.GetActiveCase(txCtx, record.UserID)
+		if err != nil {
+			return err
+		}
+		if active != nil {
+			return fmt.Errorf("%w: %s (case %s)", domain.ErrMemberAlreadyInDefault, record.UserID, active.CaseID)
+		}
+		contracts, err := s.contracts.ListOpenByCounterparty(txCtx, record.UserID)
+		if err != nil {
+			return err
+		}
+
+		dc = domain.NewDefaultCase(fmt.Sprintf("DEF-%d", idgen.GenID()), record, contracts, method, s.auctionWindow)
+		if err := s.repo.SaveCase(txCtx, dc); err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(contracts))
+		for _, c := range contracts {
+			ids = append(ids, c.ContractID)
+		}
+		if err := s.contracts.UpdateStatus(txCtx, ids, domain.ContractDefaulted); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		event := domain.DefaultDeclaredEvent{
+			BaseEvent: domain.BaseEvent{Timestamp: time.Now()},
+			CaseID:    dc.CaseID,
+			UserID:    dc.UserID,
+			Reason:    dc.Reason,
+			Method:    string(dc.Method),
+			Lots:      len(dc.Lots),
+			Contracts: len(contracts),
+		}
+		return s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.DefaultDeclaredEventType, dc.CaseID, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WarnContext(ctx, "clearing member declared in default", "case_id", dc.CaseID, "user_id", dc.UserID, "method", dc.Method, "lots", len(dc.Lots))
+
+	if dc.ReadyToClose(time.Now()) {
+		if err := s.closeOut(ctx, dc.CaseID); err != nil {
+			// 案例已落库，对冲失败时由周期任务重试
+			s.logger.ErrorContext(ctx, "default close-out failed", "case_id", dc.CaseID, "error", err)
+		}
+		return s.repo.GetCase(ctx, dc.CaseID)
+	}
+	return dc, nil
+}
+
+// SubmitBid 存续会员在拍卖窗口内对违约批次报价
+func (s *DefaultManagementService) SubmitBid(ctx context.Context, caseID string, cmd *SubmitAuctionBidCommand) (*domain.AuctionBid, error) {
+	if !cmd.Price.IsPositive() {
+		return nil, errors.New("bid price must be positive")
+	}
+	dc, err := s.repo.GetCase(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if dc == nil {
+		return nil, domain.ErrDefaultCaseNotFound
+	}
+	if !dc.AcceptsBids(time.Now()) {
+		return nil, domain.ErrAuctionClosed
+	}
+	lot := dc.Lot(cmd.LotID)
+	if lot == nil || lot.Closed() {
+		return nil, fmt.Errorf("%w: lot %s", domain.ErrAuctionClosed, cmd.LotID)
+	}
+	if cmd.Bidder == dc.UserID || cmd.Bidder == domain.ClearingHouseUserID {
+		return nil, domain.ErrIneligibleBidder
+	}
+	member, err := s.repo.GetCollateral(ctx, cmd.Bidder, lot.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, domain.ErrIneligibleBidder
+	}
+
+	bid := &domain.AuctionBid{
+		BidID:  fmt.Sprintf("BID-%d", idgen.GenID()),
+		CaseID: caseID,
+		LotID:  lot.LotID,
+		Bidder: cmd.Bidder,
+		Price:  cmd.Price,
+	}
+	if err := s.repo.SaveBid(ctx, bid); err != nil {
+		return nil, err
+	}
+	return bid, nil
+}
+
+// CloseOut 立即了结案例，不再等待拍卖窗口结束
+func (s *DefaultManagementService) CloseOut(ctx context.Context, caseID string) (*domain.DefaultCase, error) {
+	if err := s.closeOut(ctx, caseID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCase(ctx, caseID)
+}
+
+// ProcessDueCases 了结拍卖窗口已结束的案例
+func (s *DefaultManagementService) ProcessDueCases(ctx context.Context) {
+	cases, err := s.repo.ListCases(ctx, domain.DefaultCaseClosingOut, defaultCaseSweepLimit)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list open default cases", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, dc := range cases {
+		if !dc.ReadyToClose(now) {
+			continue
+		}
+		if err := s.closeOut(ctx, dc.CaseID); err != nil {
+			s.logger.ErrorContext(ctx, "default close-out failed", "case_id", dc.CaseID, "error", err)
+		}
+	}
+}
+
+// closeOut 平仓全部批次后按瀑布分摊损失。对冲报价在事务外获取，
+// 任一批次取价失败则整体放弃本次处理，案例保持原状待下次重试。
+func (s *DefaultManagementService) closeOut(ctx context.Context, caseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dc, err := s.repo.GetCase(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if dc == nil {
+		return domain.ErrDefaultCaseNotFound
+	}
+	if dc.Status == domain.DefaultCaseCompleted {
+		return nil
+	}
+
+	var bids []*domain.AuctionBid
+	if dc.Method == domain.CloseOutAuction {
+		if bids, err = s.repo.ListBids(ctx, caseID); err != nil {
+			return err
+		}
+	}
+	var replacements []*domain.CCPContract
+	for _, lot := range dc.Lots {
+		if lot.Closed() {
+			continue
+		}
+		if dc.Method == domain.CloseOutAuction && dc.AwardAuction(lot, bids) {
+			replacements = append(replacements, replacementContract(lot))
+			continue
+		}
+		if s.prices == nil {
+			return fmt.Errorf("no close-out price source for lot %s", lot.LotID)
+		}
+		price, err := s.prices.ClosePrice(ctx, lot.Symbol, lot.NetQuantity.IsPositive())
+		if err != nil {
+			return fmt.Errorf("close-out price for %s: %w", lot.Symbol, err)
+		}
+		lot.Close(domain.CloseOutHedge, price, "")
+	}
+
+	var reports []*domain.MemberDefaultReport
+	err = s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		all, err := s.repo.ListCollateral(txCtx, "")
+		if err != nil {
+			return err
+		}
+		collateral := make(map[string][]*domain.MemberCollateral)
+		members := make([]string, 0, len(all))
+		for _, mc := range all {
+			collateral[mc.Currency] = append(collateral[mc.Currency], mc)
+			members = append(members, mc.UserID)
+		}
+		dc.ApplyWaterfall(collateral)
+		dc.Complete()
+
+		for _, step := range dc.Steps {
+			if !step.Applied.IsPositive() {
+				continue
+			}
+			for _, charge := range step.Charges {
+				for _, mc := range collateral[step.Currency] {
+					if mc.UserID == charge.UserID {
+						if err := s.repo.SaveCollateral(txCtx, mc); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		if err := s.repo.SaveCase(txCtx, dc); err != nil {
+			return err
+		}
+		if err := s.contracts.SaveContracts(txCtx, replacements); err != nil {
+			return err
+		}
+
+		reports = dc.MemberReports(members)
+		if s.publisher == nil {
+			return nil
+		}
+		tx := contextx.GetTx(txCtx)
+		event := domain.DefaultCaseCompletedEvent{
+			BaseEvent: domain.BaseEvent{Timestamp: time.Now()},
+			CaseID:    dc.CaseID,
+			UserID:    dc.UserID,
+			TotalLoss: dc.TotalLoss,
+			Uncovered: dc.Uncovered,
+			Steps:     dc.Steps,
+		}
+		if err := s.publisher.PublishInTx(ctx, tx, domain.DefaultCaseCompletedEventType, dc.CaseID, event); err != nil {
+			return err
+		}
+		for _, report := range reports {
+			if err := s.publisher.PublishInTx(ctx, tx, domain.DefaultMemberReportEventType, report.UserID, report); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for currency, remaining := range dc.Uncovered {
+		s.logger.ErrorContext(ctx, "default waterfall exhausted", "case_id", dc.CaseID, "currency", currency, "uncovered", remaining.String())
+	}
+	s.logger.InfoContext(ctx, "default case completed", "case_id", dc.CaseID, "user_id", dc.UserID, "steps", len(dc.Steps), "reports", len(reports))
+	return nil
+}
+
+// replacementContract 中标会员接手违约批次净头寸，与清算所形成新的 CCP 合同
+func replacementContract(lot *domain.CloseOutLot) *domain.CCPContract {
+	side := "BUY"
+	if lot.NetQuantity.IsNegative() {
+		side = "SELL"
+	}
+	qty := lot.NetQuantity.Abs()
+	return &domain.CCPContract{
+		ContractID:   fmt.Sprintf("CCP-%s-%s", lot.LotID, lot.Winner),
+		OriginalID:   lot.LotID,
+		Counterparty: lot.Winner,
+		Symbol:       lot.Symbol,
+		Currency:     lot.Currency,
+		Side:         side,
+		Quantity:     qty,
+		Price:        lot.ClosePrice,
+		TotalAmount:  qty.Mul(lot.ClosePrice),
+		Status:       domain.ContractOpen,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// GetCase 查询违约案例
+func (s *DefaultManagementService) GetCase(ctx context.Context, caseID string) (*domain.DefaultCase, error) {
+	return s.repo.GetCase(ctx, caseID)
+}
+
+// ListCases 按状态查询违约案例，status 为空时返回全部
+func (s *DefaultManagementService) ListCases(ctx context.Context, status domain.DefaultCaseStatus, limit int) ([]*domain.DefaultCase, error) {
+	if limit <= 0 || limit > defaultReportCaseLimit {
+		limit = defaultReportCaseLimit
+	}
+	return s.repo.ListCases(ctx, status, limit)
+}
+
+// ListBids 查询案例的拍卖报价
+func (s *DefaultManagementService) ListBids(ctx context.Context, caseID string) ([]*domain.AuctionBid, error) {
+	return s.repo.ListBids(ctx, caseID)
+}
+
+// MemberReports 查询已完成案例的会员报告，userID 为空时返回全部会员
+func (s *DefaultManagementService) MemberReports(ctx context.Context, caseID, userID string) ([]*domain.MemberDefaultReport, error) {
+	dc, err := s.repo.GetCase(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if dc == nil {
+		return nil, domain.ErrDefaultCaseNotFound
+	}
+	all, err := s.repo.ListCollateral(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(all))
+	for _, mc := range all {
+		members = append(members, mc.UserID)
+	}
+	reports := dc.MemberReports(members)
+	if userID == "" {
+		return reports, nil
+	}
+	for _, r := range reports {
+		if r.UserID == userID {
+			return []*domain.MemberDefaultReport{r}, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveCollateral 登记会员保证金与违约基金份额；清算所风险准备金以 CLEARING_HOUSE 登记
+func (s *DefaultManagementService) SaveCollateral(ctx context.Context, collateral *domain.MemberCollateral) error {
+	if collateral.UserID == "" || collateral.Currency == "" {
+		return errors.New("user_id and currency are required")
+	}
+	if collateral.Margin.IsNegative() || collateral.DefaultFund.IsNegative() {
+		return errors.New("collateral amounts must not be negative")
+	}
+	return s.repo.SaveCollateral(ctx, collateral)
+}
+
+// ListCollateral 查询会员抵押品
+func (s *DefaultManagementService) ListCollateral(ctx context.Context, currency string) ([]*domain.MemberCollateral, error) {
+	return s.repo.ListCollateral(ctx, currency)
+}
@@ -0,0 +1,358 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/transaction"
+)
+
+const (
+	dvpSweepInterval = 10 * time.Second
+	// dvpExpiryGrace 巡检只处理超过期限一段时间的交收，避免与正在提交的流程竞争
+	dvpExpiryGrace = 5 * time.Second
+	dvpLegRetries  = 3
+	dvpRetryDelay  = 200 * time.Millisecond
+)
+
+// DVPService 银货对付交收：先在托管服务锁券、在账户服务冻款，两腿均锁定且未超期才提交划转。
+// 锁定阶段任一腿失败或超时即释放两腿；提交后的划转只重试、不补偿，未完成的由巡检继续推进。
+type DVPService struct {
+	repo   domain.DVPRepository
+	engine *domain.DVPEngine
+	assets domain.DVPAssetLeg
+	cash   domain.DVPCashLeg
+	logger *slog.Logger
+
+	onFinal func(ctx context.Context, dvp *domain.DVPSettlement)
+
+	mu sync.Mutex
+}
+
+// NewDVPService 创建 DVP 交收服务，lockTimeout 为锁定期限
+func NewDVPService(repo domain.DVPRepository, assets domain.DVPAssetLeg, cash domain.DVPCashLeg, lockTimeout time.Duration, logger *slog.Logger) *DVPService {
+	return &DVPService{
+		repo:   repo,
+		engine: domain.NewDVPEngine(lockTimeout),
+		assets: assets,
+		cash:   cash,
+		logger: logger,
+	}
+}
+
+// SetFinalHandler 设置交收进入终态（SETTLED / FAILED）时的回调
+func (s *DVPService) SetFinalHandler(fn func(ctx context.Context, dvp *domain.DVPSettlement)) {
+	s.onFinal = fn
+}
+
+// Start 周期性巡检未完成的交收，直到 ctx 结束
+func (s *DVPService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(dvpSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.Sweep(ctx); err != nil {
+				s.logger.ErrorContext(ctx, "dvp sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Settle 对结算单执行 DVP 交收。同一结算单重复调用时继续推进已有交收。
+func (s *DVPService) Settle(ctx context.Context, settlement *domain.Settlement) (*domain.DVPSettlement, error) {
+	dvp, err := s.repo.Get(ctx, "DVP-"+settlement.SettlementID)
+	switch {
+	case errors.Is(err, domain.ErrDVPNotFound):
+		dvp = s.engine.InitiateDVP(settlement)
+		if err := s.repo.Save(ctx, dvp); err != nil {
+			return nil, fmt.Errorf("failed to save dvp: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	if dvp.Status.Locking() {
+		if err := s.lock(ctx, dvp); err != nil {
+			return dvp, err
+		}
+	}
+	if dvp.Status == domain.DVPStatusSettling {
+		if err := s.deliver(ctx, dvp); err != nil {
+			return dvp, err
+		}
+	}
+	return dvp, nil
+}
+
+// lock 锁定阶段：锁券、冻款、提交三步组成 Saga，失败时逆序释放已锁定的腿
+func (s *DVPService) lock(ctx context.Context, dvp *domain.DVPSettlement) error {
+	saga := transaction.NewSagaCoordinator()
+	saga.AddStep(&dvpLockAssetStep{
+		BaseStep: transaction.BaseStep{StepName: "LockAsset"},
+		svc:      s,
+		dvp:      dvp,
+	}).AddStep(&dvpFreezeFundStep{
+		BaseStep: transaction.BaseStep{StepName: "FreezeFund"},
+		svc:      s,
+		dvp:      dvp,
+	}).AddStep(&dvpCommitStep{
+		BaseStep: transaction.BaseStep{StepName: "Commit"},
+		svc:      s,
+		dvp:      dvp,
+	})
+
+	err := saga.Execute(ctx)
+	if err == nil {
+		s.logger.InfoContext(ctx, "dvp legs locked and committed", "dvp_id", dvp.DVPID)
+		return nil
+	}
+	if dvp.Status == domain.DVPStatusSettling {
+		// 提交已落库，之后的错误不再触发失败
+		return nil
+	}
+	s.fail(ctx, dvp, err.Error())
+	return err
+}
+
+// legContext 锁定调用受锁定期限约束
+func legContext(ctx context.Context, dvp *domain.DVPSettlement) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, dvp.Deadline)
+}
+
+type dvpLockAssetStep struct {
+	transaction.BaseStep
+	svc *DVPService
+	dvp *domain.DVPSettlement
+}
+
+func (st *dvpLockAssetStep) Execute(ctx context.Context) error {
+	if st.dvp.AssetLocked {
+		return nil
+	}
+	legCtx, cancel := legContext(ctx, st.dvp)
+	defer cancel()
+	if err := st.svc.assets.Lock(legCtx, st.dvp); err != nil {
+		// 调用结果未知（如超时）时锁定可能已生效，本步骤也需释放
+		st.svc.releaseAsset(ctx, st.dvp)
+		return fmt.Errorf("asset lock: %w", err)
+	}
+	if err := st.svc.engine.LockAsset(st.dvp, time.Now()); err != nil {
+		st.svc.releaseAsset(ctx, st.dvp)
+		return err
+	}
+	return st.svc.repo.Save(ctx, st.dvp)
+}
+
+func (st *dvpLockAssetStep) Compensate(ctx context.Context) error {
+	st.svc.releaseAsset(ctx, st.dvp)
+	return nil
+}
+
+type dvpFreezeFundStep struct {
+	transaction.BaseStep
+	svc *DVPService
+	dvp *domain.DVPSettlement
+}
+
+func (st *dvpFreezeFundStep) Execute(ctx context.Context) error {
+	if st.dvp.FundLocked {
+		return nil
+	}
+	legCtx, cancel := legContext(ctx, st.dvp)
+	defer cancel()
+	if err := st.svc.cash.Freeze(legCtx, st.dvp); err != nil {
+		st.svc.releaseFund(ctx, st.dvp)
+		return fmt.Errorf("fund freeze: %w", err)
+	}
+	if err := st.svc.engine.LockFund(st.dvp, time.Now()); err != nil {
+		st.svc.releaseFund(ctx, st.dvp)
+		return err
+	}
+	return st.svc.repo.Save(ctx, st.dvp)
+}
+
+func (st *dvpFreezeFundStep) Compensate(ctx context.Context) error {
+	st.svc.releaseFund(ctx, st.dvp)
+	return nil
+}
+
+type dvpCommitStep struct {
+	transaction.BaseStep
+	svc *DVPService
+	dvp *domain.DVPSettlement
+}
+
+// Execute 重新加载交收记录后提交，并以 LOCKED 为条件落库：超期巡检已先行落失败状态时条件更新不生效，
+// 提交失败并由 Saga 补偿释放两腿；进程内互斥只减少同实例内的竞争
+func (st *dvpCommitStep) Execute(ctx context.Context) error {
+	st.svc.mu.Lock()
+	defer st.svc.mu.Unlock()
+	current, err := st.svc.repo.Get(ctx, st.dvp.DVPID)
+	if err != nil {
+		return err
+	}
+	*st.dvp = *current
+	if err := st.svc.engine.Finalize(st.dvp, time.Now()); err != nil {
+		return err
+	}
+	ok, err := st.svc.repo.SaveIfStatus(ctx, st.dvp, domain.DVPStatusLocked)
+	if err != nil || !ok {
+		st.dvp.Status = domain.DVPStatusLocked
+		if err == nil {
+			err = fmt.Errorf("%w: status changed before commit", domain.ErrDVPNotLocked)
+		}
+		return err
+	}
+	return nil
+}
+
+func (st *dvpCommitStep) Compensate(context.Context) error { return nil }
+
+func (s *DVPService) releaseAsset(ctx context.Context, dvp *domain.DVPSettlement) {
+	if err := s.assets.Release(ctx, dvp); err != nil {
+		s.logger.ErrorContext(ctx, "failed to release dvp asset lock", "dvp_id", dvp.DVPID, "error", err)
+		return
+	}
+	if err := s.engine.ReleaseAsset(dvp); err == nil {
+		_ = s.repo.Save(ctx, dvp)
+	}
+}
+
+func (s *DVPService) releaseFund(ctx context.Context, dvp *domain.DVPSettlement) {
+	if err := s.cash.Unfreeze(ctx, dvp); err != nil {
+		s.logger.ErrorContext(ctx, "failed to release dvp fund freeze", "dvp_id", dvp.DVPID, "error", err)
+		return
+	}
+	if err := s.engine.ReleaseFund(dvp); err == nil {
+		_ = s.repo.Save(ctx, dvp)
+	}
+}
+
+func (s *DVPService) fail(ctx context.Context, dvp *domain.DVPSettlement, reason string) {
+	if err := s.engine.Fail(dvp, reason); err != nil {
+		s.logger.ErrorContext(ctx, "failed to mark dvp failed", "dvp_id", dvp.DVPID, "error", err)
+		return
+	}
+	if err := s.repo.Save(ctx, dvp); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save failed dvp", "dvp_id", dvp.DVPID, "error", err)
+	}
+	s.logger.WarnContext(ctx, "dvp settlement failed", "dvp_id", dvp.DVPID, "reason", reason)
+	s.notify(ctx, dvp)
+}
+
+// deliver 划转阶段：交券与付款分别幂等重试，任一腿未完成时保持 SETTLING 由巡检继续
+func (s *DVPService) deliver(ctx context.Context, dvp *domain.DVPSettlement) error {
+	if !dvp.AssetDelivered {
+		if err := retryLeg(ctx, func() error { return s.assets.Deliver(ctx, dvp) }); err != nil {
+			return fmt.Errorf("asset delivery: %w", err)
+		}
+		if err := s.engine.MarkAssetDelivered(dvp); err != nil {
+			return err
+		}
+		if err := s.repo.Save(ctx, dvp); err != nil {
+			return err
+		}
+	}
+	if !dvp.FundPaid {
+		if err := retryLeg(ctx, func() error { return s.cash.Pay(ctx, dvp) }); err != nil {
+			return fmt.Errorf("fund payment: %w", err)
+		}
+		if err := s.engine.MarkFundPaid(dvp); err != nil {
+			return err
+		}
+		if err := s.repo.Save(ctx, dvp); err != nil {
+			return err
+		}
+	}
+	s.logger.InfoContext(ctx, "dvp settled", "dvp_id", dvp.DVPID, "settlement_id", dvp.SettlementID)
+	s.notify(ctx, dvp)
+	return nil
+}
+
+func retryLeg(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < dvpLegRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(dvpRetryDelay << attempt):
+		}
+	}
+	return err
+}
+
+func (s *DVPService) notify(ctx context.Context, dvp *domain.DVPSettlement) {
+	if s.onFinal != nil && dvp.Status.Final() {
+		s.onFinal(ctx, dvp)
+	}
+}
+
+// Sweep 巡检未完成的交收：超期未提交的释放两腿并标记失败，已提交的继续划转
+func (s *DVPService) Sweep(ctx context.Context) error {
+	open, err := s.repo.ListOpen(ctx, 100)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, dvp := range open {
+		switch {
+		case dvp.Status == domain.DVPStatusSettling:
+			if now.Sub(dvp.LastUpdate) < dvpExpiryGrace {
+				continue
+			}
+			if err := s.deliver(ctx, dvp); err != nil {
+				s.logger.WarnContext(ctx, "dvp delivery still pending", "dvp_id", dvp.DVPID, "error", err)
+			}
+		case s.engine.Expired(dvp, now.Add(-dvpExpiryGrace)):
+			s.expire(ctx, dvp.DVPID)
+		}
+	}
+	return nil
+}
+
+func (s *DVPService) expire(ctx context.Context, dvpID string) {
+	s.mu.Lock()
+	dvp, err := s.repo.Get(ctx, dvpID)
+	if err != nil || !dvp.Status.Locking() {
+		s.mu.Unlock()
+		return
+	}
+	// 以加载时的状态为条件落失败状态，与提交步骤的 LOCKED 条件更新互斥：
+	// 提交已生效时本次更新不生效，失败状态落库后迟到的提交也无法再生效，之后才释放两腿
+	loaded := dvp.Status
+	if err := s.engine.Fail(dvp, domain.ErrDVPExpired.Error()); err != nil {
+		s.mu.Unlock()
+		return
+	}
+	ok, err := s.repo.SaveIfStatus(ctx, dvp, loaded)
+	s.mu.Unlock()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to expire dvp", "dvp_id", dvpID, "error", err)
+		return
+	}
+	if !ok {
+		s.logger.InfoContext(ctx, "dvp changed before expiry, skipped", "dvp_id", dvpID)
+		return
+	}
+
+	s.releaseFund(ctx, dvp)
+	s.releaseAsset(ctx, dvp)
+	s.logger.WarnContext(ctx, "dvp lock timed out, legs released", "dvp_id", dvpID)
+	s.notify(ctx, dvp)
+}
+
+// GetDVP 查询交收记录
+func (s *DVPService) GetDVP(ctx context.Context, dvpID string) (*domain.DVPSettlement, error) {
+	return s.repo.Get(ctx, dvpID)
+}
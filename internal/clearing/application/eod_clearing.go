@@ -0,0 +1,488 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+const (
+	eodSelectPageSize     = 500
+	eodProgressSaveEvery  = 20
+	eodResumeListLimit    = 30
+	eodFailureReasonLimit = 512
+	// eodPlanAttempts 结算单在选取后被 DVP 认领时重新生成计划的次数上限
+	eodPlanAttempts = 3
+)
+
+// errSettlementClaimed 结算单在生成计划后被 DVP 交收认领
+var errSettlementClaimed = errors.New("settlement claimed by dvp")
+
+// ClearingRunDTO 日终清算任务传输对象
+type ClearingRunDTO struct {
+	RunID           string `json:"run_id"`
+	ClearingDate    string `json:"clearing_date"`
+	Status          string `json:"status"`
+	Stage           string `json:"stage"`
+	SettlementCount int    `json:"settlement_count"`
+	NettedCount     int    `json:"netted_count"`
+	MemberCount     int    `json:"member_count"`
+	TotalVolume     string `json:"total_volume"`
+	TotalFee        string `json:"total_fee"`
+	PostingsTotal   int    `json:"postings_total"`
+	PostingsDone    int    `json:"postings_done"`
+	Progress        int64  `json:"progress"`
+	Attempts        int    `json:"attempts"`
+	ErrorMessage    string `json:"error_message"`
+	StartedAt       int64  `json:"started_at"`
+	CompletedAt     int64  `json:"completed_at"`
+}
+
+func toClearingRunDTO(run *domain.ClearingRun) *ClearingRunDTO {
+	var completedAt int64
+	if run.CompletedAt != nil {
+		completedAt = run.CompletedAt.Unix()
+	}
+	return &ClearingRunDTO{
+		RunID:           run.RunID,
+		ClearingDate:    run.ClearingDate,
+		Status:          string(run.Status),
+		Stage:           string(run.Stage),
+		SettlementCount: run.SettlementCount,
+		NettedCount:     run.NettedCount,
+		MemberCount:     run.MemberCount,
+		TotalVolume:     run.TotalVolume.String(),
+		TotalFee:        run.TotalFee.String(),
+		PostingsTotal:   run.PostingsTotal,
+		PostingsDone:    run.PostingsDone,
+		Progress:        run.Progress(),
+		Attempts:        run.Attempts,
+		ErrorMessage:    run.ErrorMessage,
+		StartedAt:       run.StartedAt.Unix(),
+		CompletedAt:     completedAt,
+	}
+}
+
+// EODClearingService 日终清算：按清算日选取结算单，计算多边净额与清算费用，
+// 将净资金、净证券与费用过账到账户与持仓服务，并生成会员清算结算单。
+// 每个清算日只有一个任务，各阶段完成后落检查点，重复触发或进程重启后从检查点继续。
+type EODClearingService struct {
+	settlements domain.SettlementRepository
+	runs        domain.ClearingRunRepository
+	poster      domain.ClearingPoster
+	publisher   messagequeue.EventPublisher
+	logger      *slog.Logger
+	contracts   domain.CCPContractRepository
+
+	fees     domain.FeeSchedule
+	location *time.Location
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewEODClearingService 创建日终清算服务
+func NewEODClearingService(
+	settlements domain.SettlementRepository,
+	runs domain.ClearingRunRepository,
+	poster domain.ClearingPoster,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+) *EODClearingService {
+	return &EODClearingService{
+		settlements: settlements,
+		runs:        runs,
+		poster:      poster,
+		publisher:   publisher,
+		logger:      logger,
+		fees:        domain.DefaultFeeSchedule,
+		location:    time.Local,
+		running:     make(map[string]bool),
+	}
+}
+
+// SetFeeSchedule 设置清算费率
+func (s *EODClearingService) SetFeeSchedule(fees domain.FeeSchedule) {
+	s.fees = fees
+}
+
+// SetCCPContractRepository 注入 CCP 合同仓储，净额交收完成后了结对应合同
+func (s *EODClearingService) SetCCPContractRepository(contracts domain.CCPContractRepository) {
+	s.contracts = contracts
+}
+
+// SetLocation 设置清算日所属时区，结算单按该时区的自然日归属清算日
+func (s *EODClearingService) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.location = loc
+	}
+}
+
+// Trigger 触发指定清算日的日终清算并立即返回任务快照，任务在后台执行。
+// 已完成的清算日直接返回结果；正在执行的任务不会重复启动。
+func (s *EODClearingService) Trigger(ctx context.Context, clearingDate string) (*ClearingRunDTO, error) {
+	run, err := s.loadOrCreate(ctx, clearingDate)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == domain.ClearingRunCompleted || !s.acquire(run.RunID) {
+		return toClearingRunDTO(run), nil
+	}
+
+	dto := toClearingRunDTO(run)
+	go func() {
+		defer s.release(run.RunID)
+		if err := s.execute(context.Background(), run); err != nil {
+			s.logger.Error("EOD clearing run failed", "run_id", run.RunID, "stage", run.Stage, "error", err)
+		}
+	}()
+	return dto, nil
+}
+
+// Run 同步执行指定清算日的日终清算
+func (s *EODClearingService) Run(ctx context.Context, clearingDate string) (*ClearingRunDTO, error) {
+	run, err := s.loadOrCreate(ctx, clearingDate)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == domain.ClearingRunCompleted {
+		return toClearingRunDTO(run), nil
+	}
+	if !s.acquire(run.RunID) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrClearingRunInProgress, run.RunID)
+	}
+	defer s.release(run.RunID)
+
+	err = s.execute(ctx, run)
+	return toClearingRunDTO(run), err
+}
+
+// Resume 恢复进程崩溃时仍处于 RUNNING 的任务，失败任务需重新触发
+func (s *EODClearingService) Resume(ctx context.Context) error {
+	runs, err := s.runs.ListRuns(ctx, domain.ClearingRunRunning, eodResumeListLimit)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if !s.acquire(run.RunID) {
+			continue
+		}
+		s.logger.InfoContext(ctx, "resuming EOD clearing run", "run_id", run.RunID, "stage", run.Stage)
+		if err := s.execute(ctx, run); err != nil {
+			s.logger.ErrorContext(ctx, "resumed EOD clearing run failed", "run_id", run.RunID, "error", err)
+		}
+		s.release(run.RunID)
+	}
+	return nil
+}
+
+// GetRun 查询日终清算任务，不存在时返回 nil
+func (s *EODClearingService) GetRun(ctx context.Context, runID string) (*ClearingRunDTO, error) {
+	run, err := s.runs.GetRun(ctx, runID)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	return toClearingRunDTO(run), nil
+}
+
+// ListStatements 查询任务的会员清算结算单，userID 为空时返回全部会员
+func (s *EODClearingService) ListStatements(ctx context.Context, runID, userID string) ([]*domain.MemberStatement, error) {
+	return s.runs.ListStatements(ctx, runID, userID)
+}
+
+// ListPostings 查询任务的过账分录
+func (s *EODClearingService) ListPostings(ctx context.Context, runID string) ([]*domain.ClearingPosting, error) {
+	return s.runs.ListPostings(ctx, runID)
+}
+
+func (s *EODClearingService) acquire(runID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[runID] {
+		return false
+	}
+	s.running[runID] = true
+	return true
+}
+
+func (s *EODClearingService) release(runID string) {
+	s.mu.Lock()
+	delete(s.running, runID)
+	s.mu.Unlock()
+}
+
+func (s *EODClearingService) loadOrCreate(ctx context.Context, clearingDate string) (*domain.ClearingRun, error) {
+	if _, _, err := domain.ParseClearingDate(clearingDate, s.location); err != nil {
+		return nil, err
+	}
+	run, err := s.runs.GetRun(ctx, domain.ClearingRunID(clearingDate))
+	if err != nil {
+		return nil, err
+	}
+	if run != nil {
+		return run, nil
+	}
+	run = domain.NewClearingRun(clearingDate)
+	if err := s.runs.SaveRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// execute 从任务当前阶段继续执行，直到完成或失败
+func (s *EODClearingService) execute(ctx context.Context, run *domain.ClearingRun) error {
+	run.Attempts++
+	run.Status = domain.ClearingRunRunning
+	run.ErrorMessage = ""
+	if err := s.runs.SaveRun(ctx, run); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "EOD clearing run started", "run_id", run.RunID, "stage", run.Stage, "attempt", run.Attempts)
+
+	for run.Stage != domain.ClearingStageDone {
+		var err error
+		switch run.Stage {
+		case domain.ClearingStagePlanning:
+			err = s.plan(ctx, run)
+		case domain.ClearingStagePosting:
+			err = s.post(ctx, run)
+		case domain.ClearingStageFinalizing:
+			err = s.finalize(ctx, run)
+		default:
+			err = fmt.Errorf("unknown clearing stage %q", run.Stage)
+		}
+		if err != nil {
+			s.fail(ctx, run, err)
+			return err
+		}
+	}
+
+	s.logger.InfoContext(ctx, "EOD clearing run completed",
+		"run_id", run.RunID,
+		"settlements", run.SettlementCount,
+		"netted", run.NettedCount,
+		"members", run.MemberCount,
+		"total_volume", run.TotalVolume.String(),
+		"total_fee", run.TotalFee.String())
+	return nil
+}
+
+// plan 选取预定结算日不晚于清算日的结算单并在单个事务内认领结算单、写入分录与结算单、推进到过账阶段。
+// 事务未提交前崩溃不会留下任何痕迹，重试时重新生成同一计划。
+func (s *EODClearingService) plan(ctx context.Context, run *domain.ClearingRun) error {
+	var err error
+	for attempt := 0; attempt < eodPlanAttempts; attempt++ {
+		if err = s.planOnce(ctx, run); !errors.Is(err, errSettlementClaimed) {
+			return err
+		}
+		s.logger.InfoContext(ctx, "settlement claimed by dvp during planning, rebuilding plan", "run_id", run.RunID, "error", err)
+	}
+	return err
+}
+
+func (s *EODClearingService) planOnce(ctx context.Context, run *domain.ClearingRun) error {
+	businessDate, _, err := domain.ParseClearingDate(run.ClearingDate, s.location)
+	if err != nil {
+		return err
+	}
+
+	var settlements []*domain.Settlement
+	var afterID uint
+	for {
+		page, err := s.settlements.ListDue(ctx, businessDate, run.RunID, afterID, eodSelectPageSize)
+		if err != nil {
+			return fmt.Errorf("select settlements: %w", err)
+		}
+		settlements = append(settlements, page...)
+		if len(page) < eodSelectPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	plan := domain.BuildClearingPlan(run, settlements, s.fees)
+	// 事务提交后才推进内存中的检查点，避免失败时把未生效的阶段写回
+	next := *run
+	next.SettlementCount = len(plan.Settlements)
+	next.NettedCount = plan.NettedCount
+	next.MemberCount = plan.Members()
+	next.TotalVolume = plan.TotalVolume
+	next.TotalFee = plan.TotalFee
+	next.PostingsTotal = len(plan.Postings)
+	next.PostingsDone = 0
+	next.Stage = domain.ClearingStagePosting
+	err = s.runs.WithTx(ctx, func(txCtx context.Context) error {
+		for _, st := range plan.Settlements {
+			// 条件更新锁定结算单，选取后已被 DVP 认领的需重新生成计划
+			claimed, err := s.settlements.ClaimForRun(txCtx, st.SettlementID, run.RunID)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return fmt.Errorf("%w: %s", errSettlementClaimed, st.SettlementID)
+			}
+			if err := s.settlements.Save(txCtx, st); err != nil {
+				return err
+			}
+		}
+		if err := s.runs.SavePostings(txCtx, plan.Postings); err != nil {
+			return err
+		}
+		if err := s.runs.SaveStatements(txCtx, plan.Statements); err != nil {
+			return err
+		}
+		return s.runs.SaveRun(txCtx, &next)
+	})
+	if err != nil {
+		return err
+	}
+	*run = next
+	return nil
+}
+
+// post 按顺序过账尚未成功的分录，全部成功后进入收尾阶段。
+// 分录按先扣款后入账排序；净额交收的入账来源于全体会员的扣款，任一扣款失败即停止入账，
+// 待下次重试扣款全部成功后再过账入账分录
+func (s *EODClearingService) post(ctx context.Context, run *domain.ClearingRun) error {
+	postings, err := s.runs.ListPostings(ctx, run.RunID)
+	if err != nil {
+		return err
+	}
+
+	done, failed, held := 0, 0, 0
+	var firstErr error
+	for _, p := range postings {
+		if p.Status == domain.PostingPosted {
+			done++
+			continue
+		}
+		if failed > 0 && !p.IsDebit() {
+			held++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.Attempts++
+		if err := s.poster.Post(ctx, p); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("posting %s: %w", p.PostingID, err)
+			}
+			p.MarkFailed(truncate(err.Error(), eodFailureReasonLimit))
+			s.logger.WarnContext(ctx, "EOD clearing posting failed", "run_id", run.RunID, "posting_id", p.PostingID, "attempts", p.Attempts, "error", err)
+		} else {
+			p.MarkPosted()
+			done++
+		}
+		// 分录已在下游生效但本地状态未落盘时，重试依赖下游按分录 ID 幂等
+		if err := s.runs.UpdatePosting(ctx, p); err != nil {
+			return err
+		}
+		if done%eodProgressSaveEvery == 0 {
+			run.PostingsDone = done
+			if err := s.runs.SaveRun(ctx, run); err != nil {
+				return err
+			}
+		}
+	}
+
+	run.PostingsDone = done
+	if failed > 0 {
+		if held > 0 {
+			s.logger.WarnContext(ctx, "EOD clearing credits held until debits succeed", "run_id", run.RunID, "held", held)
+		}
+		return fmt.Errorf("%d of %d postings failed, %d credits held, first: %w", failed, len(postings), held, firstErr)
+	}
+	run.Stage = domain.ClearingStageFinalizing
+	return s.runs.SaveRun(ctx, run)
+}
+
+// finalize 将已净额交收的结算单置为完成，并发布结算完成与日终完成事件
+func (s *EODClearingService) finalize(ctx context.Context, run *domain.ClearingRun) error {
+	next := *run
+	next.Complete()
+	err := s.runs.WithTx(ctx, func(txCtx context.Context) error {
+		settlements, err := s.settlements.ListByClearingRun(txCtx, run.RunID)
+		if err != nil {
+			return err
+		}
+		tx := contextx.GetTx(txCtx)
+		now := time.Now()
+		for _, st := range settlements {
+			if st.Status != domain.StatusPending {
+				continue
+			}
+			st.Complete()
+			if err := s.settlements.Save(txCtx, st); err != nil {
+				return err
+			}
+			if s.contracts != nil {
+				if err := s.contracts.UpdateStatusByOriginal(txCtx, st.SettlementID, domain.ContractSettled); err != nil {
+					return err
+				}
+			}
+			if s.publisher == nil {
+				continue
+			}
+			event := domain.SettlementCompletedEvent{
+				BaseEvent:    domain.BaseEvent{Timestamp: now},
+				SettlementID: st.SettlementID,
+				TradeID:      st.TradeID,
+			}
+			if err := s.publisher.PublishInTx(ctx, tx, domain.SettlementCompletedEventType, st.SettlementID, event); err != nil {
+				return err
+			}
+		}
+
+		if err := s.runs.SaveRun(txCtx, &next); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		event := domain.ClearingRunCompletedEvent{
+			BaseEvent:       domain.BaseEvent{Timestamp: now},
+			RunID:           run.RunID,
+			ClearingDate:    run.ClearingDate,
+			SettlementCount: run.SettlementCount,
+			MemberCount:     run.MemberCount,
+			TotalVolume:     run.TotalVolume.String(),
+			TotalFee:        run.TotalFee.String(),
+		}
+		return s.publisher.PublishInTx(ctx, tx, domain.ClearingRunCompletedEventType, run.RunID, event)
+	})
+	if err != nil {
+		return err
+	}
+	*run = next
+	return nil
+}
+
+// fail 记录失败，保留当前阶段供下次触发时继续；
+// 因进程退出导致的中断仍保持 RUNNING，由重启后的 Resume 接续
+func (s *EODClearingService) fail(ctx context.Context, run *domain.ClearingRun, cause error) {
+	if ctx.Err() != nil {
+		run.ErrorMessage = truncate(cause.Error(), eodFailureReasonLimit)
+	} else {
+		run.Fail(truncate(cause.Error(), eodFailureReasonLimit))
+	}
+	// 调用方上下文可能已取消，失败状态仍须落盘
+	if err := s.runs.SaveRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.ErrorContext(ctx, "failed to persist EOD clearing failure", "run_id", run.RunID, "error", err)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
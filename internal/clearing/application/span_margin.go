@@ -0,0 +1,204 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+)
+
+const defaultSpanReloadInterval = 30 * time.Second
+
+// ErrSpanNotLoaded SPAN 参数尚未成功加载
+var ErrSpanNotLoaded = errors.New("span parameters not loaded")
+
+// CalculateSpanMarginCommand 计算 SPAN 保证金，Prices 中缺少的期货价格从行情补齐
+type CalculateSpanMarginCommand struct {
+	UserID       string                `json:"user_id"`
+	Positions    []domain.SpanPosition `json:"positions" binding:"required"`
+	Prices       map[string]float64    `json:"prices"`
+	Volatilities map[string]float64    `json:"volatilities"`
+	// Equity 非零时同时返回以 Currency 计的账户健康度
+	Equity   decimal.Decimal `json:"equity"`
+	Currency string          `json:"currency"`
+}
+
+// SpanMarginResultDTO SPAN 保证金结果
+type SpanMarginResultDTO struct {
+	*domain.SpanResult
+	Health *domain.AccountHealth `json:"health,omitempty"`
+}
+
+// SpanMarginService SPAN 组合保证金：参数按产品组从参数文件加载，
+// 后台循环检测文件变化并原子替换引擎。
+type SpanMarginService struct {
+	loader domain.SpanParameterLoader
+	prices domain.SpanPriceSource
+	logger *slog.Logger
+
+	reloadInterval time.Duration
+	mu             sync.RWMutex
+	engine         *domain.SpanEngine
+	loadedVersion  time.Time
+}
+
+// NewSpanMarginService 创建 SPAN 保证金服务
+func NewSpanMarginService(loader domain.SpanParameterLoader, logger *slog.Logger) *SpanMarginService {
+	return &SpanMarginService{
+		loader:         loader,
+		logger:         logger,
+		reloadInterval: defaultSpanReloadInterval,
+	}
+}
+
+// SetPriceSource 设置行情价格来源
+func (s *SpanMarginService) SetPriceSource(prices domain.SpanPriceSource) {
+	s.prices = prices
+}
+
+// Start 首次加载参数后轮询参数版本，变化时热加载
+func (s *SpanMarginService) Start(ctx context.Context) error {
+	if err := s.Reload(); err != nil {
+		s.logger.Error("initial span parameter load failed", "error", err)
+	}
+
+	ticker := time.NewTicker(s.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			version, err := s.loader.Version()
+			if err != nil {
+				s.logger.Warn("failed to read span parameter version", "error", err)
+				continue
+			}
+			s.mu.RLock()
+			unchanged := version.Equal(s.loadedVersion)
+			s.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				s.logger.Error("span parameter reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// Reload 加载并校验参数；校验失败时保留原引擎
+func (s *SpanMarginService) Reload() error {
+	version, err := s.loader.Version()
+	if err != nil {
+		return fmt.Errorf("failed to read span parameter version: %w", err)
+	}
+	params, err := s.loader.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load span parameters: %w", err)
+	}
+	engine, err := domain.NewSpanEngine(params)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.engine, s.loadedVersion = engine, version
+	s.mu.Unlock()
+	s.logger.Info("span parameters loaded", "groups", len(params.Groups), "inter_spreads", len(params.InterSpreads))
+	return nil
+}
+
+func (s *SpanMarginService) current() (*domain.SpanEngine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.engine == nil {
+		return nil, ErrSpanNotLoaded
+	}
+	return s.engine, nil
+}
+
+// Parameters 返回当前生效的参数集
+func (s *SpanMarginService) Parameters() (*domain.SpanParameterSet, error) {
+	engine, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return engine.Parameters(), nil
+}
+
+// Calculate 计算组合 SPAN 保证金
+func (s *SpanMarginService) Calculate(ctx context.Context, cmd CalculateSpanMarginCommand) (*SpanMarginResultDTO, error) {
+	engine, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	market, err := s.marketData(ctx, engine, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := engine.Calculate(cmd.Positions, market)
+	if err != nil {
+		return nil, err
+	}
+	dto := &SpanMarginResultDTO{SpanResult: result}
+	if !cmd.Equity.IsZero() && cmd.Currency != "" {
+		dto.Health = result.Health(cmd.UserID, cmd.Currency, cmd.Equity)
+	}
+	return dto, nil
+}
+
+// RiskArrays 返回合约的 16 情景风险数组
+func (s *SpanMarginService) RiskArrays(ctx context.Context, symbols []string, prices, vols map[string]float64) ([]*domain.SpanRiskArray, error) {
+	engine, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]domain.SpanPosition, 0, len(symbols))
+	for _, symbol := range symbols {
+		positions = append(positions, domain.SpanPosition{Symbol: symbol, Quantity: decimal.NewFromInt(1)})
+	}
+	market, err := s.marketData(ctx, engine, CalculateSpanMarginCommand{Positions: positions, Prices: prices, Volatilities: vols})
+	if err != nil {
+		return nil, err
+	}
+
+	arrays := make([]*domain.SpanRiskArray, 0, len(symbols))
+	for _, symbol := range symbols {
+		ra, err := engine.RiskArray(symbol, market)
+		if err != nil {
+			return nil, err
+		}
+		arrays = append(arrays, ra)
+	}
+	return arrays, nil
+}
+
+// marketData 合并请求价格与行情价格
+func (s *SpanMarginService) marketData(ctx context.Context, engine *domain.SpanEngine, cmd CalculateSpanMarginCommand) (*domain.SpanMarketData, error) {
+	market := &domain.SpanMarketData{
+		Prices:       make(map[string]float64),
+		Volatilities: cmd.Volatilities,
+		AsOf:         time.Now(),
+	}
+	for symbol, price := range cmd.Prices {
+		market.Prices[symbol] = price
+	}
+	for _, symbol := range engine.RequiredPrices(cmd.Positions) {
+		if market.Prices[symbol] > 0 || s.prices == nil {
+			continue
+		}
+		price, err := s.prices.LastPrice(ctx, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", domain.ErrMissingSpanPrice, symbol, err)
+		}
+		market.Prices[symbol] = price
+	}
+	return market, nil
+}
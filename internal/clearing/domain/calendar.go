@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SettlementCalendar 结算日历，由参考数据服务按交易所与币种节假日计算预定结算日
+type SettlementCalendar interface {
+	SettlementDate(ctx context.Context, market, currency string, tradeDate time.Time, days int) (time.Time, error)
+}
+
+// WeekdaySettlementDate 只跳过周末计算 T+days 结算日，成交日为周末时顺延到下一工作日；
+// 用于结算日历不可用时的回退，不识别节假日
+func WeekdaySettlementDate(tradeDate time.Time, days int) time.Time {
+	d := tradeDate
+	for isWeekend(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	for added := 0; added < days; {
+		d = d.AddDate(0, 0, 1)
+		if !isWeekend(d) {
+			added++
+		}
+	}
+	return d
+}
+
+func isWeekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
@@ -18,6 +18,7 @@ type CCPContract struct {
 	OriginalID   string
 	Counterparty string
 	Symbol       string
+	Currency     string
 	Side         string // BUY/SELL (相对于对手方)
 	Quantity     decimal.Decimal
 	Price        decimal.Decimal
@@ -50,11 +51,12 @@ func (c *ClearingHouse) NovateContracts(s *Settlement) ([]*CCPContract, error) {
 		OriginalID:   s.SettlementID,
 		Counterparty: s.BuyUserID,
 		Symbol:       s.Symbol,
+		Currency:     s.Currency,
 		Side:         "BUY",
 		Quantity:     s.Quantity,
 		Price:        s.Price,
 		TotalAmount:  s.TotalAmount,
-		Status:       "OPEN",
+		Status:       ContractOpen,
 		CreatedAt:    time.Now(),
 	}
 
@@ -64,11 +66,12 @@ func (c *ClearingHouse) NovateContracts(s *Settlement) ([]*CCPContract, error) {
 		OriginalID:   s.SettlementID,
 		Counterparty: s.SellUserID,
 		Symbol:       s.Symbol,
+		Currency:     s.Currency,
 		Side:         "SELL",
 		Quantity:     s.Quantity,
 		Price:        s.Price,
 		TotalAmount:  s.TotalAmount,
-		Status:       "OPEN",
+		Status:       ContractOpen,
 		CreatedAt:    time.Now(),
 	}
 
@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // SettlementRepository 结算仓储接口
 type SettlementRepository interface {
@@ -13,6 +16,14 @@ type SettlementRepository interface {
 	Get(ctx context.Context, id string) (*Settlement, error)
 	GetByTradeID(ctx context.Context, tradeID string) (*Settlement, error)
 	List(ctx context.Context, limit int) ([]*Settlement, error)
+	// ListDue 按主键游标分页查询预定结算日不晚于 settlementDate、仍为 PENDING 且未被其他日终任务认领的结算单
+	ListDue(ctx context.Context, settlementDate time.Time, runID string, afterID uint, limit int) ([]*Settlement, error)
+	// ListByClearingRun 查询被指定日终任务认领的结算单
+	ListByClearingRun(ctx context.Context, runID string) ([]*Settlement, error)
+	// ClaimForDVP 将仍为 PENDING 且未被日终任务认领的结算单置为 DVP 交收中，返回是否认领成功
+	ClaimForDVP(ctx context.Context, settlementID string) (bool, error)
+	// ClaimForRun 日终任务认领结算单，已进入 DVP 交收或被其他任务认领时返回 false
+	ClaimForRun(ctx context.Context, settlementID, runID string) (bool, error)
 }
 
 // SettlementReadRepository 结算读模型（Redis）。
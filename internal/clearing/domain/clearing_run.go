@@ -0,0 +1,390 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ClearingDateLayout 清算日期格式
+const ClearingDateLayout = "2006-01-02"
+
+var (
+	// ErrInvalidClearingDate 清算日期格式错误
+	ErrInvalidClearingDate = errors.New("invalid clearing date")
+	// ErrClearingRunInProgress 同一清算日的日终任务正在执行
+	ErrClearingRunInProgress = errors.New("clearing run already in progress")
+)
+
+// ClearingRunStatus 日终清算任务状态
+type ClearingRunStatus string
+
+const (
+	ClearingRunRunning   ClearingRunStatus = "RUNNING"
+	ClearingRunCompleted ClearingRunStatus = "COMPLETED"
+	ClearingRunFailed    ClearingRunStatus = "FAILED"
+)
+
+// ClearingRunStage 日终清算阶段，每个阶段完成后持久化检查点，崩溃后从检查点继续
+type ClearingRunStage string
+
+const (
+	// ClearingStagePlanning 选取结算单、计算净额与费用并生成过账分录
+	ClearingStagePlanning ClearingRunStage = "PLANNING"
+	// ClearingStagePosting 逐笔过账到账户与持仓服务
+	ClearingStagePosting ClearingRunStage = "POSTING"
+	// ClearingStageFinalizing 回写结算单状态并发布完成事件
+	ClearingStageFinalizing ClearingRunStage = "FINALIZING"
+	// ClearingStageDone 全部完成
+	ClearingStageDone ClearingRunStage = "DONE"
+)
+
+// ClearingRun 日终清算任务，每个清算日唯一
+type ClearingRun struct {
+	RunID           string            `json:"run_id"`
+	ClearingDate    string            `json:"clearing_date"`
+	Status          ClearingRunStatus `json:"status"`
+	Stage           ClearingRunStage  `json:"stage"`
+	SettlementCount int               `json:"settlement_count"`
+	NettedCount     int               `json:"netted_count"`
+	MemberCount     int               `json:"member_count"`
+	TotalVolume     decimal.Decimal   `json:"total_volume"`
+	TotalFee        decimal.Decimal   `json:"total_fee"`
+	PostingsTotal   int               `json:"postings_total"`
+	PostingsDone    int               `json:"postings_done"`
+	Attempts        int               `json:"attempts"`
+	ErrorMessage    string            `json:"error_message"`
+	StartedAt       time.Time         `json:"started_at"`
+	CompletedAt     *time.Time        `json:"completed_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// ClearingRunID 清算日对应的任务 ID，同一日期重复触发得到同一任务
+func ClearingRunID(clearingDate string) string {
+	return "EOD-" + clearingDate
+}
+
+// ParseClearingDate 解析清算日期，返回该日在指定时区内的 [start, end) 区间
+func ParseClearingDate(clearingDate string, loc *time.Location) (time.Time, time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	start, err := time.ParseInLocation(ClearingDateLayout, clearingDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s", ErrInvalidClearingDate, clearingDate)
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// NewClearingRun 创建日终清算任务
+func NewClearingRun(clearingDate string) *ClearingRun {
+	return &ClearingRun{
+		RunID:        ClearingRunID(clearingDate),
+		ClearingDate: clearingDate,
+		Status:       ClearingRunRunning,
+		Stage:        ClearingStagePlanning,
+		StartedAt:    time.Now(),
+	}
+}
+
+// Progress 过账进度百分比
+func (r *ClearingRun) Progress() int64 {
+	switch {
+	case r.Stage == ClearingStageDone:
+		return 100
+	case r.PostingsTotal == 0:
+		return 0
+	default:
+		return int64(r.PostingsDone * 100 / r.PostingsTotal)
+	}
+}
+
+// Fail 记录失败原因，任务停留在当前阶段等待重试
+func (r *ClearingRun) Fail(reason string) {
+	r.Status = ClearingRunFailed
+	r.ErrorMessage = reason
+}
+
+// Complete 标记任务完成
+func (r *ClearingRun) Complete() {
+	now := time.Now()
+	r.Status = ClearingRunCompleted
+	r.Stage = ClearingStageDone
+	r.ErrorMessage = ""
+	r.CompletedAt = &now
+}
+
+// PostingKind 过账分录类型
+type PostingKind string
+
+const (
+	// PostingCash 净资金划转，金额为正表示应收
+	PostingCash PostingKind = "CASH"
+	// PostingSecurities 净证券交收，数量为正表示应收
+	PostingSecurities PostingKind = "SECURITIES"
+	// PostingFee 清算费用，金额恒为正，表示应付
+	PostingFee PostingKind = "FEE"
+)
+
+// PostingStatus 分录状态
+type PostingStatus string
+
+const (
+	PostingPending PostingStatus = "PENDING"
+	PostingPosted  PostingStatus = "POSTED"
+	PostingFailed  PostingStatus = "FAILED"
+)
+
+// ClearingPosting 日终清算过账分录。
+// PostingID 由任务、类型、会员与标的/币种确定性生成，作为下游幂等键。
+type ClearingPosting struct {
+	PostingID    string          `json:"posting_id"`
+	RunID        string          `json:"run_id"`
+	UserID       string          `json:"user_id"`
+	Kind         PostingKind     `json:"kind"`
+	Symbol       string          `json:"symbol"`
+	Currency     string          `json:"currency"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	Amount       decimal.Decimal `json:"amount"`
+	Price        decimal.Decimal `json:"price"`
+	Sequence     int             `json:"sequence"`
+	Status       PostingStatus   `json:"status"`
+	Attempts     int             `json:"attempts"`
+	ErrorMessage string          `json:"error_message"`
+	PostedAt     *time.Time      `json:"posted_at"`
+}
+
+// MarkPosted 标记分录已过账
+func (p *ClearingPosting) MarkPosted() {
+	now := time.Now()
+	p.Status = PostingPosted
+	p.ErrorMessage = ""
+	p.PostedAt = &now
+}
+
+// MarkFailed 标记分录过账失败
+func (p *ClearingPosting) MarkFailed(reason string) {
+	p.Status = PostingFailed
+	p.ErrorMessage = reason
+}
+
+// StatementLine 会员结算单中单个标的的净额明细
+type StatementLine struct {
+	Symbol      string          `json:"symbol"`
+	NetQuantity decimal.Decimal `json:"net_quantity"`
+	NetAmount   decimal.Decimal `json:"net_amount"`
+}
+
+// MemberStatement 会员在某清算日、某币种下的清算结算单
+type MemberStatement struct {
+	RunID        string           `json:"run_id"`
+	ClearingDate string           `json:"clearing_date"`
+	UserID       string           `json:"user_id"`
+	Currency     string           `json:"currency"`
+	TradeCount   int              `json:"trade_count"`
+	BuyAmount    decimal.Decimal  `json:"buy_amount"`
+	SellAmount   decimal.Decimal  `json:"sell_amount"`
+	NetCash      decimal.Decimal  `json:"net_cash"`
+	Fee          decimal.Decimal  `json:"fee"`
+	NetAfterFee  decimal.Decimal  `json:"net_after_fee"`
+	Lines        []*StatementLine `json:"lines"`
+}
+
+// FeeSchedule 清算费率，买卖双方各按成交额收取，不足最低收费时按最低收费
+type FeeSchedule struct {
+	Rate    decimal.Decimal `json:"rate"`
+	Minimum decimal.Decimal `json:"minimum"`
+}
+
+// DefaultFeeSchedule 默认费率：成交额的万分之二，无最低收费
+var DefaultFeeSchedule = FeeSchedule{Rate: decimal.NewFromFloat(0.0002)}
+
+// Charge 单边应收费用
+func (f FeeSchedule) Charge(notional decimal.Decimal) decimal.Decimal {
+	fee := notional.Abs().Mul(f.Rate).Round(8)
+	if fee.LessThan(f.Minimum) {
+		return f.Minimum
+	}
+	return fee
+}
+
+// ClearingPlan 清算计划：本次任务认领的结算单、生成的分录与结算单
+type ClearingPlan struct {
+	Settlements []*Settlement
+	Postings    []*ClearingPosting
+	Statements  []*MemberStatement
+	NettedCount int
+	TotalVolume decimal.Decimal
+	TotalFee    decimal.Decimal
+}
+
+type memberCurrency struct {
+	userID   string
+	currency string
+}
+
+// BuildClearingPlan 基于到期应结算的结算单生成清算计划。
+// 只认领仍处于 PENDING 且未被其他任务认领的结算单：这些结算单由本次任务完成交收，
+// 按费率计费并参与多边净额生成资金与证券分录；已完成、失败或 DVP 交收中的结算单不计费。
+func BuildClearingPlan(run *ClearingRun, settlements []*Settlement, fees FeeSchedule) *ClearingPlan {
+	plan := &ClearingPlan{}
+	statements := make(map[memberCurrency]*MemberStatement)
+	statementOf := func(userID, currency string) *MemberStatement {
+		key := memberCurrency{userID: userID, currency: currency}
+		st, ok := statements[key]
+		if !ok {
+			st = &MemberStatement{RunID: run.RunID, ClearingDate: run.ClearingDate, UserID: userID, Currency: currency}
+			statements[key] = st
+		}
+		return st
+	}
+
+	var pending []*Settlement
+	for _, s := range settlements {
+		if s.Status != StatusPending || (s.ClearingRunID != "" && s.ClearingRunID != run.RunID) {
+			continue
+		}
+		buyFee, sellFee := fees.Charge(s.TotalAmount), fees.Charge(s.TotalAmount)
+		s.ClearingRunID = run.RunID
+		s.Fee = buyFee.Add(sellFee)
+		plan.Settlements = append(plan.Settlements, s)
+		plan.TotalVolume = plan.TotalVolume.Add(s.TotalAmount)
+		plan.TotalFee = plan.TotalFee.Add(s.Fee)
+
+		buyer := statementOf(s.BuyUserID, s.Currency)
+		buyer.TradeCount++
+		buyer.BuyAmount = buyer.BuyAmount.Add(s.TotalAmount)
+		buyer.Fee = buyer.Fee.Add(buyFee)
+
+		seller := statementOf(s.SellUserID, s.Currency)
+		seller.TradeCount++
+		seller.SellAmount = seller.SellAmount.Add(s.TotalAmount)
+		seller.Fee = seller.Fee.Add(sellFee)
+
+		pending = append(pending, s)
+	}
+	plan.NettedCount = len(pending)
+
+	netting := NewNettingEngine().CalculateMultilateralNetting(pending)
+	for userID, bySymbol := range netting {
+		for symbol, byCurrency := range bySymbol {
+			for currency, res := range byCurrency {
+				st := statementOf(userID, currency)
+				st.NetCash = st.NetCash.Add(res.NetAmount)
+				if res.NetQuantity.IsZero() && res.NetAmount.IsZero() {
+					continue
+				}
+				st.Lines = append(st.Lines, &StatementLine{Symbol: symbol, NetQuantity: res.NetQuantity, NetAmount: res.NetAmount})
+				if res.NetQuantity.IsZero() {
+					continue
+				}
+				plan.Postings = append(plan.Postings, &ClearingPosting{
+					PostingID: fmt.Sprintf("%s-%s-%s-%s-%s", run.RunID, PostingSecurities, userID, symbol, currency),
+					RunID:     run.RunID,
+					UserID:    userID,
+					Kind:      PostingSecurities,
+					Symbol:    symbol,
+					Currency:  currency,
+					Quantity:  res.NetQuantity,
+					Amount:    res.NetAmount,
+					Price:     res.NetAmount.Div(res.NetQuantity).Abs().Round(8),
+					Status:    PostingPending,
+				})
+			}
+		}
+	}
+
+	for _, st := range statements {
+		st.NetAfterFee = st.NetCash.Sub(st.Fee)
+		sort.Slice(st.Lines, func(i, j int) bool { return st.Lines[i].Symbol < st.Lines[j].Symbol })
+		plan.Statements = append(plan.Statements, st)
+		if !st.NetCash.IsZero() {
+			plan.Postings = append(plan.Postings, &ClearingPosting{
+				PostingID: fmt.Sprintf("%s-%s-%s-%s", run.RunID, PostingCash, st.UserID, st.Currency),
+				RunID:     run.RunID,
+				UserID:    st.UserID,
+				Kind:      PostingCash,
+				Currency:  st.Currency,
+				Amount:    st.NetCash,
+				Status:    PostingPending,
+			})
+		}
+		if st.Fee.IsPositive() {
+			plan.Postings = append(plan.Postings, &ClearingPosting{
+				PostingID: fmt.Sprintf("%s-%s-%s-%s", run.RunID, PostingFee, st.UserID, st.Currency),
+				RunID:     run.RunID,
+				UserID:    st.UserID,
+				Kind:      PostingFee,
+				Currency:  st.Currency,
+				Amount:    st.Fee,
+				Status:    PostingPending,
+			})
+		}
+	}
+
+	sort.Slice(plan.Statements, func(i, j int) bool {
+		a, b := plan.Statements[i], plan.Statements[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Currency < b.Currency
+	})
+	// 过账顺序：先扣款（应付资金、费用、应付证券）后入账，避免中途失败时出现只入不出
+	sort.SliceStable(plan.Postings, func(i, j int) bool {
+		di, dj := plan.Postings[i].IsDebit(), plan.Postings[j].IsDebit()
+		if di != dj {
+			return di
+		}
+		return plan.Postings[i].PostingID < plan.Postings[j].PostingID
+	})
+	for i, p := range plan.Postings {
+		p.Sequence = i + 1
+	}
+	return plan
+}
+
+// IsDebit 分录是否为扣款方向（应付资金、费用或应付证券）
+func (p *ClearingPosting) IsDebit() bool {
+	switch p.Kind {
+	case PostingFee:
+		return true
+	case PostingSecurities:
+		return p.Quantity.IsNegative()
+	default:
+		return p.Amount.IsNegative()
+	}
+}
+
+// Members 计划涉及的会员数
+func (p *ClearingPlan) Members() int {
+	members := make(map[string]struct{}, len(p.Statements))
+	for _, st := range p.Statements {
+		members[st.UserID] = struct{}{}
+	}
+	return len(members)
+}
+
+// ClearingPoster 将日终清算分录过账到账户与持仓服务，实现须以 PostingID 保证幂等
+type ClearingPoster interface {
+	Post(ctx context.Context, posting *ClearingPosting) error
+}
+
+// ClearingRunRepository 日终清算任务、分录与会员结算单仓储
+type ClearingRunRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	GetRun(ctx context.Context, runID string) (*ClearingRun, error)
+	SaveRun(ctx context.Context, run *ClearingRun) error
+	ListRuns(ctx context.Context, status ClearingRunStatus, limit int) ([]*ClearingRun, error)
+
+	SavePostings(ctx context.Context, postings []*ClearingPosting) error
+	UpdatePosting(ctx context.Context, posting *ClearingPosting) error
+	ListPostings(ctx context.Context, runID string) ([]*ClearingPosting, error)
+
+	SaveStatements(ctx context.Context, statements []*MemberStatement) error
+	ListStatements(ctx context.Context, runID, userID string) ([]*MemberStatement, error)
+}
@@ -0,0 +1,466 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CCP 合同状态
+const (
+	ContractOpen      = "OPEN"
+	ContractSettled   = "SETTLED"
+	ContractDefaulted = "DEFAULTED"
+)
+
+var (
+	// ErrDefaultCaseNotFound 违约处置案例不存在
+	ErrDefaultCaseNotFound = errors.New("default case not found")
+	// ErrMemberAlreadyInDefault 会员已有未结束的违约处置
+	ErrMemberAlreadyInDefault = errors.New("member already in default")
+	// ErrAuctionClosed 拍卖已结束或该批次不接受竞价
+	ErrAuctionClosed = errors.New("auction closed")
+	// ErrIneligibleBidder 竞价方不是存续的清算会员
+	ErrIneligibleBidder = errors.New("bidder is not an eligible clearing member")
+)
+
+// CloseOutMethod 违约头寸平仓方式
+type CloseOutMethod string
+
+const (
+	// CloseOutAuction 向存续会员拍卖违约头寸，流拍批次回退为市场对冲
+	CloseOutAuction CloseOutMethod = "AUCTION"
+	// CloseOutHedge 由清算所按市场价对冲平仓
+	CloseOutHedge CloseOutMethod = "HEDGE"
+)
+
+// DefaultCaseStatus 违约处置状态
+type DefaultCaseStatus string
+
+const (
+	DefaultCaseClosingOut DefaultCaseStatus = "CLOSING_OUT"
+	DefaultCaseCompleted  DefaultCaseStatus = "COMPLETED"
+)
+
+// WaterfallLayer 违约损失分摊层级，按声明顺序依次动用
+type WaterfallLayer string
+
+const (
+	LayerDefaulterMargin WaterfallLayer = "DEFAULTER_MARGIN"
+	LayerDefaulterFund   WaterfallLayer = "DEFAULTER_DEFAULT_FUND"
+	LayerCCPSkin         WaterfallLayer = "CCP_SKIN_IN_THE_GAME"
+	LayerMutualisedFund  WaterfallLayer = "MUTUALISED_DEFAULT_FUND"
+)
+
+// MemberCollateral 清算会员在某币种下存放于清算所的保证金与违约基金份额。
+// 清算所自有资金（风险准备金）以 ClearingHouseUserID 记录在 DefaultFund 中。
+type MemberCollateral struct {
+	UserID      string          `json:"user_id"`
+	Currency    string          `json:"currency"`
+	Margin      decimal.Decimal `json:"margin"`
+	DefaultFund decimal.Decimal `json:"default_fund"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// CloseOutLot 违约会员某标的、某币种的净头寸，作为一个拍卖或对冲批次
+type CloseOutLot struct {
+	LotID    string `json:"lot_id"`
+	Symbol   string `json:"symbol"`
+	Currency string `json:"currency"`
+	// NetQuantity 违约方净头寸，正数为净买入（清算所需卖出）
+	NetQuantity decimal.Decimal `json:"net_quantity"`
+	// NetValue 违约方按合同价应付的净金额
+	NetValue    decimal.Decimal `json:"net_value"`
+	ContractIDs []string        `json:"contract_ids"`
+	Method      CloseOutMethod  `json:"method"`
+	ClosePrice  decimal.Decimal `json:"close_price"`
+	Winner      string          `json:"winner,omitempty"`
+	// Loss 清算所平仓损失，负数为收益
+	Loss     decimal.Decimal `json:"loss"`
+	ClosedAt *time.Time      `json:"closed_at,omitempty"`
+}
+
+// Closed 批次是否已平仓
+func (l *CloseOutLot) Closed() bool {
+	return l.ClosedAt != nil
+}
+
+// Close 按成交价平仓并计算损失：清算所承接违约方头寸后以 price 了结，
+// 损失 = 违约方应付净金额 - 平仓回收金额
+func (l *CloseOutLot) Close(method CloseOutMethod, price decimal.Decimal, winner string) {
+	now := time.Now()
+	l.Method = method
+	l.ClosePrice = price
+	l.Winner = winner
+	l.Loss = l.NetValue.Sub(l.NetQuantity.Mul(price))
+	l.ClosedAt = &now
+}
+
+// AuctionBid 存续会员对违约批次的竞价，价格为接手该批次净头寸的单价
+type AuctionBid struct {
+	BidID     string          `json:"bid_id"`
+	CaseID    string          `json:"case_id"`
+	LotID     string          `json:"lot_id"`
+	Bidder    string          `json:"bidder"`
+	Price     decimal.Decimal `json:"price"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// better 对清算所更优的报价：卖出净多头时价高者优，买入净空头时价低者优
+func (b *AuctionBid) better(other *AuctionBid, lot *CloseOutLot) bool {
+	if other == nil {
+		return true
+	}
+	if !b.Price.Equal(other.Price) {
+		if lot.NetQuantity.IsPositive() {
+			return b.Price.GreaterThan(other.Price)
+		}
+		return b.Price.LessThan(other.Price)
+	}
+	return b.CreatedAt.Before(other.CreatedAt)
+}
+
+// MemberCharge 某一层级对单个会员的扣减
+type MemberCharge struct {
+	UserID    string          `json:"user_id"`
+	Available decimal.Decimal `json:"available"`
+	Charged   decimal.Decimal `json:"charged"`
+}
+
+// WaterfallStep 违约损失瀑布的一步
+type WaterfallStep struct {
+	Sequence      int             `json:"sequence"`
+	Layer         WaterfallLayer  `json:"layer"`
+	Currency      string          `json:"currency"`
+	LossBefore    decimal.Decimal `json:"loss_before"`
+	Available     decimal.Decimal `json:"available"`
+	Applied       decimal.Decimal `json:"applied"`
+	RemainingLoss decimal.Decimal `json:"remaining_loss"`
+	Charges       []*MemberCharge `json:"charges"`
+	AppliedAt     time.Time       `json:"applied_at"`
+}
+
+// DefaultCase 清算会员违约处置案例
+type DefaultCase struct {
+	CaseID    string                     `json:"case_id"`
+	UserID    string                     `json:"user_id"`
+	Reason    string                     `json:"reason"`
+	Method    CloseOutMethod             `json:"method"`
+	Status    DefaultCaseStatus          `json:"status"`
+	Lots      []*CloseOutLot             `json:"lots"`
+	Steps     []*WaterfallStep           `json:"steps"`
+	TotalLoss map[string]decimal.Decimal `json:"total_loss"`
+	// Uncovered 瀑布耗尽后仍未覆盖的损失，需启动会员追加摊缴等恢复措施
+	Uncovered       map[string]decimal.Decimal `json:"uncovered"`
+	AuctionDeadline time.Time                  `json:"auction_deadline"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	CompletedAt     *time.Time                 `json:"completed_at,omitempty"`
+}
+
+// NewDefaultCase 汇总违约会员未了结的 CCP 合同，按标的与币种生成平仓批次
+func NewDefaultCase(caseID string, record *DefaultRecord, contracts []*CCPContract, method CloseOutMethod, auctionWindow time.Duration) *DefaultCase {
+	type lotKey struct{ symbol, currency string }
+	lots := make(map[lotKey]*CloseOutLot)
+	for _, c := range contracts {
+		if c.Counterparty != record.UserID || c.Status != ContractOpen {
+			continue
+		}
+		key := lotKey{symbol: c.Symbol, currency: c.Currency}
+		lot, ok := lots[key]
+		if !ok {
+			lot = &CloseOutLot{Symbol: c.Symbol, Currency: c.Currency}
+			lots[key] = lot
+		}
+		qty, value := c.Quantity, c.TotalAmount
+		if c.Side == "SELL" {
+			qty, value = qty.Neg(), value.Neg()
+		}
+		lot.NetQuantity = lot.NetQuantity.Add(qty)
+		lot.NetValue = lot.NetValue.Add(value)
+		lot.ContractIDs = append(lot.ContractIDs, c.ContractID)
+	}
+
+	now := time.Now()
+	dc := &DefaultCase{
+		CaseID:          caseID,
+		UserID:          record.UserID,
+		Reason:          record.Reason,
+		Method:          method,
+		Status:          DefaultCaseClosingOut,
+		TotalLoss:       make(map[string]decimal.Decimal),
+		Uncovered:       make(map[string]decimal.Decimal),
+		AuctionDeadline: now.Add(auctionWindow),
+		CreatedAt:       now,
+	}
+	for _, lot := range lots {
+		dc.Lots = append(dc.Lots, lot)
+	}
+	sort.Slice(dc.Lots, func(i, j int) bool {
+		if dc.Lots[i].Symbol != dc.Lots[j].Symbol {
+			return dc.Lots[i].Symbol < dc.Lots[j].Symbol
+		}
+		return dc.Lots[i].Currency < dc.Lots[j].Currency
+	})
+	for i, lot := range dc.Lots {
+		lot.LotID = fmt.Sprintf("%s-L%d", caseID, i+1)
+		// 净头寸为零的批次无需平仓，直接按合同价了结剩余现金差额
+		if lot.NetQuantity.IsZero() {
+			lot.Close(CloseOutHedge, decimal.Zero, "")
+		}
+	}
+	return dc
+}
+
+// Lot 查找批次
+func (c *DefaultCase) Lot(lotID string) *CloseOutLot {
+	for _, lot := range c.Lots {
+		if lot.LotID == lotID {
+			return lot
+		}
+	}
+	return nil
+}
+
+// AcceptsBids 是否仍在拍卖窗口内
+func (c *DefaultCase) AcceptsBids(now time.Time) bool {
+	return c.Status == DefaultCaseClosingOut && c.Method == CloseOutAuction && now.Before(c.AuctionDeadline)
+}
+
+// ReadyToClose 拍卖窗口结束或采用对冲方式时可进入平仓
+func (c *DefaultCase) ReadyToClose(now time.Time) bool {
+	return c.Status == DefaultCaseClosingOut && (c.Method == CloseOutHedge || !now.Before(c.AuctionDeadline))
+}
+
+// AwardAuction 将批次授予最优报价，没有有效报价时返回 false，由调用方改为对冲
+func (c *DefaultCase) AwardAuction(lot *CloseOutLot, bids []*AuctionBid) bool {
+	var best *AuctionBid
+	for _, bid := range bids {
+		if bid.LotID == lot.LotID && bid.Bidder != c.UserID && bid.better(best, lot) {
+			best = bid
+		}
+	}
+	if best == nil {
+		return false
+	}
+	lot.Close(CloseOutAuction, best.Price, best.Bidder)
+	return true
+}
+
+// LossByCurrency 汇总各币种平仓损失，同币种批次间盈亏相抵
+func (c *DefaultCase) LossByCurrency() map[string]decimal.Decimal {
+	losses := make(map[string]decimal.Decimal)
+	for _, lot := range c.Lots {
+		losses[lot.Currency] = losses[lot.Currency].Add(lot.Loss)
+	}
+	return losses
+}
+
+// ApplyWaterfall 按瀑布顺序分摊各币种损失：违约方保证金、违约方违约基金、
+// 清算所风险准备金、存续会员违约基金（按份额比例）。
+// collateral 为各会员在对应币种下的抵押品，函数直接扣减其余额，调用方负责持久化。
+func (c *DefaultCase) ApplyWaterfall(collateral map[string][]*MemberCollateral) {
+	currencies := make([]string, 0)
+	for currency, loss := range c.LossByCurrency() {
+		c.TotalLoss[currency] = loss
+		if loss.IsPositive() {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		remaining := c.TotalLoss[currency]
+		var defaulter, ccp *MemberCollateral
+		var survivors []*MemberCollateral
+		for _, mc := range collateral[currency] {
+			switch mc.UserID {
+			case c.UserID:
+				defaulter = mc
+			case ClearingHouseUserID:
+				ccp = mc
+			default:
+				survivors = append(survivors, mc)
+			}
+		}
+		sort.Slice(survivors, func(i, j int) bool { return survivors[i].UserID < survivors[j].UserID })
+
+		remaining = c.applySingle(LayerDefaulterMargin, currency, remaining, defaulter, func(mc *MemberCollateral) *decimal.Decimal { return &mc.Margin })
+		remaining = c.applySingle(LayerDefaulterFund, currency, remaining, defaulter, func(mc *MemberCollateral) *decimal.Decimal { return &mc.DefaultFund })
+		remaining = c.applySingle(LayerCCPSkin, currency, remaining, ccp, func(mc *MemberCollateral) *decimal.Decimal { return &mc.DefaultFund })
+		remaining = c.applyMutualised(currency, remaining, survivors)
+		if remaining.IsPositive() {
+			c.Uncovered[currency] = remaining
+		}
+	}
+}
+
+func (c *DefaultCase) applySingle(layer WaterfallLayer, currency string, loss decimal.Decimal, mc *MemberCollateral, balance func(*MemberCollateral) *decimal.Decimal) decimal.Decimal {
+	step := &WaterfallStep{Layer: layer, Currency: currency, LossBefore: loss}
+	if mc != nil && loss.IsPositive() {
+		bal := balance(mc)
+		step.Available = *bal
+		step.Applied = decimal.Min(loss, *bal)
+		*bal = bal.Sub(step.Applied)
+		step.Charges = []*MemberCharge{{UserID: mc.UserID, Available: step.Available, Charged: step.Applied}}
+	}
+	return c.recordStep(step)
+}
+
+// applyMutualised 按存续会员违约基金份额比例分摊，尾差由份额最大的会员承担
+func (c *DefaultCase) applyMutualised(currency string, loss decimal.Decimal, survivors []*MemberCollateral) decimal.Decimal {
+	step := &WaterfallStep{Layer: LayerMutualisedFund, Currency: currency, LossBefore: loss}
+	for _, mc := range survivors {
+		step.Available = step.Available.Add(mc.DefaultFund)
+	}
+	if loss.IsPositive() && step.Available.IsPositive() {
+		step.Applied = decimal.Min(loss, step.Available)
+		var largest *MemberCharge
+		allocated := decimal.Zero
+		for _, mc := range survivors {
+			if !mc.DefaultFund.IsPositive() {
+				continue
+			}
+			charge := &MemberCharge{
+				UserID:    mc.UserID,
+				Available: mc.DefaultFund,
+				Charged:   step.Applied.Mul(mc.DefaultFund).Div(step.Available).RoundDown(8),
+			}
+			allocated = allocated.Add(charge.Charged)
+			step.Charges = append(step.Charges, charge)
+			if largest == nil || charge.Available.GreaterThan(largest.Available) {
+				largest = charge
+			}
+		}
+		if residual := step.Applied.Sub(allocated); largest != nil && residual.IsPositive() {
+			largest.Charged = decimal.Min(largest.Available, largest.Charged.Add(residual))
+		}
+		applied := decimal.Zero
+		for _, charge := range step.Charges {
+			for _, mc := range survivors {
+				if mc.UserID == charge.UserID {
+					mc.DefaultFund = mc.DefaultFund.Sub(charge.Charged)
+				}
+			}
+			applied = applied.Add(charge.Charged)
+		}
+		step.Applied = applied
+	}
+	return c.recordStep(step)
+}
+
+func (c *DefaultCase) recordStep(step *WaterfallStep) decimal.Decimal {
+	step.Sequence = len(c.Steps) + 1
+	step.RemainingLoss = step.LossBefore.Sub(step.Applied)
+	step.AppliedAt = time.Now()
+	c.Steps = append(c.Steps, step)
+	return step.RemainingLoss
+}
+
+// Complete 标记处置完成
+func (c *DefaultCase) Complete() {
+	now := time.Now()
+	c.Status = DefaultCaseCompleted
+	c.CompletedAt = &now
+}
+
+// MemberDefaultReport 违约处置完成后发送给清算会员的报告
+type MemberDefaultReport struct {
+	CaseID      string                     `json:"case_id"`
+	Defaulter   string                     `json:"defaulter"`
+	UserID      string                     `json:"user_id"`
+	Role        string                     `json:"role"` // DEFAULTER / CCP / SURVIVOR
+	TotalLoss   map[string]decimal.Decimal `json:"total_loss"`
+	Uncovered   map[string]decimal.Decimal `json:"uncovered"`
+	Charges     []*ReportCharge            `json:"charges"`
+	LotsAwarded []*CloseOutLot             `json:"lots_awarded"`
+	CompletedAt time.Time                  `json:"completed_at"`
+}
+
+// ReportCharge 报告中会员在某层级被扣减的金额
+type ReportCharge struct {
+	Layer     WaterfallLayer  `json:"layer"`
+	Currency  string          `json:"currency"`
+	Available decimal.Decimal `json:"available"`
+	Charged   decimal.Decimal `json:"charged"`
+}
+
+// MemberReports 为违约方、清算所及全部存续会员生成报告
+func (c *DefaultCase) MemberReports(members []string) []*MemberDefaultReport {
+	var completedAt time.Time
+	if c.CompletedAt != nil {
+		completedAt = *c.CompletedAt
+	}
+	seen := make(map[string]bool)
+	reports := make([]*MemberDefaultReport, 0, len(members)+2)
+	for _, userID := range append([]string{c.UserID, ClearingHouseUserID}, members...) {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		role := "SURVIVOR"
+		switch userID {
+		case c.UserID:
+			role = "DEFAULTER"
+		case ClearingHouseUserID:
+			role = "CCP"
+		}
+		report := &MemberDefaultReport{
+			CaseID:      c.CaseID,
+			Defaulter:   c.UserID,
+			UserID:      userID,
+			Role:        role,
+			TotalLoss:   c.TotalLoss,
+			Uncovered:   c.Uncovered,
+			CompletedAt: completedAt,
+		}
+		for _, step := range c.Steps {
+			for _, charge := range step.Charges {
+				if charge.UserID == userID {
+					report.Charges = append(report.Charges, &ReportCharge{Layer: step.Layer, Currency: step.Currency, Available: charge.Available, Charged: charge.Charged})
+				}
+			}
+		}
+		for _, lot := range c.Lots {
+			if lot.Winner == userID {
+				report.LotsAwarded = append(report.LotsAwarded, lot)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// CloseOutPriceSource 对冲平仓价格来源，sell 为 true 表示清算所卖出
+type CloseOutPriceSource interface {
+	ClosePrice(ctx context.Context, symbol string, sell bool) (decimal.Decimal, error)
+}
+
+// CCPContractRepository CCP 合同仓储
+type CCPContractRepository interface {
+	SaveContracts(ctx context.Context, contracts []*CCPContract) error
+	ListOpenByCounterparty(ctx context.Context, userID string) ([]*CCPContract, error)
+	// UpdateStatusByOriginal 按原结算单批量更新仍未了结合同的状态
+	UpdateStatusByOriginal(ctx context.Context, settlementID, status string) error
+	UpdateStatus(ctx context.Context, contractIDs []string, status string) error
+}
+
+// DefaultManagementRepository 违约处置仓储：会员抵押品、处置案例与拍卖报价
+type DefaultManagementRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	GetCollateral(ctx context.Context, userID, currency string) (*MemberCollateral, error)
+	ListCollateral(ctx context.Context, currency string) ([]*MemberCollateral, error)
+	SaveCollateral(ctx context.Context, collateral *MemberCollateral) error
+
+	SaveCase(ctx context.Context, dc *DefaultCase) error
+	GetCase(ctx context.Context, caseID string) (*DefaultCase, error)
+	GetActiveCase(ctx context.Context, userID string) (*DefaultCase, error)
+	ListCases(ctx context.Context, status DefaultCaseStatus, limit int) ([]*DefaultCase, error)
+
+	SaveBid(ctx context.Context, bid *AuctionBid) error
+	ListBids(ctx context.Context, caseID string) ([]*AuctionBid, error)
+}
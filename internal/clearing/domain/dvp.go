@@ -1,12 +1,29 @@
 // 变更说明：实现 DVP (银货对付) 交收流程，确保券款同步交换，防止本金风险。
-// 假设：DVP 流程包含预取券、预锁款及最终交收三个阶段。
+// 假设：DVP 流程包含锁券、冻款、提交及交收四个阶段；券款两腿均锁定并在期限内提交后才允许任一腿划转，
+// 提交前的任何失败或超时都释放已锁定的腿，提交后只能向前重试直至交收完成。
 package domain
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrDVPNotFound DVP 交收记录不存在
+	ErrDVPNotFound = errors.New("dvp settlement not found")
+	// ErrDVPExpired 锁定期限已过，不允许继续锁定或提交
+	ErrDVPExpired = errors.New("dvp lock deadline exceeded")
+	// ErrDVPNotLocked 券款未同时锁定，不允许提交交收
+	ErrDVPNotLocked = errors.New("dvp legs not both locked")
 )
 
+// DefaultDVPLockTimeout 默认锁定期限：从发起到提交的最长时间
+const DefaultDVPLockTimeout = 30 * time.Second
+
 // DVPStatus 交收状态
 type DVPStatus string
 
@@ -14,72 +31,220 @@ const (
 	DVPStatusPending     DVPStatus = "PENDING"
 	DVPStatusAssetLocked DVPStatus = "ASSET_LOCKED"
 	DVPStatusFundLocked  DVPStatus = "FUND_LOCKED"
-	DVPStatusSettled     DVPStatus = "SETTLED"
-	DVPStatusFailed      DVPStatus = "FAILED"
+	// DVPStatusLocked 券款均已锁定，等待提交
+	DVPStatusLocked DVPStatus = "LOCKED"
+	// DVPStatusSettling 已提交，两腿划转中；此后不再补偿
+	DVPStatusSettling DVPStatus = "SETTLING"
+	DVPStatusSettled  DVPStatus = "SETTLED"
+	DVPStatusFailed   DVPStatus = "FAILED"
 )
 
-// DVPSettlement DVP 交收对象
+// Locking 是否处于提交前的锁定阶段
+func (s DVPStatus) Locking() bool {
+	switch s {
+	case DVPStatusPending, DVPStatusAssetLocked, DVPStatusFundLocked, DVPStatusLocked:
+		return true
+	}
+	return false
+}
+
+// Final 是否为终态
+func (s DVPStatus) Final() bool {
+	return s == DVPStatusSettled || s == DVPStatusFailed
+}
+
+// DVPSettlement DVP 交收对象：卖方交券、买方付款
 type DVPSettlement struct {
-	DVPID        string
-	SettlementID string
-	Status       DVPStatus
-	AssetLocked  bool
-	FundLocked   bool
-	LastUpdate   time.Time
+	DVPID          string
+	SettlementID   string
+	BuyUserID      string
+	SellUserID     string
+	Symbol         string
+	Quantity       decimal.Decimal
+	Currency       string
+	Amount         decimal.Decimal
+	Status         DVPStatus
+	AssetLocked    bool
+	FundLocked     bool
+	AssetDelivered bool
+	FundPaid       bool
+	// Deadline 锁定期限，过期未提交则释放两腿
+	Deadline   time.Time
+	FailReason string
+	CreatedAt  time.Time
+	SettledAt  *time.Time
+	LastUpdate time.Time
 }
 
 // DVPEngine DVP 交收引擎
-type DVPEngine struct{}
+type DVPEngine struct {
+	lockTimeout time.Duration
+}
 
-func NewDVPEngine() *DVPEngine {
-	return &DVPEngine{}
+// NewDVPEngine 创建交收引擎，lockTimeout 不大于 0 时使用默认锁定期限
+func NewDVPEngine(lockTimeout time.Duration) *DVPEngine {
+	if lockTimeout <= 0 {
+		lockTimeout = DefaultDVPLockTimeout
+	}
+	return &DVPEngine{lockTimeout: lockTimeout}
 }
 
 // InitiateDVP 发起 DVP 流程
 func (e *DVPEngine) InitiateDVP(s *Settlement) *DVPSettlement {
+	now := time.Now()
 	return &DVPSettlement{
 		DVPID:        "DVP-" + s.SettlementID,
 		SettlementID: s.SettlementID,
+		BuyUserID:    s.BuyUserID,
+		SellUserID:   s.SellUserID,
+		Symbol:       s.Symbol,
+		Quantity:     s.Quantity,
+		Currency:     s.Currency,
+		Amount:       s.TotalAmount,
 		Status:       DVPStatusPending,
-		LastUpdate:   time.Now(),
+		Deadline:     now.Add(e.lockTimeout),
+		CreatedAt:    now,
+		LastUpdate:   now,
 	}
 }
 
+// Expired 锁定阶段是否已超过期限
+func (e *DVPEngine) Expired(dvp *DVPSettlement, now time.Time) bool {
+	return dvp.Status.Locking() && !now.Before(dvp.Deadline)
+}
+
 // LockAsset 锁定资产 (券)
-func (e *DVPEngine) LockAsset(dvp *DVPSettlement) error {
-	if dvp.Status != DVPStatusPending {
+func (e *DVPEngine) LockAsset(dvp *DVPSettlement, now time.Time) error {
+	if dvp.Status != DVPStatusPending && dvp.Status != DVPStatusFundLocked {
 		return fmt.Errorf("invalid status for asset locking: %s", dvp.Status)
 	}
+	if e.Expired(dvp, now) {
+		return ErrDVPExpired
+	}
 	dvp.AssetLocked = true
 	if dvp.FundLocked {
-		dvp.Status = DVPStatusSettled
+		dvp.Status = DVPStatusLocked
 	} else {
 		dvp.Status = DVPStatusAssetLocked
 	}
-	dvp.LastUpdate = time.Now()
+	dvp.LastUpdate = now
 	return nil
 }
 
 // LockFund 锁定资金 (款)
-func (e *DVPEngine) LockFund(dvp *DVPSettlement) error {
+func (e *DVPEngine) LockFund(dvp *DVPSettlement, now time.Time) error {
 	if dvp.Status != DVPStatusPending && dvp.Status != DVPStatusAssetLocked {
 		return fmt.Errorf("invalid status for fund locking: %s", dvp.Status)
 	}
+	if e.Expired(dvp, now) {
+		return ErrDVPExpired
+	}
 	dvp.FundLocked = true
 	if dvp.AssetLocked {
-		dvp.Status = DVPStatusSettled
+		dvp.Status = DVPStatusLocked
 	} else {
 		dvp.Status = DVPStatusFundLocked
 	}
+	dvp.LastUpdate = now
+	return nil
+}
+
+// Finalize 提交交收：仅当券款均已锁定且未超过锁定期限时进入划转阶段
+func (e *DVPEngine) Finalize(dvp *DVPSettlement, now time.Time) error {
+	if dvp.Status != DVPStatusLocked || !dvp.AssetLocked || !dvp.FundLocked {
+		return fmt.Errorf("%w: status %s", ErrDVPNotLocked, dvp.Status)
+	}
+	if e.Expired(dvp, now) {
+		return ErrDVPExpired
+	}
+	dvp.Status = DVPStatusSettling
+	dvp.LastUpdate = now
+	return nil
+}
+
+// ReleaseAsset 记录券已释放
+func (e *DVPEngine) ReleaseAsset(dvp *DVPSettlement) error {
+	if !dvp.Status.Locking() && dvp.Status != DVPStatusFailed {
+		return fmt.Errorf("cannot release asset in status %s", dvp.Status)
+	}
+	dvp.AssetLocked = false
+	dvp.LastUpdate = time.Now()
+	return nil
+}
+
+// ReleaseFund 记录款已解冻
+func (e *DVPEngine) ReleaseFund(dvp *DVPSettlement) error {
+	if !dvp.Status.Locking() && dvp.Status != DVPStatusFailed {
+		return fmt.Errorf("cannot release fund in status %s", dvp.Status)
+	}
+	dvp.FundLocked = false
 	dvp.LastUpdate = time.Now()
 	return nil
 }
 
-// Finalize 执行交收
-func (e *DVPEngine) Finalize(dvp *DVPSettlement) error {
-	if dvp.Status != DVPStatusSettled {
-		return fmt.Errorf("cannot finalize: assets or funds not locked")
+// Fail 提交前失败：标记失败，已锁定的腿由调用方释放
+func (e *DVPEngine) Fail(dvp *DVPSettlement, reason string) error {
+	if !dvp.Status.Locking() && dvp.Status != DVPStatusFailed {
+		return fmt.Errorf("cannot fail dvp in status %s", dvp.Status)
 	}
+	dvp.Status = DVPStatusFailed
+	dvp.FailReason = reason
 	dvp.LastUpdate = time.Now()
 	return nil
 }
+
+// MarkAssetDelivered 记录券已交付
+func (e *DVPEngine) MarkAssetDelivered(dvp *DVPSettlement) error {
+	if dvp.Status != DVPStatusSettling {
+		return fmt.Errorf("cannot deliver asset in status %s", dvp.Status)
+	}
+	dvp.AssetDelivered = true
+	e.complete(dvp)
+	return nil
+}
+
+// MarkFundPaid 记录款已划付
+func (e *DVPEngine) MarkFundPaid(dvp *DVPSettlement) error {
+	if dvp.Status != DVPStatusSettling {
+		return fmt.Errorf("cannot pay fund in status %s", dvp.Status)
+	}
+	dvp.FundPaid = true
+	e.complete(dvp)
+	return nil
+}
+
+func (e *DVPEngine) complete(dvp *DVPSettlement) {
+	now := time.Now()
+	if dvp.AssetDelivered && dvp.FundPaid {
+		dvp.Status = DVPStatusSettled
+		dvp.SettledAt = &now
+	}
+	dvp.LastUpdate = now
+}
+
+// DVPAssetLeg 证券腿：在托管库位锁定、释放与交付卖方证券，所有操作按 DVPID 幂等
+type DVPAssetLeg interface {
+	Lock(ctx context.Context, dvp *DVPSettlement) error
+	// Release 须容忍锁定请求未到达或尚未到达的情况（空补偿与防悬挂）
+	Release(ctx context.Context, dvp *DVPSettlement) error
+	Deliver(ctx context.Context, dvp *DVPSettlement) error
+}
+
+// DVPCashLeg 资金腿：冻结、解冻买方资金并划付给卖方，所有操作按 DVPID 幂等
+type DVPCashLeg interface {
+	Freeze(ctx context.Context, dvp *DVPSettlement) error
+	// Unfreeze 须容忍冻结请求未到达或尚未到达的情况（空补偿与防悬挂）
+	Unfreeze(ctx context.Context, dvp *DVPSettlement) error
+	Pay(ctx context.Context, dvp *DVPSettlement) error
+}
+
+// DVPRepository DVP 交收仓储
+type DVPRepository interface {
+	Save(ctx context.Context, dvp *DVPSettlement) error
+	// SaveIfStatus 仅当库中状态仍为 expected 时保存，返回是否更新成功；用于提交与超期失败之间的状态竞争
+	SaveIfStatus(ctx context.Context, dvp *DVPSettlement, expected DVPStatus) (bool, error)
+	// Get 记录不存在时返回 ErrDVPNotFound
+	Get(ctx context.Context, dvpID string) (*DVPSettlement, error)
+	// ListOpen 返回尚未进入终态的交收
+	ListOpen(ctx context.Context, limit int) ([]*DVPSettlement, error)
+}
@@ -1,12 +1,20 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 const (
-	SettlementCreatedEventType   = "clearing.settlement.created"
-	SettlementCompletedEventType = "clearing.settlement.completed"
-	SettlementFailedEventType    = "clearing.settlement.failed"
-	FXHedgeExecutedEventType     = "clearing.fx_hedge.executed"
+	SettlementCreatedEventType    = "clearing.settlement.created"
+	SettlementCompletedEventType  = "clearing.settlement.completed"
+	SettlementFailedEventType     = "clearing.settlement.failed"
+	FXHedgeExecutedEventType      = "clearing.fx_hedge.executed"
+	ClearingRunCompletedEventType = "clearing.eod.completed"
+	DefaultDeclaredEventType      = "clearing.default.declared"
+	DefaultCaseCompletedEventType = "clearing.default.completed"
+	DefaultMemberReportEventType  = "clearing.default.member_report"
 )
 
 // ClearingEvent 清算领域事件接口
@@ -51,3 +59,41 @@ type SettlementFailedEvent struct {
 }
 
 func (e SettlementFailedEvent) EventType() string { return "SettlementFailed" }
+
+// ClearingRunCompletedEvent 日终清算完成事件
+type ClearingRunCompletedEvent struct {
+	BaseEvent
+	RunID           string `json:"run_id"`
+	ClearingDate    string `json:"clearing_date"`
+	SettlementCount int    `json:"settlement_count"`
+	MemberCount     int    `json:"member_count"`
+	TotalVolume     string `json:"total_volume"`
+	TotalFee        string `json:"total_fee"`
+}
+
+func (e ClearingRunCompletedEvent) EventType() string { return "ClearingRunCompleted" }
+
+// DefaultDeclaredEvent 清算会员违约事件
+type DefaultDeclaredEvent struct {
+	BaseEvent
+	CaseID    string `json:"case_id"`
+	UserID    string `json:"user_id"`
+	Reason    string `json:"reason"`
+	Method    string `json:"method"`
+	Lots      int    `json:"lots"`
+	Contracts int    `json:"contracts"`
+}
+
+func (e DefaultDeclaredEvent) EventType() string { return "DefaultDeclared" }
+
+// DefaultCaseCompletedEvent 违约处置完成事件
+type DefaultCaseCompletedEvent struct {
+	BaseEvent
+	CaseID    string                     `json:"case_id"`
+	UserID    string                     `json:"user_id"`
+	TotalLoss map[string]decimal.Decimal `json:"total_loss"`
+	Uncovered map[string]decimal.Decimal `json:"uncovered"`
+	Steps     []*WaterfallStep           `json:"steps"`
+}
+
+func (e DefaultCaseCompletedEvent) EventType() string { return "DefaultCaseCompleted" }
@@ -13,6 +13,8 @@ const (
 	StatusPending   SettlementStatus = "PENDING"
 	StatusCompleted SettlementStatus = "COMPLETED"
 	StatusFailed    SettlementStatus = "FAILED"
+	// StatusDVPInProgress 已被 DVP 交收认领，日终清算不再纳入
+	StatusDVPInProgress SettlementStatus = "DVP_IN_PROGRESS"
 )
 
 // Settlement 结算单聚合根
@@ -33,22 +35,29 @@ type Settlement struct {
 	Status       SettlementStatus `json:"status"`
 	SettledAt    *time.Time       `json:"settled_at"`
 	ErrorMessage string           `json:"error_message"`
+	// ClearingRunID 认领该结算单的日终清算任务
+	ClearingRunID string `json:"clearing_run_id"`
+	// SettlementDate 预定结算日（按结算日历计算 T+N，日历不可用时按跳过周末的 T+N）
+	SettlementDate time.Time `json:"settlement_date"`
 }
 
 // NewSettlement 创建新的结算单
 func NewSettlement(settlementID, tradeID, buyUser, sellUser, symbol, currency string, qty, price decimal.Decimal) *Settlement {
 	total := qty.Mul(price)
+	y, m, d := time.Now().Date()
+	tradeDate := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
 	return &Settlement{
-		SettlementID: settlementID,
-		TradeID:      tradeID,
-		BuyUserID:    buyUser,
-		SellUserID:   sellUser,
-		Symbol:       symbol,
-		Currency:     currency,
-		Quantity:     qty,
-		Price:        price,
-		TotalAmount:  total,
-		Status:       StatusPending,
+		SettlementID:   settlementID,
+		TradeID:        tradeID,
+		BuyUserID:      buyUser,
+		SellUserID:     sellUser,
+		Symbol:         symbol,
+		Currency:       currency,
+		Quantity:       qty,
+		Price:          price,
+		TotalAmount:    total,
+		Status:         StatusPending,
+		SettlementDate: tradeDate,
 	}
 }
 
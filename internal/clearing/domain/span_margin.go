@@ -0,0 +1,614 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SpanScenarioCount SPAN 标准情景数量
+const SpanScenarioCount = 16
+
+var (
+	// ErrInvalidSpanParameters SPAN 参数文件不合法
+	ErrInvalidSpanParameters = errors.New("invalid span parameters")
+	// ErrUnknownSpanInstrument 持仓合约未在任何产品组中配置
+	ErrUnknownSpanInstrument = errors.New("unknown span instrument")
+	// ErrMissingSpanPrice 缺少合约或标的价格
+	ErrMissingSpanPrice = errors.New("missing span price")
+)
+
+// SpanScenario 单个 SPAN 情景：价格变动为扫描区间的倍数，波动率变动为波动率扫描区间的倍数，
+// Cover 为计入组合损失的比例（极端情景仅计入部分损失）
+type SpanScenario struct {
+	PriceMove float64 `json:"price_move"`
+	VolMove   float64 `json:"vol_move"`
+	Cover     float64 `json:"cover"`
+}
+
+// SpanScenarios 返回标准 16 情景：价格 0、±1/3、±2/3、±3/3 与波动率上/下组合，
+// 以及价格 ±极端倍数、波动率不变的两个极端情景
+func SpanScenarios(extremeMultiple, extremeCover float64) [SpanScenarioCount]SpanScenario {
+	var s [SpanScenarioCount]SpanScenario
+	moves := []float64{0, 1.0 / 3, -1.0 / 3, 2.0 / 3, -2.0 / 3, 1, -1}
+	i := 0
+	for _, move := range moves {
+		s[i] = SpanScenario{PriceMove: move, VolMove: 1, Cover: 1}
+		s[i+1] = SpanScenario{PriceMove: move, VolMove: -1, Cover: 1}
+		i += 2
+	}
+	s[14] = SpanScenario{PriceMove: extremeMultiple, Cover: extremeCover}
+	s[15] = SpanScenario{PriceMove: -extremeMultiple, Cover: extremeCover}
+	return s
+}
+
+// SpanInstrumentType 合约类型
+type SpanInstrumentType string
+
+const (
+	SpanFuture SpanInstrumentType = "FUTURE"
+	SpanOption SpanInstrumentType = "OPTION"
+)
+
+// SpanInstrument 产品组内的合约定义
+type SpanInstrument struct {
+	Symbol     string             `json:"symbol"`
+	Type       SpanInstrumentType `json:"type"`
+	Underlying string             `json:"underlying,omitempty"`  // 期权对应的期货合约
+	OptionType string             `json:"option_type,omitempty"` // CALL / PUT
+	Strike     float64            `json:"strike,omitempty"`
+	Expiry     time.Time          `json:"expiry"`
+	Multiplier float64            `json:"multiplier"`
+	// Tier 跨期价差分层，不同层之间的对冲头寸计收跨期价差费
+	Tier int `json:"tier"`
+	// Volatility 未提供行情波动率时使用的默认隐含波动率
+	Volatility float64 `json:"volatility,omitempty"`
+}
+
+// SpanProductGroup 一个产品组（组合商品）的 SPAN 参数，对应一个参数文件
+type SpanProductGroup struct {
+	Group    string `json:"group"`
+	Currency string `json:"currency"`
+	// PriceScanRate 价格扫描区间，占标的价格比例
+	PriceScanRate float64 `json:"price_scan_rate"`
+	// VolScanRange 波动率扫描区间，绝对值
+	VolScanRange        float64 `json:"vol_scan_range"`
+	ExtremeMoveMultiple float64 `json:"extreme_move_multiple"`
+	ExtremeMoveCover    float64 `json:"extreme_move_cover"`
+	// IntraSpreadCharge 每组跨期价差（1 delta 对 1 delta）的收费
+	IntraSpreadCharge float64 `json:"intra_spread_charge"`
+	// ShortOptionMinimum 每手空头期权的最低保证金
+	ShortOptionMinimum  float64          `json:"short_option_minimum"`
+	InitialToMaintRatio float64          `json:"initial_to_maintenance"`
+	RiskFreeRate        float64          `json:"risk_free_rate"`
+	Instruments         []SpanInstrument `json:"instruments"`
+}
+
+// Validate 校验参数并补齐默认值
+func (g *SpanProductGroup) Validate() error {
+	if g.Group == "" {
+		return fmt.Errorf("%w: group is required", ErrInvalidSpanParameters)
+	}
+	if g.PriceScanRate <= 0 || g.PriceScanRate >= 1 {
+		return fmt.Errorf("%w: %s price_scan_rate must be in (0,1)", ErrInvalidSpanParameters, g.Group)
+	}
+	if g.VolScanRange < 0 || g.IntraSpreadCharge < 0 || g.ShortOptionMinimum < 0 {
+		return fmt.Errorf("%w: %s ranges and charges must not be negative", ErrInvalidSpanParameters, g.Group)
+	}
+	if g.ExtremeMoveMultiple == 0 {
+		g.ExtremeMoveMultiple = 3
+	}
+	if g.ExtremeMoveCover == 0 {
+		g.ExtremeMoveCover = 0.35
+	}
+	if g.InitialToMaintRatio == 0 {
+		g.InitialToMaintRatio = 1.1
+	}
+	if g.InitialToMaintRatio < 1 {
+		return fmt.Errorf("%w: %s initial_to_maintenance must be >= 1", ErrInvalidSpanParameters, g.Group)
+	}
+	if len(g.Instruments) == 0 {
+		return fmt.Errorf("%w: %s has no instruments", ErrInvalidSpanParameters, g.Group)
+	}
+
+	futures := make(map[string]bool)
+	for _, inst := range g.Instruments {
+		if inst.Type == SpanFuture {
+			futures[inst.Symbol] = true
+		}
+	}
+	seen := make(map[string]bool)
+	for i := range g.Instruments {
+		inst := &g.Instruments[i]
+		if inst.Symbol == "" || seen[inst.Symbol] {
+			return fmt.Errorf("%w: %s has empty or duplicate symbol %q", ErrInvalidSpanParameters, g.Group, inst.Symbol)
+		}
+		seen[inst.Symbol] = true
+		if inst.Multiplier == 0 {
+			inst.Multiplier = 1
+		}
+		switch inst.Type {
+		case SpanFuture:
+		case SpanOption:
+			if !futures[inst.Underlying] {
+				return fmt.Errorf("%w: option %s underlying %q is not a future of group %s", ErrInvalidSpanParameters, inst.Symbol, inst.Underlying, g.Group)
+			}
+			if inst.OptionType != "CALL" && inst.OptionType != "PUT" {
+				return fmt.Errorf("%w: option %s has invalid option_type %q", ErrInvalidSpanParameters, inst.Symbol, inst.OptionType)
+			}
+			if inst.Strike <= 0 {
+				return fmt.Errorf("%w: option %s strike must be positive", ErrInvalidSpanParameters, inst.Symbol)
+			}
+		default:
+			return fmt.Errorf("%w: %s has unsupported type %q", ErrInvalidSpanParameters, inst.Symbol, inst.Type)
+		}
+	}
+	return nil
+}
+
+// InterSpreadLeg 跨品种价差的一条腿，DeltaRatio 为组成一组价差所需的该组净 delta。
+// Side 为 A 或 B：同侧各腿净 delta 同向、两侧相反才能组对；两腿价差可省略，默认首腿 A、次腿 B。
+type InterSpreadLeg struct {
+	Group      string  `json:"group"`
+	DeltaRatio float64 `json:"delta_ratio"`
+	Side       string  `json:"side"`
+}
+
+const (
+	InterSpreadSideA = "A"
+	InterSpreadSideB = "B"
+)
+
+// legSide 返回腿所在的一侧，兼容未标注 Side 的两腿价差
+func (s *InterCommoditySpread) legSide(i int) string {
+	if side := s.Legs[i].Side; side != "" {
+		return side
+	}
+	if i == 0 {
+		return InterSpreadSideA
+	}
+	return InterSpreadSideB
+}
+
+// validate 校验腿数、抵扣比例与两侧划分；多于两腿时每条腿必须显式标注 Side
+func (s *InterCommoditySpread) validate(groups map[string]*SpanProductGroup) error {
+	if len(s.Legs) < 2 || s.CreditRate <= 0 || s.CreditRate > 1 {
+		return fmt.Errorf("%w: inter spread priority %d needs >=2 legs and credit_rate in (0,1]", ErrInvalidSpanParameters, s.Priority)
+	}
+	var sideA, sideB int
+	for i, leg := range s.Legs {
+		if _, ok := groups[leg.Group]; !ok || leg.DeltaRatio <= 0 {
+			return fmt.Errorf("%w: inter spread priority %d has invalid leg %s", ErrInvalidSpanParameters, s.Priority, leg.Group)
+		}
+		if len(s.Legs) > 2 && leg.Side == "" {
+			return fmt.Errorf("%w: inter spread priority %d leg %s needs side A or B", ErrInvalidSpanParameters, s.Priority, leg.Group)
+		}
+		switch s.legSide(i) {
+		case InterSpreadSideA:
+			sideA++
+		case InterSpreadSideB:
+			sideB++
+		default:
+			return fmt.Errorf("%w: inter spread priority %d leg %s has invalid side %q", ErrInvalidSpanParameters, s.Priority, leg.Group, leg.Side)
+		}
+	}
+	if sideA == 0 || sideB == 0 {
+		return fmt.Errorf("%w: inter spread priority %d needs legs on both sides", ErrInvalidSpanParameters, s.Priority)
+	}
+	return nil
+}
+
+// InterCommoditySpread 跨品种价差抵扣参数，按 Priority 从小到大依次组对
+type InterCommoditySpread struct {
+	Priority   int              `json:"priority"`
+	Legs       []InterSpreadLeg `json:"legs"`
+	CreditRate float64          `json:"credit_rate"`
+}
+
+// SpanParameterSet 全部产品组与跨品种价差参数
+type SpanParameterSet struct {
+	Groups       []*SpanProductGroup     `json:"groups"`
+	InterSpreads []*InterCommoditySpread `json:"inter_spreads"`
+	LoadedAt     time.Time               `json:"loaded_at"`
+}
+
+// SpanPosition 持仓，数量为带符号手数，正数为多头
+type SpanPosition struct {
+	Symbol   string          `json:"symbol"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// SpanMarketData 行情：期货价格与各期货标的的隐含波动率
+type SpanMarketData struct {
+	Prices       map[string]float64 `json:"prices"`
+	Volatilities map[string]float64 `json:"volatilities"`
+	AsOf         time.Time          `json:"as_of"`
+}
+
+// SpanRiskArray 单个合约一手多头在 16 个情景下的损失（正数为损失）
+type SpanRiskArray struct {
+	Symbol    string                     `json:"symbol"`
+	Price     float64                    `json:"price"`
+	Delta     float64                    `json:"delta"`
+	Scenarios [SpanScenarioCount]float64 `json:"scenarios"`
+}
+
+// SpanGroupResult 产品组保证金明细
+type SpanGroupResult struct {
+	Group              string          `json:"group"`
+	Currency           string          `json:"currency"`
+	ScanRisk           decimal.Decimal `json:"scan_risk"`
+	WorstScenario      int             `json:"worst_scenario"`
+	IntraSpreadCharge  decimal.Decimal `json:"intra_spread_charge"`
+	InterSpreadCredit  decimal.Decimal `json:"inter_spread_credit"`
+	ShortOptionMinimum decimal.Decimal `json:"short_option_minimum"`
+	NetDelta           float64         `json:"net_delta"`
+	NetOptionValue     decimal.Decimal `json:"net_option_value"`   // 期权多头市值减空头市值，按权利金结算抵扣保证金
+	RiskRequirement    decimal.Decimal `json:"risk_requirement"`   // 扫描风险 + 跨期价差 - 跨品种抵扣，不低于空头期权最低保证金
+	MarginRequirement  decimal.Decimal `json:"margin_requirement"` // 维持保证金 = max(RiskRequirement - NetOptionValue, 0)
+	ScenarioLosses     []float64       `json:"scenario_losses"`
+}
+
+// SpanResult 组合 SPAN 保证金结果，按币种汇总
+type SpanResult struct {
+	Groups            []*SpanGroupResult         `json:"groups"`
+	MaintenanceMargin map[string]decimal.Decimal `json:"maintenance_margin"`
+	InitialMargin     map[string]decimal.Decimal `json:"initial_margin"`
+	CalculatedAt      time.Time                  `json:"calculated_at"`
+}
+
+type spanInstrumentRef struct {
+	group *SpanProductGroup
+	inst  *SpanInstrument
+}
+
+// SpanEngine SPAN 风险数组保证金引擎，参数集加载后只读，可并发使用
+type SpanEngine struct {
+	params      *SpanParameterSet
+	groups      map[string]*SpanProductGroup
+	instruments map[string]spanInstrumentRef
+	pricer      *BlackScholesOptionPricer
+}
+
+// NewSpanEngine 校验参数集并构建引擎
+func NewSpanEngine(params *SpanParameterSet) (*SpanEngine, error) {
+	e := &SpanEngine{
+		params:      params,
+		groups:      make(map[string]*SpanProductGroup),
+		instruments: make(map[string]spanInstrumentRef),
+		pricer:      &BlackScholesOptionPricer{},
+	}
+	for _, g := range params.Groups {
+		if err := g.Validate(); err != nil {
+			return nil, err
+		}
+		if _, dup := e.groups[g.Group]; dup {
+			return nil, fmt.Errorf("%w: duplicate group %s", ErrInvalidSpanParameters, g.Group)
+		}
+		e.groups[g.Group] = g
+		for i := range g.Instruments {
+			inst := &g.Instruments[i]
+			if other, dup := e.instruments[inst.Symbol]; dup {
+				return nil, fmt.Errorf("%w: symbol %s defined in both %s and %s", ErrInvalidSpanParameters, inst.Symbol, other.group.Group, g.Group)
+			}
+			e.instruments[inst.Symbol] = spanInstrumentRef{group: g, inst: inst}
+		}
+	}
+	for _, s := range params.InterSpreads {
+		if err := s.validate(e.groups); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(params.InterSpreads, func(i, j int) bool { return params.InterSpreads[i].Priority < params.InterSpreads[j].Priority })
+	return e, nil
+}
+
+// Parameters 返回当前参数集
+func (e *SpanEngine) Parameters() *SpanParameterSet {
+	return e.params
+}
+
+// RequiredPrices 返回计算持仓所需的期货价格代码（期权取其标的）
+func (e *SpanEngine) RequiredPrices(positions []SpanPosition) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, pos := range positions {
+		ref, ok := e.instruments[pos.Symbol]
+		if !ok {
+			continue
+		}
+		symbol := ref.inst.Symbol
+		if ref.inst.Type == SpanOption {
+			symbol = ref.inst.Underlying
+		}
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// RiskArray 计算合约一手多头的风险数组
+func (e *SpanEngine) RiskArray(symbol string, market *SpanMarketData) (*SpanRiskArray, error) {
+	ref, ok := e.instruments[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSpanInstrument, symbol)
+	}
+	g, inst := ref.group, ref.inst
+	scenarios := SpanScenarios(g.ExtremeMoveMultiple, g.ExtremeMoveCover)
+	asOf := market.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+
+	if inst.Type == SpanFuture {
+		price, ok := market.Prices[inst.Symbol]
+		if !ok || price <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMissingSpanPrice, inst.Symbol)
+		}
+		ra := &SpanRiskArray{Symbol: symbol, Price: price, Delta: 1}
+		scan := price * g.PriceScanRate
+		for i, s := range scenarios {
+			ra.Scenarios[i] = -s.PriceMove * scan * s.Cover * inst.Multiplier
+		}
+		return ra, nil
+	}
+
+	underlying, ok := market.Prices[inst.Underlying]
+	if !ok || underlying <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingSpanPrice, inst.Underlying)
+	}
+	vol := market.Volatilities[inst.Underlying]
+	if vol <= 0 {
+		vol = inst.Volatility
+	}
+	if vol <= 0 {
+		return nil, fmt.Errorf("%w: volatility of %s", ErrMissingSpanPrice, inst.Underlying)
+	}
+	t := inst.Expiry.Sub(asOf).Hours() / 24 / 365
+	price := e.pricer.Price(inst.OptionType, underlying, inst.Strike, t, g.RiskFreeRate, vol)
+	ra := &SpanRiskArray{Symbol: symbol, Price: price}
+
+	// 数值 delta：标的上下各移动 1%
+	bump := underlying * 0.01
+	up := e.pricer.Price(inst.OptionType, underlying+bump, inst.Strike, t, g.RiskFreeRate, vol)
+	down := e.pricer.Price(inst.OptionType, underlying-bump, inst.Strike, t, g.RiskFreeRate, vol)
+	ra.Delta = (up - down) / (2 * bump)
+
+	scan := underlying * g.PriceScanRate
+	for i, s := range scenarios {
+		shocked := math.Max(underlying+s.PriceMove*scan, 0)
+		shockedVol := math.Max(vol+s.VolMove*g.VolScanRange, 0.0001)
+		value := e.pricer.Price(inst.OptionType, shocked, inst.Strike, t, g.RiskFreeRate, shockedVol)
+		ra.Scenarios[i] = (price - value) * s.Cover * inst.Multiplier
+	}
+	return ra, nil
+}
+
+// Calculate 计算组合 SPAN 保证金：
+// 各产品组取 16 情景下的最大损失作为扫描风险，加收跨期价差费，扣减跨品种价差抵扣，
+// 并与空头期权最低保证金取大者；维持保证金按币种汇总，初始保证金按组乘以初始/维持比例。
+func (e *SpanEngine) Calculate(positions []SpanPosition, market *SpanMarketData) (*SpanResult, error) {
+	type groupState struct {
+		result     *SpanGroupResult
+		losses     [SpanScenarioCount]float64
+		tierDeltas map[int]float64
+		shortCalls float64
+		shortPuts  float64
+		remaining  float64 // 尚未参与跨品种组对的净 delta
+	}
+	states := make(map[string]*groupState)
+
+	for _, pos := range positions {
+		if pos.Quantity.IsZero() {
+			continue
+		}
+		ref, ok := e.instruments[pos.Symbol]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSpanInstrument, pos.Symbol)
+		}
+		ra, err := e.RiskArray(pos.Symbol, market)
+		if err != nil {
+			return nil, err
+		}
+		g, inst := ref.group, ref.inst
+		st, ok := states[g.Group]
+		if !ok {
+			st = &groupState{
+				result:     &SpanGroupResult{Group: g.Group, Currency: g.Currency},
+				tierDeltas: make(map[int]float64),
+			}
+			states[g.Group] = st
+		}
+
+		qty := pos.Quantity.InexactFloat64()
+		for i := range st.losses {
+			st.losses[i] += qty * ra.Scenarios[i]
+		}
+		st.tierDeltas[inst.Tier] += qty * ra.Delta
+		st.result.NetDelta += qty * ra.Delta
+		if inst.Type == SpanOption {
+			st.result.NetOptionValue = st.result.NetOptionValue.Add(decimal.NewFromFloat(qty * ra.Price * inst.Multiplier))
+			if qty < 0 {
+				if inst.OptionType == "CALL" {
+					st.shortCalls += -qty
+				} else {
+					st.shortPuts += -qty
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scanRisk := make(map[string]float64, len(states))
+	for _, name := range names {
+		st, g := states[name], e.groups[name]
+		worst, worstIdx := 0.0, 0
+		for i, loss := range st.losses {
+			if loss > worst {
+				worst, worstIdx = loss, i+1
+			}
+		}
+		scanRisk[name] = worst
+		st.remaining = st.result.NetDelta
+		st.result.ScanRisk = decimal.NewFromFloat(worst).Round(2)
+		st.result.WorstScenario = worstIdx
+		st.result.ScenarioLosses = st.losses[:]
+		st.result.NetOptionValue = st.result.NetOptionValue.Round(2)
+		st.result.IntraSpreadCharge = decimal.NewFromFloat(intraSpreads(st.tierDeltas) * g.IntraSpreadCharge).Round(2)
+		st.result.ShortOptionMinimum = decimal.NewFromFloat(math.Max(st.shortCalls, st.shortPuts) * g.ShortOptionMinimum).Round(2)
+	}
+
+	// 跨品种价差：A 侧各腿净 delta 同向、B 侧与之相反时才能组对，组数取各腿可用 delta / 比例的最小值；
+	// 每条腿抵扣额 = 组数 × 该腿 delta 比例 × 每 delta 价格风险 × 抵扣比例
+	credits := make(map[string]float64)
+	for _, spread := range e.params.InterSpreads {
+		legs := make([]*groupState, len(spread.Legs))
+		spreads := math.Inf(1)
+		sign := 0.0
+		for i, leg := range spread.Legs {
+			st := states[leg.Group]
+			if st == nil || st.remaining == 0 {
+				spreads = 0
+				break
+			}
+			want := math.Copysign(1, st.remaining)
+			if spread.legSide(i) != InterSpreadSideA {
+				want = -want
+			}
+			if sign == 0 {
+				sign = want
+			} else if want != sign {
+				spreads = 0
+				break
+			}
+			legs[i] = st
+			spreads = math.Min(spreads, math.Abs(st.remaining)/leg.DeltaRatio)
+		}
+		if spreads <= 0 || math.IsInf(spreads, 1) {
+			continue
+		}
+		for i, leg := range spread.Legs {
+			st := legs[i]
+			used := spreads * leg.DeltaRatio
+			if st.result.NetDelta != 0 {
+				perDelta := scanRisk[leg.Group] / math.Abs(st.result.NetDelta)
+				credits[leg.Group] += used * perDelta * spread.CreditRate
+			}
+			if st.remaining > 0 {
+				st.remaining -= used
+			} else {
+				st.remaining += used
+			}
+		}
+	}
+
+	result := &SpanResult{
+		MaintenanceMargin: make(map[string]decimal.Decimal),
+		InitialMargin:     make(map[string]decimal.Decimal),
+		CalculatedAt:      time.Now(),
+	}
+	for _, name := range names {
+		st, g := states[name], e.groups[name]
+		credit := math.Min(credits[name], scanRisk[name])
+		st.result.InterSpreadCredit = decimal.NewFromFloat(credit).Round(2)
+		risk := st.result.ScanRisk.Add(st.result.IntraSpreadCharge).Sub(st.result.InterSpreadCredit)
+		risk = decimal.Max(risk, st.result.ShortOptionMinimum, decimal.Zero)
+		st.result.RiskRequirement = risk
+		// 期权权利金已结算：多头期权市值可抵扣、空头期权市值需额外覆盖
+		margin := decimal.Max(risk.Sub(st.result.NetOptionValue), decimal.Zero)
+		st.result.MarginRequirement = margin
+
+		result.Groups = append(result.Groups, st.result)
+		result.MaintenanceMargin[g.Currency] = result.MaintenanceMargin[g.Currency].Add(margin)
+		initial := decimal.Max(risk.Mul(decimal.NewFromFloat(g.InitialToMaintRatio)).Sub(st.result.NetOptionValue), decimal.Zero).Round(2)
+		result.InitialMargin[g.Currency] = result.InitialMargin[g.Currency].Add(initial)
+	}
+	return result, nil
+}
+
+// intraSpreads 同组不同层之间可组成的跨期价差组数：
+// 多头 delta 与空头 delta 分属不同层时按较小一侧计组
+func intraSpreads(tierDeltas map[int]float64) float64 {
+	if len(tierDeltas) < 2 {
+		return 0
+	}
+	var long, short float64
+	for _, d := range tierDeltas {
+		if d > 0 {
+			long += d
+		} else {
+			short -= d
+		}
+	}
+	return math.Min(long, short)
+}
+
+// Health 以某币种的 SPAN 维持保证金评估账户健康度，equity 须为同一币种
+func (r *SpanResult) Health(userID, currency string, equity decimal.Decimal) *AccountHealth {
+	mm := r.MaintenanceMargin[currency]
+	scale := 1.0
+	if mm.IsPositive() {
+		scale = equity.InexactFloat64() / mm.InexactFloat64()
+	}
+	return &AccountHealth{
+		UserID:            userID,
+		TotalEquity:       equity,
+		MaintenanceMargin: mm,
+		HealthScale:       scale,
+	}
+}
+
+// CheckAccountHealth 以 SPAN 维持保证金核查账户健康度
+func (e *SpanEngine) CheckAccountHealth(userID, currency string, equity decimal.Decimal, positions []SpanPosition, market *SpanMarketData) (*AccountHealth, error) {
+	res, err := e.Calculate(positions, market)
+	if err != nil {
+		return nil, err
+	}
+	return res.Health(userID, currency, equity), nil
+}
+
+// BlackScholesOptionPricer 期货期权 Black-76 定价（以期货价格为标的）
+type BlackScholesOptionPricer struct{}
+
+// Price 计算期权理论价格；到期或零波动率时返回内在价值
+func (p *BlackScholesOptionPricer) Price(optionType string, forward, strike, t, rate, vol float64) float64 {
+	isCall := optionType != "PUT"
+	if t <= 0 || vol <= 0 || forward <= 0 {
+		if isCall {
+			return math.Max(forward-strike, 0)
+		}
+		return math.Max(strike-forward, 0)
+	}
+	sqrtT := math.Sqrt(t)
+	d1 := (math.Log(forward/strike) + 0.5*vol*vol*t) / (vol * sqrtT)
+	d2 := d1 - vol*sqrtT
+	discount := math.Exp(-rate * t)
+	cdf := func(x float64) float64 { return 0.5 * math.Erfc(-x/math.Sqrt2) }
+	if isCall {
+		return discount * (forward*cdf(d1) - strike*cdf(d2))
+	}
+	return discount * (strike*cdf(-d2) - forward*cdf(-d1))
+}
+
+// SpanPriceSource 补齐请求中未提供的期货价格
+type SpanPriceSource interface {
+	LastPrice(ctx context.Context, symbol string) (float64, error)
+}
+
+// SpanParameterLoader SPAN 参数来源，Version 用于判断参数是否变化以便热加载
+type SpanParameterLoader interface {
+	Load() (*SpanParameterSet, error)
+	Version() (time.Time, error)
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+)
+
+const calendarDateLayout = "2006-01-02"
+
+// GRPCSettlementCalendar 通过参考数据服务的节假日日历计算预定结算日
+type GRPCSettlementCalendar struct {
+	client referencedatav1.ReferenceDataServiceClient
+}
+
+// NewGRPCSettlementCalendar 创建结算日历客户端
+func NewGRPCSettlementCalendar(client referencedatav1.ReferenceDataServiceClient) *GRPCSettlementCalendar {
+	return &GRPCSettlementCalendar{client: client}
+}
+
+func (c *GRPCSettlementCalendar) SettlementDate(ctx context.Context, market, currency string, tradeDate time.Time, days int) (time.Time, error) {
+	resp, err := c.client.GetSettlementDate(ctx, &referencedatav1.GetSettlementDateRequest{
+		Market:    market,
+		Currency:  currency,
+		TradeDate: tradeDate.Format(calendarDateLayout),
+		Days:      int32(days),
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(calendarDateLayout, resp.SettlementDate)
+}
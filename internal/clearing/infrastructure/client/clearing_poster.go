@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dtm-labs/client/dtmgrpc/dtmgimp"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+)
+
+// GRPCClearingPoster 通过账户与持仓服务的 Saga 接口过账日终清算分录。
+// 分录 ID 同时作为业务幂等键与 DTM 全局事务 ID 透传，下游据此识别重复过账。
+type GRPCClearingPoster struct {
+	account  accountv1.AccountServiceClient
+	position positionv1.PositionServiceClient
+}
+
+// NewGRPCClearingPoster 创建分录过账适配器
+func NewGRPCClearingPoster(account accountv1.AccountServiceClient, position positionv1.PositionServiceClient) *GRPCClearingPoster {
+	return &GRPCClearingPoster{account: account, position: position}
+}
+
+func (p *GRPCClearingPoster) Post(ctx context.Context, posting *domain.ClearingPosting) error {
+	ctx = dtmgimp.TransInfo2Ctx(ctx, posting.PostingID, "saga", "01", "action", "")
+
+	switch posting.Kind {
+	case domain.PostingCash:
+		req := &accountv1.SagaAccountRequest{
+			UserId:   posting.UserID,
+			Currency: posting.Currency,
+			Amount:   posting.Amount.Abs().String(),
+			TradeId:  posting.PostingID,
+		}
+		if posting.Amount.IsNegative() {
+			_, err := p.account.SagaSubBalance(ctx, req)
+			return err
+		}
+		_, err := p.account.SagaAddBalance(ctx, req)
+		return err
+	case domain.PostingFee:
+		_, err := p.account.SagaSubBalance(ctx, &accountv1.SagaAccountRequest{
+			UserId:   posting.UserID,
+			Currency: posting.Currency,
+			Amount:   posting.Amount.String(),
+			TradeId:  posting.PostingID,
+		})
+		return err
+	case domain.PostingSecurities:
+		req := &positionv1.SagaPositionRequest{
+			UserId:   posting.UserID,
+			Symbol:   posting.Symbol,
+			Quantity: posting.Quantity.Abs().String(),
+			Price:    posting.Price.String(),
+			TradeId:  posting.PostingID,
+		}
+		if posting.Quantity.IsNegative() {
+			_, err := p.position.SagaSubPosition(ctx, req)
+			return err
+		}
+		_, err := p.position.SagaAddPosition(ctx, req)
+		return err
+	default:
+		return fmt.Errorf("unsupported posting kind %q", posting.Kind)
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/dtm-labs/client/dtmgrpc/dtmgimp"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	custodyv1 "github.com/wyfcoding/financialtrading/go-api/custody/v1"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+)
+
+// GRPCCustodyAssetLeg 通过托管服务的证券锁定接口实现 DVP 证券腿，DVPID 作为锁定业务键
+type GRPCCustodyAssetLeg struct {
+	custody custodyv1.CustodyServiceClient
+}
+
+// NewGRPCCustodyAssetLeg 创建证券腿适配器
+func NewGRPCCustodyAssetLeg(custody custodyv1.CustodyServiceClient) *GRPCCustodyAssetLeg {
+	return &GRPCCustodyAssetLeg{custody: custody}
+}
+
+func (l *GRPCCustodyAssetLeg) Lock(ctx context.Context, dvp *domain.DVPSettlement) error {
+	seller, err := custodyUserID(dvp.SellUserID)
+	if err != nil {
+		return err
+	}
+	// 托管库位以整数单位记账
+	if !dvp.Quantity.IsInteger() || !dvp.Quantity.IsPositive() {
+		return fmt.Errorf("dvp quantity %s is not a positive whole number", dvp.Quantity)
+	}
+	_, err = l.custody.LockAsset(ctx, &custodyv1.LockAssetRequest{
+		LockId: dvp.DVPID,
+		UserId: seller,
+		Symbol: dvp.Symbol,
+		Amount: dvp.Quantity.IntPart(),
+		Reason: "dvp:" + dvp.SettlementID,
+	})
+	return err
+}
+
+func (l *GRPCCustodyAssetLeg) Release(ctx context.Context, dvp *domain.DVPSettlement) error {
+	_, err := l.custody.ReleaseAsset(ctx, &custodyv1.ReleaseAssetRequest{
+		LockId: dvp.DVPID,
+		Reason: "dvp_release:" + dvp.SettlementID,
+	})
+	return err
+}
+
+func (l *GRPCCustodyAssetLeg) Deliver(ctx context.Context, dvp *domain.DVPSettlement) error {
+	buyer, err := custodyUserID(dvp.BuyUserID)
+	if err != nil {
+		return err
+	}
+	_, err = l.custody.DeliverAsset(ctx, &custodyv1.DeliverAssetRequest{
+		LockId:   dvp.DVPID,
+		ToUserId: buyer,
+	})
+	return err
+}
+
+func custodyUserID(userID string) (uint64, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid custody user id %q: %w", userID, err)
+	}
+	return id, nil
+}
+
+// GRPCAccountCashLeg 通过账户服务的 TCC 冻结接口实现 DVP 资金腿：Try 冻结买方资金、Cancel 解冻、
+// Confirm 扣减冻结，卖方入账调用 Saga 加款接口。
+// DVP 流程由 DVPService 本地编排，并未在 DTM 服务端注册全局事务，DTM 不会调度或重试这些分支；
+// 这里只借用账户服务的子事务屏障：以 DVPID 作为 gid、固定分支号构造分支上下文，
+// 使重复请求、空补偿与悬挂按 (gid, branch, op) 去重。重试与超期释放由 DVPService 的巡检负责。
+type GRPCAccountCashLeg struct {
+	account accountv1.AccountServiceClient
+}
+
+// NewGRPCAccountCashLeg 创建资金腿适配器
+func NewGRPCAccountCashLeg(account accountv1.AccountServiceClient) *GRPCAccountCashLeg {
+	return &GRPCAccountCashLeg{account: account}
+}
+
+func (l *GRPCAccountCashLeg) freezeRequest(dvp *domain.DVPSettlement) *accountv1.TccFreezeRequest {
+	return &accountv1.TccFreezeRequest{
+		UserId:   dvp.BuyUserID,
+		Currency: dvp.Currency,
+		Amount:   dvp.Amount.String(),
+		OrderId:  dvp.DVPID,
+	}
+}
+
+func (l *GRPCAccountCashLeg) Freeze(ctx context.Context, dvp *domain.DVPSettlement) error {
+	ctx = dtmgimp.TransInfo2Ctx(ctx, dvp.DVPID, "tcc", "01", "try", "")
+	_, err := l.account.TccTryFreeze(ctx, l.freezeRequest(dvp))
+	return err
+}
+
+func (l *GRPCAccountCashLeg) Unfreeze(ctx context.Context, dvp *domain.DVPSettlement) error {
+	ctx = dtmgimp.TransInfo2Ctx(ctx, dvp.DVPID, "tcc", "01", "cancel", "")
+	_, err := l.account.TccCancelFreeze(ctx, l.freezeRequest(dvp))
+	return err
+}
+
+func (l *GRPCAccountCashLeg) Pay(ctx context.Context, dvp *domain.DVPSettlement) error {
+	confirmCtx := dtmgimp.TransInfo2Ctx(ctx, dvp.DVPID, "tcc", "01", "confirm", "")
+	if _, err := l.account.TccConfirmFreeze(confirmCtx, l.freezeRequest(dvp)); err != nil {
+		return err
+	}
+	creditCtx := dtmgimp.TransInfo2Ctx(ctx, dvp.DVPID, "saga", "02", "action", "")
+	_, err := l.account.SagaAddBalance(creditCtx, &accountv1.SagaAccountRequest{
+		UserId:   dvp.SellUserID,
+		Currency: dvp.Currency,
+		Amount:   dvp.Amount.String(),
+		TradeId:  dvp.DVPID,
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+)
+
+// GRPCClosePriceSource 以最新报价作为违约头寸对冲价格，并计入对冲滑点：
+// 清算所卖出时取买一价下浮，买入时取卖一价上浮，缺少盘口时回退到最新成交价
+type GRPCClosePriceSource struct {
+	client   marketdatav1.MarketDataServiceClient
+	slippage decimal.Decimal
+}
+
+// NewGRPCClosePriceSource 创建对冲价格来源，slippage 为对冲滑点比例
+func NewGRPCClosePriceSource(client marketdatav1.MarketDataServiceClient, slippage decimal.Decimal) *GRPCClosePriceSource {
+	return &GRPCClosePriceSource{client: client, slippage: slippage}
+}
+
+func (s *GRPCClosePriceSource) ClosePrice(ctx context.Context, symbol string, sell bool) (decimal.Decimal, error) {
+	quote, err := s.client.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: symbol})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	raw := quote.AskPrice
+	if sell {
+		raw = quote.BidPrice
+	}
+	if raw <= 0 {
+		raw = quote.LastPrice
+	}
+	if raw <= 0 {
+		return decimal.Zero, fmt.Errorf("no close-out price for %s", symbol)
+	}
+
+	price := decimal.NewFromFloat(raw)
+	if sell {
+		return price.Mul(decimal.NewFromInt(1).Sub(s.slippage)), nil
+	}
+	return price.Mul(decimal.NewFromInt(1).Add(s.slippage)), nil
+}
+
+// GRPCSpanPriceSource 以最新成交价作为 SPAN 计算的期货价格
+type GRPCSpanPriceSource struct {
+	client marketdatav1.MarketDataServiceClient
+}
+
+// NewGRPCSpanPriceSource 创建 SPAN 价格来源
+func NewGRPCSpanPriceSource(client marketdatav1.MarketDataServiceClient) *GRPCSpanPriceSource {
+	return &GRPCSpanPriceSource{client: client}
+}
+
+func (s *GRPCSpanPriceSource) LastPrice(ctx context.Context, symbol string) (float64, error) {
+	quote, err := s.client.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: symbol})
+	if err != nil {
+		return 0, err
+	}
+	if quote.LastPrice <= 0 {
+		return 0, fmt.Errorf("no last price for %s", symbol)
+	}
+	return quote.LastPrice, nil
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
 	"github.com/wyfcoding/pkg/contextx"
@@ -63,19 +64,20 @@ func (r *settlementRepository) Save(ctx context.Context, s *domain.Settlement) e
 		Model(&SettlementModel{}).
 		Where("id = ?", model.ID).
 		Updates(map[string]any{
-			"settlement_id": model.SettlementID,
-			"trade_id":      model.TradeID,
-			"buy_user_id":   model.BuyUserID,
-			"sell_user_id":  model.SellUserID,
-			"symbol":        model.Symbol,
-			"currency":      model.Currency,
-			"quantity":      model.Quantity,
-			"price":         model.Price,
-			"total_amount":  model.TotalAmount,
-			"fee":           model.Fee,
-			"status":        model.Status,
-			"settled_at":    model.SettledAt,
-			"error_message": model.ErrorMessage,
+			"settlement_id":   model.SettlementID,
+			"trade_id":        model.TradeID,
+			"buy_user_id":     model.BuyUserID,
+			"sell_user_id":    model.SellUserID,
+			"symbol":          model.Symbol,
+			"currency":        model.Currency,
+			"quantity":        model.Quantity,
+			"price":           model.Price,
+			"total_amount":    model.TotalAmount,
+			"fee":             model.Fee,
+			"status":          model.Status,
+			"settled_at":      model.SettledAt,
+			"error_message":   model.ErrorMessage,
+			"clearing_run_id": model.ClearingRunID,
 		}).Error
 }
 
@@ -113,6 +115,62 @@ func (r *settlementRepository) List(ctx context.Context, limit int) ([]*domain.S
 	return list, nil
 }
 
+func (r *settlementRepository) ListDue(ctx context.Context, settlementDate time.Time, runID string, afterID uint, limit int) ([]*domain.Settlement, error) {
+	var models []*SettlementModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("settlement_date <= ? AND status = ? AND clearing_run_id IN ? AND id > ?",
+			settlementDate.Format(domain.ClearingDateLayout), domain.StatusPending, []string{"", runID}, afterID).
+		Order("id asc").Limit(limit).Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*domain.Settlement, len(models))
+	for i, model := range models {
+		list[i] = toSettlement(model)
+	}
+	return list, nil
+}
+
+func (r *settlementRepository) ClaimForDVP(ctx context.Context, settlementID string) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).
+		Model(&SettlementModel{}).
+		Where("settlement_id = ? AND status = ? AND clearing_run_id = ?", settlementID, domain.StatusPending, "").
+		Update("status", domain.StatusDVPInProgress)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *settlementRepository) ClaimForRun(ctx context.Context, settlementID, runID string) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).
+		Model(&SettlementModel{}).
+		Where("settlement_id = ? AND status <> ? AND clearing_run_id IN ?", settlementID, domain.StatusDVPInProgress, []string{"", runID}).
+		Update("clearing_run_id", runID)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// 已由本任务认领且无变更时 MySQL 返回 0 行，需再确认
+	var count int64
+	err := r.getDB(ctx).WithContext(ctx).
+		Model(&SettlementModel{}).
+		Where("settlement_id = ? AND status <> ? AND clearing_run_id = ?", settlementID, domain.StatusDVPInProgress, runID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *settlementRepository) ListByClearingRun(ctx context.Context, runID string) ([]*domain.Settlement, error) {
+	var models []*SettlementModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("clearing_run_id = ?", runID).Order("id asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*domain.Settlement, len(models))
+	for i, model := range models {
+		list[i] = toSettlement(model)
+	}
+	return list, nil
+}
+
 func (r *settlementRepository) getDB(ctx context.Context) *gorm.DB {
 	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
 		return tx
@@ -0,0 +1,315 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClearingRunModel 日终清算任务表映射
+type ClearingRunModel struct {
+	gorm.Model
+	RunID           string          `gorm:"column:run_id;type:varchar(32);uniqueIndex;not null;comment:任务ID"`
+	ClearingDate    string          `gorm:"column:clearing_date;type:varchar(10);uniqueIndex;not null;comment:清算日期"`
+	Status          string          `gorm:"column:status;type:varchar(20);index;not null;comment:状态"`
+	Stage           string          `gorm:"column:stage;type:varchar(20);not null;comment:阶段"`
+	SettlementCount int             `gorm:"column:settlement_count;not null;default:0;comment:结算单数"`
+	NettedCount     int             `gorm:"column:netted_count;not null;default:0;comment:净额结算单数"`
+	MemberCount     int             `gorm:"column:member_count;not null;default:0;comment:会员数"`
+	TotalVolume     decimal.Decimal `gorm:"column:total_volume;type:decimal(32,18);not null;comment:总成交额"`
+	TotalFee        decimal.Decimal `gorm:"column:total_fee;type:decimal(32,18);not null;comment:总费用"`
+	PostingsTotal   int             `gorm:"column:postings_total;not null;default:0;comment:分录总数"`
+	PostingsDone    int             `gorm:"column:postings_done;not null;default:0;comment:已过账分录数"`
+	Attempts        int             `gorm:"column:attempts;not null;default:0;comment:执行次数"`
+	ErrorMessage    string          `gorm:"column:error_message;type:text;comment:错误信息"`
+	StartedAt       time.Time       `gorm:"column:started_at;not null;comment:开始时间"`
+	CompletedAt     *time.Time      `gorm:"column:completed_at;comment:完成时间"`
+}
+
+func (ClearingRunModel) TableName() string { return "clearing_runs" }
+
+// ClearingPostingModel 日终清算分录表映射
+type ClearingPostingModel struct {
+	gorm.Model
+	PostingID    string          `gorm:"column:posting_id;type:varchar(128);uniqueIndex;not null;comment:分录ID"`
+	RunID        string          `gorm:"column:run_id;type:varchar(32);index;not null;comment:任务ID"`
+	UserID       string          `gorm:"column:user_id;type:varchar(32);index;not null;comment:会员ID"`
+	Kind         string          `gorm:"column:kind;type:varchar(16);not null;comment:分录类型"`
+	Symbol       string          `gorm:"column:symbol;type:varchar(20);comment:标的"`
+	Currency     string          `gorm:"column:currency;type:varchar(10);not null;comment:币种"`
+	Quantity     decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);not null;comment:净数量"`
+	Amount       decimal.Decimal `gorm:"column:amount;type:decimal(32,18);not null;comment:净金额"`
+	Price        decimal.Decimal `gorm:"column:price;type:decimal(32,18);not null;comment:交收均价"`
+	Sequence     int             `gorm:"column:sequence;not null;comment:过账顺序"`
+	Status       string          `gorm:"column:status;type:varchar(16);index;not null;comment:状态"`
+	Attempts     int             `gorm:"column:attempts;not null;default:0;comment:尝试次数"`
+	ErrorMessage string          `gorm:"column:error_message;type:text;comment:错误信息"`
+	PostedAt     *time.Time      `gorm:"column:posted_at;comment:过账时间"`
+}
+
+func (ClearingPostingModel) TableName() string { return "clearing_postings" }
+
+// MemberStatementModel 会员清算结算单表映射
+type MemberStatementModel struct {
+	gorm.Model
+	RunID        string          `gorm:"column:run_id;type:varchar(32);uniqueIndex:uk_run_user_ccy;not null;comment:任务ID"`
+	ClearingDate string          `gorm:"column:clearing_date;type:varchar(10);index;not null;comment:清算日期"`
+	UserID       string          `gorm:"column:user_id;type:varchar(32);uniqueIndex:uk_run_user_ccy;not null;comment:会员ID"`
+	Currency     string          `gorm:"column:currency;type:varchar(10);uniqueIndex:uk_run_user_ccy;not null;comment:币种"`
+	TradeCount   int             `gorm:"column:trade_count;not null;comment:成交笔数"`
+	BuyAmount    decimal.Decimal `gorm:"column:buy_amount;type:decimal(32,18);not null;comment:买入总额"`
+	SellAmount   decimal.Decimal `gorm:"column:sell_amount;type:decimal(32,18);not null;comment:卖出总额"`
+	NetCash      decimal.Decimal `gorm:"column:net_cash;type:decimal(32,18);not null;comment:净资金"`
+	Fee          decimal.Decimal `gorm:"column:fee;type:decimal(32,18);not null;comment:费用"`
+	NetAfterFee  decimal.Decimal `gorm:"column:net_after_fee;type:decimal(32,18);not null;comment:费后净资金"`
+	Lines        string          `gorm:"column:lines;type:text;comment:标的明细(JSON)"`
+}
+
+func (MemberStatementModel) TableName() string { return "clearing_member_statements" }
+
+type clearingRunRepository struct {
+	db *gorm.DB
+}
+
+// NewClearingRunRepository 创建日终清算任务仓储
+func NewClearingRunRepository(db *gorm.DB) domain.ClearingRunRepository {
+	return &clearingRunRepository{db: db}
+}
+
+func (r *clearingRunRepository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(contextx.WithTx(ctx, tx))
+	})
+}
+
+func (r *clearingRunRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *clearingRunRepository) GetRun(ctx context.Context, runID string) (*domain.ClearingRun, error) {
+	var model ClearingRunModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("run_id = ?", runID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toClearingRun(&model), nil
+}
+
+func (r *clearingRunRepository) SaveRun(ctx context.Context, run *domain.ClearingRun) error {
+	model := &ClearingRunModel{
+		RunID:           run.RunID,
+		ClearingDate:    run.ClearingDate,
+		Status:          string(run.Status),
+		Stage:           string(run.Stage),
+		SettlementCount: run.SettlementCount,
+		NettedCount:     run.NettedCount,
+		MemberCount:     run.MemberCount,
+		TotalVolume:     run.TotalVolume,
+		TotalFee:        run.TotalFee,
+		PostingsTotal:   run.PostingsTotal,
+		PostingsDone:    run.PostingsDone,
+		Attempts:        run.Attempts,
+		ErrorMessage:    run.ErrorMessage,
+		StartedAt:       run.StartedAt,
+		CompletedAt:     run.CompletedAt,
+	}
+	err := r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "run_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "stage", "settlement_count", "netted_count", "member_count", "total_volume", "total_fee",
+			"postings_total", "postings_done", "attempts", "error_message", "completed_at", "updated_at",
+		}),
+	}).Create(model).Error
+	if err != nil {
+		return err
+	}
+	run.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
+func (r *clearingRunRepository) ListRuns(ctx context.Context, status domain.ClearingRunStatus, limit int) ([]*domain.ClearingRun, error) {
+	db := r.getDB(ctx).WithContext(ctx)
+	if status != "" {
+		db = db.Where("status = ?", string(status))
+	}
+	var models []*ClearingRunModel
+	if err := db.Order("clearing_date desc").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	runs := make([]*domain.ClearingRun, len(models))
+	for i, m := range models {
+		runs[i] = toClearingRun(m)
+	}
+	return runs, nil
+}
+
+// SavePostings 批量写入分录，已存在的分录保持原状态，保证重放计划阶段时不会覆盖过账进度
+func (r *clearingRunRepository) SavePostings(ctx context.Context, postings []*domain.ClearingPosting) error {
+	if len(postings) == 0 {
+		return nil
+	}
+	models := make([]*ClearingPostingModel, len(postings))
+	for i, p := range postings {
+		models[i] = toClearingPostingModel(p)
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "posting_id"}},
+		DoNothing: true,
+	}).CreateInBatches(models, 200).Error
+}
+
+func (r *clearingRunRepository) UpdatePosting(ctx context.Context, p *domain.ClearingPosting) error {
+	return r.getDB(ctx).WithContext(ctx).Model(&ClearingPostingModel{}).
+		Where("posting_id = ?", p.PostingID).
+		Updates(map[string]any{
+			"status":        string(p.Status),
+			"attempts":      p.Attempts,
+			"error_message": p.ErrorMessage,
+			"posted_at":     p.PostedAt,
+		}).Error
+}
+
+func (r *clearingRunRepository) ListPostings(ctx context.Context, runID string) ([]*domain.ClearingPosting, error) {
+	var models []*ClearingPostingModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("run_id = ?", runID).Order("sequence asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	postings := make([]*domain.ClearingPosting, len(models))
+	for i, m := range models {
+		postings[i] = toClearingPosting(m)
+	}
+	return postings, nil
+}
+
+func (r *clearingRunRepository) SaveStatements(ctx context.Context, statements []*domain.MemberStatement) error {
+	if len(statements) == 0 {
+		return nil
+	}
+	models := make([]*MemberStatementModel, len(statements))
+	for i, st := range statements {
+		lines, err := json.Marshal(st.Lines)
+		if err != nil {
+			return err
+		}
+		models[i] = &MemberStatementModel{
+			RunID:        st.RunID,
+			ClearingDate: st.ClearingDate,
+			UserID:       st.UserID,
+			Currency:     st.Currency,
+			TradeCount:   st.TradeCount,
+			BuyAmount:    st.BuyAmount,
+			SellAmount:   st.SellAmount,
+			NetCash:      st.NetCash,
+			Fee:          st.Fee,
+			NetAfterFee:  st.NetAfterFee,
+			Lines:        string(lines),
+		}
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "run_id"}, {Name: "user_id"}, {Name: "currency"}},
+		DoNothing: true,
+	}).CreateInBatches(models, 200).Error
+}
+
+func (r *clearingRunRepository) ListStatements(ctx context.Context, runID, userID string) ([]*domain.MemberStatement, error) {
+	db := r.getDB(ctx).WithContext(ctx).Where("run_id = ?", runID)
+	if userID != "" {
+		db = db.Where("user_id = ?", userID)
+	}
+	var models []*MemberStatementModel
+	if err := db.Order("user_id asc, currency asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	statements := make([]*domain.MemberStatement, len(models))
+	for i, m := range models {
+		var lines []*domain.StatementLine
+		if m.Lines != "" {
+			_ = json.Unmarshal([]byte(m.Lines), &lines)
+		}
+		statements[i] = &domain.MemberStatement{
+			RunID:        m.RunID,
+			ClearingDate: m.ClearingDate,
+			UserID:       m.UserID,
+			Currency:     m.Currency,
+			TradeCount:   m.TradeCount,
+			BuyAmount:    m.BuyAmount,
+			SellAmount:   m.SellAmount,
+			NetCash:      m.NetCash,
+			Fee:          m.Fee,
+			NetAfterFee:  m.NetAfterFee,
+			Lines:        lines,
+		}
+	}
+	return statements, nil
+}
+
+func toClearingRun(m *ClearingRunModel) *domain.ClearingRun {
+	return &domain.ClearingRun{
+		RunID:           m.RunID,
+		ClearingDate:    m.ClearingDate,
+		Status:          domain.ClearingRunStatus(m.Status),
+		Stage:           domain.ClearingRunStage(m.Stage),
+		SettlementCount: m.SettlementCount,
+		NettedCount:     m.NettedCount,
+		MemberCount:     m.MemberCount,
+		TotalVolume:     m.TotalVolume,
+		TotalFee:        m.TotalFee,
+		PostingsTotal:   m.PostingsTotal,
+		PostingsDone:    m.PostingsDone,
+		Attempts:        m.Attempts,
+		ErrorMessage:    m.ErrorMessage,
+		StartedAt:       m.StartedAt,
+		CompletedAt:     m.CompletedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+func toClearingPostingModel(p *domain.ClearingPosting) *ClearingPostingModel {
+	return &ClearingPostingModel{
+		PostingID:    p.PostingID,
+		RunID:        p.RunID,
+		UserID:       p.UserID,
+		Kind:         string(p.Kind),
+		Symbol:       p.Symbol,
+		Currency:     p.Currency,
+		Quantity:     p.Quantity,
+		Amount:       p.Amount,
+		Price:        p.Price,
+		Sequence:     p.Sequence,
+		Status:       string(p.Status),
+		Attempts:     p.Attempts,
+		ErrorMessage: p.ErrorMessage,
+		PostedAt:     p.PostedAt,
+	}
+}
+
+func toClearingPosting(m *ClearingPostingModel) *domain.ClearingPosting {
+	return &domain.ClearingPosting{
+		PostingID:    m.PostingID,
+		RunID:        m.RunID,
+		UserID:       m.UserID,
+		Kind:         domain.PostingKind(m.Kind),
+		Symbol:       m.Symbol,
+		Currency:     m.Currency,
+		Quantity:     m.Quantity,
+		Amount:       m.Amount,
+		Price:        m.Price,
+		Sequence:     m.Sequence,
+		Status:       domain.PostingStatus(m.Status),
+		Attempts:     m.Attempts,
+		ErrorMessage: m.ErrorMessage,
+		PostedAt:     m.PostedAt,
+	}
+}
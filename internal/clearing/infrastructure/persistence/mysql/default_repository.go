@@ -0,0 +1,360 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CCPContractModel CCP 合同表映射
+type CCPContractModel struct {
+	gorm.Model
+	ContractID   string          `gorm:"column:contract_id;type:varchar(96);uniqueIndex;not null;comment:合同ID"`
+	OriginalID   string          `gorm:"column:original_id;type:varchar(64);index;not null;comment:原结算单ID"`
+	Counterparty string          `gorm:"column:counterparty;type:varchar(32);index:idx_ccp_cp_status;not null;comment:对手方"`
+	Symbol       string          `gorm:"column:symbol;type:varchar(20);not null;comment:标的"`
+	Currency     string          `gorm:"column:currency;type:varchar(10);not null;comment:币种"`
+	Side         string          `gorm:"column:side;type:varchar(8);not null;comment:方向"`
+	Quantity     decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);not null;comment:数量"`
+	Price        decimal.Decimal `gorm:"column:price;type:decimal(32,18);not null;comment:价格"`
+	TotalAmount  decimal.Decimal `gorm:"column:total_amount;type:decimal(32,18);not null;comment:金额"`
+	Status       string          `gorm:"column:status;type:varchar(16);index:idx_ccp_cp_status;not null;comment:状态"`
+}
+
+func (CCPContractModel) TableName() string { return "ccp_contracts" }
+
+// MemberCollateralModel 清算会员抵押品表映射
+type MemberCollateralModel struct {
+	gorm.Model
+	UserID      string          `gorm:"column:user_id;type:varchar(32);uniqueIndex:uk_member_ccy;not null;comment:会员ID"`
+	Currency    string          `gorm:"column:currency;type:varchar(10);uniqueIndex:uk_member_ccy;not null;comment:币种"`
+	Margin      decimal.Decimal `gorm:"column:margin;type:decimal(32,18);not null;comment:保证金"`
+	DefaultFund decimal.Decimal `gorm:"column:default_fund;type:decimal(32,18);not null;comment:违约基金份额"`
+}
+
+func (MemberCollateralModel) TableName() string { return "clearing_member_collateral" }
+
+// DefaultCaseModel 违约处置案例表映射
+type DefaultCaseModel struct {
+	gorm.Model
+	CaseID          string     `gorm:"column:case_id;type:varchar(32);uniqueIndex;not null;comment:案例ID"`
+	UserID          string     `gorm:"column:user_id;type:varchar(32);index;not null;comment:违约会员"`
+	Reason          string     `gorm:"column:reason;type:varchar(255);comment:违约原因"`
+	Method          string     `gorm:"column:method;type:varchar(16);not null;comment:平仓方式"`
+	Status          string     `gorm:"column:status;type:varchar(16);index;not null;comment:状态"`
+	Lots            string     `gorm:"column:lots;type:json;comment:平仓批次"`
+	Steps           string     `gorm:"column:steps;type:json;comment:瀑布步骤"`
+	TotalLoss       string     `gorm:"column:total_loss;type:json;comment:各币种损失"`
+	Uncovered       string     `gorm:"column:uncovered;type:json;comment:未覆盖损失"`
+	AuctionDeadline time.Time  `gorm:"column:auction_deadline;not null;comment:拍卖截止时间"`
+	CompletedAt     *time.Time `gorm:"column:completed_at;comment:完成时间"`
+}
+
+func (DefaultCaseModel) TableName() string { return "clearing_default_cases" }
+
+// AuctionBidModel 违约拍卖报价表映射
+type AuctionBidModel struct {
+	gorm.Model
+	BidID  string          `gorm:"column:bid_id;type:varchar(32);uniqueIndex;not null;comment:报价ID"`
+	CaseID string          `gorm:"column:case_id;type:varchar(32);index;not null;comment:案例ID"`
+	LotID  string          `gorm:"column:lot_id;type:varchar(48);not null;comment:批次ID"`
+	Bidder string          `gorm:"column:bidder;type:varchar(32);not null;comment:竞价会员"`
+	Price  decimal.Decimal `gorm:"column:price;type:decimal(32,18);not null;comment:报价"`
+}
+
+func (AuctionBidModel) TableName() string { return "clearing_auction_bids" }
+
+type ccpContractRepository struct {
+	db *gorm.DB
+}
+
+// NewCCPContractRepository 创建 CCP 合同仓储
+func NewCCPContractRepository(db *gorm.DB) domain.CCPContractRepository {
+	return &ccpContractRepository{db: db}
+}
+
+func (r *ccpContractRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *ccpContractRepository) SaveContracts(ctx context.Context, contracts []*domain.CCPContract) error {
+	if len(contracts) == 0 {
+		return nil
+	}
+	models := make([]*CCPContractModel, len(contracts))
+	for i, c := range contracts {
+		models[i] = &CCPContractModel{
+			ContractID:   c.ContractID,
+			OriginalID:   c.OriginalID,
+			Counterparty: c.Counterparty,
+			Symbol:       c.Symbol,
+			Currency:     c.Currency,
+			Side:         c.Side,
+			Quantity:     c.Quantity,
+			Price:        c.Price,
+			TotalAmount:  c.TotalAmount,
+			Status:       c.Status,
+		}
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contract_id"}},
+		DoNothing: true,
+	}).Create(models).Error
+}
+
+func (r *ccpContractRepository) ListOpenByCounterparty(ctx context.Context, userID string) ([]*domain.CCPContract, error) {
+	var models []*CCPContractModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("counterparty = ? AND status = ?", userID, domain.ContractOpen).
+		Order("id asc").Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	contracts := make([]*domain.CCPContract, len(models))
+	for i, m := range models {
+		contracts[i] = &domain.CCPContract{
+			ContractID:   m.ContractID,
+			OriginalID:   m.OriginalID,
+			Counterparty: m.Counterparty,
+			Symbol:       m.Symbol,
+			Currency:     m.Currency,
+			Side:         m.Side,
+			Quantity:     m.Quantity,
+			Price:        m.Price,
+			TotalAmount:  m.TotalAmount,
+			Status:       m.Status,
+			CreatedAt:    m.CreatedAt,
+		}
+	}
+	return contracts, nil
+}
+
+func (r *ccpContractRepository) UpdateStatusByOriginal(ctx context.Context, settlementID, status string) error {
+	return r.getDB(ctx).WithContext(ctx).Model(&CCPContractModel{}).
+		Where("original_id = ? AND status = ?", settlementID, domain.ContractOpen).
+		Update("status", status).Error
+}
+
+func (r *ccpContractRepository) UpdateStatus(ctx context.Context, contractIDs []string, status string) error {
+	if len(contractIDs) == 0 {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Model(&CCPContractModel{}).
+		Where("contract_id IN ?", contractIDs).
+		Update("status", status).Error
+}
+
+type defaultManagementRepository struct {
+	db *gorm.DB
+}
+
+// NewDefaultManagementRepository 创建违约处置仓储
+func NewDefaultManagementRepository(db *gorm.DB) domain.DefaultManagementRepository {
+	return &defaultManagementRepository{db: db}
+}
+
+func (r *defaultManagementRepository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(contextx.WithTx(ctx, tx))
+	})
+}
+
+func (r *defaultManagementRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *defaultManagementRepository) GetCollateral(ctx context.Context, userID, currency string) (*domain.MemberCollateral, error) {
+	var model MemberCollateralModel
+	err := r.getDB(ctx).WithContext(ctx).Where("user_id = ? AND currency = ?", userID, currency).First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toMemberCollateral(&model), nil
+}
+
+func (r *defaultManagementRepository) ListCollateral(ctx context.Context, currency string) ([]*domain.MemberCollateral, error) {
+	db := r.getDB(ctx).WithContext(ctx)
+	if currency != "" {
+		db = db.Where("currency = ?", currency)
+	}
+	var models []*MemberCollateralModel
+	if err := db.Order("user_id asc, currency asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]*domain.MemberCollateral, len(models))
+	for i, m := range models {
+		list[i] = toMemberCollateral(m)
+	}
+	return list, nil
+}
+
+func (r *defaultManagementRepository) SaveCollateral(ctx context.Context, c *domain.MemberCollateral) error {
+	model := &MemberCollateralModel{
+		UserID:      c.UserID,
+		Currency:    c.Currency,
+		Margin:      c.Margin,
+		DefaultFund: c.DefaultFund,
+	}
+	err := r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"margin", "default_fund", "updated_at"}),
+	}).Create(model).Error
+	if err != nil {
+		return err
+	}
+	c.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
+func (r *defaultManagementRepository) SaveCase(ctx context.Context, dc *domain.DefaultCase) error {
+	lots, err := json.Marshal(dc.Lots)
+	if err != nil {
+		return err
+	}
+	steps, err := json.Marshal(dc.Steps)
+	if err != nil {
+		return err
+	}
+	totalLoss, err := json.Marshal(dc.TotalLoss)
+	if err != nil {
+		return err
+	}
+	uncovered, err := json.Marshal(dc.Uncovered)
+	if err != nil {
+		return err
+	}
+	model := &DefaultCaseModel{
+		CaseID:          dc.CaseID,
+		UserID:          dc.UserID,
+		Reason:          dc.Reason,
+		Method:          string(dc.Method),
+		Status:          string(dc.Status),
+		Lots:            string(lots),
+		Steps:           string(steps),
+		TotalLoss:       string(totalLoss),
+		Uncovered:       string(uncovered),
+		AuctionDeadline: dc.AuctionDeadline,
+		CompletedAt:     dc.CompletedAt,
+	}
+	model.CreatedAt = dc.CreatedAt
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "case_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "lots", "steps", "total_loss", "uncovered", "completed_at", "updated_at"}),
+	}).Create(model).Error
+}
+
+func (r *defaultManagementRepository) GetCase(ctx context.Context, caseID string) (*domain.DefaultCase, error) {
+	return r.findCase(r.getDB(ctx).WithContext(ctx).Where("case_id = ?", caseID))
+}
+
+func (r *defaultManagementRepository) GetActiveCase(ctx context.Context, userID string) (*domain.DefaultCase, error) {
+	return r.findCase(r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND status <> ?", userID, domain.DefaultCaseCompleted))
+}
+
+func (r *defaultManagementRepository) findCase(query *gorm.DB) (*domain.DefaultCase, error) {
+	var model DefaultCaseModel
+	if err := query.First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toDefaultCase(&model), nil
+}
+
+func (r *defaultManagementRepository) ListCases(ctx context.Context, status domain.DefaultCaseStatus, limit int) ([]*domain.DefaultCase, error) {
+	db := r.getDB(ctx).WithContext(ctx)
+	if status != "" {
+		db = db.Where("status = ?", string(status))
+	}
+	var models []*DefaultCaseModel
+	if err := db.Order("id desc").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	cases := make([]*domain.DefaultCase, len(models))
+	for i, m := range models {
+		cases[i] = toDefaultCase(m)
+	}
+	return cases, nil
+}
+
+func (r *defaultManagementRepository) SaveBid(ctx context.Context, bid *domain.AuctionBid) error {
+	model := &AuctionBidModel{
+		BidID:  bid.BidID,
+		CaseID: bid.CaseID,
+		LotID:  bid.LotID,
+		Bidder: bid.Bidder,
+		Price:  bid.Price,
+	}
+	if err := r.getDB(ctx).WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+	bid.CreatedAt = model.CreatedAt
+	return nil
+}
+
+func (r *defaultManagementRepository) ListBids(ctx context.Context, caseID string) ([]*domain.AuctionBid, error) {
+	var models []*AuctionBidModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("case_id = ?", caseID).Order("id asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	bids := make([]*domain.AuctionBid, len(models))
+	for i, m := range models {
+		bids[i] = &domain.AuctionBid{
+			BidID:     m.BidID,
+			CaseID:    m.CaseID,
+			LotID:     m.LotID,
+			Bidder:    m.Bidder,
+			Price:     m.Price,
+			CreatedAt: m.CreatedAt,
+		}
+	}
+	return bids, nil
+}
+
+func toMemberCollateral(m *MemberCollateralModel) *domain.MemberCollateral {
+	return &domain.MemberCollateral{
+		UserID:      m.UserID,
+		Currency:    m.Currency,
+		Margin:      m.Margin,
+		DefaultFund: m.DefaultFund,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func toDefaultCase(m *DefaultCaseModel) *domain.DefaultCase {
+	dc := &domain.DefaultCase{
+		CaseID:          m.CaseID,
+		UserID:          m.UserID,
+		Reason:          m.Reason,
+		Method:          domain.CloseOutMethod(m.Method),
+		Status:          domain.DefaultCaseStatus(m.Status),
+		TotalLoss:       make(map[string]decimal.Decimal),
+		Uncovered:       make(map[string]decimal.Decimal),
+		AuctionDeadline: m.AuctionDeadline,
+		CreatedAt:       m.CreatedAt,
+		CompletedAt:     m.CompletedAt,
+	}
+	_ = json.Unmarshal([]byte(m.Lots), &dc.Lots)
+	_ = json.Unmarshal([]byte(m.Steps), &dc.Steps)
+	_ = json.Unmarshal([]byte(m.TotalLoss), &dc.TotalLoss)
+	_ = json.Unmarshal([]byte(m.Uncovered), &dc.Uncovered)
+	return dc
+}
@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DVPSettlementModel DVP 交收表映射
+type DVPSettlementModel struct {
+	gorm.Model
+	DVPID          string          `gorm:"column:dvp_id;type:varchar(64);uniqueIndex;not null;comment:交收ID"`
+	SettlementID   string          `gorm:"column:settlement_id;type:varchar(64);index;not null;comment:结算单ID"`
+	BuyUserID      string          `gorm:"column:buy_user_id;type:varchar(32);not null;comment:买方"`
+	SellUserID     string          `gorm:"column:sell_user_id;type:varchar(32);not null;comment:卖方"`
+	Symbol         string          `gorm:"column:symbol;type:varchar(20);not null;comment:标的"`
+	Quantity       decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);not null;comment:数量"`
+	Currency       string          `gorm:"column:currency;type:varchar(10);not null;comment:币种"`
+	Amount         decimal.Decimal `gorm:"column:amount;type:decimal(32,18);not null;comment:金额"`
+	Status         string          `gorm:"column:status;type:varchar(16);index;not null;comment:状态"`
+	AssetLocked    bool            `gorm:"column:asset_locked;not null;default:false;comment:券已锁定"`
+	FundLocked     bool            `gorm:"column:fund_locked;not null;default:false;comment:款已冻结"`
+	AssetDelivered bool            `gorm:"column:asset_delivered;not null;default:false;comment:券已交付"`
+	FundPaid       bool            `gorm:"column:fund_paid;not null;default:false;comment:款已划付"`
+	Deadline       time.Time       `gorm:"column:deadline;not null;comment:锁定期限"`
+	FailReason     string          `gorm:"column:fail_reason;type:varchar(255);comment:失败原因"`
+	SettledAt      *time.Time      `gorm:"column:settled_at;comment:交收完成时间"`
+}
+
+func (DVPSettlementModel) TableName() string { return "clearing_dvp_settlements" }
+
+type dvpRepository struct {
+	db *gorm.DB
+}
+
+// NewDVPRepository 创建 DVP 交收仓储
+func NewDVPRepository(db *gorm.DB) domain.DVPRepository {
+	return &dvpRepository{db: db}
+}
+
+func (r *dvpRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *dvpRepository) Save(ctx context.Context, dvp *domain.DVPSettlement) error {
+	model := &DVPSettlementModel{
+		DVPID:          dvp.DVPID,
+		SettlementID:   dvp.SettlementID,
+		BuyUserID:      dvp.BuyUserID,
+		SellUserID:     dvp.SellUserID,
+		Symbol:         dvp.Symbol,
+		Quantity:       dvp.Quantity,
+		Currency:       dvp.Currency,
+		Amount:         dvp.Amount,
+		Status:         string(dvp.Status),
+		AssetLocked:    dvp.AssetLocked,
+		FundLocked:     dvp.FundLocked,
+		AssetDelivered: dvp.AssetDelivered,
+		FundPaid:       dvp.FundPaid,
+		Deadline:       dvp.Deadline,
+		FailReason:     dvp.FailReason,
+		SettledAt:      dvp.SettledAt,
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "dvp_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "asset_locked", "fund_locked", "asset_delivered", "fund_paid",
+			"fail_reason", "settled_at", "updated_at",
+		}),
+	}).Create(model).Error
+}
+
+func (r *dvpRepository) SaveIfStatus(ctx context.Context, dvp *domain.DVPSettlement, expected domain.DVPStatus) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).
+		Model(&DVPSettlementModel{}).
+		Where("dvp_id = ? AND status = ?", dvp.DVPID, string(expected)).
+		Updates(map[string]any{
+			"status":          string(dvp.Status),
+			"asset_locked":    dvp.AssetLocked,
+			"fund_locked":     dvp.FundLocked,
+			"asset_delivered": dvp.AssetDelivered,
+			"fund_paid":       dvp.FundPaid,
+			"fail_reason":     dvp.FailReason,
+			"settled_at":      dvp.SettledAt,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *dvpRepository) Get(ctx context.Context, dvpID string) (*domain.DVPSettlement, error) {
+	var model DVPSettlementModel
+	err := r.getDB(ctx).WithContext(ctx).Where("dvp_id = ?", dvpID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrDVPNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDVPSettlement(&model), nil
+}
+
+func (r *dvpRepository) ListOpen(ctx context.Context, limit int) ([]*domain.DVPSettlement, error) {
+	var models []*DVPSettlementModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("status NOT IN ?", []string{string(domain.DVPStatusSettled), string(domain.DVPStatusFailed)}).
+		Order("id asc").Limit(limit).Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*domain.DVPSettlement, len(models))
+	for i, m := range models {
+		list[i] = toDVPSettlement(m)
+	}
+	return list, nil
+}
+
+func toDVPSettlement(m *DVPSettlementModel) *domain.DVPSettlement {
+	return &domain.DVPSettlement{
+		DVPID:          m.DVPID,
+		SettlementID:   m.SettlementID,
+		BuyUserID:      m.BuyUserID,
+		SellUserID:     m.SellUserID,
+		Symbol:         m.Symbol,
+		Quantity:       m.Quantity,
+		Currency:       m.Currency,
+		Amount:         m.Amount,
+		Status:         domain.DVPStatus(m.Status),
+		AssetLocked:    m.AssetLocked,
+		FundLocked:     m.FundLocked,
+		AssetDelivered: m.AssetDelivered,
+		FundPaid:       m.FundPaid,
+		Deadline:       m.Deadline,
+		FailReason:     m.FailReason,
+		CreatedAt:      m.CreatedAt,
+		SettledAt:      m.SettledAt,
+		LastUpdate:     m.UpdatedAt,
+	}
+}
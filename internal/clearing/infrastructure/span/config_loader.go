@@ -0,0 +1,76 @@
+package span
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+)
+
+// Config 服务配置文件 [span] 段：产品组与跨品种价差参数直接写在配置中，
+// 由配置加载器统一处理环境变量覆盖与热更新；ParamsDir 非空时改为从该目录读取 JSON 参数文件
+type Config struct {
+	ParamsDir    string           `mapstructure:"params_dir"    toml:"params_dir"`
+	Groups       []map[string]any `mapstructure:"groups"        toml:"groups"`
+	InterSpreads []map[string]any `mapstructure:"inter_spreads" toml:"inter_spreads"`
+}
+
+// ConfigParameterLoader 从服务配置读取 SPAN 参数。配置热更新会原地改写 Config，
+// 因此以参数内容摘要判断是否变化，摘要变化时版本推进到当前时间
+type ConfigParameterLoader struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	digest  [sha256.Size]byte
+	version time.Time
+}
+
+// NewConfigParameterLoader 创建配置参数加载器，cfg 须指向随配置热更新的同一结构
+func NewConfigParameterLoader(cfg *Config) *ConfigParameterLoader {
+	return &ConfigParameterLoader{cfg: cfg}
+}
+
+// Load 将配置中的参数转换为参数集；字段名与参数文件的 JSON 字段一致
+func (l *ConfigParameterLoader) Load() (*domain.SpanParameterSet, error) {
+	data, err := l.encode()
+	if err != nil {
+		return nil, err
+	}
+	set := &domain.SpanParameterSet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSpanParameters, err)
+	}
+	set.LoadedAt = time.Now()
+	return set, nil
+}
+
+// Version 返回参数最近一次变化的时间
+func (l *ConfigParameterLoader) Version() (time.Time, error) {
+	data, err := l.encode()
+	if err != nil {
+		return time.Time{}, err
+	}
+	digest := sha256.Sum256(data)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.version.IsZero() || digest != l.digest {
+		l.digest = digest
+		l.version = time.Now()
+	}
+	return l.version, nil
+}
+
+func (l *ConfigParameterLoader) encode() ([]byte, error) {
+	data, err := json.Marshal(map[string]any{
+		"groups":        l.cfg.Groups,
+		"inter_spreads": l.cfg.InterSpreads,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSpanParameters, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,84 @@
+package span
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
+)
+
+// InterCommodityFile 跨品种价差参数文件名，目录下其余 *.json 均视为产品组参数文件
+const InterCommodityFile = "inter_commodity.json"
+
+// FileParameterLoader 从目录加载 SPAN 参数：每个产品组一个 JSON 文件
+type FileParameterLoader struct {
+	dir string
+}
+
+// NewFileParameterLoader 创建目录参数加载器
+func NewFileParameterLoader(dir string) *FileParameterLoader {
+	return &FileParameterLoader{dir: dir}
+}
+
+func (l *FileParameterLoader) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(l.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Load 读取目录下全部参数文件
+func (l *FileParameterLoader) Load() (*domain.SpanParameterSet, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	set := &domain.SpanParameterSet{LoadedAt: time.Now()}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(file) == InterCommodityFile {
+			if err := json.Unmarshal(data, &set.InterSpreads); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", domain.ErrInvalidSpanParameters, file, err)
+			}
+			continue
+		}
+		group := &domain.SpanProductGroup{}
+		if err := json.Unmarshal(data, group); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", domain.ErrInvalidSpanParameters, file, err)
+		}
+		set.Groups = append(set.Groups, group)
+	}
+	return set, nil
+}
+
+// Version 返回参数文件的最新修改时间，文件增删也会改变目录修改时间
+func (l *FileParameterLoader) Version() (time.Time, error) {
+	info, err := os.Stat(l.dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := info.ModTime()
+	files, err := l.files()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -5,12 +5,14 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
 	"github.com/shopspring/decimal"
 	pb "github.com/wyfcoding/financialtrading/go-api/clearing/v1"
 	"github.com/wyfcoding/financialtrading/internal/clearing/application"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -112,15 +114,24 @@ func (h *Handler) SagaMarkSettlementFailed(ctx context.Context, req *pb.SagaSett
 
 // ExecuteEODClearing 执行日终清算
 func (h *Handler) ExecuteEODClearing(ctx context.Context, req *pb.ExecuteEODClearingRequest) (*pb.ExecuteEODClearingResponse, error) {
-	clearingID, err := h.cmd.ExecuteEODClearing(ctx, req.ClearingDate)
+	run, err := h.cmd.ExecuteEODClearing(ctx, req.ClearingDate)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClearingDate) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid clearing date: %v", err)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to execute EOD clearing: %v", err)
 	}
 
+	var tradesSettled int64
+	if run.Status == string(domain.ClearingRunCompleted) {
+		tradesSettled = int64(run.SettlementCount)
+	}
 	return &pb.ExecuteEODClearingResponse{
-		Status:     "PROCESSING",
-		ClearingId: clearingID,
-		StartTime:  time.Now().Unix(),
+		Status:        run.Status,
+		ClearingId:    run.RunID,
+		StartTime:     run.StartedAt,
+		EndTime:       run.CompletedAt,
+		TradesSettled: tradesSettled,
 	}, nil
 }
 
@@ -135,10 +146,11 @@ func (h *Handler) GetClearingStatus(ctx context.Context, req *pb.GetClearingStat
 	}
 
 	return &pb.GetClearingStatusResponse{
-		ClearingId:      dto.SettlementID,
-		Status:          dto.Status,
-		TradesProcessed: int64(dto.TradesSettled),
-		TradesTotal:     int64(dto.TotalTrades),
+		ClearingId:         dto.RunID,
+		Status:             dto.Status,
+		ProgressPercentage: dto.Progress,
+		TradesProcessed:    int64(dto.SettlementCount) * dto.Progress / 100,
+		TradesTotal:        int64(dto.SettlementCount),
 	}, nil
 }
 
@@ -1,29 +1,79 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	clearingv1 "github.com/wyfcoding/financialtrading/go-api/clearing/v1"
 	"github.com/wyfcoding/financialtrading/internal/clearing/application"
+	"github.com/wyfcoding/financialtrading/internal/clearing/domain"
 )
 
 type ClearingHandler struct {
 	cmd   *application.ClearingCommandService
 	query *application.ClearingQueryService
+	eod   *application.EODClearingService
+	dm    *application.DefaultManagementService
+	span  *application.SpanMarginService
 }
 
 func NewClearingHandler(cmd *application.ClearingCommandService, query *application.ClearingQueryService) *ClearingHandler {
 	return &ClearingHandler{cmd: cmd, query: query}
 }
 
+// SetEODClearingService 注入日终清算服务，注入后注册日终清算路由
+func (h *ClearingHandler) SetEODClearingService(eod *application.EODClearingService) {
+	h.eod = eod
+}
+
+// SetDefaultManagementService 注入违约管理服务，注入后注册违约处置路由
+func (h *ClearingHandler) SetDefaultManagementService(dm *application.DefaultManagementService) {
+	h.dm = dm
+}
+
+// SetSpanMarginService 注入 SPAN 保证金服务，注入后注册 SPAN 路由
+func (h *ClearingHandler) SetSpanMarginService(span *application.SpanMarginService) {
+	h.span = span
+}
+
 func (h *ClearingHandler) RegisterRoutes(r *gin.RouterGroup) {
 	v1 := r.Group("/v1/clearing")
 	{
 		v1.POST("/settle", h.SettleTrade)
 		v1.GET("/settlement/:id", h.GetSettlement)
 	}
+	if h.eod != nil {
+		eod := v1.Group("/eod")
+		eod.POST("", h.ExecuteEODClearing)
+		eod.GET("/:run_id", h.GetClearingRun)
+		eod.GET("/:run_id/statements", h.ListMemberStatements)
+		eod.GET("/:run_id/postings", h.ListClearingPostings)
+	}
+	if h.dm != nil {
+		defaults := v1.Group("/defaults")
+		defaults.POST("", h.DeclareDefault)
+		defaults.GET("", h.ListDefaultCases)
+		defaults.GET("/:case_id", h.GetDefaultCase)
+		defaults.POST("/:case_id/bids", h.SubmitAuctionBid)
+		defaults.GET("/:case_id/bids", h.ListAuctionBids)
+		defaults.POST("/:case_id/close-out", h.CloseOutDefault)
+		defaults.GET("/:case_id/reports", h.ListDefaultReports)
+
+		collateral := v1.Group("/collateral")
+		collateral.PUT("", h.SaveMemberCollateral)
+		collateral.GET("", h.ListMemberCollateral)
+	}
+	if h.span != nil {
+		span := v1.Group("/span")
+		span.POST("/margin", h.CalculateSpanMargin)
+		span.POST("/risk-arrays", h.GetSpanRiskArrays)
+		span.GET("/parameters", h.GetSpanParameters)
+		span.POST("/reload", h.ReloadSpanParameters)
+	}
 }
 
 func (h *ClearingHandler) SettleTrade(c *gin.Context) {
@@ -63,3 +113,244 @@ func (h *ClearingHandler) GetSettlement(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, dto)
 }
+
+func (h *ClearingHandler) ExecuteEODClearing(c *gin.Context) {
+	var req struct {
+		ClearingDate string `json:"clearing_date" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dto, err := h.cmd.ExecuteEODClearing(c.Request.Context(), req.ClearingDate)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClearingDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, dto)
+}
+
+func (h *ClearingHandler) GetClearingRun(c *gin.Context) {
+	dto, err := h.eod.GetRun(c.Request.Context(), c.Param("run_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if dto == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clearing run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, dto)
+}
+
+func (h *ClearingHandler) ListMemberStatements(c *gin.Context) {
+	statements, err := h.eod.ListStatements(c.Request.Context(), c.Param("run_id"), c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, statements)
+}
+
+func (h *ClearingHandler) ListClearingPostings(c *gin.Context) {
+	postings, err := h.eod.ListPostings(c.Request.Context(), c.Param("run_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, postings)
+}
+
+func (h *ClearingHandler) DeclareDefault(c *gin.Context) {
+	var cmd application.DeclareDefaultCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record := &domain.DefaultRecord{UserID: cmd.UserID, Reason: cmd.Reason, Amount: cmd.Amount, Timestamp: time.Now()}
+	dc, err := h.dm.DeclareDefault(c.Request.Context(), record, cmd.Method)
+	if err != nil {
+		if errors.Is(err, domain.ErrMemberAlreadyInDefault) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, dc)
+}
+
+func (h *ClearingHandler) ListDefaultCases(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	cases, err := h.dm.ListCases(c.Request.Context(), domain.DefaultCaseStatus(c.Query("status")), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cases)
+}
+
+func (h *ClearingHandler) GetDefaultCase(c *gin.Context) {
+	dc, err := h.dm.GetCase(c.Request.Context(), c.Param("case_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if dc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrDefaultCaseNotFound.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dc)
+}
+
+func (h *ClearingHandler) SubmitAuctionBid(c *gin.Context) {
+	var cmd application.SubmitAuctionBidCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bid, err := h.dm.SubmitBid(c.Request.Context(), c.Param("case_id"), &cmd)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrDefaultCaseNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrAuctionClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrIneligibleBidder):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, bid)
+}
+
+func (h *ClearingHandler) ListAuctionBids(c *gin.Context) {
+	bids, err := h.dm.ListBids(c.Request.Context(), c.Param("case_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, bids)
+}
+
+func (h *ClearingHandler) CloseOutDefault(c *gin.Context) {
+	dc, err := h.dm.CloseOut(c.Request.Context(), c.Param("case_id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrDefaultCaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dc)
+}
+
+func (h *ClearingHandler) ListDefaultReports(c *gin.Context) {
+	reports, err := h.dm.MemberReports(c.Request.Context(), c.Param("case_id"), c.Query("user_id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrDefaultCaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+func (h *ClearingHandler) SaveMemberCollateral(c *gin.Context) {
+	var req domain.MemberCollateral
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.dm.SaveCollateral(c.Request.Context(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func (h *ClearingHandler) ListMemberCollateral(c *gin.Context) {
+	list, err := h.dm.ListCollateral(c.Request.Context(), c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *ClearingHandler) CalculateSpanMargin(c *gin.Context) {
+	var cmd application.CalculateSpanMarginCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.span.Calculate(c.Request.Context(), cmd)
+	if err != nil {
+		h.spanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type spanRiskArraysRequest struct {
+	Symbols      []string           `json:"symbols" binding:"required"`
+	Prices       map[string]float64 `json:"prices"`
+	Volatilities map[string]float64 `json:"volatilities"`
+}
+
+func (h *ClearingHandler) GetSpanRiskArrays(c *gin.Context) {
+	var req spanRiskArraysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	arrays, err := h.span.RiskArrays(c.Request.Context(), req.Symbols, req.Prices, req.Volatilities)
+	if err != nil {
+		h.spanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, arrays)
+}
+
+func (h *ClearingHandler) GetSpanParameters(c *gin.Context) {
+	params, err := h.span.Parameters()
+	if err != nil {
+		h.spanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, params)
+}
+
+func (h *ClearingHandler) ReloadSpanParameters(c *gin.Context) {
+	if err := h.span.Reload(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	params, _ := h.span.Parameters()
+	c.JSON(http.StatusOK, params)
+}
+
+func (h *ClearingHandler) spanError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, application.ErrSpanNotLoaded):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrUnknownSpanInstrument), errors.Is(err, domain.ErrMissingSpanPrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -162,6 +162,17 @@ func (s *CorporateActionService) ProcessPayments(ctx context.Context, eventID st
 	return s.actionRepo.Save(ctx, action)
 }
 
+// ListAccountEntitlements 查询账户在支付日区间内的权益
+func (s *CorporateActionService) ListAccountEntitlements(ctx context.Context, accountID string, from, to time.Time) ([]*domain.AccountEntitlement, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("account id is required")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid period: %s - %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return s.entitlementRepo.ListByAccount(ctx, accountID, from, to)
+}
+
 type CreateActionCmd struct {
 	Symbol           string
 	Type             domain.ActionType
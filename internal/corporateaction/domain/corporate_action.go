@@ -87,6 +87,16 @@ type Entitlement struct {
 
 func (Entitlement) TableName() string { return "ca_entitlements" }
 
+// AccountEntitlement 账户权益及其所属公司行动的摘要，用于账户对账单等查询
+type AccountEntitlement struct {
+	Entitlement
+	EventID     string     `json:"event_id"`
+	Symbol      string     `json:"symbol"`
+	ActionType  ActionType `json:"action_type"`
+	Currency    string     `json:"currency"`
+	PaymentDate time.Time  `json:"payment_date"`
+}
+
 // NewCorporateAction 创建新的公司行动
 func NewCorporateAction(eventID, symbol string, typ ActionType) *CorporateAction {
 	return &CorporateAction{
@@ -143,4 +153,6 @@ type EntitlementRepository interface {
 	Save(ctx context.Context, ent *Entitlement) error
 	ListByActionID(ctx context.Context, actionID uint) ([]*Entitlement, error)
 	GetByAccountAndAction(ctx context.Context, accountID string, actionID uint) (*Entitlement, error)
+	// ListByAccount 查询账户在支付日 [from, to) 内的权益，按支付日升序
+	ListByAccount(ctx context.Context, accountID string, from, to time.Time) ([]*AccountEntitlement, error)
 }
@@ -73,3 +73,14 @@ func (r *EntitlementRepositoryImpl) GetByAccountAndAction(ctx context.Context, a
 	err := r.getDB(ctx).Where("account_id = ? AND action_id = ?", accountID, actionID).First(&ent).Error
 	return &ent, err
 }
+
+func (r *EntitlementRepositoryImpl) ListByAccount(ctx context.Context, accountID string, from, to time.Time) ([]*domain.AccountEntitlement, error) {
+	var rows []*domain.AccountEntitlement
+	err := r.getDB(ctx).Model(&domain.Entitlement{}).
+		Select("ca_entitlements.*, ca_actions.event_id, ca_actions.symbol, ca_actions.type AS action_type, ca_actions.currency, ca_actions.payment_date").
+		Joins("JOIN ca_actions ON ca_actions.id = ca_entitlements.action_id AND ca_actions.deleted_at IS NULL").
+		Where("ca_entitlements.account_id = ? AND ca_actions.payment_date >= ? AND ca_actions.payment_date < ?", accountID, from, to).
+		Order("ca_actions.payment_date ASC, ca_entitlements.id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
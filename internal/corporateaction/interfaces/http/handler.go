@@ -23,6 +23,7 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	g := r.Group("/corporate-actions")
 	{
 		g.POST("", h.AnnounceAction)
+		g.GET("/entitlements", h.ListAccountEntitlements)
 		g.POST("/:id/calculate", h.CalculateEntitlements)
 		g.POST("/:id/process", h.ProcessPayments)
 	}
@@ -91,3 +92,32 @@ func (h *Handler) ProcessPayments(c *gin.Context) {
 	}
 	c.Status(http.StatusOK)
 }
+
+// ListAccountEntitlements 查询账户权益，from/to 为支付日区间（RFC3339 或 2006-01-02，to 不含）
+func (h *Handler) ListAccountEntitlements(c *gin.Context) {
+	accountID := c.Query("account_id")
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+
+	ents, err := h.service.ListAccountEntitlements(c.Request.Context(), accountID, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entitlements": ents})
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
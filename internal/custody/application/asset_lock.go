@@ -0,0 +1,182 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/custody/domain"
+)
+
+// LockAssetCommand 按业务键锁定客户库位中的证券
+type LockAssetCommand struct {
+	LockID string
+	UserID uint64
+	Symbol string
+	Amount int64
+	Reason string
+}
+
+// SetAssetLockRepository 启用按业务键幂等的证券锁定（DVP 交收使用）
+func (s *CustodyCommandService) SetAssetLockRepository(repo domain.AssetLockRepository) {
+	s.lockRepo = repo
+}
+
+// LockAsset 锁定证券。同一 LockID 重复请求返回已有记录；
+// 已被释放（含空补偿）的 LockID 不允许再次锁定。
+func (s *CustodyCommandService) LockAsset(ctx context.Context, cmd LockAssetCommand) (*domain.AssetLock, error) {
+	if s.lockRepo == nil {
+		return nil, errors.New("asset locking is not enabled")
+	}
+	var lock *domain.AssetLock
+	err := s.lockRepo.WithTx(ctx, func(txCtx context.Context) error {
+		existing, err := s.lockRepo.FindLock(txCtx, cmd.LockID)
+		if err == nil {
+			if existing.Status == domain.AssetLockReleased {
+				return fmt.Errorf("%w: lock %s already released", domain.ErrLockConflict, cmd.LockID)
+			}
+			lock = existing
+			return nil
+		}
+		if !errors.Is(err, domain.ErrLockNotFound) {
+			return err
+		}
+
+		vault, err := s.vaultRepo.FindVaultByUser(txCtx, cmd.UserID, cmd.Symbol)
+		if err != nil {
+			return fmt.Errorf("customer vault not found: %w", err)
+		}
+		if lock, err = domain.NewAssetLock(cmd.LockID, vault, cmd.Amount, cmd.Reason); err != nil {
+			return err
+		}
+		if err := s.vaultRepo.SaveVault(txCtx, vault); err != nil {
+			return err
+		}
+		return s.lockRepo.SaveLock(txCtx, lock)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("asset locked", "lock_id", lock.LockID, "vault_id", lock.VaultID, "amount", lock.Amount)
+	return lock, nil
+}
+
+// ReleaseAsset 释放锁定证券。LockID 不存在时登记空补偿，已交付时返回冲突。
+func (s *CustodyCommandService) ReleaseAsset(ctx context.Context, lockID, reason string) (*domain.AssetLock, error) {
+	if s.lockRepo == nil {
+		return nil, errors.New("asset locking is not enabled")
+	}
+	var lock *domain.AssetLock
+	err := s.lockRepo.WithTx(ctx, func(txCtx context.Context) error {
+		existing, err := s.lockRepo.FindLock(txCtx, lockID)
+		if errors.Is(err, domain.ErrLockNotFound) {
+			lock = domain.NewReleasedAssetLock(lockID, reason)
+			return s.lockRepo.SaveLock(txCtx, lock)
+		}
+		if err != nil {
+			return err
+		}
+		lock = existing
+		if lock.Status == domain.AssetLockReleased {
+			return nil
+		}
+
+		vault, err := s.vaultRepo.FindVaultByID(txCtx, lock.VaultID)
+		if err != nil {
+			return fmt.Errorf("locked vault not found: %w", err)
+		}
+		if err := lock.Release(vault); err != nil {
+			return err
+		}
+		if err := s.vaultRepo.SaveVault(txCtx, vault); err != nil {
+			return err
+		}
+		return s.lockRepo.SaveLock(txCtx, lock)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("asset lock released", "lock_id", lockID, "reason", reason)
+	return lock, nil
+}
+
+// DeliverAsset 将锁定证券交付给接收方客户库位，接收方库位不存在时自动开立
+func (s *CustodyCommandService) DeliverAsset(ctx context.Context, lockID string, toUserID uint64) (*domain.AssetLock, error) {
+	if s.lockRepo == nil {
+		return nil, errors.New("asset locking is not enabled")
+	}
+	var lock *domain.AssetLock
+	err := s.lockRepo.WithTx(ctx, func(txCtx context.Context) error {
+		var err error
+		if lock, err = s.lockRepo.FindLock(txCtx, lockID); err != nil {
+			return err
+		}
+		if lock.Status == domain.AssetLockDelivered {
+			return nil
+		}
+
+		from, err := s.vaultRepo.FindVaultByID(txCtx, lock.VaultID)
+		if err != nil {
+			return fmt.Errorf("locked vault not found: %w", err)
+		}
+		to, err := s.vaultRepo.FindVaultByUser(txCtx, toUserID, lock.Symbol)
+		if err != nil {
+			to = domain.NewCustomerVault(toUserID, lock.Symbol)
+		}
+		if err := lock.Deliver(from, to); err != nil {
+			return err
+		}
+
+		transfer := &domain.CustodyTransfer{
+			TransferID: fmt.Sprintf("TX-%d", time.Now().UnixNano()),
+			FromVault:  from.VaultID,
+			ToVault:    to.VaultID,
+			Symbol:     lock.Symbol,
+			Amount:     lock.Amount,
+			Reason:     "lock_delivery:" + lockID,
+			Timestamp:  time.Now(),
+		}
+		if err := s.vaultRepo.SaveVault(txCtx, from); err != nil {
+			return err
+		}
+		if err := s.vaultRepo.SaveVault(txCtx, to); err != nil {
+			return err
+		}
+		if err := s.vaultRepo.SaveTransfer(txCtx, transfer); err != nil {
+			return err
+		}
+		return s.lockRepo.SaveLock(txCtx, lock)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("locked asset delivered", "lock_id", lockID, "to_vault", lock.ToVault, "amount", lock.Amount)
+	return lock, nil
+}
+
+// SetAssetLockRepository 启用证券锁定接口
+func (s *CustodyAppService) SetAssetLockRepository(repo domain.AssetLockRepository) {
+	s.cmd.SetAssetLockRepository(repo)
+}
+
+func (s *CustodyAppService) LockAsset(ctx context.Context, lockID string, userID uint64, symbol string, amount int64, reason string) (*domain.AssetLock, error) {
+	return s.cmd.LockAsset(ctx, LockAssetCommand{
+		LockID: lockID,
+		UserID: userID,
+		Symbol: symbol,
+		Amount: amount,
+		Reason: reason,
+	})
+}
+
+func (s *CustodyAppService) ReleaseAsset(ctx context.Context, lockID, reason string) (*domain.AssetLock, error) {
+	return s.cmd.ReleaseAsset(ctx, lockID, reason)
+}
+
+func (s *CustodyAppService) DeliverAsset(ctx context.Context, lockID string, toUserID uint64) (*domain.AssetLock, error) {
+	return s.cmd.DeliverAsset(ctx, lockID, toUserID)
+}
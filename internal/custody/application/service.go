@@ -36,6 +36,7 @@ type AnnounceActionCommand struct {
 type CustodyCommandService struct {
 	vaultRepo  domain.CustodyRepository
 	actionRepo domain.CorpActionRepository
+	lockRepo   domain.AssetLockRepository
 	logger     *slog.Logger
 }
 
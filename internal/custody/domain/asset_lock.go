@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrLockNotFound 锁定记录不存在
+	ErrLockNotFound = errors.New("asset lock not found")
+	// ErrLockConflict 锁定记录已处于不允许当前操作的终态
+	ErrLockConflict = errors.New("asset lock conflict")
+)
+
+// AssetLockStatus 证券锁定状态
+type AssetLockStatus string
+
+const (
+	AssetLockLocked    AssetLockStatus = "LOCKED"
+	AssetLockReleased  AssetLockStatus = "RELEASED"
+	AssetLockDelivered AssetLockStatus = "DELIVERED"
+)
+
+// AssetLock 以业务键标识的一笔证券锁定（如 DVP 交收编号）。
+// 锁定、释放与交付都按 LockID 幂等，供跨服务事务的重试与补偿使用。
+type AssetLock struct {
+	LockID    string          `json:"lock_id"`
+	VaultID   string          `json:"vault_id"`
+	UserID    uint64          `json:"user_id"`
+	Symbol    string          `json:"symbol"`
+	Amount    int64           `json:"amount"`
+	Status    AssetLockStatus `json:"status"`
+	ToVault   string          `json:"to_vault"`
+	Reason    string          `json:"reason"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// NewAssetLock 在客户库位上锁定指定数量的证券
+func NewAssetLock(lockID string, vault *AssetVault, amount int64, reason string) (*AssetLock, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("lock amount must be positive: %d", amount)
+	}
+	if err := vault.Lock(amount); err != nil {
+		return nil, fmt.Errorf("vault %s: %w", vault.VaultID, err)
+	}
+	now := time.Now()
+	return &AssetLock{
+		LockID:    lockID,
+		VaultID:   vault.VaultID,
+		UserID:    vault.UserID,
+		Symbol:    vault.Symbol,
+		Amount:    amount,
+		Status:    AssetLockLocked,
+		Reason:    reason,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// NewReleasedAssetLock 登记一条从未锁定即被释放的记录（空补偿），
+// 防止补偿先于锁定请求到达时，迟到的锁定再次冻结证券。
+func NewReleasedAssetLock(lockID, reason string) *AssetLock {
+	now := time.Now()
+	return &AssetLock{
+		LockID:    lockID,
+		Status:    AssetLockReleased,
+		Reason:    reason,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Release 解除锁定，返还库位可用余额；已释放时为空操作
+func (l *AssetLock) Release(vault *AssetVault) error {
+	switch l.Status {
+	case AssetLockReleased:
+		return nil
+	case AssetLockDelivered:
+		return fmt.Errorf("%w: lock %s already delivered", ErrLockConflict, l.LockID)
+	}
+	vault.Unlock(l.Amount)
+	l.Status = AssetLockReleased
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// Deliver 将锁定证券从原库位划入目标库位；已交付时为空操作
+func (l *AssetLock) Deliver(from, to *AssetVault) error {
+	switch l.Status {
+	case AssetLockDelivered:
+		return nil
+	case AssetLockReleased:
+		return fmt.Errorf("%w: lock %s already released", ErrLockConflict, l.LockID)
+	}
+	if from.Symbol != to.Symbol {
+		return fmt.Errorf("symbol mismatch: %s vs %s", from.Symbol, to.Symbol)
+	}
+	if err := from.DeliverLocked(l.Amount); err != nil {
+		return err
+	}
+	to.SafeCredit(l.Amount)
+	l.Status = AssetLockDelivered
+	l.ToVault = to.VaultID
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// AssetLockRepository 证券锁定记录仓储
+type AssetLockRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// FindLock 记录不存在时返回 ErrLockNotFound
+	FindLock(ctx context.Context, lockID string) (*AssetLock, error)
+	SaveLock(ctx context.Context, lock *AssetLock) error
+}
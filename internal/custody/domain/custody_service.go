@@ -90,6 +90,18 @@ func (v *AssetVault) Unlock(amount int64) {
 	v.UpdatedAt = time.Now()
 }
 
+// DeliverLocked 从已锁定部分中扣减并交付，余额与锁定量同时减少
+func (v *AssetVault) DeliverLocked(amount int64) error {
+	if v.Locked < amount {
+		return fmt.Errorf("insufficient locked balance in vault %s: locked=%d, requested=%d",
+			v.VaultID, v.Locked, amount)
+	}
+	v.Locked -= amount
+	v.Balance -= amount
+	v.UpdatedAt = time.Now()
+	return nil
+}
+
 func (v *AssetVault) IsCustomerVault() bool {
 	return v.Type == VaultCustomer
 }
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wyfcoding/financialtrading/internal/custody/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AssetLockRepo struct {
+	db *gorm.DB
+}
+
+func NewAssetLockRepo(db *gorm.DB) domain.AssetLockRepository {
+	return &AssetLockRepo{db: db}
+}
+
+func (r *AssetLockRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(contextx.WithTx(ctx, tx))
+	})
+}
+
+func (r *AssetLockRepo) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+// FindLock 在事务内加行锁，串行化同一锁定记录的并发操作
+func (r *AssetLockRepo) FindLock(ctx context.Context, lockID string) (*domain.AssetLock, error) {
+	var model AssetLockModel
+	db := r.getDB(ctx).WithContext(ctx)
+	if _, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := db.Where("lock_id = ?", lockID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrLockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomainLock(&model), nil
+}
+
+func (r *AssetLockRepo) SaveLock(ctx context.Context, lock *domain.AssetLock) error {
+	model := AssetLockModel{
+		LockID:  lock.LockID,
+		VaultID: lock.VaultID,
+		UserID:  lock.UserID,
+		Symbol:  lock.Symbol,
+		Amount:  lock.Amount,
+		Status:  string(lock.Status),
+		ToVault: lock.ToVault,
+		Reason:  lock.Reason,
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "lock_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "to_vault", "updated_at"}),
+	}).Create(&model).Error
+}
+
+func toDomainLock(m *AssetLockModel) *domain.AssetLock {
+	return &domain.AssetLock{
+		LockID:    m.LockID,
+		VaultID:   m.VaultID,
+		UserID:    m.UserID,
+		Symbol:    m.Symbol,
+		Amount:    m.Amount,
+		Status:    domain.AssetLockStatus(m.Status),
+		ToVault:   m.ToVault,
+		Reason:    m.Reason,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+var _ domain.AssetLockRepository = (*AssetLockRepo)(nil)
@@ -57,3 +57,17 @@ type CorpActionExecutionModel struct {
 }
 
 func (CorpActionExecutionModel) TableName() string { return "corp_action_executions" }
+
+type AssetLockModel struct {
+	gorm.Model
+	LockID  string `gorm:"column:lock_id;type:varchar(64);uniqueIndex;not null"`
+	VaultID string `gorm:"column:vault_id;type:varchar(64);index"`
+	UserID  uint64 `gorm:"column:user_id;index"`
+	Symbol  string `gorm:"column:symbol;type:varchar(32)"`
+	Amount  int64  `gorm:"column:amount;not null;default:0"`
+	Status  string `gorm:"column:status;type:varchar(16);not null"`
+	ToVault string `gorm:"column:to_vault;type:varchar(64)"`
+	Reason  string `gorm:"column:reason;type:varchar(255)"`
+}
+
+func (AssetLockModel) TableName() string { return "asset_locks" }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/wyfcoding/financialtrading/internal/custody/domain"
+	"github.com/wyfcoding/pkg/contextx"
 	"gorm.io/gorm"
 )
 
@@ -15,9 +16,16 @@ func NewCustodyRepo(db *gorm.DB) domain.CustodyRepository {
 	return &CustodyRepo{db: db}
 }
 
+func (r *CustodyRepo) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
 func (r *CustodyRepo) FindVaultByID(ctx context.Context, vaultID string) (*domain.AssetVault, error) {
 	var model AssetVaultModel
-	if err := r.db.WithContext(ctx).Where("vault_id = ?", vaultID).First(&model).Error; err != nil {
+	if err := r.getDB(ctx).WithContext(ctx).Where("vault_id = ?", vaultID).First(&model).Error; err != nil {
 		return nil, err
 	}
 	return toDomainVault(&model), nil
@@ -25,7 +33,7 @@ func (r *CustodyRepo) FindVaultByID(ctx context.Context, vaultID string) (*domai
 
 func (r *CustodyRepo) FindVaultByUser(ctx context.Context, userID uint64, symbol string) (*domain.AssetVault, error) {
 	var model AssetVaultModel
-	if err := r.db.WithContext(ctx).
+	if err := r.getDB(ctx).WithContext(ctx).
 		Where("user_id = ? AND symbol = ? AND type = ?", userID, symbol, string(domain.VaultCustomer)).
 		First(&model).Error; err != nil {
 		return nil, err
@@ -35,7 +43,7 @@ func (r *CustodyRepo) FindVaultByUser(ctx context.Context, userID uint64, symbol
 
 func (r *CustodyRepo) FindVaultByType(ctx context.Context, vaultType domain.VaultType, symbol string) (*domain.AssetVault, error) {
 	var model AssetVaultModel
-	query := r.db.WithContext(ctx).Where("type = ?", string(vaultType))
+	query := r.getDB(ctx).WithContext(ctx).Where("type = ?", string(vaultType))
 	if symbol != "" {
 		query = query.Where("symbol = ?", symbol)
 	}
@@ -47,7 +55,7 @@ func (r *CustodyRepo) FindVaultByType(ctx context.Context, vaultType domain.Vaul
 
 func (r *CustodyRepo) FindVaultsByUserID(ctx context.Context, userID uint64) ([]*domain.AssetVault, error) {
 	var models []AssetVaultModel
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&models).Error; err != nil {
+	if err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	var vaults []*domain.AssetVault
@@ -63,7 +71,7 @@ func (r *CustodyRepo) ListVaultsByUser(ctx context.Context, userID uint64) ([]*d
 
 func (r *CustodyRepo) ListVaultsByType(ctx context.Context, vaultType domain.VaultType) ([]*domain.AssetVault, error) {
 	var models []AssetVaultModel
-	if err := r.db.WithContext(ctx).Where("type = ?", string(vaultType)).Find(&models).Error; err != nil {
+	if err := r.getDB(ctx).WithContext(ctx).Where("type = ?", string(vaultType)).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	vaults := make([]*domain.AssetVault, 0, len(models))
@@ -75,7 +83,7 @@ func (r *CustodyRepo) ListVaultsByType(ctx context.Context, vaultType domain.Vau
 
 func (r *CustodyRepo) ListVaultsBySymbol(ctx context.Context, symbol string) ([]*domain.AssetVault, error) {
 	var models []AssetVaultModel
-	if err := r.db.WithContext(ctx).Where("symbol = ?", symbol).Find(&models).Error; err != nil {
+	if err := r.getDB(ctx).WithContext(ctx).Where("symbol = ?", symbol).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	vaults := make([]*domain.AssetVault, 0, len(models))
@@ -96,11 +104,11 @@ func (r *CustodyRepo) SaveVault(ctx context.Context, vault *domain.AssetVault) e
 	}
 	// Check exist for ID
 	var exist AssetVaultModel
-	if err := r.db.WithContext(ctx).Where("vault_id = ?", vault.VaultID).First(&exist).Error; err == nil {
+	if err := r.getDB(ctx).WithContext(ctx).Where("vault_id = ?", vault.VaultID).First(&exist).Error; err == nil {
 		model.ID = exist.ID
 		model.CreatedAt = exist.CreatedAt
 	}
-	return r.db.WithContext(ctx).Save(&model).Error
+	return r.getDB(ctx).WithContext(ctx).Save(&model).Error
 }
 
 func (r *CustodyRepo) SaveTransfer(ctx context.Context, transfer *domain.CustodyTransfer) error {
@@ -113,12 +121,12 @@ func (r *CustodyRepo) SaveTransfer(ctx context.Context, transfer *domain.Custody
 		Reason:     transfer.Reason,
 		Timestamp:  transfer.Timestamp,
 	}
-	return r.db.WithContext(ctx).Save(&model).Error
+	return r.getDB(ctx).WithContext(ctx).Save(&model).Error
 }
 
 func (r *CustodyRepo) ListTransfersByVault(ctx context.Context, vaultID string, limit int) ([]*domain.CustodyTransfer, error) {
 	var models []CustodyTransferModel
-	query := r.db.WithContext(ctx).
+	query := r.getDB(ctx).WithContext(ctx).
 		Where("from_vault = ? OR to_vault = ?", vaultID, vaultID).
 		Order("timestamp DESC")
 	if limit > 0 {
@@ -2,10 +2,12 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	pb "github.com/wyfcoding/financialtrading/go-api/custody/v1"
 	"github.com/wyfcoding/financialtrading/internal/custody/application"
+	"github.com/wyfcoding/financialtrading/internal/custody/domain"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -75,3 +77,53 @@ func (s *Server) ExecuteBatchAction(ctx context.Context, req *pb.ExecuteBatchAct
 	}
 	return &pb.ExecuteBatchActionResponse{Success: true}, nil
 }
+
+func (s *Server) LockAsset(ctx context.Context, req *pb.LockAssetRequest) (*pb.AssetLockResponse, error) {
+	if req.LockId == "" || req.Amount <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "lock_id and positive amount are required")
+	}
+	lock, err := s.app.LockAsset(ctx, req.LockId, req.UserId, req.Symbol, req.Amount, req.Reason)
+	if err != nil {
+		return nil, lockError("lock", err)
+	}
+	return toLockResponse(lock), nil
+}
+
+func (s *Server) ReleaseAsset(ctx context.Context, req *pb.ReleaseAssetRequest) (*pb.AssetLockResponse, error) {
+	lock, err := s.app.ReleaseAsset(ctx, req.LockId, req.Reason)
+	if err != nil {
+		return nil, lockError("release", err)
+	}
+	return toLockResponse(lock), nil
+}
+
+func (s *Server) DeliverAsset(ctx context.Context, req *pb.DeliverAssetRequest) (*pb.AssetLockResponse, error) {
+	lock, err := s.app.DeliverAsset(ctx, req.LockId, req.ToUserId)
+	if err != nil {
+		return nil, lockError("delivery", err)
+	}
+	return toLockResponse(lock), nil
+}
+
+// lockError 业务拒绝返回 Aborted，调用方据此停止重试并进入补偿
+func lockError(op string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrLockNotFound):
+		return status.Errorf(codes.NotFound, "%s failed: %v", op, err)
+	case errors.Is(err, domain.ErrLockConflict):
+		return status.Errorf(codes.FailedPrecondition, "%s failed: %v", op, err)
+	default:
+		return status.Errorf(codes.Aborted, "%s failed: %v", op, err)
+	}
+}
+
+func toLockResponse(lock *domain.AssetLock) *pb.AssetLockResponse {
+	return &pb.AssetLockResponse{
+		LockId:  lock.LockID,
+		VaultId: lock.VaultID,
+		Symbol:  lock.Symbol,
+		Amount:  lock.Amount,
+		Status:  string(lock.Status),
+		ToVault: lock.ToVault,
+	}
+}
@@ -80,6 +80,7 @@ func (q *ExecutionQueryService) toDTO(t *domain.Trade) *ExecutionDTO {
 		ExecutionID: t.TradeID,
 		OrderID:     t.OrderID,
 		Symbol:      t.Symbol,
+		Side:        string(t.Side),
 		Status:      "FILLED",
 		ExecutedQty: t.ExecutedQuantity.String(),
 		ExecutedPx:  t.ExecutedPrice.String(),
@@ -7,6 +7,7 @@ type ExecutionDTO struct {
 	ExecutionID string `json:"execution_id"`
 	OrderID     string `json:"order_id"`
 	Symbol      string `json:"symbol,omitempty"`
+	Side        string `json:"side,omitempty"`
 	Status      string `json:"status"`
 	ExecutedQty string `json:"executed_qty"`
 	ExecutedPx  string `json:"executed_px"`
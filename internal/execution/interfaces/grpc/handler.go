@@ -65,7 +65,14 @@ func (h *Handler) GetExecutionHistory(ctx context.Context, req *pb.GetExecutionH
 	start := time.Now()
 	slog.DebugContext(ctx, "grpc get_execution_history received", "user_id", req.UserId)
 
-	dtos, _, err := h.query.ListExecutions(ctx, req.UserId, "", 100, 0)
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 100
+		if req.StartTime > 0 || req.EndTime > 0 {
+			limit = 1000
+		}
+	}
+	dtos, _, err := h.query.ListExecutions(ctx, req.UserId, req.Symbol, limit, 0)
 	if err != nil {
 		slog.ErrorContext(ctx, "grpc get_execution_history failed", "user_id", req.UserId, "error", err, "duration", time.Since(start))
 		return nil, status.Errorf(codes.Internal, "failed to get execution history: %v", err)
@@ -73,6 +80,9 @@ func (h *Handler) GetExecutionHistory(ctx context.Context, req *pb.GetExecutionH
 
 	records := make([]*pb.ExecutionRecord, 0, len(dtos))
 	for _, dto := range dtos {
+		if (req.StartTime > 0 && dto.Timestamp < req.StartTime) || (req.EndTime > 0 && dto.Timestamp >= req.EndTime) {
+			continue
+		}
 		records = append(records, &pb.ExecutionRecord{
 			ExecutionId:      dto.ExecutionID,
 			OrderId:          dto.OrderID,
@@ -80,6 +90,7 @@ func (h *Handler) GetExecutionHistory(ctx context.Context, req *pb.GetExecutionH
 			ExecutedQuantity: dto.ExecutedQty,
 			ExecutedPrice:    dto.ExecutedPx,
 			Timestamp:        dto.Timestamp,
+			Side:             dto.Side,
 		})
 	}
 
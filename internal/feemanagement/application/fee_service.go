@@ -6,8 +6,8 @@ import (
 	"log/slog"
 	"time"
 
-	pb "github.com/wyfcoding/financialTrading/go-api/feemanagement/v1"
-	"github.com/wyfcoding/financialTrading/internal/feemanagement/domain"
+	pb "github.com/wyfcoding/financialtrading/go-api/feemanagement/v1"
+	"github.com/wyfcoding/financialtrading/internal/feemanagement/domain"
 	"github.com/wyfcoding/pkg/idgen"
 )
 
@@ -84,3 +84,14 @@ func (s *FeeService) CalculateTradeFees(ctx context.Context, tradeID, orderID, u
 func (s *FeeService) ListSchedules(ctx context.Context) ([]*domain.FeeSchedule, error) {
 	return s.repo.ListSchedules(ctx)
 }
+
+// ListTradeFees 查询用户在指定区间内的交易手续费，供账户对账单汇总使用
+func (s *FeeService) ListTradeFees(ctx context.Context, userID string, from, to time.Time) ([]*domain.TradeFeeRecord, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid period: %s - %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	return s.repo.ListTradeFeesByUser(ctx, userID, from, to)
+}
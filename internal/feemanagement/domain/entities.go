@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	pb "github.com/wyfcoding/financialTrading/go-api/feemanagement/v1"
+	pb "github.com/wyfcoding/financialtrading/go-api/feemanagement/v1"
 )
 
 // FeeSchedule 聚合根，代表手续费率表.
@@ -45,6 +45,8 @@ type FeeRepository interface {
 	ListSchedules(ctx context.Context) ([]*FeeSchedule, error)
 	SaveTradeFee(ctx context.Context, f *TradeFeeRecord) error
 	GetTradeFees(ctx context.Context, tradeID string) (*TradeFeeRecord, error)
+	// ListTradeFeesByUser 查询用户在 [from, to) 内计收的手续费，按计收时间升序.
+	ListTradeFeesByUser(ctx context.Context, userID string, from, to time.Time) ([]*TradeFeeRecord, error)
 }
 
 // 领域辅助方法：根据成交信息计算手续费
@@ -58,4 +60,3 @@ func (s *FeeSchedule) Calculate(amount float64) float64 {
 	}
 	return fee
 }
-创新：通过领域模型直接封装计算逻辑，保证了业务规则的内聚。
@@ -3,8 +3,9 @@ package mysql
 import (
 	"context"
 	"encoding/json"
+	"time"
 
-	"github.com/wyfcoding/financialTrading/internal/feemanagement/domain"
+	"github.com/wyfcoding/financialtrading/internal/feemanagement/domain"
 	"github.com/wyfcoding/pkg/database"
 	"github.com/wyfcoding/pkg/logging"
 	"gorm.io/gorm"
@@ -100,6 +101,20 @@ func (r *feeRepository) GetTradeFees(ctx context.Context, tradeID string) (*doma
 	return toTradeFeeDomain(&m), nil
 }
 
+func (r *feeRepository) ListTradeFeesByUser(ctx context.Context, userID string, from, to time.Time) ([]*domain.TradeFeeRecord, error) {
+	var models []*TradeFeeModel
+	if err := r.db.RawDB().WithContext(ctx).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, from, to).
+		Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.TradeFeeRecord, len(models))
+	for i, m := range models {
+		res[i] = toTradeFeeDomain(m)
+	}
+	return res, nil
+}
+
 func toScheduleDomain(m *FeeScheduleModel) *domain.FeeSchedule {
 	return &domain.FeeSchedule{
 		ID:         m.ScheduleID,
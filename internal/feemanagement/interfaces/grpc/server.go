@@ -0,0 +1,43 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/wyfcoding/financialtrading/go-api/feemanagement/v1"
+	"github.com/wyfcoding/financialtrading/internal/feemanagement/application"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	pb.UnimplementedFeeManagementServiceServer
+	app *application.FeeService
+}
+
+func NewServer(app *application.FeeService) *Server {
+	return &Server{app: app}
+}
+
+func (s *Server) ListTradeFees(ctx context.Context, req *pb.ListTradeFeesRequest) (*pb.ListTradeFeesResponse, error) {
+	to := time.Now()
+	if req.EndTime != nil {
+		to = req.EndTime.AsTime()
+	}
+	fees, err := s.app.ListTradeFees(ctx, req.UserId, req.StartTime.AsTime(), to)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "list trade fees failed: %v", err)
+	}
+	resp := &pb.ListTradeFeesResponse{Fees: make([]*pb.TradeFeeRecord, len(fees))}
+	for i, f := range fees {
+		resp.Fees[i] = &pb.TradeFeeRecord{
+			TradeId:      f.TradeID,
+			OrderId:      f.OrderID,
+			TotalFee:     f.TotalFee,
+			Currency:     f.Currency,
+			CalculatedAt: timestamppb.New(f.CalculatedAt),
+		}
+	}
+	return resp, nil
+}
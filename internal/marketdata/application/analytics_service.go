@@ -0,0 +1,219 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/marketdata/domain"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+// defaultAnalyticsPublishInterval 同一标的分析事件的最小发布间隔，避免逐笔成交放大消息量
+const defaultAnalyticsPublishInterval = time.Second
+
+// MarketAnalyticsService 维护各标的的流式衍生行情分析（VWAP、已实现波动率锥、价差、盘口失衡、VPIN）。
+// 由行情事件投影驱动，快照写入读模型缓存，并通过广播与领域事件推送给订阅方。
+type MarketAnalyticsService struct {
+	cfg         domain.AnalyticsConfig
+	readRepo    domain.AnalyticsReadRepository
+	publisher   messagequeue.EventPublisher
+	broadcaster Broadcaster
+	logger      *slog.Logger
+
+	mu          sync.Mutex
+	calculators map[string]*symbolCalculator
+
+	publishInterval time.Duration
+}
+
+type symbolCalculator struct {
+	mu            sync.Mutex
+	analytics     *domain.SymbolAnalytics
+	lastPublished time.Time
+	// trailing 节流窗口内有未发布的更新时，窗口结束时补发最新快照
+	trailing *time.Timer
+}
+
+// NewMarketAnalyticsService 构造函数。
+func NewMarketAnalyticsService(cfg domain.AnalyticsConfig, readRepo domain.AnalyticsReadRepository, publisher messagequeue.EventPublisher, logger *slog.Logger) *MarketAnalyticsService {
+	return &MarketAnalyticsService{
+		cfg:             cfg,
+		readRepo:        readRepo,
+		publisher:       publisher,
+		logger:          logger,
+		calculators:     make(map[string]*symbolCalculator),
+		publishInterval: defaultAnalyticsPublishInterval,
+	}
+}
+
+// SetBroadcaster 启用分析快照的实时推送
+func (s *MarketAnalyticsService) SetBroadcaster(b Broadcaster) {
+	s.broadcaster = b
+}
+
+// OnTrade 处理公开成交
+func (s *MarketAnalyticsService) OnTrade(ctx context.Context, trade *domain.Trade) {
+	if trade == nil {
+		return
+	}
+	ts := trade.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	s.apply(ctx, trade.Symbol, func(a *domain.SymbolAnalytics) {
+		a.OnTrade(trade.Price, trade.Quantity, trade.Side, ts)
+	})
+}
+
+// OnQuote 处理最优报价
+func (s *MarketAnalyticsService) OnQuote(ctx context.Context, quote *domain.Quote) {
+	if quote == nil {
+		return
+	}
+	s.apply(ctx, quote.Symbol, func(a *domain.SymbolAnalytics) {
+		a.OnQuote(quote.BidPrice, quote.AskPrice, quote.BidSize, quote.AskSize, quote.Timestamp)
+	})
+}
+
+// OnOrderBook 处理订单簿快照
+func (s *MarketAnalyticsService) OnOrderBook(ctx context.Context, ob *domain.OrderBook) {
+	if ob == nil {
+		return
+	}
+	s.apply(ctx, ob.Symbol, func(a *domain.SymbolAnalytics) {
+		a.OnOrderBook(ob)
+	})
+}
+
+// GetAnalytics 查询标的的最新分析快照，优先使用本地计算器，其次读模型缓存
+func (s *MarketAnalyticsService) GetAnalytics(ctx context.Context, symbol string) (*MarketAnalyticsDTO, error) {
+	s.mu.Lock()
+	calc, ok := s.calculators[symbol]
+	s.mu.Unlock()
+	if ok {
+		calc.mu.Lock()
+		snap := calc.analytics.Snapshot()
+		calc.mu.Unlock()
+		return toMarketAnalyticsDTO(snap), nil
+	}
+
+	if s.readRepo == nil {
+		return nil, nil
+	}
+	snap, err := s.readRepo.Get(ctx, symbol)
+	if err != nil || snap == nil {
+		return nil, err
+	}
+	return toMarketAnalyticsDTO(snap), nil
+}
+
+// RealizedVolatility 返回指定窗口的已实现波动率，供波动率查询使用
+func (s *MarketAnalyticsService) RealizedVolatility(ctx context.Context, symbol string, window time.Duration) (decimal.Decimal, bool) {
+	s.mu.Lock()
+	calc, ok := s.calculators[symbol]
+	s.mu.Unlock()
+
+	var snap *domain.MarketAnalytics
+	if ok {
+		calc.mu.Lock()
+		snap = calc.analytics.Snapshot()
+		calc.mu.Unlock()
+	} else if s.readRepo != nil {
+		snap, _ = s.readRepo.Get(ctx, symbol)
+	}
+	if snap == nil {
+		return decimal.Zero, false
+	}
+	vol, found := snap.RealizedVolatility(window)
+	if !found || vol <= 0 {
+		return decimal.Zero, false
+	}
+	return decimal.NewFromFloat(vol), true
+}
+
+func (s *MarketAnalyticsService) calculator(symbol string) *symbolCalculator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calc, ok := s.calculators[symbol]
+	if !ok {
+		calc = &symbolCalculator{analytics: domain.NewSymbolAnalytics(symbol, s.cfg)}
+		s.calculators[symbol] = calc
+	}
+	return calc
+}
+
+// apply 在标的锁内更新计算器，并按发布间隔写入读模型及推送。
+// 节流窗口内的更新不会丢失：窗口结束时补发一次最新快照
+func (s *MarketAnalyticsService) apply(ctx context.Context, symbol string, fn func(a *domain.SymbolAnalytics)) {
+	if symbol == "" {
+		return
+	}
+	calc := s.calculator(symbol)
+
+	calc.mu.Lock()
+	fn(calc.analytics)
+	now := time.Now()
+	if wait := s.publishInterval - now.Sub(calc.lastPublished); wait > 0 {
+		if calc.trailing == nil {
+			flushCtx := context.WithoutCancel(ctx)
+			calc.trailing = time.AfterFunc(wait, func() { s.flush(flushCtx, calc) })
+		}
+		calc.mu.Unlock()
+		return
+	}
+	if calc.trailing != nil {
+		calc.trailing.Stop()
+		calc.trailing = nil
+	}
+	calc.lastPublished = now
+	snap := calc.analytics.Snapshot()
+	calc.mu.Unlock()
+
+	s.emit(ctx, snap)
+}
+
+// flush 节流窗口结束时发布窗口内积累的最新快照
+func (s *MarketAnalyticsService) flush(ctx context.Context, calc *symbolCalculator) {
+	calc.mu.Lock()
+	calc.trailing = nil
+	calc.lastPublished = time.Now()
+	snap := calc.analytics.Snapshot()
+	calc.mu.Unlock()
+
+	s.emit(ctx, snap)
+}
+
+func (s *MarketAnalyticsService) emit(ctx context.Context, snap *domain.MarketAnalytics) {
+	if s.readRepo != nil {
+		if err := s.readRepo.Save(ctx, snap); err != nil {
+			s.logger.WarnContext(ctx, "failed to save analytics snapshot", "symbol", snap.Symbol, "error", err)
+		}
+	}
+
+	if s.broadcaster != nil {
+		if err := s.broadcaster.Broadcast("marketdata.analytics."+snap.Symbol, toMarketAnalyticsDTO(snap)); err != nil {
+			s.logger.WarnContext(ctx, "failed to broadcast analytics", "symbol", snap.Symbol, "error", err)
+		}
+	}
+
+	if s.publisher == nil {
+		return
+	}
+	event := domain.AnalyticsUpdatedEvent{
+		Symbol:             snap.Symbol,
+		VWAP:               snap.VWAP.String(),
+		SessionVolume:      snap.SessionVolume.String(),
+		QuotedSpreadBps:    snap.QuotedSpreadBps,
+		EffectiveSpreadBps: snap.EffectiveSpreadBps,
+		BookImbalance:      snap.BookImbalance,
+		VPIN:               snap.VPIN,
+		Volatility:         snap.Volatility,
+		Timestamp:          snap.UpdatedAt,
+	}
+	if err := s.publisher.Publish(ctx, domain.AnalyticsUpdatedEventType, snap.Symbol, event); err != nil {
+		s.logger.WarnContext(ctx, "failed to publish analytics event", "symbol", snap.Symbol, "error", err)
+	}
+}
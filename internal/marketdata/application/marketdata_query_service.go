@@ -18,6 +18,7 @@ type MarketDataQueryService struct {
 	orderBookReadRepo domain.OrderBookReadRepository
 	searchRepo        domain.MarketDataSearchRepository
 	history           *HistoryService
+	analytics         *MarketAnalyticsService
 }
 
 // NewMarketDataQueryService 构造函数。
@@ -41,6 +42,11 @@ func NewMarketDataQueryService(
 	}
 }
 
+// SetAnalytics 注入衍生行情分析服务
+func (s *MarketDataQueryService) SetAnalytics(analytics *MarketAnalyticsService) {
+	s.analytics = analytics
+}
+
 // GetLatestQuote 获取最新报价
 func (s *MarketDataQueryService) GetLatestQuote(ctx context.Context, symbol string) (*QuoteDTO, error) {
 	if s.quoteReadRepo != nil {
@@ -106,15 +112,38 @@ func (s *MarketDataQueryService) GetTrades(ctx context.Context, symbol string, l
 	return toTradeDTOs(trades), nil
 }
 
-// GetVolatility 计算波动率
+// GetVolatility 计算年化波动率。
+// 优先使用流式分析的 24h 已实现波动率，其次基于 1h K 线收盘价估算，最后退化为历史分布估算。
 func (s *MarketDataQueryService) GetVolatility(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	if s.analytics != nil {
+		if vol, ok := s.analytics.RealizedVolatility(ctx, symbol, 24*time.Hour); ok {
+			return vol, nil
+		}
+	}
+
 	const interval = "1h"
 	const periods = 24
 	klines, err := s.repo.GetKlines(ctx, symbol, interval, periods)
 	if err != nil || len(klines) < 2 {
 		return s.estimateVolatilityFromHistory(ctx, symbol), nil
 	}
-	return decimal.NewFromFloat(0.25), nil
+	closes := make([]decimal.Decimal, 0, len(klines))
+	for _, k := range klines {
+		closes = append(closes, k.Close)
+	}
+	vol, ok := domain.RealizedVolatilityFromCloses(closes, 365*24)
+	if !ok {
+		return s.estimateVolatilityFromHistory(ctx, symbol), nil
+	}
+	return decimal.NewFromFloat(vol), nil
+}
+
+// GetMarketAnalytics 获取衍生行情分析快照
+func (s *MarketDataQueryService) GetMarketAnalytics(ctx context.Context, symbol string) (*MarketAnalyticsDTO, error) {
+	if s.analytics == nil {
+		return nil, nil
+	}
+	return s.analytics.GetAnalytics(ctx, symbol)
 }
 
 func (s *MarketDataQueryService) GetOrderBook(ctx context.Context, symbol string) (*OrderBookDTO, error) {
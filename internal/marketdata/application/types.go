@@ -148,3 +148,66 @@ func toOrderBookDTO(ob *domain.OrderBook) *OrderBookDTO {
 		Timestamp: ob.Timestamp.UnixMilli(),
 	}
 }
+
+// VolatilityWindowDTO 已实现波动率窗口 DTO
+type VolatilityWindowDTO struct {
+	Window  string  `json:"window"`
+	Current float64 `json:"current"`
+	Min     float64 `json:"min"`
+	P10     float64 `json:"p10"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	Max     float64 `json:"max"`
+	Samples int     `json:"samples"`
+}
+
+// MarketAnalyticsDTO 衍生行情分析 DTO
+type MarketAnalyticsDTO struct {
+	Symbol             string                `json:"symbol"`
+	SessionDate        string                `json:"session_date"`
+	VWAP               string                `json:"vwap"`
+	SessionVolume      string                `json:"session_volume"`
+	LastPrice          string                `json:"last_price"`
+	MidPrice           string                `json:"mid_price"`
+	QuotedSpreadBps    float64               `json:"quoted_spread_bps"`
+	EffectiveSpreadBps float64               `json:"effective_spread_bps"`
+	LastEffSpreadBps   float64               `json:"last_eff_spread_bps"`
+	BookImbalance      float64               `json:"book_imbalance"`
+	VPIN               float64               `json:"vpin"`
+	Volatility         []VolatilityWindowDTO `json:"volatility"`
+	Timestamp          int64                 `json:"timestamp"`
+}
+
+func toMarketAnalyticsDTO(m *domain.MarketAnalytics) *MarketAnalyticsDTO {
+	if m == nil {
+		return nil
+	}
+	vols := make([]VolatilityWindowDTO, 0, len(m.Volatility))
+	for _, w := range m.Volatility {
+		vols = append(vols, VolatilityWindowDTO{
+			Window:  w.Window.String(),
+			Current: w.Current,
+			Min:     w.Min,
+			P10:     w.P10,
+			P50:     w.P50,
+			P90:     w.P90,
+			Max:     w.Max,
+			Samples: w.Samples,
+		})
+	}
+	return &MarketAnalyticsDTO{
+		Symbol:             m.Symbol,
+		SessionDate:        m.SessionDate,
+		VWAP:               m.VWAP.String(),
+		SessionVolume:      m.SessionVolume.String(),
+		LastPrice:          m.LastPrice.String(),
+		MidPrice:           m.MidPrice.String(),
+		QuotedSpreadBps:    m.QuotedSpreadBps,
+		EffectiveSpreadBps: m.EffectiveSpreadBps,
+		LastEffSpreadBps:   m.LastEffSpreadBps,
+		BookImbalance:      m.BookImbalance,
+		VPIN:               m.VPIN,
+		Volatility:         vols,
+		Timestamp:          m.UpdatedAt.UnixMilli(),
+	}
+}
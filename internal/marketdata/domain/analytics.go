@@ -0,0 +1,416 @@
+package domain
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// secondsPerYear 年化使用的秒数（按 365 天自然日计算，适配 7x24 交易品种）
+const secondsPerYear = 365 * 24 * 3600
+
+// AnalyticsConfig 衍生行情分析参数
+type AnalyticsConfig struct {
+	VolWindows       []time.Duration // 已实现波动率滚动窗口，例如 5m/1h/24h
+	SampleInterval   time.Duration   // 波动率采样间隔，同一间隔内只保留最后成交价
+	ConeHistory      int             // 每个窗口保留的波动率观测数，用于构建波动率锥
+	VPINBucketVolume float64         // VPIN 单个成交量桶的大小
+	VPINBuckets      int             // VPIN 计算使用的桶数量
+}
+
+// DefaultAnalyticsConfig 返回默认分析参数
+func DefaultAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{
+		VolWindows:       []time.Duration{5 * time.Minute, time.Hour, 24 * time.Hour},
+		SampleInterval:   time.Second,
+		ConeHistory:      500,
+		VPINBucketVolume: 1000,
+		VPINBuckets:      50,
+	}
+}
+
+// VolatilityWindow 单个窗口的已实现波动率及其波动率锥分位
+type VolatilityWindow struct {
+	Window  time.Duration `json:"window"`
+	Current float64       `json:"current"` // 当前年化已实现波动率
+	Min     float64       `json:"min"`
+	P10     float64       `json:"p10"`
+	P50     float64       `json:"p50"`
+	P90     float64       `json:"p90"`
+	Max     float64       `json:"max"`
+	Samples int           `json:"samples"` // 当前窗口内的收益率样本数
+}
+
+// MarketAnalytics 单个标的的衍生行情分析快照（读模型）
+type MarketAnalytics struct {
+	Symbol             string             `json:"symbol"`
+	SessionDate        string             `json:"session_date"` // VWAP 所属交易日 (UTC, YYYY-MM-DD)
+	VWAP               decimal.Decimal    `json:"vwap"`
+	SessionVolume      decimal.Decimal    `json:"session_volume"`
+	LastPrice          decimal.Decimal    `json:"last_price"`
+	MidPrice           decimal.Decimal    `json:"mid_price"`
+	QuotedSpreadBps    float64            `json:"quoted_spread_bps"`    // (ask-bid)/mid
+	EffectiveSpreadBps float64            `json:"effective_spread_bps"` // 当日成交量加权的 2*|p-mid|/mid
+	LastEffSpreadBps   float64            `json:"last_eff_spread_bps"`  // 最近一笔成交的有效价差
+	BookImbalance      float64            `json:"book_imbalance"`       // (bidSz-askSz)/(bidSz+askSz)，范围 [-1, 1]
+	VPIN               float64            `json:"vpin"`                 // 成交量同步的知情交易概率
+	Volatility         []VolatilityWindow `json:"volatility"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+// RealizedVolatility 返回指定窗口的当前已实现波动率，未找到时返回 false
+func (m *MarketAnalytics) RealizedVolatility(window time.Duration) (float64, bool) {
+	for _, w := range m.Volatility {
+		if w.Window == window && w.Samples > 0 {
+			return w.Current, true
+		}
+	}
+	return 0, false
+}
+
+type priceSample struct {
+	ts    time.Time
+	price float64
+}
+
+type vpinBucket struct {
+	buy  float64
+	sell float64
+}
+
+// SymbolAnalytics 单标的流式分析计算器。
+// 非并发安全，由应用层按标的加锁串行驱动。
+type SymbolAnalytics struct {
+	symbol string
+	cfg    AnalyticsConfig
+
+	// VWAP
+	sessionDate string
+	cumPV       decimal.Decimal
+	cumVolume   decimal.Decimal
+	lastPrice   decimal.Decimal
+
+	// 波动率
+	samples   []priceSample
+	cones     map[time.Duration][]float64
+	coneMarks map[time.Duration]time.Time // 各窗口上一次计入波动率锥的时间，保证观测窗口互不重叠
+
+	// 价差与盘口
+	bid, ask       float64
+	bidSize        float64
+	askSize        float64
+	effSpreadSumPV float64
+	effSpreadVol   float64
+	lastEffSpread  float64
+
+	// VPIN
+	current    vpinBucket
+	buckets    []vpinBucket
+	lastTickPx float64
+	lastSign   float64
+
+	updatedAt time.Time
+}
+
+// NewSymbolAnalytics 创建单标的分析计算器
+func NewSymbolAnalytics(symbol string, cfg AnalyticsConfig) *SymbolAnalytics {
+	def := DefaultAnalyticsConfig()
+	if len(cfg.VolWindows) == 0 {
+		cfg.VolWindows = def.VolWindows
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = def.SampleInterval
+	}
+	if cfg.ConeHistory <= 0 {
+		cfg.ConeHistory = def.ConeHistory
+	}
+	if cfg.VPINBucketVolume <= 0 {
+		cfg.VPINBucketVolume = def.VPINBucketVolume
+	}
+	if cfg.VPINBuckets <= 0 {
+		cfg.VPINBuckets = def.VPINBuckets
+	}
+	windows := slices.Clone(cfg.VolWindows)
+	slices.Sort(windows)
+	cfg.VolWindows = windows
+
+	return &SymbolAnalytics{
+		symbol:    symbol,
+		cfg:       cfg,
+		cones:     make(map[time.Duration][]float64, len(windows)),
+		coneMarks: make(map[time.Duration]time.Time, len(windows)),
+	}
+}
+
+// OnTrade 处理一笔公开成交。side 为主动方方向（buy/sell），为空时使用 tick rule 推断。
+func (a *SymbolAnalytics) OnTrade(price, qty decimal.Decimal, side string, ts time.Time) {
+	if price.LessThanOrEqual(decimal.Zero) || qty.LessThanOrEqual(decimal.Zero) {
+		return
+	}
+	a.updatedAt = ts
+	a.lastPrice = price
+
+	// 1. 日内 VWAP，跨 UTC 日重置
+	date := ts.UTC().Format("2006-01-02")
+	if date != a.sessionDate {
+		a.sessionDate = date
+		a.cumPV = decimal.Zero
+		a.cumVolume = decimal.Zero
+		a.effSpreadSumPV = 0
+		a.effSpreadVol = 0
+	}
+	a.cumPV = a.cumPV.Add(price.Mul(qty))
+	a.cumVolume = a.cumVolume.Add(qty)
+
+	px := price.InexactFloat64()
+	q := qty.InexactFloat64()
+
+	// 2. 有效价差（相对成交时刻的中间价）
+	if mid := a.mid(); mid > 0 {
+		a.lastEffSpread = 2 * math.Abs(px-mid) / mid * 1e4
+		a.effSpreadSumPV += a.lastEffSpread * q
+		a.effSpreadVol += q
+	}
+
+	// 3. 波动率采样
+	a.recordSample(px, ts)
+
+	// 4. VPIN 成交量桶
+	a.classifyVolume(px, q, side)
+}
+
+// OnQuote 处理一次最优买卖报价更新
+func (a *SymbolAnalytics) OnQuote(bid, ask, bidSize, askSize decimal.Decimal, ts time.Time) {
+	if bid.LessThanOrEqual(decimal.Zero) || ask.LessThanOrEqual(decimal.Zero) || ask.LessThan(bid) {
+		return
+	}
+	a.bid = bid.InexactFloat64()
+	a.ask = ask.InexactFloat64()
+	a.bidSize = bidSize.InexactFloat64()
+	a.askSize = askSize.InexactFloat64()
+	a.updatedAt = ts
+}
+
+// OnOrderBook 使用订单簿最优档刷新盘口
+func (a *SymbolAnalytics) OnOrderBook(ob *OrderBook) {
+	if ob == nil || len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return
+	}
+	a.OnQuote(ob.Bids[0].Price, ob.Asks[0].Price, ob.Bids[0].Quantity, ob.Asks[0].Quantity, ob.Timestamp)
+}
+
+// Snapshot 生成当前分析快照
+func (a *SymbolAnalytics) Snapshot() *MarketAnalytics {
+	snap := &MarketAnalytics{
+		Symbol:           a.symbol,
+		SessionDate:      a.sessionDate,
+		SessionVolume:    a.cumVolume,
+		LastPrice:        a.lastPrice,
+		LastEffSpreadBps: a.lastEffSpread,
+		UpdatedAt:        a.updatedAt,
+	}
+	if a.cumVolume.IsPositive() {
+		snap.VWAP = a.cumPV.Div(a.cumVolume)
+	}
+	if mid := a.mid(); mid > 0 {
+		snap.MidPrice = decimal.NewFromFloat(mid)
+		snap.QuotedSpreadBps = (a.ask - a.bid) / mid * 1e4
+	}
+	if a.effSpreadVol > 0 {
+		snap.EffectiveSpreadBps = a.effSpreadSumPV / a.effSpreadVol
+	}
+	if total := a.bidSize + a.askSize; total > 0 {
+		snap.BookImbalance = (a.bidSize - a.askSize) / total
+	}
+	snap.VPIN = a.vpin()
+
+	snap.Volatility = make([]VolatilityWindow, 0, len(a.cfg.VolWindows))
+	for _, w := range a.cfg.VolWindows {
+		vw := VolatilityWindow{Window: w}
+		vw.Current, vw.Samples = a.realizedVol(w)
+		if cone := a.cones[w]; len(cone) > 0 {
+			sorted := slices.Clone(cone)
+			slices.Sort(sorted)
+			vw.Min = sorted[0]
+			vw.Max = sorted[len(sorted)-1]
+			vw.P10 = percentile(sorted, 0.10)
+			vw.P50 = percentile(sorted, 0.50)
+			vw.P90 = percentile(sorted, 0.90)
+		}
+		snap.Volatility = append(snap.Volatility, vw)
+	}
+	return snap
+}
+
+func (a *SymbolAnalytics) mid() float64 {
+	if a.bid <= 0 || a.ask <= 0 {
+		return 0
+	}
+	return (a.bid + a.ask) / 2
+}
+
+// recordSample 以固定间隔采样成交价，并在每个窗口走完一整段后把该段波动率计入波动率锥。
+// 相邻观测不共享收益率样本，避免滚动窗口重叠导致的自相关压窄锥的分位区间。
+func (a *SymbolAnalytics) recordSample(px float64, ts time.Time) {
+	if n := len(a.samples); n > 0 && ts.Sub(a.samples[n-1].ts) < a.cfg.SampleInterval {
+		a.samples[n-1].price = px
+		return
+	}
+	a.samples = append(a.samples, priceSample{ts: ts, price: px})
+
+	// 仅保留最长窗口内的样本
+	longest := a.cfg.VolWindows[len(a.cfg.VolWindows)-1]
+	cutoff := ts.Add(-longest)
+	idx := 0
+	for idx < len(a.samples)-1 && a.samples[idx].ts.Before(cutoff) {
+		idx++
+	}
+	if idx > 0 {
+		a.samples = slices.Delete(a.samples, 0, idx)
+	}
+
+	for _, w := range a.cfg.VolWindows {
+		mark, ok := a.coneMarks[w]
+		if !ok {
+			a.coneMarks[w] = a.samples[0].ts
+			continue
+		}
+		if ts.Sub(mark) < w {
+			continue
+		}
+		vol, n := a.realizedVol(w)
+		a.coneMarks[w] = ts
+		if n < 2 {
+			continue
+		}
+		cone := append(a.cones[w], vol)
+		if len(cone) > a.cfg.ConeHistory {
+			cone = cone[len(cone)-a.cfg.ConeHistory:]
+		}
+		a.cones[w] = cone
+	}
+}
+
+// realizedVol 计算窗口内对数收益率平方和，并按实际跨度年化
+func (a *SymbolAnalytics) realizedVol(window time.Duration) (float64, int) {
+	n := len(a.samples)
+	if n < 2 {
+		return 0, 0
+	}
+	last := a.samples[n-1].ts
+	start := n - 1
+	for start > 0 && last.Sub(a.samples[start-1].ts) <= window {
+		start--
+	}
+	if n-1-start < 1 {
+		return 0, 0
+	}
+	var sumSq float64
+	for i := start + 1; i < n; i++ {
+		r := math.Log(a.samples[i].price / a.samples[i-1].price)
+		sumSq += r * r
+	}
+	span := last.Sub(a.samples[start].ts).Seconds()
+	if span <= 0 {
+		return 0, 0
+	}
+	return math.Sqrt(sumSq * secondsPerYear / span), n - 1 - start
+}
+
+// classifyVolume 将成交量划入买/卖方向并装桶
+func (a *SymbolAnalytics) classifyVolume(px, qty float64, side string) {
+	sign := 0.0
+	switch strings.ToLower(side) {
+	case "buy", "b":
+		sign = 1
+	case "sell", "s":
+		sign = -1
+	default:
+		// tick rule：价格上升为买，下降为卖，持平沿用上一笔方向
+		switch {
+		case a.lastTickPx == 0:
+			sign = 1
+		case px > a.lastTickPx:
+			sign = 1
+		case px < a.lastTickPx:
+			sign = -1
+		default:
+			sign = a.lastSign
+		}
+	}
+	a.lastTickPx = px
+	if sign != 0 {
+		a.lastSign = sign
+	}
+
+	remaining := qty
+	for remaining > 0 {
+		room := a.cfg.VPINBucketVolume - (a.current.buy + a.current.sell)
+		fill := math.Min(room, remaining)
+		if sign >= 0 {
+			a.current.buy += fill
+		} else {
+			a.current.sell += fill
+		}
+		remaining -= fill
+		if a.current.buy+a.current.sell >= a.cfg.VPINBucketVolume-1e-9 {
+			a.buckets = append(a.buckets, a.current)
+			if len(a.buckets) > a.cfg.VPINBuckets {
+				a.buckets = a.buckets[len(a.buckets)-a.cfg.VPINBuckets:]
+			}
+			a.current = vpinBucket{}
+		}
+	}
+}
+
+func (a *SymbolAnalytics) vpin() float64 {
+	if len(a.buckets) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, b := range a.buckets {
+		sum += math.Abs(b.buy - b.sell)
+	}
+	return sum / (float64(len(a.buckets)) * a.cfg.VPINBucketVolume)
+}
+
+// RealizedVolatilityFromCloses 基于收盘价序列计算年化已实现波动率。
+// periodsPerYear 为每年的周期数，例如 1h K 线取 365*24。
+func RealizedVolatilityFromCloses(closes []decimal.Decimal, periodsPerYear float64) (float64, bool) {
+	if len(closes) < 3 || periodsPerYear <= 0 {
+		return 0, false
+	}
+	returns := make([]float64, 0, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		prev, cur := closes[i-1].InexactFloat64(), closes[i].InexactFloat64()
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(cur/prev))
+	}
+	if len(returns) < 2 {
+		return 0, false
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return math.Sqrt(variance * periodsPerYear), true
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Round(p * float64(len(sorted)-1)))
+	return sorted[idx]
+}
@@ -7,6 +7,7 @@ const (
 	KlineUpdatedEventType     = "marketdata.kline.updated"
 	TradeExecutedEventType    = "marketdata.trade.executed"
 	OrderBookUpdatedEventType = "marketdata.orderbook.updated"
+	AnalyticsUpdatedEventType = "marketdata.analytics.updated"
 )
 
 // QuoteUpdatedEvent 报价更新事件
@@ -52,3 +53,16 @@ type OrderBookUpdatedEvent struct {
 	Asks      [][2]string `json:"asks"`
 	Timestamp time.Time   `json:"timestamp"`
 }
+
+// AnalyticsUpdatedEvent 衍生行情分析更新事件，供执行算法与风控保证金计算消费
+type AnalyticsUpdatedEvent struct {
+	Symbol             string             `json:"symbol"`
+	VWAP               string             `json:"vwap"`
+	SessionVolume      string             `json:"session_volume"`
+	QuotedSpreadBps    float64            `json:"quoted_spread_bps"`
+	EffectiveSpreadBps float64            `json:"effective_spread_bps"`
+	BookImbalance      float64            `json:"book_imbalance"`
+	VPIN               float64            `json:"vpin"`
+	Volatility         []VolatilityWindow `json:"volatility"`
+	Timestamp          time.Time          `json:"timestamp"`
+}
@@ -55,6 +55,12 @@ type OrderBookReadRepository interface {
 	Get(ctx context.Context, symbol string) (*OrderBook, error)
 }
 
+// AnalyticsReadRepository 提供衍生行情分析快照缓存
+type AnalyticsReadRepository interface {
+	Save(ctx context.Context, analytics *MarketAnalytics) error
+	Get(ctx context.Context, symbol string) (*MarketAnalytics, error)
+}
+
 // MarketDataSearchRepository 提供基于 Elasticsearch 的行情搜索能力
 type MarketDataSearchRepository interface {
 	IndexQuote(ctx context.Context, quote *Quote) error
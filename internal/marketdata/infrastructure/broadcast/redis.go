@@ -0,0 +1,36 @@
+// Package broadcast 行情推送的广播实现。
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// publishTimeout 单次广播的超时时间，广播接口不带 ctx，避免 Redis 抖动阻塞行情处理
+const publishTimeout = 500 * time.Millisecond
+
+// RedisBroadcaster 基于 Redis Pub/Sub 的广播器：按主题（如 marketdata.analytics.{symbol}）发布 JSON，
+// 由网关的 WebSocket 推送层订阅后转发给客户端。
+type RedisBroadcaster struct {
+	client redis.UniversalClient
+}
+
+// NewRedisBroadcaster 创建 Redis 广播器
+func NewRedisBroadcaster(client redis.UniversalClient) *RedisBroadcaster {
+	return &RedisBroadcaster{client: client}
+}
+
+// Broadcast 把数据序列化为 JSON 后发布到主题频道
+func (b *RedisBroadcaster) Broadcast(topic string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal broadcast payload: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	return b.client.Publish(ctx, topic, payload).Err()
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/wyfcoding/financialtrading/internal/marketdata/domain"
+)
+
+type AnalyticsRedisRepository struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewAnalyticsRedisRepository 创建一个新的基于 Redis 的衍生行情分析读模型仓储。
+func NewAnalyticsRedisRepository(client redis.UniversalClient) *AnalyticsRedisRepository {
+	return &AnalyticsRedisRepository{
+		client: client,
+		prefix: "marketdata:analytics:",
+		ttl:    48 * time.Hour,
+	}
+}
+
+func (r *AnalyticsRedisRepository) Save(ctx context.Context, analytics *domain.MarketAnalytics) error {
+	if analytics == nil {
+		return nil
+	}
+	key := r.prefix + analytics.Symbol
+	data, err := json.Marshal(analytics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analytics: %w", err)
+	}
+	return r.client.Set(ctx, key, data, r.ttl).Err()
+}
+
+func (r *AnalyticsRedisRepository) Get(ctx context.Context, symbol string) (*domain.MarketAnalytics, error) {
+	key := r.prefix + symbol
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get analytics from redis: %w", err)
+	}
+
+	var analytics domain.MarketAnalytics
+	if err := json.Unmarshal(data, &analytics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal analytics: %w", err)
+	}
+	return &analytics, nil
+}
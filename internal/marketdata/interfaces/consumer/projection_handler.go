@@ -13,6 +13,7 @@ import (
 
 type MarketDataProjectionHandler struct {
 	projector *application.MarketDataProjectionService
+	analytics *application.MarketAnalyticsService
 	logger    *slog.Logger
 }
 
@@ -20,6 +21,11 @@ func NewMarketDataProjectionHandler(projector *application.MarketDataProjectionS
 	return &MarketDataProjectionHandler{projector: projector, logger: logger}
 }
 
+// SetAnalytics 注入衍生行情分析服务，投影时同步驱动流式分析
+func (h *MarketDataProjectionHandler) SetAnalytics(analytics *application.MarketAnalyticsService) {
+	h.analytics = analytics
+}
+
 func (h *MarketDataProjectionHandler) Handle(ctx context.Context, msg kafka.Message) error {
 	switch msg.Topic {
 	case domain.QuoteUpdatedEventType:
@@ -38,6 +44,9 @@ func (h *MarketDataProjectionHandler) Handle(ctx context.Context, msg kafka.Mess
 			LastSize:  mustDecimal(event.LastSize),
 			Timestamp: event.Timestamp,
 		}
+		if h.analytics != nil {
+			h.analytics.OnQuote(ctx, quote)
+		}
 		return h.projector.ProjectQuote(ctx, quote)
 	case domain.KlineUpdatedEventType:
 		var event domain.KlineUpdatedEvent
@@ -71,6 +80,9 @@ func (h *MarketDataProjectionHandler) Handle(ctx context.Context, msg kafka.Mess
 			Side:      event.Side,
 			Timestamp: event.Timestamp,
 		}
+		if h.analytics != nil {
+			h.analytics.OnTrade(ctx, trade)
+		}
 		return h.projector.ProjectTrade(ctx, trade)
 	case domain.OrderBookUpdatedEventType:
 		var event domain.OrderBookUpdatedEvent
@@ -104,6 +116,9 @@ func (h *MarketDataProjectionHandler) Handle(ctx context.Context, msg kafka.Mess
 			Asks:      asks,
 			Timestamp: event.Timestamp,
 		}
+		if h.analytics != nil {
+			h.analytics.OnOrderBook(ctx, ob)
+		}
 		return h.projector.ProjectOrderBook(ctx, ob)
 	default:
 		h.logger.WarnContext(ctx, "unknown marketdata event topic", "topic", msg.Topic)
@@ -261,6 +261,115 @@ func (h *MarketDataHandler) GetVolatility(ctx context.Context, req *pb.GetVolati
 	}, nil
 }
 
+// GetMarketAnalytics 获取衍生行情分析
+func (h *MarketDataHandler) GetMarketAnalytics(ctx context.Context, req *pb.GetMarketAnalyticsRequest) (*pb.GetMarketAnalyticsResponse, error) {
+	if req.Symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+
+	dto, err := h.query.GetMarketAnalytics(ctx, req.Symbol)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if dto == nil {
+		return &pb.GetMarketAnalyticsResponse{Analytics: &pb.MarketAnalytics{Symbol: req.Symbol}}, nil
+	}
+	return &pb.GetMarketAnalyticsResponse{Analytics: toPBMarketAnalytics(dto)}, nil
+}
+
+// SubscribeAnalytics 订阅衍生行情分析更新（流式）
+func (h *MarketDataHandler) SubscribeAnalytics(req *pb.SubscribeAnalyticsRequest, stream pb.MarketDataService_SubscribeAnalyticsServer) error {
+	symbols := make([]string, 0, len(req.Symbols))
+	seen := make(map[string]struct{}, len(req.Symbols))
+	for _, raw := range req.Symbols {
+		symbol := strings.TrimSpace(raw)
+		if symbol == "" {
+			continue
+		}
+		if _, ok := seen[symbol]; ok {
+			continue
+		}
+		seen[symbol] = struct{}{}
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) == 0 {
+		return status.Error(codes.InvalidArgument, "symbols is required")
+	}
+
+	// 仅在快照时间戳变化时推送，避免重复数据
+	lastSent := make(map[string]int64, len(symbols))
+	sendOnce := func(ctx context.Context) error {
+		for _, symbol := range symbols {
+			dto, err := h.query.GetMarketAnalytics(ctx, symbol)
+			if err != nil {
+				logging.Error(ctx, "failed to load analytics for stream", "symbol", symbol, "error", err)
+				return status.Error(codes.Internal, err.Error())
+			}
+			if dto == nil || dto.Timestamp == lastSent[symbol] {
+				continue
+			}
+			if err := stream.Send(toPBMarketAnalytics(dto)); err != nil {
+				return err
+			}
+			lastSent[symbol] = dto.Timestamp
+		}
+		return nil
+	}
+
+	ctx := stream.Context()
+	if err := sendOnce(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := sendOnce(ctx); err != nil {
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+func toPBMarketAnalytics(dto *application.MarketAnalyticsDTO) *pb.MarketAnalytics {
+	vols := make([]*pb.VolatilityWindow, 0, len(dto.Volatility))
+	for _, w := range dto.Volatility {
+		vols = append(vols, &pb.VolatilityWindow{
+			Window:  w.Window,
+			Current: w.Current,
+			Min:     w.Min,
+			P10:     w.P10,
+			P50:     w.P50,
+			P90:     w.P90,
+			Max:     w.Max,
+			Samples: int32(w.Samples),
+		})
+	}
+	return &pb.MarketAnalytics{
+		Symbol:             dto.Symbol,
+		Vwap:               parseFloat(dto.VWAP),
+		SessionVolume:      parseFloat(dto.SessionVolume),
+		LastPrice:          parseFloat(dto.LastPrice),
+		MidPrice:           parseFloat(dto.MidPrice),
+		QuotedSpreadBps:    dto.QuotedSpreadBps,
+		EffectiveSpreadBps: dto.EffectiveSpreadBps,
+		BookImbalance:      dto.BookImbalance,
+		Vpin:               dto.VPIN,
+		Volatility:         vols,
+		Timestamp:          dto.Timestamp,
+	}
+}
+
 func parseFloat(s string) float64 {
 	f, _ := strconv.ParseFloat(s, 64)
 	return f
@@ -24,6 +24,7 @@ func (h *MarketDataHandler) RegisterRoutes(r *gin.RouterGroup) {
 		v1.GET("/trades", h.GetTrades)
 		v1.GET("/orderbook", h.GetOrderBook)
 		v1.GET("/volatility", h.GetVolatility)
+		v1.GET("/analytics", h.GetMarketAnalytics)
 	}
 }
 
@@ -116,3 +117,22 @@ func (h *MarketDataHandler) GetVolatility(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"symbol": symbol, "volatility": vol.InexactFloat64()})
 }
+
+func (h *MarketDataHandler) GetMarketAnalytics(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+
+	dto, err := h.query.GetMarketAnalytics(c.Request.Context(), symbol)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if dto == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "analytics not found"})
+		return
+	}
+	c.JSON(http.StatusOK, dto)
+}
@@ -22,6 +22,7 @@ const (
 	NotificationTypeSystem     NotificationType = "SYSTEM"
 	NotificationTypePriceAlert NotificationType = "PRICE_ALERT"
 	NotificationTypeExecution  NotificationType = "EXECUTION"
+	NotificationTypeStatement  NotificationType = "STATEMENT"
 )
 
 type NotificationPriority string
@@ -0,0 +1,74 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wyfcoding/financialtrading/internal/notification/application"
+	"github.com/wyfcoding/financialtrading/internal/notification/domain"
+)
+
+// StatementReadyTopic 账户服务发布的对账单就绪事件主题
+const StatementReadyTopic = "account.statement_ready"
+
+// StatementReadyHandler 消费对账单就绪事件，通知用户对账单可供下载
+type StatementReadyHandler struct {
+	app    *application.NotificationApplicationService
+	logger *slog.Logger
+}
+
+func NewStatementReadyHandler(app *application.NotificationApplicationService, logger *slog.Logger) *StatementReadyHandler {
+	return &StatementReadyHandler{app: app, logger: logger}
+}
+
+type statementReadyPayload struct {
+	StatementID string   `json:"statement_id"`
+	UserID      string   `json:"user_id"`
+	Frequency   string   `json:"frequency"`
+	PeriodStart string   `json:"period_start"`
+	PeriodEnd   string   `json:"period_end"`
+	Formats     []string `json:"formats"`
+	Warnings    int      `json:"warnings"`
+}
+
+func (h *StatementReadyHandler) Handle(ctx context.Context, msg kafka.Message) error {
+	var payload statementReadyPayload
+	if err := json.Unmarshal(msg.Value, &payload); err != nil {
+		h.logger.ErrorContext(ctx, "failed to unmarshal statement event", "error", err)
+		return err
+	}
+	userID, err := strconv.ParseUint(payload.UserID, 10, 64)
+	if err != nil {
+		// 非数字用户 ID 无法投递，丢弃而不是阻塞消费
+		h.logger.WarnContext(ctx, "statement event with invalid user id", "user_id", payload.UserID, "statement_id", payload.StatementID)
+		return nil
+	}
+
+	period := payload.PeriodStart
+	if start, err := time.Parse(time.RFC3339, payload.PeriodStart); err == nil {
+		period = start.Format("2006-01-02")
+		if payload.Frequency == "MONTHLY" {
+			period = start.Format("2006-01")
+		}
+	}
+	_, err = h.app.Send(ctx, &application.SendNotificationCommand{
+		UserID:   userID,
+		Type:     domain.NotificationTypeStatement,
+		Priority: domain.PriorityNormal,
+		Title:    "Your account statement is ready",
+		Content:  fmt.Sprintf("Your %s statement for %s is ready to download.", payload.Frequency, period),
+		Data: map[string]any{
+			"statement_id": payload.StatementID,
+			"period_start": payload.PeriodStart,
+			"period_end":   payload.PeriodEnd,
+			"formats":      payload.Formats,
+		},
+		Channels: []domain.NotificationChannel{domain.ChannelInApp, domain.ChannelEmail},
+	})
+	return err
+}
@@ -153,6 +153,56 @@ func (c *OrderCommandService) CancelOrder(ctx context.Context, cmd CancelOrderCo
 	})
 }
 
+// massCancelPageSize 批量撤单每次读取的挂单数；massCancelMaxPages 限制单次请求的读取轮数，
+// 防止撤单失败或并发新挂单导致同一页反复出现时无限循环
+const (
+	massCancelPageSize = 100
+	massCancelMaxPages = 1000
+)
+
+// MassCancel 按用户和/或品种撤销全部可撤挂单（已校验、部分成交），单笔失败记录后继续
+func (c *OrderCommandService) MassCancel(ctx context.Context, cmd MassCancelCommand) (*MassCancelResult, error) {
+	if cmd.UserID == "" && cmd.Symbol == "" {
+		return nil, errors.New("user_id or symbol is required")
+	}
+	result := &MassCancelResult{}
+	for _, st := range []domain.OrderStatus{domain.StatusValidated, domain.StatusPartiallyFilled} {
+		// 撤单成功的订单离开该状态，偏移量只需跳过撤单失败或品种不符的订单
+		skipped := 0
+		for page := 0; ; page++ {
+			if page >= massCancelMaxPages {
+				return result, fmt.Errorf("mass cancel exceeded %d pages for status %s", massCancelMaxPages, st)
+			}
+			var orders []*domain.Order
+			var err error
+			if cmd.UserID != "" {
+				orders, _, err = c.repo.ListByUser(ctx, cmd.UserID, st, massCancelPageSize, skipped)
+			} else {
+				orders, _, err = c.repo.ListBySymbol(ctx, cmd.Symbol, st, massCancelPageSize, skipped)
+			}
+			if err != nil {
+				return result, err
+			}
+			for _, o := range orders {
+				if cmd.Symbol != "" && o.Symbol != cmd.Symbol {
+					skipped++
+					continue
+				}
+				if err := c.CancelOrder(ctx, CancelOrderCommand{OrderID: o.OrderID, Reason: cmd.Reason}); err != nil {
+					skipped++
+					result.FailedOrderIDs = append(result.FailedOrderIDs, o.OrderID)
+					continue
+				}
+				result.CancelledOrderIDs = append(result.CancelledOrderIDs, o.OrderID)
+			}
+			if len(orders) < massCancelPageSize {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
 // UpdateOrderExecution 更新订单执行状态
 func (c *OrderCommandService) UpdateOrderExecution(ctx context.Context, orderID string, filledQty, tradePrice float64) error {
 	if orderID == "" {
@@ -34,6 +34,19 @@ type CancelOrderCommand struct {
 	Reason  string
 }
 
+// MassCancelCommand 按用户和/或品种批量撤销挂单，UserID 与 Symbol 至少其一非空
+type MassCancelCommand struct {
+	UserID string
+	Symbol string
+	Reason string
+}
+
+// MassCancelResult 批量撤单结果
+type MassCancelResult struct {
+	CancelledOrderIDs []string
+	FailedOrderIDs    []string
+}
+
 // OrderDTO API/Query 输出结构
 
 type OrderDTO struct {
@@ -63,6 +63,36 @@ func (h *Handler) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (
 	return &pb.CancelOrderResponse{Success: true}, nil
 }
 
+// MassCancelOrders 按用户和/或品种批量撤销挂单，供风控熔断开关按账户、交易台或品种撤单
+func (h *Handler) MassCancelOrders(ctx context.Context, req *pb.MassCancelOrdersRequest) (*pb.MassCancelOrdersResponse, error) {
+	if req.UserId == "" && req.Symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id or symbol is required")
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "mass cancel"
+	}
+	res, err := h.cmd.MassCancel(ctx, application.MassCancelCommand{
+		UserID: req.UserId,
+		Symbol: req.Symbol,
+		Reason: reason,
+	})
+	if err != nil {
+		cancelled := 0
+		if res != nil {
+			cancelled = len(res.CancelledOrderIDs)
+		}
+		return nil, status.Errorf(codes.Aborted, "mass cancel incomplete after %d cancellations: %v", cancelled, err)
+	}
+	return &pb.MassCancelOrdersResponse{
+		CancelledCount:    int32(len(res.CancelledOrderIDs)),
+		FailedCount:       int32(len(res.FailedOrderIDs)),
+		CancelledOrderIds: res.CancelledOrderIDs,
+		FailedOrderIds:    res.FailedOrderIDs,
+		CancelledAt:       timestamppb.Now(),
+	}, nil
+}
+
 func (h *Handler) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
 	dto, err := h.query.GetOrder(ctx, req.OrderId)
 	if err != nil {
@@ -0,0 +1,557 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+// allocationPostingBatch 每次读取的待过账回分条数
+const allocationPostingBatch = 200
+
+// SaveModelCommand 新建或更新模型组合
+type SaveModelCommand struct {
+	ModelID        string
+	Name           string
+	Description    string
+	Status         domain.ModelStatus
+	CashWeight     decimal.Decimal
+	DriftThreshold decimal.Decimal
+	Components     map[string]decimal.Decimal
+}
+
+// AssignModelCommand 账户绑定模型
+type AssignModelCommand struct {
+	UserID            string
+	ModelID           string
+	RestrictedSymbols []string
+	MinCash           decimal.Decimal
+	TaxAware          bool
+	Currency          string
+}
+
+// ProposeRebalanceCommand 对模型下全部账户生成调仓建议
+type ProposeRebalanceCommand struct {
+	ModelID string
+	// OnlyBreached 为 true 时只为偏离度超过阈值的账户生成建议
+	OnlyBreached bool
+}
+
+// SkippedAccount 未生成建议的账户及原因
+type SkippedAccount struct {
+	UserID string
+	Reason string
+}
+
+// RebalanceBatch 一次批量调仓的建议集合
+type RebalanceBatch struct {
+	BatchID   string
+	ModelID   string
+	Proposals []*domain.RebalanceProposal
+	Skipped   []SkippedAccount
+}
+
+// SubmitBlockOrdersCommand 把批次内已审批建议汇总为大宗单下发；UserID 为下单的综合账户
+type SubmitBlockOrdersCommand struct {
+	BatchID         string
+	UserID          string
+	AlgoType        string
+	ExecutionWindow time.Duration
+}
+
+// RecordBlockFillCommand 大宗单成交回报
+type RecordBlockFillCommand struct {
+	FillID   string
+	BlockID  string
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+}
+
+// SetModelPortfolioRepository 注入模型组合仓储，启用模型组合与批量调仓
+func (s *PortfolioAppService) SetModelPortfolioRepository(repo domain.ModelPortfolioRepository) {
+	s.models = repo
+}
+
+// SetAccountStateSource 注入账户现金与计税批次数据源
+func (s *PortfolioAppService) SetAccountStateSource(source domain.AccountStateSource) {
+	s.accountState = source
+}
+
+// SetAllocationPoster 启用大宗单回分过账到持仓与账户服务；未注入时回分只记录在组合服务内
+func (s *PortfolioAppService) SetAllocationPoster(poster domain.AllocationPoster) {
+	s.allocations = poster
+}
+
+// SaveModel 新建或更新模型组合，成分整体替换
+func (s *PortfolioAppService) SaveModel(ctx context.Context, cmd SaveModelCommand) (*domain.ModelPortfolio, error) {
+	if s.models == nil {
+		return nil, errors.New("model portfolio repository not configured")
+	}
+	model := &domain.ModelPortfolio{
+		ModelID:        cmd.ModelID,
+		Name:           cmd.Name,
+		Description:    cmd.Description,
+		Status:         cmd.Status,
+		CashWeight:     cmd.CashWeight,
+		DriftThreshold: cmd.DriftThreshold,
+	}
+	if model.Status == "" {
+		model.Status = domain.ModelActive
+	}
+	symbols := make([]string, 0, len(cmd.Components))
+	for symbol := range cmd.Components {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		model.Components = append(model.Components, domain.ModelComponent{ModelID: cmd.ModelID, Symbol: symbol, TargetWeight: cmd.Components[symbol]})
+	}
+	if err := model.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.models.SaveModel(ctx, model); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "model portfolio saved", "model_id", model.ModelID, "components", len(model.Components))
+	return model, nil
+}
+
+// AssignModel 绑定账户到启用中的模型，已绑定时覆盖
+func (s *PortfolioAppService) AssignModel(ctx context.Context, cmd AssignModelCommand) error {
+	if s.models == nil {
+		return errors.New("model portfolio repository not configured")
+	}
+	if cmd.UserID == "" || cmd.Currency == "" {
+		return errors.New("user_id and currency are required")
+	}
+	if cmd.MinCash.IsNegative() {
+		return errors.New("min_cash must not be negative")
+	}
+	model, err := s.activeModel(ctx, cmd.ModelID)
+	if err != nil {
+		return err
+	}
+	return s.models.SaveAssignment(ctx, &domain.ModelAssignment{
+		UserID:            cmd.UserID,
+		ModelID:           model.ModelID,
+		RestrictedSymbols: strings.Join(cmd.RestrictedSymbols, ","),
+		MinCash:           cmd.MinCash,
+		TaxAware:          cmd.TaxAware,
+		Currency:          strings.ToUpper(cmd.Currency),
+	})
+}
+
+// GetModelDrift 计算模型下各账户的偏离度；单个账户取数失败时记录日志并跳过
+func (s *PortfolioAppService) GetModelDrift(ctx context.Context, modelID string) ([]*domain.DriftReport, error) {
+	if err := s.requireModelSources(); err != nil {
+		return nil, err
+	}
+	model, err := s.activeModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.models.ListAssignments(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]decimal.Decimal)
+	reports := make([]*domain.DriftReport, 0, len(assignments))
+	for _, a := range assignments {
+		state, err := s.loadAccountState(ctx, model, a, prices, false)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to load account state for drift", "user_id", a.UserID, "model_id", modelID, "error", err)
+			continue
+		}
+		reports = append(reports, domain.MeasureDrift(model, state))
+	}
+	return reports, nil
+}
+
+// ProposeRebalance 为模型下的账户批量生成待审批的调仓建议
+func (s *PortfolioAppService) ProposeRebalance(ctx context.Context, cmd ProposeRebalanceCommand) (*RebalanceBatch, error) {
+	if err := s.requireModelSources(); err != nil {
+		return nil, err
+	}
+	model, err := s.activeModel(ctx, cmd.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.models.ListAssignments(ctx, cmd.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &RebalanceBatch{BatchID: fmt.Sprintf("RB-%d", idgen.GenID()), ModelID: model.ModelID}
+	prices := make(map[string]decimal.Decimal)
+	lotSizes := make(map[string]decimal.Decimal)
+	for _, a := range assignments {
+		state, err := s.loadAccountState(ctx, model, a, prices, true)
+		if err != nil {
+			batch.Skipped = append(batch.Skipped, SkippedAccount{UserID: a.UserID, Reason: err.Error()})
+			continue
+		}
+		if cmd.OnlyBreached && !domain.MeasureDrift(model, state).Breached {
+			batch.Skipped = append(batch.Skipped, SkippedAccount{UserID: a.UserID, Reason: "drift within threshold"})
+			continue
+		}
+		for symbol := range state.Prices {
+			if _, ok := lotSizes[symbol]; !ok {
+				if lotSizes[symbol], err = s.marketData.LotSize(ctx, symbol); err != nil {
+					s.logger.WarnContext(ctx, "failed to get lot size, quantities will not be rounded", "symbol", symbol, "error", err)
+				}
+			}
+		}
+		proposal, err := domain.BuildRebalanceProposal(domain.ProposalInput{
+			ProposalID: fmt.Sprintf("RP-%d", idgen.GenID()),
+			BatchID:    batch.BatchID,
+			Model:      model,
+			Assignment: a,
+			State:      state,
+			LotSizes:   lotSizes,
+		})
+		if err != nil {
+			batch.Skipped = append(batch.Skipped, SkippedAccount{UserID: a.UserID, Reason: err.Error()})
+			continue
+		}
+		if proposal == nil {
+			batch.Skipped = append(batch.Skipped, SkippedAccount{UserID: a.UserID, Reason: "no tradable rebalance"})
+			continue
+		}
+		batch.Proposals = append(batch.Proposals, proposal)
+	}
+	if err := s.models.SaveProposals(ctx, batch.Proposals); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "rebalance proposals generated", "batch_id", batch.BatchID, "model_id", model.ModelID,
+		"proposals", len(batch.Proposals), "skipped", len(batch.Skipped))
+	return batch, nil
+}
+
+// ReviewProposal 审批通过或驳回调仓建议
+func (s *PortfolioAppService) ReviewProposal(ctx context.Context, proposalID string, approve bool, reviewer string) (*domain.RebalanceProposal, error) {
+	if s.models == nil {
+		return nil, errors.New("model portfolio repository not configured")
+	}
+	if reviewer == "" {
+		return nil, errors.New("reviewer is required")
+	}
+	var proposal *domain.RebalanceProposal
+	err := s.models.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if proposal, err = s.models.GetProposal(ctx, proposalID); err != nil {
+			return err
+		}
+		if proposal == nil {
+			return fmt.Errorf("proposal %s not found", proposalID)
+		}
+		if err := proposal.Review(approve, reviewer); err != nil {
+			return err
+		}
+		return s.models.UpdateProposal(ctx, proposal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "rebalance proposal reviewed", "proposal_id", proposalID, "status", proposal.Status, "reviewer", reviewer)
+	return proposal, nil
+}
+
+// SubmitBlockOrders 把批次内已审批建议按 标的+方向 汇总为大宗单并下发为算法单
+func (s *PortfolioAppService) SubmitBlockOrders(ctx context.Context, cmd SubmitBlockOrdersCommand) ([]*domain.BlockOrder, error) {
+	if s.models == nil || s.algoOrders == nil {
+		return nil, errors.New("block order submission not configured")
+	}
+	if cmd.BatchID == "" || cmd.UserID == "" {
+		return nil, errors.New("batch_id and user_id are required")
+	}
+
+	var blocks []*domain.BlockOrder
+	err := s.models.WithTx(ctx, func(ctx context.Context) error {
+		proposals, err := s.models.ListProposals(ctx, cmd.BatchID, domain.ProposalApproved)
+		if err != nil {
+			return err
+		}
+		if len(proposals) == 0 {
+			return fmt.Errorf("batch %s has no approved proposals", cmd.BatchID)
+		}
+		lotSizes := make(map[string]decimal.Decimal)
+		if s.marketData != nil {
+			for _, p := range proposals {
+				for _, l := range p.Lines {
+					if _, ok := lotSizes[l.Symbol]; !ok {
+						lotSizes[l.Symbol], _ = s.marketData.LotSize(ctx, l.Symbol)
+					}
+				}
+			}
+		}
+		blocks = domain.AggregateBlockOrders(cmd.BatchID, proposals, lotSizes, func() string {
+			return fmt.Sprintf("BO-%d", idgen.GenID())
+		})
+		if err := s.models.SaveBlockOrders(ctx, blocks); err != nil {
+			return err
+		}
+		for _, p := range proposals {
+			if err := s.models.UpdateProposal(ctx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	algoType := strings.ToUpper(cmd.AlgoType)
+	if algoType == "" {
+		algoType = defaultAlgoType
+	}
+	window := cmd.ExecutionWindow
+	if window <= 0 {
+		window = defaultExecutionWindow
+	}
+	now := time.Now()
+	for _, b := range blocks {
+		id, err := s.algoOrders.SubmitAlgoOrder(ctx, domain.AlgoOrder{
+			UserID:   cmd.UserID,
+			Symbol:   b.Symbol,
+			Side:     b.Side,
+			Quantity: b.Quantity,
+			AlgoType: algoType,
+			Start:    now,
+			End:      now.Add(window),
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to submit block order", "block_id", b.BlockID, "symbol", b.Symbol, "error", err)
+			b.Status = domain.BlockFailed
+		} else {
+			b.AlgoOrderID = id
+		}
+		if err := s.models.UpdateBlockOrder(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+	s.logger.InfoContext(ctx, "block orders submitted", "batch_id", cmd.BatchID, "blocks", len(blocks))
+	return blocks, nil
+}
+
+// RecordBlockFill 记入大宗单成交，只把本笔成交数量按比例回分到各账户，并在同一事务内生成各账户的过账，
+// 提交后过账到持仓与账户服务；同一 FillID 只处理一次
+func (s *PortfolioAppService) RecordBlockFill(ctx context.Context, cmd RecordBlockFillCommand) (*domain.BlockOrder, bool, error) {
+	if s.models == nil {
+		return nil, false, errors.New("model portfolio repository not configured")
+	}
+	if cmd.FillID == "" || cmd.BlockID == "" {
+		return nil, false, errors.New("fill_id and block_id are required")
+	}
+	var block *domain.BlockOrder
+	applied := false
+	err := s.models.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if block, err = s.models.GetBlockOrder(ctx, cmd.BlockID); err != nil {
+			return err
+		}
+		if block == nil {
+			return fmt.Errorf("block order %s not found", cmd.BlockID)
+		}
+		if applied, err = s.models.RecordBlockFill(ctx, &domain.BlockFill{
+			FillID:   cmd.FillID,
+			BlockID:  cmd.BlockID,
+			Quantity: cmd.Quantity,
+			Price:    cmd.Price,
+		}); err != nil || !applied {
+			return err
+		}
+		increments, err := block.ApplyFill(cmd.Quantity, cmd.Price)
+		if err != nil {
+			return err
+		}
+		if err := s.models.UpdateBlockOrder(ctx, block); err != nil {
+			return err
+		}
+		currencies, err := s.allocationCurrencies(ctx, block, increments)
+		if err != nil {
+			return err
+		}
+		if err := s.models.SaveAllocationBookings(ctx,
+			domain.NewAllocationBookings(block, cmd.FillID, cmd.Price, increments, currencies)); err != nil {
+			return err
+		}
+		touched := make(map[string]bool)
+		for _, a := range block.Allocations {
+			if err := s.models.UpdateLineFill(ctx, a.LineID, a.FilledQuantity, a.Price); err != nil {
+				return err
+			}
+			touched[a.ProposalID] = true
+		}
+		if block.Status != domain.BlockFilled {
+			return nil
+		}
+		for proposalID := range touched {
+			proposal, err := s.models.GetProposal(ctx, proposalID)
+			if err != nil || proposal == nil {
+				return err
+			}
+			proposal.RefreshFillStatus()
+			if proposal.Status == domain.ProposalFilled {
+				if err := s.models.UpdateProposal(ctx, proposal); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if applied {
+		s.logger.InfoContext(ctx, "block fill allocated", "block_id", block.BlockID, "fill_id", cmd.FillID,
+			"filled", block.FilledQuantity.String(), "avg_price", block.AvgPrice.String())
+		s.postAllocationBookings(ctx, block.BlockID)
+	}
+	return block, applied, nil
+}
+
+// allocationCurrencies 本次获得分配的账户的结算币种，取自账户的模型绑定
+func (s *PortfolioAppService) allocationCurrencies(ctx context.Context, block *domain.BlockOrder, increments []decimal.Decimal) (map[string]string, error) {
+	currencies := make(map[string]string)
+	for i, inc := range increments {
+		userID := block.Allocations[i].UserID
+		if _, ok := currencies[userID]; ok || !inc.IsPositive() {
+			continue
+		}
+		assignment, err := s.models.GetAssignment(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if assignment == nil {
+			return nil, fmt.Errorf("no model assignment for %s", userID)
+		}
+		currencies[userID] = assignment.Currency
+	}
+	return currencies, nil
+}
+
+// postAllocationBookings 按创建顺序过账待处理的回分，遇到失败即停止，保持 PENDING 留待重试。
+// blockID 为空时处理所有大宗单
+func (s *PortfolioAppService) postAllocationBookings(ctx context.Context, blockID string) {
+	if s.allocations == nil {
+		return
+	}
+	for {
+		bookings, err := s.models.ListPendingAllocationBookings(ctx, blockID, allocationPostingBatch)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to list pending allocation bookings", "block_id", blockID, "error", err)
+			return
+		}
+		for _, b := range bookings {
+			if err := s.allocations.Post(ctx, b); err != nil {
+				s.logger.WarnContext(ctx, "allocation booking failed, will retry", "booking_id", b.BookingID, "user_id", b.UserID, "error", err)
+				return
+			}
+			if err := s.models.MarkAllocationBookingPosted(ctx, b.BookingID); err != nil {
+				s.logger.ErrorContext(ctx, "failed to mark allocation booking posted", "booking_id", b.BookingID, "error", err)
+				return
+			}
+		}
+		if len(bookings) < allocationPostingBatch {
+			return
+		}
+	}
+}
+
+// StartAllocationPosting 周期性重试未过账的回分
+func (s *PortfolioAppService) StartAllocationPosting(ctx context.Context, interval time.Duration) {
+	if s.models == nil || s.allocations == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.postAllocationBookings(ctx, "")
+		}
+	}
+}
+
+// ListBlockOrders 列出批次内的大宗单及分配
+func (s *PortfolioAppService) ListBlockOrders(ctx context.Context, batchID string) ([]*domain.BlockOrder, error) {
+	if s.models == nil {
+		return nil, errors.New("model portfolio repository not configured")
+	}
+	return s.models.ListBlockOrders(ctx, batchID)
+}
+
+func (s *PortfolioAppService) requireModelSources() error {
+	if s.models == nil || s.holdings == nil || s.marketData == nil || s.accountState == nil {
+		return errors.New("model portfolio rebalancing not configured")
+	}
+	return nil
+}
+
+func (s *PortfolioAppService) activeModel(ctx context.Context, modelID string) (*domain.ModelPortfolio, error) {
+	model, err := s.models.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil {
+		return nil, fmt.Errorf("model %s not found", modelID)
+	}
+	if model.Status != domain.ModelActive {
+		return nil, fmt.Errorf("model %s is %s", modelID, model.Status)
+	}
+	return model, nil
+}
+
+// loadAccountState 读取账户现金、持仓与价格，withLots 时同时读取持仓标的的计税批次；prices 为批次内共享的价格缓存
+func (s *PortfolioAppService) loadAccountState(ctx context.Context, model *domain.ModelPortfolio, a *domain.ModelAssignment, prices map[string]decimal.Decimal, withLots bool) (*domain.AccountState, error) {
+	cash, err := s.accountState.Cash(ctx, a.UserID, a.Currency)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := s.holdings.Holdings(ctx, a.UserID)
+	if err != nil {
+		return nil, err
+	}
+	state := &domain.AccountState{
+		UserID:   a.UserID,
+		Cash:     cash,
+		Holdings: holdings,
+		Prices:   make(map[string]decimal.Decimal),
+		Lots:     make(map[string][]domain.TaxLot),
+	}
+	symbols := make([]string, 0, len(holdings)+len(model.Components))
+	for _, c := range model.Components {
+		symbols = append(symbols, c.Symbol)
+	}
+	for symbol := range holdings {
+		symbols = append(symbols, symbol)
+	}
+	for _, symbol := range symbols {
+		price, ok := prices[symbol]
+		if !ok {
+			if price, err = s.marketData.LatestPrice(ctx, symbol); err != nil {
+				return nil, err
+			}
+			prices[symbol] = price
+		}
+		state.Prices[symbol] = price
+	}
+	if withLots {
+		for symbol := range holdings {
+			if state.Lots[symbol], err = s.accountState.TaxLots(ctx, a.UserID, symbol); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return state, nil
+}
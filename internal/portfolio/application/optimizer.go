@@ -0,0 +1,215 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+)
+
+const (
+	defaultLookbackDays    = 252
+	defaultAlgoType        = "TWAP"
+	defaultExecutionWindow = time.Hour
+)
+
+// OptimizePortfolioCommand 组合优化与调仓请求；Assets 为空时以当前持仓为优化范围
+type OptimizePortfolioCommand struct {
+	UserID      string
+	Method      domain.OptimizationMethod
+	Assets      []domain.OptimizationAsset
+	Views       []domain.View
+	Constraints domain.OptimizationConstraints
+	// LookbackDays 估计协方差使用的交易日数，0 时取 252
+	LookbackDays int
+	// PortfolioValue 参与优化的资金规模，为 0 时取优化范围内持仓市值
+	PortfolioValue decimal.Decimal
+	// Submit 为 true 时把交易清单作为算法单下发执行服务
+	Submit          bool
+	AlgoType        string
+	ExecutionWindow time.Duration
+}
+
+// OptimizationPlan 优化结果与调仓交易清单
+type OptimizationPlan struct {
+	*domain.OptimizationResult
+	PortfolioValue decimal.Decimal
+	CurrentWeights map[string]float64
+	Trades         []domain.ProposedTrade
+	Submitted      bool
+}
+
+// SetAssetReturnRepository 注入标的日收益仓储，用于协方差估计
+func (s *PortfolioAppService) SetAssetReturnRepository(repo domain.AssetReturnRepository) {
+	s.assetReturns = repo
+}
+
+// SetOptimizerSources 注入持仓与行情数据源，启用组合优化
+func (s *PortfolioAppService) SetOptimizerSources(holdings domain.HoldingsSource, marketData domain.OptimizerMarketData) {
+	s.holdings = holdings
+	s.marketData = marketData
+}
+
+// SetAlgoOrderSubmitter 注入算法单下发，启用调仓交易提交
+func (s *PortfolioAppService) SetAlgoOrderSubmitter(submitter domain.AlgoOrderSubmitter) {
+	s.algoOrders = submitter
+}
+
+// OptimizePortfolio 求解目标权重并生成按最小交易单位取整的调仓交易，按需下发为算法单
+func (s *PortfolioAppService) OptimizePortfolio(ctx context.Context, cmd OptimizePortfolioCommand) (*OptimizationPlan, error) {
+	if s.assetReturns == nil || s.holdings == nil || s.marketData == nil {
+		return nil, errors.New("portfolio optimizer not configured")
+	}
+	if cmd.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	if cmd.Submit && s.algoOrders == nil {
+		return nil, errors.New("algo order submitter not configured")
+	}
+	if cmd.LookbackDays <= 0 {
+		cmd.LookbackDays = defaultLookbackDays
+	}
+
+	holdings, err := s.holdings.Holdings(ctx, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	assets := append([]domain.OptimizationAsset(nil), cmd.Assets...)
+	if len(assets) == 0 {
+		for symbol := range holdings {
+			assets = append(assets, domain.OptimizationAsset{Symbol: symbol})
+		}
+		if len(assets) == 0 {
+			return nil, errors.New("no holdings to optimize")
+		}
+	}
+
+	symbols := make([]string, len(assets))
+	prices := make(map[string]decimal.Decimal, len(assets))
+	lotSizes := make(map[string]decimal.Decimal, len(assets))
+	heldValue := decimal.Zero
+	for i, a := range assets {
+		symbols[i] = a.Symbol
+		if prices[a.Symbol], err = s.marketData.LatestPrice(ctx, a.Symbol); err != nil {
+			return nil, err
+		}
+		if lotSizes[a.Symbol], err = s.marketData.LotSize(ctx, a.Symbol); err != nil {
+			s.logger.WarnContext(ctx, "failed to get lot size, trades will not be rounded", "symbol", a.Symbol, "error", err)
+		}
+		heldValue = heldValue.Add(holdings[a.Symbol].Mul(prices[a.Symbol]))
+	}
+	value := cmd.PortfolioValue
+	if !value.IsPositive() {
+		value = heldValue
+	}
+	if !value.IsPositive() {
+		return nil, errors.New("portfolio value must be positive")
+	}
+	currentWeights := make(map[string]float64, len(assets))
+	for i := range assets {
+		weight, _ := holdings[assets[i].Symbol].Mul(prices[assets[i].Symbol]).Div(value).Float64()
+		assets[i].CurrentWeight = weight
+		currentWeights[assets[i].Symbol] = weight
+	}
+
+	returns, err := s.loadReturns(ctx, symbols, cmd.LookbackDays)
+	if err != nil {
+		return nil, err
+	}
+	result, err := domain.Optimize(domain.OptimizationInput{
+		Method:      cmd.Method,
+		Assets:      assets,
+		Returns:     returns,
+		Views:       cmd.Views,
+		Constraints: cmd.Constraints,
+	})
+	if err != nil {
+		return nil, err
+	}
+	trades, err := domain.BuildTradeList(domain.TradeListInput{
+		PortfolioValue: value,
+		Weights:        result.Weights,
+		Holdings:       holdings,
+		Prices:         prices,
+		LotSizes:       lotSizes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OptimizationPlan{OptimizationResult: result, PortfolioValue: value, CurrentWeights: currentWeights, Trades: trades}
+	if cmd.Submit {
+		s.submitTrades(ctx, cmd, plan)
+	}
+	s.logger.InfoContext(ctx, "portfolio optimized", "user_id", cmd.UserID, "method", cmd.Method,
+		"trades", len(trades), "turnover", result.Turnover, "shrinkage", result.Shrinkage, "submitted", plan.Submitted)
+	return plan, nil
+}
+
+// loadReturns 读取回看窗口内的日收益，存量不足一半的标的先经行情日线补齐
+func (s *PortfolioAppService) loadReturns(ctx context.Context, symbols []string, lookback int) ([]domain.AssetReturn, error) {
+	end := time.Now().UTC()
+	// 交易日换算为自然日并留出节假日余量
+	start := end.AddDate(0, 0, -(lookback*7/5 + 10))
+	returns, err := s.assetReturns.ListReturns(ctx, symbols, start, end)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(symbols))
+	for _, r := range returns {
+		counts[r.Symbol]++
+	}
+	backfilled := false
+	for _, symbol := range symbols {
+		if counts[symbol] >= lookback/2 {
+			continue
+		}
+		closes, err := s.marketData.DailyCloses(ctx, symbol, lookback+1)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.assetReturns.SaveReturns(ctx, domain.ReturnsFromCloses(symbol, closes)); err != nil {
+			return nil, err
+		}
+		backfilled = true
+	}
+	if !backfilled {
+		return returns, nil
+	}
+	return s.assetReturns.ListReturns(ctx, symbols, start, end)
+}
+
+// submitTrades 逐笔下发算法单，单笔失败记录在交易上而不中断其余交易
+func (s *PortfolioAppService) submitTrades(ctx context.Context, cmd OptimizePortfolioCommand, plan *OptimizationPlan) {
+	algoType := strings.ToUpper(cmd.AlgoType)
+	if algoType == "" {
+		algoType = defaultAlgoType
+	}
+	window := cmd.ExecutionWindow
+	if window <= 0 {
+		window = defaultExecutionWindow
+	}
+	now := time.Now()
+	for i := range plan.Trades {
+		t := &plan.Trades[i]
+		id, err := s.algoOrders.SubmitAlgoOrder(ctx, domain.AlgoOrder{
+			UserID:   cmd.UserID,
+			Symbol:   t.Symbol,
+			Side:     t.Side,
+			Quantity: t.Quantity,
+			AlgoType: algoType,
+			Start:    now,
+			End:      now.Add(window),
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to submit rebalance algo order", "user_id", cmd.UserID, "symbol", t.Symbol, "error", err)
+			t.SubmitError = err.Error()
+			continue
+		}
+		t.AlgoOrderID = id
+		plan.Submitted = true
+	}
+}
@@ -0,0 +1,193 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+)
+
+// RecordCashFlowCommand 记录外部现金流
+type RecordCashFlowCommand struct {
+	Reference string
+	UserID    string
+	FlowDate  time.Time
+	Amount    decimal.Decimal
+	Currency  string
+}
+
+// PerformanceAnalyticsQuery 业绩分析查询；BenchmarkSymbol 为空时只计算绝对收益
+type PerformanceAnalyticsQuery struct {
+	UserID          string
+	BenchmarkSymbol string
+	Start           time.Time
+	End             time.Time
+	Period          domain.PeriodGranularity
+}
+
+// PeriodComparison 单个复合区间的组合与基准收益
+type PeriodComparison struct {
+	Period          string
+	Start           time.Time
+	End             time.Time
+	PortfolioReturn float64
+	BenchmarkReturn float64
+}
+
+// PerformanceAnalytics 区间业绩分析结果
+type PerformanceAnalytics struct {
+	TimeWeightedReturn            float64
+	AnnualizedTimeWeightedReturn  float64
+	MoneyWeightedReturn           float64
+	AnnualizedMoneyWeightedReturn float64
+	MaxDrawdown                   float64
+
+	BenchmarkSymbol      string
+	BenchmarkName        string
+	BenchmarkReturn      float64
+	BenchmarkMaxDrawdown float64
+	domain.RelativeAnalytics
+
+	Returns          domain.ReturnSeries
+	BenchmarkReturns domain.ReturnSeries
+	Periods          []PeriodComparison
+}
+
+// ActiveReturn 组合相对基准的区间超额收益
+func (a *PerformanceAnalytics) ActiveReturn() float64 {
+	return a.TimeWeightedReturn - a.BenchmarkReturn
+}
+
+// SetCashFlowRepository 注入外部现金流仓储，启用按现金流调整的收益计算
+func (s *PortfolioAppService) SetCashFlowRepository(repo domain.CashFlowRepository) {
+	s.cashFlows = repo
+}
+
+// SetBenchmarkSource 注入基准指数数据源，启用相对基准分析
+func (s *PortfolioAppService) SetBenchmarkSource(source domain.BenchmarkSource) {
+	s.benchmarks = source
+}
+
+// RecordCashFlow 记录入金或出金，同一 Reference 只记录一次
+func (s *PortfolioAppService) RecordCashFlow(ctx context.Context, cmd RecordCashFlowCommand) (bool, error) {
+	if s.cashFlows == nil {
+		return false, errors.New("cash flow repository not configured")
+	}
+	if cmd.Reference == "" || cmd.UserID == "" {
+		return false, errors.New("reference and user_id are required")
+	}
+	if cmd.Amount.IsZero() {
+		return false, errors.New("amount must not be zero")
+	}
+	if cmd.FlowDate.IsZero() {
+		return false, errors.New("flow_date is required")
+	}
+	return s.cashFlows.SaveCashFlow(ctx, &domain.CashFlow{
+		Reference: cmd.Reference,
+		UserID:    cmd.UserID,
+		FlowDate:  cmd.FlowDate,
+		Amount:    cmd.Amount,
+		Currency:  strings.ToUpper(cmd.Currency),
+	})
+}
+
+// GetPerformanceAnalytics 计算区间内的 TWR、MWR，并在指定基准时计算跟踪误差、信息比率、Beta 与 Alpha
+func (s *PortfolioAppService) GetPerformanceAnalytics(ctx context.Context, q PerformanceAnalyticsQuery) (*PerformanceAnalytics, error) {
+	if q.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	if q.End.IsZero() {
+		q.End = time.Now().UTC()
+	}
+	if q.Period == "" {
+		q.Period = domain.PeriodMonth
+	}
+	if !q.End.After(q.Start) {
+		return nil, errors.New("end date must be after start date")
+	}
+
+	snapshots, err := s.portfolioRepo.GetSnapshots(ctx, q.UserID, q.Start, q.End)
+	if err != nil {
+		return nil, err
+	}
+	dates := domain.ValuationDates(snapshots)
+	if len(dates) < 2 {
+		return nil, domain.ErrInsufficientHistory
+	}
+	var flows []domain.CashFlow
+	if s.cashFlows != nil {
+		if flows, err = s.cashFlows.ListCashFlows(ctx, q.UserID, dates[0], dates[len(dates)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	returns := domain.TimeWeightedReturns(snapshots, flows)
+	result := &PerformanceAnalytics{
+		TimeWeightedReturn: returns.Link(time.Time{}, time.Time{}),
+		MaxDrawdown:        returns.MaxDrawdown(),
+		Returns:            returns,
+	}
+	result.AnnualizedTimeWeightedReturn = returns.Annualize(result.TimeWeightedReturn)
+	if annualized, period, ok := domain.MoneyWeightedReturn(snapshots, flows); ok {
+		result.AnnualizedMoneyWeightedReturn, result.MoneyWeightedReturn = annualized, period
+	} else {
+		s.logger.WarnContext(ctx, "money-weighted return has no solution", "user_id", q.UserID)
+	}
+
+	if q.BenchmarkSymbol != "" {
+		if err := s.compareToBenchmark(ctx, q.BenchmarkSymbol, dates, result); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range returns.LinkByPeriod(q.Period) {
+		growth := 1.0
+		for _, b := range result.BenchmarkReturns {
+			if !b.Date.Before(p.Start) && !b.Date.After(p.End) {
+				growth *= 1 + b.Return
+			}
+		}
+		result.Periods = append(result.Periods, PeriodComparison{
+			Period:          p.Period,
+			Start:           p.Start,
+			End:             p.End,
+			PortfolioReturn: p.Return,
+			BenchmarkReturn: growth - 1,
+		})
+	}
+	return result, nil
+}
+
+// compareToBenchmark 按组合估值区间对齐基准收益并计算相对指标
+func (s *PortfolioAppService) compareToBenchmark(ctx context.Context, symbol string, dates []time.Time, result *PerformanceAnalytics) error {
+	if s.benchmarks == nil {
+		return errors.New("benchmark source not configured")
+	}
+	index, err := s.benchmarks.GetIndex(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	if index == nil {
+		return fmt.Errorf("benchmark index %s not found", symbol)
+	}
+	levels, err := s.benchmarks.GetLevels(ctx, symbol, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return err
+	}
+	if len(levels) < 2 {
+		return fmt.Errorf("benchmark index %s has no levels in range", symbol)
+	}
+
+	bench := domain.BenchmarkReturns(levels).AlignTo(dates)
+	result.BenchmarkSymbol = index.Symbol
+	result.BenchmarkName = index.Name
+	result.BenchmarkReturns = bench
+	result.BenchmarkReturn = bench.Link(time.Time{}, time.Time{})
+	result.BenchmarkMaxDrawdown = bench.MaxDrawdown()
+	result.RelativeAnalytics = domain.CompareToBenchmark(result.Returns, bench)
+	return nil
+}
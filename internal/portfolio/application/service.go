@@ -276,6 +276,16 @@ type PortfolioSnapshotView struct {
 
 type PortfolioAppService struct {
 	portfolioRepo domain.PortfolioRepository
+	hierarchy     domain.AccountHierarchyResolver
+	cashFlows     domain.CashFlowRepository
+	benchmarks    domain.BenchmarkSource
+	assetReturns  domain.AssetReturnRepository
+	holdings      domain.HoldingsSource
+	marketData    domain.OptimizerMarketData
+	algoOrders    domain.AlgoOrderSubmitter
+	models        domain.ModelPortfolioRepository
+	accountState  domain.AccountStateSource
+	allocations   domain.AllocationPoster
 	logger        *slog.Logger
 }
 
@@ -286,7 +296,22 @@ func NewPortfolioAppService(repo domain.PortfolioRepository, logger *slog.Logger
 	}
 }
 
-func (s *PortfolioAppService) GetPortfolio(ctx context.Context, userID, currency string) (float64, float64, float64, float64, error) {
+// SetAccountHierarchyResolver 注入主子账户层级解析，启用按层级合并查询
+func (s *PortfolioAppService) SetAccountHierarchyResolver(resolver domain.AccountHierarchyResolver) {
+	s.hierarchy = resolver
+}
+
+func (s *PortfolioAppService) GetPortfolio(ctx context.Context, userID, currency string, includeSubAccounts bool) (float64, float64, float64, float64, error) {
+	if includeSubAccounts {
+		members, err := s.resolveMembers(ctx, userID)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+		if len(members) > 1 {
+			return s.getConsolidatedPortfolio(ctx, members)
+		}
+	}
+
 	snapshot, err := s.portfolioRepo.GetLatestSnapshot(ctx, userID)
 	if err != nil || snapshot == nil {
 		return 0, 0, 0, 0, err
@@ -297,13 +322,23 @@ func (s *PortfolioAppService) GetPortfolio(ctx context.Context, userID, currency
 	return totalEquity, 0, 0, dailyPnLPct, nil
 }
 
-func (s *PortfolioAppService) GetPositions(ctx context.Context, userID string) ([]PortfolioPositionView, error) {
+func (s *PortfolioAppService) GetPositions(ctx context.Context, userID string, includeSubAccounts bool) ([]PortfolioPositionView, error) {
 	// Stage A: this entrypoint keeps cmd/portfolio buildable even when position read model is not wired.
 	return []PortfolioPositionView{}, nil
 }
 
-func (s *PortfolioAppService) GetPerformance(ctx context.Context, userID, timeframe string) ([]PortfolioSnapshotView, float64, float64, float64, error) {
+func (s *PortfolioAppService) GetPerformance(ctx context.Context, userID, timeframe string, includeSubAccounts bool) ([]PortfolioSnapshotView, float64, float64, float64, error) {
 	limit := timeframeToLimit(timeframe)
+	if includeSubAccounts {
+		members, err := s.resolveMembers(ctx, userID)
+		if err != nil {
+			return nil, 0, 0, 0, err
+		}
+		if len(members) > 1 {
+			return s.getConsolidatedPerformance(ctx, members, limit)
+		}
+	}
+
 	snapshots, err := s.portfolioRepo.ListSnapshots(ctx, userID, limit)
 	if err != nil {
 		return nil, 0, 0, 0, err
@@ -331,6 +366,65 @@ func (s *PortfolioAppService) GetPerformance(ctx context.Context, userID, timefr
 	return history, totalReturn, sharpeRatio, maxDrawdown, nil
 }
 
+// resolveMembers 解析层级内全部用户；未注入解析器时只返回自身
+func (s *PortfolioAppService) resolveMembers(ctx context.Context, userID string) ([]string, error) {
+	if s.hierarchy == nil {
+		return []string{userID}, nil
+	}
+	_, members, err := s.hierarchy.ResolveMembers(ctx, userID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to resolve account hierarchy", "user_id", userID, "error", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// getConsolidatedPortfolio 汇总层级内各账户的最新快照
+func (s *PortfolioAppService) getConsolidatedPortfolio(ctx context.Context, members []string) (float64, float64, float64, float64, error) {
+	snapshots := make([]*domain.PortfolioSnapshot, 0, len(members))
+	for _, member := range members {
+		snapshot, err := s.portfolioRepo.GetLatestSnapshot(ctx, member)
+		if err != nil || snapshot == nil {
+			// 尚未生成快照的子账户不计入合并
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	equity, dayReturn := domain.RollupLatest(snapshots)
+	totalEquity, _ := equity.Float64()
+	dailyPnLPct, _ := dayReturn.Float64()
+	return totalEquity, 0, 0, dailyPnLPct, nil
+}
+
+// getConsolidatedPerformance 按日期合并层级内各账户的权益曲线，并据此重算业绩指标
+func (s *PortfolioAppService) getConsolidatedPerformance(ctx context.Context, members []string, limit int) ([]PortfolioSnapshotView, float64, float64, float64, error) {
+	var snapshots []*domain.PortfolioSnapshot
+	for _, member := range members {
+		list, err := s.portfolioRepo.ListSnapshots(ctx, member, limit)
+		if err != nil {
+			return nil, 0, 0, 0, err
+		}
+		snapshots = append(snapshots, list...)
+	}
+
+	curve := domain.RollupEquityCurve(snapshots)
+	if len(curve) > limit {
+		curve = curve[len(curve)-limit:]
+	}
+	// 与单账户查询保持一致：按日期倒序返回
+	history := make([]PortfolioSnapshotView, 0, len(curve))
+	for i := len(curve) - 1; i >= 0; i-- {
+		equity, _ := curve[i].Equity.Float64()
+		history = append(history, PortfolioSnapshotView{
+			Timestamp: curve[i].Date.Format("2006-01-02"),
+			Equity:    equity,
+		})
+	}
+
+	totalReturn, sharpeRatio, maxDrawdown := domain.CurveMetrics(curve)
+	return history, totalReturn, sharpeRatio, maxDrawdown, nil
+}
+
 func timeframeToLimit(timeframe string) int {
 	switch timeframe {
 	case "1D":
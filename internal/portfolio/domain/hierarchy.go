@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountHierarchyResolver 解析用户所在的主子账户层级
+type AccountHierarchyResolver interface {
+	// ResolveMembers 返回主账户及层级内全部用户（主账户在前）；没有子账户的用户只返回自身
+	ResolveMembers(ctx context.Context, userID string) (masterUserID string, userIDs []string, err error)
+}
+
+// EquityPoint 合并权益曲线上的一个点
+type EquityPoint struct {
+	Date   time.Time
+	Equity decimal.Decimal
+}
+
+// RollupLatest 汇总各成员最新快照的权益，并按前一日权益还原合并后的当日收益率
+func RollupLatest(snapshots []*PortfolioSnapshot) (equity, dayReturn decimal.Decimal) {
+	var previous decimal.Decimal
+	for _, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		equity = equity.Add(s.TotalValue)
+		// 前一日权益 = 当日权益 / (1 + 当日收益率)
+		base := decimal.NewFromInt(1).Add(s.DayReturn)
+		if base.IsPositive() {
+			previous = previous.Add(s.TotalValue.Div(base))
+		}
+	}
+	if previous.IsPositive() {
+		dayReturn = equity.Div(previous).Sub(decimal.NewFromInt(1))
+	}
+	return equity, dayReturn
+}
+
+// RollupEquityCurve 按快照日期汇总各成员权益，按日期升序返回
+func RollupEquityCurve(snapshots []*PortfolioSnapshot) []EquityPoint {
+	index := make(map[string]int)
+	curve := make([]EquityPoint, 0)
+	for _, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		day := s.SnapshotDate.Format("2006-01-02")
+		i, ok := index[day]
+		if !ok {
+			i = len(curve)
+			index[day] = i
+			curve = append(curve, EquityPoint{Date: s.SnapshotDate})
+		}
+		curve[i].Equity = curve[i].Equity.Add(s.TotalValue)
+	}
+	sort.Slice(curve, func(i, j int) bool { return curve[i].Date.Before(curve[j].Date) })
+	return curve
+}
+
+// CurveMetrics 由权益曲线计算累计收益、年化夏普（无风险利率按 0）与最大回撤
+func CurveMetrics(curve []EquityPoint) (totalReturn, sharpe, maxDrawdown float64) {
+	if len(curve) < 2 {
+		return 0, 0, 0
+	}
+	first, _ := curve[0].Equity.Float64()
+	last, _ := curve[len(curve)-1].Equity.Float64()
+	if first > 0 {
+		totalReturn = last/first - 1
+	}
+
+	returns := make([]float64, 0, len(curve)-1)
+	peak := first
+	for i := 1; i < len(curve); i++ {
+		prev, _ := curve[i-1].Equity.Float64()
+		cur, _ := curve[i].Equity.Float64()
+		if prev > 0 {
+			returns = append(returns, cur/prev-1)
+		}
+		peak = math.Max(peak, cur)
+		if peak > 0 {
+			maxDrawdown = math.Max(maxDrawdown, (peak-cur)/peak)
+		}
+	}
+	if len(returns) < 2 {
+		return totalReturn, 0, maxDrawdown
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	if std > 0 {
+		sharpe = mean / std * math.Sqrt(252)
+	}
+	return totalReturn, sharpe, maxDrawdown
+}
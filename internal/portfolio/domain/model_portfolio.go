@@ -0,0 +1,778 @@
+// 变更说明：增加模型组合：账户与模型的绑定、偏离度监控、按账户现金/限制名单/计税批次生成的批量调仓建议，
+// 审批通过的建议按 标的+方向 汇总为大宗单；每笔成交只把新增数量按比例回分到各账户，同一笔成交各账户同价，
+// 回分生成各账户的持仓与现金过账。
+// 假设：限制名单内的标的不买入、已持有的冻结不卖，其模型权重以外的资金按其余成分权重等比例分配；
+// 计税敏感账户卖出时不动用短期浮盈批次；卖出与买入均按最小交易单位向下取整，买入额不超过卖出后可用现金。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidModel        = errors.New("invalid model portfolio")
+	ErrInvalidProposalFlow = errors.New("invalid rebalance proposal transition")
+)
+
+// weightTolerance 模型权重合计允许的误差
+var weightTolerance = decimal.NewFromFloat(0.000001)
+
+// ModelStatus 模型状态
+type ModelStatus string
+
+const (
+	ModelActive  ModelStatus = "ACTIVE"
+	ModelRetired ModelStatus = "RETIRED"
+)
+
+// ModelPortfolio 模型组合：成分权重与现金权重合计为 1
+type ModelPortfolio struct {
+	gorm.Model
+	ModelID     string      `gorm:"column:model_id;type:varchar(32);uniqueIndex;not null" json:"model_id"`
+	Name        string      `gorm:"column:name;type:varchar(128);not null" json:"name"`
+	Description string      `gorm:"column:description;type:varchar(512)" json:"description"`
+	Status      ModelStatus `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	// CashWeight 模型现金目标权重
+	CashWeight decimal.Decimal `gorm:"column:cash_weight;type:decimal(10,6);not null" json:"cash_weight"`
+	// DriftThreshold 单边偏离度（Σ|Δw|/2）超过该值时视为需要调仓
+	DriftThreshold decimal.Decimal  `gorm:"column:drift_threshold;type:decimal(10,6);not null" json:"drift_threshold"`
+	Components     []ModelComponent `gorm:"foreignKey:ModelID;references:ModelID" json:"components"`
+}
+
+func (ModelPortfolio) TableName() string { return "portfolio_models" }
+
+// ModelComponent 模型成分
+type ModelComponent struct {
+	gorm.Model
+	ModelID      string          `gorm:"column:model_id;type:varchar(32);index;not null" json:"model_id"`
+	Symbol       string          `gorm:"column:symbol;type:varchar(20);not null" json:"symbol"`
+	TargetWeight decimal.Decimal `gorm:"column:target_weight;type:decimal(10,6);not null" json:"target_weight"`
+}
+
+func (ModelComponent) TableName() string { return "portfolio_model_components" }
+
+// Validate 校验成分不重复、权重非负且与现金权重合计为 1
+func (m *ModelPortfolio) Validate() error {
+	if m.ModelID == "" || m.Name == "" {
+		return fmt.Errorf("%w: model_id and name are required", ErrInvalidModel)
+	}
+	if len(m.Components) == 0 {
+		return fmt.Errorf("%w: at least one component is required", ErrInvalidModel)
+	}
+	if m.CashWeight.IsNegative() || m.DriftThreshold.IsNegative() {
+		return fmt.Errorf("%w: cash weight and drift threshold must not be negative", ErrInvalidModel)
+	}
+	total := m.CashWeight
+	seen := make(map[string]bool, len(m.Components))
+	for _, c := range m.Components {
+		if c.Symbol == "" || seen[c.Symbol] {
+			return fmt.Errorf("%w: empty or duplicate component %q", ErrInvalidModel, c.Symbol)
+		}
+		if c.TargetWeight.IsNegative() {
+			return fmt.Errorf("%w: negative weight for %s", ErrInvalidModel, c.Symbol)
+		}
+		seen[c.Symbol] = true
+		total = total.Add(c.TargetWeight)
+	}
+	if total.Sub(decimal.NewFromInt(1)).Abs().GreaterThan(weightTolerance) {
+		return fmt.Errorf("%w: weights sum to %s, want 1", ErrInvalidModel, total)
+	}
+	return nil
+}
+
+// Weights 成分目标权重
+func (m *ModelPortfolio) Weights() map[string]decimal.Decimal {
+	weights := make(map[string]decimal.Decimal, len(m.Components))
+	for _, c := range m.Components {
+		weights[c.Symbol] = c.TargetWeight
+	}
+	return weights
+}
+
+// ModelAssignment 账户与模型的绑定及账户级约束
+type ModelAssignment struct {
+	gorm.Model
+	UserID  string `gorm:"column:user_id;type:varchar(64);uniqueIndex;not null" json:"user_id"`
+	ModelID string `gorm:"column:model_id;type:varchar(32);index;not null" json:"model_id"`
+	// RestrictedSymbols 限制名单（逗号分隔）：不买入，已持有的冻结不卖
+	RestrictedSymbols string `gorm:"column:restricted_symbols;type:varchar(1024)" json:"restricted_symbols"`
+	// MinCash 账户最低保留现金，高于模型现金目标时以此为准
+	MinCash decimal.Decimal `gorm:"column:min_cash;type:decimal(32,8);not null" json:"min_cash"`
+	// TaxAware 卖出时不动用短期浮盈批次
+	TaxAware bool   `gorm:"column:tax_aware;not null" json:"tax_aware"`
+	Currency string `gorm:"column:currency;type:varchar(10);not null" json:"currency"`
+}
+
+func (ModelAssignment) TableName() string { return "portfolio_model_assignments" }
+
+// Restricted 限制名单
+func (a *ModelAssignment) Restricted() map[string]bool {
+	restricted := make(map[string]bool)
+	for _, s := range strings.Split(a.RestrictedSymbols, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			restricted[s] = true
+		}
+	}
+	return restricted
+}
+
+// TaxLot 账户在持计税批次
+type TaxLot struct {
+	LotID    uint
+	Quantity decimal.Decimal
+	TaxBasis decimal.Decimal
+	// Term SHORT_TERM 或 LONG_TERM
+	Term string
+}
+
+const shortTermLot = "SHORT_TERM"
+
+// AccountState 生成调仓建议所需的账户现状
+type AccountState struct {
+	UserID   string
+	Cash     decimal.Decimal
+	Holdings map[string]decimal.Decimal
+	Prices   map[string]decimal.Decimal
+	Lots     map[string][]TaxLot
+}
+
+// TotalValue 现金与已定价持仓市值之和
+func (s *AccountState) TotalValue() decimal.Decimal {
+	total := s.Cash
+	for symbol, qty := range s.Holdings {
+		total = total.Add(qty.Mul(s.Prices[symbol]))
+	}
+	return total
+}
+
+// DriftLine 单个标的的偏离
+type DriftLine struct {
+	Symbol        string  `json:"symbol"`
+	CurrentWeight float64 `json:"current_weight"`
+	TargetWeight  float64 `json:"target_weight"`
+	Drift         float64 `json:"drift"`
+}
+
+// DriftReport 账户相对模型的偏离度
+type DriftReport struct {
+	UserID     string          `json:"user_id"`
+	ModelID    string          `json:"model_id"`
+	TotalValue decimal.Decimal `json:"total_value"`
+	// Drift 单边偏离度 Σ|w - target|/2，现金计入
+	Drift    float64     `json:"drift"`
+	Breached bool        `json:"breached"`
+	Lines    []DriftLine `json:"lines"`
+}
+
+// MeasureDrift 计算账户相对模型（含现金）的偏离度
+func MeasureDrift(model *ModelPortfolio, state *AccountState) *DriftReport {
+	report := &DriftReport{UserID: state.UserID, ModelID: model.ModelID, TotalValue: state.TotalValue()}
+	if !report.TotalValue.IsPositive() {
+		return report
+	}
+	targets := model.Weights()
+	symbols := make(map[string]bool, len(targets)+len(state.Holdings))
+	for s := range targets {
+		symbols[s] = true
+	}
+	for s, qty := range state.Holdings {
+		if !qty.IsZero() {
+			symbols[s] = true
+		}
+	}
+	ordered := make([]string, 0, len(symbols))
+	for s := range symbols {
+		ordered = append(ordered, s)
+	}
+	sort.Strings(ordered)
+
+	var total float64
+	for _, s := range ordered {
+		current, _ := state.Holdings[s].Mul(state.Prices[s]).Div(report.TotalValue).Float64()
+		target, _ := targets[s].Float64()
+		report.Lines = append(report.Lines, DriftLine{Symbol: s, CurrentWeight: current, TargetWeight: target, Drift: current - target})
+		total += math.Abs(current - target)
+	}
+	cashCurrent, _ := state.Cash.Div(report.TotalValue).Float64()
+	cashWeight, _ := model.CashWeight.Float64()
+	total += math.Abs(cashCurrent - cashWeight)
+
+	report.Drift = total / 2
+	threshold, _ := model.DriftThreshold.Float64()
+	report.Breached = report.Drift > threshold
+	return report
+}
+
+// ProposalStatus 调仓建议状态
+type ProposalStatus string
+
+const (
+	ProposalPending  ProposalStatus = "PENDING"
+	ProposalApproved ProposalStatus = "APPROVED"
+	ProposalRejected ProposalStatus = "REJECTED"
+	ProposalOrdered  ProposalStatus = "ORDERED"
+	ProposalFilled   ProposalStatus = "FILLED"
+)
+
+// RebalanceProposal 单个账户的调仓建议
+type RebalanceProposal struct {
+	gorm.Model
+	ProposalID string          `gorm:"column:proposal_id;type:varchar(32);uniqueIndex;not null" json:"proposal_id"`
+	BatchID    string          `gorm:"column:batch_id;type:varchar(32);index;not null" json:"batch_id"`
+	UserID     string          `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	ModelID    string          `gorm:"column:model_id;type:varchar(32);not null" json:"model_id"`
+	Status     ProposalStatus  `gorm:"column:status;type:varchar(16);index;not null" json:"status"`
+	Drift      decimal.Decimal `gorm:"column:drift;type:decimal(10,6);not null" json:"drift"`
+	TotalValue decimal.Decimal `gorm:"column:total_value;type:decimal(32,8);not null" json:"total_value"`
+	CashBefore decimal.Decimal `gorm:"column:cash_before;type:decimal(32,8);not null" json:"cash_before"`
+	CashAfter  decimal.Decimal `gorm:"column:cash_after;type:decimal(32,8);not null" json:"cash_after"`
+	// EstimatedGain 按所选批次估算的已实现损益
+	EstimatedGain decimal.Decimal `gorm:"column:estimated_gain;type:decimal(32,8);not null" json:"estimated_gain"`
+	ReviewedBy    string          `gorm:"column:reviewed_by;type:varchar(64)" json:"reviewed_by"`
+	Lines         []RebalanceLine `gorm:"foreignKey:ProposalID;references:ProposalID" json:"lines"`
+}
+
+func (RebalanceProposal) TableName() string { return "portfolio_rebalance_proposals" }
+
+// RebalanceLine 调仓建议中的单笔交易
+type RebalanceLine struct {
+	gorm.Model
+	ProposalID string          `gorm:"column:proposal_id;type:varchar(32);index;not null" json:"proposal_id"`
+	Symbol     string          `gorm:"column:symbol;type:varchar(20);not null" json:"symbol"`
+	Side       string          `gorm:"column:side;type:varchar(8);not null" json:"side"`
+	Quantity   decimal.Decimal `gorm:"column:quantity;type:decimal(32,8);not null" json:"quantity"`
+	Price      decimal.Decimal `gorm:"column:price;type:decimal(32,8);not null" json:"price"`
+	// LotIDs 卖出时选定的计税批次（逗号分隔），供按指定批次记账
+	LotIDs         string          `gorm:"column:lot_ids;type:varchar(512)" json:"lot_ids"`
+	EstimatedGain  decimal.Decimal `gorm:"column:estimated_gain;type:decimal(32,8);not null" json:"estimated_gain"`
+	BlockID        string          `gorm:"column:block_id;type:varchar(32);index" json:"block_id"`
+	FilledQuantity decimal.Decimal `gorm:"column:filled_quantity;type:decimal(32,8);not null" json:"filled_quantity"`
+	AvgFillPrice   decimal.Decimal `gorm:"column:avg_fill_price;type:decimal(32,8);not null" json:"avg_fill_price"`
+}
+
+func (RebalanceLine) TableName() string { return "portfolio_rebalance_lines" }
+
+// Review 审批：只有待审批的建议可以通过或驳回
+func (p *RebalanceProposal) Review(approve bool, reviewer string) error {
+	if p.Status != ProposalPending {
+		return fmt.Errorf("%w: proposal %s is %s", ErrInvalidProposalFlow, p.ProposalID, p.Status)
+	}
+	p.Status = ProposalRejected
+	if approve {
+		p.Status = ProposalApproved
+	}
+	p.ReviewedBy = reviewer
+	return nil
+}
+
+// RefreshFillStatus 所有交易行全部成交后标记为已成交
+func (p *RebalanceProposal) RefreshFillStatus() {
+	if p.Status != ProposalOrdered {
+		return
+	}
+	for _, l := range p.Lines {
+		if l.FilledQuantity.LessThan(l.Quantity) {
+			return
+		}
+	}
+	p.Status = ProposalFilled
+}
+
+// ProposalInput 生成调仓建议的输入
+type ProposalInput struct {
+	ProposalID string
+	BatchID    string
+	Model      *ModelPortfolio
+	Assignment *ModelAssignment
+	State      *AccountState
+	// LotSizes 最小交易单位，缺失或非正时数量保留 8 位小数
+	LotSizes map[string]decimal.Decimal
+}
+
+// BuildRebalanceProposal 按模型生成账户调仓建议；没有可执行交易时返回 nil
+func BuildRebalanceProposal(in ProposalInput) (*RebalanceProposal, error) {
+	state := in.State
+	total := state.TotalValue()
+	if !total.IsPositive() {
+		return nil, fmt.Errorf("account %s has no value to rebalance", state.UserID)
+	}
+	for symbol := range in.Model.Weights() {
+		if !state.Prices[symbol].IsPositive() {
+			return nil, fmt.Errorf("no price for model component %s", symbol)
+		}
+	}
+	targets := accountTargets(in.Model, in.Assignment, state, total)
+
+	proposal := &RebalanceProposal{
+		ProposalID:    in.ProposalID,
+		BatchID:       in.BatchID,
+		UserID:        state.UserID,
+		ModelID:       in.Model.ModelID,
+		Status:        ProposalPending,
+		Drift:         decimal.NewFromFloat(MeasureDrift(in.Model, state).Drift).Round(6),
+		TotalValue:    total,
+		CashBefore:    state.Cash,
+		EstimatedGain: decimal.Zero,
+	}
+
+	symbols := make([]string, 0, len(targets))
+	for s := range targets {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+
+	cash := state.Cash
+	type buy struct {
+		symbol string
+		qty    decimal.Decimal
+	}
+	var buys []buy
+	for _, symbol := range symbols {
+		price := state.Prices[symbol]
+		if !price.IsPositive() {
+			continue
+		}
+		current := state.Holdings[symbol]
+		delta := targets[symbol].Sub(current.Mul(price)).Div(price)
+		if delta.IsPositive() {
+			buys = append(buys, buy{symbol: symbol, qty: delta})
+			continue
+		}
+		qty := floorToLot(delta.Neg(), in.LotSizes[symbol])
+		if qty.GreaterThan(current) {
+			qty = current
+		}
+		line := RebalanceLine{ProposalID: in.ProposalID, Symbol: symbol, Side: "SELL", Price: price}
+		qty, line.LotIDs, line.EstimatedGain = selectSellLots(state.Lots[symbol], qty, price, in.Assignment.TaxAware, in.LotSizes[symbol])
+		if !qty.IsPositive() {
+			continue
+		}
+		line.Quantity = qty
+		line.FilledQuantity, line.AvgFillPrice = decimal.Zero, decimal.Zero
+		cash = cash.Add(qty.Mul(price))
+		proposal.EstimatedGain = proposal.EstimatedGain.Add(line.EstimatedGain)
+		proposal.Lines = append(proposal.Lines, line)
+	}
+
+	// 买入额以卖出后的现金扣除保留现金为限，超出时等比例缩减
+	available := cash.Sub(cashTarget(in.Model, in.Assignment, total))
+	var need decimal.Decimal
+	for _, b := range buys {
+		need = need.Add(b.qty.Mul(state.Prices[b.symbol]))
+	}
+	scale := decimal.NewFromInt(1)
+	if !available.IsPositive() {
+		scale = decimal.Zero
+	} else if need.GreaterThan(available) {
+		scale = available.Div(need)
+	}
+	for _, b := range buys {
+		qty := floorToLot(b.qty.Mul(scale), in.LotSizes[b.symbol])
+		if !qty.IsPositive() {
+			continue
+		}
+		price := state.Prices[b.symbol]
+		cash = cash.Sub(qty.Mul(price))
+		proposal.Lines = append(proposal.Lines, RebalanceLine{
+			ProposalID:     in.ProposalID,
+			Symbol:         b.symbol,
+			Side:           "BUY",
+			Quantity:       qty,
+			Price:          price,
+			EstimatedGain:  decimal.Zero,
+			FilledQuantity: decimal.Zero,
+			AvgFillPrice:   decimal.Zero,
+		})
+	}
+	if len(proposal.Lines) == 0 {
+		return nil, nil
+	}
+	proposal.CashAfter = cash
+	return proposal, nil
+}
+
+// cashTarget 模型现金目标与账户最低保留现金取较大者
+func cashTarget(model *ModelPortfolio, assignment *ModelAssignment, total decimal.Decimal) decimal.Decimal {
+	return decimal.Max(total.Mul(model.CashWeight), assignment.MinCash)
+}
+
+// accountTargets 计算各标的目标市值：限制名单持仓冻结，其余资金按未受限成分权重等比例分配，模型外持仓目标为 0
+func accountTargets(model *ModelPortfolio, assignment *ModelAssignment, state *AccountState, total decimal.Decimal) map[string]decimal.Decimal {
+	restricted := assignment.Restricted()
+	targets := make(map[string]decimal.Decimal)
+	frozen := decimal.Zero
+	for symbol, qty := range state.Holdings {
+		if restricted[symbol] {
+			value := qty.Mul(state.Prices[symbol])
+			targets[symbol] = value
+			frozen = frozen.Add(value)
+		} else if !qty.IsZero() {
+			targets[symbol] = decimal.Zero
+		}
+	}
+	weights := model.Weights()
+	eligible := decimal.Zero
+	for symbol, w := range weights {
+		if !restricted[symbol] {
+			eligible = eligible.Add(w)
+		}
+	}
+	investable := total.Sub(frozen).Sub(cashTarget(model, assignment, total))
+	if !eligible.IsPositive() || !investable.IsPositive() {
+		return targets
+	}
+	for symbol, w := range weights {
+		if restricted[symbol] {
+			continue
+		}
+		targets[symbol] = investable.Mul(w).Div(eligible)
+	}
+	return targets
+}
+
+// selectSellLots 按计税成本从高到低选择卖出批次；计税敏感时跳过短期浮盈批次。
+// 返回可卖数量（按最小交易单位向下取整）、所选批次与估算损益；没有批次明细时按原数量卖出且不估算损益
+func selectSellLots(lots []TaxLot, qty, price decimal.Decimal, taxAware bool, lot decimal.Decimal) (decimal.Decimal, string, decimal.Decimal) {
+	if len(lots) == 0 {
+		return qty, "", decimal.Zero
+	}
+	candidates := make([]TaxLot, 0, len(lots))
+	for _, l := range lots {
+		if !l.Quantity.IsPositive() {
+			continue
+		}
+		if taxAware && l.Term == shortTermLot && price.GreaterThan(l.TaxBasis) {
+			continue
+		}
+		candidates = append(candidates, l)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].TaxBasis.GreaterThan(candidates[j].TaxBasis) })
+
+	var available decimal.Decimal
+	for _, l := range candidates {
+		available = available.Add(l.Quantity)
+	}
+	if qty.GreaterThan(available) {
+		qty = floorToLot(available, lot)
+	}
+
+	remaining := qty
+	gain := decimal.Zero
+	ids := make([]string, 0, len(candidates))
+	for _, l := range candidates {
+		if !remaining.IsPositive() {
+			break
+		}
+		take := decimal.Min(remaining, l.Quantity)
+		gain = gain.Add(price.Sub(l.TaxBasis).Mul(take))
+		ids = append(ids, fmt.Sprintf("%d", l.LotID))
+		remaining = remaining.Sub(take)
+	}
+	return qty, strings.Join(ids, ","), gain
+}
+
+// floorToLot 按最小交易单位向下取整，无最小交易单位时保留 8 位小数
+func floorToLot(qty, lot decimal.Decimal) decimal.Decimal {
+	if lot.IsPositive() {
+		return qty.Div(lot).Floor().Mul(lot)
+	}
+	return qty.Truncate(unlottedQuantityScale)
+}
+
+// BlockOrderStatus 大宗单状态
+type BlockOrderStatus string
+
+const (
+	BlockSubmitted       BlockOrderStatus = "SUBMITTED"
+	BlockPartiallyFilled BlockOrderStatus = "PARTIALLY_FILLED"
+	BlockFilled          BlockOrderStatus = "FILLED"
+	BlockFailed          BlockOrderStatus = "FAILED"
+)
+
+// BlockOrder 多个账户同一标的同一方向的汇总委托
+type BlockOrder struct {
+	gorm.Model
+	BlockID        string            `gorm:"column:block_id;type:varchar(32);uniqueIndex;not null" json:"block_id"`
+	BatchID        string            `gorm:"column:batch_id;type:varchar(32);index;not null" json:"batch_id"`
+	Symbol         string            `gorm:"column:symbol;type:varchar(20);not null" json:"symbol"`
+	Side           string            `gorm:"column:side;type:varchar(8);not null" json:"side"`
+	Quantity       decimal.Decimal   `gorm:"column:quantity;type:decimal(32,8);not null" json:"quantity"`
+	LotSize        decimal.Decimal   `gorm:"column:lot_size;type:decimal(32,8);not null" json:"lot_size"`
+	FilledQuantity decimal.Decimal   `gorm:"column:filled_quantity;type:decimal(32,8);not null" json:"filled_quantity"`
+	AvgPrice       decimal.Decimal   `gorm:"column:avg_price;type:decimal(32,8);not null" json:"avg_price"`
+	AlgoOrderID    string            `gorm:"column:algo_order_id;type:varchar(64)" json:"algo_order_id"`
+	Status         BlockOrderStatus  `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	Allocations    []BlockAllocation `gorm:"foreignKey:BlockID;references:BlockID" json:"allocations"`
+}
+
+func (BlockOrder) TableName() string { return "portfolio_block_orders" }
+
+// BlockAllocation 大宗单在各账户间的分配
+type BlockAllocation struct {
+	gorm.Model
+	BlockID        string          `gorm:"column:block_id;type:varchar(32);index;not null" json:"block_id"`
+	ProposalID     string          `gorm:"column:proposal_id;type:varchar(32);not null" json:"proposal_id"`
+	LineID         uint            `gorm:"column:line_id;not null" json:"line_id"`
+	UserID         string          `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	Quantity       decimal.Decimal `gorm:"column:quantity;type:decimal(32,8);not null" json:"quantity"`
+	FilledQuantity decimal.Decimal `gorm:"column:filled_quantity;type:decimal(32,8);not null" json:"filled_quantity"`
+	Price          decimal.Decimal `gorm:"column:price;type:decimal(32,8);not null" json:"price"`
+}
+
+func (BlockAllocation) TableName() string { return "portfolio_block_allocations" }
+
+// BlockFill 大宗单成交回报，FillID 去重
+type BlockFill struct {
+	gorm.Model
+	FillID   string          `gorm:"column:fill_id;type:varchar(64);uniqueIndex;not null" json:"fill_id"`
+	BlockID  string          `gorm:"column:block_id;type:varchar(32);index;not null" json:"block_id"`
+	Quantity decimal.Decimal `gorm:"column:quantity;type:decimal(32,8);not null" json:"quantity"`
+	Price    decimal.Decimal `gorm:"column:price;type:decimal(32,8);not null" json:"price"`
+}
+
+func (BlockFill) TableName() string { return "portfolio_block_fills" }
+
+// AggregateBlockOrders 把已审批建议的交易行按 标的+方向 汇总为大宗单，并回写交易行的 BlockID
+func AggregateBlockOrders(batchID string, proposals []*RebalanceProposal, lotSizes map[string]decimal.Decimal, newID func() string) []*BlockOrder {
+	blocks := make(map[string]*BlockOrder)
+	keys := make([]string, 0)
+	for _, p := range proposals {
+		if p.Status != ProposalApproved {
+			continue
+		}
+		for i := range p.Lines {
+			line := &p.Lines[i]
+			key := line.Symbol + "|" + line.Side
+			block, ok := blocks[key]
+			if !ok {
+				block = &BlockOrder{
+					BlockID:        newID(),
+					BatchID:        batchID,
+					Symbol:         line.Symbol,
+					Side:           line.Side,
+					Quantity:       decimal.Zero,
+					LotSize:        lotSizes[line.Symbol],
+					FilledQuantity: decimal.Zero,
+					AvgPrice:       decimal.Zero,
+					Status:         BlockSubmitted,
+				}
+				blocks[key] = block
+				keys = append(keys, key)
+			}
+			line.BlockID = block.BlockID
+			block.Quantity = block.Quantity.Add(line.Quantity)
+			block.Allocations = append(block.Allocations, BlockAllocation{
+				BlockID:        block.BlockID,
+				ProposalID:     p.ProposalID,
+				LineID:         line.ID,
+				UserID:         p.UserID,
+				Quantity:       line.Quantity,
+				FilledQuantity: decimal.Zero,
+				Price:          decimal.Zero,
+			})
+		}
+		p.Status = ProposalOrdered
+	}
+	sort.Strings(keys)
+	out := make([]*BlockOrder, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, blocks[k])
+	}
+	return out
+}
+
+// ApplyFill 记入一笔成交，更新大宗单均价并只把本笔成交数量回分到各账户；返回与 Allocations 下标对应的本次分配数量
+func (b *BlockOrder) ApplyFill(qty, price decimal.Decimal) ([]decimal.Decimal, error) {
+	if !qty.IsPositive() || !price.IsPositive() {
+		return nil, errors.New("fill quantity and price must be positive")
+	}
+	if b.FilledQuantity.Add(qty).GreaterThan(b.Quantity) {
+		return nil, fmt.Errorf("fill of %s exceeds remaining quantity of block %s", qty, b.BlockID)
+	}
+	filled := b.FilledQuantity.Add(qty)
+	b.AvgPrice = b.AvgPrice.Mul(b.FilledQuantity).Add(price.Mul(qty)).DivRound(filled, 8)
+	b.FilledQuantity = filled
+	b.Status = BlockPartiallyFilled
+	if filled.Equal(b.Quantity) {
+		b.Status = BlockFilled
+	}
+	increments := b.allocate(qty)
+	for i, inc := range increments {
+		if !inc.IsPositive() {
+			continue
+		}
+		a := &b.Allocations[i]
+		total := a.FilledQuantity.Add(inc)
+		a.Price = a.Price.Mul(a.FilledQuantity).Add(price.Mul(inc)).DivRound(total, 8)
+		a.FilledQuantity = total
+	}
+	return increments, nil
+}
+
+// allocate 把本笔成交分配到各账户：各账户按委托数量比例参与每一笔成交，使其累计均价与大宗单均价一致。
+// 以累计成交的比例份额（按最小交易单位向下取整）为目标，只分配目标与已分配之间的差额，已分配数量不再变动；
+// 取整产生的余量逐单位补给距比例份额最远的账户。大宗单全部成交时各账户恰好补足委托数量
+func (b *BlockOrder) allocate(qty decimal.Decimal) []decimal.Decimal {
+	n := len(b.Allocations)
+	increments := make([]decimal.Decimal, n)
+	exact := make([]decimal.Decimal, n)
+	allocated := decimal.Zero
+	for i := range b.Allocations {
+		a := &b.Allocations[i]
+		exact[i] = decimal.Zero
+		increments[i] = decimal.Zero
+		if b.Quantity.IsPositive() {
+			exact[i] = b.FilledQuantity.Mul(a.Quantity).Div(b.Quantity)
+		}
+		target := decimal.Min(floorToLot(exact[i], b.LotSize), a.Quantity)
+		if target.GreaterThan(a.FilledQuantity) {
+			increments[i] = target.Sub(a.FilledQuantity)
+			allocated = allocated.Add(increments[i])
+		}
+	}
+	unit := b.LotSize
+	if !unit.IsPositive() {
+		unit = decimal.New(1, -unlottedQuantityScale)
+	}
+	// shortfall 分配后距比例份额的差距，为负表示已超出份额
+	shortfall := func(i int) decimal.Decimal {
+		return exact[i].Sub(b.Allocations[i].FilledQuantity).Sub(increments[i])
+	}
+	// 此前补给的余量可能使目标差额之和超过本笔成交，从超出份额最多的账户收回
+	for allocated.GreaterThan(qty) {
+		best := -1
+		for i := range increments {
+			if increments[i].GreaterThanOrEqual(unit) && (best < 0 || shortfall(i).LessThan(shortfall(best))) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		increments[best] = increments[best].Sub(unit)
+		allocated = allocated.Sub(unit)
+	}
+	for remaining := qty.Sub(allocated); remaining.GreaterThanOrEqual(unit); remaining = remaining.Sub(unit) {
+		best := -1
+		for i := range increments {
+			gap := b.Allocations[i].Quantity.Sub(b.Allocations[i].FilledQuantity).Sub(increments[i])
+			if gap.GreaterThanOrEqual(unit) && (best < 0 || shortfall(i).GreaterThan(shortfall(best))) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		increments[best] = increments[best].Add(unit)
+	}
+	return increments
+}
+
+// AllocationBookingStatus 回分过账状态
+type AllocationBookingStatus string
+
+const (
+	BookingPending AllocationBookingStatus = "PENDING"
+	BookingPosted  AllocationBookingStatus = "POSTED"
+)
+
+// AllocationBooking 一笔成交回分到某账户的过账：持仓增减与现金收付。与成交在同一事务内落库，提交后过账到
+// 持仓与账户服务，失败时保持 PENDING 待重试；BookingID 作为下游幂等键
+type AllocationBooking struct {
+	gorm.Model
+	BookingID string                  `gorm:"column:booking_id;type:varchar(128);uniqueIndex;not null" json:"booking_id"`
+	FillID    string                  `gorm:"column:fill_id;type:varchar(64);not null" json:"fill_id"`
+	BlockID   string                  `gorm:"column:block_id;type:varchar(32);index;not null" json:"block_id"`
+	UserID    string                  `gorm:"column:user_id;type:varchar(64);not null" json:"user_id"`
+	Symbol    string                  `gorm:"column:symbol;type:varchar(20);not null" json:"symbol"`
+	Side      string                  `gorm:"column:side;type:varchar(8);not null" json:"side"`
+	Currency  string                  `gorm:"column:currency;type:varchar(10);not null" json:"currency"`
+	Quantity  decimal.Decimal         `gorm:"column:quantity;type:decimal(32,8);not null" json:"quantity"`
+	Price     decimal.Decimal         `gorm:"column:price;type:decimal(32,8);not null" json:"price"`
+	Status    AllocationBookingStatus `gorm:"column:status;type:varchar(16);index;not null" json:"status"`
+}
+
+func (AllocationBooking) TableName() string { return "portfolio_allocation_bookings" }
+
+// Notional 成交金额，买入为账户付款、卖出为账户收款
+func (b *AllocationBooking) Notional() decimal.Decimal {
+	return b.Quantity.Mul(b.Price).Round(unlottedQuantityScale)
+}
+
+// NewAllocationBookings 由本笔成交的分配数量生成各账户过账，同一笔成交内所有账户取相同价格；
+// currencies 为账户的结算币种
+func NewAllocationBookings(b *BlockOrder, fillID string, price decimal.Decimal, increments []decimal.Decimal, currencies map[string]string) []*AllocationBooking {
+	bookings := make([]*AllocationBooking, 0, len(increments))
+	for i, inc := range increments {
+		if !inc.IsPositive() {
+			continue
+		}
+		a := b.Allocations[i]
+		bookings = append(bookings, &AllocationBooking{
+			BookingID: fmt.Sprintf("ALLOC:%s:%d", fillID, a.LineID),
+			FillID:    fillID,
+			BlockID:   b.BlockID,
+			UserID:    a.UserID,
+			Symbol:    b.Symbol,
+			Side:      b.Side,
+			Currency:  currencies[a.UserID],
+			Quantity:  inc,
+			Price:     price,
+			Status:    BookingPending,
+		})
+	}
+	return bookings
+}
+
+// AllocationPoster 把回分过账到持仓与账户服务，须按 BookingID 幂等
+type AllocationPoster interface {
+	Post(ctx context.Context, booking *AllocationBooking) error
+}
+
+// ModelPortfolioRepository 模型组合、账户绑定、调仓建议与大宗单仓储
+type ModelPortfolioRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	SaveModel(ctx context.Context, model *ModelPortfolio) error
+	// GetModel 不存在时返回 nil, nil
+	GetModel(ctx context.Context, modelID string) (*ModelPortfolio, error)
+	SaveAssignment(ctx context.Context, assignment *ModelAssignment) error
+	ListAssignments(ctx context.Context, modelID string) ([]*ModelAssignment, error)
+
+	SaveProposals(ctx context.Context, proposals []*RebalanceProposal) error
+	// GetProposal 不存在时返回 nil, nil
+	GetProposal(ctx context.Context, proposalID string) (*RebalanceProposal, error)
+	ListProposals(ctx context.Context, batchID string, status ProposalStatus) ([]*RebalanceProposal, error)
+	UpdateProposal(ctx context.Context, proposal *RebalanceProposal) error
+
+	SaveBlockOrders(ctx context.Context, blocks []*BlockOrder) error
+	// GetBlockOrder 加锁读取大宗单及其分配，不存在时返回 nil, nil
+	GetBlockOrder(ctx context.Context, blockID string) (*BlockOrder, error)
+	ListBlockOrders(ctx context.Context, batchID string) ([]*BlockOrder, error)
+	UpdateBlockOrder(ctx context.Context, block *BlockOrder) error
+	// RecordBlockFill 记录成交回报，FillID 已存在时返回 false
+	RecordBlockFill(ctx context.Context, fill *BlockFill) (bool, error)
+	// UpdateLineFill 回写调仓交易行的累计成交与均价
+	UpdateLineFill(ctx context.Context, lineID uint, filled, avgPrice decimal.Decimal) error
+
+	// GetAssignment 查询账户的模型绑定，不存在时返回 nil, nil
+	GetAssignment(ctx context.Context, userID string) (*ModelAssignment, error)
+	SaveAllocationBookings(ctx context.Context, bookings []*AllocationBooking) error
+	// ListPendingAllocationBookings 按创建顺序列出待过账的回分，blockID 为空时不限大宗单
+	ListPendingAllocationBookings(ctx context.Context, blockID string, limit int) ([]*AllocationBooking, error)
+	MarkAllocationBookingPosted(ctx context.Context, bookingID string) error
+}
+
+// AccountStateSource 账户现金、持仓与计税批次
+type AccountStateSource interface {
+	Cash(ctx context.Context, userID, currency string) (decimal.Decimal, error)
+	TaxLots(ctx context.Context, userID, symbol string) ([]TaxLot, error)
+}
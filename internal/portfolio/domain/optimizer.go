@@ -0,0 +1,903 @@
+// 变更说明：增加组合优化器：均值-方差、最小方差、风险平价与 Black-Litterman，协方差由存储的日收益按
+// Ledoit-Wolf 向单位阵收缩估计；支持权重上下限、行业上限（SectorAllocation.TargetWeight 作为上限）、
+// 换手率上限，并按参考数据的最小交易单位把目标权重转换为可直接下发执行服务的调仓交易清单。
+// 假设：组合只做多、目标权重合计为 1（现金全部投入）；换手率按单边 (Σ|Δw| + |Δ现金|)/2 计算，换手上限作为
+// 以当前权重为中心的 L1 球与其他约束一起在投影中满足，无法同时满足时返回 ErrInfeasibleConstraint；
+// 收益与协方差按 252 个交易日年化。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// OptimizationMethod 组合优化方法
+type OptimizationMethod string
+
+const (
+	OptimizeMeanVariance   OptimizationMethod = "MEAN_VARIANCE"
+	OptimizeMinVariance    OptimizationMethod = "MIN_VARIANCE"
+	OptimizeRiskParity     OptimizationMethod = "RISK_PARITY"
+	OptimizeBlackLitterman OptimizationMethod = "BLACK_LITTERMAN"
+)
+
+const (
+	// defaultRiskAversion 未指定时的风险厌恶系数
+	defaultRiskAversion = 2.5
+	// blackLittermanTau 先验协方差缩放系数
+	blackLittermanTau = 0.05
+	// minReturnObservations 估计协方差所需的最少共同观测数
+	minReturnObservations = 20
+	// unlottedQuantityScale 无最小交易单位时数量保留的小数位
+	unlottedQuantityScale = 8
+	// turnoverProjectionRounds 权重约束与换手约束交替投影的最大轮数
+	turnoverProjectionRounds = 1000
+	// constraintTolerance 求解结果校验约束时的容差
+	constraintTolerance = 1e-6
+)
+
+var (
+	ErrInvalidOptimization  = errors.New("invalid optimization request")
+	ErrInfeasibleConstraint = errors.New("optimization constraints are infeasible")
+)
+
+// AssetReturn 标的日收益（收盘价计算）
+type AssetReturn struct {
+	gorm.Model
+	Symbol     string          `gorm:"column:symbol;type:varchar(20);uniqueIndex:idx_asset_return_symbol_date;not null" json:"symbol"`
+	ReturnDate time.Time       `gorm:"column:return_date;type:date;uniqueIndex:idx_asset_return_symbol_date;not null" json:"return_date"`
+	Return     decimal.Decimal `gorm:"column:daily_return;type:decimal(20,10);not null" json:"return"`
+}
+
+func (AssetReturn) TableName() string { return "portfolio_asset_returns" }
+
+// AssetReturnRepository 标的日收益仓储
+type AssetReturnRepository interface {
+	// SaveReturns 写入日收益，同一标的同一日期已存在时覆盖
+	SaveReturns(ctx context.Context, returns []AssetReturn) error
+	// ListReturns 按日期升序返回 [start, end] 内各标的的日收益
+	ListReturns(ctx context.Context, symbols []string, start, end time.Time) ([]AssetReturn, error)
+}
+
+// DailyClose 日线收盘价
+type DailyClose struct {
+	Date  time.Time
+	Close decimal.Decimal
+}
+
+// ReturnsFromCloses 由按日期升序的收盘价计算日收益
+func ReturnsFromCloses(symbol string, closes []DailyClose) []AssetReturn {
+	returns := make([]AssetReturn, 0, len(closes))
+	for i := 1; i < len(closes); i++ {
+		prev := closes[i-1].Close
+		if !prev.IsPositive() {
+			continue
+		}
+		returns = append(returns, AssetReturn{
+			Symbol:     symbol,
+			ReturnDate: closes[i].Date,
+			Return:     closes[i].Close.Sub(prev).DivRound(prev, 10),
+		})
+	}
+	return returns
+}
+
+// HoldingsSource 用户当前持仓数量（标的 → 数量）
+type HoldingsSource interface {
+	Holdings(ctx context.Context, userID string) (map[string]decimal.Decimal, error)
+}
+
+// OptimizerMarketData 优化所需的最新价、最小交易单位与日线收盘价
+type OptimizerMarketData interface {
+	LatestPrice(ctx context.Context, symbol string) (decimal.Decimal, error)
+	LotSize(ctx context.Context, symbol string) (decimal.Decimal, error)
+	// DailyCloses 返回最近 limit 根日线收盘价，按日期升序
+	DailyCloses(ctx context.Context, symbol string, limit int) ([]DailyClose, error)
+}
+
+// AlgoOrder 下发执行服务的算法单
+type AlgoOrder struct {
+	UserID   string
+	Symbol   string
+	Side     string
+	Quantity decimal.Decimal
+	AlgoType string
+	Start    time.Time
+	End      time.Time
+}
+
+// AlgoOrderSubmitter 算法单下发
+type AlgoOrderSubmitter interface {
+	SubmitAlgoOrder(ctx context.Context, order AlgoOrder) (string, error)
+}
+
+// OptimizationAsset 参与优化的标的
+type OptimizationAsset struct {
+	Symbol string
+	Sector string
+	// MinWeight、MaxWeight 为空时使用约束中的默认上下限
+	MinWeight *float64
+	MaxWeight *float64
+	// CurrentWeight 当前权重，用于换手约束
+	CurrentWeight float64
+	// MarketWeight Black-Litterman 先验的市场（基准）权重，为 0 时回退到当前权重
+	MarketWeight float64
+}
+
+// View Black-Litterman 观点：Weights 为观点组合（绝对观点为单个标的权重 1，相对观点多空权重之和为 0）
+type View struct {
+	Weights map[string]float64
+	// Return 观点组合的年化预期收益
+	Return float64
+	// Confidence 观点置信度 (0,1)，缺省 0.5
+	Confidence float64
+}
+
+// OptimizationConstraints 优化约束
+type OptimizationConstraints struct {
+	DefaultMinWeight float64
+	// DefaultMaxWeight 为 0 时视为 1
+	DefaultMaxWeight float64
+	// SectorCaps 行业权重上限，取 TargetWeight
+	SectorCaps []SectorAllocation
+	// MaxTurnover 单边换手上限，0 表示不限
+	MaxTurnover float64
+	// RiskAversion 风险厌恶系数，0 时取默认值
+	RiskAversion float64
+}
+
+// OptimizationInput 优化输入；Returns 为各标的日收益（只使用所有标的均有数据的日期）
+type OptimizationInput struct {
+	Method      OptimizationMethod
+	Assets      []OptimizationAsset
+	Returns     []AssetReturn
+	Views       []View
+	Constraints OptimizationConstraints
+}
+
+// OptimizationResult 优化结果
+type OptimizationResult struct {
+	Method            OptimizationMethod `json:"method"`
+	Weights           map[string]float64 `json:"weights"`
+	ExpectedReturns   map[string]float64 `json:"expected_returns"`
+	RiskContributions map[string]float64 `json:"risk_contributions"`
+	ExpectedReturn    float64            `json:"expected_return"`
+	Volatility        float64            `json:"volatility"`
+	Turnover          float64            `json:"turnover"`
+	Shrinkage         float64            `json:"shrinkage"`
+	Observations      int                `json:"observations"`
+}
+
+// Optimize 按指定方法求解满足约束的目标权重
+func Optimize(in OptimizationInput) (*OptimizationResult, error) {
+	n := len(in.Assets)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: no assets", ErrInvalidOptimization)
+	}
+	symbols := make([]string, n)
+	index := make(map[string]int, n)
+	for i, a := range in.Assets {
+		if _, dup := index[a.Symbol]; dup {
+			return nil, fmt.Errorf("%w: duplicate asset %s", ErrInvalidOptimization, a.Symbol)
+		}
+		symbols[i] = a.Symbol
+		index[a.Symbol] = i
+	}
+
+	matrix := ReturnMatrix(in.Returns, symbols)
+	if len(matrix) < minReturnObservations {
+		return nil, fmt.Errorf("%w: %d common return observations, need at least %d", ErrInvalidOptimization, len(matrix), minReturnObservations)
+	}
+	mu := annualizedMeans(matrix)
+	cov, shrinkage := ShrunkCovariance(matrix)
+
+	set, err := newConstraintSet(in.Assets, in.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	lambda := in.Constraints.RiskAversion
+	if lambda <= 0 {
+		lambda = defaultRiskAversion
+	}
+
+	var w []float64
+	switch in.Method {
+	case OptimizeMeanVariance:
+		w = meanVariance(mu, cov, lambda, set)
+	case OptimizeMinVariance:
+		w = meanVariance(make([]float64, n), cov, 1, set)
+	case OptimizeRiskParity:
+		w = riskParity(cov, set)
+	case OptimizeBlackLitterman:
+		prior := make([]float64, n)
+		for i, a := range in.Assets {
+			prior[i] = a.MarketWeight
+			if prior[i] <= 0 {
+				prior[i] = a.CurrentWeight
+			}
+		}
+		mu, err = blackLittermanReturns(cov, normalize(prior), lambda, in.Views, index)
+		if err != nil {
+			return nil, err
+		}
+		w = meanVariance(mu, cov, lambda, set)
+	default:
+		return nil, fmt.Errorf("%w: unsupported method %s", ErrInvalidOptimization, in.Method)
+	}
+
+	turnover := halfTurnover(w, set.current)
+	if limit := set.maxTurnover; limit > 0 && turnover > limit+constraintTolerance {
+		return nil, fmt.Errorf("%w: turnover %.6f required by weight and sector limits exceeds max turnover %.6f",
+			ErrInfeasibleConstraint, turnover, limit)
+	}
+
+	result := &OptimizationResult{
+		Method:            in.Method,
+		Weights:           make(map[string]float64, n),
+		ExpectedReturns:   make(map[string]float64, n),
+		RiskContributions: make(map[string]float64, n),
+		Turnover:          turnover,
+		Shrinkage:         shrinkage,
+		Observations:      len(matrix),
+	}
+	sigmaW := matVec(cov, w)
+	variance := dot(w, sigmaW)
+	result.ExpectedReturn = dot(w, mu)
+	result.Volatility = math.Sqrt(math.Max(variance, 0))
+	for i, s := range symbols {
+		result.Weights[s] = w[i]
+		result.ExpectedReturns[s] = mu[i]
+		if variance > 0 {
+			result.RiskContributions[s] = w[i] * sigmaW[i] / variance
+		}
+	}
+	return result, nil
+}
+
+// ReturnMatrix 按日期对齐各标的日收益，只保留所有标的均有数据的日期；返回 观测数 × 标的数 矩阵
+func ReturnMatrix(returns []AssetReturn, symbols []string) [][]float64 {
+	index := make(map[string]int, len(symbols))
+	for i, s := range symbols {
+		index[s] = i
+	}
+	byDate := make(map[string][]float64)
+	counts := make(map[string]int)
+	dates := make([]string, 0)
+	for _, r := range returns {
+		i, ok := index[r.Symbol]
+		if !ok {
+			continue
+		}
+		key := r.ReturnDate.Format("2006-01-02")
+		row, ok := byDate[key]
+		if !ok {
+			row = make([]float64, len(symbols))
+			for j := range row {
+				row[j] = math.NaN()
+			}
+			byDate[key] = row
+			dates = append(dates, key)
+		}
+		if math.IsNaN(row[i]) {
+			counts[key]++
+		}
+		row[i], _ = r.Return.Float64()
+	}
+	sort.Strings(dates)
+	matrix := make([][]float64, 0, len(dates))
+	for _, d := range dates {
+		if counts[d] == len(symbols) {
+			matrix = append(matrix, byDate[d])
+		}
+	}
+	return matrix
+}
+
+// ShrunkCovariance 以 Ledoit-Wolf 方法把样本协方差向缩放单位阵收缩，返回年化协方差与收缩强度
+func ShrunkCovariance(matrix [][]float64) ([][]float64, float64) {
+	t := len(matrix)
+	n := len(matrix[0])
+	means := make([]float64, n)
+	for _, row := range matrix {
+		for j, v := range row {
+			means[j] += v / float64(t)
+		}
+	}
+	x := make([][]float64, t)
+	for i, row := range matrix {
+		x[i] = make([]float64, n)
+		for j, v := range row {
+			x[i][j] = v - means[j]
+		}
+	}
+
+	sample := newMatrix(n)
+	for _, row := range x {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				sample[i][j] += row[i] * row[j] / float64(t)
+			}
+		}
+	}
+	var m float64
+	for i := 0; i < n; i++ {
+		m += sample[i][i] / float64(n)
+	}
+	var d2 float64
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			diff := sample[i][j]
+			if i == j {
+				diff -= m
+			}
+			d2 += diff * diff / float64(n)
+		}
+	}
+	var b2 float64
+	for _, row := range x {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				diff := row[i]*row[j] - sample[i][j]
+				b2 += diff * diff / float64(n)
+			}
+		}
+	}
+	b2 /= float64(t) * float64(t)
+	shrinkage := 0.0
+	if d2 > 0 {
+		shrinkage = math.Min(b2, d2) / d2
+	}
+
+	cov := newMatrix(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			v := (1 - shrinkage) * sample[i][j]
+			if i == j {
+				v += shrinkage * m
+			}
+			cov[i][j] = v * tradingDaysPerYear
+		}
+	}
+	return cov, shrinkage
+}
+
+func annualizedMeans(matrix [][]float64) []float64 {
+	n := len(matrix[0])
+	mu := make([]float64, n)
+	for _, row := range matrix {
+		for j, v := range row {
+			mu[j] += v
+		}
+	}
+	for j := range mu {
+		mu[j] = mu[j] / float64(len(matrix)) * tradingDaysPerYear
+	}
+	return mu
+}
+
+// meanVariance 投影梯度法最大化 μ'w - λ/2 w'Σw
+func meanVariance(mu []float64, cov [][]float64, lambda float64, set *constraintSet) []float64 {
+	n := len(mu)
+	var trace float64
+	for i := 0; i < n; i++ {
+		trace += cov[i][i]
+	}
+	step := 1.0
+	if trace > 0 {
+		// 迹不小于最大特征值，保证步长收敛
+		step = 1 / (lambda * trace)
+	}
+	w := set.project(uniform(n))
+	for iter := 0; iter < 5000; iter++ {
+		grad := matVec(cov, w)
+		next := make([]float64, n)
+		for i := range w {
+			next[i] = w[i] + step*(mu[i]-lambda*grad[i])
+		}
+		next = set.project(next)
+		if maxAbsDiff(next, w) < 1e-10 {
+			return next
+		}
+		w = next
+	}
+	return w
+}
+
+// riskParity 迭代使各标的风险贡献相等
+func riskParity(cov [][]float64, set *constraintSet) []float64 {
+	n := len(cov)
+	w := make([]float64, n)
+	for i := range w {
+		if cov[i][i] > 0 {
+			w[i] = 1 / math.Sqrt(cov[i][i])
+		}
+	}
+	w = set.project(normalize(w))
+	for iter := 0; iter < 1000; iter++ {
+		sigmaW := matVec(cov, w)
+		variance := dot(w, sigmaW)
+		if variance <= 0 {
+			return w
+		}
+		target := variance / float64(n)
+		next := make([]float64, n)
+		for i := range w {
+			rc := w[i] * sigmaW[i]
+			if rc > 0 {
+				next[i] = w[i] * math.Sqrt(target/rc)
+			} else {
+				next[i] = w[i]
+			}
+		}
+		next = set.project(normalize(next))
+		if maxAbsDiff(next, w) < 1e-10 {
+			return next
+		}
+		w = next
+	}
+	return w
+}
+
+// blackLittermanReturns 以市场均衡收益为先验、融合观点得到后验预期收益：
+// μ = π + τΣP'(PτΣP' + Ω)^-1 (Q - Pπ)，Ω 取 diag(PτΣP')·(1-c)/c
+func blackLittermanReturns(cov [][]float64, marketWeights []float64, lambda float64, views []View, index map[string]int) ([]float64, error) {
+	n := len(cov)
+	pi := matVec(cov, marketWeights)
+	for i := range pi {
+		pi[i] *= lambda
+	}
+	if len(views) == 0 {
+		return pi, nil
+	}
+
+	k := len(views)
+	p := make([][]float64, k)
+	q := make([]float64, k)
+	for v, view := range views {
+		p[v] = make([]float64, n)
+		for symbol, weight := range view.Weights {
+			i, ok := index[symbol]
+			if !ok {
+				return nil, fmt.Errorf("%w: view references unknown asset %s", ErrInvalidOptimization, symbol)
+			}
+			p[v][i] = weight
+		}
+		q[v] = view.Return
+	}
+
+	tauSigma := newMatrix(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			tauSigma[i][j] = blackLittermanTau * cov[i][j]
+		}
+	}
+	// PτΣ (k×n)
+	pts := make([][]float64, k)
+	for v := 0; v < k; v++ {
+		pts[v] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				pts[v][j] += p[v][i] * tauSigma[i][j]
+			}
+		}
+	}
+	middle := newMatrix(k)
+	for a := 0; a < k; a++ {
+		for b := 0; b < k; b++ {
+			middle[a][b] = dot(pts[a], p[b])
+		}
+	}
+	for v, view := range views {
+		c := view.Confidence
+		if c <= 0 {
+			c = 0.5
+		}
+		c = math.Min(c, 0.99)
+		middle[v][v] += middle[v][v] * (1 - c) / c
+	}
+	inv, err := invert(middle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: views are linearly dependent", ErrInvalidOptimization)
+	}
+
+	diff := make([]float64, k)
+	for v := 0; v < k; v++ {
+		diff[v] = q[v] - dot(p[v], pi)
+	}
+	adj := matVec(inv, diff)
+	mu := make([]float64, n)
+	for i := 0; i < n; i++ {
+		mu[i] = pi[i]
+		for v := 0; v < k; v++ {
+			// (τΣP')_{i,v} = (PτΣ)_{v,i}（τΣ 对称）
+			mu[i] += pts[v][i] * adj[v]
+		}
+	}
+	return mu, nil
+}
+
+// constraintSet 权重上下限、行业上限与换手上限
+type constraintSet struct {
+	lo, hi  []float64
+	sectors []string
+	caps    map[string]float64
+	// current 当前权重，合计小于 1 的部分为现金
+	current []float64
+	// maxTurnover 单边换手上限，0 表示不限；turnoverRadius 为对应的 Σ|w-current| 上限（已扣除现金投入的换手）
+	maxTurnover    float64
+	turnoverRadius float64
+}
+
+func newConstraintSet(assets []OptimizationAsset, c OptimizationConstraints) (*constraintSet, error) {
+	defaultMax := c.DefaultMaxWeight
+	if defaultMax <= 0 {
+		defaultMax = 1
+	}
+	set := &constraintSet{
+		lo:      make([]float64, len(assets)),
+		hi:      make([]float64, len(assets)),
+		sectors: make([]string, len(assets)),
+		caps:    make(map[string]float64),
+		current: make([]float64, len(assets)),
+	}
+	for _, s := range c.SectorCaps {
+		limit, _ := s.TargetWeight.Float64()
+		set.caps[s.Sector] = limit
+	}
+	var sumLo, sumHi float64
+	sectorLo := make(map[string]float64)
+	sectorHi := make(map[string]float64)
+	for i, a := range assets {
+		set.lo[i], set.hi[i] = math.Max(c.DefaultMinWeight, 0), defaultMax
+		if a.MinWeight != nil {
+			set.lo[i] = math.Max(*a.MinWeight, 0)
+		}
+		if a.MaxWeight != nil {
+			set.hi[i] = *a.MaxWeight
+		}
+		if set.lo[i] > set.hi[i] {
+			return nil, fmt.Errorf("%w: min weight above max weight for %s", ErrInfeasibleConstraint, a.Symbol)
+		}
+		set.sectors[i] = a.Sector
+		set.current[i] = a.CurrentWeight
+		sumLo += set.lo[i]
+		sectorLo[a.Sector] += set.lo[i]
+		sectorHi[a.Sector] += set.hi[i]
+	}
+	for sector, hi := range sectorHi {
+		if limit, ok := set.caps[sector]; ok {
+			if sectorLo[sector] > limit+1e-12 {
+				return nil, fmt.Errorf("%w: minimum weights exceed sector cap of %s", ErrInfeasibleConstraint, sector)
+			}
+			hi = math.Min(hi, limit)
+		}
+		sumHi += hi
+	}
+	if sumLo > 1+1e-12 || sumHi < 1-1e-12 {
+		return nil, fmt.Errorf("%w: weight bounds cannot sum to 1", ErrInfeasibleConstraint)
+	}
+	if c.MaxTurnover > 0 {
+		// 目标权重合计为 1，现金与持仓合计的差额必然成交（现金侧与持仓侧各计一次），只有剩余额度可用于持仓之间调仓
+		cashLeg := math.Abs(1 - sum(set.current))
+		if cashLeg > c.MaxTurnover+constraintTolerance {
+			return nil, fmt.Errorf("%w: investing the cash balance needs turnover %.6f above max turnover %.6f",
+				ErrInfeasibleConstraint, cashLeg, c.MaxTurnover)
+		}
+		set.maxTurnover = c.MaxTurnover
+		set.turnoverRadius = math.Max(2*c.MaxTurnover-cashLeg, 0)
+	}
+	return set, nil
+}
+
+// project 在权重约束（上下限、行业上限、权重和为 1）与以当前权重为中心的换手 L1 球之间交替投影，
+// 两者有交集时收敛到交集内的点；没有交集时返回满足权重约束的点，由调用方按换手超限处理
+func (c *constraintSet) project(w []float64) []float64 {
+	out := c.projectWeights(w)
+	if c.maxTurnover <= 0 {
+		return out
+	}
+	for round := 0; round < turnoverProjectionRounds; round++ {
+		if halfTurnover(out, c.current) <= c.maxTurnover+constraintTolerance/10 {
+			return out
+		}
+		delta := make([]float64, len(out))
+		for i := range out {
+			delta[i] = out[i] - c.current[i]
+		}
+		delta = projectL1Ball(delta, c.turnoverRadius)
+		for i := range out {
+			out[i] = c.current[i] + delta[i]
+		}
+		out = c.projectWeights(out)
+	}
+	return out
+}
+
+// projectWeights 交替满足上下限、行业上限与权重和为 1
+func (c *constraintSet) projectWeights(w []float64) []float64 {
+	n := len(w)
+	out := make([]float64, n)
+	copy(out, w)
+	for iter := 0; iter < 200; iter++ {
+		for i := range out {
+			out[i] = math.Min(math.Max(out[i], c.lo[i]), c.hi[i])
+		}
+		// 行业超限时按各标的超出下限的部分等比例压缩
+		sectorSum, sectorExcess := c.sectorTotals(out)
+		for sector, limit := range c.caps {
+			over := sectorSum[sector] - limit
+			if over <= 1e-15 || sectorExcess[sector] <= 0 {
+				continue
+			}
+			ratio := math.Max(0, 1-over/sectorExcess[sector])
+			for i := range out {
+				if c.sectors[i] == sector {
+					out[i] = c.lo[i] + (out[i]-c.lo[i])*ratio
+				}
+			}
+		}
+
+		total := sum(out)
+		gap := 1 - total
+		if math.Abs(gap) < 1e-12 {
+			return out
+		}
+		sectorSum, _ = c.sectorTotals(out)
+		room := make([]float64, n)
+		var totalRoom float64
+		for i := range out {
+			if gap > 0 {
+				room[i] = c.hi[i] - out[i]
+				if limit, ok := c.caps[c.sectors[i]]; ok {
+					room[i] = math.Min(room[i], math.Max(limit-sectorSum[c.sectors[i]], 0))
+				}
+			} else {
+				room[i] = out[i] - c.lo[i]
+			}
+			room[i] = math.Max(room[i], 0)
+			totalRoom += room[i]
+		}
+		if totalRoom <= 0 {
+			return out
+		}
+		for i := range out {
+			out[i] += gap * room[i] / totalRoom
+		}
+	}
+	return out
+}
+
+// projectL1Ball 把向量欧氏投影到半径为 radius 的 L1 球（Duchi 等 2008 的排序阈值法）
+func projectL1Ball(v []float64, radius float64) []float64 {
+	abs := make([]float64, len(v))
+	var norm float64
+	for i, x := range v {
+		abs[i] = math.Abs(x)
+		norm += abs[i]
+	}
+	if norm <= radius {
+		return v
+	}
+	sorted := append([]float64(nil), abs...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+	var cum, theta float64
+	for j, u := range sorted {
+		cum += u
+		if t := (cum - radius) / float64(j+1); u > t {
+			theta = t
+		}
+	}
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Copysign(math.Max(abs[i]-theta, 0), x)
+	}
+	return out
+}
+
+func (c *constraintSet) sectorTotals(w []float64) (map[string]float64, map[string]float64) {
+	totals := make(map[string]float64)
+	excess := make(map[string]float64)
+	for i, v := range w {
+		totals[c.sectors[i]] += v
+		excess[c.sectors[i]] += v - c.lo[i]
+	}
+	return totals, excess
+}
+
+// ProposedTrade 调仓交易
+type ProposedTrade struct {
+	Symbol          string          `json:"symbol"`
+	Side            string          `json:"side"`
+	Quantity        decimal.Decimal `json:"quantity"`
+	Price           decimal.Decimal `json:"price"`
+	Notional        decimal.Decimal `json:"notional"`
+	CurrentQuantity decimal.Decimal `json:"current_quantity"`
+	TargetQuantity  decimal.Decimal `json:"target_quantity"`
+	CurrentWeight   float64         `json:"current_weight"`
+	TargetWeight    float64         `json:"target_weight"`
+	// AlgoOrderID、SubmitError 为下发执行服务后的结果
+	AlgoOrderID string `json:"algo_order_id,omitempty"`
+	SubmitError string `json:"submit_error,omitempty"`
+}
+
+// TradeListInput 生成交易清单的输入
+type TradeListInput struct {
+	PortfolioValue decimal.Decimal
+	Weights        map[string]float64
+	Holdings       map[string]decimal.Decimal
+	Prices         map[string]decimal.Decimal
+	// LotSizes 最小交易单位，缺失或非正时只保留 8 位小数
+	LotSizes map[string]decimal.Decimal
+}
+
+// BuildTradeList 把目标权重换算为按最小交易单位取整的买卖数量，卖出在前、买入在后
+func BuildTradeList(in TradeListInput) ([]ProposedTrade, error) {
+	if !in.PortfolioValue.IsPositive() {
+		return nil, fmt.Errorf("%w: portfolio value must be positive", ErrInvalidOptimization)
+	}
+	symbols := make([]string, 0, len(in.Weights))
+	for s := range in.Weights {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+
+	trades := make([]ProposedTrade, 0, len(symbols))
+	for _, symbol := range symbols {
+		price := in.Prices[symbol]
+		if !price.IsPositive() {
+			return nil, fmt.Errorf("%w: no price for %s", ErrInvalidOptimization, symbol)
+		}
+		current := in.Holdings[symbol]
+		weight := in.Weights[symbol]
+		target := in.PortfolioValue.Mul(decimal.NewFromFloat(weight)).Div(price)
+		delta := target.Sub(current)
+		if lot := in.LotSizes[symbol]; lot.IsPositive() {
+			delta = delta.Div(lot).Round(0).Mul(lot)
+		} else {
+			delta = delta.Round(unlottedQuantityScale)
+		}
+		// 只做多：卖出不超过现有持仓
+		if delta.IsNegative() && delta.Abs().GreaterThan(current) {
+			delta = current.Neg()
+		}
+		if delta.IsZero() {
+			continue
+		}
+		side := "BUY"
+		if delta.IsNegative() {
+			side = "SELL"
+		}
+		currentWeight, _ := current.Mul(price).Div(in.PortfolioValue).Float64()
+		trades = append(trades, ProposedTrade{
+			Symbol:          symbol,
+			Side:            side,
+			Quantity:        delta.Abs(),
+			Price:           price,
+			Notional:        delta.Abs().Mul(price),
+			CurrentQuantity: current,
+			TargetQuantity:  current.Add(delta),
+			CurrentWeight:   currentWeight,
+			TargetWeight:    weight,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Side == "SELL" && trades[j].Side == "BUY" })
+	return trades, nil
+}
+
+func newMatrix(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+	}
+	return m
+}
+
+func matVec(m [][]float64, v []float64) []float64 {
+	out := make([]float64, len(m))
+	for i, row := range m {
+		out[i] = dot(row, v)
+	}
+	return out
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func sum(v []float64) float64 {
+	var s float64
+	for _, x := range v {
+		s += x
+	}
+	return s
+}
+
+func uniform(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1 / float64(n)
+	}
+	return w
+}
+
+// normalize 归一化为和为 1，全为 0 时返回等权
+func normalize(w []float64) []float64 {
+	total := sum(w)
+	if total <= 0 {
+		return uniform(len(w))
+	}
+	out := make([]float64, len(w))
+	for i, v := range w {
+		out[i] = v / total
+	}
+	return out
+}
+
+// halfTurnover 单边换手率，含现金变动一侧：(Σ|w-current| + |Σw-Σcurrent|)/2
+func halfTurnover(w, current []float64) float64 {
+	var t float64
+	for i := range w {
+		t += math.Abs(w[i] - current[i])
+	}
+	return (t + math.Abs(sum(w)-sum(current))) / 2
+}
+
+func maxAbsDiff(a, b []float64) float64 {
+	var d float64
+	for i := range a {
+		d = math.Max(d, math.Abs(a[i]-b[i]))
+	}
+	return d
+}
+
+// invert 高斯-约当消元求逆
+func invert(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	a := make([][]float64, n)
+	for i := range m {
+		a[i] = make([]float64, 2*n)
+		copy(a[i], m[i])
+		a[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-14 {
+			return nil, errors.New("matrix is singular")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		p := a[col][col]
+		for j := range a[col] {
+			a[col][j] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			f := a[r][col]
+			for j := range a[r] {
+				a[r][j] -= f * a[col][j]
+			}
+		}
+	}
+	inv := make([][]float64, n)
+	for i := range a {
+		inv[i] = a[i][n:]
+	}
+	return inv, nil
+}
@@ -0,0 +1,377 @@
+// 变更说明：增加组合业绩度量：按外部现金流调整的日度时间加权收益（TWR）、资金加权收益（MWR/IRR），
+// 以及相对基准指数的跟踪误差、信息比率、Beta、Alpha 与最大回撤；收益序列可按任意区间链式复合并直接用于 Brinson 归因。
+// 假设：现金流视为发生在当日估值之前（当日可投资），入金为正、出金为负；年化按 252 个交易日，
+// 无风险利率按 0；MWR 以首个快照市值为初始投入，按自然日（365 天）折算年化。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	benchmark "github.com/wyfcoding/financialtrading/internal/benchmark/domain"
+	"gorm.io/gorm"
+)
+
+// tradingDaysPerYear 收益与波动年化使用的交易日数
+const tradingDaysPerYear = 252
+
+// ErrInsufficientHistory 区间内快照不足以计算收益
+var ErrInsufficientHistory = errors.New("insufficient snapshot history")
+
+// CashFlow 组合外部现金流（入金为正、出金为负）
+type CashFlow struct {
+	gorm.Model
+	Reference string          `gorm:"column:reference;type:varchar(64);uniqueIndex;not null" json:"reference"`
+	UserID    string          `gorm:"column:user_id;type:varchar(32);index:idx_cash_flow_user_date,priority:1;not null" json:"user_id"`
+	FlowDate  time.Time       `gorm:"column:flow_date;type:date;index:idx_cash_flow_user_date,priority:2;not null" json:"flow_date"`
+	Amount    decimal.Decimal `gorm:"column:amount;type:decimal(20,4);not null" json:"amount"`
+	Currency  string          `gorm:"column:currency;type:varchar(3);not null" json:"currency"`
+}
+
+func (CashFlow) TableName() string { return "portfolio_cash_flows" }
+
+// CashFlowRepository 外部现金流仓储
+type CashFlowRepository interface {
+	// SaveCashFlow 按 Reference 幂等写入，重复时返回 false
+	SaveCashFlow(ctx context.Context, flow *CashFlow) (bool, error)
+	// ListCashFlows 按日期升序返回 [start, end] 内的现金流
+	ListCashFlows(ctx context.Context, userID string, start, end time.Time) ([]CashFlow, error)
+}
+
+// BenchmarkSource 基准指数及其点位
+type BenchmarkSource interface {
+	GetIndex(ctx context.Context, symbol string) (*benchmark.BenchmarkIndex, error)
+	GetLevels(ctx context.Context, symbol string, start, end time.Time) ([]benchmark.IndexLevel, error)
+}
+
+// DailyReturn 单日收益
+type DailyReturn struct {
+	Date   time.Time `json:"date"`
+	Return float64   `json:"return"`
+}
+
+// ReturnSeries 按日期升序排列的日收益序列
+type ReturnSeries []DailyReturn
+
+// Link 链式复合 [from, to] 内的日收益，零值边界表示不限
+func (s ReturnSeries) Link(from, to time.Time) float64 {
+	growth := 1.0
+	for _, r := range s {
+		if (!from.IsZero() && r.Date.Before(from)) || (!to.IsZero() && r.Date.After(to)) {
+			continue
+		}
+		growth *= 1 + r.Return
+	}
+	return growth - 1
+}
+
+// MaxDrawdown 以复合净值计算最大回撤（正数）
+func (s ReturnSeries) MaxDrawdown() float64 {
+	nav, peak, maxDD := 1.0, 1.0, 0.0
+	for _, r := range s {
+		nav *= 1 + r.Return
+		peak = math.Max(peak, nav)
+		if peak > 0 {
+			maxDD = math.Max(maxDD, (peak-nav)/peak)
+		}
+	}
+	return maxDD
+}
+
+// Annualize 按交易日数把区间收益折算为年化收益
+func (s ReturnSeries) Annualize(periodReturn float64) float64 {
+	if len(s) == 0 || periodReturn <= -1 {
+		return 0
+	}
+	return math.Pow(1+periodReturn, float64(tradingDaysPerYear)/float64(len(s))) - 1
+}
+
+// PeriodGranularity 链式复合的区间粒度
+type PeriodGranularity string
+
+const (
+	PeriodMonth   PeriodGranularity = "MONTH"
+	PeriodQuarter PeriodGranularity = "QUARTER"
+	PeriodYear    PeriodGranularity = "YEAR"
+)
+
+// PeriodReturn 按区间链式复合后的收益
+type PeriodReturn struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Return float64   `json:"return"`
+}
+
+// LinkByPeriod 按月、季或年对日收益链式复合，区间起止取区间内首末个有收益的日期
+func (s ReturnSeries) LinkByPeriod(granularity PeriodGranularity) []PeriodReturn {
+	periods := make([]PeriodReturn, 0)
+	for _, r := range s {
+		key := periodKey(r.Date, granularity)
+		if n := len(periods); n > 0 && periods[n-1].Period == key {
+			periods[n-1].End = r.Date
+			periods[n-1].Return = (1+periods[n-1].Return)*(1+r.Return) - 1
+			continue
+		}
+		periods = append(periods, PeriodReturn{Period: key, Start: r.Date, End: r.Date, Return: r.Return})
+	}
+	return periods
+}
+
+func periodKey(t time.Time, granularity PeriodGranularity) string {
+	switch granularity {
+	case PeriodYear:
+		return t.Format("2006")
+	case PeriodQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())+2)/3)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// TimeWeightedReturns 由日终快照与外部现金流计算日度时间加权收益。
+// 相邻两个快照之间的现金流计入后一期，视为期初发生：r = (V1 - V0 - CF) / (V0 + CF)。
+func TimeWeightedReturns(snapshots []PortfolioSnapshot, flows []CashFlow) ReturnSeries {
+	points := dedupeSnapshots(snapshots)
+	if len(points) < 2 {
+		return ReturnSeries{}
+	}
+	series := make(ReturnSeries, 0, len(points)-1)
+	flows = append([]CashFlow(nil), flows...)
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].FlowDate.Before(flows[j].FlowDate) })
+	fi := 0
+	// 首个快照之前（含当日）的现金流已体现在期初市值中
+	for fi < len(flows) && !dateOf(flows[fi].FlowDate).After(points[0].date) {
+		fi++
+	}
+	for i := 1; i < len(points); i++ {
+		var cf float64
+		for fi < len(flows) && !dateOf(flows[fi].FlowDate).After(points[i].date) {
+			amount, _ := flows[fi].Amount.Float64()
+			cf += amount
+			fi++
+		}
+		base := points[i-1].value + cf
+		var r float64
+		if base > 0 {
+			r = (points[i].value - base) / base
+		}
+		series = append(series, DailyReturn{Date: points[i].date, Return: r})
+	}
+	return series
+}
+
+// MoneyWeightedReturn 以首个快照市值为初始投入、末个快照市值为期末价值，求解年化内部收益率；
+// 返回年化 IRR 与区间 IRR，无法求解时 ok 为 false
+func MoneyWeightedReturn(snapshots []PortfolioSnapshot, flows []CashFlow) (annualized, period float64, ok bool) {
+	points := dedupeSnapshots(snapshots)
+	if len(points) < 2 {
+		return 0, 0, false
+	}
+	start, end := points[0], points[len(points)-1]
+	years := end.date.Sub(start.date).Hours() / 24 / 365
+	if years <= 0 {
+		return 0, 0, false
+	}
+
+	type flowPoint struct {
+		years  float64
+		amount float64
+	}
+	inner := make([]flowPoint, 0, len(flows))
+	for _, f := range flows {
+		d := dateOf(f.FlowDate)
+		if !d.After(start.date) || d.After(end.date) {
+			continue
+		}
+		amount, _ := f.Amount.Float64()
+		inner = append(inner, flowPoint{years: end.date.Sub(d).Hours() / 24 / 365, amount: amount})
+	}
+
+	// 期末终值差：投入按 r 复利到期末后与期末市值之差
+	npv := func(r float64) float64 {
+		v := start.value * math.Pow(1+r, years)
+		for _, f := range inner {
+			v += f.amount * math.Pow(1+r, f.years)
+		}
+		return v - end.value
+	}
+
+	lo, hi := -0.9999, 1.0
+	fLo := npv(lo)
+	for npv(hi)*fLo > 0 && hi < 1e6 {
+		hi *= 2
+	}
+	if npv(hi)*fLo > 0 {
+		return 0, 0, false
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12; i++ {
+		mid := (lo + hi) / 2
+		if npv(mid)*fLo > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	annualized = (lo + hi) / 2
+	return annualized, math.Pow(1+annualized, years) - 1, true
+}
+
+// BenchmarkReturns 由指数点位计算日收益
+func BenchmarkReturns(levels []benchmark.IndexLevel) ReturnSeries {
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].LevelDate.Before(levels[j].LevelDate) })
+	series := make(ReturnSeries, 0, len(levels))
+	for i := 1; i < len(levels); i++ {
+		prev, _ := levels[i-1].Close.Float64()
+		cur, _ := levels[i].Close.Float64()
+		if prev <= 0 {
+			continue
+		}
+		series = append(series, DailyReturn{Date: dateOf(levels[i].LevelDate), Return: cur/prev - 1})
+	}
+	return series
+}
+
+// AlignTo 把日收益按估值日期重新分段链式复合：dates[0] 为基期，第 i 段覆盖 (dates[i-1], dates[i]]，
+// 使基准收益与组合快照的估值区间一一对应
+func (s ReturnSeries) AlignTo(dates []time.Time) ReturnSeries {
+	if len(dates) < 2 {
+		return ReturnSeries{}
+	}
+	aligned := make(ReturnSeries, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		from, to := dates[i-1], dates[i]
+		growth := 1.0
+		for _, r := range s {
+			if r.Date.After(from) && !r.Date.After(to) {
+				growth *= 1 + r.Return
+			}
+		}
+		aligned = append(aligned, DailyReturn{Date: to, Return: growth - 1})
+	}
+	return aligned
+}
+
+// ValuationDates 返回去重后按升序排列的快照日期
+func ValuationDates(snapshots []PortfolioSnapshot) []time.Time {
+	points := dedupeSnapshots(snapshots)
+	dates := make([]time.Time, len(points))
+	for i, p := range points {
+		dates[i] = p.date
+	}
+	return dates
+}
+
+// RelativeAnalytics 相对基准的风险调整指标（均已年化）
+type RelativeAnalytics struct {
+	Observations     int     `json:"observations"`
+	TrackingError    float64 `json:"tracking_error"`
+	InformationRatio float64 `json:"information_ratio"`
+	Beta             float64 `json:"beta"`
+	Alpha            float64 `json:"alpha"`
+}
+
+// CompareToBenchmark 按日期对齐组合与基准日收益，计算跟踪误差、信息比率、Beta 与 Jensen Alpha
+func CompareToBenchmark(portfolio, bench ReturnSeries) RelativeAnalytics {
+	byDate := make(map[string]float64, len(bench))
+	for _, b := range bench {
+		byDate[b.Date.Format("2006-01-02")] = b.Return
+	}
+	var rp, rb []float64
+	for _, p := range portfolio {
+		if b, ok := byDate[p.Date.Format("2006-01-02")]; ok {
+			rp = append(rp, p.Return)
+			rb = append(rb, b)
+		}
+	}
+	result := RelativeAnalytics{Observations: len(rp)}
+	if len(rp) < 2 {
+		return result
+	}
+
+	active := make([]float64, len(rp))
+	for i := range rp {
+		active[i] = rp[i] - rb[i]
+	}
+	meanActive, stdActive := meanStd(active)
+	result.TrackingError = stdActive * math.Sqrt(tradingDaysPerYear)
+	if result.TrackingError > 0 {
+		result.InformationRatio = meanActive * tradingDaysPerYear / result.TrackingError
+	}
+
+	meanP, _ := meanStd(rp)
+	meanB, stdB := meanStd(rb)
+	if stdB > 0 {
+		var cov float64
+		for i := range rp {
+			cov += (rp[i] - meanP) * (rb[i] - meanB)
+		}
+		cov /= float64(len(rp) - 1)
+		result.Beta = cov / (stdB * stdB)
+	}
+	result.Alpha = (meanP - result.Beta*meanB) * tradingDaysPerYear
+	return result
+}
+
+// meanStd 均值与样本标准差
+func meanStd(values []float64) (mean, std float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)-1))
+}
+
+// LinkedBrinsonAttribution 将各资产类别的组合与基准日收益在 [from, to] 内链式复合后做 Brinson 归因，按资产类别排序
+func LinkedBrinsonAttribution(portfolioWeights, benchmarkWeights map[string]decimal.Decimal, portfolioSeries, benchmarkSeries map[string]ReturnSeries, from, to time.Time) []BrinsonAttribution {
+	portfolioReturns := make(map[string]decimal.Decimal, len(portfolioSeries))
+	for class, series := range portfolioSeries {
+		portfolioReturns[class] = decimal.NewFromFloat(series.Link(from, to))
+	}
+	benchmarkReturns := make(map[string]decimal.Decimal, len(benchmarkSeries))
+	for class, series := range benchmarkSeries {
+		benchmarkReturns[class] = decimal.NewFromFloat(series.Link(from, to))
+	}
+	attributions := CalculateBrinsonAttribution(portfolioWeights, benchmarkWeights, portfolioReturns, benchmarkReturns)
+	sort.Slice(attributions, func(i, j int) bool { return attributions[i].AssetClass < attributions[j].AssetClass })
+	return attributions
+}
+
+type valuationPoint struct {
+	date  time.Time
+	value float64
+}
+
+// dedupeSnapshots 按日期升序排列快照，同一日期只保留最后一条
+func dedupeSnapshots(snapshots []PortfolioSnapshot) []valuationPoint {
+	sorted := make([]PortfolioSnapshot, len(snapshots))
+	copy(sorted, snapshots)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SnapshotDate.Before(sorted[j].SnapshotDate) })
+	points := make([]valuationPoint, 0, len(sorted))
+	for _, s := range sorted {
+		value, _ := s.TotalValue.Float64()
+		d := dateOf(s.SnapshotDate)
+		if n := len(points); n > 0 && points[n-1].date.Equal(d) {
+			points[n-1].value = value
+			continue
+		}
+		points = append(points, valuationPoint{date: d, value: value})
+	}
+	return points
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	"google.golang.org/grpc"
+)
+
+// AccountHierarchyClient 经账户服务解析主子账户层级
+type AccountHierarchyClient struct {
+	client accountv1.AccountServiceClient
+}
+
+// NewAccountHierarchyClient 创建层级解析客户端
+func NewAccountHierarchyClient(conn grpc.ClientConnInterface) *AccountHierarchyClient {
+	return &AccountHierarchyClient{client: accountv1.NewAccountServiceClient(conn)}
+}
+
+func (c *AccountHierarchyClient) ResolveMembers(ctx context.Context, userID string) (string, []string, error) {
+	resp, err := c.client.GetAccountHierarchy(ctx, &accountv1.GetAccountHierarchyRequest{UserId: userID})
+	if err != nil {
+		return "", nil, err
+	}
+	ids := make([]string, 0, len(resp.SubAccounts)+1)
+	ids = append(ids, resp.MasterUserId)
+	for _, sub := range resp.SubAccounts {
+		ids = append(ids, sub.SubUserId)
+	}
+	return resp.MasterUserId, ids, nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"google.golang.org/grpc"
+)
+
+// AccountStateClient 经账户服务取可用现金、经持仓服务取计税批次
+type AccountStateClient struct {
+	account  accountv1.AccountServiceClient
+	position positionv1.PositionServiceClient
+}
+
+// NewAccountStateClient 创建账户现状客户端
+func NewAccountStateClient(accountConn, positionConn grpc.ClientConnInterface) *AccountStateClient {
+	return &AccountStateClient{
+		account:  accountv1.NewAccountServiceClient(accountConn),
+		position: positionv1.NewPositionServiceClient(positionConn),
+	}
+}
+
+func (c *AccountStateClient) Cash(ctx context.Context, userID, currency string) (decimal.Decimal, error) {
+	resp, err := c.account.GetBalance(ctx, &accountv1.GetBalanceRequest{UserId: userID, Currency: currency})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	cash, err := decimal.NewFromString(resp.AvailableBalance)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid available balance %q for %s: %w", resp.AvailableBalance, userID, err)
+	}
+	return cash, nil
+}
+
+func (c *AccountStateClient) TaxLots(ctx context.Context, userID, symbol string) ([]domain.TaxLot, error) {
+	resp, err := c.position.GetTaxLots(ctx, &positionv1.GetTaxLotsRequest{UserId: userID, Symbol: symbol})
+	if err != nil {
+		return nil, err
+	}
+	lots := make([]domain.TaxLot, 0, len(resp.Lots))
+	for _, l := range resp.Lots {
+		qty, err := decimal.NewFromString(l.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lot quantity %q: %w", l.Quantity, err)
+		}
+		basis, err := decimal.NewFromString(l.TaxBasis)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lot tax basis %q: %w", l.TaxBasis, err)
+		}
+		lots = append(lots, domain.TaxLot{LotID: uint(l.LotId), Quantity: qty, TaxBasis: basis, Term: l.Term})
+	}
+	return lots, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dtm-labs/client/dtmgrpc/dtmgimp"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"google.golang.org/grpc"
+)
+
+// AllocationPosterClient 通过账户与持仓服务的 Saga 接口过账大宗单回分。
+// BookingID 同时作为业务幂等键与 DTM 全局事务 ID 透传，重试不会重复过账
+type AllocationPosterClient struct {
+	account  accountv1.AccountServiceClient
+	position positionv1.PositionServiceClient
+}
+
+// NewAllocationPosterClient 创建回分过账客户端
+func NewAllocationPosterClient(accountConn, positionConn grpc.ClientConnInterface) *AllocationPosterClient {
+	return &AllocationPosterClient{
+		account:  accountv1.NewAccountServiceClient(accountConn),
+		position: positionv1.NewPositionServiceClient(positionConn),
+	}
+}
+
+// Post 买入先扣款再加仓，卖出先减仓再入账
+func (c *AllocationPosterClient) Post(ctx context.Context, b *domain.AllocationBooking) error {
+	ctx = dtmgimp.TransInfo2Ctx(ctx, b.BookingID, "saga", "01", "action", "")
+	cash := &accountv1.SagaAccountRequest{
+		UserId:   b.UserID,
+		Currency: b.Currency,
+		Amount:   b.Notional().String(),
+		TradeId:  b.BookingID,
+	}
+	position := &positionv1.SagaPositionRequest{
+		UserId:   b.UserID,
+		Symbol:   b.Symbol,
+		Quantity: b.Quantity.String(),
+		Price:    b.Price.String(),
+		TradeId:  b.BookingID,
+	}
+	switch b.Side {
+	case "BUY":
+		if _, err := c.account.SagaSubBalance(ctx, cash); err != nil {
+			return fmt.Errorf("failed to debit %s for %s: %w", b.UserID, b.BookingID, err)
+		}
+		if _, err := c.position.SagaAddPosition(ctx, position); err != nil {
+			return fmt.Errorf("failed to add position of %s for %s: %w", b.UserID, b.BookingID, err)
+		}
+	case "SELL":
+		if _, err := c.position.SagaSubPosition(ctx, position); err != nil {
+			return fmt.Errorf("failed to reduce position of %s for %s: %w", b.UserID, b.BookingID, err)
+		}
+		if _, err := c.account.SagaAddBalance(ctx, cash); err != nil {
+			return fmt.Errorf("failed to credit %s for %s: %w", b.UserID, b.BookingID, err)
+		}
+	default:
+		return fmt.Errorf("unsupported allocation side %q", b.Side)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	executionv1 "github.com/wyfcoding/financialtrading/go-api/execution/v1"
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"google.golang.org/grpc"
+)
+
+const holdingsPageSize = 200
+
+// PositionHoldingsClient 经持仓服务读取用户多头持仓数量
+type PositionHoldingsClient struct {
+	client positionv1.PositionServiceClient
+}
+
+// NewPositionHoldingsClient 创建持仓读取客户端
+func NewPositionHoldingsClient(conn grpc.ClientConnInterface) *PositionHoldingsClient {
+	return &PositionHoldingsClient{client: positionv1.NewPositionServiceClient(conn)}
+}
+
+// Holdings 分页读取全部持仓，空头与空仓不计入（优化器只做多）
+func (c *PositionHoldingsClient) Holdings(ctx context.Context, userID string) (map[string]decimal.Decimal, error) {
+	holdings := make(map[string]decimal.Decimal)
+	for page := int32(1); ; page++ {
+		resp, err := c.client.GetPositions(ctx, &positionv1.GetPositionsRequest{UserId: userID, PageSize: holdingsPageSize, Page: page})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range resp.Positions {
+			qty, err := decimal.NewFromString(p.Quantity)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quantity %q for %s: %w", p.Quantity, p.Symbol, err)
+			}
+			if qty.IsPositive() {
+				holdings[p.Symbol] = holdings[p.Symbol].Add(qty)
+			}
+		}
+		if len(resp.Positions) < holdingsPageSize || int64(page)*holdingsPageSize >= resp.Total {
+			return holdings, nil
+		}
+	}
+}
+
+// OptimizerMarketDataClient 经行情服务取最新价与日线、经参考数据服务取最小交易单位
+type OptimizerMarketDataClient struct {
+	marketData    marketdatav1.MarketDataServiceClient
+	referenceData referencedatav1.ReferenceDataServiceClient
+}
+
+// NewOptimizerMarketDataClient 创建优化器行情客户端；referenceConn 为空时不按最小交易单位取整
+func NewOptimizerMarketDataClient(marketConn, referenceConn grpc.ClientConnInterface) *OptimizerMarketDataClient {
+	c := &OptimizerMarketDataClient{marketData: marketdatav1.NewMarketDataServiceClient(marketConn)}
+	if referenceConn != nil {
+		c.referenceData = referencedatav1.NewReferenceDataServiceClient(referenceConn)
+	}
+	return c
+}
+
+// LatestPrice 取最新成交价，缺失时取买卖中间价
+func (c *OptimizerMarketDataClient) LatestPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	quote, err := c.marketData.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: symbol})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	price := quote.LastPrice
+	if price <= 0 && quote.BidPrice > 0 && quote.AskPrice > 0 {
+		price = (quote.BidPrice + quote.AskPrice) / 2
+	}
+	if price <= 0 {
+		return decimal.Zero, fmt.Errorf("no price for %s", symbol)
+	}
+	return decimal.NewFromFloat(price), nil
+}
+
+func (c *OptimizerMarketDataClient) LotSize(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	if c.referenceData == nil {
+		return decimal.Zero, nil
+	}
+	resp, err := c.referenceData.GetInstrument(ctx, &referencedatav1.GetInstrumentRequest{Symbol: symbol})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromFloat(resp.GetInstrument().GetLotSize()), nil
+}
+
+func (c *OptimizerMarketDataClient) DailyCloses(ctx context.Context, symbol string, limit int) ([]domain.DailyClose, error) {
+	resp, err := c.marketData.GetKlines(ctx, &marketdatav1.GetKlinesRequest{Symbol: symbol, Interval: "1d", Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	closes := make([]domain.DailyClose, 0, len(resp.Klines))
+	for _, k := range resp.Klines {
+		if k.Close <= 0 {
+			continue
+		}
+		closes = append(closes, domain.DailyClose{
+			Date:  time.UnixMilli(k.OpenTime).UTC().Truncate(24 * time.Hour),
+			Close: decimal.NewFromFloat(k.Close),
+		})
+	}
+	sort.Slice(closes, func(i, j int) bool { return closes[i].Date.Before(closes[j].Date) })
+	return closes, nil
+}
+
+// ExecutionAlgoClient 经执行服务提交算法单
+type ExecutionAlgoClient struct {
+	client executionv1.ExecutionServiceClient
+}
+
+// NewExecutionAlgoClient 创建算法单下发客户端
+func NewExecutionAlgoClient(conn grpc.ClientConnInterface) *ExecutionAlgoClient {
+	return &ExecutionAlgoClient{client: executionv1.NewExecutionServiceClient(conn)}
+}
+
+func (c *ExecutionAlgoClient) SubmitAlgoOrder(ctx context.Context, order domain.AlgoOrder) (string, error) {
+	resp, err := c.client.SubmitAlgoOrder(ctx, &executionv1.SubmitAlgoOrderRequest{
+		UserId:        order.UserID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		TotalQuantity: order.Quantity.String(),
+		AlgoType:      order.AlgoType,
+		StartTime:     order.Start.Unix(),
+		EndTime:       order.End.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.AlgoId, nil
+}
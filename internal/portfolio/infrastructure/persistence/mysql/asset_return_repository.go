@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"gorm.io/gorm/clause"
+)
+
+func (r *PortfolioRepo) SaveReturns(ctx context.Context, returns []domain.AssetReturn) error {
+	if len(returns) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "return_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"daily_return", "updated_at"}),
+	}).CreateInBatches(returns, 500).Error
+}
+
+func (r *PortfolioRepo) ListReturns(ctx context.Context, symbols []string, start, end time.Time) ([]domain.AssetReturn, error) {
+	var returns []domain.AssetReturn
+	if len(symbols) == 0 {
+		return returns, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("symbol IN ? AND return_date >= ? AND return_date <= ?", symbols, start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Order("return_date asc, symbol asc").
+		Find(&returns).Error
+	return returns, err
+}
@@ -0,0 +1,26 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"gorm.io/gorm/clause"
+)
+
+func (r *PortfolioRepo) SaveCashFlow(ctx context.Context, flow *domain.CashFlow) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(flow)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *PortfolioRepo) ListCashFlows(ctx context.Context, userID string, start, end time.Time) ([]domain.CashFlow, error) {
+	var flows []domain.CashFlow
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND flow_date >= ? AND flow_date <= ?", userID, start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Order("flow_date asc, id asc").
+		Find(&flows).Error
+	return flows, err
+}
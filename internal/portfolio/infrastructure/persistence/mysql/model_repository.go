@@ -0,0 +1,229 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ModelPortfolioRepo struct {
+	db *gorm.DB
+}
+
+func NewModelPortfolioRepo(db *gorm.DB) *ModelPortfolioRepo {
+	return &ModelPortfolioRepo{db: db}
+}
+
+func (r *ModelPortfolioRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(contextx.WithTx(ctx, tx))
+	})
+}
+
+func (r *ModelPortfolioRepo) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db.WithContext(ctx)
+}
+
+// SaveModel 按 ModelID 新建或更新模型，成分整体替换
+func (r *ModelPortfolioRepo) SaveModel(ctx context.Context, model *domain.ModelPortfolio) error {
+	return r.WithTx(ctx, func(ctx context.Context) error {
+		db := r.getDB(ctx)
+		var existing domain.ModelPortfolio
+		err := db.Where("model_id = ?", model.ModelID).First(&existing).Error
+		switch {
+		case err == nil:
+			model.ID, model.CreatedAt = existing.ID, existing.CreatedAt
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if err := db.Unscoped().Where("model_id = ?", model.ModelID).Delete(&domain.ModelComponent{}).Error; err != nil {
+			return err
+		}
+		for i := range model.Components {
+			model.Components[i].ID = 0
+			model.Components[i].ModelID = model.ModelID
+		}
+		return db.Session(&gorm.Session{FullSaveAssociations: true}).Save(model).Error
+	})
+}
+
+func (r *ModelPortfolioRepo) GetModel(ctx context.Context, modelID string) (*domain.ModelPortfolio, error) {
+	var model domain.ModelPortfolio
+	err := r.getDB(ctx).Preload("Components").Where("model_id = ?", modelID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// SaveAssignment 按 UserID 覆盖账户的模型绑定
+func (r *ModelPortfolioRepo) SaveAssignment(ctx context.Context, assignment *domain.ModelAssignment) error {
+	return r.getDB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"model_id", "restricted_symbols", "min_cash", "tax_aware", "currency", "updated_at", "deleted_at"}),
+	}).Create(assignment).Error
+}
+
+func (r *ModelPortfolioRepo) ListAssignments(ctx context.Context, modelID string) ([]*domain.ModelAssignment, error) {
+	var assignments []*domain.ModelAssignment
+	err := r.getDB(ctx).Where("model_id = ?", modelID).Order("user_id asc").Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *ModelPortfolioRepo) SaveProposals(ctx context.Context, proposals []*domain.RebalanceProposal) error {
+	if len(proposals) == 0 {
+		return nil
+	}
+	return r.getDB(ctx).Create(proposals).Error
+}
+
+func (r *ModelPortfolioRepo) GetProposal(ctx context.Context, proposalID string) (*domain.RebalanceProposal, error) {
+	var proposal domain.RebalanceProposal
+	err := r.getDB(ctx).Preload("Lines").Where("proposal_id = ?", proposalID).First(&proposal).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// ListProposals 列出批次内的建议，status 为空时不按状态过滤
+func (r *ModelPortfolioRepo) ListProposals(ctx context.Context, batchID string, status domain.ProposalStatus) ([]*domain.RebalanceProposal, error) {
+	db := r.getDB(ctx).Preload("Lines").Where("batch_id = ?", batchID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var proposals []*domain.RebalanceProposal
+	err := db.Order("id asc").Find(&proposals).Error
+	return proposals, err
+}
+
+// UpdateProposal 更新建议状态并回写交易行的 BlockID
+func (r *ModelPortfolioRepo) UpdateProposal(ctx context.Context, proposal *domain.RebalanceProposal) error {
+	db := r.getDB(ctx)
+	if err := db.Model(&domain.RebalanceProposal{}).Where("id = ?", proposal.ID).Updates(map[string]any{
+		"status":      proposal.Status,
+		"reviewed_by": proposal.ReviewedBy,
+	}).Error; err != nil {
+		return err
+	}
+	for _, line := range proposal.Lines {
+		if err := db.Model(&domain.RebalanceLine{}).Where("id = ?", line.ID).Update("block_id", line.BlockID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ModelPortfolioRepo) SaveBlockOrders(ctx context.Context, blocks []*domain.BlockOrder) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	return r.getDB(ctx).Create(blocks).Error
+}
+
+func (r *ModelPortfolioRepo) GetBlockOrder(ctx context.Context, blockID string) (*domain.BlockOrder, error) {
+	var block domain.BlockOrder
+	err := r.getDB(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Allocations", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).
+		Where("block_id = ?", blockID).First(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+func (r *ModelPortfolioRepo) ListBlockOrders(ctx context.Context, batchID string) ([]*domain.BlockOrder, error) {
+	var blocks []*domain.BlockOrder
+	err := r.getDB(ctx).Preload("Allocations", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).
+		Where("batch_id = ?", batchID).Order("id asc").Find(&blocks).Error
+	return blocks, err
+}
+
+// UpdateBlockOrder 更新大宗单成交进度与各账户分配
+func (r *ModelPortfolioRepo) UpdateBlockOrder(ctx context.Context, block *domain.BlockOrder) error {
+	db := r.getDB(ctx)
+	if err := db.Model(&domain.BlockOrder{}).Where("id = ?", block.ID).Updates(map[string]any{
+		"filled_quantity": block.FilledQuantity,
+		"avg_price":       block.AvgPrice,
+		"algo_order_id":   block.AlgoOrderID,
+		"status":          block.Status,
+	}).Error; err != nil {
+		return err
+	}
+	for _, a := range block.Allocations {
+		if err := db.Model(&domain.BlockAllocation{}).Where("id = ?", a.ID).Updates(map[string]any{
+			"filled_quantity": a.FilledQuantity,
+			"price":           a.Price,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ModelPortfolioRepo) RecordBlockFill(ctx context.Context, fill *domain.BlockFill) (bool, error) {
+	res := r.getDB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(fill)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *ModelPortfolioRepo) UpdateLineFill(ctx context.Context, lineID uint, filled, avgPrice decimal.Decimal) error {
+	return r.getDB(ctx).Model(&domain.RebalanceLine{}).Where("id = ?", lineID).Updates(map[string]any{
+		"filled_quantity": filled,
+		"avg_fill_price":  avgPrice,
+	}).Error
+}
+
+func (r *ModelPortfolioRepo) GetAssignment(ctx context.Context, userID string) (*domain.ModelAssignment, error) {
+	var assignment domain.ModelAssignment
+	err := r.getDB(ctx).Where("user_id = ?", userID).First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *ModelPortfolioRepo) SaveAllocationBookings(ctx context.Context, bookings []*domain.AllocationBooking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+	return r.getDB(ctx).Create(bookings).Error
+}
+
+func (r *ModelPortfolioRepo) ListPendingAllocationBookings(ctx context.Context, blockID string, limit int) ([]*domain.AllocationBooking, error) {
+	query := r.getDB(ctx).Where("status = ?", domain.BookingPending)
+	if blockID != "" {
+		query = query.Where("block_id = ?", blockID)
+	}
+	var bookings []*domain.AllocationBooking
+	err := query.Order("id asc").Limit(limit).Find(&bookings).Error
+	return bookings, err
+}
+
+func (r *ModelPortfolioRepo) MarkAllocationBookingPosted(ctx context.Context, bookingID string) error {
+	return r.getDB(ctx).Model(&domain.AllocationBooking{}).
+		Where("booking_id = ? AND status = ?", bookingID, domain.BookingPending).
+		Update("status", domain.BookingPosted).Error
+}
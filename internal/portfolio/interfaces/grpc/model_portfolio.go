@@ -0,0 +1,233 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	pb "github.com/wyfcoding/financialtrading/go-api/portfolio/v1"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/application"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) SaveModelPortfolio(ctx context.Context, req *pb.SaveModelPortfolioRequest) (*pb.SaveModelPortfolioResponse, error) {
+	cashWeight, err := parseOptionalDecimal(req.CashWeight)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid cash_weight")
+	}
+	threshold, err := parseOptionalDecimal(req.DriftThreshold)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid drift_threshold")
+	}
+	components := make(map[string]decimal.Decimal, len(req.Components))
+	for _, c := range req.Components {
+		weight, err := decimal.NewFromString(c.TargetWeight)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid target_weight for %s", c.Symbol)
+		}
+		if _, dup := components[c.Symbol]; dup {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate component %s", c.Symbol)
+		}
+		components[c.Symbol] = weight
+	}
+	model, err := s.app.SaveModel(ctx, application.SaveModelCommand{
+		ModelID:        req.ModelId,
+		Name:           req.Name,
+		Description:    req.Description,
+		Status:         domain.ModelStatus(strings.ToUpper(req.Status)),
+		CashWeight:     cashWeight,
+		DriftThreshold: threshold,
+		Components:     components,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidModel) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to save model portfolio: %v", err)
+	}
+	return &pb.SaveModelPortfolioResponse{ModelId: model.ModelID, Status: string(model.Status)}, nil
+}
+
+func (s *Server) AssignModel(ctx context.Context, req *pb.AssignModelRequest) (*pb.AssignModelResponse, error) {
+	minCash, err := parseOptionalDecimal(req.MinCash)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid min_cash")
+	}
+	if err := s.app.AssignModel(ctx, application.AssignModelCommand{
+		UserID:            req.UserId,
+		ModelID:           req.ModelId,
+		RestrictedSymbols: req.RestrictedSymbols,
+		MinCash:           minCash,
+		TaxAware:          req.TaxAware,
+		Currency:          req.Currency,
+	}); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to assign model: %v", err)
+	}
+	return &pb.AssignModelResponse{Success: true}, nil
+}
+
+func (s *Server) GetModelDrift(ctx context.Context, req *pb.GetModelDriftRequest) (*pb.GetModelDriftResponse, error) {
+	reports, err := s.app.GetModelDrift(ctx, req.ModelId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get model drift: %v", err)
+	}
+	resp := &pb.GetModelDriftResponse{ModelId: req.ModelId}
+	for _, r := range reports {
+		item := &pb.AccountDrift{
+			UserId:     r.UserID,
+			TotalValue: r.TotalValue.String(),
+			Drift:      r.Drift,
+			Breached:   r.Breached,
+		}
+		for _, l := range r.Lines {
+			item.Lines = append(item.Lines, &pb.DriftLineItem{
+				Symbol:        l.Symbol,
+				CurrentWeight: l.CurrentWeight,
+				TargetWeight:  l.TargetWeight,
+				Drift:         l.Drift,
+			})
+		}
+		resp.Accounts = append(resp.Accounts, item)
+	}
+	return resp, nil
+}
+
+func (s *Server) ProposeRebalance(ctx context.Context, req *pb.ProposeRebalanceRequest) (*pb.ProposeRebalanceResponse, error) {
+	batch, err := s.app.ProposeRebalance(ctx, application.ProposeRebalanceCommand{ModelID: req.ModelId, OnlyBreached: req.OnlyBreached})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to propose rebalance: %v", err)
+	}
+	resp := &pb.ProposeRebalanceResponse{BatchId: batch.BatchID}
+	for _, p := range batch.Proposals {
+		resp.Proposals = append(resp.Proposals, toProtoProposal(p))
+	}
+	for _, sk := range batch.Skipped {
+		resp.Skipped = append(resp.Skipped, &pb.SkippedAccountItem{UserId: sk.UserID, Reason: sk.Reason})
+	}
+	return resp, nil
+}
+
+func (s *Server) ReviewRebalanceProposal(ctx context.Context, req *pb.ReviewRebalanceProposalRequest) (*pb.ReviewRebalanceProposalResponse, error) {
+	proposal, err := s.app.ReviewProposal(ctx, req.ProposalId, req.Approve, req.Reviewer)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidProposalFlow) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to review proposal: %v", err)
+	}
+	return &pb.ReviewRebalanceProposalResponse{Proposal: toProtoProposal(proposal)}, nil
+}
+
+func (s *Server) SubmitBlockOrders(ctx context.Context, req *pb.SubmitBlockOrdersRequest) (*pb.SubmitBlockOrdersResponse, error) {
+	blocks, err := s.app.SubmitBlockOrders(ctx, application.SubmitBlockOrdersCommand{
+		BatchID:         req.BatchId,
+		UserID:          req.UserId,
+		AlgoType:        req.AlgoType,
+		ExecutionWindow: time.Duration(req.ExecutionWindowSeconds) * time.Second,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to submit block orders: %v", err)
+	}
+	resp := &pb.SubmitBlockOrdersResponse{}
+	for _, b := range blocks {
+		resp.BlockOrders = append(resp.BlockOrders, toProtoBlockOrder(b))
+	}
+	return resp, nil
+}
+
+func (s *Server) RecordBlockFill(ctx context.Context, req *pb.RecordBlockFillRequest) (*pb.RecordBlockFillResponse, error) {
+	qty, err := decimal.NewFromString(req.Quantity)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid quantity")
+	}
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid price")
+	}
+	block, applied, err := s.app.RecordBlockFill(ctx, application.RecordBlockFillCommand{
+		FillID:   req.FillId,
+		BlockID:  req.BlockId,
+		Quantity: qty,
+		Price:    price,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record block fill: %v", err)
+	}
+	return &pb.RecordBlockFillResponse{Applied: applied, BlockOrder: toProtoBlockOrder(block)}, nil
+}
+
+func (s *Server) ListBlockOrders(ctx context.Context, req *pb.ListBlockOrdersRequest) (*pb.ListBlockOrdersResponse, error) {
+	blocks, err := s.app.ListBlockOrders(ctx, req.BatchId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list block orders: %v", err)
+	}
+	resp := &pb.ListBlockOrdersResponse{}
+	for _, b := range blocks {
+		resp.BlockOrders = append(resp.BlockOrders, toProtoBlockOrder(b))
+	}
+	return resp, nil
+}
+
+func parseOptionalDecimal(v string) (decimal.Decimal, error) {
+	if v == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(v)
+}
+
+func toProtoProposal(p *domain.RebalanceProposal) *pb.RebalanceProposalItem {
+	item := &pb.RebalanceProposalItem{
+		ProposalId:    p.ProposalID,
+		BatchId:       p.BatchID,
+		UserId:        p.UserID,
+		Status:        string(p.Status),
+		Drift:         p.Drift.String(),
+		TotalValue:    p.TotalValue.String(),
+		CashBefore:    p.CashBefore.String(),
+		CashAfter:     p.CashAfter.String(),
+		EstimatedGain: p.EstimatedGain.String(),
+	}
+	for _, l := range p.Lines {
+		item.Lines = append(item.Lines, &pb.RebalanceLineItem{
+			LineId:         uint64(l.ID),
+			Symbol:         l.Symbol,
+			Side:           l.Side,
+			Quantity:       l.Quantity.String(),
+			Price:          l.Price.String(),
+			LotIds:         l.LotIDs,
+			EstimatedGain:  l.EstimatedGain.String(),
+			BlockId:        l.BlockID,
+			FilledQuantity: l.FilledQuantity.String(),
+			AvgFillPrice:   l.AvgFillPrice.String(),
+		})
+	}
+	return item
+}
+
+func toProtoBlockOrder(b *domain.BlockOrder) *pb.BlockOrderItem {
+	item := &pb.BlockOrderItem{
+		BlockId:        b.BlockID,
+		BatchId:        b.BatchID,
+		Symbol:         b.Symbol,
+		Side:           b.Side,
+		Quantity:       b.Quantity.String(),
+		FilledQuantity: b.FilledQuantity.String(),
+		AvgPrice:       b.AvgPrice.String(),
+		AlgoOrderId:    b.AlgoOrderID,
+		Status:         string(b.Status),
+	}
+	for _, a := range b.Allocations {
+		item.Allocations = append(item.Allocations, &pb.BlockAllocationItem{
+			UserId:         a.UserID,
+			ProposalId:     a.ProposalID,
+			Quantity:       a.Quantity.String(),
+			FilledQuantity: a.FilledQuantity.String(),
+			Price:          a.Price.String(),
+		})
+	}
+	return item
+}
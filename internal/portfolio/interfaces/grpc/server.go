@@ -2,9 +2,15 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/shopspring/decimal"
 	pb "github.com/wyfcoding/financialtrading/go-api/portfolio/v1"
 	"github.com/wyfcoding/financialtrading/internal/portfolio/application"
+	"github.com/wyfcoding/financialtrading/internal/portfolio/domain"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -19,7 +25,7 @@ func NewServer(app *application.PortfolioAppService) *Server {
 }
 
 func (s *Server) GetPortfolio(ctx context.Context, req *pb.GetPortfolioRequest) (*pb.GetPortfolioResponse, error) {
-	eq, upnl, rpnl, dpnl, err := s.app.GetPortfolio(ctx, req.UserId, req.Currency)
+	eq, upnl, rpnl, dpnl, err := s.app.GetPortfolio(ctx, req.UserId, req.Currency, req.IncludeSubAccounts)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get portfolio: %v", err)
 	}
@@ -33,7 +39,7 @@ func (s *Server) GetPortfolio(ctx context.Context, req *pb.GetPortfolioRequest)
 }
 
 func (s *Server) GetPositions(ctx context.Context, req *pb.GetPositionsRequest) (*pb.GetPositionsResponse, error) {
-	positions, err := s.app.GetPositions(ctx, req.UserId)
+	positions, err := s.app.GetPositions(ctx, req.UserId, req.IncludeSubAccounts)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get positions: %v", err)
 	}
@@ -62,7 +68,7 @@ func (s *Server) GetPositions(ctx context.Context, req *pb.GetPositionsRequest)
 }
 
 func (s *Server) GetPerformance(ctx context.Context, req *pb.GetPerformanceRequest) (*pb.GetPerformanceResponse, error) {
-	snaps, ret, sharpe, dd, err := s.app.GetPerformance(ctx, req.UserId, req.Timeframe)
+	snaps, ret, sharpe, dd, err := s.app.GetPerformance(ctx, req.UserId, req.Timeframe, req.IncludeSubAccounts)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get performance: %v", err)
 	}
@@ -82,3 +88,195 @@ func (s *Server) GetPerformance(ctx context.Context, req *pb.GetPerformanceReque
 		MaxDrawdown: dd,
 	}, nil
 }
+
+func (s *Server) RecordCashFlow(ctx context.Context, req *pb.RecordCashFlowRequest) (*pb.RecordCashFlowResponse, error) {
+	flowDate, err := time.Parse("2006-01-02", req.FlowDate)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid flow_date")
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+	recorded, err := s.app.RecordCashFlow(ctx, application.RecordCashFlowCommand{
+		Reference: req.Reference,
+		UserID:    req.UserId,
+		FlowDate:  flowDate,
+		Amount:    amount,
+		Currency:  req.Currency,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to record cash flow: %v", err)
+	}
+	return &pb.RecordCashFlowResponse{Recorded: recorded}, nil
+}
+
+func (s *Server) GetPerformanceAnalytics(ctx context.Context, req *pb.GetPerformanceAnalyticsRequest) (*pb.GetPerformanceAnalyticsResponse, error) {
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid start_date")
+	}
+	var end time.Time
+	if req.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid end_date")
+		}
+	}
+	period := domain.PeriodGranularity(strings.ToUpper(req.Period))
+	switch period {
+	case "", domain.PeriodMonth, domain.PeriodQuarter, domain.PeriodYear:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported period: %s", req.Period)
+	}
+
+	a, err := s.app.GetPerformanceAnalytics(ctx, application.PerformanceAnalyticsQuery{
+		UserID:          req.UserId,
+		BenchmarkSymbol: req.BenchmarkSymbol,
+		Start:           start,
+		End:             end,
+		Period:          period,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientHistory) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get performance analytics: %v", err)
+	}
+
+	benchByDate := make(map[string]float64, len(a.BenchmarkReturns))
+	for _, b := range a.BenchmarkReturns {
+		benchByDate[b.Date.Format("2006-01-02")] = b.Return
+	}
+	daily := make([]*pb.ReturnPoint, 0, len(a.Returns))
+	for _, r := range a.Returns {
+		date := r.Date.Format("2006-01-02")
+		daily = append(daily, &pb.ReturnPoint{Date: date, PortfolioReturn: r.Return, BenchmarkReturn: benchByDate[date]})
+	}
+	periods := make([]*pb.PeriodReturnItem, 0, len(a.Periods))
+	for _, p := range a.Periods {
+		periods = append(periods, &pb.PeriodReturnItem{
+			Period:          p.Period,
+			StartDate:       p.Start.Format("2006-01-02"),
+			EndDate:         p.End.Format("2006-01-02"),
+			PortfolioReturn: p.PortfolioReturn,
+			BenchmarkReturn: p.BenchmarkReturn,
+			ActiveReturn:    p.PortfolioReturn - p.BenchmarkReturn,
+		})
+	}
+
+	return &pb.GetPerformanceAnalyticsResponse{
+		TimeWeightedReturn:            a.TimeWeightedReturn,
+		AnnualizedTimeWeightedReturn:  a.AnnualizedTimeWeightedReturn,
+		MoneyWeightedReturn:           a.MoneyWeightedReturn,
+		AnnualizedMoneyWeightedReturn: a.AnnualizedMoneyWeightedReturn,
+		MaxDrawdown:                   a.MaxDrawdown,
+		BenchmarkSymbol:               a.BenchmarkSymbol,
+		BenchmarkName:                 a.BenchmarkName,
+		BenchmarkReturn:               a.BenchmarkReturn,
+		ActiveReturn:                  a.ActiveReturn(),
+		TrackingError:                 a.TrackingError,
+		InformationRatio:              a.InformationRatio,
+		Beta:                          a.Beta,
+		Alpha:                         a.Alpha,
+		BenchmarkMaxDrawdown:          a.BenchmarkMaxDrawdown,
+		DailyReturns:                  daily,
+		PeriodReturns:                 periods,
+	}, nil
+}
+
+func (s *Server) OptimizePortfolio(ctx context.Context, req *pb.OptimizePortfolioRequest) (*pb.OptimizePortfolioResponse, error) {
+	var value decimal.Decimal
+	if req.PortfolioValue != "" {
+		v, err := decimal.NewFromString(req.PortfolioValue)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid portfolio_value")
+		}
+		value = v
+	}
+	assets := make([]domain.OptimizationAsset, 0, len(req.Assets))
+	for _, a := range req.Assets {
+		assets = append(assets, domain.OptimizationAsset{
+			Symbol:       a.Symbol,
+			Sector:       a.Sector,
+			MinWeight:    a.MinWeight,
+			MaxWeight:    a.MaxWeight,
+			MarketWeight: a.MarketWeight,
+		})
+	}
+	caps := make([]domain.SectorAllocation, 0, len(req.SectorCaps))
+	for _, c := range req.SectorCaps {
+		caps = append(caps, domain.SectorAllocation{Sector: c.Sector, TargetWeight: decimal.NewFromFloat(c.MaxWeight)})
+	}
+	views := make([]domain.View, 0, len(req.Views))
+	for _, v := range req.Views {
+		views = append(views, domain.View{Weights: v.Weights, Return: v.ExpectedReturn, Confidence: v.Confidence})
+	}
+
+	plan, err := s.app.OptimizePortfolio(ctx, application.OptimizePortfolioCommand{
+		UserID: req.UserId,
+		Method: domain.OptimizationMethod(strings.ToUpper(req.Method)),
+		Assets: assets,
+		Views:  views,
+		Constraints: domain.OptimizationConstraints{
+			DefaultMinWeight: req.DefaultMinWeight,
+			DefaultMaxWeight: req.DefaultMaxWeight,
+			SectorCaps:       caps,
+			MaxTurnover:      req.MaxTurnover,
+			RiskAversion:     req.RiskAversion,
+		},
+		LookbackDays:    int(req.LookbackDays),
+		PortfolioValue:  value,
+		Submit:          req.Submit,
+		AlgoType:        req.AlgoType,
+		ExecutionWindow: time.Duration(req.ExecutionWindowSeconds) * time.Second,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidOptimization) || errors.Is(err, domain.ErrInfeasibleConstraint) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to optimize portfolio: %v", err)
+	}
+
+	trades := make([]*pb.ProposedTradeItem, 0, len(plan.Trades))
+	for _, t := range plan.Trades {
+		trades = append(trades, &pb.ProposedTradeItem{
+			Symbol:        t.Symbol,
+			Side:          t.Side,
+			Quantity:      t.Quantity.String(),
+			Price:         t.Price.String(),
+			Notional:      t.Notional.String(),
+			CurrentWeight: t.CurrentWeight,
+			TargetWeight:  t.TargetWeight,
+			AlgoOrderId:   t.AlgoOrderID,
+			SubmitError:   t.SubmitError,
+		})
+	}
+	symbols := make([]string, 0, len(plan.Weights))
+	for symbol := range plan.Weights {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	weights := make([]*pb.OptimizedWeight, 0, len(symbols))
+	for _, symbol := range symbols {
+		weights = append(weights, &pb.OptimizedWeight{
+			Symbol:           symbol,
+			CurrentWeight:    plan.CurrentWeights[symbol],
+			TargetWeight:     plan.Weights[symbol],
+			ExpectedReturn:   plan.ExpectedReturns[symbol],
+			RiskContribution: plan.RiskContributions[symbol],
+		})
+	}
+
+	return &pb.OptimizePortfolioResponse{
+		Method:         string(plan.Method),
+		Weights:        weights,
+		Trades:         trades,
+		ExpectedReturn: plan.ExpectedReturn,
+		Volatility:     plan.Volatility,
+		Turnover:       plan.Turnover,
+		Shrinkage:      plan.Shrinkage,
+		Observations:   int32(plan.Observations),
+		PortfolioValue: plan.PortfolioValue.String(),
+		Submitted:      plan.Submitted,
+	}, nil
+}
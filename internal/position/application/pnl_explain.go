@@ -0,0 +1,400 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
+)
+
+// snapshotPageSize 生成日初快照时逐页读取持仓的页大小
+const snapshotPageSize = 200
+
+// RecordPnLChargeCommand 手续费或资金费入账
+type RecordPnLChargeCommand struct {
+	ChargeID   string
+	UserID     string
+	Symbol     string
+	Type       domain.PnLEventType
+	Amount     decimal.Decimal
+	Currency   string
+	OccurredAt time.Time
+}
+
+// PnLExplainService 维护损益事件流与日初快照，并按营业日输出损益归因
+type PnLExplainService struct {
+	repo              domain.PositionRepository
+	pnl               domain.PnLRepository
+	market            domain.PnLMarketData
+	reportingCurrency string
+	location          *time.Location
+	logger            *slog.Logger
+}
+
+// NewPnLExplainService 创建损益归因服务，营业日按 location 的自然日切分
+func NewPnLExplainService(repo domain.PositionRepository, pnl domain.PnLRepository, market domain.PnLMarketData, reportingCurrency string, location *time.Location, logger *slog.Logger) *PnLExplainService {
+	if location == nil {
+		location = time.UTC
+	}
+	return &PnLExplainService{
+		repo:              repo,
+		pnl:               pnl,
+		market:            market,
+		reportingCurrency: strings.ToUpper(reportingCurrency),
+		location:          location,
+		logger:            logger,
+	}
+}
+
+// BusinessDate 返回时刻所属营业日（以 UTC 零点表示）
+func (s *PnLExplainService) BusinessDate(t time.Time) time.Time {
+	y, m, d := t.In(s.location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// calendarDay 取日期的日历日作为营业日，零值表示当前营业日
+func (s *PnLExplainService) calendarDay(date time.Time) time.Time {
+	if date.IsZero() {
+		return s.BusinessDate(time.Now())
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dayWindow 营业日在本地时区内的起止时刻
+func (s *PnLExplainService) dayWindow(day time.Time) (time.Time, time.Time) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// RecordTrade 记录持仓成交事件，eventID 重复时忽略
+func (s *PnLExplainService) RecordTrade(ctx context.Context, eventID string, e *domain.PositionUpdatedEvent) error {
+	if e.TradeQuantity.IsZero() {
+		return nil
+	}
+	occurredAt := e.OccurredOn
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	_, err := s.pnl.SaveEvent(ctx, &domain.PnLEvent{
+		EventID:    eventID,
+		UserID:     e.UserID,
+		Symbol:     e.Symbol,
+		Type:       domain.PnLEventTrade,
+		Side:       strings.ToLower(e.TradeSide),
+		Quantity:   e.TradeQuantity,
+		Price:      e.TradePrice,
+		OccurredAt: occurredAt,
+	})
+	return err
+}
+
+// RecordCharge 记录手续费或资金费/利息；同一 ChargeID 只入账一次，返回是否为首次入账
+func (s *PnLExplainService) RecordCharge(ctx context.Context, cmd RecordPnLChargeCommand) (bool, error) {
+	if cmd.ChargeID == "" || cmd.UserID == "" {
+		return false, errors.New("charge_id and user_id are required")
+	}
+	if cmd.Type != domain.PnLEventFee && cmd.Type != domain.PnLEventFunding {
+		return false, fmt.Errorf("unsupported charge type: %s", cmd.Type)
+	}
+	if cmd.Amount.IsZero() {
+		return false, errors.New("amount must not be zero")
+	}
+	if cmd.OccurredAt.IsZero() {
+		cmd.OccurredAt = time.Now()
+	}
+	return s.pnl.SaveEvent(ctx, &domain.PnLEvent{
+		EventID:    string(cmd.Type) + ":" + cmd.ChargeID,
+		UserID:     cmd.UserID,
+		Symbol:     cmd.Symbol,
+		Type:       cmd.Type,
+		Amount:     cmd.Amount,
+		Currency:   strings.ToUpper(cmd.Currency),
+		OccurredAt: cmd.OccurredAt,
+	})
+}
+
+// TakeStartOfDaySnapshot 为全部未平持仓生成营业日日初快照，已存在的快照不覆盖；返回写入的持仓数。
+// 任务晚于营业日开始执行时，按当日已记录的成交回退出日初数量。
+func (s *PnLExplainService) TakeStartOfDaySnapshot(ctx context.Context, day time.Time) (int, error) {
+	day = s.calendarDay(day)
+	dayStart, _ := s.dayWindow(day)
+	now := time.Now()
+	marks := make(map[string]decimal.Decimal)
+	currencies := make(map[string]string)
+	rates := make(map[string]decimal.Decimal)
+
+	taken := 0
+	var afterID uint
+	for {
+		page, err := s.repo.ListOpen(ctx, afterID, snapshotPageSize)
+		if err != nil {
+			return taken, err
+		}
+		snapshots := make([]*domain.StartOfDaySnapshot, 0, len(page))
+		for _, p := range page {
+			afterID = p.ID
+			quantity := p.Quantity
+			if p.UpdatedAt.After(dayStart) {
+				events, err := s.pnl.ListEvents(ctx, p.UserID, p.Symbol, dayStart, now.Add(time.Second))
+				if err != nil {
+					return taken, err
+				}
+				quantity = domain.RewindQuantity(quantity, events)
+			}
+			if quantity.IsZero() {
+				continue
+			}
+
+			mark, ok := marks[p.Symbol]
+			if !ok {
+				if mark, err = s.market.MarkPrice(ctx, p.Symbol); err != nil {
+					s.logger.WarnContext(ctx, "mark price unavailable, using average entry price", "symbol", p.Symbol, "error", err)
+					mark = p.AverageEntryPrice
+				}
+				marks[p.Symbol] = mark
+			}
+			currency, fx, err := s.currencyRate(ctx, p.Symbol, currencies, rates)
+			if err != nil {
+				return taken, err
+			}
+			snapshots = append(snapshots, &domain.StartOfDaySnapshot{
+				UserID:            p.UserID,
+				Symbol:            p.Symbol,
+				BusinessDate:      day,
+				Quantity:          quantity,
+				AverageEntryPrice: p.AverageEntryPrice,
+				MarkPrice:         mark,
+				Currency:          currency,
+				ReportingCurrency: s.reportingCurrency,
+				FXRate:            fx,
+				TakenAt:           now,
+			})
+		}
+		if err := s.pnl.SaveSnapshots(ctx, snapshots); err != nil {
+			return taken, err
+		}
+		taken += len(snapshots)
+		if len(page) < snapshotPageSize {
+			return taken, nil
+		}
+	}
+}
+
+// currencyRate 取标的计价币种及其对报告币种的汇率，带缓存；计价币种未知时视同报告币种
+func (s *PnLExplainService) currencyRate(ctx context.Context, symbol string, currencies map[string]string, rates map[string]decimal.Decimal) (string, decimal.Decimal, error) {
+	currency, ok := currencies[symbol]
+	if !ok {
+		c, err := s.market.QuoteCurrency(ctx, symbol)
+		if err != nil {
+			return "", decimal.Zero, fmt.Errorf("failed to get quote currency of %s: %w", symbol, err)
+		}
+		currency = c
+		currencies[symbol] = currency
+	}
+	fx, err := s.rate(ctx, currency, rates)
+	return currency, fx, err
+}
+
+func (s *PnLExplainService) rate(ctx context.Context, currency string, rates map[string]decimal.Decimal) (decimal.Decimal, error) {
+	if currency == "" || currency == s.reportingCurrency {
+		return decimal.NewFromInt(1), nil
+	}
+	if fx, ok := rates[currency]; ok {
+		return fx, nil
+	}
+	fx, err := s.market.FXRate(ctx, currency, s.reportingCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	rates[currency] = fx
+	return fx, nil
+}
+
+// Explain 计算用户在某营业日（取 date 的日历日，零值为当日）的损益归因，symbol 为空时覆盖全部标的。
+// 历史营业日以次日日初快照的标记价与汇率作为期末值，当日则取实时行情。
+func (s *PnLExplainService) Explain(ctx context.Context, userID, symbol string, date time.Time) (*domain.DailyPnLExplain, error) {
+	if userID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	day := s.calendarDay(date)
+	from, to := s.dayWindow(day)
+	now := time.Now()
+	if from.After(now) {
+		return nil, errors.New("business date is in the future")
+	}
+	live := to.After(now)
+
+	sods, err := s.pnl.ListSnapshots(ctx, userID, symbol, day)
+	if err != nil {
+		return nil, err
+	}
+	end := to
+	if live {
+		end = now.Add(time.Second)
+	}
+	events, err := s.pnl.ListEvents(ctx, userID, symbol, from, end)
+	if err != nil {
+		return nil, err
+	}
+	closing := make(map[string]*domain.StartOfDaySnapshot)
+	if !live {
+		next, err := s.pnl.ListSnapshots(ctx, userID, symbol, day.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range next {
+			closing[n.Symbol] = n
+		}
+	}
+
+	sodBySymbol := make(map[string]*domain.StartOfDaySnapshot, len(sods))
+	symbols := make([]string, 0, len(sods))
+	for _, sod := range sods {
+		sodBySymbol[sod.Symbol] = sod
+		symbols = append(symbols, sod.Symbol)
+	}
+	eventsBySymbol := make(map[string][]*domain.PnLEvent)
+	for _, e := range events {
+		if _, ok := sodBySymbol[e.Symbol]; !ok {
+			if _, seen := eventsBySymbol[e.Symbol]; !seen {
+				symbols = append(symbols, e.Symbol)
+			}
+		}
+		eventsBySymbol[e.Symbol] = append(eventsBySymbol[e.Symbol], e)
+	}
+
+	rates := make(map[string]decimal.Decimal)
+	explains := make([]*domain.PnLExplain, 0, len(symbols))
+	for _, sym := range symbols {
+		sod := sodBySymbol[sym]
+		symEvents := eventsBySymbol[sym]
+		chargeRates := make(map[string]decimal.Decimal)
+		for _, e := range symEvents {
+			if e.Type != domain.PnLEventTrade && e.Currency != "" {
+				fx, err := s.rate(ctx, e.Currency, rates)
+				if err != nil {
+					return nil, err
+				}
+				chargeRates[e.Currency] = fx
+			}
+		}
+
+		// 账户级费用不归属任何标的，只含费用分量
+		if sym == "" {
+			explain := domain.ExplainPnL(userID, sym, day, nil, symEvents, decimal.Zero, decimal.NewFromInt(1), chargeRates)
+			explain.Currency, explain.ReportingCurrency = s.reportingCurrency, s.reportingCurrency
+			explains = append(explains, explain)
+			continue
+		}
+
+		mark, fx, currency, err := s.closingValues(ctx, sym, sod, closing[sym], symEvents, rates)
+		if err != nil {
+			return nil, err
+		}
+		explain := domain.ExplainPnL(userID, sym, day, sod, symEvents, mark, fx, chargeRates)
+		explain.Currency, explain.ReportingCurrency = currency, s.reportingCurrency
+		explains = append(explains, explain)
+	}
+	return domain.SummarizePnL(userID, day, s.reportingCurrency, explains), nil
+}
+
+// closingValues 确定期末标记价与汇率：优先次日日初快照；期末已无持仓时价格分量与标记价无关，取最后成交价；否则取实时行情
+func (s *PnLExplainService) closingValues(ctx context.Context, symbol string, sod, closing *domain.StartOfDaySnapshot, events []*domain.PnLEvent, rates map[string]decimal.Decimal) (decimal.Decimal, decimal.Decimal, string, error) {
+	currency := ""
+	if sod != nil {
+		currency = sod.Currency
+	} else if closing != nil {
+		currency = closing.Currency
+	} else {
+		c, err := s.market.QuoteCurrency(ctx, symbol)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, "", fmt.Errorf("failed to get quote currency of %s: %w", symbol, err)
+		}
+		currency = c
+	}
+
+	if closing != nil {
+		return closing.MarkPrice, closing.FXRate, currency, nil
+	}
+	fx, err := s.rate(ctx, currency, rates)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, "", err
+	}
+
+	quantity := decimal.Zero
+	if sod != nil {
+		quantity = sod.Quantity
+	}
+	lastPrice := decimal.Zero
+	for _, e := range events {
+		if e.Type == domain.PnLEventTrade {
+			quantity = quantity.Add(e.SignedQuantity())
+			lastPrice = e.Price
+		}
+	}
+	if quantity.IsZero() && lastPrice.IsPositive() {
+		return lastPrice, fx, currency, nil
+	}
+	mark, err := s.market.MarkPrice(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, "", err
+	}
+	return mark, fx, currency, nil
+}
+
+// Start 每个营业日开始后生成一次日初快照；重启后当日已有快照则跳过
+func (s *PnLExplainService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("start-of-day pnl snapshot job started", "interval", interval, "location", s.location.String())
+	lastRun := s.runDue(ctx, time.Time{})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lastRun = s.runDue(ctx, lastRun)
+		}
+	}
+}
+
+func (s *PnLExplainService) runDue(ctx context.Context, lastRun time.Time) time.Time {
+	day := s.BusinessDate(time.Now())
+	if day.Equal(lastRun) {
+		return lastRun
+	}
+	exists, err := s.pnl.HasSnapshots(ctx, day)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to check start-of-day snapshots", "business_date", day.Format("2006-01-02"), "error", err)
+		return lastRun
+	}
+	if exists {
+		return day
+	}
+	taken, err := s.TakeStartOfDaySnapshot(ctx, day)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to take start-of-day snapshots", "business_date", day.Format("2006-01-02"), "error", err)
+		return lastRun
+	}
+	s.logger.InfoContext(ctx, "start-of-day snapshots taken", "business_date", day.Format("2006-01-02"), "positions", taken)
+	return day
+}
+
+// SetPnLExplainService 启用日内损益归因查询
+func (s *PositionQueryService) SetPnLExplainService(svc *PnLExplainService) {
+	s.pnlExplain = svc
+}
+
+// GetPnLExplain 查询用户某营业日的损益归因，symbol 为空时覆盖全部标的
+func (s *PositionQueryService) GetPnLExplain(ctx context.Context, userID, symbol string, date time.Time) (*domain.DailyPnLExplain, error) {
+	if s.pnlExplain == nil {
+		return nil, errors.New("pnl explain not configured")
+	}
+	return s.pnlExplain.Explain(ctx, userID, symbol, date)
+}
@@ -18,6 +18,7 @@ import (
 type PositionCommandService struct {
 	repo           domain.PositionRepository
 	eventPublisher messagequeue.EventPublisher
+	taxLots        domain.TaxLotRepository
 }
 
 // NewPositionCommandService 创建新的 PositionCommandService 实例
@@ -64,7 +65,7 @@ func (c *PositionCommandService) UpdatePosition(ctx context.Context, cmd UpdateP
 			}
 		}
 
-		if err := c.applyTrade(txCtx, tx, position, cmd.Side, cmd.Quantity, cmd.Price); err != nil {
+		if err := c.applyLotTrade(txCtx, tx, position, cmd.Side, cmd.Quantity, cmd.Price, cmd.Lots); err != nil {
 			return err
 		}
 
@@ -96,8 +97,12 @@ func (c *PositionCommandService) ChangeCostMethod(ctx context.Context, cmd Chang
 
 		oldMethod := position.Method
 		newMethod := domain.CostBasisMethod(cmd.Method)
-		if newMethod == "" {
+		switch newMethod {
+		case "":
 			newMethod = domain.CostBasisAverage
+		case domain.CostBasisFIFO, domain.CostBasisLIFO, domain.CostBasisAverage, domain.CostBasisHIFO, domain.CostBasisSpecificID:
+		default:
+			return fmt.Errorf("unsupported cost method %q", cmd.Method)
 		}
 		if oldMethod == newMethod {
 			return nil
@@ -286,7 +291,7 @@ func (c *PositionCommandService) SagaRefundFrozen(ctx context.Context, barrier a
 }
 
 // SagaAddPosition SAGA 增加头寸
-func (c *PositionCommandService) SagaAddPosition(ctx context.Context, barrier any, userID string, symbol string, quantity decimal.Decimal, price decimal.Decimal) error {
+func (c *PositionCommandService) SagaAddPosition(ctx context.Context, barrier any, tradeID, userID string, symbol string, quantity decimal.Decimal, price decimal.Decimal) error {
 	if userID == "" || symbol == "" {
 		return errors.New("user_id and symbol are required")
 	}
@@ -295,6 +300,9 @@ func (c *PositionCommandService) SagaAddPosition(ctx context.Context, barrier an
 	}
 
 	return c.repo.ExecWithBarrier(ctx, barrier, func(txCtx context.Context) error {
+		if applied, err := c.markPosting(txCtx, tradeID); err != nil || applied {
+			return err
+		}
 		tx := contextx.GetTx(txCtx)
 		position, err := c.ensurePosition(txCtx, tx, userID, symbol)
 		if err != nil {
@@ -305,7 +313,7 @@ func (c *PositionCommandService) SagaAddPosition(ctx context.Context, barrier an
 }
 
 // SagaSubPosition SAGA 减少头寸
-func (c *PositionCommandService) SagaSubPosition(ctx context.Context, barrier any, userID string, symbol string, quantity decimal.Decimal) error {
+func (c *PositionCommandService) SagaSubPosition(ctx context.Context, barrier any, tradeID, userID string, symbol string, quantity decimal.Decimal) error {
 	if userID == "" || symbol == "" {
 		return errors.New("user_id and symbol are required")
 	}
@@ -314,6 +322,9 @@ func (c *PositionCommandService) SagaSubPosition(ctx context.Context, barrier an
 	}
 
 	return c.repo.ExecWithBarrier(ctx, barrier, func(txCtx context.Context) error {
+		if applied, err := c.markPosting(txCtx, tradeID); err != nil || applied {
+			return err
+		}
 		tx := contextx.GetTx(txCtx)
 		position, err := c.repo.GetByUserSymbol(txCtx, userID, symbol)
 		if err != nil {
@@ -329,6 +340,18 @@ func (c *PositionCommandService) SagaSubPosition(ctx context.Context, barrier an
 	})
 }
 
+// markPosting 在过账事务内登记幂等键，返回 true 表示该键已过账、本次应跳过
+func (c *PositionCommandService) markPosting(ctx context.Context, tradeID string) (bool, error) {
+	if tradeID == "" {
+		return false, nil
+	}
+	fresh, err := c.repo.MarkPostingApplied(ctx, tradeID)
+	if err != nil {
+		return false, err
+	}
+	return !fresh, nil
+}
+
 func (c *PositionCommandService) ensurePosition(ctx context.Context, tx any, userID, symbol string) (*domain.Position, error) {
 	position, err := c.repo.GetByUserSymbol(ctx, userID, symbol)
 	if err != nil {
@@ -388,11 +411,22 @@ func fallbackPrice(position *domain.Position) decimal.Decimal {
 }
 
 func (c *PositionCommandService) applyTrade(ctx context.Context, tx any, position *domain.Position, side string, qty, price decimal.Decimal) error {
+	return c.applyLotTrade(ctx, tx, position, side, qty, price, nil)
+}
+
+// applyLotTrade 按成交更新头寸，lots 非空时按指定批次平仓
+func (c *PositionCommandService) applyLotTrade(ctx context.Context, tx any, position *domain.Position, side string, qty, price decimal.Decimal, lots []domain.LotSelection) error {
 	oldQuantity := position.Quantity
 	oldAveragePrice := position.AverageEntryPrice
 	oldRealizedPnL := position.RealizedPnL
 
-	position.UpdatePosition(side, qty, price)
+	now := time.Now()
+	if _, _, err := position.Trade(side, qty, price, now, lots); err != nil {
+		return err
+	}
+	if err := c.recordTaxLots(ctx, tx, position, now); err != nil {
+		return err
+	}
 
 	if err := c.repo.Save(ctx, position); err != nil {
 		return err
@@ -2,14 +2,23 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/shopspring/decimal"
 	"github.com/wyfcoding/financialtrading/internal/position/domain"
 )
 
+// consolidationPageSize 汇总持仓时逐页读取各账户持仓的页大小
+const consolidationPageSize = 500
+
 // PositionQueryService 处理所有持仓相关的查询操作（Queries）。
 type PositionQueryService struct {
-	repo     domain.PositionRepository
-	readRepo domain.PositionReadRepository
+	repo       domain.PositionRepository
+	readRepo   domain.PositionReadRepository
+	hierarchy  domain.AccountHierarchyResolver
+	taxLots    domain.TaxLotRepository
+	pnlExplain *PnLExplainService
 }
 
 // NewPositionQueryService 构造函数。
@@ -17,6 +26,11 @@ func NewPositionQueryService(repo domain.PositionRepository, readRepo domain.Pos
 	return &PositionQueryService{repo: repo, readRepo: readRepo}
 }
 
+// SetAccountHierarchyResolver 启用主子账户持仓汇总
+func (s *PositionQueryService) SetAccountHierarchyResolver(resolver domain.AccountHierarchyResolver) {
+	s.hierarchy = resolver
+}
+
 func (s *PositionQueryService) GetPositions(ctx context.Context, userID string, limit, offset int) ([]*PositionDTO, int64, error) {
 	positions, total, err := s.repo.GetByUser(ctx, userID, limit, offset)
 	if err != nil {
@@ -43,3 +57,44 @@ func (s *PositionQueryService) GetPosition(ctx context.Context, positionID strin
 
 	return toPositionDTO(pos), nil
 }
+
+// GetConsolidatedPositions 汇总用户所在层级（主账户及全部子账户）的持仓与盈亏；userID 可以是层级内任一账户。
+// 已平仓但有已实现盈亏的持仓也计入，保证层级盈亏完整。
+func (s *PositionQueryService) GetConsolidatedPositions(ctx context.Context, userID string) (*ConsolidatedPositionsDTO, error) {
+	if s.hierarchy == nil {
+		return nil, errors.New("account hierarchy resolver not configured")
+	}
+	master, members, err := s.hierarchy.ResolveMembers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve account hierarchy: %w", err)
+	}
+
+	var all []*domain.Position
+	for _, member := range members {
+		for offset := 0; ; offset += consolidationPageSize {
+			page, _, err := s.repo.GetByUser(ctx, member, consolidationPageSize, offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range page {
+				if !p.Quantity.IsZero() || !p.RealizedPnL.IsZero() {
+					all = append(all, p)
+				}
+			}
+			if len(page) < consolidationPageSize {
+				break
+			}
+		}
+	}
+
+	dto := &ConsolidatedPositionsDTO{MasterUserID: master, UserIDs: members}
+	realized, unrealized := decimal.Zero, decimal.Zero
+	for _, c := range domain.ConsolidatePositions(all) {
+		realized = realized.Add(c.RealizedPnL)
+		unrealized = unrealized.Add(c.UnrealizedPnL)
+		dto.Positions = append(dto.Positions, toConsolidatedPositionDTO(c))
+	}
+	dto.RealizedPnL = realized.String()
+	dto.UnrealizedPnL = unrealized.String()
+	return dto, nil
+}
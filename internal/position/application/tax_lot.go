@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+// corporateActionPageSize 公司行动逐页调整持仓的页大小
+const corporateActionPageSize = 200
+
+// ApplyCorporateActionCommand 按公司行动调整某标的全部持仓的批次
+type ApplyCorporateActionCommand struct {
+	Symbol string
+	domain.CorporateActionAdjustment
+}
+
+// SetTaxLotRepository 启用批次实现记录、洗售调整与公司行动批次调整
+func (c *PositionCommandService) SetTaxLotRepository(repo domain.TaxLotRepository) {
+	c.taxLots = repo
+}
+
+// recordTaxLots 落库本次成交的批次实现记录，并与窗口内未匹配的亏损做洗售调整（须在保存头寸前调用）
+func (c *PositionCommandService) recordTaxLots(ctx context.Context, tx any, position *domain.Position, at time.Time) error {
+	realized := position.TakeRealizations()
+	if c.taxLots == nil {
+		return nil
+	}
+	for _, r := range realized {
+		r.RealizationNo = fmt.Sprintf("RL-%d", idgen.GenID())
+	}
+
+	candidates, err := c.taxLots.ListWashSaleCandidates(ctx, position.UserID, position.Symbol, at.Add(-domain.WashSaleWindow))
+	if err != nil {
+		return err
+	}
+	adjusted := position.ApplyWashSales(append(candidates, realized...))
+
+	// 本次新生成的记录与被调整的历史记录一并写入
+	changed := realized
+	for _, a := range adjusted {
+		if a.RealizationNo != "" && !containsRealization(realized, a) {
+			changed = append(changed, a)
+		}
+	}
+	if err := c.taxLots.SaveRealizations(ctx, changed); err != nil {
+		return err
+	}
+
+	if c.eventPublisher == nil {
+		return nil
+	}
+	if len(realized) > 0 {
+		if err := c.eventPublisher.PublishInTx(ctx, tx, domain.PositionLotsRealizedEventType, positionKey(position), map[string]any{
+			"user_id": position.UserID,
+			"symbol":  position.Symbol,
+			"lots":    realized,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, a := range adjusted {
+		if err := c.eventPublisher.PublishInTx(ctx, tx, domain.PositionWashSaleAdjustedEventType, a.RealizationNo, map[string]any{
+			"user_id":              a.UserID,
+			"symbol":               a.Symbol,
+			"realization_no":       a.RealizationNo,
+			"washed_quantity":      a.WashedQuantity.String(),
+			"wash_sale_disallowed": a.WashSaleDisallowed.String(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsRealization(list []*domain.RealizedLot, target *domain.RealizedLot) bool {
+	for _, r := range list {
+		if r == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyCorporateAction 按公司行动调整某标的全部持仓的批次，同一事件对同一持仓只调整一次；返回本次调整的持仓数
+func (c *PositionCommandService) ApplyCorporateAction(ctx context.Context, cmd ApplyCorporateActionCommand) (int, error) {
+	if cmd.Symbol == "" || cmd.EventID == "" {
+		return 0, errors.New("symbol and event_id are required")
+	}
+	if c.taxLots == nil {
+		return 0, errors.New("tax lot repository not configured")
+	}
+
+	var ids []uint
+	for offset := 0; ; offset += corporateActionPageSize {
+		page, _, err := c.repo.GetBySymbol(ctx, cmd.Symbol, corporateActionPageSize, offset)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range page {
+			if !p.Quantity.IsZero() {
+				ids = append(ids, p.ID)
+			}
+		}
+		if len(page) < corporateActionPageSize {
+			break
+		}
+	}
+
+	applied := 0
+	for _, id := range ids {
+		err := c.repo.WithTx(ctx, func(txCtx context.Context) error {
+			first, err := c.taxLots.MarkCorporateActionApplied(txCtx, cmd.EventID, id)
+			if err != nil || !first {
+				return err
+			}
+			position, err := c.repo.Get(txCtx, strconv.FormatUint(uint64(id), 10))
+			if err != nil || position == nil {
+				return err
+			}
+			oldQuantity := position.Quantity
+			if err := position.ApplyCorporateAction(cmd.CorporateActionAdjustment); err != nil {
+				return err
+			}
+			if err := c.repo.Save(txCtx, position); err != nil {
+				return err
+			}
+			applied++
+			return c.publishTxEvent(txCtx, domain.PositionCorporateActionEventType, positionKey(position), map[string]any{
+				"event_id":     cmd.EventID,
+				"type":         string(cmd.Type),
+				"user_id":      position.UserID,
+				"symbol":       position.Symbol,
+				"old_quantity": oldQuantity.String(),
+				"new_quantity": position.Quantity.String(),
+			})
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply corporate action %s to position %d: %w", cmd.EventID, id, err)
+		}
+	}
+	return applied, nil
+}
+
+// SetTaxLotRepository 启用计税批次查询
+func (s *PositionQueryService) SetTaxLotRepository(repo domain.TaxLotRepository) {
+	s.taxLots = repo
+}
+
+// GetTaxLots 查询用户某标的的在持批次及其计税成本与当前持有期
+func (s *PositionQueryService) GetTaxLots(ctx context.Context, userID, symbol string) (*TaxLotsDTO, error) {
+	position, err := s.repo.GetByUserSymbol(ctx, userID, symbol)
+	if err != nil || position == nil {
+		return nil, err
+	}
+	return toTaxLotsDTO(position, time.Now()), nil
+}
+
+// GetRealizedGainReport 用户年度已实现损益报告（按处置时间 UTC 归属年度）
+func (s *PositionQueryService) GetRealizedGainReport(ctx context.Context, userID string, year int) (*domain.RealizedGainReport, error) {
+	if s.taxLots == nil {
+		return nil, errors.New("tax lot repository not configured")
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lots, err := s.taxLots.ListRealizations(ctx, userID, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lots {
+		l.DisposedAt = l.DisposedAt.UTC()
+	}
+	return domain.BuildRealizedGainReport(userID, year, lots), nil
+}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/wyfcoding/financialtrading/internal/position/domain"
@@ -14,6 +15,8 @@ type UpdatePositionCommand struct {
 	Side     string
 	Quantity decimal.Decimal
 	Price    decimal.Decimal
+	// Lots 指定平仓批次（可选）
+	Lots []domain.LotSelection
 }
 
 // ChangeCostMethodCommand 变更成本计算方法命令
@@ -74,6 +77,11 @@ func toPositionDTO(p *domain.Position) *PositionDTO {
 	}
 }
 
+// ToPositionDTO 转换为持仓 DTO
+func ToPositionDTO(p *domain.Position) *PositionDTO {
+	return toPositionDTO(p)
+}
+
 func toPositionDTOs(positions []*domain.Position) []*PositionDTO {
 	if len(positions) == 0 {
 		return nil
@@ -84,3 +92,84 @@ func toPositionDTOs(positions []*domain.Position) []*PositionDTO {
 	}
 	return dtos
 }
+
+// ConsolidatedPositionDTO 层级汇总持仓 DTO
+type ConsolidatedPositionDTO struct {
+	Symbol            string         `json:"symbol"`
+	Quantity          string         `json:"quantity"`
+	GrossQuantity     string         `json:"gross_quantity"`
+	EntryPrice        string         `json:"entry_price"`
+	RealizedPnL       string         `json:"realized_pnl"`
+	UnrealizedPnL     string         `json:"unrealized_pnl"`
+	MarginRequirement string         `json:"margin_requirement"`
+	Members           []*PositionDTO `json:"members"`
+}
+
+// ConsolidatedPositionsDTO 主账户层级持仓汇总 DTO
+type ConsolidatedPositionsDTO struct {
+	MasterUserID  string                     `json:"master_user_id"`
+	UserIDs       []string                   `json:"user_ids"`
+	Positions     []*ConsolidatedPositionDTO `json:"positions"`
+	RealizedPnL   string                     `json:"realized_pnl"`
+	UnrealizedPnL string                     `json:"unrealized_pnl"`
+}
+
+func toConsolidatedPositionDTO(c *domain.ConsolidatedPosition) *ConsolidatedPositionDTO {
+	return &ConsolidatedPositionDTO{
+		Symbol:            c.Symbol,
+		Quantity:          c.Quantity.String(),
+		GrossQuantity:     c.GrossQuantity.String(),
+		EntryPrice:        c.AverageEntryPrice.String(),
+		RealizedPnL:       c.RealizedPnL.String(),
+		UnrealizedPnL:     c.UnrealizedPnL.String(),
+		MarginRequirement: c.MarginRequirement.String(),
+		Members:           toPositionDTOs(c.Members),
+	}
+}
+
+// TaxLotDTO 在持批次 DTO
+type TaxLotDTO struct {
+	LotID           uint   `json:"lot_id"`
+	Quantity        string `json:"quantity"`
+	Price           string `json:"price"`
+	BasisAdjustment string `json:"basis_adjustment"`
+	TaxBasis        string `json:"tax_basis"`
+	AcquiredAt      int64  `json:"acquired_at"`
+	HoldingStartAt  int64  `json:"holding_start_at"`
+	Term            string `json:"term"`
+	WashSaleOf      string `json:"wash_sale_of,omitempty"`
+}
+
+// TaxLotsDTO 持仓批次明细 DTO
+type TaxLotsDTO struct {
+	UserID     string       `json:"user_id"`
+	Symbol     string       `json:"symbol"`
+	Quantity   string       `json:"quantity"`
+	CostMethod string       `json:"cost_method"`
+	Lots       []*TaxLotDTO `json:"lots"`
+}
+
+func toTaxLotsDTO(p *domain.Position, now time.Time) *TaxLotsDTO {
+	dto := &TaxLotsDTO{
+		UserID:     p.UserID,
+		Symbol:     p.Symbol,
+		Quantity:   p.Quantity.String(),
+		CostMethod: string(p.Method),
+		Lots:       make([]*TaxLotDTO, 0, len(p.Lots)),
+	}
+	for i := range p.Lots {
+		lot := &p.Lots[i]
+		dto.Lots = append(dto.Lots, &TaxLotDTO{
+			LotID:           lot.ID,
+			Quantity:        lot.Quantity.String(),
+			Price:           lot.Price.String(),
+			BasisAdjustment: lot.BasisAdjustment.String(),
+			TaxBasis:        lot.TaxBasis().String(),
+			AcquiredAt:      lot.AcquiredAt.Unix(),
+			HoldingStartAt:  lot.HoldingStartAt.Unix(),
+			Term:            string(domain.ClassifyHoldingTerm(lot.HoldingStartAt, now)),
+			WashSaleOf:      lot.WashSaleOf,
+		})
+	}
+	return dto
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// AccountHierarchyResolver 解析用户所在的主子账户层级
+type AccountHierarchyResolver interface {
+	// ResolveMembers 返回主账户及层级内全部用户（主账户在前）；没有子账户的用户只返回自身
+	ResolveMembers(ctx context.Context, userID string) (masterUserID string, userIDs []string, err error)
+}
+
+// ConsolidatedPosition 主账户层面按标的汇总的持仓
+type ConsolidatedPosition struct {
+	Symbol string
+	// Quantity 层级内净持仓（多为正、空为负）
+	Quantity decimal.Decimal
+	// GrossQuantity 层级内各账户持仓绝对值之和
+	GrossQuantity decimal.Decimal
+	// AverageEntryPrice 与净持仓同方向的账户按数量加权的开仓均价
+	AverageEntryPrice decimal.Decimal
+	RealizedPnL       decimal.Decimal
+	UnrealizedPnL     decimal.Decimal
+	MarginRequirement decimal.Decimal
+	Members           []*Position
+}
+
+// ConsolidatePositions 把层级内各账户的持仓按标的汇总，结果按标的排序
+func ConsolidatePositions(positions []*Position) []*ConsolidatedPosition {
+	bySymbol := make(map[string]*ConsolidatedPosition)
+	for _, p := range positions {
+		c, ok := bySymbol[p.Symbol]
+		if !ok {
+			c = &ConsolidatedPosition{Symbol: p.Symbol}
+			bySymbol[p.Symbol] = c
+		}
+		c.Quantity = c.Quantity.Add(p.Quantity)
+		c.GrossQuantity = c.GrossQuantity.Add(p.Quantity.Abs())
+		c.RealizedPnL = c.RealizedPnL.Add(p.RealizedPnL)
+		c.UnrealizedPnL = c.UnrealizedPnL.Add(p.UnrealizedPnL)
+		c.MarginRequirement = c.MarginRequirement.Add(p.MarginRequirement)
+		c.Members = append(c.Members, p)
+	}
+
+	list := make([]*ConsolidatedPosition, 0, len(bySymbol))
+	for _, c := range bySymbol {
+		var qty, cost decimal.Decimal
+		for _, p := range c.Members {
+			if p.Quantity.Sign() != 0 && p.Quantity.Sign() == c.Quantity.Sign() {
+				qty = qty.Add(p.Quantity.Abs())
+				cost = cost.Add(p.Quantity.Abs().Mul(p.AverageEntryPrice))
+			}
+		}
+		if qty.IsPositive() {
+			c.AverageEntryPrice = cost.Div(qty)
+		}
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
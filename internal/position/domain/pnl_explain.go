@@ -0,0 +1,211 @@
+// 变更说明：日内持仓损益归因：以日初快照为基准，按事件流把当日损益拆分为新成交损益、日初持仓价格变动、
+// 手续费、资金费/利息与汇率折算五部分，各分量均折算为报告币种，合计与按市值重估的损益一致。
+// 假设：成交事件来自本服务的 PositionUpdated 事件流，手续费与资金费由上游以费用记录的形式入账；
+// 费用未指定币种时视为标的计价币种；汇率折算只作用于日初持仓市值，当日新增现金流按当前汇率折算。
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PnLEventType 损益事件类型
+type PnLEventType string
+
+const (
+	PnLEventTrade   PnLEventType = "TRADE"
+	PnLEventFee     PnLEventType = "FEE"
+	PnLEventFunding PnLEventType = "FUNDING"
+)
+
+// PnLEvent 损益归因使用的事件流记录
+type PnLEvent struct {
+	// EventID 幂等键：成交取消息坐标，费用取上游单号
+	EventID  string          `json:"event_id"`
+	UserID   string          `json:"user_id"`
+	Symbol   string          `json:"symbol"`
+	Type     PnLEventType    `json:"type"`
+	Side     string          `json:"side,omitempty"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Price    decimal.Decimal `json:"price"`
+	// Amount 费用金额：手续费为正数表示支出，资金费正数表示收入、负数表示支出
+	Amount     decimal.Decimal `json:"amount"`
+	Currency   string          `json:"currency,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// SignedQuantity 成交数量，买入为正、卖出为负
+func (e *PnLEvent) SignedQuantity() decimal.Decimal {
+	if e.Side == "sell" || e.Side == "SELL" {
+		return e.Quantity.Neg()
+	}
+	return e.Quantity
+}
+
+// StartOfDaySnapshot 日初持仓快照
+type StartOfDaySnapshot struct {
+	UserID            string          `json:"user_id"`
+	Symbol            string          `json:"symbol"`
+	BusinessDate      time.Time       `json:"business_date"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	AverageEntryPrice decimal.Decimal `json:"average_entry_price"`
+	MarkPrice         decimal.Decimal `json:"mark_price"`
+	Currency          string          `json:"currency"`
+	ReportingCurrency string          `json:"reporting_currency"`
+	// FXRate 1 单位标的计价币种折合报告币种
+	FXRate  decimal.Decimal `json:"fx_rate"`
+	TakenAt time.Time       `json:"taken_at"`
+}
+
+// PnLExplain 单个持仓在一个营业日内的损益归因（报告币种）
+type PnLExplain struct {
+	UserID            string          `json:"user_id"`
+	Symbol            string          `json:"symbol"`
+	BusinessDate      time.Time       `json:"business_date"`
+	Currency          string          `json:"currency"`
+	ReportingCurrency string          `json:"reporting_currency"`
+	StartQuantity     decimal.Decimal `json:"start_quantity"`
+	StartPrice        decimal.Decimal `json:"start_price"`
+	StartFXRate       decimal.Decimal `json:"start_fx_rate"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	MarkPrice         decimal.Decimal `json:"mark_price"`
+	FXRate            decimal.Decimal `json:"fx_rate"`
+	Trades            int             `json:"trades"`
+	PnLBreakdown
+}
+
+// PnLBreakdown 损益分量
+type PnLBreakdown struct {
+	// NewTradePnL 当日成交相对当前价的损益
+	NewTradePnL decimal.Decimal `json:"new_trade_pnl"`
+	// PriceMovePnL 日初持仓的价格变动损益
+	PriceMovePnL decimal.Decimal `json:"price_move_pnl"`
+	// Fees 手续费（支出为负）
+	Fees decimal.Decimal `json:"fees"`
+	// Funding 资金费与利息
+	Funding decimal.Decimal `json:"funding"`
+	// FXTranslation 日初持仓市值的汇率折算差
+	FXTranslation decimal.Decimal `json:"fx_translation"`
+	Total         decimal.Decimal `json:"total"`
+}
+
+func (b *PnLBreakdown) add(o PnLBreakdown) {
+	b.NewTradePnL = b.NewTradePnL.Add(o.NewTradePnL)
+	b.PriceMovePnL = b.PriceMovePnL.Add(o.PriceMovePnL)
+	b.Fees = b.Fees.Add(o.Fees)
+	b.Funding = b.Funding.Add(o.Funding)
+	b.FXTranslation = b.FXTranslation.Add(o.FXTranslation)
+	b.Total = b.Total.Add(o.Total)
+}
+
+// ExplainPnL 计算单个持仓的损益归因。sod 为空表示日初无持仓；mark、fx 为期末价格与汇率；
+// rates 为费用币种到报告币种的汇率，缺失时按 fx 折算。
+func ExplainPnL(userID, symbol string, businessDate time.Time, sod *StartOfDaySnapshot, events []*PnLEvent, mark, fx decimal.Decimal, rates map[string]decimal.Decimal) *PnLExplain {
+	e := &PnLExplain{
+		UserID:       userID,
+		Symbol:       symbol,
+		BusinessDate: businessDate,
+		MarkPrice:    mark,
+		FXRate:       fx,
+	}
+	if sod != nil {
+		e.Currency = sod.Currency
+		e.ReportingCurrency = sod.ReportingCurrency
+		e.StartQuantity = sod.Quantity
+		e.StartPrice = sod.MarkPrice
+		e.StartFXRate = sod.FXRate
+	}
+	e.Quantity = e.StartQuantity
+
+	// 本币分量
+	priceMove := e.StartQuantity.Mul(mark.Sub(e.StartPrice))
+	var newTrade decimal.Decimal
+	for _, ev := range events {
+		switch ev.Type {
+		case PnLEventTrade:
+			signed := ev.SignedQuantity()
+			newTrade = newTrade.Add(signed.Mul(mark.Sub(ev.Price)))
+			e.Quantity = e.Quantity.Add(signed)
+			e.Trades++
+		case PnLEventFee:
+			e.Fees = e.Fees.Sub(ev.Amount.Mul(chargeRate(ev, fx, rates)))
+		case PnLEventFunding:
+			e.Funding = e.Funding.Add(ev.Amount.Mul(chargeRate(ev, fx, rates)))
+		}
+	}
+	if e.StartQuantity.IsZero() {
+		// 日初无持仓时价格变动分量恒为 0，不依赖日初价格
+		priceMove = decimal.Zero
+	}
+
+	e.PriceMovePnL = priceMove.Mul(fx)
+	e.NewTradePnL = newTrade.Mul(fx)
+	if !e.StartQuantity.IsZero() && e.StartFXRate.IsPositive() {
+		e.FXTranslation = e.StartQuantity.Mul(e.StartPrice).Mul(fx.Sub(e.StartFXRate))
+	}
+	e.Total = e.NewTradePnL.Add(e.PriceMovePnL).Add(e.Fees).Add(e.Funding).Add(e.FXTranslation)
+	return e
+}
+
+func chargeRate(ev *PnLEvent, fx decimal.Decimal, rates map[string]decimal.Decimal) decimal.Decimal {
+	if ev.Currency != "" {
+		if r, ok := rates[ev.Currency]; ok {
+			return r
+		}
+	}
+	return fx
+}
+
+// DailyPnLExplain 用户在一个营业日内全部持仓的损益归因
+type DailyPnLExplain struct {
+	UserID            string        `json:"user_id"`
+	BusinessDate      time.Time     `json:"business_date"`
+	ReportingCurrency string        `json:"reporting_currency"`
+	Positions         []*PnLExplain `json:"positions"`
+	PnLBreakdown
+}
+
+// SummarizePnL 汇总各持仓归因，按标的排序
+func SummarizePnL(userID string, businessDate time.Time, reportingCurrency string, explains []*PnLExplain) *DailyPnLExplain {
+	d := &DailyPnLExplain{UserID: userID, BusinessDate: businessDate, ReportingCurrency: reportingCurrency, Positions: explains}
+	sort.Slice(d.Positions, func(i, j int) bool { return d.Positions[i].Symbol < d.Positions[j].Symbol })
+	for _, e := range explains {
+		d.add(e.PnLBreakdown)
+	}
+	return d
+}
+
+// RewindQuantity 由当前持仓数量扣除 since 之后的成交，还原出 since 时点的数量（用于补拍日初快照）
+func RewindQuantity(current decimal.Decimal, events []*PnLEvent) decimal.Decimal {
+	for _, ev := range events {
+		if ev.Type == PnLEventTrade {
+			current = current.Sub(ev.SignedQuantity())
+		}
+	}
+	return current
+}
+
+// PnLRepository 损益事件流与日初快照仓储
+type PnLRepository interface {
+	// SaveEvent 按 EventID 幂等写入，重复时返回 false
+	SaveEvent(ctx context.Context, e *PnLEvent) (bool, error)
+	// ListEvents 查询 [from, to) 内的事件，symbol 为空时返回用户全部标的
+	ListEvents(ctx context.Context, userID, symbol string, from, to time.Time) ([]*PnLEvent, error)
+	// SaveSnapshots 写入日初快照，同一用户、标的、营业日已存在时不覆盖
+	SaveSnapshots(ctx context.Context, snapshots []*StartOfDaySnapshot) error
+	// ListSnapshots 查询用户某营业日的日初快照，symbol 为空时返回全部
+	ListSnapshots(ctx context.Context, userID, symbol string, businessDate time.Time) ([]*StartOfDaySnapshot, error)
+	// HasSnapshots 某营业日是否已生成日初快照
+	HasSnapshots(ctx context.Context, businessDate time.Time) (bool, error)
+}
+
+// PnLMarketData 损益归因所需的行情与参考数据
+type PnLMarketData interface {
+	MarkPrice(ctx context.Context, symbol string) (decimal.Decimal, error)
+	QuoteCurrency(ctx context.Context, symbol string) (string, error)
+	// FXRate 1 单位 from 折合多少 to
+	FXRate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -13,9 +15,13 @@ const (
 	CostBasisFIFO    CostBasisMethod = "FIFO"
 	CostBasisLIFO    CostBasisMethod = "LIFO"
 	CostBasisAverage CostBasisMethod = "AVERAGE"
+	// CostBasisHIFO 优先平掉成本最高的批次
+	CostBasisHIFO CostBasisMethod = "HIFO"
+	// CostBasisSpecificID 平仓时指定批次，未指定时按 FIFO
+	CostBasisSpecificID CostBasisMethod = "SPECIFIC_ID"
 )
 
-// PositionLot 仓位头寸记录 (用于 FIFO/LIFO)
+// PositionLot 仓位头寸记录 (用于 FIFO/LIFO/HIFO/指定批次及计税)
 type PositionLot struct {
 	ID         uint            `json:"id"`
 	CreatedAt  time.Time       `json:"created_at"`
@@ -23,6 +29,19 @@ type PositionLot struct {
 	PositionID uint            `json:"position_id"`
 	Quantity   decimal.Decimal `json:"quantity"`
 	Price      decimal.Decimal `json:"price"`
+	// AcquiredAt 取得时间
+	AcquiredAt time.Time `json:"acquired_at"`
+	// HoldingStartAt 计税持有期起点，洗售替代批次会承接被洗售批次的持有期
+	HoldingStartAt time.Time `json:"holding_start_at"`
+	// BasisAdjustment 每单位计税成本调整（洗售递延亏损、资本返还等）
+	BasisAdjustment decimal.Decimal `json:"basis_adjustment"`
+	// WashSaleOf 作为哪笔已实现亏损的洗售替代批次
+	WashSaleOf string `json:"wash_sale_of,omitempty"`
+}
+
+// TaxBasis 每单位计税成本
+func (l *PositionLot) TaxBasis() decimal.Decimal {
+	return l.Price.Add(l.BasisAdjustment)
 }
 
 // Position represents a user's holding in a symbol
@@ -39,6 +58,9 @@ type Position struct {
 	MarginRequirement decimal.Decimal `json:"margin_requirement"`
 	Method            CostBasisMethod `json:"method"`
 	Lots              []PositionLot   `json:"lots,omitempty"`
+
+	// realizations 本次成交产生、尚未落库的批次实现记录
+	realizations []*RealizedLot
 }
 
 func NewPosition(userID, symbol string) *Position {
@@ -55,13 +77,30 @@ func NewPosition(userID, symbol string) *Position {
 
 // UpdatePosition updates the position based on a trade execution
 func (p *Position) UpdatePosition(side string, qty, price decimal.Decimal) ([]PositionLot, []uint) {
+	created, deleted, _ := p.Trade(side, qty, price, time.Now(), nil)
+	return created, deleted
+}
+
+// Trade 按成交更新头寸；selections 非空时按指定批次平仓（仅对减仓部分生效）。
+// 平仓产生的批次实现记录通过 TakeRealizations 取出。
+func (p *Position) Trade(side string, qty, price decimal.Decimal, at time.Time, selections []LotSelection) ([]PositionLot, []uint, error) {
 	var created []PositionLot
 	var deleted []uint
 
 	isBuy := (side == "buy" || side == "BUY")
+	opening := (isBuy && !p.Quantity.IsNegative()) || (!isBuy && !p.Quantity.IsPositive())
+	if len(selections) > 0 {
+		if opening {
+			return nil, nil, fmt.Errorf("%w: lots can only be selected when reducing a position", ErrInvalidLotSelection)
+		}
+		if err := p.validateSelections(decimal.Min(qty, p.Quantity.Abs()), selections); err != nil {
+			return nil, nil, err
+		}
+		selections = slices.Clone(selections)
+	}
 
 	// 1. 如果是增加现有头寸方向 (或开新仓)
-	if (isBuy && !p.Quantity.IsNegative()) || (!isBuy && !p.Quantity.IsPositive()) {
+	if opening {
 		// 计算平均价 (始终维护平均价作为参考)
 		absQty := p.Quantity.Abs()
 		totalValue := absQty.Mul(p.AverageEntryPrice).Add(qty.Mul(price))
@@ -78,25 +117,30 @@ func (p *Position) UpdatePosition(side string, qty, price decimal.Decimal) ([]Po
 
 		// 记录 Lot
 		lot := PositionLot{
-			PositionID: p.ID,
-			Quantity:   qty,
-			Price:      price,
+			PositionID:     p.ID,
+			Quantity:       qty,
+			Price:          price,
+			AcquiredAt:     at,
+			HoldingStartAt: at,
 		}
 		p.Lots = append(p.Lots, lot)
 		created = append(created, lot)
 	} else {
 		// 2. 减少现有头寸方向 (平仓/反手)
+		averageBasis := p.AverageEntryPrice
 		remQty := qty
 		for remQty.IsPositive() && len(p.Lots) > 0 {
-			var idx int
-			if p.Method == CostBasisLIFO {
-				idx = len(p.Lots) - 1
-			} else {
-				idx = 0
+			idx, limit := p.nextLot(isBuy, selections)
+			if idx < 0 {
+				break
 			}
 
 			lot := &p.Lots[idx]
 			matchQty := decimal.Min(remQty, lot.Quantity)
+			if limit.IsPositive() {
+				matchQty = decimal.Min(matchQty, limit)
+				selections = consumeSelection(selections, lot.ID, matchQty)
+			}
 
 			// 计算盈亏
 			var pnl decimal.Decimal
@@ -106,6 +150,7 @@ func (p *Position) UpdatePosition(side string, qty, price decimal.Decimal) ([]Po
 				pnl = price.Sub(lot.Price).Mul(matchQty)
 			}
 			p.RealizedPnL = p.RealizedPnL.Add(pnl)
+			p.realizations = append(p.realizations, p.realize(lot, isBuy, matchQty, price, averageBasis, at))
 
 			remQty = remQty.Sub(matchQty)
 			lot.Quantity = lot.Quantity.Sub(matchQty)
@@ -133,9 +178,11 @@ func (p *Position) UpdatePosition(side string, qty, price decimal.Decimal) ([]Po
 				p.Quantity = remQty.Neg()
 			}
 			lot := PositionLot{
-				PositionID: p.ID,
-				Quantity:   remQty,
-				Price:      price,
+				PositionID:     p.ID,
+				Quantity:       remQty,
+				Price:          price,
+				AcquiredAt:     at,
+				HoldingStartAt: at,
 			}
 			p.Lots = append(p.Lots, lot)
 			created = append(created, lot)
@@ -145,7 +192,7 @@ func (p *Position) UpdatePosition(side string, qty, price decimal.Decimal) ([]Po
 			p.AverageEntryPrice = decimal.Zero
 		}
 	}
-	return created, deleted
+	return created, deleted, nil
 }
 
 // MarkToMarket 计算浮动盈亏
@@ -23,11 +23,16 @@ type PositionRepository interface {
 	GetByUser(ctx context.Context, userID string, limit, offset int) ([]*Position, int64, error)
 	// GetBySymbol 获取交易对持仓列表
 	GetBySymbol(ctx context.Context, symbol string, limit, offset int) ([]*Position, int64, error)
+	// ListOpen 按 ID 升序分页列出持仓数量非零的持仓（afterID 为上一页最后一条的 ID）
+	ListOpen(ctx context.Context, afterID uint, limit int) ([]*Position, error)
 	// Update 显式更新持仓全量信息
 	Update(ctx context.Context, position *Position) error
 	// Close 平仓并记录平仓价格
 	Close(ctx context.Context, positionID string, closePrice decimal.Decimal) error
 
+	// MarkPostingApplied 登记幂等键对应的持仓过账，已登记过时返回 false
+	MarkPostingApplied(ctx context.Context, key string) (bool, error)
+
 	// ExecWithBarrier 在分布式事务屏障下执行业务逻辑
 	ExecWithBarrier(ctx context.Context, barrier any, fn func(ctx context.Context) error) error
 }
@@ -0,0 +1,387 @@
+// 变更说明：计税批次核算：平仓时支持指定批次（specific identification）与 HIFO，逐批次生成实现记录并按持有期
+// 划分短期/长期；多头亏损卖出前后 30 天内买入的批次视为洗售替代批次，递延亏损计入其成本并承接持有期；
+// 拆股、合股、送股与资本返还按比例调整批次数量与成本；按年汇总用户已实现损益。
+// 假设：空头平仓一律按短期处理，洗售只针对多头；拆股/送股比例沿用公司行动服务"每股派送股数"的口径，
+// 合股比例为"每股合并后股数"；碎股不做现金折算。
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// WashSaleWindow 洗售窗口：亏损卖出前后各 30 天
+const WashSaleWindow = 30 * 24 * time.Hour
+
+const (
+	PositionLotsRealizedEventType     = "position.lots_realized"
+	PositionWashSaleAdjustedEventType = "position.wash_sale_adjusted"
+	PositionCorporateActionEventType  = "position.corporate_action_applied"
+)
+
+var (
+	// ErrInvalidLotSelection 指定批次不合法
+	ErrInvalidLotSelection = errors.New("invalid lot selection")
+	// ErrUnsupportedCorporateAction 不支持的公司行动
+	ErrUnsupportedCorporateAction = errors.New("unsupported corporate action")
+)
+
+// LotSelection 平仓时指定的批次与数量
+type LotSelection struct {
+	LotID    uint            `json:"lot_id"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// HoldingTerm 计税持有期
+type HoldingTerm string
+
+const (
+	HoldingTermShort HoldingTerm = "SHORT_TERM"
+	HoldingTermLong  HoldingTerm = "LONG_TERM"
+)
+
+// ClassifyHoldingTerm 持有超过一年为长期
+func ClassifyHoldingTerm(holdingStart, disposedAt time.Time) HoldingTerm {
+	if disposedAt.After(holdingStart.AddDate(1, 0, 0)) {
+		return HoldingTermLong
+	}
+	return HoldingTermShort
+}
+
+// LotDirection 被平掉的批次方向
+type LotDirection string
+
+const (
+	LotDirectionLong  LotDirection = "LONG"
+	LotDirectionShort LotDirection = "SHORT"
+)
+
+// RealizedLot 单个批次（部分）平仓的计税实现记录
+type RealizedLot struct {
+	RealizationNo  string          `json:"realization_no"`
+	UserID         string          `json:"user_id"`
+	Symbol         string          `json:"symbol"`
+	LotID          uint            `json:"lot_id"`
+	Direction      LotDirection    `json:"direction"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	AcquiredAt     time.Time       `json:"acquired_at"`
+	HoldingStartAt time.Time       `json:"holding_start_at"`
+	DisposedAt     time.Time       `json:"disposed_at"`
+	Proceeds       decimal.Decimal `json:"proceeds"`
+	CostBasis      decimal.Decimal `json:"cost_basis"`
+	// GainLoss 经济损益：Proceeds - CostBasis
+	GainLoss decimal.Decimal `json:"gain_loss"`
+	Term     HoldingTerm     `json:"term"`
+	// WashSaleDisallowed 因洗售递延、不得在本期确认的亏损
+	WashSaleDisallowed decimal.Decimal `json:"wash_sale_disallowed"`
+	// WashedQuantity 已匹配到替代批次的数量
+	WashedQuantity decimal.Decimal `json:"washed_quantity"`
+}
+
+// ReportableGain 本期可确认的损益：经济损益加回被递延的洗售亏损
+func (r *RealizedLot) ReportableGain() decimal.Decimal {
+	return r.GainLoss.Add(r.WashSaleDisallowed)
+}
+
+// washSaleEligible 多头亏损且尚有未匹配替代批次的数量
+func (r *RealizedLot) washSaleEligible() bool {
+	return r.Direction == LotDirectionLong && r.GainLoss.IsNegative() && r.Quantity.GreaterThan(r.WashedQuantity)
+}
+
+// TakeRealizations 取出并清空本次成交产生的批次实现记录
+func (p *Position) TakeRealizations() []*RealizedLot {
+	list := p.realizations
+	p.realizations = nil
+	return list
+}
+
+// validateSelections 指定批次必须存在、数量为正且不超过批次余量，合计等于本次平仓数量
+func (p *Position) validateSelections(closing decimal.Decimal, selections []LotSelection) error {
+	used := make(map[uint]decimal.Decimal, len(selections))
+	total := decimal.Zero
+	for _, sel := range selections {
+		if !sel.Quantity.IsPositive() {
+			return fmt.Errorf("%w: lot %d quantity must be positive", ErrInvalidLotSelection, sel.LotID)
+		}
+		lot := p.lotByID(sel.LotID)
+		if lot == nil {
+			return fmt.Errorf("%w: lot %d not found", ErrInvalidLotSelection, sel.LotID)
+		}
+		used[sel.LotID] = used[sel.LotID].Add(sel.Quantity)
+		if used[sel.LotID].GreaterThan(lot.Quantity) {
+			return fmt.Errorf("%w: lot %d has only %s", ErrInvalidLotSelection, sel.LotID, lot.Quantity)
+		}
+		total = total.Add(sel.Quantity)
+	}
+	if !total.Equal(closing) {
+		return fmt.Errorf("%w: selected %s, closing %s", ErrInvalidLotSelection, total, closing)
+	}
+	return nil
+}
+
+func (p *Position) lotByID(id uint) *PositionLot {
+	if id == 0 {
+		return nil
+	}
+	for i := range p.Lots {
+		if p.Lots[i].ID == id {
+			return &p.Lots[i]
+		}
+	}
+	return nil
+}
+
+// nextLot 选出下一个要平掉的批次；按指定批次时同时返回该批次剩余的指定数量
+func (p *Position) nextLot(closingShort bool, selections []LotSelection) (int, decimal.Decimal) {
+	for _, sel := range selections {
+		for i := range p.Lots {
+			if p.Lots[i].ID == sel.LotID {
+				return i, sel.Quantity
+			}
+		}
+	}
+	if len(p.Lots) == 0 {
+		return -1, decimal.Zero
+	}
+
+	best := 0
+	for i := 1; i < len(p.Lots); i++ {
+		cand, cur := &p.Lots[i], &p.Lots[best]
+		switch p.Method {
+		case CostBasisLIFO:
+			if !cand.AcquiredAt.Before(cur.AcquiredAt) {
+				best = i
+			}
+		case CostBasisHIFO:
+			// 平多先平成本最高的批次，平空先平开仓价最低的批次，均使本次实现收益最小
+			if (!closingShort && cand.TaxBasis().GreaterThan(cur.TaxBasis())) ||
+				(closingShort && cand.Price.LessThan(cur.Price)) {
+				best = i
+			}
+		default:
+			if cand.AcquiredAt.Before(cur.AcquiredAt) {
+				best = i
+			}
+		}
+	}
+	return best, decimal.Zero
+}
+
+// consumeSelection 扣减某批次的指定数量，用尽后移除
+func consumeSelection(selections []LotSelection, lotID uint, qty decimal.Decimal) []LotSelection {
+	for i := range selections {
+		if selections[i].LotID != lotID {
+			continue
+		}
+		selections[i].Quantity = selections[i].Quantity.Sub(qty)
+		if !selections[i].Quantity.IsPositive() {
+			return append(selections[:i], selections[i+1:]...)
+		}
+		return selections
+	}
+	return selections
+}
+
+// realize 生成批次实现记录；AVERAGE 法以平均成本计税
+func (p *Position) realize(lot *PositionLot, closingShort bool, qty, price, averageBasis decimal.Decimal, at time.Time) *RealizedLot {
+	basis := lot.TaxBasis()
+	if p.Method == CostBasisAverage && averageBasis.IsPositive() {
+		basis = averageBasis.Add(lot.BasisAdjustment)
+	}
+	r := &RealizedLot{
+		UserID:         p.UserID,
+		Symbol:         p.Symbol,
+		LotID:          lot.ID,
+		Quantity:       qty,
+		AcquiredAt:     lot.AcquiredAt,
+		HoldingStartAt: lot.HoldingStartAt,
+		DisposedAt:     at,
+	}
+	if closingShort {
+		r.Direction = LotDirectionShort
+		r.Proceeds = basis.Mul(qty)
+		r.CostBasis = price.Mul(qty)
+		r.Term = HoldingTermShort
+	} else {
+		r.Direction = LotDirectionLong
+		r.Proceeds = price.Mul(qty)
+		r.CostBasis = basis.Mul(qty)
+		r.Term = ClassifyHoldingTerm(lot.HoldingStartAt, at)
+	}
+	r.GainLoss = r.Proceeds.Sub(r.CostBasis)
+	return r
+}
+
+// ApplyWashSales 将亏损实现记录与当前多头批次匹配：取得时间落在亏损卖出前后 30 天内的批次（被卖出批次本身除外）
+// 作为替代批次，递延亏损按单位计入其成本并承接原持有期；替代批次部分匹配时拆分。返回本次有调整的亏损记录。
+func (p *Position) ApplyWashSales(losses []*RealizedLot) []*RealizedLot {
+	if !p.Quantity.IsPositive() {
+		return nil
+	}
+	var adjusted []*RealizedLot
+	for _, loss := range losses {
+		if loss == nil || loss.Symbol != p.Symbol || !loss.washSaleEligible() {
+			continue
+		}
+		lossPerUnit := loss.GainLoss.Neg().Div(loss.Quantity)
+		held := loss.DisposedAt.Sub(loss.HoldingStartAt)
+		touched := false
+		for i := 0; i < len(p.Lots); i++ {
+			remaining := loss.Quantity.Sub(loss.WashedQuantity)
+			if !remaining.IsPositive() {
+				break
+			}
+			lot := p.Lots[i]
+			if lot.WashSaleOf != "" || (lot.ID != 0 && lot.ID == loss.LotID) || !withinWashWindow(lot.AcquiredAt, loss.DisposedAt) {
+				continue
+			}
+			match := decimal.Min(remaining, lot.Quantity)
+			if match.LessThan(lot.Quantity) {
+				rest := lot
+				rest.ID = 0
+				rest.Quantity = lot.Quantity.Sub(match)
+				p.Lots = append(p.Lots, rest)
+			}
+			replacement := &p.Lots[i]
+			replacement.Quantity = match
+			replacement.BasisAdjustment = replacement.BasisAdjustment.Add(lossPerUnit)
+			replacement.WashSaleOf = loss.RealizationNo
+			replacement.HoldingStartAt = replacement.AcquiredAt.Add(-held)
+
+			loss.WashedQuantity = loss.WashedQuantity.Add(match)
+			loss.WashSaleDisallowed = loss.WashSaleDisallowed.Add(lossPerUnit.Mul(match))
+			touched = true
+		}
+		if touched {
+			adjusted = append(adjusted, loss)
+		}
+	}
+	return adjusted
+}
+
+func withinWashWindow(acquiredAt, disposedAt time.Time) bool {
+	d := acquiredAt.Sub(disposedAt)
+	return d >= -WashSaleWindow && d <= WashSaleWindow
+}
+
+// CorporateActionType 影响批次的公司行动类型（取值与公司行动服务一致）
+type CorporateActionType string
+
+const (
+	CorporateActionStockSplit      CorporateActionType = "STOCK_SPLIT"
+	CorporateActionReverseSplit    CorporateActionType = "REVERSE_SPLIT"
+	CorporateActionStockDividend   CorporateActionType = "STOCK_DIVIDEND"
+	CorporateActionReturnOfCapital CorporateActionType = "RETURN_OF_CAPITAL"
+)
+
+// CorporateActionAdjustment 公司行动对批次的调整参数
+type CorporateActionAdjustment struct {
+	EventID string              `json:"event_id"`
+	Type    CorporateActionType `json:"type"`
+	// RatioNumerator/RatioDenominator 拆股、送股为每股派送股数，合股为每股合并后股数，资本返还为每股返还金额
+	RatioNumerator   decimal.Decimal `json:"ratio_numerator"`
+	RatioDenominator decimal.Decimal `json:"ratio_denominator"`
+}
+
+func (a CorporateActionAdjustment) ratio() (decimal.Decimal, error) {
+	if !a.RatioNumerator.IsPositive() || !a.RatioDenominator.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: ratio must be positive", ErrUnsupportedCorporateAction)
+	}
+	return a.RatioNumerator.Div(a.RatioDenominator), nil
+}
+
+// ApplyCorporateAction 按公司行动调整全部批次：送股类按比例放大数量、摊薄单位成本，总成本与持有期不变；
+// 资本返还按每股金额降低计税成本
+func (p *Position) ApplyCorporateAction(a CorporateActionAdjustment) error {
+	rate, err := a.ratio()
+	if err != nil {
+		return err
+	}
+
+	var factor decimal.Decimal
+	switch a.Type {
+	case CorporateActionStockSplit, CorporateActionStockDividend:
+		factor = decimal.NewFromInt(1).Add(rate)
+	case CorporateActionReverseSplit:
+		factor = rate
+	case CorporateActionReturnOfCapital:
+		for i := range p.Lots {
+			p.Lots[i].BasisAdjustment = p.Lots[i].BasisAdjustment.Sub(rate)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCorporateAction, a.Type)
+	}
+
+	for i := range p.Lots {
+		lot := &p.Lots[i]
+		lot.Quantity = lot.Quantity.Mul(factor)
+		lot.Price = lot.Price.Div(factor)
+		lot.BasisAdjustment = lot.BasisAdjustment.Div(factor)
+	}
+	p.Quantity = p.Quantity.Mul(factor)
+	p.AverageEntryPrice = p.AverageEntryPrice.Div(factor)
+	return nil
+}
+
+// GainSummary 某一持有期的已实现损益汇总
+type GainSummary struct {
+	Proceeds           decimal.Decimal `json:"proceeds"`
+	CostBasis          decimal.Decimal `json:"cost_basis"`
+	WashSaleDisallowed decimal.Decimal `json:"wash_sale_disallowed"`
+	// NetGain 可确认的净损益
+	NetGain decimal.Decimal `json:"net_gain"`
+}
+
+func (s *GainSummary) add(r *RealizedLot) {
+	s.Proceeds = s.Proceeds.Add(r.Proceeds)
+	s.CostBasis = s.CostBasis.Add(r.CostBasis)
+	s.WashSaleDisallowed = s.WashSaleDisallowed.Add(r.WashSaleDisallowed)
+	s.NetGain = s.NetGain.Add(r.ReportableGain())
+}
+
+// RealizedGainReport 用户年度已实现损益报告
+type RealizedGainReport struct {
+	UserID    string         `json:"user_id"`
+	Year      int            `json:"year"`
+	ShortTerm GainSummary    `json:"short_term"`
+	LongTerm  GainSummary    `json:"long_term"`
+	Total     GainSummary    `json:"total"`
+	Lots      []*RealizedLot `json:"lots"`
+}
+
+// BuildRealizedGainReport 汇总某年度处置的批次，按处置时间排序
+func BuildRealizedGainReport(userID string, year int, lots []*RealizedLot) *RealizedGainReport {
+	report := &RealizedGainReport{UserID: userID, Year: year, Lots: make([]*RealizedLot, 0, len(lots))}
+	for _, r := range lots {
+		if r == nil || r.UserID != userID || r.DisposedAt.Year() != year {
+			continue
+		}
+		if r.Term == HoldingTermLong {
+			report.LongTerm.add(r)
+		} else {
+			report.ShortTerm.add(r)
+		}
+		report.Total.add(r)
+		report.Lots = append(report.Lots, r)
+	}
+	sort.SliceStable(report.Lots, func(i, j int) bool { return report.Lots[i].DisposedAt.Before(report.Lots[j].DisposedAt) })
+	return report
+}
+
+// TaxLotRepository 批次实现记录仓储
+type TaxLotRepository interface {
+	// SaveRealizations 按 RealizationNo 插入或更新
+	SaveRealizations(ctx context.Context, lots []*RealizedLot) error
+	// ListWashSaleCandidates 自 since 起处置、尚未完全匹配替代批次的多头亏损记录
+	ListWashSaleCandidates(ctx context.Context, userID, symbol string, since time.Time) ([]*RealizedLot, error)
+	// ListRealizations 查询 [from, to) 内处置的实现记录
+	ListRealizations(ctx context.Context, userID string, from, to time.Time) ([]*RealizedLot, error)
+	// MarkCorporateActionApplied 登记公司行动已作用于持仓，已登记过时返回 false
+	MarkCorporateActionApplied(ctx context.Context, eventID string, positionID uint) (bool, error)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+	"google.golang.org/grpc"
+)
+
+// PnLMarketDataClient 经行情服务取标记价与汇率、经参考数据服务取标的计价币种
+type PnLMarketDataClient struct {
+	marketData    marketdatav1.MarketDataServiceClient
+	referenceData referencedatav1.ReferenceDataServiceClient
+}
+
+// NewPnLMarketDataClient 创建损益归因行情客户端；referenceConn 为空时计价币种视为未知
+func NewPnLMarketDataClient(marketConn, referenceConn grpc.ClientConnInterface) *PnLMarketDataClient {
+	c := &PnLMarketDataClient{marketData: marketdatav1.NewMarketDataServiceClient(marketConn)}
+	if referenceConn != nil {
+		c.referenceData = referencedatav1.NewReferenceDataServiceClient(referenceConn)
+	}
+	return c
+}
+
+// MarkPrice 以最新成交价为标记价，缺失时取买卖中间价
+func (c *PnLMarketDataClient) MarkPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	quote, err := c.marketData.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: symbol})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	price := quote.LastPrice
+	if price <= 0 && quote.BidPrice > 0 && quote.AskPrice > 0 {
+		price = (quote.BidPrice + quote.AskPrice) / 2
+	}
+	if price <= 0 {
+		return decimal.Zero, fmt.Errorf("no mark price for %s", symbol)
+	}
+	return decimal.NewFromFloat(price), nil
+}
+
+func (c *PnLMarketDataClient) QuoteCurrency(ctx context.Context, symbol string) (string, error) {
+	if c.referenceData == nil {
+		return "", nil
+	}
+	resp, err := c.referenceData.GetSymbol(ctx, &referencedatav1.GetSymbolRequest{SymbolCode: symbol})
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(resp.GetSymbol().GetQuoteCurrency()), nil
+}
+
+// FXRate 取 FROM/TO 货币对的标记价，缺失时取 TO/FROM 的倒数
+func (c *PnLMarketDataClient) FXRate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == "" || to == "" || from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, err := c.MarkPrice(ctx, from+"/"+to); err == nil {
+		return rate, nil
+	}
+	inverse, err := c.MarkPrice(ctx, to+"/"+from)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("no fx rate for %s/%s: %w", from, to, err)
+	}
+	return decimal.NewFromInt(1).DivRound(inverse, 12), nil
+}
@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 	"github.com/wyfcoding/financialtrading/internal/position/domain"
 	"gorm.io/gorm"
@@ -23,9 +25,13 @@ func (PositionModel) TableName() string { return "positions" }
 // PositionLotModel MySQL 持仓批次表映射
 type PositionLotModel struct {
 	gorm.Model
-	PositionID uint            `gorm:"column:position_id;index;not null"`
-	Quantity   decimal.Decimal `gorm:"column:quantity;type:decimal(32,18)"`
-	Price      decimal.Decimal `gorm:"column:price;type:decimal(32,18)"`
+	PositionID      uint            `gorm:"column:position_id;index;not null"`
+	Quantity        decimal.Decimal `gorm:"column:quantity;type:decimal(32,18)"`
+	Price           decimal.Decimal `gorm:"column:price;type:decimal(32,18)"`
+	AcquiredAt      *time.Time      `gorm:"column:acquired_at"`
+	HoldingStartAt  *time.Time      `gorm:"column:holding_start_at"`
+	BasisAdjustment decimal.Decimal `gorm:"column:basis_adjustment;type:decimal(32,18);default:0"`
+	WashSaleOf      string          `gorm:"column:wash_sale_of;type:varchar(32)"`
 }
 
 func (PositionLotModel) TableName() string { return "position_lots" }
@@ -82,9 +88,13 @@ func toPositionLotModels(lots []domain.PositionLot) []PositionLotModel {
 				CreatedAt: lots[i].CreatedAt,
 				UpdatedAt: lots[i].UpdatedAt,
 			},
-			PositionID: lots[i].PositionID,
-			Quantity:   lots[i].Quantity,
-			Price:      lots[i].Price,
+			PositionID:      lots[i].PositionID,
+			Quantity:        lots[i].Quantity,
+			Price:           lots[i].Price,
+			AcquiredAt:      optionalTime(lots[i].AcquiredAt),
+			HoldingStartAt:  optionalTime(lots[i].HoldingStartAt),
+			BasisAdjustment: lots[i].BasisAdjustment,
+			WashSaleOf:      lots[i].WashSaleOf,
 		}
 	}
 	return models
@@ -97,13 +107,39 @@ func toPositionLots(models []PositionLotModel) []domain.PositionLot {
 	lots := make([]domain.PositionLot, len(models))
 	for i := range models {
 		lots[i] = domain.PositionLot{
-			ID:         models[i].ID,
-			CreatedAt:  models[i].CreatedAt,
-			UpdatedAt:  models[i].UpdatedAt,
-			PositionID: models[i].PositionID,
-			Quantity:   models[i].Quantity,
-			Price:      models[i].Price,
+			ID:              models[i].ID,
+			CreatedAt:       models[i].CreatedAt,
+			UpdatedAt:       models[i].UpdatedAt,
+			PositionID:      models[i].PositionID,
+			Quantity:        models[i].Quantity,
+			Price:           models[i].Price,
+			BasisAdjustment: models[i].BasisAdjustment,
+			WashSaleOf:      models[i].WashSaleOf,
+		}
+		// 早期批次未记录取得时间，以创建时间代替
+		lots[i].AcquiredAt = models[i].CreatedAt
+		if models[i].AcquiredAt != nil {
+			lots[i].AcquiredAt = *models[i].AcquiredAt
+		}
+		lots[i].HoldingStartAt = lots[i].AcquiredAt
+		if models[i].HoldingStartAt != nil {
+			lots[i].HoldingStartAt = *models[i].HoldingStartAt
 		}
 	}
 	return lots
 }
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// PostingApplicationModel 已过账的 Saga 持仓变动，保证同一幂等键（如清算分录 ID）只过账一次
+type PostingApplicationModel struct {
+	gorm.Model
+	PostingKey string `gorm:"column:posting_key;type:varchar(191);uniqueIndex;not null"`
+}
+
+func (PostingApplicationModel) TableName() string { return "position_applied_postings" }
@@ -0,0 +1,169 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PnLEventModel 损益事件流表映射
+type PnLEventModel struct {
+	gorm.Model
+	EventID    string          `gorm:"column:event_id;type:varchar(128);uniqueIndex;not null"`
+	UserID     string          `gorm:"column:user_id;type:varchar(50);index:idx_pnl_event_user_time,priority:1;not null"`
+	Symbol     string          `gorm:"column:symbol;type:varchar(20);index"`
+	Type       string          `gorm:"column:type;type:varchar(16);not null"`
+	Side       string          `gorm:"column:side;type:varchar(8)"`
+	Quantity   decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);default:0"`
+	Price      decimal.Decimal `gorm:"column:price;type:decimal(32,18);default:0"`
+	Amount     decimal.Decimal `gorm:"column:amount;type:decimal(32,18);default:0"`
+	Currency   string          `gorm:"column:currency;type:varchar(10)"`
+	OccurredAt time.Time       `gorm:"column:occurred_at;index:idx_pnl_event_user_time,priority:2;not null"`
+}
+
+func (PnLEventModel) TableName() string { return "position_pnl_events" }
+
+// StartOfDaySnapshotModel 日初持仓快照表映射
+type StartOfDaySnapshotModel struct {
+	gorm.Model
+	UserID            string          `gorm:"column:user_id;type:varchar(50);uniqueIndex:idx_sod_user_symbol_date,priority:1;not null"`
+	Symbol            string          `gorm:"column:symbol;type:varchar(20);uniqueIndex:idx_sod_user_symbol_date,priority:2;not null"`
+	BusinessDate      time.Time       `gorm:"column:business_date;type:date;uniqueIndex:idx_sod_user_symbol_date,priority:3;index;not null"`
+	Quantity          decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);not null"`
+	AverageEntryPrice decimal.Decimal `gorm:"column:average_entry_price;type:decimal(32,18)"`
+	MarkPrice         decimal.Decimal `gorm:"column:mark_price;type:decimal(32,18)"`
+	Currency          string          `gorm:"column:currency;type:varchar(10)"`
+	ReportingCurrency string          `gorm:"column:reporting_currency;type:varchar(10)"`
+	FXRate            decimal.Decimal `gorm:"column:fx_rate;type:decimal(32,18)"`
+	TakenAt           time.Time       `gorm:"column:taken_at;not null"`
+}
+
+func (StartOfDaySnapshotModel) TableName() string { return "position_sod_snapshots" }
+
+type pnlRepository struct {
+	db *gorm.DB
+}
+
+// NewPnLRepository 创建损益事件流与日初快照仓储
+func NewPnLRepository(db *gorm.DB) domain.PnLRepository {
+	return &pnlRepository{db: db}
+}
+
+func (r *pnlRepository) SaveEvent(ctx context.Context, e *domain.PnLEvent) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&PnLEventModel{
+		EventID:    e.EventID,
+		UserID:     e.UserID,
+		Symbol:     e.Symbol,
+		Type:       string(e.Type),
+		Side:       e.Side,
+		Quantity:   e.Quantity,
+		Price:      e.Price,
+		Amount:     e.Amount,
+		Currency:   e.Currency,
+		OccurredAt: e.OccurredAt,
+	})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *pnlRepository) ListEvents(ctx context.Context, userID, symbol string, from, to time.Time) ([]*domain.PnLEvent, error) {
+	query := r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND occurred_at >= ? AND occurred_at < ?", userID, from, to)
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+	var models []*PnLEventModel
+	if err := query.Order("occurred_at ASC, id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	events := make([]*domain.PnLEvent, len(models))
+	for i, m := range models {
+		events[i] = &domain.PnLEvent{
+			EventID:    m.EventID,
+			UserID:     m.UserID,
+			Symbol:     m.Symbol,
+			Type:       domain.PnLEventType(m.Type),
+			Side:       m.Side,
+			Quantity:   m.Quantity,
+			Price:      m.Price,
+			Amount:     m.Amount,
+			Currency:   m.Currency,
+			OccurredAt: m.OccurredAt,
+		}
+	}
+	return events, nil
+}
+
+func (r *pnlRepository) SaveSnapshots(ctx context.Context, snapshots []*domain.StartOfDaySnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	models := make([]*StartOfDaySnapshotModel, len(snapshots))
+	for i, s := range snapshots {
+		models[i] = &StartOfDaySnapshotModel{
+			UserID:            s.UserID,
+			Symbol:            s.Symbol,
+			BusinessDate:      s.BusinessDate,
+			Quantity:          s.Quantity,
+			AverageEntryPrice: s.AverageEntryPrice,
+			MarkPrice:         s.MarkPrice,
+			Currency:          s.Currency,
+			ReportingCurrency: s.ReportingCurrency,
+			FXRate:            s.FXRate,
+			TakenAt:           s.TakenAt,
+		}
+	}
+	// 日初快照一经生成不再覆盖，重跑任务时保持基准不变
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&models, 200).Error
+}
+
+func (r *pnlRepository) ListSnapshots(ctx context.Context, userID, symbol string, businessDate time.Time) ([]*domain.StartOfDaySnapshot, error) {
+	query := r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND business_date = ?", userID, businessDate.Format("2006-01-02"))
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+	var models []*StartOfDaySnapshotModel
+	if err := query.Order("symbol ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	snapshots := make([]*domain.StartOfDaySnapshot, len(models))
+	for i, m := range models {
+		snapshots[i] = &domain.StartOfDaySnapshot{
+			UserID:            m.UserID,
+			Symbol:            m.Symbol,
+			BusinessDate:      businessDate,
+			Quantity:          m.Quantity,
+			AverageEntryPrice: m.AverageEntryPrice,
+			MarkPrice:         m.MarkPrice,
+			Currency:          m.Currency,
+			ReportingCurrency: m.ReportingCurrency,
+			FXRate:            m.FXRate,
+			TakenAt:           m.TakenAt,
+		}
+	}
+	return snapshots, nil
+}
+
+func (r *pnlRepository) HasSnapshots(ctx context.Context, businessDate time.Time) (bool, error) {
+	var count int64
+	err := r.getDB(ctx).WithContext(ctx).Model(&StartOfDaySnapshotModel{}).
+		Where("business_date = ?", businessDate.Format("2006-01-02")).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *pnlRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/wyfcoding/financialtrading/internal/position/domain"
 	"github.com/wyfcoding/pkg/contextx"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type positionRepository struct {
@@ -86,15 +87,15 @@ func (r *positionRepository) Save(ctx context.Context, position *domain.Position
 			"symbol":              model.Symbol,
 			"quantity":            model.Quantity,
 			"average_entry_price": model.AverageEntryPrice,
-			"realized_pnl":         model.RealizedPnL,
+			"realized_pnl":        model.RealizedPnL,
 			"cost_method":         model.Method,
 			"updated_at":          time.Now(),
 		}).Error; err != nil {
 		return err
 	}
 
-	// sync lots (simple strategy: delete + insert)
-	if err := db.Where("position_id = ?", model.ID).Delete(&PositionLotModel{}).Error; err != nil {
+	// sync lots (simple strategy: delete + insert)，物理删除以便按原批次 ID 重新写入，保持指定批次平仓所用 ID 稳定
+	if err := db.Unscoped().Where("position_id = ?", model.ID).Delete(&PositionLotModel{}).Error; err != nil {
 		return err
 	}
 	if len(position.Lots) > 0 {
@@ -160,6 +161,20 @@ func (r *positionRepository) GetBySymbol(ctx context.Context, symbol string, lim
 	return positions, total, nil
 }
 
+func (r *positionRepository) ListOpen(ctx context.Context, afterID uint, limit int) ([]*domain.Position, error) {
+	var models []PositionModel
+	if err := r.getDB(ctx).WithContext(ctx).
+		Where("id > ? AND quantity <> 0", afterID).
+		Order("id asc").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	positions := make([]*domain.Position, len(models))
+	for i := range models {
+		positions[i] = toPosition(&models[i])
+	}
+	return positions, nil
+}
+
 func (r *positionRepository) ExecWithBarrier(ctx context.Context, barrier any, fn func(ctx context.Context) error) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		txCtx := contextx.WithTx(ctx, tx)
@@ -167,6 +182,15 @@ func (r *positionRepository) ExecWithBarrier(ctx context.Context, barrier any, f
 	})
 }
 
+func (r *positionRepository) MarkPostingApplied(ctx context.Context, key string) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&PostingApplicationModel{PostingKey: key})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
 func (r *positionRepository) getDB(ctx context.Context) *gorm.DB {
 	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
 		return tx
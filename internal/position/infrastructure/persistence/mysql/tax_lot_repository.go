@@ -0,0 +1,148 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RealizedLotModel 批次实现记录表映射
+type RealizedLotModel struct {
+	gorm.Model
+	RealizationNo      string          `gorm:"column:realization_no;type:varchar(32);uniqueIndex;not null"`
+	UserID             string          `gorm:"column:user_id;type:varchar(50);index:idx_realized_user_disposed,priority:1;index:idx_realized_user_symbol,priority:1;not null"`
+	Symbol             string          `gorm:"column:symbol;type:varchar(20);index:idx_realized_user_symbol,priority:2;not null"`
+	LotID              uint            `gorm:"column:lot_id"`
+	Direction          string          `gorm:"column:direction;type:varchar(8);not null"`
+	Quantity           decimal.Decimal `gorm:"column:quantity;type:decimal(32,18);not null"`
+	AcquiredAt         time.Time       `gorm:"column:acquired_at;not null"`
+	HoldingStartAt     time.Time       `gorm:"column:holding_start_at;not null"`
+	DisposedAt         time.Time       `gorm:"column:disposed_at;index:idx_realized_user_disposed,priority:2;index:idx_realized_user_symbol,priority:3;not null"`
+	Proceeds           decimal.Decimal `gorm:"column:proceeds;type:decimal(32,18);not null"`
+	CostBasis          decimal.Decimal `gorm:"column:cost_basis;type:decimal(32,18);not null"`
+	GainLoss           decimal.Decimal `gorm:"column:gain_loss;type:decimal(32,18);not null"`
+	Term               string          `gorm:"column:term;type:varchar(16);not null"`
+	WashSaleDisallowed decimal.Decimal `gorm:"column:wash_sale_disallowed;type:decimal(32,18);default:0"`
+	WashedQuantity     decimal.Decimal `gorm:"column:washed_quantity;type:decimal(32,18);default:0"`
+}
+
+func (RealizedLotModel) TableName() string { return "position_realized_lots" }
+
+// CorporateActionApplicationModel 公司行动已调整的持仓，保证同一事件只调整一次
+type CorporateActionApplicationModel struct {
+	gorm.Model
+	EventID    string `gorm:"column:event_id;type:varchar(64);uniqueIndex:idx_ca_event_position;not null"`
+	PositionID uint   `gorm:"column:position_id;uniqueIndex:idx_ca_event_position;not null"`
+}
+
+func (CorporateActionApplicationModel) TableName() string { return "position_corporate_actions" }
+
+type taxLotRepository struct {
+	db *gorm.DB
+}
+
+// NewTaxLotRepository 创建批次实现记录仓储
+func NewTaxLotRepository(db *gorm.DB) domain.TaxLotRepository {
+	return &taxLotRepository{db: db}
+}
+
+func (r *taxLotRepository) SaveRealizations(ctx context.Context, lots []*domain.RealizedLot) error {
+	if len(lots) == 0 {
+		return nil
+	}
+	models := make([]*RealizedLotModel, len(lots))
+	for i, l := range lots {
+		models[i] = &RealizedLotModel{
+			RealizationNo:      l.RealizationNo,
+			UserID:             l.UserID,
+			Symbol:             l.Symbol,
+			LotID:              l.LotID,
+			Direction:          string(l.Direction),
+			Quantity:           l.Quantity,
+			AcquiredAt:         l.AcquiredAt,
+			HoldingStartAt:     l.HoldingStartAt,
+			DisposedAt:         l.DisposedAt,
+			Proceeds:           l.Proceeds,
+			CostBasis:          l.CostBasis,
+			GainLoss:           l.GainLoss,
+			Term:               string(l.Term),
+			WashSaleDisallowed: l.WashSaleDisallowed,
+			WashedQuantity:     l.WashedQuantity,
+		}
+	}
+	// 已有记录只会因洗售匹配而变化
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "realization_no"}},
+		DoUpdates: clause.AssignmentColumns([]string{"wash_sale_disallowed", "washed_quantity", "updated_at"}),
+	}).Create(&models).Error
+}
+
+func (r *taxLotRepository) ListWashSaleCandidates(ctx context.Context, userID, symbol string, since time.Time) ([]*domain.RealizedLot, error) {
+	var models []*RealizedLotModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND symbol = ? AND disposed_at >= ?", userID, symbol, since).
+		Where("direction = ? AND gain_loss < 0 AND washed_quantity < quantity", string(domain.LotDirectionLong)).
+		Order("disposed_at ASC, id ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	return toRealizedLots(models), nil
+}
+
+func (r *taxLotRepository) ListRealizations(ctx context.Context, userID string, from, to time.Time) ([]*domain.RealizedLot, error) {
+	var models []*RealizedLotModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("user_id = ? AND disposed_at >= ? AND disposed_at < ?", userID, from, to).
+		Order("disposed_at ASC, id ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	return toRealizedLots(models), nil
+}
+
+func (r *taxLotRepository) MarkCorporateActionApplied(ctx context.Context, eventID string, positionID uint) (bool, error) {
+	res := r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&CorporateActionApplicationModel{EventID: eventID, PositionID: positionID})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *taxLotRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func toRealizedLots(models []*RealizedLotModel) []*domain.RealizedLot {
+	lots := make([]*domain.RealizedLot, len(models))
+	for i, m := range models {
+		lots[i] = &domain.RealizedLot{
+			RealizationNo:      m.RealizationNo,
+			UserID:             m.UserID,
+			Symbol:             m.Symbol,
+			LotID:              m.LotID,
+			Direction:          domain.LotDirection(m.Direction),
+			Quantity:           m.Quantity,
+			AcquiredAt:         m.AcquiredAt,
+			HoldingStartAt:     m.HoldingStartAt,
+			DisposedAt:         m.DisposedAt,
+			Proceeds:           m.Proceeds,
+			CostBasis:          m.CostBasis,
+			GainLoss:           m.GainLoss,
+			Term:               domain.HoldingTerm(m.Term),
+			WashSaleDisallowed: m.WashSaleDisallowed,
+			WashedQuantity:     m.WashedQuantity,
+		}
+	}
+	return lots
+}
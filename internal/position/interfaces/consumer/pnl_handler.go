@@ -0,0 +1,43 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wyfcoding/financialtrading/internal/position/application"
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
+)
+
+// PnLTopics 损益归因需要订阅的主题
+var PnLTopics = []string{domain.PositionUpdatedEventType}
+
+// PnLHandler 消费持仓成交事件，写入损益归因事件流
+type PnLHandler struct {
+	svc    *application.PnLExplainService
+	logger *slog.Logger
+}
+
+func NewPnLHandler(svc *application.PnLExplainService, logger *slog.Logger) *PnLHandler {
+	return &PnLHandler{svc: svc, logger: logger}
+}
+
+func (h *PnLHandler) Handle(ctx context.Context, msg kafka.Message) error {
+	switch msg.Topic {
+	case domain.PositionUpdatedEventType:
+		var event domain.PositionUpdatedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			h.logger.WarnContext(ctx, "invalid position updated payload", "offset", msg.Offset, "error", err)
+			return nil
+		}
+		if event.UserID == "" || event.Symbol == "" {
+			return nil
+		}
+		// 以消息坐标作为幂等键，重复投递只记录一次
+		eventID := fmt.Sprintf("%s:%d:%d", msg.Topic, msg.Partition, msg.Offset)
+		return h.svc.RecordTrade(ctx, eventID, &event)
+	}
+	return nil
+}
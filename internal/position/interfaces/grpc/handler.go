@@ -210,7 +210,7 @@ func (h *Handler) SagaAddPosition(ctx context.Context, req *pb.SagaPositionReque
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid price: %v", err)
 	}
-	if err := h.cmd.SagaAddPosition(ctx, barrier, req.UserId, req.Symbol, qty, price); err != nil {
+	if err := h.cmd.SagaAddPosition(ctx, barrier, req.TradeId, req.UserId, req.Symbol, qty, price); err != nil {
 		slog.ErrorContext(ctx, "saga_add_position failed", "error", err)
 		return nil, status.Errorf(codes.Aborted, "SagaAddPosition failed: %v", err)
 	}
@@ -227,13 +227,77 @@ func (h *Handler) SagaSubPosition(ctx context.Context, req *pb.SagaPositionReque
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid quantity: %v", err)
 	}
-	if err := h.cmd.SagaSubPosition(ctx, barrier, req.UserId, req.Symbol, qty); err != nil {
+	if err := h.cmd.SagaSubPosition(ctx, barrier, req.TradeId, req.UserId, req.Symbol, qty); err != nil {
 		slog.ErrorContext(ctx, "saga_sub_position failed", "error", err)
 		return nil, status.Errorf(codes.Internal, "SagaSubPosition failed: %v", err)
 	}
 	return &pb.SagaPositionResponse{Success: true}, nil
 }
 
+// GetConsolidatedPositions 汇总主账户层级的持仓与盈亏。
+func (h *Handler) GetConsolidatedPositions(ctx context.Context, req *pb.GetConsolidatedPositionsRequest) (*pb.GetConsolidatedPositionsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	dto, err := h.query.GetConsolidatedPositions(ctx, req.UserId)
+	if err != nil {
+		slog.ErrorContext(ctx, "grpc get_consolidated_positions failed", "user_id", req.UserId, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get consolidated positions: %v", err)
+	}
+
+	resp := &pb.GetConsolidatedPositionsResponse{
+		MasterUserId:  dto.MasterUserID,
+		UserIds:       dto.UserIDs,
+		RealizedPnl:   dto.RealizedPnL,
+		UnrealizedPnl: dto.UnrealizedPnL,
+	}
+	for _, c := range dto.Positions {
+		item := &pb.ConsolidatedPosition{
+			Symbol:            c.Symbol,
+			Quantity:          c.Quantity,
+			GrossQuantity:     c.GrossQuantity,
+			EntryPrice:        c.EntryPrice,
+			RealizedPnl:       c.RealizedPnL,
+			UnrealizedPnl:     c.UnrealizedPnL,
+			MarginRequirement: c.MarginRequirement,
+		}
+		for _, m := range c.Members {
+			item.Members = append(item.Members, h.toProtoPosition(m))
+		}
+		resp.Positions = append(resp.Positions, item)
+	}
+	return resp, nil
+}
+
+// GetTaxLots 查询用户某标的的在持计税批次。
+func (h *Handler) GetTaxLots(ctx context.Context, req *pb.GetTaxLotsRequest) (*pb.GetTaxLotsResponse, error) {
+	if req.UserId == "" || req.Symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and symbol are required")
+	}
+	dto, err := h.query.GetTaxLots(ctx, req.UserId, req.Symbol)
+	if err != nil {
+		slog.ErrorContext(ctx, "grpc get_tax_lots failed", "user_id", req.UserId, "symbol", req.Symbol, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get tax lots: %v", err)
+	}
+	resp := &pb.GetTaxLotsResponse{UserId: req.UserId, Symbol: req.Symbol, Quantity: "0"}
+	if dto == nil {
+		return resp, nil
+	}
+	resp.Quantity = dto.Quantity
+	for _, l := range dto.Lots {
+		resp.Lots = append(resp.Lots, &pb.TaxLot{
+			LotId:          uint64(l.LotID),
+			Quantity:       l.Quantity,
+			Price:          l.Price,
+			TaxBasis:       l.TaxBasis,
+			AcquiredAt:     l.AcquiredAt,
+			HoldingStartAt: l.HoldingStartAt,
+			Term:           l.Term,
+		})
+	}
+	return resp, nil
+}
+
 func (h *Handler) toProtoPosition(dto *application.PositionDTO) *pb.Position {
 	if dto == nil {
 		return nil
@@ -1,14 +1,17 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/wyfcoding/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"github.com/wyfcoding/financialtrading/internal/position/application"
+	"github.com/wyfcoding/financialtrading/internal/position/domain"
 	"github.com/wyfcoding/pkg/logging"
 )
 
@@ -17,6 +20,7 @@ import (
 type PositionHandler struct {
 	cmd   *application.PositionCommandService
 	query *application.PositionQueryService
+	pnl   *application.PnLExplainService
 }
 
 // 创建 HTTP 处理器
@@ -27,11 +31,25 @@ func NewPositionHandler(cmd *application.PositionCommandService, query *applicat
 	}
 }
 
+// SetPnLExplainService 启用损益费用入账与日初快照接口
+func (h *PositionHandler) SetPnLExplainService(svc *application.PnLExplainService) {
+	h.pnl = svc
+}
+
 // 注册路由
 func (h *PositionHandler) RegisterRoutes(router *gin.RouterGroup) {
 	api := router.Group("/api/v1/positions")
 	{
 		api.GET("", h.GetPositions)
+		api.GET("/consolidated", h.GetConsolidatedPositions)
+		api.POST("/trades", h.ApplyTrade)
+		api.PUT("/cost-method", h.ChangeCostMethod)
+		api.GET("/tax-lots", h.GetTaxLots)
+		api.GET("/realized-gains", h.GetRealizedGainReport)
+		api.POST("/corporate-actions", h.ApplyCorporateAction)
+		api.GET("/pnl-explain", h.GetPnLExplain)
+		api.POST("/pnl/charges", h.RecordPnLCharge)
+		api.POST("/pnl/snapshots", h.TakeStartOfDaySnapshot)
 		api.GET("/:id", h.GetPosition)
 		api.POST("/:id/close", h.ClosePosition)
 	}
@@ -72,6 +90,24 @@ func (h *PositionHandler) GetPositions(c *gin.Context) {
 	})
 }
 
+// GetConsolidatedPositions 获取主账户层级汇总持仓
+func (h *PositionHandler) GetConsolidatedPositions(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+
+	dto, err := h.query.GetConsolidatedPositions(c.Request.Context(), userID)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to get consolidated positions", "user_id", userID, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, dto)
+}
+
 // GetPosition 获取持仓详情
 func (h *PositionHandler) GetPosition(c *gin.Context) {
 	positionID := c.Param("id")
@@ -130,3 +166,268 @@ func (h *PositionHandler) ClosePosition(c *gin.Context) {
 
 	response.Success(c, dto)
 }
+
+// TradeRequest 成交入账请求，lots 为可选的指定平仓批次
+type TradeRequest struct {
+	UserID   string `json:"user_id" binding:"required"`
+	Symbol   string `json:"symbol" binding:"required"`
+	Side     string `json:"side" binding:"required"`
+	Quantity string `json:"quantity" binding:"required"`
+	Price    string `json:"price" binding:"required"`
+	Lots     []struct {
+		LotID    uint   `json:"lot_id"`
+		Quantity string `json:"quantity"`
+	} `json:"lots"`
+}
+
+// ApplyTrade 按成交更新持仓，支持指定批次平仓
+func (h *PositionHandler) ApplyTrade(c *gin.Context) {
+	var req TradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	qty, err := decimal.NewFromString(req.Quantity)
+	if err != nil || !qty.IsPositive() {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid quantity", "")
+		return
+	}
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil || !price.IsPositive() {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid price", "")
+		return
+	}
+	lots := make([]domain.LotSelection, 0, len(req.Lots))
+	for _, l := range req.Lots {
+		lotQty, err := decimal.NewFromString(l.Quantity)
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, "invalid lot quantity", "")
+			return
+		}
+		lots = append(lots, domain.LotSelection{LotID: l.LotID, Quantity: lotQty})
+	}
+
+	position, err := h.cmd.UpdatePosition(c.Request.Context(), application.UpdatePositionCommand{
+		UserID:   req.UserID,
+		Symbol:   req.Symbol,
+		Side:     req.Side,
+		Quantity: qty,
+		Price:    price,
+		Lots:     lots,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidLotSelection) {
+			status = http.StatusBadRequest
+		}
+		logging.Error(c.Request.Context(), "Failed to apply trade", "user_id", req.UserID, "symbol", req.Symbol, "error", err)
+		response.ErrorWithStatus(c, status, err.Error(), "")
+		return
+	}
+	response.Success(c, application.ToPositionDTO(position))
+}
+
+// ChangeCostMethodRequest 变更成本计算方法请求
+type ChangeCostMethodRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Symbol string `json:"symbol" binding:"required"`
+	Method string `json:"method" binding:"required"`
+}
+
+// ChangeCostMethod 变更持仓的成本计算方法（FIFO/LIFO/AVERAGE/HIFO/SPECIFIC_ID）
+func (h *PositionHandler) ChangeCostMethod(c *gin.Context) {
+	var req ChangeCostMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	if err := h.cmd.ChangeCostMethod(c.Request.Context(), application.ChangeCostMethodCommand{
+		UserID: req.UserID,
+		Symbol: req.Symbol,
+		Method: req.Method,
+	}); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	response.Success(c, gin.H{"status": "ok"})
+}
+
+// GetTaxLots 获取持仓批次及计税成本
+func (h *PositionHandler) GetTaxLots(c *gin.Context) {
+	userID, symbol := c.Query("user_id"), c.Query("symbol")
+	if userID == "" || symbol == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id and symbol are required", "")
+		return
+	}
+	dto, err := h.query.GetTaxLots(c.Request.Context(), userID, symbol)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to get tax lots", "user_id", userID, "symbol", symbol, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	if dto == nil {
+		response.ErrorWithStatus(c, http.StatusNotFound, "position not found", "")
+		return
+	}
+	response.Success(c, dto)
+}
+
+// GetRealizedGainReport 获取年度已实现损益报告，year 缺省为当年
+func (h *PositionHandler) GetRealizedGainReport(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().UTC().Year())))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid year", "")
+		return
+	}
+	report, err := h.query.GetRealizedGainReport(c.Request.Context(), userID, year)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to get realized gain report", "user_id", userID, "year", year, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	response.Success(c, report)
+}
+
+// CorporateActionRequest 公司行动批次调整请求
+type CorporateActionRequest struct {
+	EventID          string `json:"event_id" binding:"required"`
+	Symbol           string `json:"symbol" binding:"required"`
+	Type             string `json:"type" binding:"required"`
+	RatioNumerator   string `json:"ratio_numerator" binding:"required"`
+	RatioDenominator string `json:"ratio_denominator" binding:"required"`
+}
+
+// ApplyCorporateAction 按公司行动调整该标的全部持仓批次
+func (h *PositionHandler) ApplyCorporateAction(c *gin.Context) {
+	var req CorporateActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	num, err := decimal.NewFromString(req.RatioNumerator)
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid ratio_numerator", "")
+		return
+	}
+	den, err := decimal.NewFromString(req.RatioDenominator)
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid ratio_denominator", "")
+		return
+	}
+	applied, err := h.cmd.ApplyCorporateAction(c.Request.Context(), application.ApplyCorporateActionCommand{
+		Symbol: req.Symbol,
+		CorporateActionAdjustment: domain.CorporateActionAdjustment{
+			EventID:          req.EventID,
+			Type:             domain.CorporateActionType(req.Type),
+			RatioNumerator:   num,
+			RatioDenominator: den,
+		},
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrUnsupportedCorporateAction) {
+			status = http.StatusBadRequest
+		}
+		logging.Error(c.Request.Context(), "Failed to apply corporate action", "event_id", req.EventID, "symbol", req.Symbol, "error", err)
+		response.ErrorWithStatus(c, status, err.Error(), "")
+		return
+	}
+	response.Success(c, gin.H{"applied_positions": applied})
+}
+
+// parseBusinessDate 解析 YYYY-MM-DD 营业日，缺省为零值（当前营业日）
+func parseBusinessDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetPnLExplain 获取用户某营业日的损益归因，symbol 缺省为全部标的，date 缺省为当日
+func (h *PositionHandler) GetPnLExplain(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+	date, err := parseBusinessDate(c.Query("date"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid date", "")
+		return
+	}
+	explain, err := h.query.GetPnLExplain(c.Request.Context(), userID, c.Query("symbol"), date)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to explain pnl", "user_id", userID, "date", c.Query("date"), "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	response.Success(c, explain)
+}
+
+// PnLChargeRequest 手续费或资金费入账请求；手续费金额为正表示支出，资金费金额为正表示收入
+type PnLChargeRequest struct {
+	ChargeID   string    `json:"charge_id" binding:"required"`
+	UserID     string    `json:"user_id" binding:"required"`
+	Symbol     string    `json:"symbol"`
+	Type       string    `json:"type" binding:"required"`
+	Amount     string    `json:"amount" binding:"required"`
+	Currency   string    `json:"currency"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// RecordPnLCharge 记录手续费或资金费/利息
+func (h *PositionHandler) RecordPnLCharge(c *gin.Context) {
+	if h.pnl == nil {
+		response.ErrorWithStatus(c, http.StatusServiceUnavailable, "pnl explain not configured", "")
+		return
+	}
+	var req PnLChargeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid amount", "")
+		return
+	}
+	recorded, err := h.pnl.RecordCharge(c.Request.Context(), application.RecordPnLChargeCommand{
+		ChargeID:   req.ChargeID,
+		UserID:     req.UserID,
+		Symbol:     req.Symbol,
+		Type:       domain.PnLEventType(req.Type),
+		Amount:     amount,
+		Currency:   req.Currency,
+		OccurredAt: req.OccurredAt,
+	})
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	response.Success(c, gin.H{"recorded": recorded})
+}
+
+// TakeStartOfDaySnapshot 手动生成日初快照，date 缺省为当日；已存在的快照不覆盖
+func (h *PositionHandler) TakeStartOfDaySnapshot(c *gin.Context) {
+	if h.pnl == nil {
+		response.ErrorWithStatus(c, http.StatusServiceUnavailable, "pnl explain not configured", "")
+		return
+	}
+	date, err := parseBusinessDate(c.Query("date"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid date", "")
+		return
+	}
+	taken, err := h.pnl.TakeStartOfDaySnapshot(c.Request.Context(), date)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to take start-of-day snapshots", "date", c.Query("date"), "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	response.Success(c, gin.H{"positions": taken})
+}
@@ -2,6 +2,9 @@ package application
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -126,3 +129,47 @@ func (s *PricingQueryService) toDTO(p *domain.Price) *PriceDTO {
 		Source:    p.Source,
 	}
 }
+
+// RevalueOptions 按 Black-Scholes 批量重估期权价格，结果与请求顺序一致。
+// 只做计算，不保存定价结果也不发布事件，供风险情景重估等高频场景使用；到期或零波动率时取内在价值
+func (s *PricingQueryService) RevalueOptions(ctx context.Context, cmds []PriceOptionCommand) ([]float64, error) {
+	now := time.Now().UnixMilli()
+	prices := make([]float64, len(cmds))
+	for i, cmd := range cmds {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if cmd.UnderlyingPrice <= 0 || cmd.StrikePrice <= 0 {
+			return nil, fmt.Errorf("invalid option input at %d: underlying=%f strike=%f", i, cmd.UnderlyingPrice, cmd.StrikePrice)
+		}
+		isPut := strings.EqualFold(cmd.OptionType, string(domain.OptionTypePut))
+		timeToExpiry := float64(cmd.ExpiryDate-now) / 1000 / 24 / 3600 / 365
+		if timeToExpiry <= 0 || cmd.Volatility <= 0 {
+			if isPut {
+				prices[i] = math.Max(cmd.StrikePrice-cmd.UnderlyingPrice, 0)
+			} else {
+				prices[i] = math.Max(cmd.UnderlyingPrice-cmd.StrikePrice, 0)
+			}
+			continue
+		}
+
+		spot := decimal.NewFromFloat(cmd.UnderlyingPrice)
+		strike := decimal.NewFromFloat(cmd.StrikePrice)
+		expiry := decimal.NewFromFloat(timeToExpiry)
+		rate := decimal.NewFromFloat(cmd.RiskFreeRate)
+		vol := decimal.NewFromFloat(cmd.Volatility)
+		div := decimal.NewFromFloat(cmd.DividendYield)
+		var price decimal.Decimal
+		var err error
+		if isPut {
+			price, err = s.bsCalc.CalculatePutPrice(spot, strike, expiry, rate, vol, div)
+		} else {
+			price, err = s.bsCalc.CalculateCallPrice(spot, strike, expiry, rate, vol, div)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to price option at %d: %w", i, err)
+		}
+		prices[i] = price.InexactFloat64()
+	}
+	return prices, nil
+}
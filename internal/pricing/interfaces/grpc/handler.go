@@ -100,6 +100,36 @@ func (h *Handler) GetOptionPrice(ctx context.Context, req *pb.GetOptionPriceRequ
 	}, nil
 }
 
+// BatchGetOptionPrices 批量重估期权价格，只计算不落库
+func (h *Handler) BatchGetOptionPrices(ctx context.Context, req *pb.BatchGetOptionPricesRequest) (*pb.BatchGetOptionPricesResponse, error) {
+	cmds := make([]application.PriceOptionCommand, len(req.Requests))
+	for i, r := range req.Requests {
+		if r.Contract == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "contract is required at %d", i)
+		}
+		cmds[i] = application.PriceOptionCommand{
+			Symbol:          r.Contract.Symbol,
+			OptionType:      r.Contract.Type,
+			StrikePrice:     r.Contract.StrikePrice,
+			ExpiryDate:      r.Contract.ExpiryDate.AsTime().UnixMilli(),
+			UnderlyingPrice: r.UnderlyingPrice,
+			Volatility:      r.Volatility,
+			RiskFreeRate:    r.RiskFreeRate,
+			PricingModel:    "BlackScholes",
+		}
+	}
+
+	prices, err := h.query.RevalueOptions(ctx, cmds)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to batch price options", "count", len(cmds), "error", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.BatchGetOptionPricesResponse{
+		Prices:          prices,
+		CalculationTime: timestamppb.Now(),
+	}, nil
+}
+
 // GetGreeks 获取希腊字母
 func (h *Handler) GetGreeks(ctx context.Context, req *pb.GetGreeksRequest) (*pb.GetGreeksResponse, error) {
 	contract := domain.OptionContract{
@@ -0,0 +1,317 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/wyfcoding/financialtrading/internal/referencedata/domain"
+)
+
+const seedPageSize = 500
+
+// ImportHolidaysCommand 导入节假日文件
+type ImportHolidaysCommand struct {
+	Calendar string // CSV 含 calendar 列时可为空
+	Format   string // CSV, ICS
+	Data     []byte
+	Kind     domain.CalendarKind
+	Name     string
+	Timezone string
+}
+
+// SettlementDateQuery 证券 T+N 结算日查询
+type SettlementDateQuery struct {
+	Market    string
+	Currency  string
+	TradeDate time.Time
+	Days      int
+}
+
+// FXValueDateResult 外汇起息日
+type FXValueDateResult struct {
+	ValueDate time.Time          `json:"value_date"`
+	SpotDays  int                `json:"spot_days"`
+	Legs      []domain.FXLegDate `json:"legs"`
+}
+
+// CalendarService 节假日日历与工作日计算：
+// 按交易所与币种维护日历，由参考数据中的交易所、交易对币种初始化，支持 CSV/ICS 导入。
+type CalendarService struct {
+	repo    domain.CalendarRepository
+	refRepo domain.ReferenceDataRepository
+	logger  *slog.Logger
+
+	mu    sync.RWMutex
+	cache map[string]*domain.HolidayCalendar
+}
+
+// NewCalendarService 创建日历服务
+func NewCalendarService(repo domain.CalendarRepository, refRepo domain.ReferenceDataRepository, logger *slog.Logger) *CalendarService {
+	return &CalendarService{
+		repo:    repo,
+		refRepo: refRepo,
+		logger:  logger,
+		cache:   make(map[string]*domain.HolidayCalendar),
+	}
+}
+
+// Seed 为参考数据中的交易所与币种建立日历，并导入 dir 下的节假日文件：
+// CSV 文件按 calendar 列归属（缺省时为文件名），ICS 文件归属文件名对应的日历
+func (s *CalendarService) Seed(ctx context.Context, dir string) error {
+	existing, err := s.repo.ListCalendars(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list calendars: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		known[c.Code] = true
+	}
+	ensure := func(cal *domain.HolidayCalendar) error {
+		if known[cal.Code] {
+			return nil
+		}
+		known[cal.Code] = true
+		return s.repo.SaveCalendar(ctx, cal)
+	}
+
+	for offset := 0; ; offset += seedPageSize {
+		exchanges, err := s.refRepo.ListExchanges(ctx, seedPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list exchanges: %w", err)
+		}
+		for _, ex := range exchanges {
+			if err := ensure(domain.NewHolidayCalendar(ex.Name, domain.CalendarExchange, ex.Name, ex.Timezone)); err != nil {
+				return err
+			}
+		}
+		if len(exchanges) < seedPageSize {
+			break
+		}
+	}
+	for offset := 0; ; offset += seedPageSize {
+		symbols, err := s.refRepo.ListSymbols(ctx, "", "", seedPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list symbols: %w", err)
+		}
+		for _, sym := range symbols {
+			for _, ccy := range []string{sym.BaseCurrency, sym.QuoteCurrency} {
+				if isCurrencyCode(ccy) {
+					if err := ensure(domain.NewHolidayCalendar(ccy, domain.CalendarCurrency, ccy, "UTC")); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if len(symbols) < seedPageSize {
+			break
+		}
+	}
+
+	if dir == "" {
+		return nil
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	imported := 0
+	for _, f := range files {
+		ext := strings.ToUpper(strings.TrimPrefix(filepath.Ext(f.Name()), "."))
+		if f.IsDir() || (ext != "CSV" && ext != "ICS") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+		code := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		if i := strings.IndexAny(code, "_-"); i > 0 {
+			code = code[:i] // XNYS_2026.csv -> XNYS
+		}
+		n, err := s.ImportHolidays(ctx, ImportHolidaysCommand{Calendar: code, Format: ext, Data: data})
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", f.Name(), err)
+		}
+		imported += n
+	}
+	s.logger.InfoContext(ctx, "holiday calendars seeded", "calendars", len(known), "holidays_imported", imported)
+	return nil
+}
+
+// ImportHolidays 导入 CSV/ICS 节假日，日历不存在时自动创建；返回导入条数
+func (s *CalendarService) ImportHolidays(ctx context.Context, cmd ImportHolidaysCommand) (int, error) {
+	byCalendar := make(map[string][]domain.Holiday)
+	switch strings.ToUpper(cmd.Format) {
+	case "CSV":
+		parsed, err := domain.ParseHolidayCSV(bytes.NewReader(cmd.Data), cmd.Calendar)
+		if err != nil {
+			return 0, err
+		}
+		byCalendar = parsed
+	case "ICS":
+		if cmd.Calendar == "" {
+			return 0, fmt.Errorf("%w: calendar is required for ICS", domain.ErrInvalidHolidayFile)
+		}
+		holidays, err := domain.ParseHolidayICS(bytes.NewReader(cmd.Data))
+		if err != nil {
+			return 0, err
+		}
+		byCalendar[strings.ToUpper(cmd.Calendar)] = holidays
+	default:
+		return 0, fmt.Errorf("%w: unsupported format %q", domain.ErrInvalidHolidayFile, cmd.Format)
+	}
+
+	total := 0
+	err := s.refRepo.WithTx(ctx, func(txCtx context.Context) error {
+		for code, holidays := range byCalendar {
+			cal, err := s.repo.GetCalendar(txCtx, code)
+			if err != nil {
+				return err
+			}
+			if cal == nil {
+				kind := cmd.Kind
+				if kind == "" {
+					kind = domain.CalendarExchange
+					if isCurrencyCode(code) {
+						kind = domain.CalendarCurrency
+					}
+				}
+				name := cmd.Name
+				if name == "" {
+					name = code
+				}
+				if err := s.repo.SaveCalendar(txCtx, domain.NewHolidayCalendar(code, kind, name, cmd.Timezone)); err != nil {
+					return err
+				}
+			}
+			if err := s.repo.SaveHolidays(txCtx, code, holidays); err != nil {
+				return err
+			}
+			total += len(holidays)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	for code := range byCalendar {
+		delete(s.cache, code)
+	}
+	s.mu.Unlock()
+	s.logger.InfoContext(ctx, "holidays imported", "format", cmd.Format, "calendars", len(byCalendar), "count", total)
+	return total, nil
+}
+
+// GetCalendar 获取日历（带缓存）
+func (s *CalendarService) GetCalendar(ctx context.Context, code string) (*domain.HolidayCalendar, error) {
+	code = strings.ToUpper(code)
+	s.mu.RLock()
+	cal, ok := s.cache[code]
+	s.mu.RUnlock()
+	if ok {
+		return cal, nil
+	}
+
+	cal, err := s.repo.GetCalendar(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if cal == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrCalendarNotFound, code)
+	}
+	s.mu.Lock()
+	s.cache[code] = cal
+	s.mu.Unlock()
+	return cal, nil
+}
+
+// ListCalendars 列出全部日历
+func (s *CalendarService) ListCalendars(ctx context.Context) ([]*domain.HolidayCalendar, error) {
+	return s.repo.ListCalendars(ctx)
+}
+
+// ListHolidays 查询日历在区间内的节假日
+func (s *CalendarService) ListHolidays(ctx context.Context, code string, from, to time.Time) ([]domain.Holiday, error) {
+	return s.repo.ListHolidays(ctx, code, from, to)
+}
+
+// SettlementDate 证券 T+N 预定结算日，Market 与 Currency 至少提供一个
+func (s *CalendarService) SettlementDate(ctx context.Context, q SettlementDateQuery) (time.Time, error) {
+	var market, currency domain.BusinessCalendar
+	if q.Market != "" {
+		cal, err := s.GetCalendar(ctx, q.Market)
+		if err != nil {
+			return time.Time{}, err
+		}
+		market = cal
+	}
+	if q.Currency != "" {
+		cal, err := s.GetCalendar(ctx, q.Currency)
+		if err != nil {
+			return time.Time{}, err
+		}
+		currency = cal
+	}
+	if market == nil && currency == nil {
+		return time.Time{}, fmt.Errorf("%w: market or currency is required", domain.ErrCalendarNotFound)
+	}
+	return domain.SecuritiesSettlementDate(market, currency, q.TradeDate, q.Days), nil
+}
+
+// FXValueDate 外汇起息日，days 小于等于 0 时按货币对即期天数
+func (s *CalendarService) FXValueDate(ctx context.Context, base, quote string, tradeDate time.Time, days int) (*FXValueDateResult, error) {
+	baseCal, err := s.GetCalendar(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	quoteCal, err := s.GetCalendar(ctx, quote)
+	if err != nil {
+		return nil, err
+	}
+	// 美元日历缺失时不阻断非美元货币对
+	usdCal, _ := s.GetCalendar(ctx, "USD")
+	if days <= 0 {
+		days = domain.FXSpotDays(base, quote)
+	}
+	value, legs := domain.FXValueDate(baseCal, quoteCal, usdCal, tradeDate, days)
+	return &FXValueDateResult{ValueDate: value, SpotDays: days, Legs: legs}, nil
+}
+
+// AdjustDate 按联合日历与调整规则调整日期，同时返回原日期是否为工作日
+func (s *CalendarService) AdjustDate(ctx context.Context, calendars []string, date time.Time, convention domain.BusinessDayConvention) (time.Time, bool, error) {
+	joint := make(domain.JointCalendar, 0, len(calendars))
+	for _, code := range calendars {
+		cal, err := s.GetCalendar(ctx, code)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		joint = append(joint, cal)
+	}
+	return domain.Adjust(joint, date, convention), joint.IsBusinessDay(domain.CivilDate(date)), nil
+}
+
+// isCurrencyCode 三位大写字母视为 ISO 4217 币种代码
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -62,6 +62,7 @@ type InstrumentDTO struct {
 	LotSize       string `json:"lot_size"`
 	Type          string `json:"type"`
 	MaxLeverage   int    `json:"max_leverage"`
+	Sector        string `json:"sector"`
 	CreatedAt     int64  `json:"created_at"`
 	UpdatedAt     int64  `json:"updated_at"`
 }
@@ -153,6 +154,7 @@ func toInstrumentDTO(i *domain.Instrument) *InstrumentDTO {
 		LotSize:       decimal.NewFromFloat(i.LotSize).String(),
 		Type:          string(i.Type),
 		MaxLeverage:   i.MaxLeverage,
+		Sector:        i.Sector,
 		CreatedAt:     i.CreatedAt.Unix(),
 		UpdatedAt:     i.UpdatedAt.Unix(),
 	}
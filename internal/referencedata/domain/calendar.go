@@ -0,0 +1,394 @@
+package domain
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout 日历日期格式
+const DateLayout = "2006-01-02"
+
+var (
+	// ErrCalendarNotFound 日历不存在
+	ErrCalendarNotFound = errors.New("calendar not found")
+	// ErrInvalidHolidayFile 节假日文件格式错误
+	ErrInvalidHolidayFile = errors.New("invalid holiday file")
+)
+
+// CalendarKind 日历类型
+type CalendarKind string
+
+const (
+	CalendarExchange CalendarKind = "EXCHANGE" // 交易所/托管结算系统日历
+	CalendarCurrency CalendarKind = "CURRENCY" // 货币支付系统日历
+)
+
+// BusinessDayConvention 非工作日调整规则
+type BusinessDayConvention string
+
+const (
+	ConventionUnadjusted        BusinessDayConvention = "UNADJUSTED"
+	ConventionFollowing         BusinessDayConvention = "FOLLOWING"
+	ConventionModifiedFollowing BusinessDayConvention = "MODIFIED_FOLLOWING"
+	ConventionPreceding         BusinessDayConvention = "PRECEDING"
+)
+
+// Holiday 节假日
+type Holiday struct {
+	Date   time.Time `json:"date"`
+	Name   string    `json:"name"`
+	Source string    `json:"source"` // SEED, CSV, ICS
+}
+
+// HolidayCalendar 节假日日历，按交易所或币种维护，周末规则可配置
+type HolidayCalendar struct {
+	Code     string         `json:"code"`
+	Kind     CalendarKind   `json:"kind"`
+	Name     string         `json:"name"`
+	Timezone string         `json:"timezone"`
+	Weekend  []time.Weekday `json:"weekend"`
+	holidays map[string]Holiday
+}
+
+// NewHolidayCalendar 创建日历，默认周六、周日为周末
+func NewHolidayCalendar(code string, kind CalendarKind, name, timezone string) *HolidayCalendar {
+	return &HolidayCalendar{
+		Code:     strings.ToUpper(code),
+		Kind:     kind,
+		Name:     name,
+		Timezone: timezone,
+		Weekend:  []time.Weekday{time.Saturday, time.Sunday},
+		holidays: make(map[string]Holiday),
+	}
+}
+
+// AddHoliday 添加节假日，同一日期重复添加时覆盖
+func (c *HolidayCalendar) AddHoliday(h Holiday) {
+	if c.holidays == nil {
+		c.holidays = make(map[string]Holiday)
+	}
+	h.Date = CivilDate(h.Date)
+	c.holidays[h.Date.Format(DateLayout)] = h
+}
+
+// Holidays 返回按日期排序的节假日
+func (c *HolidayCalendar) Holidays() []Holiday {
+	list := make([]Holiday, 0, len(c.holidays))
+	for _, h := range c.holidays {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	return list
+}
+
+// IsHoliday 是否为节假日
+func (c *HolidayCalendar) IsHoliday(d time.Time) bool {
+	_, ok := c.holidays[CivilDate(d).Format(DateLayout)]
+	return ok
+}
+
+// IsWeekend 是否为周末
+func (c *HolidayCalendar) IsWeekend(d time.Time) bool {
+	wd := d.Weekday()
+	for _, w := range c.Weekend {
+		if w == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBusinessDay 是否为工作日
+func (c *HolidayCalendar) IsBusinessDay(d time.Time) bool {
+	return !c.IsWeekend(d) && !c.IsHoliday(d)
+}
+
+// BusinessCalendar 工作日判断
+type BusinessCalendar interface {
+	IsBusinessDay(d time.Time) bool
+}
+
+// JointCalendar 联合日历：所有日历均为工作日时才是工作日
+type JointCalendar []BusinessCalendar
+
+func (j JointCalendar) IsBusinessDay(d time.Time) bool {
+	for _, c := range j {
+		if c != nil && !c.IsBusinessDay(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// CivilDate 取日期部分（UTC 零点），日历运算均以日期为单位
+func CivilDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Adjust 按约定将非工作日调整为工作日：
+// FOLLOWING 顺延；MODIFIED_FOLLOWING 顺延跨月时改为提前；PRECEDING 提前
+func Adjust(cal BusinessCalendar, d time.Time, convention BusinessDayConvention) time.Time {
+	d = CivilDate(d)
+	switch convention {
+	case ConventionFollowing:
+		return rollForward(cal, d)
+	case ConventionModifiedFollowing:
+		next := rollForward(cal, d)
+		if next.Month() != d.Month() {
+			return rollBackward(cal, d)
+		}
+		return next
+	case ConventionPreceding:
+		return rollBackward(cal, d)
+	default:
+		return d
+	}
+}
+
+func rollForward(cal BusinessCalendar, d time.Time) time.Time {
+	for i := 0; i < 366 && !cal.IsBusinessDay(d); i++ {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+func rollBackward(cal BusinessCalendar, d time.Time) time.Time {
+	for i := 0; i < 366 && !cal.IsBusinessDay(d); i++ {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+// AddBusinessDays 从 d 起加减 n 个工作日，n 为 0 时按 FOLLOWING 调整
+func AddBusinessDays(cal BusinessCalendar, d time.Time, n int) time.Time {
+	d = CivilDate(d)
+	if n == 0 {
+		return rollForward(cal, d)
+	}
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		d = d.AddDate(0, 0, step)
+		if cal.IsBusinessDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// BusinessDaysBetween 统计 (from, to] 区间内的工作日数量
+func BusinessDaysBetween(cal BusinessCalendar, from, to time.Time) int {
+	from, to = CivilDate(from), CivilDate(to)
+	days := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if cal.IsBusinessDay(d) {
+			days++
+		}
+	}
+	return days
+}
+
+// SecuritiesSettlementDate 证券 T+N 预定结算日：按市场日历与结算币种日历的联合工作日计数，
+// 交易日本身为非工作日时先按 FOLLOWING 调整
+func SecuritiesSettlementDate(market, currency BusinessCalendar, tradeDate time.Time, days int) time.Time {
+	cal := JointCalendar{market, currency}
+	start := rollForward(cal, CivilDate(tradeDate))
+	return AddBusinessDays(cal, start, days)
+}
+
+// FXLegDate 外汇结算单一币种腿的交收日
+type FXLegDate struct {
+	Currency string    `json:"currency"`
+	Date     time.Time `json:"date"`
+}
+
+// FXSpotDays 货币对即期天数：USD/CAD 等北美货币对为 T+1，其余 T+2
+func FXSpotDays(base, quote string) int {
+	pair := strings.ToUpper(base + quote)
+	switch pair {
+	case "USDCAD", "CADUSD", "USDTRY", "TRYUSD", "USDRUB", "RUBUSD", "USDPHP", "PHPUSD":
+		return 1
+	}
+	return 2
+}
+
+// FXValueDate 外汇起息日：中间日只需非美元货币均为工作日（美元节假日不阻断计数），
+// 起息日必须同时是两种货币（及美元）的工作日，否则顺延。返回起息日及各币种腿交收日。
+func FXValueDate(base, quote, usd *HolidayCalendar, tradeDate time.Time, days int) (time.Time, []FXLegDate) {
+	var counting JointCalendar
+	for _, c := range []*HolidayCalendar{base, quote} {
+		if c != nil && c.Code != "USD" {
+			counting = append(counting, c)
+		}
+	}
+	if len(counting) == 0 && usd != nil {
+		counting = append(counting, usd)
+	}
+
+	settle := JointCalendar{}
+	for _, c := range []*HolidayCalendar{base, quote, usd} {
+		if c != nil {
+			settle = append(settle, c)
+		}
+	}
+
+	value := AddBusinessDays(counting, CivilDate(tradeDate), days)
+	value = rollForward(settle, value)
+
+	var legs []FXLegDate
+	for _, c := range []*HolidayCalendar{base, quote} {
+		if c != nil {
+			legs = append(legs, FXLegDate{Currency: c.Code, Date: value})
+		}
+	}
+	return value, legs
+}
+
+// ParseHolidayCSV 解析 CSV 节假日文件：date,name[,calendar]，首行可为表头。
+// 指定 calendar 列时按列值归属，否则归属 defaultCode。
+func ParseHolidayCSV(r io.Reader, defaultCode string) (map[string][]Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	result := make(map[string][]Holiday)
+	line := 0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHolidayFile, err)
+		}
+		line++
+		if len(rec) == 0 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		date, err := time.Parse(DateLayout, strings.TrimSpace(rec[0]))
+		if err != nil {
+			if line == 1 {
+				continue // 表头
+			}
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidHolidayFile, line, err)
+		}
+		h := Holiday{Date: date, Source: "CSV"}
+		if len(rec) > 1 {
+			h.Name = strings.TrimSpace(rec[1])
+		}
+		code := defaultCode
+		if len(rec) > 2 && strings.TrimSpace(rec[2]) != "" {
+			code = strings.TrimSpace(rec[2])
+		}
+		if code == "" {
+			return nil, fmt.Errorf("%w: line %d: calendar is required", ErrInvalidHolidayFile, line)
+		}
+		code = strings.ToUpper(code)
+		result[code] = append(result[code], h)
+	}
+	return result, nil
+}
+
+// ParseHolidayICS 解析 iCalendar 文件中的 VEVENT：DTSTART 为开始日，DTEND（不含）存在时展开多日事件
+func ParseHolidayICS(r io.Reader) ([]Holiday, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// 折行：以空格或制表符开头的行续接上一行
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHolidayFile, err)
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		prop, _, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(prop) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART":
+			if inEvent {
+				d, err := parseICSDate(value)
+				if err != nil {
+					return nil, err
+				}
+				start = d
+			}
+		case "DTEND":
+			if inEvent {
+				d, err := parseICSDate(value)
+				if err != nil {
+					return nil, err
+				}
+				end = d
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ").Replace(value)
+			}
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("%w: VEVENT without DTSTART", ErrInvalidHolidayFile)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: d, Name: summary, Source: "ICS"})
+			}
+		}
+	}
+	return holidays, nil
+}
+
+func parseICSDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 8 {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidHolidayFile, v)
+	}
+	d, err := time.Parse("20060102", v[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidHolidayFile, v)
+	}
+	return d, nil
+}
+
+// CalendarRepository 日历仓储
+type CalendarRepository interface {
+	SaveCalendar(ctx context.Context, cal *HolidayCalendar) error
+	// GetCalendar 返回含全部节假日的日历，不存在时返回 nil
+	GetCalendar(ctx context.Context, code string) (*HolidayCalendar, error)
+	ListCalendars(ctx context.Context) ([]*HolidayCalendar, error)
+	SaveHolidays(ctx context.Context, code string, holidays []Holiday) error
+	ListHolidays(ctx context.Context, code string, from, to time.Time) ([]Holiday, error)
+}
@@ -23,6 +23,7 @@ type Instrument struct {
 	LotSize       float64        `json:"lot_size"`
 	Type          InstrumentType `json:"type"`
 	MaxLeverage   int            `json:"max_leverage"`
+	Sector        string         `json:"sector"` // 行业分类，例如 FINANCIALS
 }
 
 func NewInstrument(symbol, base, quote string, tick, lot float64, typ InstrumentType) *Instrument {
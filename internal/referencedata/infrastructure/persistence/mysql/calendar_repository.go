@@ -0,0 +1,156 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/referencedata/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HolidayCalendarModel 节假日日历表映射
+type HolidayCalendarModel struct {
+	gorm.Model
+	Code     string `gorm:"column:code;type:varchar(16);uniqueIndex;not null"`
+	Kind     string `gorm:"column:kind;type:varchar(16);not null"`
+	Name     string `gorm:"column:name;type:varchar(100)"`
+	Timezone string `gorm:"column:timezone;type:varchar(50)"`
+	Weekend  string `gorm:"column:weekend;type:varchar(32)"` // 逗号分隔的 time.Weekday
+}
+
+func (HolidayCalendarModel) TableName() string { return "holiday_calendars" }
+
+// HolidayModel 节假日表映射
+type HolidayModel struct {
+	gorm.Model
+	CalendarCode string    `gorm:"column:calendar_code;type:varchar(16);uniqueIndex:uk_calendar_date;not null"`
+	Date         time.Time `gorm:"column:date;type:date;uniqueIndex:uk_calendar_date;not null"`
+	Name         string    `gorm:"column:name;type:varchar(100)"`
+	Source       string    `gorm:"column:source;type:varchar(16)"`
+}
+
+func (HolidayModel) TableName() string { return "holidays" }
+
+type calendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository 创建日历仓储实例
+func NewCalendarRepository(db *gorm.DB) domain.CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+func (r *calendarRepository) SaveCalendar(ctx context.Context, cal *domain.HolidayCalendar) error {
+	weekend := make([]string, 0, len(cal.Weekend))
+	for _, w := range cal.Weekend {
+		weekend = append(weekend, strconv.Itoa(int(w)))
+	}
+	model := &HolidayCalendarModel{
+		Code:     cal.Code,
+		Kind:     string(cal.Kind),
+		Name:     cal.Name,
+		Timezone: cal.Timezone,
+		Weekend:  strings.Join(weekend, ","),
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "name", "timezone", "weekend", "updated_at"}),
+	}).Create(model).Error
+}
+
+func (r *calendarRepository) GetCalendar(ctx context.Context, code string) (*domain.HolidayCalendar, error) {
+	var model HolidayCalendarModel
+	err := r.getDB(ctx).WithContext(ctx).Where("code = ?", strings.ToUpper(code)).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cal := toHolidayCalendar(&model)
+
+	holidays, err := r.ListHolidays(ctx, cal.Code, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holidays {
+		cal.AddHoliday(h)
+	}
+	return cal, nil
+}
+
+func (r *calendarRepository) ListCalendars(ctx context.Context) ([]*domain.HolidayCalendar, error) {
+	var models []HolidayCalendarModel
+	if err := r.getDB(ctx).WithContext(ctx).Order("code ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*domain.HolidayCalendar, 0, len(models))
+	for i := range models {
+		result = append(result, toHolidayCalendar(&models[i]))
+	}
+	return result, nil
+}
+
+// SaveHolidays 按 (日历, 日期) 覆盖名称与来源
+func (r *calendarRepository) SaveHolidays(ctx context.Context, code string, holidays []domain.Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+	models := make([]*HolidayModel, 0, len(holidays))
+	for _, h := range holidays {
+		models = append(models, &HolidayModel{
+			CalendarCode: strings.ToUpper(code),
+			Date:         domain.CivilDate(h.Date),
+			Name:         h.Name,
+			Source:       h.Source,
+		})
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "calendar_code"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "source", "updated_at"}),
+	}).CreateInBatches(models, 200).Error
+}
+
+func (r *calendarRepository) ListHolidays(ctx context.Context, code string, from, to time.Time) ([]domain.Holiday, error) {
+	db := r.getDB(ctx).WithContext(ctx).Where("calendar_code = ?", strings.ToUpper(code))
+	if !from.IsZero() {
+		db = db.Where("date >= ?", domain.CivilDate(from))
+	}
+	if !to.IsZero() {
+		db = db.Where("date <= ?", domain.CivilDate(to))
+	}
+	var models []HolidayModel
+	if err := db.Order("date ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]domain.Holiday, 0, len(models))
+	for _, m := range models {
+		result = append(result, domain.Holiday{Date: domain.CivilDate(m.Date), Name: m.Name, Source: m.Source})
+	}
+	return result, nil
+}
+
+func (r *calendarRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func toHolidayCalendar(m *HolidayCalendarModel) *domain.HolidayCalendar {
+	cal := domain.NewHolidayCalendar(m.Code, domain.CalendarKind(m.Kind), m.Name, m.Timezone)
+	if m.Weekend != "" {
+		cal.Weekend = nil
+		for _, s := range strings.Split(m.Weekend, ",") {
+			if w, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+				cal.Weekend = append(cal.Weekend, time.Weekday(w))
+			}
+		}
+	}
+	return cal
+}
@@ -44,6 +44,7 @@ type InstrumentModel struct {
 	LotSize       float64 `gorm:"column:lot_size;type:decimal(20,8);not null"`
 	Type          string  `gorm:"column:type;type:varchar(10);not null"`
 	MaxLeverage   int     `gorm:"column:max_leverage;default:1"`
+	Sector        string  `gorm:"column:sector;type:varchar(32)"`
 }
 
 func (InstrumentModel) TableName() string { return "instruments" }
@@ -125,6 +126,7 @@ func toInstrumentModel(i *domain.Instrument) *InstrumentModel {
 		LotSize:       i.LotSize,
 		Type:          string(i.Type),
 		MaxLeverage:   i.MaxLeverage,
+		Sector:        i.Sector,
 	}
 }
 
@@ -143,5 +145,6 @@ func toInstrument(m *InstrumentModel) *domain.Instrument {
 		LotSize:       m.LotSize,
 		Type:          domain.InstrumentType(m.Type),
 		MaxLeverage:   m.MaxLeverage,
+		Sector:        m.Sector,
 	}
 }
@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+	"github.com/wyfcoding/financialtrading/internal/referencedata/application"
+	"github.com/wyfcoding/financialtrading/internal/referencedata/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListCalendars 列出节假日日历
+func (h *Handler) ListCalendars(ctx context.Context, _ *pb.ListCalendarsRequest) (*pb.ListCalendarsResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	calendars, err := h.calendar.ListCalendars(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListCalendarsResponse{Calendars: make([]*pb.HolidayCalendar, 0, len(calendars))}
+	for _, c := range calendars {
+		weekend := make([]int32, 0, len(c.Weekend))
+		for _, w := range c.Weekend {
+			weekend = append(weekend, int32(w))
+		}
+		resp.Calendars = append(resp.Calendars, &pb.HolidayCalendar{
+			Code:     c.Code,
+			Kind:     string(c.Kind),
+			Name:     c.Name,
+			Timezone: c.Timezone,
+			Weekend:  weekend,
+		})
+	}
+	return resp, nil
+}
+
+// ListHolidays 查询日历节假日
+func (h *Handler) ListHolidays(ctx context.Context, req *pb.ListHolidaysRequest) (*pb.ListHolidaysResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	from, err := parseOptionalDate(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseOptionalDate(req.To)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := h.calendar.ListHolidays(ctx, req.Calendar, from, to)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListHolidaysResponse{Holidays: make([]*pb.Holiday, 0, len(holidays))}
+	for _, hd := range holidays {
+		resp.Holidays = append(resp.Holidays, &pb.Holiday{
+			Date:   hd.Date.Format(domain.DateLayout),
+			Name:   hd.Name,
+			Source: hd.Source,
+		})
+	}
+	return resp, nil
+}
+
+// ImportHolidays 导入 CSV/ICS 节假日文件
+func (h *Handler) ImportHolidays(ctx context.Context, req *pb.ImportHolidaysRequest) (*pb.ImportHolidaysResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	n, err := h.calendar.ImportHolidays(ctx, application.ImportHolidaysCommand{
+		Calendar: req.Calendar,
+		Format:   req.Format,
+		Data:     req.Data,
+		Kind:     domain.CalendarKind(req.Kind),
+		Name:     req.Name,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		return nil, calendarError(err)
+	}
+	return &pb.ImportHolidaysResponse{Imported: int32(n)}, nil
+}
+
+// GetSettlementDate 计算证券 T+N 预定结算日
+func (h *Handler) GetSettlementDate(ctx context.Context, req *pb.GetSettlementDateRequest) (*pb.GetSettlementDateResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	tradeDate, err := parseDate(req.TradeDate)
+	if err != nil {
+		return nil, err
+	}
+	date, err := h.calendar.SettlementDate(ctx, application.SettlementDateQuery{
+		Market:    req.Market,
+		Currency:  req.Currency,
+		TradeDate: tradeDate,
+		Days:      int(req.Days),
+	})
+	if err != nil {
+		return nil, calendarError(err)
+	}
+	return &pb.GetSettlementDateResponse{SettlementDate: date.Format(domain.DateLayout)}, nil
+}
+
+// GetFXValueDate 计算外汇起息日及各币种腿交收日
+func (h *Handler) GetFXValueDate(ctx context.Context, req *pb.GetFXValueDateRequest) (*pb.GetFXValueDateResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	tradeDate, err := parseDate(req.TradeDate)
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.calendar.FXValueDate(ctx, req.BaseCurrency, req.QuoteCurrency, tradeDate, int(req.Days))
+	if err != nil {
+		return nil, calendarError(err)
+	}
+	resp := &pb.GetFXValueDateResponse{
+		ValueDate: result.ValueDate.Format(domain.DateLayout),
+		SpotDays:  int32(result.SpotDays),
+		Legs:      make([]*pb.FXLegDate, 0, len(result.Legs)),
+	}
+	for _, leg := range result.Legs {
+		resp.Legs = append(resp.Legs, &pb.FXLegDate{Currency: leg.Currency, Date: leg.Date.Format(domain.DateLayout)})
+	}
+	return resp, nil
+}
+
+// AdjustDate 按联合日历与调整规则调整日期
+func (h *Handler) AdjustDate(ctx context.Context, req *pb.AdjustDateRequest) (*pb.AdjustDateResponse, error) {
+	if h.calendar == nil {
+		return nil, status.Error(codes.Unimplemented, "calendar service not configured")
+	}
+	date, err := parseDate(req.Date)
+	if err != nil {
+		return nil, err
+	}
+	convention := domain.BusinessDayConvention(req.Convention)
+	if convention == "" {
+		convention = domain.ConventionFollowing
+	}
+	adjusted, isBusinessDay, err := h.calendar.AdjustDate(ctx, req.Calendars, date, convention)
+	if err != nil {
+		return nil, calendarError(err)
+	}
+	return &pb.AdjustDateResponse{
+		Date:          adjusted.Format(domain.DateLayout),
+		IsBusinessDay: isBusinessDay,
+	}, nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	d, err := time.Parse(domain.DateLayout, v)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid date %q", v)
+	}
+	return d, nil
+}
+
+func parseOptionalDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return parseDate(v)
+}
+
+func calendarError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrCalendarNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidHolidayFile):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
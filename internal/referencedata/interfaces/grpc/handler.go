@@ -18,6 +18,8 @@ type Handler struct {
 	pb.UnimplementedReferenceDataServiceServer
 	cmd   *application.ReferenceDataCommandService
 	query *application.ReferenceDataQueryService
+
+	calendar *application.CalendarService
 }
 
 // NewHandler 创建 gRPC 处理器实例
@@ -28,6 +30,11 @@ func NewHandler(cmd *application.ReferenceDataCommandService, query *application
 	}
 }
 
+// SetCalendarService 设置节假日日历服务
+func (h *Handler) SetCalendarService(calendar *application.CalendarService) {
+	h.calendar = calendar
+}
+
 // GetInstrument 获取合约详情
 func (h *Handler) GetInstrument(ctx context.Context, req *pb.GetInstrumentRequest) (*pb.GetInstrumentResponse, error) {
 	dto, err := h.query.GetInstrument(ctx, req.Symbol)
@@ -134,6 +141,7 @@ func (h *Handler) toProtoInstrument(d *application.InstrumentDTO) *pb.Instrument
 		LotSize:       lot.InexactFloat64(),
 		Type:          mapInstrumentType(d.Type),
 		MaxLeverage:   int32(d.MaxLeverage),
+		Sector:        d.Sector,
 	}
 }
 
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wyfcoding/financialtrading/internal/referencedata/application"
+	"github.com/wyfcoding/financialtrading/internal/referencedata/domain"
+	"github.com/wyfcoding/pkg/logging"
+	"github.com/wyfcoding/pkg/response"
+)
+
+const maxHolidayFileSize = 4 << 20
+
+// ListCalendars 列出节假日日历
+func (h *ReferenceDataHandler) ListCalendars(c *gin.Context) {
+	calendars, err := h.calendar.ListCalendars(c.Request.Context())
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list calendars", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	response.Success(c, calendars)
+}
+
+// ListHolidays 查询日历节假日，可选 from/to（YYYY-MM-DD）
+func (h *ReferenceDataHandler) ListHolidays(c *gin.Context) {
+	code := c.Param("code")
+	from, ok := queryDate(c, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := queryDate(c, "to", false)
+	if !ok {
+		return
+	}
+	holidays, err := h.calendar.ListHolidays(c.Request.Context(), code, from, to)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list holidays", "calendar", code, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	response.Success(c, holidays)
+}
+
+// ImportHolidays 导入节假日文件：multipart 字段 file 或原始请求体，format 缺省时按文件扩展名判断
+func (h *ReferenceDataHandler) ImportHolidays(c *gin.Context) {
+	format := strings.ToUpper(c.Query("format"))
+	var data []byte
+	if fh, err := c.FormFile("file"); err == nil {
+		if format == "" {
+			format = strings.ToUpper(strings.TrimPrefix(filepath.Ext(fh.Filename), "."))
+		}
+		f, err := fh.Open()
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		defer f.Close()
+		data, err = io.ReadAll(io.LimitReader(f, maxHolidayFileSize))
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+	} else {
+		data, err = io.ReadAll(io.LimitReader(c.Request.Body, maxHolidayFileSize))
+		if err != nil {
+			response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+	}
+	if format == "" {
+		format = "CSV"
+	}
+
+	n, err := h.calendar.ImportHolidays(c.Request.Context(), application.ImportHolidaysCommand{
+		Calendar: c.Param("code"),
+		Format:   format,
+		Data:     data,
+		Kind:     domain.CalendarKind(strings.ToUpper(c.Query("kind"))),
+		Name:     c.Query("name"),
+		Timezone: c.Query("timezone"),
+	})
+	if err != nil {
+		h.calendarError(c, "Failed to import holidays", err)
+		return
+	}
+	response.Success(c, gin.H{"imported": n})
+}
+
+// GetSettlementDate 证券 T+N 预定结算日：market、currency、trade_date、days
+func (h *ReferenceDataHandler) GetSettlementDate(c *gin.Context) {
+	tradeDate, ok := queryDate(c, "trade_date", true)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "2"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid days", "")
+		return
+	}
+	date, err := h.calendar.SettlementDate(c.Request.Context(), application.SettlementDateQuery{
+		Market:    c.Query("market"),
+		Currency:  c.Query("currency"),
+		TradeDate: tradeDate,
+		Days:      days,
+	})
+	if err != nil {
+		h.calendarError(c, "Failed to compute settlement date", err)
+		return
+	}
+	response.Success(c, gin.H{"settlement_date": date.Format(domain.DateLayout)})
+}
+
+// GetFXValueDate 外汇起息日：base、quote、trade_date，days 缺省为即期
+func (h *ReferenceDataHandler) GetFXValueDate(c *gin.Context) {
+	tradeDate, ok := queryDate(c, "trade_date", true)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "0"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid days", "")
+		return
+	}
+	result, err := h.calendar.FXValueDate(c.Request.Context(), c.Query("base"), c.Query("quote"), tradeDate, days)
+	if err != nil {
+		h.calendarError(c, "Failed to compute fx value date", err)
+		return
+	}
+	response.Success(c, result)
+}
+
+// AdjustDate 按日历调整日期：calendars 逗号分隔，convention 缺省为 FOLLOWING
+func (h *ReferenceDataHandler) AdjustDate(c *gin.Context) {
+	date, ok := queryDate(c, "date", true)
+	if !ok {
+		return
+	}
+	calendars := strings.Split(c.Query("calendars"), ",")
+	convention := domain.BusinessDayConvention(strings.ToUpper(c.DefaultQuery("convention", string(domain.ConventionFollowing))))
+	adjusted, isBusinessDay, err := h.calendar.AdjustDate(c.Request.Context(), calendars, date, convention)
+	if err != nil {
+		h.calendarError(c, "Failed to adjust date", err)
+		return
+	}
+	response.Success(c, gin.H{"date": adjusted.Format(domain.DateLayout), "is_business_day": isBusinessDay})
+}
+
+func (h *ReferenceDataHandler) calendarError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrCalendarNotFound):
+		response.ErrorWithStatus(c, http.StatusNotFound, err.Error(), "")
+	case errors.Is(err, domain.ErrInvalidHolidayFile):
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+	default:
+		logging.Error(c.Request.Context(), msg, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+	}
+}
+
+func queryDate(c *gin.Context, key string, required bool) (time.Time, bool) {
+	v := c.Query(key)
+	if v == "" {
+		if required {
+			response.ErrorWithStatus(c, http.StatusBadRequest, key+" is required", "")
+			return time.Time{}, false
+		}
+		return time.Time{}, true
+	}
+	d, err := time.Parse(domain.DateLayout, v)
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid "+key, "")
+		return time.Time{}, false
+	}
+	return d, true
+}
@@ -13,7 +13,8 @@ import (
 
 // ReferenceDataHandler 负责处理与参考数据相关的 HTTP 请求
 type ReferenceDataHandler struct {
-	query *application.ReferenceDataQueryService
+	query    *application.ReferenceDataQueryService
+	calendar *application.CalendarService
 }
 
 // NewReferenceDataHandler 创建 HTTP 处理器实例
@@ -21,6 +22,11 @@ func NewReferenceDataHandler(query *application.ReferenceDataQueryService) *Refe
 	return &ReferenceDataHandler{query: query}
 }
 
+// SetCalendarService 设置节假日日历服务，设置后注册日历相关路由
+func (h *ReferenceDataHandler) SetCalendarService(calendar *application.CalendarService) {
+	h.calendar = calendar
+}
+
 // RegisterRoutes 注册路由
 func (h *ReferenceDataHandler) RegisterRoutes(router *gin.RouterGroup) {
 	api := router.Group("/api/v1/referencedata")
@@ -30,6 +36,15 @@ func (h *ReferenceDataHandler) RegisterRoutes(router *gin.RouterGroup) {
 		api.GET("/exchanges", h.ListExchanges)
 		api.GET("/exchanges/:id", h.GetExchange)
 	}
+	if h.calendar != nil {
+		api.GET("/calendars", h.ListCalendars)
+		api.GET("/calendars/:code/holidays", h.ListHolidays)
+		api.POST("/calendars/:code/import", h.ImportHolidays)
+		api.POST("/holidays/import", h.ImportHolidays)
+		api.GET("/settlement-date", h.GetSettlementDate)
+		api.GET("/fx-value-date", h.GetFXValueDate)
+		api.GET("/adjust-date", h.AdjustDate)
+	}
 }
 
 // GetSymbol 获取交易对
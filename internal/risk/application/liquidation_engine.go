@@ -4,16 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 	"time"
 
 	"github.com/shopspring/decimal"
 	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
 	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
 	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
 	"github.com/wyfcoding/pkg/messagequeue"
 )
 
-// LiquidationEngine 强平引擎，负责定期检查杠杆账户风险并触发强平。
+// LiquidationEngine 强平引擎，负责定期检查杠杆账户风险并分级处置：
+// 撤单 -> 按冲击成本部分平仓 -> 风险准备金接管 -> 自动减仓，全过程记录审计轨迹。
 type LiquidationEngine struct {
 	accountClient  accountv1.AccountServiceClient
 	positionClient positionv1.PositionServiceClient
@@ -21,6 +26,16 @@ type LiquidationEngine struct {
 	logger         *slog.Logger
 	checkInterval  time.Duration
 	mmThreshold    decimal.Decimal // 维持保证金阈值，例如 110% (1.1)
+
+	riskRepo     domain.RiskRepository
+	caseRepo     domain.LiquidationRepository
+	canceller    domain.OpenOrderCanceller
+	books        domain.OrderBookProvider
+	maxSlippage  float64       // 部分强平允许吃到的最大滑点
+	bookDepth    int           // 评估冲击时读取的订单簿档数
+	cooldown     time.Duration // 已下发强平指令后等待成交回报的时间
+	fundCurrency string        // 风险准备金币种
+	marginState  *domain.MarginStateCache
 }
 
 func NewLiquidationEngine(
@@ -36,9 +51,34 @@ func NewLiquidationEngine(
 		logger:         logger,
 		checkInterval:  10 * time.Second,
 		mmThreshold:    decimal.NewFromFloat(1.1),
+		maxSlippage:    0.02,
+		bookDepth:      20,
+		cooldown:       30 * time.Second,
+		fundCurrency:   "USD",
 	}
 }
 
+// SetRepository 注入案件仓储；riskRepo 提供事务边界
+func (e *LiquidationEngine) SetRepository(riskRepo domain.RiskRepository, caseRepo domain.LiquidationRepository) {
+	e.riskRepo = riskRepo
+	e.caseRepo = caseRepo
+}
+
+// SetOrderCanceller 注入撤单能力，未注入时跳过撤单阶段
+func (e *LiquidationEngine) SetOrderCanceller(canceller domain.OpenOrderCanceller) {
+	e.canceller = canceller
+}
+
+// SetMarginState 注入保证金监控维护的内存账户状态，自动减仓候选从中排序
+func (e *LiquidationEngine) SetMarginState(state *domain.MarginStateCache) {
+	e.marginState = state
+}
+
+// SetOrderBookProvider 注入订单簿深度，未注入时按现价估算
+func (e *LiquidationEngine) SetOrderBookProvider(books domain.OrderBookProvider) {
+	e.books = books
+}
+
 // Start 启动强平引擎监控循环。
 func (e *LiquidationEngine) Start(ctx context.Context) error {
 	ticker := time.NewTicker(e.checkInterval)
@@ -61,6 +101,14 @@ func (e *LiquidationEngine) Start(ctx context.Context) error {
 
 // RunCycle 执行一次扫描。
 func (e *LiquidationEngine) RunCycle(ctx context.Context) error {
+	return e.eachMarginAccount(ctx, func(acc *accountv1.AccountResponse) {
+		if err := e.CheckAccountRisk(ctx, acc); err != nil {
+			e.logger.Error("failed to check account risk", "account_id", acc.AccountId, "error", err)
+		}
+	})
+}
+
+func (e *LiquidationEngine) eachMarginAccount(ctx context.Context, fn func(acc *accountv1.AccountResponse)) error {
 	pageToken := int32(0)
 	for {
 		resp, err := e.accountClient.ListAccounts(ctx, &accountv1.ListAccountsRequest{
@@ -73,9 +121,7 @@ func (e *LiquidationEngine) RunCycle(ctx context.Context) error {
 		}
 
 		for _, acc := range resp.Accounts {
-			if err := e.CheckAccountRisk(ctx, acc); err != nil {
-				e.logger.Error("failed to check account risk", "account_id", acc.AccountId, "error", err)
-			}
+			fn(acc)
 		}
 
 		if resp.NextPageToken == 0 {
@@ -86,13 +132,20 @@ func (e *LiquidationEngine) RunCycle(ctx context.Context) error {
 	return nil
 }
 
-// CheckAccountRisk 检查单个账户的强平风险。
-func (e *LiquidationEngine) CheckAccountRisk(ctx context.Context, acc *accountv1.AccountResponse) error {
+// accountSnapshot 账户保证金快照
+type accountSnapshot struct {
+	positions   []*positionv1.Position
+	equity      decimal.Decimal
+	usedMargin  decimal.Decimal
+	marginLevel decimal.Decimal
+}
+
+func (e *LiquidationEngine) snapshot(ctx context.Context, acc *accountv1.AccountResponse) (*accountSnapshot, error) {
 	posResp, err := e.positionClient.GetPositions(ctx, &positionv1.GetPositionsRequest{
 		UserId: acc.UserId,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get positions for user %s: %w", acc.UserId, err)
+		return nil, fmt.Errorf("failed to get positions for user %s: %w", acc.UserId, err)
 	}
 
 	totalUsedMargin := decimal.Zero
@@ -108,46 +161,481 @@ func (e *LiquidationEngine) CheckAccountRisk(ctx context.Context, acc *accountv1
 		totalUnrealizedPnL = totalUnrealizedPnL.Add(unrealizedPnL)
 	}
 
-	if totalUsedMargin.IsZero() {
-		return nil
+	snap := &accountSnapshot{
+		positions:  posResp.Positions,
+		equity:     balance.Add(totalUnrealizedPnL),
+		usedMargin: totalUsedMargin,
+	}
+	if !totalUsedMargin.IsZero() {
+		snap.marginLevel = snap.equity.Div(totalUsedMargin)
+	}
+	return snap, nil
+}
+
+// CheckAccountRisk 检查单个账户的强平风险，并按阶段推进强平案件。
+func (e *LiquidationEngine) CheckAccountRisk(ctx context.Context, acc *accountv1.AccountResponse) error {
+	snap, err := e.snapshot(ctx, acc)
+	if err != nil {
+		return err
 	}
 
-	equity := balance.Add(totalUnrealizedPnL)
-	marginLevel := equity.Div(totalUsedMargin)
+	var active *domain.LiquidationCase
+	if e.caseRepo != nil {
+		if active, err = e.caseRepo.GetActiveCase(ctx, acc.AccountId); err != nil {
+			return fmt.Errorf("failed to load liquidation case: %w", err)
+		}
+	}
+
+	if snap.usedMargin.IsZero() || snap.marginLevel.GreaterThanOrEqual(e.mmThreshold) {
+		if active != nil {
+			return e.recover(ctx, active, snap)
+		}
+		return nil
+	}
 
 	e.logger.Debug("Account risk check",
 		"account_id", acc.AccountId,
-		"equity", equity.String(),
-		"used_margin", totalUsedMargin.String(),
-		"margin_level", marginLevel.String())
+		"equity", snap.equity.String(),
+		"used_margin", snap.usedMargin.String(),
+		"margin_level", snap.marginLevel.String())
 
-	if marginLevel.LessThan(e.mmThreshold) {
+	// 已下发的强平指令仍在成交窗口内，等待回报后再推进
+	if active != nil && time.Since(active.UpdatedAt) < e.cooldown {
+		return nil
+	}
+
+	c := active
+	if c == nil {
 		e.logger.Warn("LIQUIDATION TRIGGERED",
 			"account_id", acc.AccountId,
 			"user_id", acc.UserId,
-			"margin_level", marginLevel.String())
+			"margin_level", snap.marginLevel.String())
+		c = domain.NewLiquidationCase(fmt.Sprintf("LIQ-%d", idgen.GenID()), acc.UserId, acc.AccountId, snap.marginLevel, snap.equity, snap.usedMargin)
+	}
+
+	// 阶段一：撤销挂单，释放冻结保证金后重新评估
+	if e.canceller != nil && !hasStage(c, domain.LiquidationStageCancelOrders) {
+		n, err := e.canceller.CancelOpenOrders(ctx, acc.UserId)
+		step := domain.LiquidationStep{
+			Stage:            domain.LiquidationStageCancelOrders,
+			Action:           "CANCEL_OPEN_ORDERS",
+			Quantity:         decimal.NewFromInt(int64(n)),
+			MarginLevelAfter: snap.marginLevel,
+			Detail:           fmt.Sprintf("cancelled %d open orders", n),
+		}
+		if err != nil {
+			step.Detail = fmt.Sprintf("cancelled %d open orders, error: %v", n, err)
+		}
+		if n > 0 {
+			if refreshed, rerr := e.refresh(ctx, acc); rerr == nil {
+				snap = refreshed
+				step.MarginLevelAfter = snap.marginLevel
+			}
+		}
+		c.Record(step)
+		if snap.usedMargin.IsZero() || snap.marginLevel.GreaterThanOrEqual(e.mmThreshold) {
+			return e.recover(ctx, c, snap)
+		}
+	}
+
+	// 阶段二：按单位冲击成本释放的保证金排序，部分平仓
+	positions := toLiquidationPositions(snap.positions)
+	required := snap.usedMargin
+	if snap.equity.IsPositive() {
+		required = snap.usedMargin.Sub(snap.equity.Div(e.mmThreshold))
+	}
+	plan := domain.PlanPartialLiquidation(positions, e.orderBooks(ctx, positions), required.InexactFloat64(), e.maxSlippage)
+
+	triggered := make([]domain.PositionLiquidationTriggeredEvent, 0, len(plan.Orders))
+	for _, o := range plan.Orders {
+		c.Record(domain.LiquidationStep{
+			Stage:            domain.LiquidationStagePartial,
+			Action:           "LIQUIDATE",
+			Symbol:           o.Symbol,
+			Side:             o.Side,
+			Quantity:         decimal.NewFromFloat(o.Quantity),
+			Price:            decimal.NewFromFloat(o.EstimatedVWAP),
+			Amount:           decimal.NewFromFloat(o.MarginRelief),
+			MarginLevelAfter: snap.marginLevel,
+			Detail:           fmt.Sprintf("position=%s impact_cost=%.8f score=%.4f", o.PositionID, o.ImpactCost, o.Score),
+		})
+		triggered = append(triggered, domain.PositionLiquidationTriggeredEvent{
+			CaseID:        c.ID,
+			UserID:        acc.UserId,
+			AccountID:     acc.AccountId,
+			Symbol:        o.Symbol,
+			Side:          o.Side,
+			Quantity:      o.Quantity,
+			MarginLevel:   snap.marginLevel.InexactFloat64(),
+			TriggerPrice:  o.EstimatedVWAP,
+			TriggerReason: "Margin Level below MM threshold",
+			TriggeredAt:   time.Now().Unix(),
+			OccurredOn:    time.Now(),
+		})
+	}
+
+	deficit := decimal.Zero
+	if snap.equity.IsNegative() {
+		deficit = snap.equity.Neg()
+	}
+	c.Deficit = deficit
+
+	var (
+		takeovers []domain.InsuranceFundTakeoverEvent
+		adl       []domain.PositionAutoDeleveragedEvent
+	)
+	if len(plan.Residual) > 0 || deficit.IsPositive() {
+		takeovers, adl, err = e.absorbResidual(ctx, c, snap, plan.Residual)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.persist(ctx, c, triggered, takeovers, adl)
+}
+
+// absorbResidual 阶段三/四：风险准备金接管，不足部分对盈利对手方自动减仓
+func (e *LiquidationEngine) absorbResidual(ctx context.Context, c *domain.LiquidationCase, snap *accountSnapshot, residual []domain.LiquidationPosition) ([]domain.InsuranceFundTakeoverEvent, []domain.PositionAutoDeleveragedEvent, error) {
+	if e.caseRepo == nil || e.riskRepo == nil {
+		c.Record(domain.LiquidationStep{
+			Stage:  domain.LiquidationStageInsuranceFund,
+			Action: "SKIPPED",
+			Amount: c.Deficit,
+			Detail: "insurance fund repository not configured",
+		})
+		c.Close(domain.LiquidationStatusFailed)
+		return nil, nil, nil
+	}
+
+	residualMargin := 0.0
+	for _, r := range residual {
+		residualMargin += r.MarginRequirement
+	}
+	bankruptcy := make([]float64, len(residual))
+	for i, r := range residual {
+		share := snap.equity.InexactFloat64()
+		if residualMargin > 0 {
+			share *= r.MarginRequirement / residualMargin
+		}
+		bankruptcy[i] = domain.BankruptcyPrice(r, share)
+	}
+
+	var (
+		takeovers []domain.InsuranceFundTakeoverEvent
+		takenOver bool
+	)
+	err := e.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		fund, err := e.caseRepo.GetInsuranceFund(txCtx, e.fundCurrency)
+		if err != nil {
+			return err
+		}
+		takenOver = fund.CanTakeOver(c.Deficit, decimal.NewFromFloat(residualMargin))
+		covered := fund.Cover(c.Deficit)
+		if err := e.caseRepo.SaveInsuranceFund(txCtx, fund); err != nil {
+			return err
+		}
+		c.Record(domain.LiquidationStep{
+			Stage:  domain.LiquidationStageInsuranceFund,
+			Action: "COVER_DEFICIT",
+			Amount: covered,
+			Detail: fmt.Sprintf("deficit=%s fund_balance=%s", c.Deficit.String(), fund.Balance.String()),
+		})
+		if !takenOver {
+			return nil
+		}
+		for i, r := range residual {
+			c.Record(domain.LiquidationStep{
+				Stage:    domain.LiquidationStageInsuranceFund,
+				Action:   "TAKEOVER",
+				Symbol:   r.Symbol,
+				Side:     sideOf(r.Quantity),
+				Quantity: decimal.NewFromFloat(math.Abs(r.Quantity)),
+				Price:    decimal.NewFromFloat(bankruptcy[i]),
+				Amount:   decimal.NewFromFloat(r.MarginRequirement),
+			})
+			takeovers = append(takeovers, domain.InsuranceFundTakeoverEvent{
+				CaseID:          c.ID,
+				UserID:          c.UserID,
+				Symbol:          r.Symbol,
+				Side:            sideOf(r.Quantity),
+				Quantity:        math.Abs(r.Quantity),
+				BankruptcyPrice: bankruptcy[i],
+				DeficitCovered:  covered.String(),
+				FundBalance:     fund.Balance.String(),
+				OccurredOn:      time.Now(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("insurance fund stage failed: %w", err)
+	}
+	if takenOver {
+		c.Close(domain.LiquidationStatusResolved)
+		return takeovers, nil, nil
+	}
+	if len(residual) == 0 {
+		// 仅有穿仓、无剩余持仓，准备金不足的部分记为未弥补损失
+		c.Close(domain.LiquidationStatusFailed)
+		return nil, nil, nil
+	}
+
+	candidates, err := e.adlCandidates(ctx, c.UserID, residual)
+	if err != nil {
+		return nil, nil, err
+	}
+	ranked := domain.RankADLCandidates(candidates)
 
-		for _, pos := range posResp.Positions {
-			qty, _ := decimal.NewFromString(pos.Quantity)
-			event := domain.PositionLiquidationTriggeredEvent{
+	adl := make([]domain.PositionAutoDeleveragedEvent, 0)
+	unfilled := 0.0
+	for i, r := range residual {
+		allocations, remaining := domain.PlanAutoDeleverage(r, ranked, bankruptcy[i])
+		for _, a := range allocations {
+			c.Record(domain.LiquidationStep{
+				Stage:        domain.LiquidationStageAutoDeleverage,
+				Action:       "DELEVERAGE",
+				Symbol:       r.Symbol,
+				Side:         sideOf(a.Candidate.Quantity),
+				Quantity:     decimal.NewFromFloat(a.Quantity),
+				Price:        decimal.NewFromFloat(a.Price),
+				Counterparty: a.Candidate.UserID,
+				Detail:       fmt.Sprintf("position=%s score=%.4f", a.Candidate.PositionID, a.Candidate.Score),
+			})
+			adl = append(adl, domain.PositionAutoDeleveragedEvent{
+				CaseID:           c.ID,
+				UserID:           a.Candidate.UserID,
+				AccountID:        a.Candidate.AccountID,
+				PositionID:       a.Candidate.PositionID,
+				LiquidatedUserID: c.UserID,
+				Symbol:           r.Symbol,
+				Side:             sideOf(a.Candidate.Quantity),
+				Quantity:         a.Quantity,
+				BankruptcyPrice:  a.Price,
+				OccurredOn:       time.Now(),
+			})
+		}
+		unfilled += remaining
+	}
+	if unfilled > 0 {
+		c.Record(domain.LiquidationStep{
+			Stage:    domain.LiquidationStageAutoDeleverage,
+			Action:   "UNFILLED",
+			Quantity: decimal.NewFromFloat(unfilled),
+			Detail:   "no profitable opposite positions left",
+		})
+		c.Close(domain.LiquidationStatusFailed)
+	} else {
+		c.Close(domain.LiquidationStatusResolved)
+	}
+	return nil, adl, nil
+}
+
+// adlCandidates 从事件驱动维护的内存保证金状态中收集与剩余持仓同品种的持仓，方向由 PlanAutoDeleverage 匹配；
+// 未注入内存状态时回退为逐户扫描杠杆账户
+func (e *LiquidationEngine) adlCandidates(ctx context.Context, liquidatedUser string, residual []domain.LiquidationPosition) ([]domain.ADLCandidate, error) {
+	symbols := make([]string, 0, len(residual))
+	for _, r := range residual {
+		if !slices.Contains(symbols, r.Symbol) {
+			symbols = append(symbols, r.Symbol)
+		}
+	}
+	if e.marginState != nil {
+		return e.marginState.ADLCandidates(symbols, liquidatedUser), nil
+	}
+
+	e.logger.WarnContext(ctx, "margin state not configured, scanning margin accounts for adl candidates")
+	candidates := make([]domain.ADLCandidate, 0)
+	err := e.eachMarginAccount(ctx, func(acc *accountv1.AccountResponse) {
+		if acc.UserId == liquidatedUser {
+			return
+		}
+		snap, err := e.snapshot(ctx, acc)
+		if err != nil {
+			e.logger.WarnContext(ctx, "skip adl candidate account", "account_id", acc.AccountId, "error", err)
+			return
+		}
+		for _, p := range toLiquidationPositions(snap.positions) {
+			if !slices.Contains(symbols, p.Symbol) {
+				continue
+			}
+			candidates = append(candidates, domain.ADLCandidate{
 				UserID:        acc.UserId,
 				AccountID:     acc.AccountId,
-				Symbol:        pos.Symbol,
-				Side:          pos.Side,
-				Quantity:      qty.InexactFloat64(),
-				MarginLevel:   marginLevel.InexactFloat64(),
-				TriggerReason: "Margin Level below MM threshold",
-				TriggeredAt:   time.Now().Unix(),
-				OccurredOn:    time.Now(),
+				PositionID:    p.PositionID,
+				Symbol:        p.Symbol,
+				Quantity:      p.Quantity,
+				EntryPrice:    p.EntryPrice,
+				UnrealizedPnL: (p.Price - p.EntryPrice) * p.Quantity,
+				Equity:        snap.equity.InexactFloat64(),
+			})
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect adl candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+func (e *LiquidationEngine) recover(ctx context.Context, c *domain.LiquidationCase, snap *accountSnapshot) error {
+	c.Record(domain.LiquidationStep{
+		Stage:            domain.LiquidationStageRecovered,
+		Action:           "MARGIN_RESTORED",
+		MarginLevelAfter: snap.marginLevel,
+		Detail:           fmt.Sprintf("equity=%s used_margin=%s", snap.equity.String(), snap.usedMargin.String()),
+	})
+	c.Close(domain.LiquidationStatusResolved)
+	e.logger.Info("liquidation case resolved", "case_id", c.ID, "account_id", c.AccountID)
+	return e.persist(ctx, c, nil, nil, nil)
+}
+
+func (e *LiquidationEngine) refresh(ctx context.Context, acc *accountv1.AccountResponse) (*accountSnapshot, error) {
+	resp, err := e.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{AccountId: acc.AccountId, UserId: acc.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Account != nil {
+		acc = resp.Account
+	}
+	return e.snapshot(ctx, acc)
+}
+
+func (e *LiquidationEngine) orderBooks(ctx context.Context, positions []domain.LiquidationPosition) map[string]*domain.OrderBookDepth {
+	books := make(map[string]*domain.OrderBookDepth, len(positions))
+	if e.books == nil {
+		return books
+	}
+	for _, p := range positions {
+		if _, ok := books[p.Symbol]; ok {
+			continue
+		}
+		book, err := e.books.GetOrderBookDepth(ctx, p.Symbol, e.bookDepth)
+		if err != nil {
+			e.logger.Warn("order book unavailable, using mark price", "symbol", p.Symbol, "error", err)
+			book = nil
+		}
+		books[p.Symbol] = book
+	}
+	return books
+}
+
+// persist 保存案件并发布事件；未配置仓储时退化为仅发布
+func (e *LiquidationEngine) persist(
+	ctx context.Context,
+	c *domain.LiquidationCase,
+	triggered []domain.PositionLiquidationTriggeredEvent,
+	takeovers []domain.InsuranceFundTakeoverEvent,
+	adl []domain.PositionAutoDeleveragedEvent,
+) error {
+	updated := domain.LiquidationCaseUpdatedEvent{
+		CaseID:     c.ID,
+		UserID:     c.UserID,
+		AccountID:  c.AccountID,
+		Status:     string(c.Status),
+		Stage:      string(c.Stage),
+		Deficit:    c.Deficit.String(),
+		Steps:      len(c.Steps),
+		OccurredOn: time.Now(),
+	}
+
+	if e.caseRepo == nil || e.riskRepo == nil {
+		if e.publisher == nil {
+			return nil
+		}
+		for _, ev := range triggered {
+			if err := e.publisher.Publish(ctx, domain.PositionLiquidationTriggeredEventType, c.UserID, ev); err != nil {
+				e.logger.Error("failed to publish liquidation event", "error", err)
 			}
+		}
+		return nil
+	}
 
-			if e.publisher != nil {
-				if err := e.publisher.Publish(ctx, domain.PositionLiquidationTriggeredEventType, acc.UserId, event); err != nil {
-					e.logger.Error("failed to publish liquidation event", "error", err)
-				}
+	return e.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := e.caseRepo.SaveCase(txCtx, c); err != nil {
+			return err
+		}
+		if e.publisher == nil {
+			return nil
+		}
+		tx := contextx.GetTx(txCtx)
+		for _, ev := range triggered {
+			if err := e.publisher.PublishInTx(ctx, tx, domain.PositionLiquidationTriggeredEventType, c.UserID, ev); err != nil {
+				return err
 			}
 		}
+		for _, ev := range takeovers {
+			if err := e.publisher.PublishInTx(ctx, tx, domain.InsuranceFundTakeoverEventType, c.UserID, ev); err != nil {
+				return err
+			}
+		}
+		for _, ev := range adl {
+			if err := e.publisher.PublishInTx(ctx, tx, domain.PositionAutoDeleveragedEventType, ev.UserID, ev); err != nil {
+				return err
+			}
+		}
+		return e.publisher.PublishInTx(ctx, tx, domain.LiquidationCaseUpdatedEventType, c.UserID, updated)
+	})
+}
+
+// ListCases 查询用户强平案件
+func (e *LiquidationEngine) ListCases(ctx context.Context, userID string, limit int) ([]*domain.LiquidationCase, error) {
+	if e.caseRepo == nil {
+		return nil, fmt.Errorf("liquidation repository not configured")
 	}
+	if limit <= 0 {
+		limit = 50
+	}
+	return e.caseRepo.ListCases(ctx, userID, limit)
+}
 
-	return nil
+// GetCase 查询强平案件及审计轨迹
+func (e *LiquidationEngine) GetCase(ctx context.Context, id string) (*domain.LiquidationCase, error) {
+	if e.caseRepo == nil {
+		return nil, fmt.Errorf("liquidation repository not configured")
+	}
+	return e.caseRepo.GetCase(ctx, id)
+}
+
+func hasStage(c *domain.LiquidationCase, stage domain.LiquidationStage) bool {
+	for _, s := range c.Steps {
+		if s.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func sideOf(qty float64) string {
+	if qty > 0 {
+		return "buy"
+	}
+	return "sell"
+}
+
+// toLiquidationPositions 持仓数量统一为带符号（空头为负），缺失现价时回退到开仓价
+func toLiquidationPositions(positions []*positionv1.Position) []domain.LiquidationPosition {
+	out := make([]domain.LiquidationPosition, 0, len(positions))
+	for _, p := range positions {
+		qty, _ := decimal.NewFromString(p.Quantity)
+		if qty.IsZero() {
+			continue
+		}
+		if p.Side == "sell" && qty.IsPositive() {
+			qty = qty.Neg()
+		}
+		entry, _ := decimal.NewFromString(p.EntryPrice)
+		price, err := decimal.NewFromString(p.CurrentPrice)
+		if err != nil || price.IsZero() {
+			price = entry
+		}
+		margin, _ := decimal.NewFromString(p.MarginRequirement)
+		out = append(out, domain.LiquidationPosition{
+			PositionID:        p.PositionId,
+			Symbol:            p.Symbol,
+			Quantity:          qty.InexactFloat64(),
+			Price:             price.InexactFloat64(),
+			EntryPrice:        entry.InexactFloat64(),
+			MarginRequirement: margin.InexactFloat64(),
+		})
+	}
+	return out
 }
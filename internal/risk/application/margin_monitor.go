@@ -0,0 +1,345 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+)
+
+// MarginMonitor 事件驱动的实时保证金监控。
+// 账户权益与保证金状态常驻内存，由成交、持仓、行情与资金事件增量更新，
+// 只有输入发生变化的账户才会被重新评估；跌破维持保证金阈值时交由 LiquidationEngine 处置。
+type MarginMonitor struct {
+	cache          *domain.MarginStateCache
+	liq            *LiquidationEngine
+	accountClient  accountv1.AccountServiceClient
+	positionClient positionv1.PositionServiceClient
+	snapshots      domain.MarginSnapshotRepository
+	ruleEngine     *domain.RealTimeRiskEngine
+	logger         *slog.Logger
+
+	evalInterval      time.Duration // 待评估账户的批量处理间隔
+	snapshotInterval  time.Duration
+	reconcileInterval time.Duration // 全量回源对账间隔，兜底事件丢失
+
+	// 以下字段仅由监控循环所在协程访问
+	escalated map[string]time.Time // 已交由强平引擎处理的账户 -> 上次复核时间
+}
+
+func NewMarginMonitor(
+	liq *LiquidationEngine,
+	accClient accountv1.AccountServiceClient,
+	posClient positionv1.PositionServiceClient,
+	logger *slog.Logger,
+) *MarginMonitor {
+	return &MarginMonitor{
+		cache:             domain.NewMarginStateCache(),
+		liq:               liq,
+		accountClient:     accClient,
+		positionClient:    posClient,
+		logger:            logger,
+		evalInterval:      200 * time.Millisecond,
+		snapshotInterval:  30 * time.Second,
+		reconcileInterval: 30 * time.Minute,
+		escalated:         make(map[string]time.Time),
+	}
+}
+
+// SetSnapshotRepository 注入快照仓储，未注入时启动即全量加载
+func (m *MarginMonitor) SetSnapshotRepository(repo domain.MarginSnapshotRepository) {
+	m.snapshots = repo
+}
+
+// State 返回事件驱动维护的账户保证金状态，供自动减仓排序等只读场景使用
+func (m *MarginMonitor) State() *domain.MarginStateCache {
+	return m.cache
+}
+
+// SetRuleEngine 注入事前风控规则引擎，评估时同步其持仓与敞口缓存
+func (m *MarginMonitor) SetRuleEngine(engine *domain.RealTimeRiskEngine) {
+	m.ruleEngine = engine
+}
+
+// Start 恢复状态后启动评估循环。
+func (m *MarginMonitor) Start(ctx context.Context) error {
+	restored := m.restore(ctx)
+	if !restored {
+		if err := m.Reconcile(ctx); err != nil {
+			m.logger.Error("initial margin state load failed", "error", err)
+		}
+	}
+
+	evalTicker := time.NewTicker(m.evalInterval)
+	defer evalTicker.Stop()
+	snapshotTicker := time.NewTicker(m.snapshotInterval)
+	defer snapshotTicker.Stop()
+	reconcileTicker := time.NewTicker(m.reconcileInterval)
+	defer reconcileTicker.Stop()
+
+	// 快照之后、停机之前消费的事件可能未进入快照，恢复后尽快对账一次
+	reconcileNow := make(chan struct{}, 1)
+	if restored {
+		reconcileNow <- struct{}{}
+	}
+
+	m.logger.Info("Margin monitor started", "accounts", m.cache.Len(), "restored", restored)
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.saveSnapshot(context.WithoutCancel(ctx))
+			m.logger.Info("Margin monitor stopping...")
+			return nil
+		case <-evalTicker.C:
+			m.evaluatePending(ctx)
+		case <-snapshotTicker.C:
+			m.saveSnapshot(ctx)
+		case <-reconcileNow:
+			if err := m.Reconcile(ctx); err != nil {
+				m.logger.Error("margin state reconcile failed", "error", err)
+			}
+		case <-reconcileTicker.C:
+			if err := m.Reconcile(ctx); err != nil {
+				m.logger.Error("margin state reconcile failed", "error", err)
+			}
+		}
+	}
+}
+
+// Reconcile 全量回源加载全部杠杆账户，用于冷启动与定期兜底
+func (m *MarginMonitor) Reconcile(ctx context.Context) error {
+	loaded := 0
+	err := m.liq.eachMarginAccount(ctx, func(acc *accountv1.AccountResponse) {
+		if err := m.load(ctx, acc); err != nil {
+			m.logger.Warn("failed to load margin state", "account_id", acc.AccountId, "error", err)
+			return
+		}
+		loaded++
+	})
+	m.logger.Info("margin state reconciled", "accounts", loaded)
+	return err
+}
+
+// OnPositionChanged 持仓事件：以最新数量和均价更新
+func (m *MarginMonitor) OnPositionChanged(userID, symbol string, quantity, entryPrice decimal.Decimal) {
+	m.cache.ApplyPosition(userID, symbol, quantity, entryPrice)
+	if m.ruleEngine != nil {
+		mark, ok := m.cache.Mark(symbol)
+		if !ok {
+			mark = entryPrice
+		}
+		m.ruleEngine.SetPosition(userID, symbol, quantity, entryPrice, mark)
+	}
+}
+
+// OnTrade 成交事件：成交价作为标记价格，并唤醒双方账户
+func (m *MarginMonitor) OnTrade(symbol string, price decimal.Decimal, userIDs ...string) {
+	m.cache.ApplyPrice(symbol, price)
+	for _, userID := range userIDs {
+		m.cache.TouchUser(userID)
+	}
+}
+
+// OnPrice 行情事件：更新标记价格
+func (m *MarginMonitor) OnPrice(symbol string, price decimal.Decimal) {
+	m.cache.ApplyPrice(symbol, price)
+}
+
+// OnBalance 携带最新余额的资金事件
+func (m *MarginMonitor) OnBalance(accountID string, balance decimal.Decimal) {
+	m.cache.ApplyBalance(accountID, balance)
+}
+
+// OnAccountChanged 无法增量应用的资金事件，标记账户回源刷新
+func (m *MarginMonitor) OnAccountChanged(accountID string) {
+	m.cache.MarkStale(accountID)
+}
+
+// OnAccountCreated 新开户事件，杠杆账户纳入监控
+func (m *MarginMonitor) OnAccountCreated(ctx context.Context, accountID, userID string) error {
+	resp, err := m.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{AccountId: accountID, UserId: userID})
+	if err != nil {
+		return fmt.Errorf("failed to get account %s: %w", accountID, err)
+	}
+	if resp.Account == nil || resp.Account.AccountType != "MARGIN" {
+		return nil
+	}
+	return m.load(ctx, resp.Account)
+}
+
+// GetAccountState 查询内存中的账户保证金状态
+func (m *MarginMonitor) GetAccountState(accountID string) (*domain.AccountMarginState, domain.MarginFigures, bool) {
+	return m.cache.Get(accountID)
+}
+
+func (m *MarginMonitor) evaluatePending(ctx context.Context) {
+	pending := m.cache.DrainDirty()
+	// 已升级的账户按强平引擎的检查周期复核，直到案件结束
+	for accountID, last := range m.escalated {
+		if time.Since(last) >= m.liq.checkInterval {
+			pending = append(pending, accountID)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(pending))
+	for _, accountID := range pending {
+		if _, ok := seen[accountID]; ok {
+			continue
+		}
+		seen[accountID] = struct{}{}
+		if err := m.evaluate(ctx, accountID); err != nil {
+			m.logger.Error("failed to evaluate margin", "account_id", accountID, "error", err)
+		}
+	}
+}
+
+func (m *MarginMonitor) evaluate(ctx context.Context, accountID string) error {
+	state, figures, ok := m.cache.Get(accountID)
+	if !ok {
+		delete(m.escalated, accountID)
+		return nil
+	}
+	if state.Stale {
+		acc, err := m.fetchAccount(ctx, state)
+		if err != nil {
+			return err
+		}
+		if err := m.load(ctx, acc); err != nil {
+			return err
+		}
+		// 回源加载会再次标记账户，这里直接使用最新状态继续评估
+		m.cache.DrainOne(accountID)
+		if state, figures, ok = m.cache.Get(accountID); !ok {
+			return nil
+		}
+	}
+
+	m.syncRuleEngine(state)
+
+	below := figures.UsedMargin.IsPositive() && figures.MarginLevel.LessThan(m.liq.mmThreshold)
+	_, wasEscalated := m.escalated[accountID]
+	if !below && !wasEscalated {
+		return nil
+	}
+
+	// 强平决策以回源数据为准，内存状态只负责触发
+	acc, err := m.fetchAccount(ctx, state)
+	if err != nil {
+		return err
+	}
+	if below {
+		m.logger.Warn("margin level below threshold",
+			"account_id", accountID,
+			"user_id", state.UserID,
+			"margin_level", figures.MarginLevel.String())
+		m.escalated[accountID] = time.Now()
+	} else {
+		delete(m.escalated, accountID)
+	}
+	return m.liq.CheckAccountRisk(ctx, acc)
+}
+
+// syncRuleEngine 以最新标记价格刷新规则引擎中该用户的持仓与多空敞口
+func (m *MarginMonitor) syncRuleEngine(state *domain.AccountMarginState) {
+	if m.ruleEngine == nil {
+		return
+	}
+	exposure := &domain.UserExposure{UserID: state.UserID}
+	for symbol, p := range state.Positions {
+		mark, ok := m.cache.Mark(symbol)
+		if !ok || !mark.IsPositive() {
+			mark = p.EntryPrice
+		}
+		m.ruleEngine.SetPosition(state.UserID, symbol, p.Quantity, p.EntryPrice, mark)
+		value := p.Quantity.Mul(mark)
+		if value.IsPositive() {
+			exposure.LongExposure = exposure.LongExposure.Add(value)
+		} else {
+			exposure.ShortExposure = exposure.ShortExposure.Add(value.Abs())
+		}
+	}
+	exposure.GrossExposure = exposure.LongExposure.Add(exposure.ShortExposure)
+	exposure.NetExposure = exposure.LongExposure.Sub(exposure.ShortExposure)
+	exposure.DeltaExposure = exposure.NetExposure
+	m.ruleEngine.UpdateExposure(state.UserID, exposure)
+}
+
+func (m *MarginMonitor) fetchAccount(ctx context.Context, state *domain.AccountMarginState) (*accountv1.AccountResponse, error) {
+	resp, err := m.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{AccountId: state.AccountID, UserId: state.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %w", state.AccountID, err)
+	}
+	if resp.Account == nil {
+		m.cache.Remove(state.AccountID)
+		return nil, fmt.Errorf("account %s not found", state.AccountID)
+	}
+	return resp.Account, nil
+}
+
+// load 以账户服务与持仓服务的数据重建账户状态
+func (m *MarginMonitor) load(ctx context.Context, acc *accountv1.AccountResponse) error {
+	posResp, err := m.positionClient.GetPositions(ctx, &positionv1.GetPositionsRequest{UserId: acc.UserId})
+	if err != nil {
+		return fmt.Errorf("failed to get positions for user %s: %w", acc.UserId, err)
+	}
+	balance, _ := decimal.NewFromString(acc.Balance)
+	state := &domain.AccountMarginState{
+		AccountID: acc.AccountId,
+		UserID:    acc.UserId,
+		Balance:   balance,
+		Positions: make(map[string]*domain.MarginPosition, len(posResp.Positions)),
+	}
+	for _, p := range toLiquidationPositions(posResp.Positions) {
+		rate := decimal.Zero
+		if notional := p.Quantity * p.Price; notional != 0 {
+			rate = decimal.NewFromFloat(p.MarginRequirement / math.Abs(notional))
+		}
+		state.Positions[p.Symbol] = &domain.MarginPosition{
+			PositionID: p.PositionID,
+			Symbol:     p.Symbol,
+			Quantity:   decimal.NewFromFloat(p.Quantity),
+			EntryPrice: decimal.NewFromFloat(p.EntryPrice),
+			MarginRate: rate,
+		}
+		// 用回源现价补齐尚未收到行情的品种
+		if p.Price > 0 {
+			m.cache.SeedPrice(p.Symbol, decimal.NewFromFloat(p.Price))
+		}
+	}
+	m.cache.Load(state)
+	return nil
+}
+
+func (m *MarginMonitor) restore(ctx context.Context) bool {
+	if m.snapshots == nil {
+		return false
+	}
+	snap, err := m.snapshots.LoadMarginSnapshot(ctx)
+	if err != nil {
+		m.logger.Warn("failed to load margin snapshot", "error", err)
+		return false
+	}
+	if snap == nil {
+		return false
+	}
+	m.cache.Restore(snap)
+	m.logger.Info("margin state restored from snapshot", "sequence", snap.Sequence, "taken_at", snap.TakenAt, "accounts", len(snap.Accounts))
+	return true
+}
+
+func (m *MarginMonitor) saveSnapshot(ctx context.Context) {
+	if m.snapshots == nil {
+		return
+	}
+	snap := m.cache.Snapshot()
+	if err := m.snapshots.SaveMarginSnapshot(ctx, snap); err != nil {
+		m.logger.Error("failed to save margin snapshot", "error", err)
+	}
+}
@@ -0,0 +1,172 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+// PreTradeRuleService 管理事前风控表达式规则：
+// 规则持久化在仓储中，保存前编译校验，并由后台循环按版本变化热加载到 RealTimeRiskEngine。
+type PreTradeRuleService struct {
+	engine *domain.RealTimeRiskEngine
+	repo   domain.RiskRuleRepository
+	logger *slog.Logger
+
+	reloadInterval time.Duration
+	// 最近一次加载的规则集版本，mu 同时串行化并发的重新加载
+	mu            sync.Mutex
+	loadedCount   int64
+	loadedUpdated time.Time
+}
+
+// NewPreTradeRuleService 构造函数。
+func NewPreTradeRuleService(engine *domain.RealTimeRiskEngine, repo domain.RiskRuleRepository, logger *slog.Logger) *PreTradeRuleService {
+	return &PreTradeRuleService{
+		engine:         engine,
+		repo:           repo,
+		logger:         logger,
+		reloadInterval: 10 * time.Second,
+	}
+}
+
+// Engine 返回规则引擎
+func (s *PreTradeRuleService) Engine() *domain.RealTimeRiskEngine {
+	return s.engine
+}
+
+// Start 首次加载规则后轮询规则集版本，变化时热加载
+func (s *PreTradeRuleService) Start(ctx context.Context) error {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error("initial risk rule load failed", "error", err)
+	}
+
+	ticker := time.NewTicker(s.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			count, updated, err := s.repo.Version(ctx)
+			if err != nil {
+				s.logger.Warn("failed to read risk rule version", "error", err)
+				continue
+			}
+			s.mu.Lock()
+			unchanged := count == s.loadedCount && updated.Equal(s.loadedUpdated)
+			s.mu.Unlock()
+			if unchanged {
+				continue
+			}
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error("risk rule reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// Reload 从仓储加载启用的规则并原子替换引擎规则集；无效规则被跳过并记录
+func (s *PreTradeRuleService) Reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, updated, err := s.repo.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read rule version: %w", err)
+	}
+	rules, err := s.repo.ListEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list enabled rules: %w", err)
+	}
+	loaded, invalid := s.engine.LoadRules(rules)
+	if invalid != nil {
+		s.logger.Warn("invalid risk rules skipped", "error", invalid)
+	}
+	s.loadedCount, s.loadedUpdated = count, updated
+	s.logger.Info("risk rules loaded", "loaded", loaded, "total", len(rules))
+	return nil
+}
+
+// SaveRule 校验并保存规则，保存后立即重新加载
+func (s *PreTradeRuleService) SaveRule(ctx context.Context, cmd SaveRiskRuleCommand) (*domain.RiskRule, error) {
+	threshold := decimal.Zero
+	if cmd.Threshold != "" {
+		var err error
+		if threshold, err = decimal.NewFromString(cmd.Threshold); err != nil {
+			return nil, fmt.Errorf("invalid threshold: %w", err)
+		}
+	}
+	rule := &domain.RiskRule{
+		ID:              cmd.RuleID,
+		Name:            cmd.Name,
+		Category:        domain.RiskRuleCategory(cmd.Category),
+		Description:     cmd.Description,
+		Enabled:         cmd.Enabled,
+		Priority:        cmd.Priority,
+		Condition:       cmd.Condition,
+		Expression:      cmd.Expression,
+		Threshold:       threshold,
+		Action:          domain.RiskAction(cmd.Action),
+		CoolDownSeconds: cmd.CoolDownSeconds,
+		NotifyChannels:  cmd.NotifyChannels,
+	}
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("RR-%d", idgen.GenID())
+	}
+	if _, err := domain.CompileRiskRule(rule); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByID(ctx, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		err = s.repo.Create(ctx, rule)
+	} else {
+		err = s.repo.Update(ctx, rule)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error("risk rule reload after save failed", "rule_id", rule.ID, "error", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule 删除规则并重新加载
+func (s *PreTradeRuleService) DeleteRule(ctx context.Context, ruleID string) error {
+	if ruleID == "" {
+		return errors.New("rule id is required")
+	}
+	if err := s.repo.Delete(ctx, ruleID); err != nil {
+		return err
+	}
+	return s.Reload(ctx)
+}
+
+// ListRules 列出仓储中的全部规则（含未启用）
+func (s *PreTradeRuleService) ListRules(ctx context.Context) ([]*domain.RiskRule, error) {
+	return s.repo.List(ctx)
+}
+
+// ValidateExpression 编译表达式但不保存
+func (s *PreTradeRuleService) ValidateExpression(expr string) error {
+	_, err := domain.CompileRuleExpression(expr)
+	return err
+}
+
+// RuleStats 各规则的求值次数、命中次数与耗时统计
+func (s *PreTradeRuleService) RuleStats() []*domain.RuleStat {
+	return s.engine.RuleStats()
+}
@@ -0,0 +1,408 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+// PreTradeControlService 订单级事前控制的管理面：
+// 维护控制参数、交易台归属与熔断开关并同步到内存引擎，熔断时撤销范围内挂单，
+// 所有变更与拒单均写入审计。
+type PreTradeControlService struct {
+	controls  *domain.PreTradeControlEngine
+	repo      domain.PreTradeControlRepository
+	riskRepo  domain.RiskRepository
+	publisher messagequeue.EventPublisher
+	canceller domain.ScopedOrderCanceller
+	logger    *slog.Logger
+
+	syncInterval time.Duration // 多实例部署时从仓储同步参数与开关的间隔
+	// syncMu 避免整体同步覆盖尚未落库的开关变更
+	syncMu  sync.Mutex
+	audits  chan *domain.ControlAudit
+	dropped atomic.Int64
+}
+
+// NewPreTradeControlService 构造函数。
+func NewPreTradeControlService(
+	controls *domain.PreTradeControlEngine,
+	repo domain.PreTradeControlRepository,
+	riskRepo domain.RiskRepository,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+) *PreTradeControlService {
+	s := &PreTradeControlService{
+		controls:     controls,
+		repo:         repo,
+		riskRepo:     riskRepo,
+		publisher:    publisher,
+		logger:       logger,
+		syncInterval: 5 * time.Second,
+		audits:       make(chan *domain.ControlAudit, 4096),
+	}
+	controls.SetRejectionHook(s.onReject)
+	return s
+}
+
+// SetOrderCanceller 注入撤单能力，未注入时熔断开关只拒绝新单
+func (s *PreTradeControlService) SetOrderCanceller(canceller domain.ScopedOrderCanceller) {
+	s.canceller = canceller
+}
+
+// Start 加载参数后运行同步、清理与拒单审计落库循环
+func (s *PreTradeControlService) Start(ctx context.Context) error {
+	if err := s.Sync(ctx); err != nil {
+		s.logger.Error("initial pre-trade control load failed", "error", err)
+	}
+
+	syncTicker := time.NewTicker(s.syncInterval)
+	defer syncTicker.Stop()
+	sweepTicker := time.NewTicker(time.Minute)
+	defer sweepTicker.Stop()
+	flushTicker := time.NewTicker(time.Second)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushAudits(context.WithoutCancel(ctx))
+			return nil
+		case <-syncTicker.C:
+			if err := s.Sync(ctx); err != nil {
+				s.logger.Warn("pre-trade control sync failed", "error", err)
+			}
+		case <-sweepTicker.C:
+			s.controls.Sweep()
+		case <-flushTicker.C:
+			s.flushAudits(ctx)
+		}
+	}
+}
+
+// Sync 从仓储整体加载控制参数、交易台归属与生效中的熔断开关
+func (s *PreTradeControlService) Sync(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	limits, err := s.repo.ListLimits(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list limits: %w", err)
+	}
+	desks, err := s.repo.ListDesks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list desks: %w", err)
+	}
+	kills, err := s.repo.ListKillSwitches(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to list kill switches: %w", err)
+	}
+	s.controls.LoadLimits(limits)
+	s.controls.LoadDesks(desks)
+	s.controls.LoadKillSwitches(kills)
+	return nil
+}
+
+// SaveLimits 保存一组控制参数
+func (s *PreTradeControlService) SaveLimits(ctx context.Context, cmd SavePreTradeLimitsCommand) (*domain.PreTradeLimits, error) {
+	limits, err := cmd.toLimits()
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	limits.UpdatedAt = time.Now()
+
+	err = s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.SaveLimits(txCtx, limits); err != nil {
+			return err
+		}
+		return s.repo.AppendAudits(txCtx, []*domain.ControlAudit{s.audit(domain.AuditLimitsUpdated, string(limits.Scope), limits.Key, cmd.Actor,
+			fmt.Sprintf("qty<=%s notional<=%s deviation<=%s otr<=%s(min %d) msgs<=%d/%ds",
+				limits.MaxOrderQuantity, limits.MaxOrderNotional, limits.MaxPriceDeviation,
+				limits.MaxOrderToTradeRatio, limits.MinOrdersForRatio, limits.MaxMessages, limits.WindowSeconds))})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.controls.SetLimits(limits)
+	return limits, nil
+}
+
+// RemoveLimits 删除一组控制参数
+func (s *PreTradeControlService) RemoveLimits(ctx context.Context, scope domain.ControlScope, key, actor string) error {
+	err := s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeleteLimits(txCtx, scope, key); err != nil {
+			return err
+		}
+		return s.repo.AppendAudits(txCtx, []*domain.ControlAudit{s.audit(domain.AuditLimitsRemoved, string(scope), key, actor, "")})
+	})
+	if err != nil {
+		return err
+	}
+	s.controls.RemoveLimits(scope, key)
+	return nil
+}
+
+// AssignDesk 设置用户所属交易台
+func (s *PreTradeControlService) AssignDesk(ctx context.Context, cmd AssignDeskCommand) error {
+	if cmd.UserID == "" {
+		return errors.New("user_id is required")
+	}
+	err := s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.AssignDesk(txCtx, cmd.UserID, cmd.Desk); err != nil {
+			return err
+		}
+		a := s.audit(domain.AuditDeskAssigned, string(domain.KillSwitchDesk), cmd.Desk, cmd.Actor, "")
+		a.UserID = cmd.UserID
+		return s.repo.AppendAudits(txCtx, []*domain.ControlAudit{a})
+	})
+	if err != nil {
+		return err
+	}
+	s.controls.AssignDesk(cmd.UserID, cmd.Desk)
+	return nil
+}
+
+// ActivateKillSwitch 激活熔断开关：先在内存中拦截新单，再持久化并撤销范围内全部挂单。
+// 撤单失败不回滚开关，失败原因记录在审计与返回结果中。
+func (s *PreTradeControlService) ActivateKillSwitch(ctx context.Context, cmd ActivateKillSwitchCommand) (*domain.KillSwitch, error) {
+	scope := domain.KillSwitchScope(cmd.Scope)
+	switch scope {
+	case domain.KillSwitchAccount, domain.KillSwitchDesk, domain.KillSwitchSymbol:
+	default:
+		return nil, fmt.Errorf("unsupported kill switch scope %q", cmd.Scope)
+	}
+	if cmd.Target == "" {
+		return nil, errors.New("target is required")
+	}
+
+	ks := &domain.KillSwitch{
+		ID:          fmt.Sprintf("KS-%d", idgen.GenID()),
+		Scope:       scope,
+		Target:      cmd.Target,
+		Reason:      cmd.Reason,
+		Active:      true,
+		ActivatedBy: cmd.Actor,
+		ActivatedAt: time.Now(),
+	}
+	s.syncMu.Lock()
+	s.controls.Activate(ks)
+	err := s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.SaveKillSwitch(txCtx, ks); err != nil {
+			return err
+		}
+		if err := s.repo.AppendAudits(txCtx, []*domain.ControlAudit{s.audit(domain.AuditKillSwitchActivated, string(scope), cmd.Target, cmd.Actor, cmd.Reason)}); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		return s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.KillSwitchActivatedEventType, ks.ID, domain.KillSwitchActivatedEvent{
+			KillSwitchID: ks.ID,
+			Scope:        string(ks.Scope),
+			Target:       ks.Target,
+			Reason:       ks.Reason,
+			ActivatedBy:  ks.ActivatedBy,
+			OccurredOn:   ks.ActivatedAt,
+		})
+	})
+	if err != nil {
+		s.controls.Release(ks.ID)
+	}
+	s.syncMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, cancelErr := s.cancelScope(ctx, ks)
+	ks.CancelledOrders = cancelled
+	detail := fmt.Sprintf("cancelled %d resting orders", cancelled)
+	if cancelErr != nil {
+		detail += ": " + cancelErr.Error()
+		s.logger.Error("kill switch order cancellation incomplete", "kill_switch_id", ks.ID, "cancelled", cancelled, "error", cancelErr)
+	}
+	if err := s.repo.SaveKillSwitch(ctx, ks); err != nil {
+		s.logger.Error("failed to update kill switch", "kill_switch_id", ks.ID, "error", err)
+	}
+	s.enqueueAudit(s.audit(domain.AuditKillSwitchCancelled, string(scope), cmd.Target, cmd.Actor, detail))
+	s.logger.Warn("kill switch activated", "kill_switch_id", ks.ID, "scope", ks.Scope, "target", ks.Target, "cancelled", cancelled)
+	return ks, cancelErr
+}
+
+// ReleaseKillSwitch 解除熔断开关
+func (s *PreTradeControlService) ReleaseKillSwitch(ctx context.Context, id, actor string) (*domain.KillSwitch, error) {
+	ks, err := s.repo.GetKillSwitch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ks == nil {
+		return nil, fmt.Errorf("kill switch %s not found", id)
+	}
+	if !ks.Active {
+		return ks, nil
+	}
+	now := time.Now()
+	ks.Active = false
+	ks.ReleasedBy = actor
+	ks.ReleasedAt = &now
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	err = s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.SaveKillSwitch(txCtx, ks); err != nil {
+			return err
+		}
+		if err := s.repo.AppendAudits(txCtx, []*domain.ControlAudit{s.audit(domain.AuditKillSwitchReleased, string(ks.Scope), ks.Target, actor, ks.ID)}); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		return s.publisher.PublishInTx(ctx, contextx.GetTx(txCtx), domain.KillSwitchReleasedEventType, ks.ID, domain.KillSwitchReleasedEvent{
+			KillSwitchID: ks.ID,
+			Scope:        string(ks.Scope),
+			Target:       ks.Target,
+			ReleasedBy:   actor,
+			OccurredOn:   now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.controls.Release(ks.ID)
+	return ks, nil
+}
+
+// ListKillSwitches 查询熔断开关
+func (s *PreTradeControlService) ListKillSwitches(ctx context.Context, activeOnly bool) ([]*domain.KillSwitch, error) {
+	return s.repo.ListKillSwitches(ctx, activeOnly)
+}
+
+// ListLimits 当前生效的控制参数
+func (s *PreTradeControlService) ListLimits() []domain.PreTradeLimits {
+	return s.controls.Limits()
+}
+
+// Usage 查询用户的报单频率、报单成交比与生效中的开关
+func (s *PreTradeControlService) Usage(userID string) *domain.ControlUsage {
+	return s.controls.Usage(userID)
+}
+
+// ListAudits 查询审计记录，target 为空时返回全部
+func (s *PreTradeControlService) ListAudits(ctx context.Context, target string, limit int) ([]*domain.ControlAudit, error) {
+	s.flushAudits(ctx)
+	return s.repo.ListAudits(ctx, target, limit)
+}
+
+// OnQuote 行情事件：更新价格笼子参考价
+func (s *PreTradeControlService) OnQuote(symbol string, last, bid, ask decimal.Decimal) {
+	s.controls.UpdateReferencePrice(symbol, last, bid, ask)
+}
+
+// OnTrade 成交事件：更新最新价并计入双方成交数
+func (s *PreTradeControlService) OnTrade(symbol string, price decimal.Decimal, userIDs ...string) {
+	s.controls.UpdateReferencePrice(symbol, price, decimal.Zero, decimal.Zero)
+	for _, userID := range userIDs {
+		if userID != "" {
+			s.controls.RecordTrade(userID, symbol)
+		}
+	}
+}
+
+func (s *PreTradeControlService) cancelScope(ctx context.Context, ks *domain.KillSwitch) (int, error) {
+	if s.canceller == nil {
+		return 0, nil
+	}
+	reason := "kill switch " + ks.ID
+	var filters []domain.OrderCancelFilter
+	switch ks.Scope {
+	case domain.KillSwitchAccount:
+		filters = append(filters, domain.OrderCancelFilter{UserID: ks.Target, Reason: reason})
+	case domain.KillSwitchDesk:
+		for _, userID := range s.controls.DeskMembers(ks.Target) {
+			filters = append(filters, domain.OrderCancelFilter{UserID: userID, Reason: reason})
+		}
+	case domain.KillSwitchSymbol:
+		filters = append(filters, domain.OrderCancelFilter{Symbol: ks.Target, Reason: reason})
+	}
+
+	total := 0
+	var errs []error
+	for _, f := range filters {
+		n, err := s.canceller.CancelOrders(ctx, f)
+		total += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+func (s *PreTradeControlService) onReject(order *domain.ControlOrder, decision domain.ControlDecision) {
+	a := s.audit(domain.AuditOrderRejected, string(decision.Scope), order.UserID, "", decision.Reason)
+	a.UserID = order.UserID
+	a.Symbol = order.Symbol
+	a.Control = decision.Control
+	if decision.Limit != "" {
+		a.Detail = fmt.Sprintf("%s (limit %s, actual %s)", decision.Reason, decision.Limit, decision.Actual)
+	}
+	s.enqueueAudit(a)
+}
+
+// enqueueAudit 拒单发生在下单热路径上，审计异步批量落库；队列满时丢弃并计数
+func (s *PreTradeControlService) enqueueAudit(a *domain.ControlAudit) {
+	select {
+	case s.audits <- a:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *PreTradeControlService) flushAudits(ctx context.Context) {
+	if n := s.dropped.Swap(0); n > 0 {
+		s.logger.Warn("pre-trade control audits dropped", "count", n)
+	}
+	for {
+		batch := make([]*domain.ControlAudit, 0, 256)
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case a := <-s.audits:
+				batch = append(batch, a)
+			default:
+				break drain
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.repo.AppendAudits(ctx, batch); err != nil {
+			s.logger.Error("failed to persist pre-trade control audits", "count", len(batch), "error", err)
+			return
+		}
+	}
+}
+
+func (s *PreTradeControlService) audit(kind domain.ControlAuditKind, scope, target, actor, detail string) *domain.ControlAudit {
+	return &domain.ControlAudit{
+		ID:        fmt.Sprintf("CA-%d", idgen.GenID()),
+		Kind:      kind,
+		Scope:     scope,
+		Target:    target,
+		Detail:    detail,
+		Actor:     actor,
+		CreatedAt: time.Now(),
+	}
+}
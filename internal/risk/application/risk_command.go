@@ -22,6 +22,7 @@ type RiskCommandService struct {
 	positionClient positionv1.PositionServiceClient
 	publisher      messagequeue.EventPublisher
 	marginCalc     domain.MarginCalculator
+	ruleEngine     *domain.RealTimeRiskEngine
 }
 
 // NewRiskCommandService 创建新的 RiskCommandService 实例
@@ -43,6 +44,11 @@ func NewRiskCommandService(
 	}
 }
 
+// SetRuleEngine 注入事前风控规则引擎，注入后风险评估会执行已加载的规则
+func (s *RiskCommandService) SetRuleEngine(engine *domain.RealTimeRiskEngine) {
+	s.ruleEngine = engine
+}
+
 // AssessRisk 风险评估
 func (s *RiskCommandService) AssessRisk(ctx context.Context, cmd AssessRiskCommand) (*RiskAssessmentDTO, error) {
 	assessmentID := cmd.AssessmentID
@@ -99,6 +105,15 @@ func (s *RiskCommandService) AssessRisk(ctx context.Context, cmd AssessRiskComma
 		}
 	}
 
+	// 3. 事前风控规则 (表达式规则引擎)
+	if isAllowed && s.ruleEngine != nil {
+		result, err := s.ruleEngine.CheckPreTrade(ctx, s.preTradeContext(ctx, cmd))
+		if err == nil && !result.Passed {
+			isAllowed = false
+			reason = result.Reason
+		}
+	}
+
 	if !isAllowed && reason == "" {
 		reason = "Risk level too high or system limit reached"
 	}
@@ -181,6 +196,38 @@ func (s *RiskCommandService) AssessRisk(ctx context.Context, cmd AssessRiskComma
 	return toRiskAssessmentDTO(assessment), nil
 }
 
+// preTradeContext 构造规则求值上下文，仅当规则引用账户字段时才回源查询账户
+func (s *RiskCommandService) preTradeContext(ctx context.Context, cmd AssessRiskCommand) *domain.RiskCheckContext {
+	quantity := decimal.NewFromFloat(cmd.Quantity)
+	price := decimal.NewFromFloat(cmd.Price)
+	check := &domain.RiskCheckContext{
+		UserID:     cmd.UserID,
+		Symbol:     cmd.Symbol,
+		Side:       cmd.Side,
+		Quantity:   quantity,
+		Price:      price,
+		OrderValue: quantity.Mul(price),
+		Timestamp:  time.Now(),
+	}
+	if s.accountClient == nil || !s.ruleEngine.NeedsAccountValue() {
+		return check
+	}
+	resp, err := s.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{UserId: cmd.UserID})
+	if err != nil || resp == nil || resp.Account == nil {
+		return check
+	}
+	balance, _ := decimal.NewFromString(resp.Account.Balance)
+	borrowed, _ := decimal.NewFromString(resp.Account.BorrowedAmount)
+	interest, _ := decimal.NewFromString(resp.Account.AccruedInterest)
+	check.AccountID = resp.Account.AccountId
+	// 账户价值取净资产，当前杠杆为总资产与净资产之比
+	check.AccountValue = balance.Sub(borrowed).Sub(interest)
+	if check.AccountValue.IsPositive() {
+		check.Leverage = balance.Div(check.AccountValue)
+	}
+	return check
+}
+
 // UpdateRiskLimit 更新风险限额
 func (s *RiskCommandService) UpdateRiskLimit(ctx context.Context, cmd UpdateRiskLimitCommand) (*RiskLimitDTO, error) {
 	limitID := cmd.LimitID
@@ -15,6 +15,9 @@ type RiskQueryService struct {
 	repo       domain.RiskRepository
 	readRepo   domain.RiskReadRepository
 	searchRepo domain.RiskSearchRepository
+	varEngine  *domain.VaREngine
+
+	stressTests *StressTestService
 }
 
 // NewRiskQueryService 构造函数。
@@ -26,6 +29,16 @@ func NewRiskQueryService(repo domain.RiskRepository, readRepo domain.RiskReadRep
 	}
 }
 
+// SetVaREngine 注入全量重估 VaR 引擎
+func (s *RiskQueryService) SetVaREngine(engine *domain.VaREngine) {
+	s.varEngine = engine
+}
+
+// SetStressTestService 注入压力场景库，压力测试查询使用场景库中的最新版本
+func (s *RiskQueryService) SetStressTestService(svc *StressTestService) {
+	s.stressTests = svc
+}
+
 // GetRiskMetrics 获取风险指标（优先缓存）
 func (s *RiskQueryService) GetRiskMetrics(ctx context.Context, userID string) (*RiskMetricsDTO, error) {
 	if s.readRepo != nil {
@@ -170,6 +183,7 @@ func (s *RiskQueryService) CalculatePortfolioRisk(ctx context.Context, req *Calc
 		TimeHorizon:       horizon,
 		Simulations:       simulations,
 		ConfidenceLevel:   confidence,
+		Seed:              req.Seed,
 	})
 	if err != nil {
 		return nil, err
@@ -229,6 +243,7 @@ func (s *RiskQueryService) CalculateMonteCarloRisk(ctx context.Context, req *Mon
 		T:          req.T,
 		Iterations: iterations,
 		Steps:      steps,
+		Seed:       req.Seed,
 	})
 
 	return &MonteCarloRiskResponse{
@@ -239,6 +254,99 @@ func (s *RiskQueryService) CalculateMonteCarloRisk(ctx context.Context, req *Mon
 	}, nil
 }
 
+// CalculateVaR 基于历史情景/过滤历史情景/相关蒙特卡洛的全量重估组合 VaR
+func (s *RiskQueryService) CalculateVaR(ctx context.Context, req *CalculateVaRRequest) (*CalculateVaRResponse, error) {
+	if req == nil || len(req.Positions) == 0 {
+		return nil, errors.New("positions required")
+	}
+	if s.varEngine == nil {
+		return nil, errors.New("var engine not configured")
+	}
+
+	positions := make([]domain.VaRPosition, 0, len(req.Positions))
+	for _, p := range req.Positions {
+		qty, err := decimal.NewFromString(p.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for %s", p.Symbol)
+		}
+		price, err := decimal.NewFromString(p.Price)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price for %s", p.Symbol)
+		}
+		multiplier := decimal.NewFromInt(1)
+		if p.Multiplier != "" {
+			if multiplier, err = decimal.NewFromString(p.Multiplier); err != nil {
+				return nil, fmt.Errorf("invalid multiplier for %s", p.Symbol)
+			}
+		}
+		instrumentType := domain.InstrumentType(p.Type)
+		if instrumentType == "" {
+			instrumentType = domain.InstrumentSpot
+		}
+		pos := domain.VaRPosition{
+			PositionID:   p.PositionID,
+			Symbol:       p.Symbol,
+			Underlying:   p.Underlying,
+			Type:         instrumentType,
+			Quantity:     qty,
+			Multiplier:   multiplier,
+			Price:        price,
+			OptionType:   p.OptionType,
+			Strike:       p.Strike,
+			ImpliedVol:   p.ImpliedVol,
+			RiskFreeRate: p.RiskFreeRate,
+		}
+		if p.ExpiryDate > 0 {
+			pos.Expiry = time.UnixMilli(p.ExpiryDate)
+		}
+		if (pos.Type == domain.InstrumentOption || pos.Type == domain.InstrumentFutureOption) && (pos.Strike <= 0 || pos.Expiry.IsZero()) {
+			return nil, fmt.Errorf("option %s requires strike and expiry", p.Symbol)
+		}
+		positions = append(positions, pos)
+	}
+
+	report, err := s.varEngine.Calculate(ctx, domain.VaRRequest{
+		Positions:       positions,
+		Method:          domain.VaRMethod(req.Method),
+		Filter:          domain.VolatilityFilter(req.Filter),
+		ConfidenceLevel: req.ConfidenceLevel,
+		HorizonDays:     req.HorizonDays,
+		LookbackDays:    req.LookbackDays,
+		Simulations:     req.Simulations,
+		Seed:            req.Seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contributions := make([]*PositionRiskContributionDTO, 0, len(report.Contributions))
+	for _, c := range report.Contributions {
+		contributions = append(contributions, &PositionRiskContributionDTO{
+			PositionID:     c.PositionID,
+			Symbol:         c.Symbol,
+			MarketValue:    c.MarketValue.String(),
+			StandaloneVaR:  c.StandaloneVaR.String(),
+			ComponentVaR:   c.ComponentVaR.String(),
+			IncrementalVaR: c.IncrementalVaR.String(),
+			ComponentES:    c.ComponentES.String(),
+		})
+	}
+	return &CalculateVaRResponse{
+		Method:           string(report.Method),
+		ConfidenceLevel:  report.ConfidenceLevel,
+		HorizonDays:      report.HorizonDays,
+		Scenarios:        report.Scenarios,
+		Seed:             report.Seed,
+		PortfolioValue:   report.PortfolioValue.String(),
+		VaR:              report.VaR.String(),
+		ES:               report.ES.String(),
+		UndiversifiedVaR: report.UndiversifiedVaR.String(),
+		Diversification:  report.Diversification.String(),
+		Contributions:    contributions,
+		CalculatedAt:     report.CalculatedAt.Unix(),
+	}, nil
+}
+
 // RunStressTest 运行压力测试
 func (s *RiskQueryService) RunStressTest(ctx context.Context, req *RunStressTestRequest) (*RunStressTestResponse, error) {
 	if req == nil || len(req.Assets) == 0 {
@@ -258,25 +366,51 @@ func (s *RiskQueryService) RunStressTest(ctx context.Context, req *RunStressTest
 		})
 	}
 
-	engine := domain.NewStressTestEngine()
+	positions := make([]domain.StressPosition, 0, len(assets))
+	for _, a := range assets {
+		positions = append(positions, domain.StressPosition{VaRPosition: domain.VaRPosition{
+			Symbol:   a.Symbol,
+			Type:     domain.InstrumentSpot,
+			Quantity: a.Position,
+			Price:    a.CurrentPrice,
+		}})
+	}
 
-	// 如果指定了场景，则只跑该场景；否则跑全场景
-	results := make([]*StressTestResultDTO, 0)
-	scenarios := []string{"GFC", "FLASH_CRASH"}
+	// 如果指定了场景，则只跑该场景；否则跑整个场景库
+	var names []string
 	if req.ScenarioName != "" {
-		scenarios = []string{req.ScenarioName}
+		names = []string{req.ScenarioName}
+	}
+	engine := domain.NewStressTestEngine()
+	var scenarios []*domain.StressScenario
+	if s.stressTests != nil {
+		loaded, err := s.stressTests.LoadScenarios(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = loaded
+	} else if req.ScenarioName != "" {
+		sc, ok := engine.Scenario(req.ScenarioName)
+		if !ok {
+			return nil, fmt.Errorf("scenario %s not found", req.ScenarioName)
+		}
+		scenarios = []*domain.StressScenario{sc}
+	} else {
+		scenarios = engine.Scenarios()
 	}
 
+	results := make([]*StressTestResultDTO, 0, len(scenarios))
 	for _, sc := range scenarios {
-		res, err := engine.RunScenario(sc, assets)
+		res, err := engine.Run(ctx, sc, positions, nil)
 		if err != nil {
-			continue
+			return nil, err
 		}
 		results = append(results, &StressTestResultDTO{
-			ScenarioName:   res.ScenarioName,
-			PnLImpact:      res.PnLImpact.String(),
-			PercentageDrop: res.PercentageDrop.String(),
-			Survived:       res.Survived,
+			ScenarioName:    res.ScenarioName,
+			ScenarioVersion: res.ScenarioVersion,
+			PnLImpact:       res.PnLImpact.String(),
+			PercentageDrop:  res.PercentageDrop.String(),
+			Survived:        res.Survived,
 		})
 	}
 
@@ -0,0 +1,485 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	accountv1 "github.com/wyfcoding/financialtrading/go-api/account/v1"
+	positionv1 "github.com/wyfcoding/financialtrading/go-api/position/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"github.com/wyfcoding/pkg/messagequeue"
+)
+
+// StressTestService 管理压力场景库，并按计划对全部账户运行场景与反向压力测试。
+type StressTestService struct {
+	scenarioRepo   domain.StressScenarioRepository
+	riskRepo       domain.RiskRepository
+	accountClient  accountv1.AccountServiceClient
+	positionClient positionv1.PositionServiceClient
+	publisher      messagequeue.EventPublisher
+	logger         *slog.Logger
+
+	engine     *domain.StressTestEngine
+	history    domain.HistoricalWindowProvider
+	classifier domain.InstrumentClassifier
+
+	runInterval time.Duration
+	mmThreshold decimal.Decimal // 维持保证金倍数，与强平引擎一致
+}
+
+// NewStressTestService 构造函数。
+func NewStressTestService(
+	scenarioRepo domain.StressScenarioRepository,
+	riskRepo domain.RiskRepository,
+	accClient accountv1.AccountServiceClient,
+	posClient positionv1.PositionServiceClient,
+	publisher messagequeue.EventPublisher,
+	logger *slog.Logger,
+) *StressTestService {
+	return &StressTestService{
+		scenarioRepo:   scenarioRepo,
+		riskRepo:       riskRepo,
+		accountClient:  accClient,
+		positionClient: posClient,
+		publisher:      publisher,
+		logger:         logger,
+		engine:         domain.NewStressTestEngine(),
+		runInterval:    time.Hour,
+		mmThreshold:    decimal.NewFromFloat(1.1),
+	}
+}
+
+// SetHistoryProvider 注入历史行情窗口，用于构建历史回放场景
+func (s *StressTestService) SetHistoryProvider(history domain.HistoricalWindowProvider) {
+	s.history = history
+}
+
+// SetOptionPricer 注入期权重估模型
+func (s *StressTestService) SetOptionPricer(pricer domain.OptionPricer) {
+	s.engine.SetPricer(pricer)
+}
+
+// SetInstrumentClassifier 注入标的行业/币种分类
+func (s *StressTestService) SetInstrumentClassifier(classifier domain.InstrumentClassifier) {
+	s.classifier = classifier
+}
+
+// Start 启动定时压力测试循环
+func (s *StressTestService) Start(ctx context.Context) {
+	if err := s.EnsureBuiltinScenarios(ctx); err != nil {
+		s.logger.Error("failed to seed builtin stress scenarios", "error", err)
+	}
+
+	ticker := time.NewTicker(s.runInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Stress Test scheduler started", "interval", s.runInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stress Test scheduler stopping...")
+			return
+		case <-ticker.C:
+			if err := s.RunCycle(ctx); err != nil {
+				s.logger.Error("stress test cycle failed", "error", err)
+			}
+		}
+	}
+}
+
+// EnsureBuiltinScenarios 场景库为空时写入内置场景作为第一个版本
+func (s *StressTestService) EnsureBuiltinScenarios(ctx context.Context) error {
+	existing, err := s.scenarioRepo.ListScenarios(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	for _, sc := range domain.BuiltinStressScenarios() {
+		sc.CreatedBy = "system"
+		if err := s.scenarioRepo.SaveScenario(ctx, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveScenario 保存场景，同名场景生成新版本
+func (s *StressTestService) SaveScenario(ctx context.Context, cmd SaveStressScenarioCommand) (*StressScenarioDTO, error) {
+	scenario := &domain.StressScenario{
+		Name:         strings.TrimSpace(cmd.Name),
+		Description:  cmd.Description,
+		Source:       domain.ScenarioSourceManual,
+		SymbolShocks: cmd.SymbolShocks,
+		SectorShocks: cmd.SectorShocks,
+		DefaultShock: cmd.DefaultShock,
+		VolShock:     cmd.VolShock,
+		RateShock:    cmd.RateShock,
+		FXShocks:     cmd.FXShocks,
+		CreatedBy:    cmd.CreatedBy,
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.scenarioRepo.SaveScenario(ctx, scenario); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "stress scenario saved", "name", scenario.Name, "version", scenario.Version)
+	return toStressScenarioDTO(scenario), nil
+}
+
+// BuildHistoricalScenario 由历史窗口回放生成场景并保存为新版本
+func (s *StressTestService) BuildHistoricalScenario(ctx context.Context, cmd BuildHistoricalScenarioCommand) (*StressScenarioDTO, error) {
+	scenario, err := domain.BuildHistoricalScenario(ctx, s.history, domain.HistoricalScenarioSpec{
+		Name:         strings.TrimSpace(cmd.Name),
+		Description:  cmd.Description,
+		Symbols:      cmd.Symbols,
+		Sectors:      cmd.Sectors,
+		FXSymbols:    cmd.FXSymbols,
+		Start:        time.Unix(cmd.StartTime, 0),
+		End:          time.Unix(cmd.EndTime, 0),
+		WorstPoint:   cmd.WorstPoint,
+		BaselineDays: cmd.BaselineDays,
+		RateShock:    cmd.RateShock,
+		CreatedBy:    cmd.CreatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.scenarioRepo.SaveScenario(ctx, scenario); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "historical stress scenario built", "name", scenario.Name, "version", scenario.Version,
+		"default_shock", scenario.DefaultShock, "vol_shock", scenario.VolShock)
+	return toStressScenarioDTO(scenario), nil
+}
+
+// ListScenarios 返回各场景的最新版本
+func (s *StressTestService) ListScenarios(ctx context.Context) ([]*StressScenarioDTO, error) {
+	scenarios, err := s.scenarioRepo.ListScenarios(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toStressScenarioDTOs(scenarios), nil
+}
+
+// ListScenarioVersions 返回场景的全部历史版本
+func (s *StressTestService) ListScenarioVersions(ctx context.Context, name string) ([]*StressScenarioDTO, error) {
+	scenarios, err := s.scenarioRepo.ListScenarioVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return toStressScenarioDTOs(scenarios), nil
+}
+
+// ListStressRuns 查询账户的压力测试记录
+func (s *StressTestService) ListStressRuns(ctx context.Context, userID string, limit int) ([]*StressTestRunDTO, error) {
+	runs, err := s.scenarioRepo.ListStressRuns(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*StressTestRunDTO, 0, len(runs))
+	for _, r := range runs {
+		result = append(result, toStressTestRunDTO(r))
+	}
+	return result, nil
+}
+
+// LoadScenarios 按名称读取场景最新版本，names 为空时返回整个场景库；场景库不可用时退回内置场景
+func (s *StressTestService) LoadScenarios(ctx context.Context, names []string) ([]*domain.StressScenario, error) {
+	var scenarios []*domain.StressScenario
+	if len(names) == 0 {
+		all, err := s.scenarioRepo.ListScenarios(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = all
+	} else {
+		for _, name := range names {
+			sc, err := s.scenarioRepo.GetScenario(ctx, name, 0)
+			if err != nil {
+				return nil, err
+			}
+			if sc == nil {
+				sc, _ = s.engine.Scenario(name)
+			}
+			if sc == nil {
+				return nil, fmt.Errorf("scenario %s not found", name)
+			}
+			scenarios = append(scenarios, sc)
+		}
+	}
+	if len(scenarios) == 0 {
+		scenarios = s.engine.Scenarios()
+	}
+	return scenarios, nil
+}
+
+// RunAccountStressTest 对用户账户运行指定场景（为空时运行整个场景库）并记录结果
+func (s *StressTestService) RunAccountStressTest(ctx context.Context, req *RunAccountStressTestRequest) ([]*StressTestRunDTO, error) {
+	if req == nil || req.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	accResp, err := s.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{UserId: req.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account for user %s: %w", req.UserID, err)
+	}
+	if accResp == nil || accResp.Account == nil {
+		return nil, fmt.Errorf("account for user %s not found", req.UserID)
+	}
+	scenarios, err := s.LoadScenarios(ctx, req.ScenarioNames)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.runAccount(ctx, accResp.Account, scenarios)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*StressTestRunDTO, 0, len(runs))
+	for _, r := range runs {
+		result = append(result, toStressTestRunDTO(r))
+	}
+	return result, nil
+}
+
+// RunCycle 对全部账户运行整个场景库，击穿维持保证金的账户生成告警
+func (s *StressTestService) RunCycle(ctx context.Context) error {
+	scenarios, err := s.LoadScenarios(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load stress scenarios: %w", err)
+	}
+
+	pageToken := int32(0)
+	accounts, breaches := 0, 0
+	for {
+		resp, err := s.accountClient.ListAccounts(ctx, &accountv1.ListAccountsRequest{
+			PageSize:  100,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, acc := range resp.Accounts {
+			runs, err := s.runAccount(ctx, acc, scenarios)
+			if err != nil {
+				s.logger.Error("failed to run stress test for account", "account_id", acc.AccountId, "error", err)
+				continue
+			}
+			accounts++
+			for _, r := range runs {
+				if !r.Survived {
+					breaches++
+				}
+			}
+		}
+
+		if resp.NextPageToken == 0 {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	s.logger.Info("stress test cycle completed", "accounts", accounts, "scenarios", len(scenarios), "breaches", breaches)
+	return nil
+}
+
+// ReverseStressTest 搜索使账户权益跌破维持保证金的最小冲击
+func (s *StressTestService) ReverseStressTest(ctx context.Context, req *ReverseStressTestRequest) (*ReverseStressTestResponse, error) {
+	if req == nil || req.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+	accResp, err := s.accountClient.GetAccount(ctx, &accountv1.GetAccountRequest{UserId: req.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account for user %s: %w", req.UserID, err)
+	}
+	if accResp == nil || accResp.Account == nil {
+		return nil, fmt.Errorf("account for user %s not found", req.UserID)
+	}
+
+	var direction *domain.StressScenario
+	if req.ScenarioName != "" {
+		direction, err = s.scenarioRepo.GetScenario(ctx, req.ScenarioName, req.ScenarioVersion)
+		if err != nil {
+			return nil, err
+		}
+		if direction == nil {
+			return nil, fmt.Errorf("scenario %s not found", req.ScenarioName)
+		}
+	}
+
+	positions, margin, err := s.accountExposure(ctx, accResp.Account)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return &ReverseStressTestResponse{UserID: req.UserID}, nil
+	}
+
+	res, err := s.engine.ReverseStress(ctx, positions, margin, direction)
+	if err != nil {
+		return nil, err
+	}
+	return &ReverseStressTestResponse{
+		UserID:          req.UserID,
+		Breached:        res.Breached,
+		AlreadyBreached: res.AlreadyBreached,
+		Scale:           res.Scale,
+		Scenario:        toStressScenarioDTO(res.Scenario),
+		PnLImpact:       res.PnLImpact.String(),
+		StressedEquity:  res.StressedEquity.String(),
+		RequiredMargin:  res.RequiredMargin.String(),
+		Iterations:      res.Iterations,
+	}, nil
+}
+
+// runAccount 在单个账户上运行场景，记录结果并对未通过的场景生成告警
+func (s *StressTestService) runAccount(ctx context.Context, acc *accountv1.AccountResponse, scenarios []*domain.StressScenario) ([]*domain.StressTestRun, error) {
+	positions, margin, err := s.accountExposure(ctx, acc)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	runs := make([]*domain.StressTestRun, 0, len(scenarios))
+	for _, sc := range scenarios {
+		res, err := s.engine.Run(ctx, sc, positions, &margin)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", sc.Name, err)
+		}
+		run := &domain.StressTestRun{
+			ID:              fmt.Sprintf("ST-%d", idgen.GenID()),
+			UserID:          acc.UserId,
+			AccountID:       acc.AccountId,
+			ScenarioName:    sc.Name,
+			ScenarioVersion: sc.Version,
+			PnLImpact:       res.PnLImpact,
+			PercentageDrop:  res.PercentageDrop,
+			StressedEquity:  res.StressedEquity,
+			RequiredMargin:  res.RequiredMargin,
+			Survived:        res.Survived,
+			RunAt:           time.Now(),
+		}
+		if err := s.recordRun(ctx, run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *StressTestService) recordRun(ctx context.Context, run *domain.StressTestRun) error {
+	return s.riskRepo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.scenarioRepo.SaveStressRun(txCtx, run); err != nil {
+			return err
+		}
+		if run.Survived {
+			return nil
+		}
+
+		alert := &domain.RiskAlert{
+			ID:        fmt.Sprintf("RAL-%d", idgen.GenID()),
+			UserID:    run.UserID,
+			AlertType: "STRESS_TEST_BREACH",
+			Severity:  "HIGH",
+			Message: fmt.Sprintf("Stress scenario %s v%d breaches maintenance margin: equity %s < required %s",
+				run.ScenarioName, run.ScenarioVersion, run.StressedEquity.StringFixed(2), run.RequiredMargin.StringFixed(2)),
+		}
+		if err := s.riskRepo.SaveAlert(txCtx, alert); err != nil {
+			return err
+		}
+		if s.publisher == nil {
+			return nil
+		}
+		tx := contextx.GetTx(txCtx)
+		if err := s.publisher.PublishInTx(ctx, tx, domain.RiskAlertGeneratedEventType, alert.ID, domain.RiskAlertGeneratedEvent{
+			AlertID:     alert.ID,
+			UserID:      alert.UserID,
+			AlertType:   alert.AlertType,
+			Severity:    alert.Severity,
+			Message:     alert.Message,
+			GeneratedAt: time.Now().Unix(),
+			OccurredOn:  time.Now(),
+		}); err != nil {
+			return err
+		}
+		return s.publisher.PublishInTx(ctx, tx, domain.StressTestBreachedEventType, run.UserID, domain.StressTestBreachedEvent{
+			RunID:           run.ID,
+			UserID:          run.UserID,
+			AccountID:       run.AccountID,
+			ScenarioName:    run.ScenarioName,
+			ScenarioVersion: run.ScenarioVersion,
+			PnLImpact:       run.PnLImpact.String(),
+			StressedEquity:  run.StressedEquity.String(),
+			RequiredMargin:  run.RequiredMargin.String(),
+			OccurredOn:      time.Now(),
+		})
+	})
+}
+
+// accountExposure 读取账户持仓并构造压力测试输入。
+// 维持保证金按当前占用保证金 * 维持倍数 折算为对总敞口的比例，使压力情景下的要求随敞口变化。
+func (s *StressTestService) accountExposure(ctx context.Context, acc *accountv1.AccountResponse) ([]domain.StressPosition, domain.MarginContext, error) {
+	posResp, err := s.positionClient.GetPositions(ctx, &positionv1.GetPositionsRequest{UserId: acc.UserId})
+	if err != nil {
+		return nil, domain.MarginContext{}, fmt.Errorf("failed to get positions for user %s: %w", acc.UserId, err)
+	}
+
+	balance, _ := decimal.NewFromString(acc.Balance)
+	equity := balance
+	usedMargin := decimal.Zero
+	gross := decimal.Zero
+
+	positions := make([]domain.StressPosition, 0, len(posResp.Positions))
+	for _, p := range posResp.Positions {
+		qty, _ := decimal.NewFromString(p.Quantity)
+		if qty.IsZero() {
+			continue
+		}
+		price, _ := decimal.NewFromString(p.CurrentPrice)
+		if !price.IsPositive() {
+			price, _ = decimal.NewFromString(p.EntryPrice)
+		}
+		upnl, _ := decimal.NewFromString(p.UnrealizedPnl)
+		mr, _ := decimal.NewFromString(p.MarginRequirement)
+		equity = equity.Add(upnl)
+		usedMargin = usedMargin.Add(mr)
+		gross = gross.Add(qty.Mul(price).Abs())
+
+		sector, currency := "", domain.SymbolQuoteCurrency(p.Symbol)
+		if s.classifier != nil {
+			sector, currency = s.classifier.Classify(p.Symbol)
+		}
+		positions = append(positions, domain.StressPosition{
+			VaRPosition: domain.VaRPosition{
+				PositionID: p.PositionId,
+				Symbol:     p.Symbol,
+				Type:       domain.InstrumentSpot,
+				Quantity:   qty,
+				Price:      price,
+			},
+			Sector:   sector,
+			Currency: currency,
+		})
+	}
+
+	margin := domain.MarginContext{
+		Equity:            equity,
+		MaintenanceMargin: usedMargin.Mul(s.mmThreshold),
+	}
+	if gross.IsPositive() {
+		margin.MaintenanceRate = margin.MaintenanceMargin.Div(gross)
+	}
+	return positions, margin, nil
+}
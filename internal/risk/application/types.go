@@ -1,6 +1,9 @@
 package application
 
 import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
 	"github.com/wyfcoding/financialtrading/internal/risk/domain"
 )
 
@@ -134,6 +137,7 @@ type CalculatePortfolioRiskRequest struct {
 	TimeHorizon     float64             `json:"time_horizon"`
 	Simulations     int                 `json:"simulations"`
 	ConfidenceLevel float64             `json:"confidence_level"`
+	Seed            uint64              `json:"seed"`
 }
 
 type PortfolioAssetDTO struct {
@@ -170,6 +174,7 @@ type MonteCarloRiskRequest struct {
 	T          float64 `json:"t"`
 	Iterations int     `json:"iterations"`
 	Steps      int     `json:"steps"`
+	Seed       uint64  `json:"seed"`
 }
 
 // MonteCarloRiskResponse Monte Carlo 风险模拟响应
@@ -188,16 +193,164 @@ type RunStressTestRequest struct {
 }
 
 type StressTestResultDTO struct {
-	ScenarioName   string `json:"scenario_name"`
-	PnLImpact      string `json:"pnl_impact"`
-	PercentageDrop string `json:"percentage_drop"`
-	Survived       bool   `json:"survived"`
+	ScenarioName    string `json:"scenario_name"`
+	ScenarioVersion int    `json:"scenario_version"`
+	PnLImpact       string `json:"pnl_impact"`
+	PercentageDrop  string `json:"percentage_drop"`
+	Survived        bool   `json:"survived"`
 }
 
 type RunStressTestResponse struct {
 	Results []*StressTestResultDTO `json:"results"`
 }
 
+// --- Full Revaluation VaR ---
+
+// VaRPositionDTO 全量重估 VaR 持仓
+type VaRPositionDTO struct {
+	PositionID   string  `json:"position_id"`
+	Symbol       string  `json:"symbol"`
+	Underlying   string  `json:"underlying"`
+	Type         string  `json:"type"` // SPOT / OPTION / FUTURE_OPTION
+	Quantity     string  `json:"quantity"`
+	Multiplier   string  `json:"multiplier"`
+	Price        string  `json:"price"` // 标的当前价格
+	OptionType   string  `json:"option_type"`
+	Strike       float64 `json:"strike"`
+	ExpiryDate   int64   `json:"expiry_date"` // 毫秒时间戳
+	ImpliedVol   float64 `json:"implied_vol"`
+	RiskFreeRate float64 `json:"risk_free_rate"`
+}
+
+// CalculateVaRRequest 组合 VaR/ES 计算请求
+type CalculateVaRRequest struct {
+	Positions       []VaRPositionDTO `json:"positions"`
+	Method          string           `json:"method"` // HISTORICAL / FILTERED_HISTORICAL / MONTE_CARLO
+	Filter          string           `json:"filter"` // EWMA / GARCH
+	ConfidenceLevel float64          `json:"confidence_level"`
+	HorizonDays     int              `json:"horizon_days"`
+	LookbackDays    int              `json:"lookback_days"`
+	Simulations     int              `json:"simulations"`
+	Seed            uint64           `json:"seed"`
+}
+
+// PositionRiskContributionDTO 持仓风险贡献
+type PositionRiskContributionDTO struct {
+	PositionID     string `json:"position_id"`
+	Symbol         string `json:"symbol"`
+	MarketValue    string `json:"market_value"`
+	StandaloneVaR  string `json:"standalone_var"`
+	ComponentVaR   string `json:"component_var"`
+	IncrementalVaR string `json:"incremental_var"`
+	ComponentES    string `json:"component_es"`
+}
+
+// CalculateVaRResponse 组合 VaR/ES 计算结果
+type CalculateVaRResponse struct {
+	Method           string                         `json:"method"`
+	ConfidenceLevel  float64                        `json:"confidence_level"`
+	HorizonDays      int                            `json:"horizon_days"`
+	Scenarios        int                            `json:"scenarios"`
+	Seed             uint64                         `json:"seed"`
+	PortfolioValue   string                         `json:"portfolio_value"`
+	VaR              string                         `json:"var"`
+	ES               string                         `json:"es"`
+	UndiversifiedVaR string                         `json:"undiversified_var"`
+	Diversification  string                         `json:"diversification"`
+	Contributions    []*PositionRiskContributionDTO `json:"contributions"`
+	CalculatedAt     int64                          `json:"calculated_at"`
+}
+
+// --- Stress Scenario Library ---
+
+// StressScenarioDTO 多因子压力场景
+type StressScenarioDTO struct {
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Version      int                `json:"version"`
+	Description  string             `json:"description"`
+	Source       string             `json:"source"`
+	SymbolShocks map[string]float64 `json:"symbol_shocks,omitempty"`
+	SectorShocks map[string]float64 `json:"sector_shocks,omitempty"`
+	DefaultShock float64            `json:"default_shock"`
+	VolShock     float64            `json:"vol_shock"`
+	RateShock    float64            `json:"rate_shock"`
+	FXShocks     map[string]float64 `json:"fx_shocks,omitempty"`
+	WindowStart  int64              `json:"window_start,omitempty"`
+	WindowEnd    int64              `json:"window_end,omitempty"`
+	CreatedBy    string             `json:"created_by"`
+	CreatedAt    int64              `json:"created_at"`
+}
+
+// SaveStressScenarioCommand 保存场景（同名场景追加新版本）
+type SaveStressScenarioCommand struct {
+	Name         string             `json:"name" binding:"required"`
+	Description  string             `json:"description"`
+	SymbolShocks map[string]float64 `json:"symbol_shocks"`
+	SectorShocks map[string]float64 `json:"sector_shocks"`
+	DefaultShock float64            `json:"default_shock"`
+	VolShock     float64            `json:"vol_shock"`
+	RateShock    float64            `json:"rate_shock"`
+	FXShocks     map[string]float64 `json:"fx_shocks"`
+	CreatedBy    string             `json:"created_by"`
+}
+
+// BuildHistoricalScenarioCommand 由历史窗口回放生成场景
+type BuildHistoricalScenarioCommand struct {
+	Name         string            `json:"name" binding:"required"`
+	Description  string            `json:"description"`
+	Symbols      []string          `json:"symbols" binding:"required"`
+	Sectors      map[string]string `json:"sectors"`
+	FXSymbols    map[string]string `json:"fx_symbols"`
+	StartTime    int64             `json:"start_time" binding:"required"`
+	EndTime      int64             `json:"end_time" binding:"required"`
+	WorstPoint   bool              `json:"worst_point"`
+	BaselineDays int               `json:"baseline_days"`
+	RateShock    float64           `json:"rate_shock"`
+	CreatedBy    string            `json:"created_by"`
+}
+
+// RunAccountStressTestRequest 对指定用户账户运行场景库
+type RunAccountStressTestRequest struct {
+	UserID        string   `json:"user_id" binding:"required"`
+	ScenarioNames []string `json:"scenario_names"`
+}
+
+// StressTestRunDTO 账户压力测试结果
+type StressTestRunDTO struct {
+	ID              string `json:"id"`
+	UserID          string `json:"user_id"`
+	AccountID       string `json:"account_id"`
+	ScenarioName    string `json:"scenario_name"`
+	ScenarioVersion int    `json:"scenario_version"`
+	PnLImpact       string `json:"pnl_impact"`
+	PercentageDrop  string `json:"percentage_drop"`
+	StressedEquity  string `json:"stressed_equity"`
+	RequiredMargin  string `json:"required_margin"`
+	Survived        bool   `json:"survived"`
+	RunAt           int64  `json:"run_at"`
+}
+
+// ReverseStressTestRequest 反向压力测试请求，未指定场景时沿全市场单边涨跌方向搜索
+type ReverseStressTestRequest struct {
+	UserID          string `json:"user_id" binding:"required"`
+	ScenarioName    string `json:"scenario_name"`
+	ScenarioVersion int    `json:"scenario_version"`
+}
+
+// ReverseStressTestResponse 反向压力测试结果
+type ReverseStressTestResponse struct {
+	UserID          string             `json:"user_id"`
+	Breached        bool               `json:"breached"`
+	AlreadyBreached bool               `json:"already_breached"`
+	Scale           float64            `json:"scale"`
+	Scenario        *StressScenarioDTO `json:"scenario"`
+	PnLImpact       string             `json:"pnl_impact"`
+	StressedEquity  string             `json:"stressed_equity"`
+	RequiredMargin  string             `json:"required_margin"`
+	Iterations      int                `json:"iterations"`
+}
+
 // --- Anomaly Detection ---
 
 type GetAnomalyReportRequest struct {
@@ -316,3 +469,146 @@ func toCircuitBreakerDTO(cb *domain.CircuitBreaker) *CircuitBreakerDTO {
 		UpdatedAt:     cb.UpdatedAt.Unix(),
 	}
 }
+
+func toStressScenarioDTO(s *domain.StressScenario) *StressScenarioDTO {
+	if s == nil {
+		return nil
+	}
+	dto := &StressScenarioDTO{
+		ID:           s.ID,
+		Name:         s.Name,
+		Version:      s.Version,
+		Description:  s.Description,
+		Source:       string(s.Source),
+		SymbolShocks: s.SymbolShocks,
+		SectorShocks: s.SectorShocks,
+		DefaultShock: s.DefaultShock,
+		VolShock:     s.VolShock,
+		RateShock:    s.RateShock,
+		FXShocks:     s.FXShocks,
+		CreatedBy:    s.CreatedBy,
+	}
+	if !s.WindowStart.IsZero() {
+		dto.WindowStart = s.WindowStart.Unix()
+	}
+	if !s.WindowEnd.IsZero() {
+		dto.WindowEnd = s.WindowEnd.Unix()
+	}
+	if !s.CreatedAt.IsZero() {
+		dto.CreatedAt = s.CreatedAt.Unix()
+	}
+	return dto
+}
+
+func toStressScenarioDTOs(scenarios []*domain.StressScenario) []*StressScenarioDTO {
+	result := make([]*StressScenarioDTO, 0, len(scenarios))
+	for _, s := range scenarios {
+		result = append(result, toStressScenarioDTO(s))
+	}
+	return result
+}
+
+func toStressTestRunDTO(r *domain.StressTestRun) *StressTestRunDTO {
+	if r == nil {
+		return nil
+	}
+	return &StressTestRunDTO{
+		ID:              r.ID,
+		UserID:          r.UserID,
+		AccountID:       r.AccountID,
+		ScenarioName:    r.ScenarioName,
+		ScenarioVersion: r.ScenarioVersion,
+		PnLImpact:       r.PnLImpact.String(),
+		PercentageDrop:  r.PercentageDrop.String(),
+		StressedEquity:  r.StressedEquity.String(),
+		RequiredMargin:  r.RequiredMargin.String(),
+		Survived:        r.Survived,
+		RunAt:           r.RunAt.Unix(),
+	}
+}
+
+// SaveRiskRuleCommand 新建或更新事前风控规则
+type SaveRiskRuleCommand struct {
+	RuleID          string               `json:"rule_id"`
+	Name            string               `json:"name" binding:"required"`
+	Category        string               `json:"category" binding:"required"`
+	Description     string               `json:"description"`
+	Enabled         bool                 `json:"enabled"`
+	Priority        int                  `json:"priority"`
+	Condition       domain.RiskCondition `json:"condition"`
+	Expression      string               `json:"expression"`
+	Threshold       string               `json:"threshold"`
+	Action          string               `json:"action" binding:"required"`
+	CoolDownSeconds int                  `json:"cool_down_seconds"`
+	NotifyChannels  []string             `json:"notify_channels"`
+}
+
+// ValidateRuleExpressionRequest 校验规则表达式
+type ValidateRuleExpressionRequest struct {
+	Expression string `json:"expression" binding:"required"`
+}
+
+// SavePreTradeLimitsCommand 保存订单级控制参数，数值字段为空表示不限制
+type SavePreTradeLimitsCommand struct {
+	Scope                string `json:"scope" binding:"required"`
+	Key                  string `json:"key"`
+	MaxOrderQuantity     string `json:"max_order_quantity"`
+	MaxOrderNotional     string `json:"max_order_notional"`
+	MaxPriceDeviation    string `json:"max_price_deviation"`
+	MaxOrderToTradeRatio string `json:"max_order_to_trade_ratio"`
+	MinOrdersForRatio    int    `json:"min_orders_for_ratio"`
+	MaxMessages          int    `json:"max_messages"`
+	WindowSeconds        int    `json:"window_seconds"`
+	Actor                string `json:"actor"`
+}
+
+func (c SavePreTradeLimitsCommand) toLimits() (*domain.PreTradeLimits, error) {
+	l := &domain.PreTradeLimits{
+		Scope:             domain.ControlScope(c.Scope),
+		Key:               c.Key,
+		MinOrdersForRatio: c.MinOrdersForRatio,
+		MaxMessages:       c.MaxMessages,
+		WindowSeconds:     c.WindowSeconds,
+		UpdatedBy:         c.Actor,
+	}
+	for _, f := range []struct {
+		name string
+		raw  string
+		dst  *decimal.Decimal
+	}{
+		{"max_order_quantity", c.MaxOrderQuantity, &l.MaxOrderQuantity},
+		{"max_order_notional", c.MaxOrderNotional, &l.MaxOrderNotional},
+		{"max_price_deviation", c.MaxPriceDeviation, &l.MaxPriceDeviation},
+		{"max_order_to_trade_ratio", c.MaxOrderToTradeRatio, &l.MaxOrderToTradeRatio},
+	} {
+		if f.raw == "" {
+			continue
+		}
+		v, err := decimal.NewFromString(f.raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+		*f.dst = v
+	}
+	return l, nil
+}
+
+// AssignDeskCommand 设置用户所属交易台，Desk 为空表示移出
+type AssignDeskCommand struct {
+	UserID string `json:"user_id" binding:"required"`
+	Desk   string `json:"desk"`
+	Actor  string `json:"actor"`
+}
+
+// ActivateKillSwitchCommand 激活熔断开关
+type ActivateKillSwitchCommand struct {
+	Scope  string `json:"scope" binding:"required"` // ACCOUNT / DESK / SYMBOL
+	Target string `json:"target" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+	Actor  string `json:"actor"`
+}
+
+// ReleaseKillSwitchRequest 解除熔断开关
+type ReleaseKillSwitchRequest struct {
+	Actor string `json:"actor"`
+}
@@ -13,6 +13,12 @@ const (
 	RiskMetricsUpdatedEventType           = "risk.metrics.updated"
 	RiskLevelChangedEventType             = "risk.level.changed"
 	PositionLiquidationTriggeredEventType = "risk.position.liquidation.triggered"
+	StressTestBreachedEventType           = "risk.stress_test.breached"
+	LiquidationCaseUpdatedEventType       = "risk.liquidation.case.updated"
+	PositionAutoDeleveragedEventType      = "risk.position.auto_deleveraged"
+	InsuranceFundTakeoverEventType        = "risk.insurance_fund.takeover"
+	KillSwitchActivatedEventType          = "risk.kill_switch.activated"
+	KillSwitchReleasedEventType           = "risk.kill_switch.released"
 )
 
 // RiskAssessmentCreatedEvent 风险评估创建事件
@@ -95,6 +101,19 @@ type MarginCallEvent struct {
 	OccurredOn     time.Time `json:"occurred_on"`
 }
 
+// StressTestBreachedEvent 定时压力测试中账户权益跌破维持保证金
+type StressTestBreachedEvent struct {
+	RunID           string    `json:"run_id"`
+	UserID          string    `json:"user_id"`
+	AccountID       string    `json:"account_id"`
+	ScenarioName    string    `json:"scenario_name"`
+	ScenarioVersion int       `json:"scenario_version"`
+	PnLImpact       string    `json:"pnl_impact"`
+	StressedEquity  string    `json:"stressed_equity"`
+	RequiredMargin  string    `json:"required_margin"`
+	OccurredOn      time.Time `json:"occurred_on"`
+}
+
 // RiskMetricsUpdatedEvent 风险指标更新事件
 type RiskMetricsUpdatedEvent struct {
 	UserID         string    `json:"user_id"`
@@ -121,6 +140,7 @@ type RiskLevelChangedEvent struct {
 
 // PositionLiquidationTriggeredEvent 仓位强平触发事件
 type PositionLiquidationTriggeredEvent struct {
+	CaseID        string    `json:"case_id"`
 	UserID        string    `json:"user_id"`
 	AccountID     string    `json:"account_id"`
 	Symbol        string    `json:"symbol"`
@@ -132,3 +152,61 @@ type PositionLiquidationTriggeredEvent struct {
 	TriggeredAt   int64     `json:"triggered_at"`
 	OccurredOn    time.Time `json:"occurred_on"`
 }
+
+// LiquidationCaseUpdatedEvent 强平案件阶段推进
+type LiquidationCaseUpdatedEvent struct {
+	CaseID     string    `json:"case_id"`
+	UserID     string    `json:"user_id"`
+	AccountID  string    `json:"account_id"`
+	Status     string    `json:"status"`
+	Stage      string    `json:"stage"`
+	Deficit    string    `json:"deficit"`
+	Steps      int       `json:"steps"`
+	OccurredOn time.Time `json:"occurred_on"`
+}
+
+// PositionAutoDeleveragedEvent 对手盈利持仓按破产价被自动减仓
+type PositionAutoDeleveragedEvent struct {
+	CaseID           string    `json:"case_id"`
+	UserID           string    `json:"user_id"` // 被减仓的对手方
+	AccountID        string    `json:"account_id"`
+	PositionID       string    `json:"position_id"`
+	LiquidatedUserID string    `json:"liquidated_user_id"`
+	Symbol           string    `json:"symbol"`
+	Side             string    `json:"side"` // 对手方持仓方向
+	Quantity         float64   `json:"quantity"`
+	BankruptcyPrice  float64   `json:"bankruptcy_price"`
+	OccurredOn       time.Time `json:"occurred_on"`
+}
+
+// InsuranceFundTakeoverEvent 风险准备金接管剩余持仓
+type InsuranceFundTakeoverEvent struct {
+	CaseID          string    `json:"case_id"`
+	UserID          string    `json:"user_id"`
+	Symbol          string    `json:"symbol"`
+	Side            string    `json:"side"`
+	Quantity        float64   `json:"quantity"`
+	BankruptcyPrice float64   `json:"bankruptcy_price"`
+	DeficitCovered  string    `json:"deficit_covered"`
+	FundBalance     string    `json:"fund_balance"`
+	OccurredOn      time.Time `json:"occurred_on"`
+}
+
+// KillSwitchActivatedEvent 熔断开关激活，范围内挂单被撤销且新单被拒绝
+type KillSwitchActivatedEvent struct {
+	KillSwitchID string    `json:"kill_switch_id"`
+	Scope        string    `json:"scope"`
+	Target       string    `json:"target"`
+	Reason       string    `json:"reason"`
+	ActivatedBy  string    `json:"activated_by"`
+	OccurredOn   time.Time `json:"occurred_on"`
+}
+
+// KillSwitchReleasedEvent 熔断开关解除
+type KillSwitchReleasedEvent struct {
+	KillSwitchID string    `json:"kill_switch_id"`
+	Scope        string    `json:"scope"`
+	Target       string    `json:"target"`
+	ReleasedBy   string    `json:"released_by"`
+	OccurredOn   time.Time `json:"occurred_on"`
+}
@@ -0,0 +1,383 @@
+package domain
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LiquidationStage 分级强平阶段
+type LiquidationStage string
+
+const (
+	LiquidationStageCancelOrders   LiquidationStage = "CANCEL_ORDERS"   // 撤销挂单释放冻结保证金
+	LiquidationStagePartial        LiquidationStage = "PARTIAL"         // 按保证金释放/冲击成本排序部分平仓
+	LiquidationStageInsuranceFund  LiquidationStage = "INSURANCE_FUND"  // 风险准备金接管
+	LiquidationStageAutoDeleverage LiquidationStage = "AUTO_DELEVERAGE" // 自动减仓对手盈利持仓
+	LiquidationStageRecovered      LiquidationStage = "RECOVERED"       // 保证金水平恢复
+)
+
+// LiquidationStatus 强平案件状态
+type LiquidationStatus string
+
+const (
+	LiquidationStatusInProgress LiquidationStatus = "IN_PROGRESS"
+	LiquidationStatusResolved   LiquidationStatus = "RESOLVED"
+	LiquidationStatusFailed     LiquidationStatus = "FAILED"
+)
+
+// LiquidationStep 强平审计轨迹中的单个步骤
+type LiquidationStep struct {
+	Seq              int              `json:"seq"`
+	Stage            LiquidationStage `json:"stage"`
+	Action           string           `json:"action"`
+	Symbol           string           `json:"symbol,omitempty"`
+	Side             string           `json:"side,omitempty"`
+	Quantity         decimal.Decimal  `json:"quantity"`
+	Price            decimal.Decimal  `json:"price"`
+	Amount           decimal.Decimal  `json:"amount"`       // 释放保证金 / 准备金支出 等金额
+	Counterparty     string           `json:"counterparty"` // ADL 对手方用户
+	MarginLevelAfter decimal.Decimal  `json:"margin_level_after"`
+	Detail           string           `json:"detail"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+// LiquidationCase 账户强平案件，记录从触发到处置完毕的全过程
+type LiquidationCase struct {
+	ID                 string            `json:"id"`
+	UserID             string            `json:"user_id"`
+	AccountID          string            `json:"account_id"`
+	Status             LiquidationStatus `json:"status"`
+	Stage              LiquidationStage  `json:"stage"`
+	TriggerMarginLevel decimal.Decimal   `json:"trigger_margin_level"`
+	Equity             decimal.Decimal   `json:"equity"`
+	UsedMargin         decimal.Decimal   `json:"used_margin"`
+	Deficit            decimal.Decimal   `json:"deficit"` // 穿仓金额
+	Steps              []LiquidationStep `json:"steps"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	CompletedAt        *time.Time        `json:"completed_at,omitempty"`
+}
+
+// NewLiquidationCase 创建强平案件
+func NewLiquidationCase(id, userID, accountID string, marginLevel, equity, usedMargin decimal.Decimal) *LiquidationCase {
+	now := time.Now()
+	return &LiquidationCase{
+		ID:                 id,
+		UserID:             userID,
+		AccountID:          accountID,
+		Status:             LiquidationStatusInProgress,
+		TriggerMarginLevel: marginLevel,
+		Equity:             equity,
+		UsedMargin:         usedMargin,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+}
+
+// Record 追加审计步骤并推进阶段
+func (c *LiquidationCase) Record(step LiquidationStep) {
+	step.Seq = len(c.Steps) + 1
+	if step.CreatedAt.IsZero() {
+		step.CreatedAt = time.Now()
+	}
+	c.Steps = append(c.Steps, step)
+	if step.Stage != LiquidationStageRecovered {
+		c.Stage = step.Stage
+	}
+	c.UpdatedAt = step.CreatedAt
+}
+
+// Close 结束案件
+func (c *LiquidationCase) Close(status LiquidationStatus) {
+	now := time.Now()
+	c.Status = status
+	c.UpdatedAt = now
+	c.CompletedAt = &now
+}
+
+// BookLevel 订单簿档位
+type BookLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// OrderBookDepth 用于评估强平冲击的订单簿深度
+type OrderBookDepth struct {
+	Symbol string
+	Bids   []BookLevel // 价格降序
+	Asks   []BookLevel // 价格升序
+}
+
+func (b *OrderBookDepth) mid(fallback float64) float64 {
+	if b == nil || len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return fallback
+	}
+	return (b.Bids[0].Price + b.Asks[0].Price) / 2
+}
+
+// OrderBookProvider 提供订单簿深度
+type OrderBookProvider interface {
+	GetOrderBookDepth(ctx context.Context, symbol string, depth int) (*OrderBookDepth, error)
+}
+
+// OpenOrderCanceller 撤销用户全部挂单
+type OpenOrderCanceller interface {
+	CancelOpenOrders(ctx context.Context, userID string) (int, error)
+}
+
+// LiquidationPosition 待强平持仓
+type LiquidationPosition struct {
+	PositionID        string
+	Symbol            string
+	Quantity          float64 // 正为多头，负为空头
+	Price             float64
+	EntryPrice        float64
+	MarginRequirement float64
+}
+
+func (p *LiquidationPosition) marginPerUnit() float64 {
+	if p.Quantity == 0 {
+		return 0
+	}
+	return p.MarginRequirement / math.Abs(p.Quantity)
+}
+
+// LiquidationOrder 部分强平指令
+type LiquidationOrder struct {
+	PositionID    string
+	Symbol        string
+	Side          string // 持仓方向 buy(多)/sell(空)，与强平事件约定一致
+	Quantity      float64
+	EstimatedVWAP float64
+	MarginRelief  float64
+	ImpactCost    float64
+	Score         float64 // 单位冲击成本释放的保证金
+}
+
+// LiquidationPlan 部分强平计划；Residual 为订单簿无法承接的剩余持仓
+type LiquidationPlan struct {
+	Orders       []LiquidationOrder
+	Relief       float64
+	ImpactCost   float64
+	Residual     []LiquidationPosition
+	ResidualNeed float64 // 仍未满足的保证金释放需求
+}
+
+// PlanPartialLiquidation 以“单位市场冲击释放的保证金”对持仓排序，贪心地部分平仓直至释放 requiredRelief。
+// 每个持仓只使用订单簿中距中间价不超过 maxSlippage 的档位；订单簿缺失时视为可按现价全部成交。
+func PlanPartialLiquidation(positions []LiquidationPosition, books map[string]*OrderBookDepth, requiredRelief, maxSlippage float64) *LiquidationPlan {
+	type candidate struct {
+		pos        LiquidationPosition
+		levels     []BookLevel
+		mid        float64
+		absorbable float64
+		score      float64
+	}
+
+	candidates := make([]*candidate, 0, len(positions))
+	for _, p := range positions {
+		if p.Quantity == 0 || p.MarginRequirement <= 0 {
+			continue
+		}
+		qty := math.Abs(p.Quantity)
+		book := books[p.Symbol]
+		mid := book.mid(p.Price)
+		c := &candidate{pos: p, mid: mid}
+		if book == nil || len(book.Bids) == 0 || len(book.Asks) == 0 {
+			c.levels = []BookLevel{{Price: mid, Quantity: qty}}
+		} else if p.Quantity > 0 {
+			c.levels = collarLevels(book.Bids, mid, maxSlippage)
+		} else {
+			c.levels = collarLevels(book.Asks, mid, maxSlippage)
+		}
+		for _, l := range c.levels {
+			c.absorbable += l.Quantity
+		}
+		fill := math.Min(qty, c.absorbable)
+		if fill > 0 {
+			vwap := walkLevels(c.levels, fill)
+			impactPerUnit := math.Abs(vwap-mid) + mid*1e-6
+			c.score = p.marginPerUnit() / impactPerUnit
+		}
+		candidates = append(candidates, c)
+	}
+	slices.SortStableFunc(candidates, func(a, b *candidate) int { return cmp.Compare(b.score, a.score) })
+
+	plan := &LiquidationPlan{}
+	remaining := requiredRelief
+	unabsorbed := make([]float64, len(candidates))
+	for i, c := range candidates {
+		qty := math.Abs(c.pos.Quantity)
+		unabsorbed[i] = math.Max(qty-c.absorbable, 0)
+		if remaining <= 0 {
+			continue
+		}
+		need := remaining / c.pos.marginPerUnit()
+		take := math.Min(math.Min(qty, c.absorbable), need)
+		if take <= 0 {
+			continue
+		}
+		vwap := walkLevels(c.levels, take)
+		relief := take * c.pos.marginPerUnit()
+		cost := math.Abs(vwap-c.mid) * take
+		plan.Orders = append(plan.Orders, LiquidationOrder{
+			PositionID:    c.pos.PositionID,
+			Symbol:        c.pos.Symbol,
+			Side:          positionSide(c.pos.Quantity),
+			Quantity:      take,
+			EstimatedVWAP: vwap,
+			MarginRelief:  relief,
+			ImpactCost:    cost,
+			Score:         c.score,
+		})
+		plan.Relief += relief
+		plan.ImpactCost += cost
+		remaining -= relief
+	}
+
+	if remaining <= 0 {
+		return plan
+	}
+	plan.ResidualNeed = remaining
+	for i, c := range candidates {
+		if remaining <= 0 || unabsorbed[i] <= 0 {
+			continue
+		}
+		qty := math.Min(unabsorbed[i], remaining/c.pos.marginPerUnit())
+		residual := c.pos
+		residual.Quantity = math.Copysign(qty, c.pos.Quantity)
+		residual.MarginRequirement = qty * c.pos.marginPerUnit()
+		plan.Residual = append(plan.Residual, residual)
+		remaining -= residual.MarginRequirement
+	}
+	return plan
+}
+
+// collarLevels 截取距中间价不超过 maxSlippage 的档位
+func collarLevels(levels []BookLevel, mid, maxSlippage float64) []BookLevel {
+	out := make([]BookLevel, 0, len(levels))
+	for _, l := range levels {
+		if mid > 0 && maxSlippage > 0 && math.Abs(l.Price-mid)/mid > maxSlippage {
+			break
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// walkLevels 逐档吃单，返回成交均价
+func walkLevels(levels []BookLevel, qty float64) float64 {
+	var filled, notional float64
+	for _, l := range levels {
+		take := math.Min(l.Quantity, qty-filled)
+		if take <= 0 {
+			break
+		}
+		filled += take
+		notional += take * l.Price
+	}
+	if filled == 0 {
+		return 0
+	}
+	return notional / filled
+}
+
+func positionSide(qty float64) string {
+	if qty > 0 {
+		return "buy"
+	}
+	return "sell"
+}
+
+// BankruptcyPrice 剩余持仓在账户权益归零时的价格
+func BankruptcyPrice(residual LiquidationPosition, equity float64) float64 {
+	if residual.Quantity == 0 {
+		return residual.Price
+	}
+	return math.Max(residual.Price-equity/residual.Quantity, 0)
+}
+
+// InsuranceFund 风险准备金，用于承接穿仓损失与无法在市场处置的剩余持仓
+type InsuranceFund struct {
+	Currency  string          `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// CanTakeOver 准备金需同时覆盖穿仓损失与接管持仓的保证金缓冲
+func (f *InsuranceFund) CanTakeOver(deficit, residualMargin decimal.Decimal) bool {
+	return f.Balance.GreaterThanOrEqual(deficit.Add(residualMargin))
+}
+
+// Cover 使用准备金弥补损失，返回实际覆盖金额
+func (f *InsuranceFund) Cover(amount decimal.Decimal) decimal.Decimal {
+	covered := decimal.Min(amount, f.Balance)
+	if covered.IsNegative() {
+		covered = decimal.Zero
+	}
+	f.Balance = f.Balance.Sub(covered)
+	f.UpdatedAt = time.Now()
+	return covered
+}
+
+// ADLCandidate 自动减仓对手候选（与被强平方向相反且盈利的持仓）
+type ADLCandidate struct {
+	UserID        string
+	AccountID     string
+	PositionID    string
+	Symbol        string
+	Quantity      float64 // 带符号
+	EntryPrice    float64
+	UnrealizedPnL float64
+	Equity        float64
+	Score         float64
+}
+
+// ADLAllocation 自动减仓分配结果
+type ADLAllocation struct {
+	Candidate ADLCandidate
+	Quantity  float64
+	Price     float64
+}
+
+// RankADLCandidates 按 盈利率 * 杠杆 降序排列，盈利越多、杠杆越高的持仓越先被减仓
+func RankADLCandidates(candidates []ADLCandidate) []ADLCandidate {
+	ranked := make([]ADLCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		cost := math.Abs(c.Quantity) * c.EntryPrice
+		if c.UnrealizedPnL <= 0 || cost <= 0 {
+			continue
+		}
+		leverage := 1.0
+		if c.Equity > 0 {
+			leverage = (cost + c.UnrealizedPnL) / c.Equity
+		}
+		c.Score = c.UnrealizedPnL / cost * leverage
+		ranked = append(ranked, c)
+	}
+	slices.SortStableFunc(ranked, func(a, b ADLCandidate) int { return cmp.Compare(b.Score, a.Score) })
+	return ranked
+}
+
+// PlanAutoDeleverage 在排序后的对手持仓上按破产价分配剩余持仓
+func PlanAutoDeleverage(residual LiquidationPosition, ranked []ADLCandidate, price float64) ([]ADLAllocation, float64) {
+	remaining := math.Abs(residual.Quantity)
+	allocations := make([]ADLAllocation, 0)
+	for _, c := range ranked {
+		if remaining <= 0 {
+			break
+		}
+		if c.Symbol != residual.Symbol || (c.Quantity > 0) == (residual.Quantity > 0) {
+			continue
+		}
+		take := math.Min(math.Abs(c.Quantity), remaining)
+		allocations = append(allocations, ADLAllocation{Candidate: c, Quantity: take, Price: price})
+		remaining -= take
+	}
+	return allocations, remaining
+}
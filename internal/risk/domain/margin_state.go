@@ -0,0 +1,407 @@
+package domain
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MarginPosition 账户保证金状态中的单个持仓
+type MarginPosition struct {
+	PositionID string          `json:"position_id"`
+	Symbol     string          `json:"symbol"`
+	Quantity   decimal.Decimal `json:"quantity"` // 正为多头，负为空头
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	MarginRate decimal.Decimal `json:"margin_rate"` // 保证金占名义价值比例，加载时由持仓保证金反推
+}
+
+// AccountMarginState 单个杠杆账户的权益与保证金状态，由事件增量维护
+type AccountMarginState struct {
+	AccountID string                     `json:"account_id"`
+	UserID    string                     `json:"user_id"`
+	Balance   decimal.Decimal            `json:"balance"`
+	Positions map[string]*MarginPosition `json:"positions"`
+	// Stale 表示收到无法增量应用的变更（如冻结/扣款），评估前需回源刷新
+	Stale     bool      `json:"stale"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MarginFigures 按当前标记价格计算的保证金指标
+type MarginFigures struct {
+	Equity      decimal.Decimal
+	UsedMargin  decimal.Decimal
+	MarginLevel decimal.Decimal // UsedMargin 为零时为零
+}
+
+// Evaluate 以标记价格计算权益与占用保证金，缺失标记价格时使用开仓价
+func (s *AccountMarginState) Evaluate(marks map[string]decimal.Decimal) MarginFigures {
+	f := MarginFigures{Equity: s.Balance}
+	for _, p := range s.Positions {
+		if p.Quantity.IsZero() {
+			continue
+		}
+		mark, ok := marks[p.Symbol]
+		if !ok || !mark.IsPositive() {
+			mark = p.EntryPrice
+		}
+		f.Equity = f.Equity.Add(mark.Sub(p.EntryPrice).Mul(p.Quantity))
+		f.UsedMargin = f.UsedMargin.Add(p.Quantity.Abs().Mul(mark).Mul(p.MarginRate))
+	}
+	if f.UsedMargin.IsPositive() {
+		f.MarginLevel = f.Equity.Div(f.UsedMargin)
+	}
+	return f
+}
+
+func (s *AccountMarginState) clone() *AccountMarginState {
+	c := *s
+	c.Positions = make(map[string]*MarginPosition, len(s.Positions))
+	for k, p := range s.Positions {
+		cp := *p
+		c.Positions[k] = &cp
+	}
+	return &c
+}
+
+// MarginSnapshot 保证金状态的一致性快照，用于进程重启后恢复
+type MarginSnapshot struct {
+	Sequence uint64                         `json:"sequence"`
+	Accounts map[string]*AccountMarginState `json:"accounts"`
+	Marks    map[string]decimal.Decimal     `json:"marks"`
+	TakenAt  time.Time                      `json:"taken_at"`
+}
+
+// MarginSnapshotRepository 保证金快照存储
+type MarginSnapshotRepository interface {
+	SaveMarginSnapshot(ctx context.Context, snapshot *MarginSnapshot) error
+	LoadMarginSnapshot(ctx context.Context) (*MarginSnapshot, error)
+}
+
+// MarginStateCache 杠杆账户保证金状态缓存，与 PositionCache 一样常驻内存。
+// 每次输入变化只把受影响账户标记为待评估，由监控循环批量取走。
+type MarginStateCache struct {
+	accounts map[string]*AccountMarginState
+	byUser   map[string]map[string]struct{} // userID -> accountIDs
+	bySymbol map[string]map[string]struct{} // symbol -> 持有该品种的 accountIDs
+	marks    map[string]decimal.Decimal
+	dirty    map[string]struct{}
+	sequence uint64 // 每次变更递增，快照记录该序号
+	mu       sync.RWMutex
+}
+
+func NewMarginStateCache() *MarginStateCache {
+	return &MarginStateCache{
+		accounts: make(map[string]*AccountMarginState),
+		byUser:   make(map[string]map[string]struct{}),
+		bySymbol: make(map[string]map[string]struct{}),
+		marks:    make(map[string]decimal.Decimal),
+		dirty:    make(map[string]struct{}),
+	}
+}
+
+// Load 以回源数据整体替换账户状态
+func (c *MarginStateCache) Load(state *AccountMarginState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked(state.clone())
+	c.touchLocked(state.AccountID)
+}
+
+func (c *MarginStateCache) loadLocked(state *AccountMarginState) {
+	if old, ok := c.accounts[state.AccountID]; ok {
+		for symbol := range old.Positions {
+			c.unindexLocked(symbol, old.AccountID)
+		}
+	}
+	if state.Positions == nil {
+		state.Positions = make(map[string]*MarginPosition)
+	}
+	c.accounts[state.AccountID] = state
+	addIndex(c.byUser, state.UserID, state.AccountID)
+	for symbol := range state.Positions {
+		addIndex(c.bySymbol, symbol, state.AccountID)
+	}
+}
+
+// ApplyBalance 应用带余额的资金变更事件（充值、提现、结息等）
+func (c *MarginStateCache) ApplyBalance(accountID string, balance decimal.Decimal) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.accounts[accountID]
+	if !ok {
+		return false
+	}
+	s.Balance = balance
+	c.touchLocked(accountID)
+	return true
+}
+
+// MarkStale 标记账户需回源刷新
+func (c *MarginStateCache) MarkStale(accountID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.accounts[accountID]
+	if !ok {
+		return false
+	}
+	s.Stale = true
+	c.touchLocked(accountID)
+	return true
+}
+
+// TouchUser 将用户全部杠杆账户标记为待评估
+func (c *MarginStateCache) TouchUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for accountID := range c.byUser[userID] {
+		c.dirty[accountID] = struct{}{}
+	}
+}
+
+// ApplyPosition 以持仓事件中的最新数量和均价更新用户持仓；数量为零时移除
+func (c *MarginStateCache) ApplyPosition(userID, symbol string, quantity, entryPrice decimal.Decimal) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for accountID := range c.byUser[userID] {
+		s := c.accounts[accountID]
+		if quantity.IsZero() {
+			if _, ok := s.Positions[symbol]; ok {
+				delete(s.Positions, symbol)
+				c.unindexLocked(symbol, accountID)
+			}
+		} else if p, ok := s.Positions[symbol]; ok {
+			p.Quantity = quantity
+			p.EntryPrice = entryPrice
+		} else {
+			// 新开仓缺少保证金率，回源刷新后补齐
+			s.Positions[symbol] = &MarginPosition{Symbol: symbol, Quantity: quantity, EntryPrice: entryPrice}
+			addIndex(c.bySymbol, symbol, accountID)
+			s.Stale = true
+		}
+		c.touchLocked(accountID)
+		n++
+	}
+	return n
+}
+
+// ApplyPrice 更新标记价格，仅持有该品种的账户被标记为待评估
+func (c *MarginStateCache) ApplyPrice(symbol string, price decimal.Decimal) int {
+	if !price.IsPositive() {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.marks[symbol]; ok && old.Equal(price) {
+		return 0
+	}
+	c.marks[symbol] = price
+	c.sequence++
+	for accountID := range c.bySymbol[symbol] {
+		c.dirty[accountID] = struct{}{}
+	}
+	return len(c.bySymbol[symbol])
+}
+
+// SeedPrice 仅在尚无标记价格时写入，不触发评估
+func (c *MarginStateCache) SeedPrice(symbol string, price decimal.Decimal) {
+	if !price.IsPositive() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.marks[symbol]; !ok {
+		c.marks[symbol] = price
+	}
+}
+
+// Mark 返回品种当前标记价格
+func (c *MarginStateCache) Mark(symbol string) (decimal.Decimal, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.marks[symbol]
+	return p, ok
+}
+
+// Touch 将账户标记为待评估
+func (c *MarginStateCache) Touch(accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.accounts[accountID]; ok {
+		c.dirty[accountID] = struct{}{}
+	}
+}
+
+// Remove 移除账户
+func (c *MarginStateCache) Remove(accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.accounts[accountID]
+	if !ok {
+		return
+	}
+	for symbol := range s.Positions {
+		c.unindexLocked(symbol, accountID)
+	}
+	if ids := c.byUser[s.UserID]; ids != nil {
+		delete(ids, accountID)
+		if len(ids) == 0 {
+			delete(c.byUser, s.UserID)
+		}
+	}
+	delete(c.accounts, accountID)
+	delete(c.dirty, accountID)
+	c.sequence++
+}
+
+// DrainDirty 取走待评估账户集合
+func (c *MarginStateCache) DrainDirty() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.dirty))
+	for id := range c.dirty {
+		ids = append(ids, id)
+	}
+	c.dirty = make(map[string]struct{})
+	return ids
+}
+
+// DrainOne 取消单个账户的待评估标记
+func (c *MarginStateCache) DrainOne(accountID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.dirty, accountID)
+}
+
+// Get 返回账户状态副本及按当前标记价格计算的指标
+func (c *MarginStateCache) Get(accountID string) (*AccountMarginState, MarginFigures, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.accounts[accountID]
+	if !ok {
+		return nil, MarginFigures{}, false
+	}
+	return s.clone(), s.Evaluate(c.marks), true
+}
+
+// ADLCandidates 按品种索引收集持有指定品种的其他用户持仓作为自动减仓候选，
+// 浮盈与权益按当前标记价格计算，无需回源轮询全部账户
+func (c *MarginStateCache) ADLCandidates(symbols []string, excludeUser string) []ADLCandidate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	equity := make(map[string]float64)
+	candidates := make([]ADLCandidate, 0)
+	for _, symbol := range symbols {
+		for accountID := range c.bySymbol[symbol] {
+			s := c.accounts[accountID]
+			if s == nil || s.UserID == excludeUser {
+				continue
+			}
+			p := s.Positions[symbol]
+			if p == nil || p.Quantity.IsZero() {
+				continue
+			}
+			eq, ok := equity[accountID]
+			if !ok {
+				eq = s.Evaluate(c.marks).Equity.InexactFloat64()
+				equity[accountID] = eq
+			}
+			mark, ok := c.marks[symbol]
+			if !ok || !mark.IsPositive() {
+				mark = p.EntryPrice
+			}
+			qty := p.Quantity.InexactFloat64()
+			entry := p.EntryPrice.InexactFloat64()
+			candidates = append(candidates, ADLCandidate{
+				UserID:        s.UserID,
+				AccountID:     accountID,
+				PositionID:    p.PositionID,
+				Symbol:        symbol,
+				Quantity:      qty,
+				EntryPrice:    entry,
+				UnrealizedPnL: (mark.InexactFloat64() - entry) * qty,
+				Equity:        eq,
+			})
+		}
+	}
+	// 索引为 map，排序保证同分候选的先后顺序稳定
+	slices.SortFunc(candidates, func(a, b ADLCandidate) int {
+		return cmp.Or(cmp.Compare(a.Symbol, b.Symbol), cmp.Compare(a.AccountID, b.AccountID))
+	})
+	return candidates
+}
+
+// Len 缓存账户数
+func (c *MarginStateCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.accounts)
+}
+
+// Snapshot 在读锁下深拷贝全部状态，保证快照内账户与标记价格处于同一序号
+func (c *MarginStateCache) Snapshot() *MarginSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snap := &MarginSnapshot{
+		Sequence: c.sequence,
+		Accounts: make(map[string]*AccountMarginState, len(c.accounts)),
+		Marks:    make(map[string]decimal.Decimal, len(c.marks)),
+		TakenAt:  time.Now(),
+	}
+	for id, s := range c.accounts {
+		snap.Accounts[id] = s.clone()
+	}
+	for symbol, p := range c.marks {
+		snap.Marks[symbol] = p
+	}
+	return snap
+}
+
+// Restore 以快照替换缓存，恢复后全部账户待评估
+func (c *MarginStateCache) Restore(snap *MarginSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accounts = make(map[string]*AccountMarginState, len(snap.Accounts))
+	c.byUser = make(map[string]map[string]struct{})
+	c.bySymbol = make(map[string]map[string]struct{})
+	c.marks = make(map[string]decimal.Decimal, len(snap.Marks))
+	c.dirty = make(map[string]struct{}, len(snap.Accounts))
+	for _, s := range snap.Accounts {
+		c.loadLocked(s.clone())
+		c.dirty[s.AccountID] = struct{}{}
+	}
+	for symbol, p := range snap.Marks {
+		c.marks[symbol] = p
+	}
+	c.sequence = snap.Sequence
+}
+
+func (c *MarginStateCache) touchLocked(accountID string) {
+	if s, ok := c.accounts[accountID]; ok {
+		s.UpdatedAt = time.Now()
+	}
+	c.dirty[accountID] = struct{}{}
+	c.sequence++
+}
+
+func (c *MarginStateCache) unindexLocked(symbol, accountID string) {
+	if ids := c.bySymbol[symbol]; ids != nil {
+		delete(ids, accountID)
+		if len(ids) == 0 {
+			delete(c.bySymbol, symbol)
+		}
+	}
+}
+
+func addIndex(index map[string]map[string]struct{}, key, id string) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[string]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
@@ -5,7 +5,6 @@ import (
 	"math"
 	"math/rand/v2"
 	"slices"
-	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -19,6 +18,7 @@ type MonteCarloInput struct {
 	T          float64 // 时间跨度 (年)
 	Iterations int     // 模拟次数 (例如 10000)
 	Steps      int     // 时间步数 (例如 252)
+	Seed       uint64  // 随机种子，0 表示使用 DefaultRiskSeed
 }
 
 // MonteCarloResult 蒙特卡洛模拟输出结果
@@ -36,8 +36,12 @@ func CalculateVaR(input MonteCarloInput) *MonteCarloResult {
 		return &MonteCarloResult{}
 	}
 
-	// 真实化执行：使用更加健壮的随机数生成器
-	r := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+	// 使用确定性种子的 PCG 生成器，保证相同输入可复现
+	seed := input.Seed
+	if seed == 0 {
+		seed = DefaultRiskSeed
+	}
+	r := rand.New(rand.NewPCG(seed, 0))
 
 	dt := input.T / float64(input.Steps)
 	drift := (input.Mu - 0.5*input.Sigma*input.Sigma) * dt
@@ -5,7 +5,6 @@ import (
 	"math"
 	"math/rand/v2"
 	"slices"
-	"time"
 
 	"github.com/shopspring/decimal"
 	algorithm "github.com/wyfcoding/pkg/algorithm/math"
@@ -28,6 +27,7 @@ type PortfolioRiskInput struct {
 	TimeHorizon       float64          `json:"time_horizon"`       // 时间跨度 (年), e.g., 1/252 for 1 day
 	Simulations       int              `json:"simulations"`        // 模拟次数
 	ConfidenceLevel   float64          `json:"confidence_level"`   // 置信度, e.g., 0.95, 0.99
+	Seed              uint64           `json:"seed"`               // 随机种子，0 表示使用 DefaultRiskSeed
 }
 
 // PortfolioRiskResult 组合风险计算结果
@@ -87,7 +87,11 @@ func CalculatePortfolioRisk(input PortfolioRiskInput) (*PortfolioRiskResult, err
 	}
 
 	// 3. 蒙特卡洛模拟
-	randSource := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+	seed := input.Seed
+	if seed == 0 {
+		seed = DefaultRiskSeed
+	}
+	randSource := rand.New(rand.NewPCG(seed, 0))
 
 	// 初始组合价值
 	var initialTotalValue decimal.Decimal
@@ -0,0 +1,645 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ControlScope 事前控制参数的作用范围
+type ControlScope string
+
+const (
+	ControlScopeDefault ControlScope = "DEFAULT" // 全局默认，Key 为空
+	ControlScopeAccount ControlScope = "ACCOUNT" // Key 为用户 ID
+	ControlScopeSymbol  ControlScope = "SYMBOL"  // Key 为品种
+)
+
+// PreTradeControl 事前控制类型
+type PreTradeControl string
+
+const (
+	ControlKillSwitch      PreTradeControl = "KILL_SWITCH"
+	ControlMaxQuantity     PreTradeControl = "FAT_FINGER_QUANTITY"
+	ControlMaxNotional     PreTradeControl = "FAT_FINGER_NOTIONAL"
+	ControlPriceCollar     PreTradeControl = "PRICE_COLLAR"
+	ControlMessageThrottle PreTradeControl = "MESSAGE_THROTTLE"
+	ControlOrderToTrade    PreTradeControl = "ORDER_TO_TRADE_RATIO"
+)
+
+// KillSwitchScope 熔断开关作用范围
+type KillSwitchScope string
+
+const (
+	KillSwitchAccount KillSwitchScope = "ACCOUNT" // Target 为用户 ID 或账户 ID
+	KillSwitchDesk    KillSwitchScope = "DESK"    // Target 为交易台
+	KillSwitchSymbol  KillSwitchScope = "SYMBOL"  // Target 为品种
+)
+
+// ControlAuditKind 控制审计记录类型
+type ControlAuditKind string
+
+const (
+	AuditLimitsUpdated       ControlAuditKind = "LIMITS_UPDATED"
+	AuditLimitsRemoved       ControlAuditKind = "LIMITS_REMOVED"
+	AuditDeskAssigned        ControlAuditKind = "DESK_ASSIGNED"
+	AuditKillSwitchActivated ControlAuditKind = "KILL_SWITCH_ACTIVATED"
+	AuditKillSwitchCancelled ControlAuditKind = "KILL_SWITCH_ORDERS_CANCELLED"
+	AuditKillSwitchReleased  ControlAuditKind = "KILL_SWITCH_RELEASED"
+	AuditOrderRejected       ControlAuditKind = "ORDER_REJECTED"
+)
+
+// PreTradeLimits 一组订单级控制参数，零值表示不限制。
+// 同一笔订单同时受全局默认、所属账户与所下品种三组参数约束。
+type PreTradeLimits struct {
+	Scope             ControlScope    `json:"scope"`
+	Key               string          `json:"key"`
+	MaxOrderQuantity  decimal.Decimal `json:"max_order_quantity"`
+	MaxOrderNotional  decimal.Decimal `json:"max_order_notional"`
+	MaxPriceDeviation decimal.Decimal `json:"max_price_deviation"` // 相对最新价与中间价的最大偏离比例
+	// 报单成交比：当日报单数 / max(成交数, 1)，报单数达到 MinOrdersForRatio 后才生效
+	MaxOrderToTradeRatio decimal.Decimal `json:"max_order_to_trade_ratio"`
+	MinOrdersForRatio    int             `json:"min_orders_for_ratio"`
+	// 滑动窗口报单频率：WindowSeconds 秒内最多 MaxMessages 笔
+	MaxMessages   int       `json:"max_messages"`
+	WindowSeconds int       `json:"window_seconds"`
+	UpdatedBy     string    `json:"updated_by"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Validate 校验参数
+func (l *PreTradeLimits) Validate() error {
+	switch l.Scope {
+	case ControlScopeDefault:
+		if l.Key != "" {
+			return errors.New("default limits must not have a key")
+		}
+	case ControlScopeAccount, ControlScopeSymbol:
+		if l.Key == "" {
+			return fmt.Errorf("%s limits require a key", l.Scope)
+		}
+	default:
+		return fmt.Errorf("unsupported control scope %q", l.Scope)
+	}
+	for name, v := range map[string]decimal.Decimal{
+		"max_order_quantity":       l.MaxOrderQuantity,
+		"max_order_notional":       l.MaxOrderNotional,
+		"max_price_deviation":      l.MaxPriceDeviation,
+		"max_order_to_trade_ratio": l.MaxOrderToTradeRatio,
+	} {
+		if v.IsNegative() {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if l.MaxMessages < 0 || l.WindowSeconds < 0 || l.MinOrdersForRatio < 0 {
+		return errors.New("message and ratio counters must not be negative")
+	}
+	if l.MaxMessages > 0 && l.WindowSeconds == 0 {
+		return errors.New("window_seconds is required when max_messages is set")
+	}
+	return nil
+}
+
+func limitsKey(scope ControlScope, key string) string { return string(scope) + "|" + key }
+
+// ReferencePrice 价格笼子的参考价
+type ReferencePrice struct {
+	Last      decimal.Decimal `json:"last"`
+	Bid       decimal.Decimal `json:"bid"`
+	Ask       decimal.Decimal `json:"ask"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Mid 买卖中间价，任一侧缺失时为零
+func (r ReferencePrice) Mid() decimal.Decimal {
+	if !r.Bid.IsPositive() || !r.Ask.IsPositive() {
+		return decimal.Zero
+	}
+	return r.Bid.Add(r.Ask).Div(decimal.NewFromInt(2))
+}
+
+// KillSwitch 熔断开关：激活时撤销范围内全部挂单并拒绝新单
+type KillSwitch struct {
+	ID              string          `json:"id"`
+	Scope           KillSwitchScope `json:"scope"`
+	Target          string          `json:"target"`
+	Reason          string          `json:"reason"`
+	Active          bool            `json:"active"`
+	ActivatedBy     string          `json:"activated_by"`
+	ActivatedAt     time.Time       `json:"activated_at"`
+	ReleasedBy      string          `json:"released_by,omitempty"`
+	ReleasedAt      *time.Time      `json:"released_at,omitempty"`
+	CancelledOrders int             `json:"cancelled_orders"`
+}
+
+// ControlOrder 待检查的订单
+type ControlOrder struct {
+	UserID    string
+	AccountID string
+	Symbol    string
+	Side      string
+	Quantity  decimal.Decimal
+	Price     decimal.Decimal // 市价单为零
+	Timestamp time.Time
+}
+
+// ControlDecision 事前控制结果
+type ControlDecision struct {
+	Passed  bool            `json:"passed"`
+	Control PreTradeControl `json:"control,omitempty"`
+	Scope   ControlScope    `json:"scope,omitempty"`
+	Limit   string          `json:"limit,omitempty"`
+	Actual  string          `json:"actual,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+}
+
+// ControlAudit 控制变更与拒单审计记录
+type ControlAudit struct {
+	ID        string           `json:"id"`
+	Kind      ControlAuditKind `json:"kind"`
+	Scope     string           `json:"scope"`
+	Target    string           `json:"target"`
+	UserID    string           `json:"user_id,omitempty"`
+	Symbol    string           `json:"symbol,omitempty"`
+	Control   PreTradeControl  `json:"control,omitempty"`
+	Detail    string           `json:"detail"`
+	Actor     string           `json:"actor,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// ControlUsage 用户当前的报单频率与报单成交比
+type ControlUsage struct {
+	UserID         string           `json:"user_id"`
+	Desk           string           `json:"desk,omitempty"`
+	Day            string           `json:"day"`
+	Orders         int64            `json:"orders"`
+	Trades         int64            `json:"trades"`
+	OrderToTrade   decimal.Decimal  `json:"order_to_trade"`
+	RecentMessages map[string]int   `json:"recent_messages"` // 窗口秒数 -> 窗口内报单数
+	ActiveKills    []*KillSwitch    `json:"active_kill_switches"`
+	Limits         []PreTradeLimits `json:"limits"`
+}
+
+// OrderCancelFilter 批量撤单条件，UserID 与 Symbol 至少其一非空
+type OrderCancelFilter struct {
+	UserID string
+	Symbol string
+	Reason string
+}
+
+// ScopedOrderCanceller 按条件撤销挂单
+type ScopedOrderCanceller interface {
+	CancelOrders(ctx context.Context, filter OrderCancelFilter) (int, error)
+}
+
+// PreTradeControlRepository 事前控制参数、交易台归属、熔断开关与审计记录存储
+type PreTradeControlRepository interface {
+	SaveLimits(ctx context.Context, limits *PreTradeLimits) error
+	DeleteLimits(ctx context.Context, scope ControlScope, key string) error
+	ListLimits(ctx context.Context) ([]*PreTradeLimits, error)
+	AssignDesk(ctx context.Context, userID, desk string) error
+	ListDesks(ctx context.Context) (map[string]string, error)
+	SaveKillSwitch(ctx context.Context, ks *KillSwitch) error
+	GetKillSwitch(ctx context.Context, id string) (*KillSwitch, error)
+	ListKillSwitches(ctx context.Context, activeOnly bool) ([]*KillSwitch, error)
+	AppendAudits(ctx context.Context, audits []*ControlAudit) error
+	ListAudits(ctx context.Context, target string, limit int) ([]*ControlAudit, error)
+}
+
+type otrCounter struct {
+	orders int64
+	trades int64
+}
+
+// PreTradeControlEngine 订单级事前控制：熔断开关、胖手指、价格笼子、报单频率与报单成交比。
+// 全部状态在内存中，参数与开关由应用层从仓储加载。
+type PreTradeControlEngine struct {
+	mu        sync.Mutex
+	limits    map[string]*PreTradeLimits
+	desks     map[string]string // userID -> desk
+	kills     map[string]*KillSwitch
+	prices    map[string]ReferencePrice
+	windows   map[string][]time.Time // 报单时间戳，按时间升序
+	maxWindow time.Duration
+	otrDay    string
+	otr       map[string]*otrCounter
+	now       func() time.Time
+	onReject  func(order *ControlOrder, decision ControlDecision)
+}
+
+func NewPreTradeControlEngine() *PreTradeControlEngine {
+	return &PreTradeControlEngine{
+		limits:  make(map[string]*PreTradeLimits),
+		desks:   make(map[string]string),
+		kills:   make(map[string]*KillSwitch),
+		prices:  make(map[string]ReferencePrice),
+		windows: make(map[string][]time.Time),
+		otr:     make(map[string]*otrCounter),
+		now:     time.Now,
+	}
+}
+
+// LoadLimits 整体替换控制参数
+func (c *PreTradeControlEngine) LoadLimits(limits []*PreTradeLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limits = make(map[string]*PreTradeLimits, len(limits))
+	for _, l := range limits {
+		cp := *l
+		c.limits[limitsKey(l.Scope, l.Key)] = &cp
+	}
+	c.recomputeWindowLocked()
+}
+
+// SetLimits 新增或替换一组控制参数
+func (c *PreTradeControlEngine) SetLimits(l *PreTradeLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp := *l
+	c.limits[limitsKey(l.Scope, l.Key)] = &cp
+	c.recomputeWindowLocked()
+}
+
+// RemoveLimits 删除一组控制参数
+func (c *PreTradeControlEngine) RemoveLimits(scope ControlScope, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.limits, limitsKey(scope, key))
+	c.recomputeWindowLocked()
+}
+
+// Limits 返回全部控制参数
+func (c *PreTradeControlEngine) Limits() []PreTradeLimits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]PreTradeLimits, 0, len(c.limits))
+	for _, l := range c.limits {
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return limitsKey(out[i].Scope, out[i].Key) < limitsKey(out[j].Scope, out[j].Key)
+	})
+	return out
+}
+
+// LoadDesks 整体替换用户的交易台归属
+func (c *PreTradeControlEngine) LoadDesks(desks map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.desks = make(map[string]string, len(desks))
+	for userID, desk := range desks {
+		c.desks[userID] = desk
+	}
+}
+
+// AssignDesk 设置用户所属交易台，desk 为空表示移出
+func (c *PreTradeControlEngine) AssignDesk(userID, desk string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if desk == "" {
+		delete(c.desks, userID)
+		return
+	}
+	c.desks[userID] = desk
+}
+
+// DeskMembers 返回交易台下的全部用户
+func (c *PreTradeControlEngine) DeskMembers(desk string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var users []string
+	for userID, d := range c.desks {
+		if d == desk {
+			users = append(users, userID)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// LoadKillSwitches 整体替换生效中的熔断开关
+func (c *PreTradeControlEngine) LoadKillSwitches(kills []*KillSwitch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kills = make(map[string]*KillSwitch, len(kills))
+	for _, ks := range kills {
+		if ks.Active {
+			cp := *ks
+			c.kills[ks.ID] = &cp
+		}
+	}
+}
+
+// Activate 激活熔断开关，之后范围内的新单全部拒绝
+func (c *PreTradeControlEngine) Activate(ks *KillSwitch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp := *ks
+	cp.Active = true
+	c.kills[ks.ID] = &cp
+}
+
+// Release 解除熔断开关
+func (c *PreTradeControlEngine) Release(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.kills, id)
+}
+
+// UpdateReferencePrice 更新品种参考价，零值字段保留原值
+func (c *PreTradeControlEngine) UpdateReferencePrice(symbol string, last, bid, ask decimal.Decimal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ref := c.prices[symbol]
+	if last.IsPositive() {
+		ref.Last = last
+	}
+	if bid.IsPositive() {
+		ref.Bid = bid
+	}
+	if ask.IsPositive() {
+		ref.Ask = ask
+	}
+	ref.UpdatedAt = c.now()
+	c.prices[symbol] = ref
+}
+
+// RecordTrade 记录成交，用于报单成交比
+func (c *PreTradeControlEngine) RecordTrade(userID, symbol string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollDayLocked()
+	c.counterLocked(userKey(userID)).trades++
+	c.counterLocked(userSymbolKey(userID, symbol)).trades++
+}
+
+// SetRejectionHook 设置拒单回调，用于审计；回调在锁外执行且不应阻塞
+func (c *PreTradeControlEngine) SetRejectionHook(hook func(order *ControlOrder, decision ControlDecision)) {
+	c.onReject = hook
+}
+
+// Check 按熔断开关、胖手指、价格笼子、报单频率、报单成交比的顺序检查订单，返回第一个不通过的控制。
+// 除熔断开关拒绝的订单外，每次检查都计入报单频率与报单数。
+func (c *PreTradeControlEngine) Check(order *ControlOrder) ControlDecision {
+	decision := c.check(order)
+	if !decision.Passed && c.onReject != nil {
+		c.onReject(order, decision)
+	}
+	return decision
+}
+
+func (c *PreTradeControlEngine) check(order *ControlOrder) ControlDecision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ks := c.matchKillLocked(order); ks != nil {
+		return ControlDecision{
+			Control: ControlKillSwitch,
+			Limit:   string(ks.Scope) + ":" + ks.Target,
+			Reason:  fmt.Sprintf("kill switch %s active on %s %s: %s", ks.ID, ks.Scope, ks.Target, ks.Reason),
+		}
+	}
+
+	at := order.Timestamp
+	if at.IsZero() {
+		at = c.now()
+	}
+	c.rollDayLocked()
+	c.recordMessageLocked(userKey(order.UserID), at)
+	c.recordMessageLocked(userSymbolKey(order.UserID, order.Symbol), at)
+	c.counterLocked(userKey(order.UserID)).orders++
+	c.counterLocked(userSymbolKey(order.UserID, order.Symbol)).orders++
+
+	ref := c.prices[order.Symbol]
+	for _, l := range c.applicableLocked(order) {
+		if d := c.checkLimitsLocked(l, order, ref, at); !d.Passed {
+			return d
+		}
+	}
+	return ControlDecision{Passed: true}
+}
+
+func (c *PreTradeControlEngine) checkLimitsLocked(l *PreTradeLimits, order *ControlOrder, ref ReferencePrice, at time.Time) ControlDecision {
+	fail := func(control PreTradeControl, limit, actual, reason string) ControlDecision {
+		return ControlDecision{Control: control, Scope: l.Scope, Limit: limit, Actual: actual, Reason: reason}
+	}
+
+	if l.MaxOrderQuantity.IsPositive() && order.Quantity.GreaterThan(l.MaxOrderQuantity) {
+		return fail(ControlMaxQuantity, l.MaxOrderQuantity.String(), order.Quantity.String(),
+			fmt.Sprintf("order quantity exceeds %s maximum", l.Scope))
+	}
+
+	if l.MaxOrderNotional.IsPositive() {
+		// 市价单以参考价估算名义价值
+		price := order.Price
+		if !price.IsPositive() {
+			price = ref.Last
+			if mid := ref.Mid(); mid.IsPositive() {
+				price = mid
+			}
+		}
+		if notional := order.Quantity.Mul(price); notional.GreaterThan(l.MaxOrderNotional) {
+			return fail(ControlMaxNotional, l.MaxOrderNotional.String(), notional.String(),
+				fmt.Sprintf("order notional exceeds %s maximum", l.Scope))
+		}
+	}
+
+	// 价格笼子仅约束限价单；无参考价时放行
+	if l.MaxPriceDeviation.IsPositive() && order.Price.IsPositive() {
+		refs := []struct {
+			name  string
+			price decimal.Decimal
+		}{{"last", ref.Last}, {"mid", ref.Mid()}}
+		for _, r := range refs {
+			name, refPrice := r.name, r.price
+			if !refPrice.IsPositive() {
+				continue
+			}
+			dev := order.Price.Sub(refPrice).Abs().Div(refPrice)
+			if dev.GreaterThan(l.MaxPriceDeviation) {
+				return fail(ControlPriceCollar, l.MaxPriceDeviation.String(), dev.StringFixed(6),
+					fmt.Sprintf("price %s deviates from %s price %s beyond collar", order.Price, name, refPrice))
+			}
+		}
+	}
+
+	counterKey := userKey(order.UserID)
+	if l.Scope == ControlScopeSymbol {
+		counterKey = userSymbolKey(order.UserID, order.Symbol)
+	}
+
+	if l.MaxMessages > 0 {
+		window := time.Duration(l.WindowSeconds) * time.Second
+		if n := countSince(c.windows[counterKey], at.Add(-window)); n > l.MaxMessages {
+			return fail(ControlMessageThrottle, fmt.Sprintf("%d/%ds", l.MaxMessages, l.WindowSeconds), fmt.Sprintf("%d", n),
+				"message rate limit exceeded")
+		}
+	}
+
+	if l.MaxOrderToTradeRatio.IsPositive() {
+		counter := c.otr[counterKey]
+		if counter != nil && counter.orders >= int64(l.MinOrdersForRatio) {
+			if ratio := counter.ratio(); ratio.GreaterThan(l.MaxOrderToTradeRatio) {
+				return fail(ControlOrderToTrade, l.MaxOrderToTradeRatio.String(), ratio.StringFixed(2),
+					"order-to-trade ratio exceeded")
+			}
+		}
+	}
+
+	return ControlDecision{Passed: true}
+}
+
+// Usage 查询用户当前的控制使用情况
+func (c *PreTradeControlEngine) Usage(userID string) *ControlUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollDayLocked()
+
+	usage := &ControlUsage{
+		UserID:         userID,
+		Desk:           c.desks[userID],
+		Day:            c.otrDay,
+		RecentMessages: make(map[string]int),
+	}
+	if counter := c.otr[userKey(userID)]; counter != nil {
+		usage.Orders, usage.Trades, usage.OrderToTrade = counter.orders, counter.trades, counter.ratio()
+	}
+	now := c.now()
+	probe := &ControlOrder{UserID: userID}
+	for _, l := range c.applicableLocked(probe) {
+		usage.Limits = append(usage.Limits, *l)
+		if l.MaxMessages > 0 {
+			window := time.Duration(l.WindowSeconds) * time.Second
+			usage.RecentMessages[fmt.Sprintf("%ds", l.WindowSeconds)] = countSince(c.windows[userKey(userID)], now.Add(-window))
+		}
+	}
+	for _, ks := range c.kills {
+		if c.killMatchesUserLocked(ks, userID, "") {
+			cp := *ks
+			usage.ActiveKills = append(usage.ActiveKills, &cp)
+		}
+	}
+	return usage
+}
+
+// ActiveKillSwitches 返回生效中的熔断开关
+func (c *PreTradeControlEngine) ActiveKillSwitches() []*KillSwitch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*KillSwitch, 0, len(c.kills))
+	for _, ks := range c.kills {
+		cp := *ks
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ActivatedAt.Before(out[j].ActivatedAt) })
+	return out
+}
+
+// Sweep 清理超出最大窗口的报单时间戳，避免空闲用户长期占用内存
+func (c *PreTradeControlEngine) Sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cutoff := c.now().Add(-c.maxWindow)
+	for key, ts := range c.windows {
+		if len(ts) == 0 || !ts[len(ts)-1].After(cutoff) {
+			delete(c.windows, key)
+		}
+	}
+}
+
+func (c *PreTradeControlEngine) matchKillLocked(order *ControlOrder) *KillSwitch {
+	for _, ks := range c.kills {
+		if ks.Scope == KillSwitchSymbol && ks.Target == order.Symbol {
+			return ks
+		}
+		if c.killMatchesUserLocked(ks, order.UserID, order.AccountID) {
+			return ks
+		}
+	}
+	return nil
+}
+
+func (c *PreTradeControlEngine) killMatchesUserLocked(ks *KillSwitch, userID, accountID string) bool {
+	switch ks.Scope {
+	case KillSwitchAccount:
+		return ks.Target == userID || (accountID != "" && ks.Target == accountID)
+	case KillSwitchDesk:
+		desk, ok := c.desks[userID]
+		return ok && desk == ks.Target
+	default:
+		return false
+	}
+}
+
+// applicableLocked 订单适用的参数组：全局默认、账户、品种
+func (c *PreTradeControlEngine) applicableLocked(order *ControlOrder) []*PreTradeLimits {
+	var out []*PreTradeLimits
+	if l, ok := c.limits[limitsKey(ControlScopeDefault, "")]; ok {
+		out = append(out, l)
+	}
+	if l, ok := c.limits[limitsKey(ControlScopeAccount, order.UserID)]; ok {
+		out = append(out, l)
+	}
+	if order.Symbol != "" {
+		if l, ok := c.limits[limitsKey(ControlScopeSymbol, order.Symbol)]; ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (c *PreTradeControlEngine) recordMessageLocked(key string, at time.Time) {
+	ts := c.windows[key]
+	// 丢弃最大窗口之外的时间戳
+	cutoff := at.Add(-c.maxWindow)
+	drop := sort.Search(len(ts), func(i int) bool { return ts[i].After(cutoff) })
+	ts = append(ts[drop:], at)
+	c.windows[key] = ts
+}
+
+func (c *PreTradeControlEngine) recomputeWindowLocked() {
+	c.maxWindow = 0
+	for _, l := range c.limits {
+		if w := time.Duration(l.WindowSeconds) * time.Second; w > c.maxWindow {
+			c.maxWindow = w
+		}
+	}
+}
+
+func (c *PreTradeControlEngine) rollDayLocked() {
+	day := c.now().UTC().Format("2006-01-02")
+	if day != c.otrDay {
+		c.otrDay = day
+		c.otr = make(map[string]*otrCounter)
+	}
+}
+
+func (c *PreTradeControlEngine) counterLocked(key string) *otrCounter {
+	counter, ok := c.otr[key]
+	if !ok {
+		counter = &otrCounter{}
+		c.otr[key] = counter
+	}
+	return counter
+}
+
+func (o *otrCounter) ratio() decimal.Decimal {
+	trades := o.trades
+	if trades < 1 {
+		trades = 1
+	}
+	return decimal.NewFromInt(o.orders).Div(decimal.NewFromInt(trades))
+}
+
+func countSince(ts []time.Time, since time.Time) int {
+	return len(ts) - sort.Search(len(ts), func(i int) bool { return ts[i].After(since) })
+}
+
+func userKey(userID string) string { return "U|" + userID }
+
+func userSymbolKey(userID, symbol string) string { return "US|" + userID + "|" + symbol }
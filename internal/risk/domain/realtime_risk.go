@@ -2,6 +2,8 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -41,7 +43,9 @@ const (
 )
 
 type RealTimeRiskEngine struct {
-	rules          map[string]*RiskRule
+	rules          map[string]*compiledRiskRule
+	observer       RuleEvaluationObserver
+	controls       *PreTradeControlEngine
 	positionCache  *PositionCache
 	exposureCache  *ExposureCache
 	alertManager   *RiskAlertManager
@@ -50,19 +54,21 @@ type RealTimeRiskEngine struct {
 }
 
 type RiskRule struct {
-	ID              string           `json:"id"`
-	Name            string           `json:"name"`
-	Category        RiskRuleCategory `json:"category"`
-	Description     string           `json:"description"`
-	Enabled         bool             `json:"enabled"`
-	Priority        int              `json:"priority"`
-	Condition       RiskCondition    `json:"condition"`
-	Threshold       decimal.Decimal  `json:"threshold"`
-	Action          RiskAction       `json:"action"`
-	CoolDownSeconds int              `json:"cool_down_seconds"`
-	NotifyChannels  []string         `json:"notify_channels"`
-	CreatedAt       time.Time        `json:"created_at"`
-	UpdatedAt       time.Time        `json:"updated_at"`
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Category    RiskRuleCategory `json:"category"`
+	Description string           `json:"description"`
+	Enabled     bool             `json:"enabled"`
+	Priority    int              `json:"priority"`
+	Condition   RiskCondition    `json:"condition"`
+	// Expression 规则表达式，为空时由 Condition 或类别默认表达式推导
+	Expression      string          `json:"expression"`
+	Threshold       decimal.Decimal `json:"threshold"`
+	Action          RiskAction      `json:"action"`
+	CoolDownSeconds int             `json:"cool_down_seconds"`
+	NotifyChannels  []string        `json:"notify_channels"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
 
 type RiskCondition struct {
@@ -137,6 +143,7 @@ type CircuitBreakerState struct {
 
 type RiskCheckContext struct {
 	UserID       string          `json:"user_id"`
+	AccountID    string          `json:"account_id"`
 	Symbol       string          `json:"symbol"`
 	Side         string          `json:"side"`
 	OrderType    string          `json:"order_type"`
@@ -173,7 +180,7 @@ type TriggeredRule struct {
 
 func NewRealTimeRiskEngine() *RealTimeRiskEngine {
 	return &RealTimeRiskEngine{
-		rules:          make(map[string]*RiskRule),
+		rules:          make(map[string]*compiledRiskRule),
 		positionCache:  NewPositionCache(),
 		exposureCache:  NewExposureCache(),
 		alertManager:   NewRiskAlertManager(),
@@ -206,10 +213,24 @@ func NewCircuitBreakerManager() *CircuitBreakerManager {
 	}
 }
 
-func (e *RealTimeRiskEngine) AddRule(rule *RiskRule) {
+// AddRule 编译并加入单条规则，表达式无效时返回错误
+func (e *RealTimeRiskEngine) AddRule(rule *RiskRule) error {
+	compiled, err := CompileRiskRule(rule)
+	if err != nil {
+		return err
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.rules[rule.ID] = rule
+	if old, ok := e.rules[rule.ID]; ok && old.program.Source() == compiled.program.Source() {
+		compiled.stats = old.stats
+	}
+	e.rules[rule.ID] = compiled
+	return nil
+}
+
+// SetControls 注入订单级事前控制，在规则求值之前执行
+func (e *RealTimeRiskEngine) SetControls(controls *PreTradeControlEngine) {
+	e.controls = controls
 }
 
 func (e *RealTimeRiskEngine) RemoveRule(ruleID string) {
@@ -236,18 +257,46 @@ func (e *RealTimeRiskEngine) CheckPreTrade(ctx context.Context, checkCtx *RiskCh
 		return result, nil
 	}
 
-	e.mu.RLock()
-	rules := make([]*RiskRule, 0, len(e.rules))
-	for _, rule := range e.rules {
-		if rule.Enabled {
-			rules = append(rules, rule)
+	if e.controls != nil {
+		decision := e.controls.Check(&ControlOrder{
+			UserID:    checkCtx.UserID,
+			AccountID: checkCtx.AccountID,
+			Symbol:    checkCtx.Symbol,
+			Side:      checkCtx.Side,
+			Quantity:  checkCtx.Quantity,
+			Price:     checkCtx.Price,
+			Timestamp: checkCtx.Timestamp,
+		})
+		if !decision.Passed {
+			result.Passed = false
+			result.Action = RiskActionBlock
+			result.RiskLevel = RiskLevelHigh
+			result.Reason = decision.Reason
+			result.TriggeredRules = append(result.TriggeredRules, &TriggeredRule{
+				RuleID:    string(decision.Control),
+				RuleName:  string(decision.Control),
+				Threshold: decision.Limit,
+				Actual:    decision.Actual,
+				Message:   decision.Reason,
+			})
+			result.RiskScore = e.calculateRiskScore(result.TriggeredRules)
+			return result, nil
 		}
 	}
-	e.mu.RUnlock()
 
-	for _, rule := range rules {
-		triggered, actual := e.evaluateRule(rule, checkCtx)
+	rules := e.enabledRules()
+	facts := &RuleFacts{
+		Check:    checkCtx,
+		Position: e.positionCache.GetPosition(checkCtx.UserID, checkCtx.Symbol),
+		Exposure: e.exposureCache.GetExposure(checkCtx.UserID),
+	}
+
+	var reasons []string
+	for _, compiled := range rules {
+		rule := compiled.rule
+		triggered, actual := e.evaluateRule(compiled, facts)
 		if triggered {
+			reasons = append(reasons, rule.Name)
 			result.TriggeredRules = append(result.TriggeredRules, &TriggeredRule{
 				RuleID:    rule.ID,
 				RuleName:  rule.Name,
@@ -260,7 +309,7 @@ func (e *RealTimeRiskEngine) CheckPreTrade(ctx context.Context, checkCtx *RiskCh
 				result.Passed = false
 				result.Action = RiskActionBlock
 				result.RiskLevel = RiskLevelHigh
-			} else if rule.Action == RiskActionReview {
+			} else if rule.Action == RiskActionReview && result.Action != RiskActionBlock {
 				result.Action = RiskActionReview
 				result.RiskLevel = RiskLevelMedium
 			} else if rule.Action == RiskActionWarn && result.Action == RiskActionAllow {
@@ -273,6 +322,7 @@ func (e *RealTimeRiskEngine) CheckPreTrade(ctx context.Context, checkCtx *RiskCh
 	result.RiskScore = e.calculateRiskScore(result.TriggeredRules)
 
 	if len(result.TriggeredRules) > 0 {
+		result.Reason = "triggered rules: " + strings.Join(reasons, ", ")
 		e.alertManager.SendAlert(&RiskAlert{
 			UserID:    checkCtx.UserID,
 			AlertType: "PRE_TRADE_CHECK",
@@ -284,72 +334,32 @@ func (e *RealTimeRiskEngine) CheckPreTrade(ctx context.Context, checkCtx *RiskCh
 	return result, nil
 }
 
-func (e *RealTimeRiskEngine) evaluateRule(rule *RiskRule, ctx *RiskCheckContext) (bool, string) {
-	switch rule.Category {
-	case RiskCategoryPosition:
-		return e.evaluatePositionRule(rule, ctx)
-	case RiskCategoryExposure:
-		return e.evaluateExposureRule(rule, ctx)
-	case RiskCategoryLeverage:
-		return e.evaluateLeverageRule(rule, ctx)
-	case RiskCategoryCredit:
-		return e.evaluateCreditRule(rule, ctx)
-	default:
-		return false, ""
-	}
-}
-
-func (e *RealTimeRiskEngine) evaluatePositionRule(rule *RiskRule, ctx *RiskCheckContext) (bool, string) {
-	position := e.positionCache.GetPosition(ctx.UserID, ctx.Symbol)
-	if position == nil {
-		return false, "0"
-	}
+// evaluateRule 执行已编译的规则表达式。求值出错（如除零、字段缺失）时按命中处理并发出告警，
+// 规则失效不得放行订单
+func (e *RealTimeRiskEngine) evaluateRule(compiled *compiledRiskRule, facts *RuleFacts) (bool, string) {
+	facts.Threshold = compiled.rule.Threshold
+	start := time.Now()
+	triggered, actual, err := compiled.program.Eval(facts)
+	elapsed := time.Since(start)
 
-	newQuantity := position.Quantity
-	if ctx.Side == "BUY" {
-		newQuantity = newQuantity.Add(ctx.Quantity)
-	} else {
-		newQuantity = newQuantity.Sub(ctx.Quantity)
+	compiled.stats.record(elapsed, triggered, err != nil)
+	if e.observer != nil {
+		e.observer.ObserveRuleEvaluation(compiled.rule.ID, string(compiled.rule.Category), elapsed, triggered, err)
 	}
-
-	absQuantity := newQuantity.Abs()
-	return absQuantity.GreaterThan(rule.Threshold), absQuantity.String()
-}
-
-func (e *RealTimeRiskEngine) evaluateExposureRule(rule *RiskRule, ctx *RiskCheckContext) (bool, string) {
-	exposure := e.exposureCache.GetExposure(ctx.UserID)
-	if exposure == nil {
-		return false, "0"
-	}
-
-	newExposure := exposure.GrossExposure
-	if ctx.Side == "BUY" {
-		newExposure = newExposure.Add(ctx.OrderValue)
-	} else {
-		newExposure = newExposure.Sub(ctx.OrderValue)
-	}
-
-	absExposure := newExposure.Abs()
-	return absExposure.GreaterThan(rule.Threshold), absExposure.String()
-}
-
-func (e *RealTimeRiskEngine) evaluateLeverageRule(rule *RiskRule, ctx *RiskCheckContext) (bool, string) {
-	if ctx.AccountValue.IsZero() {
-		return false, "0"
-	}
-
-	exposure := e.exposureCache.GetExposure(ctx.UserID)
-	if exposure == nil {
-		return false, "0"
+	if err != nil {
+		var userID string
+		if facts.Check != nil {
+			userID = facts.Check.UserID
+		}
+		e.alertManager.SendAlert(&RiskAlert{
+			UserID:    userID,
+			AlertType: "RULE_EVAL_ERROR",
+			Severity:  string(RiskLevelHigh),
+			Message:   fmt.Sprintf("rule %s evaluation failed: %v", compiled.rule.ID, err),
+		})
+		return true, "eval error: " + err.Error()
 	}
-
-	newExposure := exposure.GrossExposure.Add(ctx.OrderValue)
-	leverage := newExposure.Div(ctx.AccountValue)
-	return leverage.GreaterThan(rule.Threshold), leverage.StringFixed(2)
-}
-
-func (e *RealTimeRiskEngine) evaluateCreditRule(rule *RiskRule, ctx *RiskCheckContext) (bool, string) {
-	return false, "N/A"
+	return triggered, actual
 }
 
 func (e *RealTimeRiskEngine) calculateRiskScore(triggeredRules []*TriggeredRule) decimal.Decimal {
@@ -365,6 +375,11 @@ func (e *RealTimeRiskEngine) UpdatePosition(userID, symbol string, quantity, pri
 	e.positionCache.UpdatePosition(userID, symbol, quantity, price)
 }
 
+// SetPosition 覆盖缓存中的持仓，供事件驱动的保证金状态同步使用
+func (e *RealTimeRiskEngine) SetPosition(userID, symbol string, quantity, avgPrice, markPrice decimal.Decimal) {
+	e.positionCache.SetPosition(userID, symbol, quantity, avgPrice, markPrice)
+}
+
 func (e *RealTimeRiskEngine) UpdateExposure(userID string, exposure *UserExposure) {
 	e.exposureCache.UpdateExposure(userID, exposure)
 }
@@ -381,6 +396,43 @@ func (pc *PositionCache) GetPosition(userID, symbol string) *SymbolPosition {
 	return userPos.Positions[symbol]
 }
 
+// SetPosition 以最新数量、均价与标记价格整体覆盖持仓，数量为零时移除
+func (pc *PositionCache) SetPosition(userID, symbol string, quantity, avgPrice, markPrice decimal.Decimal) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	userPos, exists := pc.positions[userID]
+	if !exists {
+		if quantity.IsZero() {
+			return
+		}
+		userPos = &UserPosition{
+			UserID:    userID,
+			Positions: make(map[string]*SymbolPosition),
+		}
+		pc.positions[userID] = userPos
+	}
+
+	if quantity.IsZero() {
+		delete(userPos.Positions, symbol)
+	} else {
+		userPos.Positions[symbol] = &SymbolPosition{
+			Symbol:        symbol,
+			Quantity:      quantity,
+			AvgPrice:      avgPrice,
+			MarketValue:   quantity.Mul(markPrice),
+			UnrealizedPnL: markPrice.Sub(avgPrice).Mul(quantity),
+			UpdatedAt:     time.Now(),
+		}
+	}
+
+	userPos.TotalValue = decimal.Zero
+	for _, pos := range userPos.Positions {
+		userPos.TotalValue = userPos.TotalValue.Add(pos.MarketValue.Abs())
+	}
+	userPos.UpdatedAt = time.Now()
+}
+
 func (pc *PositionCache) UpdatePosition(userID, symbol string, quantity, price decimal.Decimal) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -495,12 +547,15 @@ func randomSuffix() string {
 }
 
 type RiskRuleRepository interface {
-	Create(rule *RiskRule) error
-	Update(rule *RiskRule) error
-	Delete(ruleID string) error
-	FindByID(ruleID string) (*RiskRule, error)
-	FindByCategory(category RiskRuleCategory) ([]*RiskRule, error)
-	ListEnabled() ([]*RiskRule, error)
+	Create(ctx context.Context, rule *RiskRule) error
+	Update(ctx context.Context, rule *RiskRule) error
+	Delete(ctx context.Context, ruleID string) error
+	FindByID(ctx context.Context, ruleID string) (*RiskRule, error)
+	FindByCategory(ctx context.Context, category RiskRuleCategory) ([]*RiskRule, error)
+	List(ctx context.Context) ([]*RiskRule, error)
+	ListEnabled(ctx context.Context) ([]*RiskRule, error)
+	// Version 返回规则集版本（规则数与最近更新时间），用于热加载判断是否变更
+	Version(ctx context.Context) (int64, time.Time, error)
 }
 
 type RiskCheckResultRepository interface {
@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// categoryDefaultExpressions 未配置表达式与条件时各类别的默认规则，
+// 其余类别（流动性、市场、操作、合规）必须显式给出表达式
+var categoryDefaultExpressions = map[RiskRuleCategory]string{
+	RiskCategoryPosition: "abs(position.new_quantity) > threshold",
+	RiskCategoryExposure: "abs(exposure.new_gross) > threshold",
+	RiskCategoryLeverage: "account_value > 0 and new_leverage > threshold",
+	// 单笔订单占用账户价值的比例上限
+	RiskCategoryCredit: "account_value > 0 and order_value > account_value * threshold",
+}
+
+var conditionOperators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// RuleEvaluationObserver 规则求值观测，用于上报每条规则的求值耗时
+type RuleEvaluationObserver interface {
+	ObserveRuleEvaluation(ruleID, category string, elapsed time.Duration, triggered bool, err error)
+}
+
+// RuleStat 单条规则的累计求值统计
+type RuleStat struct {
+	RuleID       string           `json:"rule_id"`
+	RuleName     string           `json:"rule_name"`
+	Category     RiskRuleCategory `json:"category"`
+	Expression   string           `json:"expression"`
+	Evaluations  int64            `json:"evaluations"`
+	Triggers     int64            `json:"triggers"`
+	Errors       int64            `json:"errors"`
+	AvgLatencyNs int64            `json:"avg_latency_ns"`
+	MaxLatencyNs int64            `json:"max_latency_ns"`
+}
+
+type ruleStats struct {
+	evaluations atomic.Int64
+	triggers    atomic.Int64
+	errors      atomic.Int64
+	totalNanos  atomic.Int64
+	maxNanos    atomic.Int64
+}
+
+func (s *ruleStats) record(elapsed time.Duration, triggered, failed bool) {
+	s.evaluations.Add(1)
+	if triggered {
+		s.triggers.Add(1)
+	}
+	if failed {
+		s.errors.Add(1)
+	}
+	ns := elapsed.Nanoseconds()
+	s.totalNanos.Add(ns)
+	for {
+		cur := s.maxNanos.Load()
+		if ns <= cur || s.maxNanos.CompareAndSwap(cur, ns) {
+			return
+		}
+	}
+}
+
+type compiledRiskRule struct {
+	rule    *RiskRule
+	program *CompiledRuleExpression
+	stats   *ruleStats
+}
+
+// EffectiveExpression 规则实际执行的表达式：显式表达式 > 条件 > 类别默认
+func EffectiveExpression(rule *RiskRule) (string, error) {
+	if rule.Expression != "" {
+		return rule.Expression, nil
+	}
+	if c := rule.Condition; c.Field != "" {
+		if !conditionOperators[c.Operator] {
+			return "", fmt.Errorf("%w: unsupported condition operator %q", ErrInvalidRuleExpression, c.Operator)
+		}
+		value := "threshold"
+		if c.Value != "" {
+			value = c.Value
+			if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+				value = strconv.Quote(c.Value)
+			}
+		}
+		return fmt.Sprintf("%s %s %s", c.Field, c.Operator, value), nil
+	}
+	if expr, ok := categoryDefaultExpressions[rule.Category]; ok {
+		return expr, nil
+	}
+	return "", fmt.Errorf("%w: category %s requires an expression", ErrInvalidRuleExpression, rule.Category)
+}
+
+// CompileRiskRule 校验并编译规则
+func CompileRiskRule(rule *RiskRule) (*compiledRiskRule, error) {
+	if rule.ID == "" {
+		return nil, errors.New("rule id is required")
+	}
+	expr, err := EffectiveExpression(rule)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	program, err := CompileRuleExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	switch rule.Action {
+	case RiskActionAllow, RiskActionWarn, RiskActionBlock, RiskActionReview, RiskActionChallenge:
+	default:
+		return nil, fmt.Errorf("rule %s: unsupported action %q", rule.ID, rule.Action)
+	}
+	return &compiledRiskRule{rule: rule, program: program, stats: &ruleStats{}}, nil
+}
+
+// SetObserver 注入规则求值观测器
+func (e *RealTimeRiskEngine) SetObserver(observer RuleEvaluationObserver) {
+	e.observer = observer
+}
+
+// LoadRules 编译整组规则并原子替换当前规则集。
+// 无效规则被跳过并汇总返回，表达式未变化的规则保留累计统计。
+func (e *RealTimeRiskEngine) LoadRules(rules []*RiskRule) (int, error) {
+	next := make(map[string]*compiledRiskRule, len(rules))
+	var errs []error
+	for _, rule := range rules {
+		compiled, err := CompileRiskRule(rule)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		next[rule.ID] = compiled
+	}
+
+	e.mu.Lock()
+	for id, compiled := range next {
+		if old, ok := e.rules[id]; ok && old.program.Source() == compiled.program.Source() {
+			compiled.stats = old.stats
+		}
+	}
+	e.rules = next
+	e.mu.Unlock()
+
+	return len(next), errors.Join(errs...)
+}
+
+// Rules 返回当前生效的规则
+func (e *RealTimeRiskEngine) Rules() []*RiskRule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make([]*RiskRule, 0, len(e.rules))
+	for _, compiled := range e.rules {
+		rules = append(rules, compiled.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// RuleStats 返回每条规则的求值统计
+func (e *RealTimeRiskEngine) RuleStats() []*RuleStat {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	stats := make([]*RuleStat, 0, len(e.rules))
+	for _, compiled := range e.rules {
+		s := compiled.stats
+		stat := &RuleStat{
+			RuleID:       compiled.rule.ID,
+			RuleName:     compiled.rule.Name,
+			Category:     compiled.rule.Category,
+			Expression:   compiled.program.Source(),
+			Evaluations:  s.evaluations.Load(),
+			Triggers:     s.triggers.Load(),
+			Errors:       s.errors.Load(),
+			MaxLatencyNs: s.maxNanos.Load(),
+		}
+		if stat.Evaluations > 0 {
+			stat.AvgLatencyNs = s.totalNanos.Load() / stat.Evaluations
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].RuleID < stats[j].RuleID })
+	return stats
+}
+
+// NeedsAccountValue 当前启用的规则是否引用账户价值或杠杆，调用方据此决定是否回源查询账户
+func (e *RealTimeRiskEngine) NeedsAccountValue() bool {
+	for _, compiled := range e.enabledRules() {
+		if compiled.program.uses(scopeAccount) {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledRules 按优先级从高到低返回启用的规则
+func (e *RealTimeRiskEngine) enabledRules() []*compiledRiskRule {
+	e.mu.RLock()
+	rules := make([]*compiledRiskRule, 0, len(e.rules))
+	for _, compiled := range e.rules {
+		if compiled.rule.Enabled {
+			rules = append(rules, compiled)
+		}
+	}
+	e.mu.RUnlock()
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].rule.Priority != rules[j].rule.Priority {
+			return rules[i].rule.Priority > rules[j].rule.Priority
+		}
+		return rules[i].rule.ID < rules[j].rule.ID
+	})
+	return rules
+}
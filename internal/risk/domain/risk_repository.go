@@ -39,6 +39,34 @@ type RiskRepository interface {
 	GetCircuitBreakerByUserID(ctx context.Context, userID string) (*CircuitBreaker, error)
 }
 
+// StressScenarioRepository 压力场景库仓储，同名场景每次保存追加一个新版本
+type StressScenarioRepository interface {
+	// SaveScenario 以新版本号保存场景，并回填 ID、Version
+	SaveScenario(ctx context.Context, scenario *StressScenario) error
+	// GetScenario 获取指定版本，version <= 0 时返回最新版本
+	GetScenario(ctx context.Context, name string, version int) (*StressScenario, error)
+	// ListScenarios 返回每个场景的最新版本
+	ListScenarios(ctx context.Context) ([]*StressScenario, error)
+	ListScenarioVersions(ctx context.Context, name string) ([]*StressScenario, error)
+
+	SaveStressRun(ctx context.Context, run *StressTestRun) error
+	ListStressRuns(ctx context.Context, userID string, limit int) ([]*StressTestRun, error)
+}
+
+// LiquidationRepository 强平案件与风险准备金仓储
+type LiquidationRepository interface {
+	// SaveCase 保存案件，审计步骤只追加不覆盖
+	SaveCase(ctx context.Context, c *LiquidationCase) error
+	GetCase(ctx context.Context, id string) (*LiquidationCase, error)
+	// GetActiveCase 获取账户处理中的案件
+	GetActiveCase(ctx context.Context, accountID string) (*LiquidationCase, error)
+	ListCases(ctx context.Context, userID string, limit int) ([]*LiquidationCase, error)
+
+	// GetInsuranceFund 获取（并在事务中锁定）指定币种的风险准备金
+	GetInsuranceFund(ctx context.Context, currency string) (*InsuranceFund, error)
+	SaveInsuranceFund(ctx context.Context, fund *InsuranceFund) error
+}
+
 // RiskReadRepository 提供基于 Redis 的实时风险数据（限额、指标、熔断器）缓存
 type RiskReadRepository interface {
 	SaveLimit(ctx context.Context, userID string, limit *RiskLimit) error
@@ -0,0 +1,870 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// 风控规则表达式语言
+//
+//	expr    := or
+//	or      := and (("||" | "or") and)*
+//	and     := not (("&&" | "and") not)*
+//	not     := ("!" | "not") not | cmp
+//	cmp     := sum (("==" | "!=" | "<" | "<=" | ">" | ">=") sum | ["not"] "in" "[" literal ("," literal)* "]")?
+//	sum     := product (("+" | "-") product)*
+//	product := unary (("*" | "/" | "%") unary)*
+//	unary   := "-" unary | primary
+//	primary := number | string | "true" | "false" | field | func "(" args ")" | "(" expr ")"
+//
+// 字段见 ruleFields，函数见 ruleFuncs。表达式在加载时完成解析与类型检查，运行期不再做类型判断。
+
+// ErrInvalidRuleExpression 规则表达式无效
+var ErrInvalidRuleExpression = errors.New("invalid rule expression")
+
+type ruleType int
+
+const (
+	ruleNum ruleType = iota
+	ruleStr
+	ruleBool
+)
+
+func (t ruleType) String() string {
+	switch t {
+	case ruleNum:
+		return "number"
+	case ruleStr:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// ruleValue 运行期值，类型由编译期保证
+type ruleValue struct {
+	num decimal.Decimal
+	str string
+	b   bool
+}
+
+func (v ruleValue) format(t ruleType) string {
+	switch t {
+	case ruleNum:
+		return v.num.String()
+	case ruleStr:
+		return v.str
+	default:
+		return strconv.FormatBool(v.b)
+	}
+}
+
+type ruleEvalFn func(f *RuleFacts) (ruleValue, error)
+
+// RuleFacts 规则求值所需的事实：下单上下文、缓存的持仓与敞口、规则阈值
+type RuleFacts struct {
+	Check     *RiskCheckContext
+	Position  *SymbolPosition
+	Exposure  *UserExposure
+	Threshold decimal.Decimal
+}
+
+func (f *RuleFacts) position() *SymbolPosition {
+	if f.Position == nil {
+		return &SymbolPosition{}
+	}
+	return f.Position
+}
+
+func (f *RuleFacts) exposure() *UserExposure {
+	if f.Exposure == nil {
+		return &UserExposure{}
+	}
+	return f.Exposure
+}
+
+func (f *RuleFacts) isBuy() bool {
+	return strings.EqualFold(f.Check.Side, "BUY")
+}
+
+// newQuantity 下单成交后的持仓数量（带符号）
+func (f *RuleFacts) newQuantity() decimal.Decimal {
+	qty := f.position().Quantity
+	if f.isBuy() {
+		return qty.Add(f.Check.Quantity)
+	}
+	return qty.Sub(f.Check.Quantity)
+}
+
+// orderPrice 下单价格，优先由委托金额反推，兼容未带价格的市价单
+func (f *RuleFacts) orderPrice() decimal.Decimal {
+	if f.Check.Quantity.IsPositive() && f.Check.OrderValue.IsPositive() {
+		return f.Check.OrderValue.Div(f.Check.Quantity)
+	}
+	return f.Check.Price
+}
+
+// newGross 成交后的总敞口：按带符号持仓计算该品种 |pos'| - |pos| 的变化，
+// 卖出开空或加空会增加敞口，而不是被当作减仓
+func (f *RuleFacts) newGross() decimal.Decimal {
+	before := f.position().Quantity.Abs()
+	after := f.newQuantity().Abs()
+	return f.exposure().GrossExposure.Add(after.Sub(before).Mul(f.orderPrice()))
+}
+
+func (f *RuleFacts) newLeverage() decimal.Decimal {
+	if f.Check.AccountValue.IsZero() {
+		return decimal.Zero
+	}
+	return f.newGross().Div(f.Check.AccountValue)
+}
+
+// ruleFieldScope 字段依赖的数据来源，用于决定下单前需要准备哪些事实
+type ruleFieldScope int
+
+const (
+	scopeOrder ruleFieldScope = 1 << iota
+	scopeAccount
+	scopePosition
+	scopeExposure
+)
+
+type ruleField struct {
+	typ   ruleType
+	scope ruleFieldScope
+	get   func(f *RuleFacts) ruleValue
+}
+
+func numField(scope ruleFieldScope, get func(f *RuleFacts) decimal.Decimal) ruleField {
+	return ruleField{typ: ruleNum, scope: scope, get: func(f *RuleFacts) ruleValue { return ruleValue{num: get(f)} }}
+}
+
+func strField(get func(f *RuleFacts) string) ruleField {
+	return ruleField{typ: ruleStr, scope: scopeOrder, get: func(f *RuleFacts) ruleValue { return ruleValue{str: get(f)} }}
+}
+
+var ruleFields = map[string]ruleField{
+	"user_id":    strField(func(f *RuleFacts) string { return f.Check.UserID }),
+	"account_id": strField(func(f *RuleFacts) string { return f.Check.AccountID }),
+	"symbol":     strField(func(f *RuleFacts) string { return f.Check.Symbol }),
+	"side":       strField(func(f *RuleFacts) string { return strings.ToUpper(f.Check.Side) }),
+	"order_type": strField(func(f *RuleFacts) string { return strings.ToUpper(f.Check.OrderType) }),
+	"ip":         strField(func(f *RuleFacts) string { return f.Check.IPAddress }),
+	"device_id":  strField(func(f *RuleFacts) string { return f.Check.DeviceID }),
+
+	"quantity":      numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return f.Check.Quantity }),
+	"price":         numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return f.Check.Price }),
+	"order_value":   numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return f.Check.OrderValue }),
+	"account_value": numField(scopeAccount, func(f *RuleFacts) decimal.Decimal { return f.Check.AccountValue }),
+	"leverage":      numField(scopeAccount, func(f *RuleFacts) decimal.Decimal { return f.Check.Leverage }),
+	"new_leverage":  numField(scopeAccount|scopePosition|scopeExposure, (*RuleFacts).newLeverage),
+	"threshold":     numField(0, func(f *RuleFacts) decimal.Decimal { return f.Threshold }),
+
+	"position.quantity":       numField(scopePosition, func(f *RuleFacts) decimal.Decimal { return f.position().Quantity }),
+	"position.new_quantity":   numField(scopePosition, (*RuleFacts).newQuantity),
+	"position.avg_price":      numField(scopePosition, func(f *RuleFacts) decimal.Decimal { return f.position().AvgPrice }),
+	"position.market_value":   numField(scopePosition, func(f *RuleFacts) decimal.Decimal { return f.position().MarketValue }),
+	"position.unrealized_pnl": numField(scopePosition, func(f *RuleFacts) decimal.Decimal { return f.position().UnrealizedPnL }),
+
+	"exposure.gross":        numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().GrossExposure }),
+	"exposure.new_gross":    numField(scopePosition|scopeExposure, (*RuleFacts).newGross),
+	"exposure.net":          numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().NetExposure }),
+	"exposure.long":         numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().LongExposure }),
+	"exposure.short":        numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().ShortExposure }),
+	"exposure.delta":        numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().DeltaExposure }),
+	"exposure.gamma":        numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().GammaExposure }),
+	"exposure.vega":         numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().VegaExposure }),
+	"exposure.var_95":       numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().ValueAtRisk95 }),
+	"exposure.var_99":       numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().ValueAtRisk99 }),
+	"exposure.max_drawdown": numField(scopeExposure, func(f *RuleFacts) decimal.Decimal { return f.exposure().MaxDrawdown }),
+
+	// 时间字段取自检查时间戳
+	"hour":    numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return decimal.NewFromInt(int64(f.Check.Timestamp.Hour())) }),
+	"minute":  numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return decimal.NewFromInt(int64(f.Check.Timestamp.Minute())) }),
+	"weekday": numField(scopeOrder, func(f *RuleFacts) decimal.Decimal { return decimal.NewFromInt(int64(f.Check.Timestamp.Weekday())) }),
+}
+
+// ruleFunc 内置函数；compile 在编译期校验参数并可对常量参数做预处理
+type ruleFunc struct {
+	args    []ruleType
+	minArgs int
+	ret     ruleType
+	compile func(args []*ruleNode) (ruleEvalFn, error)
+}
+
+var ruleFuncs map[string]ruleFunc
+
+func init() {
+	ruleFuncs = map[string]ruleFunc{
+		"abs": {args: []ruleType{ruleNum}, ret: ruleNum, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			x := a[0].eval
+			return func(f *RuleFacts) (ruleValue, error) {
+				v, err := x(f)
+				return ruleValue{num: v.num.Abs()}, err
+			}, nil
+		}},
+		"min": {args: []ruleType{ruleNum, ruleNum}, ret: ruleNum, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			return numBinary(a[0].eval, a[1].eval, func(x, y decimal.Decimal) (decimal.Decimal, error) { return decimal.Min(x, y), nil }), nil
+		}},
+		"max": {args: []ruleType{ruleNum, ruleNum}, ret: ruleNum, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			return numBinary(a[0].eval, a[1].eval, func(x, y decimal.Decimal) (decimal.Decimal, error) { return decimal.Max(x, y), nil }), nil
+		}},
+		"between": {args: []ruleType{ruleNum, ruleNum, ruleNum}, ret: ruleBool, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			x, lo, hi := a[0].eval, a[1].eval, a[2].eval
+			return func(f *RuleFacts) (ruleValue, error) {
+				v, err := x(f)
+				if err != nil {
+					return v, err
+				}
+				l, err := lo(f)
+				if err != nil {
+					return l, err
+				}
+				h, err := hi(f)
+				if err != nil {
+					return h, err
+				}
+				return ruleValue{b: v.num.GreaterThanOrEqual(l.num) && v.num.LessThanOrEqual(h.num)}, nil
+			}, nil
+		}},
+		// matches 通配符匹配，如 matches(symbol, "BTC-*")
+		"matches": {args: []ruleType{ruleStr, ruleStr}, ret: ruleBool, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			pattern, ok := a[1].constant()
+			if !ok {
+				return nil, fmt.Errorf("matches: pattern must be a string literal")
+			}
+			if _, err := path.Match(pattern.str, ""); err != nil {
+				return nil, fmt.Errorf("matches: invalid pattern %q: %w", pattern.str, err)
+			}
+			x := a[0].eval
+			return func(f *RuleFacts) (ruleValue, error) {
+				v, err := x(f)
+				if err != nil {
+					return v, err
+				}
+				ok, _ := path.Match(pattern.str, v.str)
+				return ruleValue{b: ok}, nil
+			}, nil
+		}},
+		"starts_with": {args: []ruleType{ruleStr, ruleStr}, ret: ruleBool, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			x, p := a[0].eval, a[1].eval
+			return func(f *RuleFacts) (ruleValue, error) {
+				v, err := x(f)
+				if err != nil {
+					return v, err
+				}
+				pv, err := p(f)
+				return ruleValue{b: strings.HasPrefix(v.str, pv.str)}, err
+			}, nil
+		}},
+		// time_between("HH:MM", "HH:MM"[, "时区"]) 判断检查时间是否落在时间窗内，支持跨零点
+		"time_between": {args: []ruleType{ruleStr, ruleStr, ruleStr}, minArgs: 2, ret: ruleBool, compile: func(a []*ruleNode) (ruleEvalFn, error) {
+			consts := make([]string, len(a))
+			for i, n := range a {
+				c, ok := n.constant()
+				if !ok {
+					return nil, fmt.Errorf("time_between: argument %d must be a string literal", i+1)
+				}
+				consts[i] = c.str
+			}
+			start, err := parseClock(consts[0])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseClock(consts[1])
+			if err != nil {
+				return nil, err
+			}
+			loc := time.UTC
+			if len(consts) == 3 {
+				if loc, err = time.LoadLocation(consts[2]); err != nil {
+					return nil, fmt.Errorf("time_between: %w", err)
+				}
+			}
+			return func(f *RuleFacts) (ruleValue, error) {
+				t := f.Check.Timestamp.In(loc)
+				m := t.Hour()*60 + t.Minute()
+				if start <= end {
+					return ruleValue{b: m >= start && m < end}, nil
+				}
+				return ruleValue{b: m >= start || m < end}, nil
+			}, nil
+		}},
+	}
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time_between: invalid clock %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// CompiledRuleExpression 已编译的规则表达式
+type CompiledRuleExpression struct {
+	source string
+	eval   ruleEvalFn
+	// actual 顶层为比较运算时，用于在触发结果中报告左操作数的实际值
+	actual     ruleEvalFn
+	actualType ruleType
+	scope      ruleFieldScope
+}
+
+// Source 返回表达式原文
+func (c *CompiledRuleExpression) Source() string { return c.source }
+
+// Eval 求值，返回是否命中及用于展示的实际值
+func (c *CompiledRuleExpression) Eval(f *RuleFacts) (bool, string, error) {
+	v, err := c.eval(f)
+	if err != nil {
+		return false, "", err
+	}
+	if c.actual == nil {
+		return v.b, strconv.FormatBool(v.b), nil
+	}
+	a, err := c.actual(f)
+	if err != nil {
+		return v.b, "", nil
+	}
+	return v.b, a.format(c.actualType), nil
+}
+
+func (c *CompiledRuleExpression) uses(scope ruleFieldScope) bool { return c.scope&scope != 0 }
+
+// CompileRuleExpression 解析并类型检查表达式，结果必须为布尔值
+func CompileRuleExpression(src string) (*CompiledRuleExpression, error) {
+	tokens, err := lexRule(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRuleExpression, err)
+	}
+	p := &ruleParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRuleExpression, err)
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalidRuleExpression, tok.text, tok.pos)
+	}
+	if root.typ != ruleBool {
+		return nil, fmt.Errorf("%w: expression must be boolean, got %s", ErrInvalidRuleExpression, root.typ)
+	}
+	c := &CompiledRuleExpression{source: src, eval: root.eval, scope: p.scope}
+	if root.left != nil {
+		c.actual, c.actualType = root.left.eval, root.left.typ
+	}
+	return c, nil
+}
+
+// --- lexer ---
+
+type ruleTokKind int
+
+const (
+	tokEOF ruleTokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type ruleToken struct {
+	kind ruleTokKind
+	text string
+	pos  int
+}
+
+var ruleOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "(": true, ")": true, "[": true, "]": true, ",": true,
+	"<": true, ">": true, "!": true, "==": true, "!=": true, "<=": true, ">=": true, "&&": true, "||": true,
+}
+
+func lexRule(src string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, ruleToken{kind: tokNum, text: strings.ReplaceAll(string(rs[i:j]), "_", ""), pos: i})
+			i = j
+		case r == '"' || r == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for j < len(rs) && rs[j] != r {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				sb.WriteRune(rs[j])
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, ruleToken{kind: tokStr, text: sb.String(), pos: i})
+			i = j + 1
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, ruleToken{kind: tokIdent, text: string(rs[i:j]), pos: i})
+			i = j
+		default:
+			op := string(r)
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if !ruleOperators[op] {
+				return nil, fmt.Errorf("unexpected character %q at %d", r, i)
+			}
+			tokens = append(tokens, ruleToken{kind: tokOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	return append(tokens, ruleToken{kind: tokEOF, pos: len(rs)}), nil
+}
+
+// --- parser / compiler ---
+
+type ruleNode struct {
+	typ  ruleType
+	eval ruleEvalFn
+	// 常量折叠：字面量节点保留原值
+	lit *ruleValue
+	// 比较节点保留左操作数
+	left *ruleNode
+}
+
+func (n *ruleNode) constant() (ruleValue, bool) {
+	if n.lit == nil {
+		return ruleValue{}, false
+	}
+	return *n.lit, true
+}
+
+func literalNode(t ruleType, v ruleValue) *ruleNode {
+	return &ruleNode{typ: t, lit: &v, eval: func(*RuleFacts) (ruleValue, error) { return v, nil }}
+}
+
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+	scope  ruleFieldScope
+}
+
+func (p *ruleParser) peek() ruleToken { return p.tokens[p.pos] }
+
+func (p *ruleParser) next() ruleToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *ruleParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, s := range texts {
+		if t.text == s {
+			p.pos++
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func (p *ruleParser) expect(text string) error {
+	if t := p.next(); t.text != text || (t.kind != tokOp && t.kind != tokIdent) {
+		return fmt.Errorf("expected %q at %d, got %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *ruleParser) parseOr() (*ruleNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != ruleBool || right.typ != ruleBool {
+			return nil, fmt.Errorf("'or' requires bool operands")
+		}
+		l, r := left.eval, right.eval
+		left = &ruleNode{typ: ruleBool, eval: func(f *RuleFacts) (ruleValue, error) {
+			v, err := l(f)
+			if err != nil || v.b {
+				return v, err
+			}
+			return r(f)
+		}}
+	}
+}
+
+func (p *ruleParser) parseAnd() (*ruleNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != ruleBool || right.typ != ruleBool {
+			return nil, fmt.Errorf("'and' requires bool operands")
+		}
+		l, r := left.eval, right.eval
+		left = &ruleNode{typ: ruleBool, eval: func(f *RuleFacts) (ruleValue, error) {
+			v, err := l(f)
+			if err != nil || !v.b {
+				return v, err
+			}
+			return r(f)
+		}}
+	}
+}
+
+func (p *ruleParser) parseNot() (*ruleNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if inner.typ != ruleBool {
+			return nil, fmt.Errorf("'not' requires a bool operand")
+		}
+		x := inner.eval
+		return &ruleNode{typ: ruleBool, eval: func(f *RuleFacts) (ruleValue, error) {
+			v, err := x(f)
+			return ruleValue{b: !v.b}, err
+		}}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *ruleParser) parseCmp() (*ruleNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	negate := false
+	if t := p.peek(); t.kind == tokIdent && t.text == "not" && p.tokens[p.pos+1].text == "in" {
+		p.pos++
+		negate = true
+	}
+	if _, ok := p.accept("in"); ok {
+		return p.parseIn(left, negate)
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ != right.typ {
+		return nil, fmt.Errorf("cannot compare %s with %s", left.typ, right.typ)
+	}
+	if left.typ != ruleNum && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("operator %s requires numbers", op)
+	}
+
+	l, r, typ := left.eval, right.eval, left.typ
+	return &ruleNode{typ: ruleBool, left: left, eval: func(f *RuleFacts) (ruleValue, error) {
+		a, err := l(f)
+		if err != nil {
+			return a, err
+		}
+		b, err := r(f)
+		if err != nil {
+			return b, err
+		}
+		var c int
+		switch typ {
+		case ruleNum:
+			c = a.num.Cmp(b.num)
+		case ruleStr:
+			c = strings.Compare(a.str, b.str)
+		default:
+			if a.b != b.b {
+				c = 1
+			}
+		}
+		switch op {
+		case "==":
+			return ruleValue{b: c == 0}, nil
+		case "!=":
+			return ruleValue{b: c != 0}, nil
+		case "<":
+			return ruleValue{b: c < 0}, nil
+		case "<=":
+			return ruleValue{b: c <= 0}, nil
+		case ">":
+			return ruleValue{b: c > 0}, nil
+		default:
+			return ruleValue{b: c >= 0}, nil
+		}
+	}}, nil
+}
+
+// parseIn 集合成员判断，集合元素必须为同类型字面量，编译为哈希查找
+func (p *ruleParser) parseIn(left *ruleNode, negate bool) (*ruleNode, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{})
+	for {
+		item, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		v, ok := item.constant()
+		if !ok {
+			return nil, fmt.Errorf("'in' list must contain literals")
+		}
+		if item.typ != left.typ {
+			return nil, fmt.Errorf("'in' list element is %s, want %s", item.typ, left.typ)
+		}
+		set[v.format(item.typ)] = struct{}{}
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	x, typ := left.eval, left.typ
+	return &ruleNode{typ: ruleBool, left: left, eval: func(f *RuleFacts) (ruleValue, error) {
+		v, err := x(f)
+		if err != nil {
+			return v, err
+		}
+		key := v.format(typ)
+		if typ == ruleNum {
+			key = v.num.String()
+		}
+		_, found := set[key]
+		return ruleValue{b: found != negate}, nil
+	}}, nil
+}
+
+func (p *ruleParser) parseSum() (*ruleNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != ruleNum || right.typ != ruleNum {
+			return nil, fmt.Errorf("operator %s requires numbers", op)
+		}
+		fn := decimal.Decimal.Add
+		if op == "-" {
+			fn = decimal.Decimal.Sub
+		}
+		left = &ruleNode{typ: ruleNum, eval: numBinary(left.eval, right.eval, func(a, b decimal.Decimal) (decimal.Decimal, error) { return fn(a, b), nil })}
+	}
+}
+
+func (p *ruleParser) parseProduct() (*ruleNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != ruleNum || right.typ != ruleNum {
+			return nil, fmt.Errorf("operator %s requires numbers", op)
+		}
+		var fn func(a, b decimal.Decimal) (decimal.Decimal, error)
+		switch op {
+		case "*":
+			fn = func(a, b decimal.Decimal) (decimal.Decimal, error) { return a.Mul(b), nil }
+		case "/":
+			fn = func(a, b decimal.Decimal) (decimal.Decimal, error) {
+				if b.IsZero() {
+					return decimal.Zero, errors.New("division by zero")
+				}
+				return a.Div(b), nil
+			}
+		default:
+			fn = func(a, b decimal.Decimal) (decimal.Decimal, error) {
+				if b.IsZero() {
+					return decimal.Zero, errors.New("modulo by zero")
+				}
+				return a.Mod(b), nil
+			}
+		}
+		left = &ruleNode{typ: ruleNum, eval: numBinary(left.eval, right.eval, fn)}
+	}
+}
+
+func (p *ruleParser) parseUnary() (*ruleNode, error) {
+	if _, ok := p.accept("-"); ok {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if inner.typ != ruleNum {
+			return nil, fmt.Errorf("unary '-' requires a number")
+		}
+		if v, ok := inner.constant(); ok {
+			return literalNode(ruleNum, ruleValue{num: v.num.Neg()}), nil
+		}
+		x := inner.eval
+		return &ruleNode{typ: ruleNum, eval: func(f *RuleFacts) (ruleValue, error) {
+			v, err := x(f)
+			return ruleValue{num: v.num.Neg()}, err
+		}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *ruleParser) parsePrimary() (*ruleNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		d, err := decimal.NewFromString(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literalNode(ruleNum, ruleValue{num: d}), nil
+	case tokStr:
+		return literalNode(ruleStr, ruleValue{str: t.text}), nil
+	case tokOp:
+		if t.text != "(" {
+			return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return literalNode(ruleBool, ruleValue{b: t.text == "true"}), nil
+		}
+		if p.peek().kind == tokOp && p.peek().text == "(" {
+			return p.parseCall(t)
+		}
+		field, ok := ruleFields[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at %d", t.text, t.pos)
+		}
+		p.scope |= field.scope
+		get := field.get
+		return &ruleNode{typ: field.typ, eval: func(f *RuleFacts) (ruleValue, error) { return get(f), nil }}, nil
+	default:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+}
+
+func (p *ruleParser) parseCall(name ruleToken) (*ruleNode, error) {
+	fn, ok := ruleFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+	p.next() // (
+	var args []*ruleNode
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	minArgs := fn.minArgs
+	if minArgs == 0 {
+		minArgs = len(fn.args)
+	}
+	if len(args) < minArgs || len(args) > len(fn.args) {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", name.text, len(fn.args), len(args))
+	}
+	for i, a := range args {
+		if a.typ != fn.args[i] {
+			return nil, fmt.Errorf("%s argument %d must be %s, got %s", name.text, i+1, fn.args[i], a.typ)
+		}
+	}
+	if name.text == "time_between" {
+		p.scope |= scopeOrder
+	}
+	eval, err := fn.compile(args)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleNode{typ: fn.ret, eval: eval}, nil
+}
+
+func numBinary(l, r ruleEvalFn, op func(a, b decimal.Decimal) (decimal.Decimal, error)) ruleEvalFn {
+	return func(f *RuleFacts) (ruleValue, error) {
+		a, err := l(f)
+		if err != nil {
+			return a, err
+		}
+		b, err := r(f)
+		if err != nil {
+			return b, err
+		}
+		v, err := op(a.num, b.num)
+		return ruleValue{num: v}, err
+	}
+}
@@ -1,104 +1,651 @@
 package domain
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
-// StressScenario 压力测试场景
+// ScenarioSource 压力场景来源
+type ScenarioSource string
+
+const (
+	ScenarioSourceBuiltin    ScenarioSource = "BUILTIN"    // 系统内置
+	ScenarioSourceManual     ScenarioSource = "MANUAL"     // 风控人员手工定义
+	ScenarioSourceHistorical ScenarioSource = "HISTORICAL" // 由历史窗口回放生成
+)
+
+// StressScenario 多因子压力测试场景。
+// 价格冲击按 标的 > 行业 > 默认 的优先级匹配，均为相对变化（-0.20 表示下跌 20%）；
+// 波动率冲击为隐含波动率的相对变化，利率冲击为绝对变化（0.01 表示上升 100bp），
+// 汇率冲击为计价货币兑基准货币的相对变化。
 type StressScenario struct {
-	Name        string
-	Description string
-	PriceShift  map[string]float64 // Symbol -> Percentage Shift (e.g., -0.20 for 20% drop)
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Version      int                `json:"version"`
+	Description  string             `json:"description"`
+	Source       ScenarioSource     `json:"source"`
+	SymbolShocks map[string]float64 `json:"symbol_shocks"`
+	SectorShocks map[string]float64 `json:"sector_shocks"`
+	DefaultShock float64            `json:"default_shock"`
+	VolShock     float64            `json:"vol_shock"`
+	RateShock    float64            `json:"rate_shock"`
+	FXShocks     map[string]float64 `json:"fx_shocks"`
+	WindowStart  time.Time          `json:"window_start"` // 历史回放窗口（仅 HISTORICAL）
+	WindowEnd    time.Time          `json:"window_end"`
+	CreatedBy    string             `json:"created_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// Validate 校验场景参数，价格与波动率冲击不得使其变为负数
+func (s *StressScenario) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("scenario name is required")
+	}
+	if s.DefaultShock < -1 {
+		return fmt.Errorf("default shock %f below -100%%", s.DefaultShock)
+	}
+	for k, v := range s.SymbolShocks {
+		if v < -1 {
+			return fmt.Errorf("symbol shock for %s below -100%%", k)
+		}
+	}
+	for k, v := range s.SectorShocks {
+		if v < -1 {
+			return fmt.Errorf("sector shock for %s below -100%%", k)
+		}
+	}
+	for k, v := range s.FXShocks {
+		if v < -1 {
+			return fmt.Errorf("fx shock for %s below -100%%", k)
+		}
+	}
+	if s.VolShock < -1 {
+		return fmt.Errorf("vol shock %f below -100%%", s.VolShock)
+	}
+	return nil
+}
+
+// PriceShock 返回标的在该场景下的价格冲击
+func (s *StressScenario) PriceShock(symbol, sector string) float64 {
+	if v, ok := s.SymbolShocks[symbol]; ok {
+		return v
+	}
+	if sector != "" {
+		if v, ok := s.SectorShocks[sector]; ok {
+			return v
+		}
+	}
+	return s.DefaultShock
 }
 
-// StressTestEngine 压力测试引擎
+// Scaled 返回所有冲击按倍数缩放后的场景副本，用于反向压力测试
+func (s *StressScenario) Scaled(k float64) *StressScenario {
+	out := *s
+	out.SymbolShocks = scaleShocks(s.SymbolShocks, k)
+	out.SectorShocks = scaleShocks(s.SectorShocks, k)
+	out.FXShocks = scaleShocks(s.FXShocks, k)
+	out.DefaultShock = s.DefaultShock * k
+	out.VolShock = s.VolShock * k
+	out.RateShock = s.RateShock * k
+	return &out
+}
+
+// maxScale 冲击缩放的上限：任一价格/汇率/波动率不得跌破零，最多放大 10 倍
+func (s *StressScenario) maxScale() float64 {
+	limit := 10.0
+	check := func(v float64) {
+		if v < 0 {
+			limit = math.Min(limit, -1/v)
+		}
+	}
+	check(s.DefaultShock)
+	check(s.VolShock)
+	for _, m := range []map[string]float64{s.SymbolShocks, s.SectorShocks, s.FXShocks} {
+		for _, v := range m {
+			check(v)
+		}
+	}
+	return limit
+}
+
+func scaleShocks(in map[string]float64, k float64) map[string]float64 {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]float64, len(in))
+	for key, v := range in {
+		out[key] = v * k
+	}
+	return out
+}
+
+// BuiltinStressScenarios 内置场景，场景库为空时作为初始版本写入
+func BuiltinStressScenarios() []*StressScenario {
+	return []*StressScenario{
+		{
+			Name:         "GFC",
+			Description:  "Global Financial Crisis: market wide crash, high volatility, rates cut",
+			Source:       ScenarioSourceBuiltin,
+			DefaultShock: -0.40,
+			SymbolShocks: map[string]float64{"GOLD": 0.10},
+			SectorShocks: map[string]float64{"FINANCIALS": -0.55, "PRECIOUS_METALS": 0.10},
+			VolShock:     1.50,
+			RateShock:    -0.02,
+			FXShocks:     map[string]float64{"EUR": -0.15, "GBP": -0.25, "JPY": 0.20},
+		},
+		{
+			Name:         "FLASH_CRASH",
+			Description:  "Sudden 15% drop in index within minutes",
+			Source:       ScenarioSourceBuiltin,
+			DefaultShock: -0.15,
+			VolShock:     0.80,
+		},
+		{
+			Name:         "VOL_SPIKE",
+			Description:  "Sudden increase in market volatility by 50%",
+			Source:       ScenarioSourceBuiltin,
+			DefaultShock: -0.05,
+			VolShock:     0.50,
+		},
+		{
+			Name:         "RATES_SHOCK",
+			Description:  "Parallel rate rise of 200bp with equity sell-off",
+			Source:       ScenarioSourceBuiltin,
+			DefaultShock: -0.10,
+			VolShock:     0.30,
+			RateShock:    0.02,
+			FXShocks:     map[string]float64{"JPY": -0.08, "EUR": -0.05},
+		},
+	}
+}
+
+// StressPosition 参与压力测试的持仓，在 VaR 持仓的基础上补充行业、币种与利率敏感度
+type StressPosition struct {
+	VaRPosition
+	Sector   string  `json:"sector"`
+	Currency string  `json:"currency"` // 计价货币，空表示基准货币
+	Duration float64 `json:"duration"` // 修正久期，用于线性利率敏感持仓
+}
+
+// InstrumentClassifier 提供标的所属行业与计价货币，用于匹配行业与汇率冲击。
+// currency 须为 FXCurrency 归一化后的结果，与 StressScenario.FXShocks 的键一致。
+type InstrumentClassifier interface {
+	Classify(symbol string) (sector, currency string)
+}
+
+// ReportingCurrency 压力测试的基准货币，FXShocks 为各币种相对其的涨跌幅
+const ReportingCurrency = "USD"
+
+// usdStablecoins 与基准货币 1:1 锚定的稳定币，不产生汇率敞口
+var usdStablecoins = map[string]bool{"USDT": true, "USDC": true, "BUSD": true, "TUSD": true, "DAI": true, "FDUSD": true}
+
+// FXCurrency 把计价货币归一化为 FXShocks 使用的 ISO 代码（EUR/GBP/JPY 等）；
+// 基准货币及其稳定币返回空，表示无汇率敞口
+func FXCurrency(currency string) string {
+	c := strings.ToUpper(strings.TrimSpace(currency))
+	if c == ReportingCurrency || usdStablecoins[c] {
+		return ""
+	}
+	return c
+}
+
+// SymbolQuoteCurrency 在缺少参考数据时从 BASE/QUOTE、BASE-QUOTE、BASE_QUOTE 形式的代码中解析计价货币
+func SymbolQuoteCurrency(symbol string) string {
+	if i := strings.LastIndexAny(symbol, "/-_"); i >= 0 && i < len(symbol)-1 {
+		return FXCurrency(symbol[i+1:])
+	}
+	return ""
+}
+
+// MarginContext 账户保证金状态，用于判断压力情景下是否击穿维持保证金
+type MarginContext struct {
+	Equity            decimal.Decimal
+	MaintenanceMargin decimal.Decimal // 固定维持保证金
+	MaintenanceRate   decimal.Decimal // 按压力后总敞口计算的维持保证金率，非零时优先使用
+}
+
+func (m *MarginContext) required(grossExposure float64) float64 {
+	if m.MaintenanceRate.IsPositive() {
+		return grossExposure * m.MaintenanceRate.InexactFloat64()
+	}
+	return m.MaintenanceMargin.InexactFloat64()
+}
+
+// StressTestResult 压力测试报告
+type StressTestResult struct {
+	ScenarioName    string
+	ScenarioVersion int
+	BaseValue       decimal.Decimal
+	StressedValue   decimal.Decimal
+	PnLImpact       decimal.Decimal
+	PercentageDrop  decimal.Decimal
+	PositionPnL     map[string]decimal.Decimal // PositionID（为空时为 Symbol） -> PnL
+	StressedEquity  decimal.Decimal            // 提供保证金信息时有效
+	RequiredMargin  decimal.Decimal
+	Survived        bool
+}
+
+// ReverseStressResult 反向压力测试结果：沿给定冲击方向找到击穿保证金的最小冲击倍数
+type ReverseStressResult struct {
+	Breached        bool            // 在搜索范围内找到击穿点
+	AlreadyBreached bool            // 当前状态已低于维持保证金
+	Scale           float64         // 最小冲击倍数
+	Scenario        *StressScenario // 缩放后的临界场景
+	PnLImpact       decimal.Decimal
+	StressedEquity  decimal.Decimal
+	RequiredMargin  decimal.Decimal
+	Iterations      int
+}
+
+// StressTestRun 按账户执行的压力测试记录
+type StressTestRun struct {
+	ID              string          `json:"id"`
+	UserID          string          `json:"user_id"`
+	AccountID       string          `json:"account_id"`
+	ScenarioName    string          `json:"scenario_name"`
+	ScenarioVersion int             `json:"scenario_version"`
+	PnLImpact       decimal.Decimal `json:"pnl_impact"`
+	PercentageDrop  decimal.Decimal `json:"percentage_drop"`
+	StressedEquity  decimal.Decimal `json:"stressed_equity"`
+	RequiredMargin  decimal.Decimal `json:"required_margin"`
+	Survived        bool            `json:"survived"`
+	RunAt           time.Time       `json:"run_at"`
+}
+
+const (
+	reverseStressGridSteps = 20
+	reverseStressTolerance = 1e-4
+	reverseStressMaxIter   = 60
+)
+
+// StressTestEngine 压力测试引擎，按场景对持仓进行全量重估
 type StressTestEngine struct {
 	scenarios map[string]*StressScenario
+	pricer    OptionPricer
+	lossLimit float64 // 未提供保证金信息时，亏损比例超过该值视为未通过
 }
 
+// NewStressTestEngine 创建压力测试引擎，并加载内置场景
 func NewStressTestEngine() *StressTestEngine {
 	e := &StressTestEngine{
 		scenarios: make(map[string]*StressScenario),
+		pricer:    NewBlackScholesPricer(),
+		lossLimit: 0.5,
+	}
+	for _, sc := range BuiltinStressScenarios() {
+		e.Register(sc)
 	}
-	e.initDefaultScenarios()
 	return e
 }
 
-func (e *StressTestEngine) initDefaultScenarios() {
-	// 全球金融危机场景 (GFC)
-	e.scenarios["GFC"] = &StressScenario{
-		Name:        "Global Financial Crisis",
-		Description: "Market wide crash, high volatility",
-		PriceShift: map[string]float64{
-			"DEFAULT": -0.40, // 默认下跌 40%
-			"GOLD":    0.10,  // 避险资产上涨 10%
-		},
+// SetPricer 设置期权重估模型
+func (e *StressTestEngine) SetPricer(pricer OptionPricer) {
+	if pricer != nil {
+		e.pricer = pricer
 	}
+}
 
-	// 闪崩场景 (Flash Crash)
-	e.scenarios["FLASH_CRASH"] = &StressScenario{
-		Name:        "Flash Crash",
-		Description: "Sudden 15% drop in index within minutes",
-		PriceShift: map[string]float64{
-			"DEFAULT": -0.15,
-		},
+// Register 注册或覆盖同名场景
+func (e *StressTestEngine) Register(scenario *StressScenario) {
+	if scenario == nil || scenario.Name == "" {
+		return
 	}
+	e.scenarios[scenario.Name] = scenario
+}
 
-	// 波动率飙升场景 (Vol Spike)
-	e.scenarios["VOL_SPIKE"] = &StressScenario{
-		Name:        "Volatility Spike",
-		Description: "Sudden increase in market volatility by 50%",
-		PriceShift: map[string]float64{
-			"DEFAULT": -0.05, // 伴随小幅下跌
-		},
-		// 注意：实际逻辑中可能需要影响 Greeks 评估，这里作为价格影响示例
+// Scenario 按名称查找场景
+func (e *StressTestEngine) Scenario(name string) (*StressScenario, bool) {
+	sc, ok := e.scenarios[name]
+	return sc, ok
+}
+
+// Scenarios 返回全部场景（按名称排序）
+func (e *StressTestEngine) Scenarios() []*StressScenario {
+	out := make([]*StressScenario, 0, len(e.scenarios))
+	for _, sc := range e.scenarios {
+		out = append(out, sc)
 	}
+	slices.SortFunc(out, func(a, b *StressScenario) int { return strings.Compare(a.Name, b.Name) })
+	return out
 }
 
-// RunScenario 在指定资产组合上运行压力测试
+// RunScenario 在指定资产组合上运行压力测试（现货资产，不含保证金判断）
 func (e *StressTestEngine) RunScenario(scenarioName string, assets []PortfolioAsset) (*StressTestResult, error) {
 	scenario, ok := e.scenarios[scenarioName]
 	if !ok {
 		return nil, fmt.Errorf("scenario %s not found", scenarioName)
 	}
 
-	var totalInitialValue decimal.Decimal
-	var totalStressedValue decimal.Decimal
-
+	positions := make([]StressPosition, 0, len(assets))
 	for _, asset := range assets {
-		initialVal := asset.Position.Mul(asset.CurrentPrice)
-		totalInitialValue = totalInitialValue.Add(initialVal)
+		positions = append(positions, StressPosition{VaRPosition: VaRPosition{
+			Symbol:   asset.Symbol,
+			Type:     InstrumentSpot,
+			Quantity: asset.Position,
+			Price:    asset.CurrentPrice,
+		}})
+	}
+	return e.Run(context.Background(), scenario, positions, nil)
+}
 
-		shift, found := scenario.PriceShift[asset.Symbol]
-		if !found {
-			shift = scenario.PriceShift["DEFAULT"]
+// Run 在给定场景下重估持仓；margin 非空时以压力后权益是否覆盖维持保证金判断是否通过
+func (e *StressTestEngine) Run(ctx context.Context, scenario *StressScenario, positions []StressPosition, margin *MarginContext) (*StressTestResult, error) {
+	if scenario == nil {
+		return nil, errors.New("scenario is required")
+	}
+	now := time.Now()
+	baseScenario := &StressScenario{}
+
+	var baseTotal, stressedTotal, gross float64
+	positionPnL := make(map[string]decimal.Decimal, len(positions))
+	for i := range positions {
+		pos := &positions[i]
+		base, err := e.revalue(ctx, pos, baseScenario, now)
+		if err != nil {
+			return nil, err
+		}
+		stressed, err := e.revalue(ctx, pos, scenario, now)
+		if err != nil {
+			return nil, err
 		}
+		baseTotal += base
+		stressedTotal += stressed
+		gross += math.Abs(stressed)
 
-		stressedPrice := asset.CurrentPrice.Mul(decimal.NewFromFloat(1.0 + shift))
-		stressedVal := asset.Position.Mul(stressedPrice)
-		totalStressedValue = totalStressedValue.Add(stressedVal)
+		key := pos.PositionID
+		if key == "" {
+			key = pos.Symbol
+		}
+		positionPnL[key] = positionPnL[key].Add(decimal.NewFromFloat(stressed - base))
 	}
 
-	pnl := totalStressedValue.Sub(totalInitialValue)
-	percentage := decimal.Zero
-	if !totalInitialValue.IsZero() {
-		percentage = pnl.Div(totalInitialValue).Mul(decimal.NewFromInt(100))
+	pnl := stressedTotal - baseTotal
+	percentage := 0.0
+	if baseTotal != 0 {
+		percentage = pnl / math.Abs(baseTotal) * 100
 	}
 
-	return &StressTestResult{
-		ScenarioName:   scenario.Name,
-		PnLImpact:      pnl,
-		PercentageDrop: percentage,
-		Survived:       percentage.Abs().LessThan(decimal.NewFromInt(50)), // 示例：亏损超过 50% 视为未通过
-	}, nil
+	result := &StressTestResult{
+		ScenarioName:    scenario.Name,
+		ScenarioVersion: scenario.Version,
+		BaseValue:       decimal.NewFromFloat(baseTotal),
+		StressedValue:   decimal.NewFromFloat(stressedTotal),
+		PnLImpact:       decimal.NewFromFloat(pnl),
+		PercentageDrop:  decimal.NewFromFloat(percentage),
+		PositionPnL:     positionPnL,
+	}
+	if margin != nil {
+		equity := margin.Equity.InexactFloat64() + pnl
+		required := margin.required(gross)
+		result.StressedEquity = decimal.NewFromFloat(equity)
+		result.RequiredMargin = decimal.NewFromFloat(required)
+		result.Survived = equity >= required
+	} else {
+		result.Survived = math.Abs(percentage) < e.lossLimit*100
+	}
+	return result, nil
 }
 
-// StressTestResult 压力测试报告
-type StressTestResult struct {
-	ScenarioName   string
-	PnLImpact      decimal.Decimal
-	PercentageDrop decimal.Decimal
-	Survived       bool
+// ReverseStress 反向压力测试：沿冲击方向搜索使账户权益跌破维持保证金的最小冲击倍数。
+// direction 为空时分别以全市场单位下跌与单位上涨为方向，取更小的击穿冲击；
+// 先网格扫描定位首个击穿区间（兼容期权等非单调损益），再二分逼近临界点。
+func (e *StressTestEngine) ReverseStress(ctx context.Context, positions []StressPosition, margin MarginContext, direction *StressScenario) (*ReverseStressResult, error) {
+	if len(positions) == 0 {
+		return nil, errors.New("no positions to evaluate")
+	}
+	if direction != nil {
+		return e.reverseSearch(ctx, positions, margin, direction)
+	}
+
+	down, err := e.reverseSearch(ctx, positions, margin, &StressScenario{Name: "REVERSE_DOWN", DefaultShock: -1})
+	if err != nil {
+		return nil, err
+	}
+	up, err := e.reverseSearch(ctx, positions, margin, &StressScenario{Name: "REVERSE_UP", DefaultShock: 1})
+	if err != nil {
+		return nil, err
+	}
+	if up.Breached && (!down.Breached || up.Scale < down.Scale) {
+		return up, nil
+	}
+	return down, nil
+}
+
+func (e *StressTestEngine) reverseSearch(ctx context.Context, positions []StressPosition, margin MarginContext, direction *StressScenario) (*ReverseStressResult, error) {
+	iterations := 0
+	shortfall := func(k float64) (float64, *StressTestResult, error) {
+		iterations++
+		res, err := e.Run(ctx, direction.Scaled(k), positions, &margin)
+		if err != nil {
+			return 0, nil, err
+		}
+		return res.StressedEquity.Sub(res.RequiredMargin).InexactFloat64(), res, nil
+	}
+	finish := func(k float64, res *StressTestResult, breached bool) *ReverseStressResult {
+		out := &ReverseStressResult{
+			Breached:       breached,
+			Scale:          k,
+			Scenario:       direction.Scaled(k),
+			PnLImpact:      res.PnLImpact,
+			StressedEquity: res.StressedEquity,
+			RequiredMargin: res.RequiredMargin,
+			Iterations:     iterations,
+		}
+		out.Scenario.Name = direction.Name
+		return out
+	}
+
+	gap, res, err := shortfall(0)
+	if err != nil {
+		return nil, err
+	}
+	if gap < 0 {
+		out := finish(0, res, true)
+		out.AlreadyBreached = true
+		return out, nil
+	}
+
+	upper := direction.maxScale()
+	lo, hi := 0.0, -1.0
+	var hiRes *StressTestResult
+	for i := 1; i <= reverseStressGridSteps; i++ {
+		k := upper * float64(i) / reverseStressGridSteps
+		gap, res, err = shortfall(k)
+		if err != nil {
+			return nil, err
+		}
+		if gap < 0 {
+			hi, hiRes = k, res
+			break
+		}
+		lo = k
+	}
+	if hi < 0 {
+		return finish(upper, res, false), nil
+	}
+
+	for hi-lo > reverseStressTolerance && iterations < reverseStressMaxIter {
+		mid := (lo + hi) / 2
+		gap, res, err = shortfall(mid)
+		if err != nil {
+			return nil, err
+		}
+		if gap < 0 {
+			hi, hiRes = mid, res
+		} else {
+			lo = mid
+		}
+	}
+	return finish(hi, hiRes, true), nil
+}
+
+// revalue 在场景冲击下重估单个持仓（以基准货币计价）
+func (e *StressTestEngine) revalue(ctx context.Context, pos *StressPosition, sc *StressScenario, at time.Time) (float64, error) {
+	qty := pos.Quantity.InexactFloat64() * pos.multiplier()
+	underlying := pos.Price.InexactFloat64() * (1 + sc.PriceShock(pos.factor(), pos.Sector))
+	fx := 1 + sc.FXShocks[pos.Currency]
+
+	if !pos.isOption() {
+		return qty * underlying * (1 - pos.Duration*sc.RateShock) * fx, nil
+	}
+
+	input := pos.pricingInput(underlying, at)
+	input.Volatility *= 1 + sc.VolShock
+	input.RiskFreeRate += sc.RateShock
+	price, err := e.pricer.PriceOption(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revalue option %s: %w", pos.Symbol, err)
+	}
+	return qty * price * fx, nil
+}
+
+// HistoricalWindowProvider 提供指定时间窗口内的日收盘价（按时间升序）
+type HistoricalWindowProvider interface {
+	GetPriceWindow(ctx context.Context, symbol string, start, end time.Time) ([]decimal.Decimal, error)
+}
+
+// HistoricalScenarioSpec 历史回放场景的构建参数
+type HistoricalScenarioSpec struct {
+	Name         string
+	Description  string
+	Symbols      []string
+	Sectors      map[string]string // Symbol -> 行业，用于汇总行业冲击
+	FXSymbols    map[string]string // 币种 -> 汇率代码（如 EUR -> EUR/USD）
+	Start        time.Time
+	End          time.Time
+	WorstPoint   bool    // true 使用窗口内相对起点偏离最大的价格，否则使用起止收益
+	BaselineDays int     // 计算波动率冲击的基准窗口长度（窗口起点之前）
+	RateShock    float64 // 利率冲击无法从行情回放，需显式给出
+	CreatedBy    string
+}
+
+// BuildHistoricalScenario 根据历史窗口回放生成多因子场景：
+// 标的冲击取窗口内价格变动，行业与默认冲击为成员均值，
+// 波动率冲击为窗口已实现波动率相对基准窗口的变化。
+func BuildHistoricalScenario(ctx context.Context, provider HistoricalWindowProvider, spec HistoricalScenarioSpec) (*StressScenario, error) {
+	if provider == nil {
+		return nil, errors.New("historical window provider not configured")
+	}
+	if len(spec.Symbols) == 0 {
+		return nil, errors.New("symbols required")
+	}
+	if !spec.End.After(spec.Start) {
+		return nil, errors.New("window end must be after start")
+	}
+	if spec.BaselineDays <= 0 {
+		spec.BaselineDays = 250
+	}
+
+	scenario := &StressScenario{
+		Name:         spec.Name,
+		Description:  spec.Description,
+		Source:       ScenarioSourceHistorical,
+		SymbolShocks: make(map[string]float64, len(spec.Symbols)),
+		SectorShocks: make(map[string]float64),
+		RateShock:    spec.RateShock,
+		WindowStart:  spec.Start,
+		WindowEnd:    spec.End,
+		CreatedBy:    spec.CreatedBy,
+	}
+
+	sectorSum := make(map[string]float64)
+	sectorCount := make(map[string]int)
+	var shockSum, volRatioSum float64
+	var volCount int
+	for _, symbol := range spec.Symbols {
+		closes, err := provider.GetPriceWindow(ctx, symbol, spec.Start, spec.End)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load window for %s: %w", symbol, err)
+		}
+		shock, ok := windowShock(closes, spec.WorstPoint)
+		if !ok {
+			return nil, fmt.Errorf("insufficient history for %s in window", symbol)
+		}
+		scenario.SymbolShocks[symbol] = shock
+		shockSum += shock
+		if sector := spec.Sectors[symbol]; sector != "" {
+			sectorSum[sector] += shock
+			sectorCount[sector]++
+		}
+
+		baseline, err := provider.GetPriceWindow(ctx, symbol, spec.Start.AddDate(0, 0, -spec.BaselineDays), spec.Start)
+		if err != nil {
+			continue
+		}
+		stressedVol, baseVol := sampleVolatility(closes), sampleVolatility(baseline)
+		if stressedVol > 0 && baseVol > 0 {
+			volRatioSum += stressedVol / baseVol
+			volCount++
+		}
+	}
+
+	scenario.DefaultShock = shockSum / float64(len(spec.Symbols))
+	for sector, sum := range sectorSum {
+		scenario.SectorShocks[sector] = sum / float64(sectorCount[sector])
+	}
+	if volCount > 0 {
+		scenario.VolShock = math.Max(volRatioSum/float64(volCount)-1, -1)
+	}
+
+	if len(spec.FXSymbols) > 0 {
+		scenario.FXShocks = make(map[string]float64, len(spec.FXSymbols))
+		for currency, fxSymbol := range spec.FXSymbols {
+			closes, err := provider.GetPriceWindow(ctx, fxSymbol, spec.Start, spec.End)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load fx window for %s: %w", fxSymbol, err)
+			}
+			if shock, ok := windowShock(closes, spec.WorstPoint); ok {
+				scenario.FXShocks[currency] = shock
+			}
+		}
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return scenario, nil
+}
+
+// windowShock 计算窗口内相对起点的价格变动
+func windowShock(closes []decimal.Decimal, worstPoint bool) (float64, bool) {
+	if len(closes) < 2 || !closes[0].IsPositive() {
+		return 0, false
+	}
+	start := closes[0].InexactFloat64()
+	if !worstPoint {
+		return closes[len(closes)-1].InexactFloat64()/start - 1, true
+	}
+	worst := 0.0
+	for _, c := range closes[1:] {
+		move := c.InexactFloat64()/start - 1
+		if math.Abs(move) > math.Abs(worst) {
+			worst = move
+		}
+	}
+	return worst, true
+}
+
+// sampleVolatility 日对数收益率的样本标准差
+func sampleVolatility(closes []decimal.Decimal) float64 {
+	returns := logReturns(closes)
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var ss float64
+	for _, r := range returns {
+		ss += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(ss / float64(len(returns)-1))
 }
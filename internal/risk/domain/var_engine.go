@@ -0,0 +1,629 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	algorithm "github.com/wyfcoding/pkg/algorithm/math"
+)
+
+// DefaultRiskSeed 未指定随机种子时使用的固定种子，保证风险计算结果可复现
+const DefaultRiskSeed uint64 = 20240101
+
+// VaRMethod 组合 VaR 计算方法
+type VaRMethod string
+
+const (
+	VaRMethodHistorical         VaRMethod = "HISTORICAL"          // 历史模拟法
+	VaRMethodFilteredHistorical VaRMethod = "FILTERED_HISTORICAL" // 波动率过滤历史模拟法 (FHS)
+	VaRMethodMonteCarlo         VaRMethod = "MONTE_CARLO"         // 基于 Cholesky 的相关蒙特卡洛
+)
+
+// VolatilityFilter FHS 使用的条件波动率模型
+type VolatilityFilter string
+
+const (
+	VolatilityFilterEWMA  VolatilityFilter = "EWMA"
+	VolatilityFilterGARCH VolatilityFilter = "GARCH"
+)
+
+// InstrumentType 持仓工具类型
+type InstrumentType string
+
+const (
+	InstrumentSpot         InstrumentType = "SPOT"
+	InstrumentOption       InstrumentType = "OPTION"
+	InstrumentFutureOption InstrumentType = "FUTURE_OPTION" // 期货期权，Price 为期货价格，按 Black-76 定价
+)
+
+// VaRPosition 参与全量重估的单个持仓
+type VaRPosition struct {
+	PositionID string          `json:"position_id"`
+	Symbol     string          `json:"symbol"`     // 持仓代码（期权为合约代码）
+	Underlying string          `json:"underlying"` // 风险因子（现货为自身）
+	Type       InstrumentType  `json:"type"`
+	Quantity   decimal.Decimal `json:"quantity"`   // 正为多头，负为空头
+	Multiplier decimal.Decimal `json:"multiplier"` // 合约乘数，默认 1
+	Price      decimal.Decimal `json:"price"`      // 标的当前价格
+
+	// 期权参数
+	OptionType   string    `json:"option_type"` // CALL / PUT
+	Strike       float64   `json:"strike"`
+	Expiry       time.Time `json:"expiry"`
+	ImpliedVol   float64   `json:"implied_vol"`
+	RiskFreeRate float64   `json:"risk_free_rate"`
+}
+
+func (p *VaRPosition) factor() string {
+	if p.Underlying != "" {
+		return p.Underlying
+	}
+	return p.Symbol
+}
+
+func (p *VaRPosition) isOption() bool {
+	return p.Type == InstrumentOption || p.Type == InstrumentFutureOption
+}
+
+func (p *VaRPosition) pricingInput(underlying float64, at time.Time) OptionPricingInput {
+	return OptionPricingInput{
+		OptionType:   p.OptionType,
+		Underlying:   underlying,
+		Strike:       p.Strike,
+		TimeToExpiry: p.Expiry.Sub(at).Hours() / 24 / 365,
+		Volatility:   p.ImpliedVol,
+		RiskFreeRate: p.RiskFreeRate,
+		Black76:      p.Type == InstrumentFutureOption,
+	}
+}
+
+func (p *VaRPosition) multiplier() float64 {
+	if p.Multiplier.IsPositive() {
+		return p.Multiplier.InexactFloat64()
+	}
+	return 1
+}
+
+// OptionPricingInput 期权重估输入
+type OptionPricingInput struct {
+	OptionType   string
+	Underlying   float64
+	Strike       float64
+	TimeToExpiry float64 // 年
+	Volatility   float64
+	RiskFreeRate float64
+	Black76      bool // Underlying 为期货/远期价格，按 Black-76 定价
+}
+
+// OptionPricer 期权定价模型接口（由定价服务或本地模型实现）
+type OptionPricer interface {
+	PriceOption(ctx context.Context, input OptionPricingInput) (float64, error)
+}
+
+// BatchOptionPricer 支持批量定价的期权模型，结果与输入顺序一致
+type BatchOptionPricer interface {
+	OptionPricer
+	PriceOptions(ctx context.Context, inputs []OptionPricingInput) ([]float64, error)
+}
+
+// PriceOptions 使用 pricer 批量定价；pricer 不支持批量时逐个定价
+func PriceOptions(ctx context.Context, pricer OptionPricer, inputs []OptionPricingInput) ([]float64, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	if batch, ok := pricer.(BatchOptionPricer); ok {
+		prices, err := batch.PriceOptions(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if len(prices) != len(inputs) {
+			return nil, fmt.Errorf("option pricer returned %d prices for %d inputs", len(prices), len(inputs))
+		}
+		return prices, nil
+	}
+	prices := make([]float64, len(inputs))
+	for i, in := range inputs {
+		p, err := pricer.PriceOption(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		prices[i] = p
+	}
+	return prices, nil
+}
+
+// MarketHistoryProvider 提供风险因子的历史收盘价（按时间升序）
+type MarketHistoryProvider interface {
+	GetPriceHistory(ctx context.Context, symbol string, days int) ([]decimal.Decimal, error)
+}
+
+// VaRRequest 组合 VaR/ES 计算请求
+type VaRRequest struct {
+	Positions       []VaRPosition
+	Method          VaRMethod
+	Filter          VolatilityFilter
+	ConfidenceLevel float64 // 例如 0.99
+	HorizonDays     int     // 持有期（天），按 sqrt(h) 缩放
+	LookbackDays    int     // 历史窗口长度
+	Simulations     int     // 蒙特卡洛路径数
+	Seed            uint64  // 随机种子，0 表示使用 DefaultRiskSeed
+	ValuationTime   time.Time
+}
+
+// PositionRiskContribution 单个持仓的风险贡献
+type PositionRiskContribution struct {
+	PositionID     string          `json:"position_id"`
+	Symbol         string          `json:"symbol"`
+	MarketValue    decimal.Decimal `json:"market_value"`
+	StandaloneVaR  decimal.Decimal `json:"standalone_var"`
+	ComponentVaR   decimal.Decimal `json:"component_var"`   // 欧拉分解，合计等于组合 VaR
+	IncrementalVaR decimal.Decimal `json:"incremental_var"` // 移除该持仓后组合 VaR 的减少量
+	ComponentES    decimal.Decimal `json:"component_es"`
+}
+
+// VaRReport 组合 VaR/ES 结果
+type VaRReport struct {
+	Method           VaRMethod                  `json:"method"`
+	ConfidenceLevel  float64                    `json:"confidence_level"`
+	HorizonDays      int                        `json:"horizon_days"`
+	Scenarios        int                        `json:"scenarios"`
+	Seed             uint64                     `json:"seed"`
+	PortfolioValue   decimal.Decimal            `json:"portfolio_value"`
+	VaR              decimal.Decimal            `json:"var"`
+	ES               decimal.Decimal            `json:"es"`
+	UndiversifiedVaR decimal.Decimal            `json:"undiversified_var"`
+	Diversification  decimal.Decimal            `json:"diversification"`
+	Contributions    []PositionRiskContribution `json:"contributions"`
+	CalculatedAt     time.Time                  `json:"calculated_at"`
+}
+
+// VaREngine 全量重估 VaR 引擎：在每个情景下重新定价每个持仓，而非使用 Delta 近似。
+// 基准与情景网格（情景数 x 期权数）一次性交给 pricer 批量重估，市值与情景损益出自同一模型。
+type VaREngine struct {
+	history MarketHistoryProvider
+	pricer  OptionPricer
+}
+
+// NewVaREngine 创建 VaR 引擎；pricer 为空时使用本地 Black-Scholes 模型
+func NewVaREngine(history MarketHistoryProvider, pricer OptionPricer) *VaREngine {
+	if pricer == nil {
+		pricer = NewBlackScholesPricer()
+	}
+	return &VaREngine{history: history, pricer: pricer}
+}
+
+// Calculate 计算组合 VaR/ES 以及持仓级风险贡献
+func (e *VaREngine) Calculate(ctx context.Context, req VaRRequest) (*VaRReport, error) {
+	if len(req.Positions) == 0 {
+		return nil, errors.New("no positions to evaluate")
+	}
+	if e.history == nil {
+		return nil, errors.New("market history provider not configured")
+	}
+	normalizeVaRRequest(&req)
+
+	// 1. 收集风险因子及其历史对数收益率
+	factors := make([]string, 0)
+	factorIdx := make(map[string]int)
+	for i := range req.Positions {
+		f := req.Positions[i].factor()
+		if _, ok := factorIdx[f]; !ok {
+			factorIdx[f] = len(factors)
+			factors = append(factors, f)
+		}
+	}
+	returns := make([][]float64, len(factors))
+	minLen := math.MaxInt
+	for i, f := range factors {
+		closes, err := e.history.GetPriceHistory(ctx, f, req.LookbackDays+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history for %s: %w", f, err)
+		}
+		returns[i] = logReturns(closes)
+		minLen = min(minLen, len(returns[i]))
+	}
+	if minLen < 2 {
+		return nil, errors.New("insufficient market history for VaR")
+	}
+	// 按最近日期对齐各因子的收益率序列
+	for i := range returns {
+		returns[i] = returns[i][len(returns[i])-minLen:]
+	}
+
+	// 2. 生成情景收益率矩阵 [scenario][factor]
+	var scenarios [][]float64
+	var err error
+	switch req.Method {
+	case VaRMethodHistorical:
+		scenarios = historicalScenarios(returns, minLen)
+	case VaRMethodFilteredHistorical:
+		scenarios = filteredHistoricalScenarios(returns, minLen, req.Filter)
+	case VaRMethodMonteCarlo:
+		scenarios, err = monteCarloScenarios(returns, req.Simulations, req.Seed)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported VaR method: %s", req.Method)
+	}
+	horizonScale := math.Sqrt(float64(req.HorizonDays))
+
+	// 3. 全量重估：pnl[scenario][position]
+	// 期权的基准与全部情景输入合并为一批交给 pricer，保证市值与情景损益使用同一模型
+	shockedTime := req.ValuationTime.AddDate(0, 0, req.HorizonDays)
+	var options []int
+	for j := range req.Positions {
+		if req.Positions[j].isOption() {
+			options = append(options, j)
+		}
+	}
+	inputs := make([]OptionPricingInput, 0, len(options)*(len(scenarios)+1))
+	for _, j := range options {
+		pos := &req.Positions[j]
+		inputs = append(inputs, pos.pricingInput(pos.Price.InexactFloat64(), req.ValuationTime))
+	}
+	for _, sc := range scenarios {
+		for _, j := range options {
+			pos := &req.Positions[j]
+			inputs = append(inputs, pos.pricingInput(shockedPrice(pos, sc[factorIdx[pos.factor()]], horizonScale), shockedTime))
+		}
+	}
+	prices, err := PriceOptions(ctx, e.pricer, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revalue options: %w", err)
+	}
+	optionPrice := make(map[int]int, len(options))
+	for k, j := range options {
+		optionPrice[j] = k
+	}
+	// value 返回持仓在第 s 个情景（-1 为基准）下的价值
+	value := func(j, s int, underlying float64) float64 {
+		pos := &req.Positions[j]
+		qty := pos.Quantity.InexactFloat64() * pos.multiplier()
+		k, ok := optionPrice[j]
+		if !ok {
+			return qty * underlying
+		}
+		return qty * prices[(s+1)*len(options)+k]
+	}
+
+	baseValues := make([]float64, len(req.Positions))
+	for j := range req.Positions {
+		baseValues[j] = value(j, -1, req.Positions[j].Price.InexactFloat64())
+	}
+	pnl := make([][]float64, len(scenarios))
+	for s, sc := range scenarios {
+		pnl[s] = make([]float64, len(req.Positions))
+		for j := range req.Positions {
+			pos := &req.Positions[j]
+			shocked := shockedPrice(pos, sc[factorIdx[pos.factor()]], horizonScale)
+			pnl[s][j] = value(j, s, shocked) - baseValues[j]
+		}
+	}
+
+	return buildVaRReport(req, pnl, baseValues), nil
+}
+
+// shockedPrice 按情景收益率与持有期缩放冲击标的价格
+func shockedPrice(pos *VaRPosition, r, horizonScale float64) float64 {
+	return pos.Price.InexactFloat64() * math.Exp(r*horizonScale)
+}
+
+func normalizeVaRRequest(req *VaRRequest) {
+	if req.Method == "" {
+		req.Method = VaRMethodHistorical
+	}
+	if req.Filter == "" {
+		req.Filter = VolatilityFilterEWMA
+	}
+	if req.ConfidenceLevel <= 0 || req.ConfidenceLevel >= 1 {
+		req.ConfidenceLevel = 0.99
+	}
+	if req.HorizonDays <= 0 {
+		req.HorizonDays = 1
+	}
+	if req.LookbackDays <= 0 {
+		req.LookbackDays = 500
+	}
+	if req.Simulations <= 0 {
+		req.Simulations = 10000
+	}
+	if req.Seed == 0 {
+		req.Seed = DefaultRiskSeed
+	}
+	if req.ValuationTime.IsZero() {
+		req.ValuationTime = time.Now()
+	}
+}
+
+func logReturns(closes []decimal.Decimal) []float64 {
+	out := make([]float64, 0, len(closes))
+	for i := 1; i < len(closes); i++ {
+		prev, cur := closes[i-1].InexactFloat64(), closes[i].InexactFloat64()
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		out = append(out, math.Log(cur/prev))
+	}
+	return out
+}
+
+// historicalScenarios 直接回放历史收益率
+func historicalScenarios(returns [][]float64, n int) [][]float64 {
+	scenarios := make([][]float64, n)
+	for t := range n {
+		scenarios[t] = make([]float64, len(returns))
+		for f := range returns {
+			scenarios[t][f] = returns[f][t]
+		}
+	}
+	return scenarios
+}
+
+// filteredHistoricalScenarios 先用条件波动率标准化历史残差，再按当前波动率预测值重新缩放
+func filteredHistoricalScenarios(returns [][]float64, n int, filter VolatilityFilter) [][]float64 {
+	scenarios := make([][]float64, n)
+	for t := range n {
+		scenarios[t] = make([]float64, len(returns))
+	}
+	for f, series := range returns {
+		sigmas, forecast := conditionalVolatility(series, filter)
+		for t := range n {
+			z := 0.0
+			if sigmas[t] > 0 {
+				z = series[t] / sigmas[t]
+			}
+			scenarios[t][f] = z * forecast
+		}
+	}
+	return scenarios
+}
+
+// conditionalVolatility 返回每期的条件波动率以及下一期的预测波动率
+func conditionalVolatility(series []float64, filter VolatilityFilter) ([]float64, float64) {
+	var sampleVar float64
+	for _, r := range series {
+		sampleVar += r * r
+	}
+	sampleVar /= float64(len(series))
+
+	sigmas := make([]float64, len(series))
+	variance := sampleVar
+	switch filter {
+	case VolatilityFilterGARCH:
+		// GARCH(1,1) 采用行业常用参数，omega 按长期方差做方差目标化
+		const alpha, beta = 0.08, 0.90
+		omega := sampleVar * (1 - alpha - beta)
+		for t, r := range series {
+			sigmas[t] = math.Sqrt(variance)
+			variance = omega + alpha*r*r + beta*variance
+		}
+	default:
+		// RiskMetrics EWMA，lambda = 0.94
+		const lambda = 0.94
+		for t, r := range series {
+			sigmas[t] = math.Sqrt(variance)
+			variance = lambda*variance + (1-lambda)*r*r
+		}
+	}
+	return sigmas, math.Sqrt(variance)
+}
+
+// monteCarloScenarios 以历史样本协方差做 Cholesky 分解生成相关正态情景
+func monteCarloScenarios(returns [][]float64, sims int, seed uint64) ([][]float64, error) {
+	nf := len(returns)
+	n := len(returns[0])
+	means := make([]float64, nf)
+	for f := range returns {
+		for _, r := range returns[f] {
+			means[f] += r
+		}
+		means[f] /= float64(n)
+	}
+	cov := make([][]float64, nf)
+	for i := range nf {
+		cov[i] = make([]float64, nf)
+		for j := range nf {
+			var c float64
+			for t := range n {
+				c += (returns[i][t] - means[i]) * (returns[j][t] - means[j])
+			}
+			cov[i][j] = c / float64(n-1)
+		}
+		// 对角线加微小扰动，避免共线因子导致矩阵非正定
+		cov[i][i] += 1e-12
+	}
+	covMatrix, err := algorithm.NewMatrixFromData(cov)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create covariance matrix: %w", err)
+	}
+	L, err := covMatrix.Cholesky()
+	if err != nil {
+		return nil, fmt.Errorf("cholesky decomposition failed: %w", err)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	scenarios := make([][]float64, sims)
+	z := make([]float64, nf)
+	for s := range sims {
+		for i := range nf {
+			z[i] = rng.NormFloat64()
+		}
+		x, err := L.MultiplyVector(z)
+		if err != nil {
+			return nil, err
+		}
+		scenarios[s] = x
+	}
+	return scenarios, nil
+}
+
+// tailStats 返回损益分布在给定置信度下的 VaR、ES 以及 VaR 分位附近与尾部情景的索引
+func tailStats(portfolio []float64, confidence float64) (varValue, esValue float64, varIdx, tailIdx []int) {
+	n := len(portfolio)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		switch {
+		case portfolio[a] < portfolio[b]:
+			return -1
+		case portfolio[a] > portfolio[b]:
+			return 1
+		default:
+			return a - b
+		}
+	})
+
+	k := max(int(math.Floor(float64(n)*(1-confidence))), 1)
+	varValue = -portfolio[order[k-1]]
+	tailIdx = order[:k]
+	var sum float64
+	for _, i := range tailIdx {
+		sum += portfolio[i]
+	}
+	esValue = -sum / float64(k)
+
+	// VaR 分位附近取若干情景做核平滑，降低欧拉分解的抽样噪声
+	band := max(n/100, 1)
+	lo := max(k-1-band, 0)
+	hi := min(k-1+band, n-1)
+	varIdx = order[lo : hi+1]
+	return varValue, esValue, varIdx, tailIdx
+}
+
+func buildVaRReport(req VaRRequest, pnl [][]float64, baseValues []float64) *VaRReport {
+	nPos := len(req.Positions)
+	nSc := len(pnl)
+
+	portfolio := make([]float64, nSc)
+	for s := range nSc {
+		for j := range nPos {
+			portfolio[s] += pnl[s][j]
+		}
+	}
+	varValue, esValue, varIdx, tailIdx := tailStats(portfolio, req.ConfidenceLevel)
+
+	// 欧拉分解：各持仓在 VaR 分位邻域的平均损失，按组合 VaR 归一化
+	rawComponents := make([]float64, nPos)
+	var rawSum float64
+	for j := range nPos {
+		var sum float64
+		for _, s := range varIdx {
+			sum += pnl[s][j]
+		}
+		rawComponents[j] = -sum / float64(len(varIdx))
+		rawSum += rawComponents[j]
+	}
+
+	contributions := make([]PositionRiskContribution, nPos)
+	var totalValue, undiversified float64
+	column := make([]float64, nSc)
+	without := make([]float64, nSc)
+	for j := range nPos {
+		pos := req.Positions[j]
+		totalValue += baseValues[j]
+
+		for s := range nSc {
+			column[s] = pnl[s][j]
+			without[s] = portfolio[s] - pnl[s][j]
+		}
+		standalone, _, _, _ := tailStats(column, req.ConfidenceLevel)
+		undiversified += math.Max(standalone, 0)
+
+		incremental := varValue
+		if nPos > 1 {
+			withoutVaR, _, _, _ := tailStats(without, req.ConfidenceLevel)
+			incremental = varValue - withoutVaR
+		}
+
+		component := rawComponents[j]
+		if rawSum != 0 {
+			component = rawComponents[j] / rawSum * varValue
+		}
+
+		var esSum float64
+		for _, s := range tailIdx {
+			esSum += pnl[s][j]
+		}
+
+		contributions[j] = PositionRiskContribution{
+			PositionID:     pos.PositionID,
+			Symbol:         pos.Symbol,
+			MarketValue:    decimal.NewFromFloat(baseValues[j]),
+			StandaloneVaR:  decimal.NewFromFloat(standalone),
+			ComponentVaR:   decimal.NewFromFloat(component),
+			IncrementalVaR: decimal.NewFromFloat(incremental),
+			ComponentES:    decimal.NewFromFloat(-esSum / float64(len(tailIdx))),
+		}
+	}
+
+	return &VaRReport{
+		Method:           req.Method,
+		ConfidenceLevel:  req.ConfidenceLevel,
+		HorizonDays:      req.HorizonDays,
+		Scenarios:        nSc,
+		Seed:             req.Seed,
+		PortfolioValue:   decimal.NewFromFloat(totalValue),
+		VaR:              decimal.NewFromFloat(math.Max(varValue, 0)),
+		ES:               decimal.NewFromFloat(math.Max(esValue, 0)),
+		UndiversifiedVaR: decimal.NewFromFloat(undiversified),
+		Diversification:  decimal.NewFromFloat(undiversified - math.Max(varValue, 0)),
+		Contributions:    contributions,
+		CalculatedAt:     time.Now(),
+	}
+}
+
+// BlackScholesPricer 本地 Black-Scholes 欧式期权定价模型，与定价服务的模型保持一致；
+// Black76 输入按贴现后的远期价格代入，等价于 Black-76 模型
+type BlackScholesPricer struct{}
+
+func NewBlackScholesPricer() *BlackScholesPricer {
+	return &BlackScholesPricer{}
+}
+
+// PriceOptions 批量计算欧式期权理论价格
+func (p *BlackScholesPricer) PriceOptions(ctx context.Context, inputs []OptionPricingInput) ([]float64, error) {
+	prices := make([]float64, len(inputs))
+	for i, in := range inputs {
+		v, err := p.PriceOption(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		prices[i] = v
+	}
+	return prices, nil
+}
+
+// PriceOption 计算欧式期权理论价格；到期后返回内在价值
+func (p *BlackScholesPricer) PriceOption(_ context.Context, in OptionPricingInput) (float64, error) {
+	if in.Underlying <= 0 || in.Strike <= 0 {
+		return 0, fmt.Errorf("invalid option input: underlying=%f strike=%f", in.Underlying, in.Strike)
+	}
+	isCall := in.OptionType != "PUT"
+	if in.TimeToExpiry <= 0 || in.Volatility <= 0 {
+		if isCall {
+			return math.Max(in.Underlying-in.Strike, 0), nil
+		}
+		return math.Max(in.Strike-in.Underlying, 0), nil
+	}
+	if in.Black76 {
+		in.Underlying *= math.Exp(-in.RiskFreeRate * in.TimeToExpiry)
+	}
+	sqrtT := math.Sqrt(in.TimeToExpiry)
+	d1 := (math.Log(in.Underlying/in.Strike) + (in.RiskFreeRate+0.5*in.Volatility*in.Volatility)*in.TimeToExpiry) / (in.Volatility * sqrtT)
+	d2 := d1 - in.Volatility*sqrtT
+	discount := math.Exp(-in.RiskFreeRate * in.TimeToExpiry)
+	cdf := func(x float64) float64 { return 0.5 * math.Erfc(-x/math.Sqrt2) }
+	if isCall {
+		return in.Underlying*cdf(d1) - in.Strike*discount*cdf(d2), nil
+	}
+	return in.Strike*discount*cdf(-d2) - in.Underlying*cdf(-d1), nil
+}
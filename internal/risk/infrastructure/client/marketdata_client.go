@@ -1,10 +1,14 @@
 package client
 
 import (
+	"cmp"
 	"context"
+	"slices"
+	"time"
 
 	"github.com/shopspring/decimal"
 	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
 )
 
 type GRPCMarketDataClient struct {
@@ -25,3 +29,76 @@ func (c *GRPCMarketDataClient) GetVolatility(ctx context.Context, symbol string)
 
 	return decimal.NewFromFloat(resp.Volatility), nil
 }
+
+// GetPriceHistory 读取日线收盘价，按开盘时间升序返回
+func (c *GRPCMarketDataClient) GetPriceHistory(ctx context.Context, symbol string, days int) ([]decimal.Decimal, error) {
+	klines, err := c.dailyKlines(ctx, symbol, days)
+	if err != nil {
+		return nil, err
+	}
+	closes := make([]decimal.Decimal, 0, len(klines))
+	for _, k := range klines {
+		closes = append(closes, decimal.NewFromFloat(k.Close))
+	}
+	return closes, nil
+}
+
+// GetPriceWindow 读取 [start, end] 区间内的日线收盘价，按开盘时间升序返回
+func (c *GRPCMarketDataClient) GetPriceWindow(ctx context.Context, symbol string, start, end time.Time) ([]decimal.Decimal, error) {
+	days := int(time.Since(start).Hours()/24) + 1
+	klines, err := c.dailyKlines(ctx, symbol, days)
+	if err != nil {
+		return nil, err
+	}
+	from, to := start.UnixMilli(), end.UnixMilli()
+	closes := make([]decimal.Decimal, 0, len(klines))
+	for _, k := range klines {
+		if k.OpenTime < from || k.OpenTime > to {
+			continue
+		}
+		closes = append(closes, decimal.NewFromFloat(k.Close))
+	}
+	return closes, nil
+}
+
+func (c *GRPCMarketDataClient) dailyKlines(ctx context.Context, symbol string, days int) ([]*marketdatav1.Kline, error) {
+	resp, err := c.client.GetKlines(ctx, &marketdatav1.GetKlinesRequest{
+		Symbol:   symbol,
+		Interval: "1d",
+		Limit:    int32(days),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	klines := slices.Clone(resp.Klines)
+	slices.SortFunc(klines, func(a, b *marketdatav1.Kline) int {
+		return cmp.Compare(a.OpenTime, b.OpenTime)
+	})
+	return klines, nil
+}
+
+// GetOrderBookDepth 读取订单簿深度，用于评估强平的市场冲击
+func (c *GRPCMarketDataClient) GetOrderBookDepth(ctx context.Context, symbol string, depth int) (*domain.OrderBookDepth, error) {
+	resp, err := c.client.GetOrderBook(ctx, &marketdatav1.GetOrderBookRequest{
+		Symbol: symbol,
+		Depth:  int32(depth),
+	})
+	if err != nil {
+		return nil, err
+	}
+	book := &domain.OrderBookDepth{
+		Symbol: symbol,
+		Bids:   make([]domain.BookLevel, 0, len(resp.Bids)),
+		Asks:   make([]domain.BookLevel, 0, len(resp.Asks)),
+	}
+	for _, l := range resp.Bids {
+		book.Bids = append(book.Bids, domain.BookLevel{Price: l.Price, Quantity: l.Quantity})
+	}
+	for _, l := range resp.Asks {
+		book.Asks = append(book.Asks, domain.BookLevel{Price: l.Price, Quantity: l.Quantity})
+	}
+	slices.SortFunc(book.Bids, func(a, b domain.BookLevel) int { return cmp.Compare(b.Price, a.Price) })
+	slices.SortFunc(book.Asks, func(a, b domain.BookLevel) int { return cmp.Compare(a.Price, b.Price) })
+	return book, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	orderv1 "github.com/wyfcoding/financialtrading/go-api/order/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+)
+
+// GRPCOrderCanceller 通过订单服务撤销用户挂单
+type GRPCOrderCanceller struct {
+	client orderv1.OrderServiceClient
+}
+
+func NewGRPCOrderCanceller(client orderv1.OrderServiceClient) *GRPCOrderCanceller {
+	return &GRPCOrderCanceller{client: client}
+}
+
+// CancelOpenOrders 撤销用户全部未成交/部分成交挂单，返回撤单数量
+func (c *GRPCOrderCanceller) CancelOpenOrders(ctx context.Context, userID string) (int, error) {
+	return c.CancelOrders(ctx, domain.OrderCancelFilter{UserID: userID, Reason: "liquidation"})
+}
+
+// CancelOrders 按用户和/或品种撤销全部未成交/部分成交挂单，返回撤单数量。
+// 由订单服务 MassCancelOrders 在服务端按条件分页撤单，品种范围不依赖用户维度的查询。
+func (c *GRPCOrderCanceller) CancelOrders(ctx context.Context, filter domain.OrderCancelFilter) (int, error) {
+	if filter.UserID == "" && filter.Symbol == "" {
+		return 0, errors.New("cancel filter requires user or symbol")
+	}
+	resp, err := c.client.MassCancelOrders(ctx, &orderv1.MassCancelOrdersRequest{
+		UserId: filter.UserID,
+		Symbol: filter.Symbol,
+		Reason: filter.Reason,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("mass cancel orders: %w", err)
+	}
+	cancelled := int(resp.CancelledCount)
+	if resp.FailedCount > 0 {
+		return cancelled, fmt.Errorf("failed to cancel %d orders: %v", resp.FailedCount, resp.FailedOrderIds)
+	}
+	return cancelled, nil
+}
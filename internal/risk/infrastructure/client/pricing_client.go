@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	pricingv1 "github.com/wyfcoding/financialtrading/go-api/pricing/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// pricingCallTimeout 单次定价调用超时，超时视为定价服务不可达
+const pricingCallTimeout = 2 * time.Second
+
+// pricingBatchSize 批量定价单次请求的期权数，控制消息大小
+const pricingBatchSize = 5000
+
+// GRPCOptionPricer 通过定价服务对期权进行重估。
+// grpc.Dial 不阻塞建连，服务不可达只会在调用时暴露，因此在调用层识别
+// Unavailable/DeadlineExceeded 并回退到本地模型。
+type GRPCOptionPricer struct {
+	client   pricingv1.PricingServiceClient
+	fallback domain.OptionPricer
+}
+
+func NewGRPCOptionPricer(client pricingv1.PricingServiceClient) *GRPCOptionPricer {
+	return &GRPCOptionPricer{client: client, fallback: domain.NewBlackScholesPricer()}
+}
+
+// SetFallback 替换定价服务不可达时使用的本地模型，传 nil 表示不回退
+func (p *GRPCOptionPricer) SetFallback(fallback domain.OptionPricer) {
+	p.fallback = fallback
+}
+
+func (p *GRPCOptionPricer) PriceOption(ctx context.Context, input domain.OptionPricingInput) (float64, error) {
+	callCtx, cancel := context.WithTimeout(ctx, pricingCallTimeout)
+	defer cancel()
+	resp, err := p.client.GetOptionPrice(callCtx, toOptionPriceRequest(input))
+	if err != nil {
+		if p.fallback != nil && ctx.Err() == nil && unreachable(err) {
+			return p.fallback.PriceOption(ctx, input)
+		}
+		return 0, err
+	}
+	return resp.Price, nil
+}
+
+// PriceOptions 分批调用定价服务批量重估。任一批次不可达时整批改用本地模型重估，
+// 避免同一次计算混用两种模型的价格
+func (p *GRPCOptionPricer) PriceOptions(ctx context.Context, inputs []domain.OptionPricingInput) ([]float64, error) {
+	prices := make([]float64, 0, len(inputs))
+	for start := 0; start < len(inputs); start += pricingBatchSize {
+		chunk := inputs[start:min(start+pricingBatchSize, len(inputs))]
+		req := &pricingv1.BatchGetOptionPricesRequest{Requests: make([]*pricingv1.GetOptionPriceRequest, len(chunk))}
+		for i, in := range chunk {
+			req.Requests[i] = toOptionPriceRequest(in)
+		}
+		callCtx, cancel := context.WithTimeout(ctx, pricingCallTimeout)
+		resp, err := p.client.BatchGetOptionPrices(callCtx, req)
+		cancel()
+		if err != nil {
+			if p.fallback != nil && ctx.Err() == nil && unreachable(err) {
+				return domain.PriceOptions(ctx, p.fallback, inputs)
+			}
+			return nil, err
+		}
+		if len(resp.Prices) != len(chunk) {
+			return nil, fmt.Errorf("pricing service returned %d prices for %d options", len(resp.Prices), len(chunk))
+		}
+		prices = append(prices, resp.Prices...)
+	}
+	return prices, nil
+}
+
+func toOptionPriceRequest(input domain.OptionPricingInput) *pricingv1.GetOptionPriceRequest {
+	expiry := time.Now().Add(time.Duration(input.TimeToExpiry * 365 * 24 * float64(time.Hour)))
+	underlying := input.Underlying
+	if input.Black76 && input.TimeToExpiry > 0 {
+		// 定价服务为 Black-Scholes 模型，贴现远期价格后代入与 Black-76 等价
+		underlying *= math.Exp(-input.RiskFreeRate * input.TimeToExpiry)
+	}
+	return &pricingv1.GetOptionPriceRequest{
+		Contract: &pricingv1.OptionContract{
+			Type:        input.OptionType,
+			StrikePrice: input.Strike,
+			ExpiryDate:  timestamppb.New(expiry),
+		},
+		UnderlyingPrice: underlying,
+		Volatility:      input.Volatility,
+		RiskFreeRate:    input.RiskFreeRate,
+	}
+}
+
+func unreachable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
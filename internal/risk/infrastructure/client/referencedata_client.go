@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+)
+
+// referenceLookupTimeout 缓存未命中时单次查询参考数据的超时
+const referenceLookupTimeout = time.Second
+
+type instrumentClass struct {
+	sector   string
+	currency string
+}
+
+// ReferenceDataClassifier 基于参考数据服务的标的分类：行业取 Instrument.sector，
+// 币种取归一化后的计价货币。结果本地缓存，缓存未命中时按代码单独查询。
+type ReferenceDataClassifier struct {
+	client referencedatav1.ReferenceDataServiceClient
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	cache map[string]instrumentClass
+}
+
+func NewReferenceDataClassifier(client referencedatav1.ReferenceDataServiceClient, logger *slog.Logger) *ReferenceDataClassifier {
+	return &ReferenceDataClassifier{client: client, logger: logger, cache: make(map[string]instrumentClass)}
+}
+
+// Classify 实现 domain.InstrumentClassifier；参考数据不可用时退回按代码解析计价货币
+func (c *ReferenceDataClassifier) Classify(symbol string) (string, string) {
+	c.mu.RLock()
+	class, ok := c.cache[symbol]
+	c.mu.RUnlock()
+	if ok {
+		return class.sector, class.currency
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), referenceLookupTimeout)
+	defer cancel()
+	resp, err := c.client.GetInstrument(ctx, &referencedatav1.GetInstrumentRequest{Symbol: symbol})
+	if err != nil || resp.Instrument == nil {
+		c.logger.Warn("instrument classification unavailable, falling back to symbol", "symbol", symbol, "error", err)
+		return "", domain.SymbolQuoteCurrency(symbol)
+	}
+	class = c.store(resp.Instrument)
+	return class.sector, class.currency
+}
+
+// Refresh 预加载参考数据中的全部合约
+func (c *ReferenceDataClassifier) Refresh(ctx context.Context) error {
+	resp, err := c.client.ListInstruments(ctx, &referencedatav1.ListInstrumentsRequest{})
+	if err != nil {
+		return err
+	}
+	for _, inst := range resp.Instruments {
+		c.store(inst)
+	}
+	return nil
+}
+
+// Start 按固定间隔刷新缓存，使参考数据的行业调整生效
+func (c *ReferenceDataClassifier) Start(ctx context.Context, interval time.Duration) {
+	if err := c.Refresh(ctx); err != nil {
+		c.logger.WarnContext(ctx, "failed to load instrument classification", "error", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				c.logger.WarnContext(ctx, "failed to refresh instrument classification", "error", err)
+			}
+		}
+	}
+}
+
+func (c *ReferenceDataClassifier) store(inst *referencedatav1.Instrument) instrumentClass {
+	class := instrumentClass{
+		sector:   strings.ToUpper(inst.Sector),
+		currency: domain.FXCurrency(inst.QuoteCurrency),
+	}
+	c.mu.Lock()
+	c.cache[inst.Symbol] = class
+	c.mu.Unlock()
+	return class
+}
@@ -0,0 +1,277 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PreTradeLimitsModel 订单级控制参数表映射，(scope, scope_key) 唯一
+type PreTradeLimitsModel struct {
+	gorm.Model
+	Scope                string          `gorm:"column:scope;type:varchar(16);uniqueIndex:idx_pretrade_scope_key;not null"`
+	ScopeKey             string          `gorm:"column:scope_key;type:varchar(64);uniqueIndex:idx_pretrade_scope_key;not null"`
+	MaxOrderQuantity     decimal.Decimal `gorm:"column:max_order_quantity;type:decimal(32,8);not null"`
+	MaxOrderNotional     decimal.Decimal `gorm:"column:max_order_notional;type:decimal(32,8);not null"`
+	MaxPriceDeviation    decimal.Decimal `gorm:"column:max_price_deviation;type:decimal(20,8);not null"`
+	MaxOrderToTradeRatio decimal.Decimal `gorm:"column:max_order_to_trade_ratio;type:decimal(20,8);not null"`
+	MinOrdersForRatio    int             `gorm:"column:min_orders_for_ratio;not null"`
+	MaxMessages          int             `gorm:"column:max_messages;not null"`
+	WindowSeconds        int             `gorm:"column:window_seconds;not null"`
+	UpdatedBy            string          `gorm:"column:updated_by;type:varchar(64)"`
+}
+
+func (PreTradeLimitsModel) TableName() string { return "risk_pretrade_limits" }
+
+// DeskMemberModel 用户交易台归属表映射
+type DeskMemberModel struct {
+	gorm.Model
+	UserID string `gorm:"column:user_id;type:varchar(36);uniqueIndex;not null"`
+	Desk   string `gorm:"column:desk;type:varchar(64);index;not null"`
+}
+
+func (DeskMemberModel) TableName() string { return "risk_desk_members" }
+
+// KillSwitchModel 熔断开关表映射
+type KillSwitchModel struct {
+	gorm.Model
+	SwitchID        string     `gorm:"column:switch_id;type:varchar(36);uniqueIndex;not null"`
+	Scope           string     `gorm:"column:scope;type:varchar(16);not null"`
+	Target          string     `gorm:"column:target;type:varchar(64);index;not null"`
+	Reason          string     `gorm:"column:reason;type:varchar(512)"`
+	Active          bool       `gorm:"column:active;index;not null"`
+	ActivatedBy     string     `gorm:"column:activated_by;type:varchar(64)"`
+	ActivatedAt     time.Time  `gorm:"column:activated_at;not null"`
+	ReleasedBy      string     `gorm:"column:released_by;type:varchar(64)"`
+	ReleasedAt      *time.Time `gorm:"column:released_at"`
+	CancelledOrders int        `gorm:"column:cancelled_orders;not null;default:0"`
+}
+
+func (KillSwitchModel) TableName() string { return "risk_kill_switches" }
+
+// ControlAuditModel 事前控制审计表映射，只追加
+type ControlAuditModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	AuditID   string    `gorm:"column:audit_id;type:varchar(36);uniqueIndex;not null"`
+	Kind      string    `gorm:"column:kind;type:varchar(32);index;not null"`
+	Scope     string    `gorm:"column:scope;type:varchar(16)"`
+	Target    string    `gorm:"column:target;type:varchar(64);index"`
+	UserID    string    `gorm:"column:user_id;type:varchar(36);index"`
+	Symbol    string    `gorm:"column:symbol;type:varchar(32)"`
+	Control   string    `gorm:"column:control;type:varchar(32)"`
+	Detail    string    `gorm:"column:detail;type:text"`
+	Actor     string    `gorm:"column:actor;type:varchar(64)"`
+	CreatedAt time.Time `gorm:"column:created_at;index;not null"`
+}
+
+func (ControlAuditModel) TableName() string { return "risk_control_audits" }
+
+type preTradeControlRepository struct {
+	db *gorm.DB
+}
+
+// NewPreTradeControlRepository 创建事前控制仓储
+func NewPreTradeControlRepository(db *gorm.DB) domain.PreTradeControlRepository {
+	return &preTradeControlRepository{db: db}
+}
+
+func (r *preTradeControlRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *preTradeControlRepository) SaveLimits(ctx context.Context, l *domain.PreTradeLimits) error {
+	model := &PreTradeLimitsModel{
+		Scope:                string(l.Scope),
+		ScopeKey:             l.Key,
+		MaxOrderQuantity:     l.MaxOrderQuantity,
+		MaxOrderNotional:     l.MaxOrderNotional,
+		MaxPriceDeviation:    l.MaxPriceDeviation,
+		MaxOrderToTradeRatio: l.MaxOrderToTradeRatio,
+		MinOrdersForRatio:    l.MinOrdersForRatio,
+		MaxMessages:          l.MaxMessages,
+		WindowSeconds:        l.WindowSeconds,
+		UpdatedBy:            l.UpdatedBy,
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "scope_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"max_order_quantity", "max_order_notional", "max_price_deviation", "max_order_to_trade_ratio",
+			"min_orders_for_ratio", "max_messages", "window_seconds", "updated_by", "updated_at", "deleted_at",
+		}),
+	}).Create(model).Error
+}
+
+func (r *preTradeControlRepository) DeleteLimits(ctx context.Context, scope domain.ControlScope, key string) error {
+	return r.getDB(ctx).WithContext(ctx).Unscoped().
+		Where("scope = ? AND scope_key = ?", string(scope), key).
+		Delete(&PreTradeLimitsModel{}).Error
+}
+
+func (r *preTradeControlRepository) ListLimits(ctx context.Context) ([]*domain.PreTradeLimits, error) {
+	var models []*PreTradeLimitsModel
+	if err := r.getDB(ctx).WithContext(ctx).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.PreTradeLimits, 0, len(models))
+	for _, m := range models {
+		out = append(out, &domain.PreTradeLimits{
+			Scope:                domain.ControlScope(m.Scope),
+			Key:                  m.ScopeKey,
+			MaxOrderQuantity:     m.MaxOrderQuantity,
+			MaxOrderNotional:     m.MaxOrderNotional,
+			MaxPriceDeviation:    m.MaxPriceDeviation,
+			MaxOrderToTradeRatio: m.MaxOrderToTradeRatio,
+			MinOrdersForRatio:    m.MinOrdersForRatio,
+			MaxMessages:          m.MaxMessages,
+			WindowSeconds:        m.WindowSeconds,
+			UpdatedBy:            m.UpdatedBy,
+			UpdatedAt:            m.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+// AssignDesk desk 为空时移除归属
+func (r *preTradeControlRepository) AssignDesk(ctx context.Context, userID, desk string) error {
+	db := r.getDB(ctx).WithContext(ctx)
+	if desk == "" {
+		return db.Unscoped().Where("user_id = ?", userID).Delete(&DeskMemberModel{}).Error
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"desk", "updated_at", "deleted_at"}),
+	}).Create(&DeskMemberModel{UserID: userID, Desk: desk}).Error
+}
+
+func (r *preTradeControlRepository) ListDesks(ctx context.Context) (map[string]string, error) {
+	var models []*DeskMemberModel
+	if err := r.getDB(ctx).WithContext(ctx).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	desks := make(map[string]string, len(models))
+	for _, m := range models {
+		desks[m.UserID] = m.Desk
+	}
+	return desks, nil
+}
+
+func (r *preTradeControlRepository) SaveKillSwitch(ctx context.Context, ks *domain.KillSwitch) error {
+	model := &KillSwitchModel{
+		SwitchID:        ks.ID,
+		Scope:           string(ks.Scope),
+		Target:          ks.Target,
+		Reason:          ks.Reason,
+		Active:          ks.Active,
+		ActivatedBy:     ks.ActivatedBy,
+		ActivatedAt:     ks.ActivatedAt,
+		ReleasedBy:      ks.ReleasedBy,
+		ReleasedAt:      ks.ReleasedAt,
+		CancelledOrders: ks.CancelledOrders,
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "switch_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"active", "released_by", "released_at", "cancelled_orders", "updated_at"}),
+	}).Create(model).Error
+}
+
+func (r *preTradeControlRepository) GetKillSwitch(ctx context.Context, id string) (*domain.KillSwitch, error) {
+	var model KillSwitchModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("switch_id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toKillSwitch(&model), nil
+}
+
+func (r *preTradeControlRepository) ListKillSwitches(ctx context.Context, activeOnly bool) ([]*domain.KillSwitch, error) {
+	query := r.getDB(ctx).WithContext(ctx)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	} else {
+		query = query.Limit(500)
+	}
+	var models []*KillSwitchModel
+	if err := query.Order("activated_at DESC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.KillSwitch, 0, len(models))
+	for _, m := range models {
+		out = append(out, toKillSwitch(m))
+	}
+	return out, nil
+}
+
+func (r *preTradeControlRepository) AppendAudits(ctx context.Context, audits []*domain.ControlAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+	models := make([]*ControlAuditModel, 0, len(audits))
+	for _, a := range audits {
+		models = append(models, &ControlAuditModel{
+			AuditID:   a.ID,
+			Kind:      string(a.Kind),
+			Scope:     a.Scope,
+			Target:    a.Target,
+			UserID:    a.UserID,
+			Symbol:    a.Symbol,
+			Control:   string(a.Control),
+			Detail:    a.Detail,
+			Actor:     a.Actor,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(models, 200).Error
+}
+
+func (r *preTradeControlRepository) ListAudits(ctx context.Context, target string, limit int) ([]*domain.ControlAudit, error) {
+	query := r.getDB(ctx).WithContext(ctx)
+	if target != "" {
+		query = query.Where("target = ? OR user_id = ?", target, target)
+	}
+	var models []*ControlAuditModel
+	if err := query.Order("created_at DESC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.ControlAudit, 0, len(models))
+	for _, m := range models {
+		out = append(out, &domain.ControlAudit{
+			ID:        m.AuditID,
+			Kind:      domain.ControlAuditKind(m.Kind),
+			Scope:     m.Scope,
+			Target:    m.Target,
+			UserID:    m.UserID,
+			Symbol:    m.Symbol,
+			Control:   domain.PreTradeControl(m.Control),
+			Detail:    m.Detail,
+			Actor:     m.Actor,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func toKillSwitch(m *KillSwitchModel) *domain.KillSwitch {
+	return &domain.KillSwitch{
+		ID:              m.SwitchID,
+		Scope:           domain.KillSwitchScope(m.Scope),
+		Target:          m.Target,
+		Reason:          m.Reason,
+		Active:          m.Active,
+		ActivatedBy:     m.ActivatedBy,
+		ActivatedAt:     m.ActivatedAt,
+		ReleasedBy:      m.ReleasedBy,
+		ReleasedAt:      m.ReleasedAt,
+		CancelledOrders: m.CancelledOrders,
+	}
+}
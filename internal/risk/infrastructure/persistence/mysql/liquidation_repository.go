@@ -0,0 +1,241 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LiquidationCaseModel 强平案件表映射
+type LiquidationCaseModel struct {
+	gorm.Model
+	CaseID             string          `gorm:"column:case_id;type:varchar(36);uniqueIndex;not null"`
+	UserID             string          `gorm:"column:user_id;type:varchar(36);index;not null"`
+	AccountID          string          `gorm:"column:account_id;type:varchar(36);index;not null"`
+	Status             string          `gorm:"column:status;type:varchar(20);index;not null"`
+	Stage              string          `gorm:"column:stage;type:varchar(32)"`
+	TriggerMarginLevel decimal.Decimal `gorm:"column:trigger_margin_level;type:decimal(20,8);not null"`
+	Equity             decimal.Decimal `gorm:"column:equity;type:decimal(32,8);not null"`
+	UsedMargin         decimal.Decimal `gorm:"column:used_margin;type:decimal(32,8);not null"`
+	Deficit            decimal.Decimal `gorm:"column:deficit;type:decimal(32,8);not null"`
+	CompletedAt        *time.Time      `gorm:"column:completed_at"`
+}
+
+func (LiquidationCaseModel) TableName() string { return "risk_liquidation_cases" }
+
+// LiquidationStepModel 强平审计步骤表映射，(case_id, seq) 唯一，只追加
+type LiquidationStepModel struct {
+	ID               uint            `gorm:"primaryKey"`
+	CaseID           string          `gorm:"column:case_id;type:varchar(36);uniqueIndex:idx_liq_case_seq;not null"`
+	Seq              int             `gorm:"column:seq;uniqueIndex:idx_liq_case_seq;not null"`
+	Stage            string          `gorm:"column:stage;type:varchar(32);not null"`
+	Action           string          `gorm:"column:action;type:varchar(64);not null"`
+	Symbol           string          `gorm:"column:symbol;type:varchar(32)"`
+	Side             string          `gorm:"column:side;type:varchar(10)"`
+	Quantity         decimal.Decimal `gorm:"column:quantity;type:decimal(32,8)"`
+	Price            decimal.Decimal `gorm:"column:price;type:decimal(32,8)"`
+	Amount           decimal.Decimal `gorm:"column:amount;type:decimal(32,8)"`
+	Counterparty     string          `gorm:"column:counterparty;type:varchar(36)"`
+	MarginLevelAfter decimal.Decimal `gorm:"column:margin_level_after;type:decimal(20,8)"`
+	Detail           string          `gorm:"column:detail;type:text"`
+	CreatedAt        time.Time       `gorm:"column:created_at;not null"`
+}
+
+func (LiquidationStepModel) TableName() string { return "risk_liquidation_steps" }
+
+// InsuranceFundModel 风险准备金表映射
+type InsuranceFundModel struct {
+	gorm.Model
+	Currency string          `gorm:"column:currency;type:varchar(10);uniqueIndex;not null"`
+	Balance  decimal.Decimal `gorm:"column:balance;type:decimal(32,8);not null"`
+}
+
+func (InsuranceFundModel) TableName() string { return "risk_insurance_funds" }
+
+type liquidationRepository struct {
+	db *gorm.DB
+}
+
+// NewLiquidationRepository 创建强平案件仓储
+func NewLiquidationRepository(db *gorm.DB) domain.LiquidationRepository {
+	return &liquidationRepository{db: db}
+}
+
+func (r *liquidationRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *liquidationRepository) SaveCase(ctx context.Context, c *domain.LiquidationCase) error {
+	if c == nil {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		model := &LiquidationCaseModel{
+			CaseID:             c.ID,
+			UserID:             c.UserID,
+			AccountID:          c.AccountID,
+			Status:             string(c.Status),
+			Stage:              string(c.Stage),
+			TriggerMarginLevel: c.TriggerMarginLevel,
+			Equity:             c.Equity,
+			UsedMargin:         c.UsedMargin,
+			Deficit:            c.Deficit,
+			CompletedAt:        c.CompletedAt,
+		}
+		var existing LiquidationCaseModel
+		err := tx.Where("case_id = ?", c.ID).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(model).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			model.ID = existing.ID
+			model.CreatedAt = existing.CreatedAt
+			if err := tx.Save(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(c.Steps) == 0 {
+			return nil
+		}
+		steps := make([]*LiquidationStepModel, 0, len(c.Steps))
+		for _, s := range c.Steps {
+			steps = append(steps, &LiquidationStepModel{
+				CaseID:           c.ID,
+				Seq:              s.Seq,
+				Stage:            string(s.Stage),
+				Action:           s.Action,
+				Symbol:           s.Symbol,
+				Side:             s.Side,
+				Quantity:         s.Quantity,
+				Price:            s.Price,
+				Amount:           s.Amount,
+				Counterparty:     s.Counterparty,
+				MarginLevelAfter: s.MarginLevelAfter,
+				Detail:           s.Detail,
+				CreatedAt:        s.CreatedAt,
+			})
+		}
+		// 审计步骤只追加：已存在的 (case_id, seq) 不做修改
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&steps).Error
+	})
+}
+
+func (r *liquidationRepository) GetCase(ctx context.Context, id string) (*domain.LiquidationCase, error) {
+	var model LiquidationCaseModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("case_id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.loadCase(ctx, &model)
+}
+
+func (r *liquidationRepository) GetActiveCase(ctx context.Context, accountID string) (*domain.LiquidationCase, error) {
+	var model LiquidationCaseModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Where("account_id = ? AND status = ?", accountID, string(domain.LiquidationStatusInProgress)).
+		Order("created_at DESC").
+		First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.loadCase(ctx, &model)
+}
+
+func (r *liquidationRepository) ListCases(ctx context.Context, userID string, limit int) ([]*domain.LiquidationCase, error) {
+	var models []*LiquidationCaseModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	cases := make([]*domain.LiquidationCase, 0, len(models))
+	for _, m := range models {
+		c, err := r.loadCase(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+func (r *liquidationRepository) loadCase(ctx context.Context, m *LiquidationCaseModel) (*domain.LiquidationCase, error) {
+	var steps []*LiquidationStepModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("case_id = ?", m.CaseID).Order("seq ASC").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+	c := &domain.LiquidationCase{
+		ID:                 m.CaseID,
+		UserID:             m.UserID,
+		AccountID:          m.AccountID,
+		Status:             domain.LiquidationStatus(m.Status),
+		Stage:              domain.LiquidationStage(m.Stage),
+		TriggerMarginLevel: m.TriggerMarginLevel,
+		Equity:             m.Equity,
+		UsedMargin:         m.UsedMargin,
+		Deficit:            m.Deficit,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
+		CompletedAt:        m.CompletedAt,
+		Steps:              make([]domain.LiquidationStep, 0, len(steps)),
+	}
+	for _, s := range steps {
+		c.Steps = append(c.Steps, domain.LiquidationStep{
+			Seq:              s.Seq,
+			Stage:            domain.LiquidationStage(s.Stage),
+			Action:           s.Action,
+			Symbol:           s.Symbol,
+			Side:             s.Side,
+			Quantity:         s.Quantity,
+			Price:            s.Price,
+			Amount:           s.Amount,
+			Counterparty:     s.Counterparty,
+			MarginLevelAfter: s.MarginLevelAfter,
+			Detail:           s.Detail,
+			CreatedAt:        s.CreatedAt,
+		})
+	}
+	return c, nil
+}
+
+func (r *liquidationRepository) GetInsuranceFund(ctx context.Context, currency string) (*domain.InsuranceFund, error) {
+	var model InsuranceFundModel
+	query := r.getDB(ctx).WithContext(ctx)
+	if contextx.GetTx(ctx) != nil {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if err := query.Where("currency = ?", currency).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &domain.InsuranceFund{Currency: currency, Balance: decimal.Zero}, nil
+		}
+		return nil, err
+	}
+	return &domain.InsuranceFund{Currency: model.Currency, Balance: model.Balance, UpdatedAt: model.UpdatedAt}, nil
+}
+
+func (r *liquidationRepository) SaveInsuranceFund(ctx context.Context, fund *domain.InsuranceFund) error {
+	if fund == nil {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance", "updated_at"}),
+	}).Create(&InsuranceFundModel{Currency: fund.Currency, Balance: fund.Balance}).Error
+}
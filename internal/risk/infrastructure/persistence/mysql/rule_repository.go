@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"gorm.io/gorm"
+)
+
+// RiskRuleModel 事前风控规则表映射
+type RiskRuleModel struct {
+	gorm.Model
+	RuleID          string          `gorm:"column:rule_id;type:varchar(64);uniqueIndex;not null"`
+	Name            string          `gorm:"column:name;type:varchar(128);not null"`
+	Category        string          `gorm:"column:category;type:varchar(20);index;not null"`
+	Description     string          `gorm:"column:description;type:varchar(512)"`
+	Enabled         bool            `gorm:"column:enabled;index;not null"`
+	Priority        int             `gorm:"column:priority;not null;default:0"`
+	ConditionField  string          `gorm:"column:condition_field;type:varchar(64)"`
+	ConditionOp     string          `gorm:"column:condition_op;type:varchar(8)"`
+	ConditionValue  string          `gorm:"column:condition_value;type:varchar(128)"`
+	Expression      string          `gorm:"column:expression;type:text"`
+	Threshold       decimal.Decimal `gorm:"column:threshold;type:decimal(32,8);not null"`
+	Action          string          `gorm:"column:action;type:varchar(20);not null"`
+	CoolDownSeconds int             `gorm:"column:cool_down_seconds;not null;default:0"`
+	NotifyChannels  string          `gorm:"column:notify_channels;type:varchar(255)"` // JSON 数组
+}
+
+func (RiskRuleModel) TableName() string { return "risk_rules" }
+
+type riskRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewRiskRuleRepository 创建事前风控规则仓储
+func NewRiskRuleRepository(db *gorm.DB) domain.RiskRuleRepository {
+	return &riskRuleRepository{db: db}
+}
+
+func (r *riskRuleRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *riskRuleRepository) Create(ctx context.Context, rule *domain.RiskRule) error {
+	model := toRiskRuleModel(rule)
+	if err := r.getDB(ctx).WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+	rule.CreatedAt, rule.UpdatedAt = model.CreatedAt, model.UpdatedAt
+	return nil
+}
+
+func (r *riskRuleRepository) Update(ctx context.Context, rule *domain.RiskRule) error {
+	var existing RiskRuleModel
+	db := r.getDB(ctx).WithContext(ctx)
+	if err := db.Where("rule_id = ?", rule.ID).First(&existing).Error; err != nil {
+		return err
+	}
+	model := toRiskRuleModel(rule)
+	model.ID = existing.ID
+	model.CreatedAt = existing.CreatedAt
+	if err := db.Save(model).Error; err != nil {
+		return err
+	}
+	rule.CreatedAt, rule.UpdatedAt = model.CreatedAt, model.UpdatedAt
+	return nil
+}
+
+// Delete 物理删除，便于以相同 rule_id 重新创建
+func (r *riskRuleRepository) Delete(ctx context.Context, ruleID string) error {
+	return r.getDB(ctx).WithContext(ctx).Unscoped().Where("rule_id = ?", ruleID).Delete(&RiskRuleModel{}).Error
+}
+
+func (r *riskRuleRepository) FindByID(ctx context.Context, ruleID string) (*domain.RiskRule, error) {
+	var model RiskRuleModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("rule_id = ?", ruleID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toRiskRule(&model), nil
+}
+
+func (r *riskRuleRepository) FindByCategory(ctx context.Context, category domain.RiskRuleCategory) ([]*domain.RiskRule, error) {
+	return r.find(ctx, r.getDB(ctx).WithContext(ctx).Where("category = ?", string(category)))
+}
+
+func (r *riskRuleRepository) List(ctx context.Context) ([]*domain.RiskRule, error) {
+	return r.find(ctx, r.getDB(ctx).WithContext(ctx))
+}
+
+func (r *riskRuleRepository) ListEnabled(ctx context.Context) ([]*domain.RiskRule, error) {
+	return r.find(ctx, r.getDB(ctx).WithContext(ctx).Where("enabled = ?", true))
+}
+
+func (r *riskRuleRepository) Version(ctx context.Context) (int64, time.Time, error) {
+	var row struct {
+		Count     int64
+		UpdatedAt *time.Time
+	}
+	err := r.getDB(ctx).WithContext(ctx).Model(&RiskRuleModel{}).
+		Select("COUNT(*) AS count, MAX(updated_at) AS updated_at").
+		Scan(&row).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if row.UpdatedAt == nil {
+		return row.Count, time.Time{}, nil
+	}
+	return row.Count, *row.UpdatedAt, nil
+}
+
+func (r *riskRuleRepository) find(_ context.Context, query *gorm.DB) ([]*domain.RiskRule, error) {
+	var models []*RiskRuleModel
+	if err := query.Order("priority DESC, rule_id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	rules := make([]*domain.RiskRule, 0, len(models))
+	for _, m := range models {
+		rules = append(rules, toRiskRule(m))
+	}
+	return rules, nil
+}
+
+func toRiskRuleModel(rule *domain.RiskRule) *RiskRuleModel {
+	channels, _ := json.Marshal(rule.NotifyChannels)
+	return &RiskRuleModel{
+		RuleID:          rule.ID,
+		Name:            rule.Name,
+		Category:        string(rule.Category),
+		Description:     rule.Description,
+		Enabled:         rule.Enabled,
+		Priority:        rule.Priority,
+		ConditionField:  rule.Condition.Field,
+		ConditionOp:     rule.Condition.Operator,
+		ConditionValue:  rule.Condition.Value,
+		Expression:      rule.Expression,
+		Threshold:       rule.Threshold,
+		Action:          string(rule.Action),
+		CoolDownSeconds: rule.CoolDownSeconds,
+		NotifyChannels:  string(channels),
+	}
+}
+
+func toRiskRule(m *RiskRuleModel) *domain.RiskRule {
+	var channels []string
+	if m.NotifyChannels != "" {
+		_ = json.Unmarshal([]byte(m.NotifyChannels), &channels)
+	}
+	return &domain.RiskRule{
+		ID:          m.RuleID,
+		Name:        m.Name,
+		Category:    domain.RiskRuleCategory(m.Category),
+		Description: m.Description,
+		Enabled:     m.Enabled,
+		Priority:    m.Priority,
+		Condition: domain.RiskCondition{
+			Field:    m.ConditionField,
+			Operator: m.ConditionOp,
+			Value:    m.ConditionValue,
+		},
+		Expression:      m.Expression,
+		Threshold:       m.Threshold,
+		Action:          domain.RiskAction(m.Action),
+		CoolDownSeconds: m.CoolDownSeconds,
+		NotifyChannels:  channels,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
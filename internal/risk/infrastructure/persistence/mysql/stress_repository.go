@@ -0,0 +1,268 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/contextx"
+	"github.com/wyfcoding/pkg/idgen"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StressScenarioModel 压力场景表映射，(name, version) 唯一
+type StressScenarioModel struct {
+	gorm.Model
+	ScenarioID   string     `gorm:"column:scenario_id;type:varchar(36);uniqueIndex;not null"`
+	Name         string     `gorm:"column:name;type:varchar(64);uniqueIndex:idx_stress_name_version;not null"`
+	Version      int        `gorm:"column:version;uniqueIndex:idx_stress_name_version;not null"`
+	Description  string     `gorm:"column:description;type:text"`
+	Source       string     `gorm:"column:source;type:varchar(20);not null"`
+	SymbolShocks string     `gorm:"column:symbol_shocks;type:json"`
+	SectorShocks string     `gorm:"column:sector_shocks;type:json"`
+	FXShocks     string     `gorm:"column:fx_shocks;type:json"`
+	DefaultShock float64    `gorm:"column:default_shock;not null"`
+	VolShock     float64    `gorm:"column:vol_shock;not null"`
+	RateShock    float64    `gorm:"column:rate_shock;not null"`
+	WindowStart  *time.Time `gorm:"column:window_start"`
+	WindowEnd    *time.Time `gorm:"column:window_end"`
+	CreatedBy    string     `gorm:"column:created_by;type:varchar(64)"`
+}
+
+func (StressScenarioModel) TableName() string { return "risk_stress_scenarios" }
+
+// StressTestRunModel 账户压力测试记录表映射
+type StressTestRunModel struct {
+	gorm.Model
+	RunID           string          `gorm:"column:run_id;type:varchar(36);uniqueIndex;not null"`
+	UserID          string          `gorm:"column:user_id;type:varchar(36);index;not null"`
+	AccountID       string          `gorm:"column:account_id;type:varchar(36)"`
+	ScenarioName    string          `gorm:"column:scenario_name;type:varchar(64);not null"`
+	ScenarioVersion int             `gorm:"column:scenario_version;not null"`
+	PnLImpact       decimal.Decimal `gorm:"column:pnl_impact;type:decimal(32,8);not null"`
+	PercentageDrop  decimal.Decimal `gorm:"column:percentage_drop;type:decimal(20,8);not null"`
+	StressedEquity  decimal.Decimal `gorm:"column:stressed_equity;type:decimal(32,8);not null"`
+	RequiredMargin  decimal.Decimal `gorm:"column:required_margin;type:decimal(32,8);not null"`
+	Survived        bool            `gorm:"column:survived;not null"`
+	RunAt           time.Time       `gorm:"column:run_at;index;not null"`
+}
+
+func (StressTestRunModel) TableName() string { return "risk_stress_runs" }
+
+type stressScenarioRepository struct {
+	db *gorm.DB
+}
+
+// NewStressScenarioRepository 创建压力场景库仓储
+func NewStressScenarioRepository(db *gorm.DB) domain.StressScenarioRepository {
+	return &stressScenarioRepository{db: db}
+}
+
+func (r *stressScenarioRepository) getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *stressScenarioRepository) SaveScenario(ctx context.Context, scenario *domain.StressScenario) error {
+	if scenario == nil {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest StressScenarioModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ?", scenario.Name).
+			Order("version DESC").
+			First(&latest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		scenario.Version = latest.Version + 1
+		scenario.ID = fmt.Sprintf("SS-%d", idgen.GenID())
+		model, err := toStressScenarioModel(scenario)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		scenario.CreatedAt = model.CreatedAt
+		return nil
+	})
+}
+
+func (r *stressScenarioRepository) GetScenario(ctx context.Context, name string, version int) (*domain.StressScenario, error) {
+	var model StressScenarioModel
+	query := r.getDB(ctx).WithContext(ctx).Where("name = ?", name)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Order("version DESC")
+	}
+	if err := query.First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toStressScenario(&model)
+}
+
+func (r *stressScenarioRepository) ListScenarios(ctx context.Context) ([]*domain.StressScenario, error) {
+	latest := r.getDB(ctx).Model(&StressScenarioModel{}).Select("name, MAX(version) AS version").Group("name")
+	var models []*StressScenarioModel
+	err := r.getDB(ctx).WithContext(ctx).
+		Joins("JOIN (?) AS latest ON latest.name = risk_stress_scenarios.name AND latest.version = risk_stress_scenarios.version", latest).
+		Order("risk_stress_scenarios.name ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	return toStressScenarios(models)
+}
+
+func (r *stressScenarioRepository) ListScenarioVersions(ctx context.Context, name string) ([]*domain.StressScenario, error) {
+	var models []*StressScenarioModel
+	if err := r.getDB(ctx).WithContext(ctx).Where("name = ?", name).Order("version DESC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return toStressScenarios(models)
+}
+
+func (r *stressScenarioRepository) SaveStressRun(ctx context.Context, run *domain.StressTestRun) error {
+	if run == nil {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Create(&StressTestRunModel{
+		RunID:           run.ID,
+		UserID:          run.UserID,
+		AccountID:       run.AccountID,
+		ScenarioName:    run.ScenarioName,
+		ScenarioVersion: run.ScenarioVersion,
+		PnLImpact:       run.PnLImpact,
+		PercentageDrop:  run.PercentageDrop,
+		StressedEquity:  run.StressedEquity,
+		RequiredMargin:  run.RequiredMargin,
+		Survived:        run.Survived,
+		RunAt:           run.RunAt,
+	}).Error
+}
+
+func (r *stressScenarioRepository) ListStressRuns(ctx context.Context, userID string, limit int) ([]*domain.StressTestRun, error) {
+	var models []*StressTestRunModel
+	err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Order("run_at DESC").Limit(limit).Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*domain.StressTestRun, 0, len(models))
+	for _, m := range models {
+		runs = append(runs, &domain.StressTestRun{
+			ID:              m.RunID,
+			UserID:          m.UserID,
+			AccountID:       m.AccountID,
+			ScenarioName:    m.ScenarioName,
+			ScenarioVersion: m.ScenarioVersion,
+			PnLImpact:       m.PnLImpact,
+			PercentageDrop:  m.PercentageDrop,
+			StressedEquity:  m.StressedEquity,
+			RequiredMargin:  m.RequiredMargin,
+			Survived:        m.Survived,
+			RunAt:           m.RunAt,
+		})
+	}
+	return runs, nil
+}
+
+// --- mapping helpers ---
+
+func toStressScenarioModel(s *domain.StressScenario) (*StressScenarioModel, error) {
+	symbolShocks, err := json.Marshal(s.SymbolShocks)
+	if err != nil {
+		return nil, err
+	}
+	sectorShocks, err := json.Marshal(s.SectorShocks)
+	if err != nil {
+		return nil, err
+	}
+	fxShocks, err := json.Marshal(s.FXShocks)
+	if err != nil {
+		return nil, err
+	}
+	model := &StressScenarioModel{
+		ScenarioID:   s.ID,
+		Name:         s.Name,
+		Version:      s.Version,
+		Description:  s.Description,
+		Source:       string(s.Source),
+		SymbolShocks: string(symbolShocks),
+		SectorShocks: string(sectorShocks),
+		FXShocks:     string(fxShocks),
+		DefaultShock: s.DefaultShock,
+		VolShock:     s.VolShock,
+		RateShock:    s.RateShock,
+		CreatedBy:    s.CreatedBy,
+	}
+	if !s.WindowStart.IsZero() {
+		model.WindowStart = &s.WindowStart
+	}
+	if !s.WindowEnd.IsZero() {
+		model.WindowEnd = &s.WindowEnd
+	}
+	return model, nil
+}
+
+func toStressScenario(m *StressScenarioModel) (*domain.StressScenario, error) {
+	s := &domain.StressScenario{
+		ID:           m.ScenarioID,
+		Name:         m.Name,
+		Version:      m.Version,
+		Description:  m.Description,
+		Source:       domain.ScenarioSource(m.Source),
+		DefaultShock: m.DefaultShock,
+		VolShock:     m.VolShock,
+		RateShock:    m.RateShock,
+		CreatedBy:    m.CreatedBy,
+		CreatedAt:    m.CreatedAt,
+	}
+	for _, f := range []struct {
+		raw string
+		dst *map[string]float64
+	}{
+		{m.SymbolShocks, &s.SymbolShocks},
+		{m.SectorShocks, &s.SectorShocks},
+		{m.FXShocks, &s.FXShocks},
+	} {
+		if f.raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(f.raw), f.dst); err != nil {
+			return nil, fmt.Errorf("invalid shocks for scenario %s v%d: %w", m.Name, m.Version, err)
+		}
+	}
+	if m.WindowStart != nil {
+		s.WindowStart = *m.WindowStart
+	}
+	if m.WindowEnd != nil {
+		s.WindowEnd = *m.WindowEnd
+	}
+	return s, nil
+}
+
+func toStressScenarios(models []*StressScenarioModel) ([]*domain.StressScenario, error) {
+	out := make([]*domain.StressScenario, 0, len(models))
+	for _, m := range models {
+		s, err := toStressScenario(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
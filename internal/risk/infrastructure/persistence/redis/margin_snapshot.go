@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+)
+
+type marginSnapshotRepository struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewMarginSnapshotRepository 创建保证金状态快照仓储，快照整体覆盖写入且不过期
+func NewMarginSnapshotRepository(client redis.UniversalClient) domain.MarginSnapshotRepository {
+	return &marginSnapshotRepository{
+		client: client,
+		key:    "risk:margin:snapshot",
+	}
+}
+
+func (r *marginSnapshotRepository) SaveMarginSnapshot(ctx context.Context, snapshot *domain.MarginSnapshot) error {
+	if snapshot == nil {
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key, data, 0).Err()
+}
+
+func (r *marginSnapshotRepository) LoadMarginSnapshot(ctx context.Context) (*domain.MarginSnapshot, error) {
+	data, err := r.client.Get(ctx, r.key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot domain.MarginSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
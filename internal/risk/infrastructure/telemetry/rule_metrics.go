@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
+	"github.com/wyfcoding/pkg/metrics"
+)
+
+// RuleMetrics 以 Prometheus 指标上报事前风控规则的求值耗时与结果
+type RuleMetrics struct {
+	latency     *prometheus.HistogramVec
+	evaluations *prometheus.CounterVec
+}
+
+// NewRuleMetrics 注册规则求值指标
+func NewRuleMetrics(m *metrics.Metrics) domain.RuleEvaluationObserver {
+	return &RuleMetrics{
+		latency: m.NewHistogramVec(&prometheus.HistogramOpts{
+			Name: "risk_rule_evaluation_seconds",
+			Help: "Pre-trade risk rule evaluation latency",
+			// 规则求值在微秒级，桶从 1µs 起
+			Buckets: prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}, []string{"rule_id", "category"}),
+		evaluations: m.NewCounterVec(&prometheus.CounterOpts{
+			Name: "risk_rule_evaluations_total",
+			Help: "Pre-trade risk rule evaluations by outcome",
+		}, []string{"rule_id", "triggered", "error"}),
+	}
+}
+
+func (r *RuleMetrics) ObserveRuleEvaluation(ruleID, category string, elapsed time.Duration, triggered bool, err error) {
+	r.latency.WithLabelValues(ruleID, category).Observe(elapsed.Seconds())
+	r.evaluations.WithLabelValues(ruleID, strconv.FormatBool(triggered), strconv.FormatBool(err != nil)).Inc()
+}
@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/risk/application"
+)
+
+// 保证金监控订阅的上游主题
+const (
+	matchingTradeExecutedTopic = "matching.trade.executed"
+	marketQuoteUpdatedTopic    = "marketdata.quote.updated"
+	positionCreatedTopic       = "PositionCreated"
+	positionUpdatedTopic       = "PositionUpdated"
+	positionClosedTopic        = "PositionClosed"
+	accountCreatedTopic        = "account.created"
+	accountDepositedTopic      = "account.deposited"
+	accountWithdrawnTopic      = "account.withdrawn"
+	accountFrozenTopic         = "account.frozen"
+	accountUnfrozenTopic       = "account.unfrozen"
+	accountDeductedTopic       = "account.deducted"
+	accountBorrowedTopic       = "account.borrowed"
+	accountRepaidTopic         = "account.repaid"
+	accountInterestTopic       = "account.interest_settled"
+)
+
+// MarginTopics 保证金监控需要订阅的全部主题
+var MarginTopics = []string{
+	matchingTradeExecutedTopic,
+	marketQuoteUpdatedTopic,
+	positionCreatedTopic,
+	positionUpdatedTopic,
+	positionClosedTopic,
+	accountCreatedTopic,
+	accountDepositedTopic,
+	accountWithdrawnTopic,
+	accountFrozenTopic,
+	accountUnfrozenTopic,
+	accountDeductedTopic,
+	accountBorrowedTopic,
+	accountRepaidTopic,
+	accountInterestTopic,
+}
+
+// MarginHandler 消费成交、持仓、行情与资金事件，增量更新保证金状态
+type MarginHandler struct {
+	monitor  *application.MarginMonitor
+	controls *application.PreTradeControlService
+	logger   *slog.Logger
+}
+
+func NewMarginHandler(monitor *application.MarginMonitor, logger *slog.Logger) *MarginHandler {
+	return &MarginHandler{monitor: monitor, logger: logger}
+}
+
+// SetControlService 注入事前控制服务，成交与行情同时用于价格笼子参考价和报单成交比
+func (h *MarginHandler) SetControlService(controls *application.PreTradeControlService) {
+	h.controls = controls
+}
+
+func (h *MarginHandler) Handle(ctx context.Context, msg kafka.Message) error {
+	switch msg.Topic {
+	case matchingTradeExecutedTopic:
+		var payload struct {
+			BuyUserID  string `json:"buy_user_id"`
+			SellUserID string `json:"sell_user_id"`
+			Symbol     string `json:"symbol"`
+			Price      string `json:"price"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal trade event", "error", err)
+			return err
+		}
+		price, err := decimal.NewFromString(payload.Price)
+		if err != nil {
+			h.logger.WarnContext(ctx, "invalid trade price", "symbol", payload.Symbol, "price", payload.Price)
+			return nil
+		}
+		h.monitor.OnTrade(payload.Symbol, price, payload.BuyUserID, payload.SellUserID)
+		if h.controls != nil {
+			h.controls.OnTrade(payload.Symbol, price, payload.BuyUserID, payload.SellUserID)
+		}
+	case marketQuoteUpdatedTopic:
+		var payload struct {
+			Symbol    string `json:"symbol"`
+			BidPrice  string `json:"bid_price"`
+			AskPrice  string `json:"ask_price"`
+			LastPrice string `json:"last_price"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal quote event", "error", err)
+			return err
+		}
+		h.monitor.OnPrice(payload.Symbol, quoteMark(payload.LastPrice, payload.BidPrice, payload.AskPrice))
+		if h.controls != nil {
+			last, _ := decimal.NewFromString(payload.LastPrice)
+			bid, _ := decimal.NewFromString(payload.BidPrice)
+			ask, _ := decimal.NewFromString(payload.AskPrice)
+			h.controls.OnQuote(payload.Symbol, last, bid, ask)
+		}
+	case positionCreatedTopic:
+		var payload struct {
+			UserID            string          `json:"user_id"`
+			Symbol            string          `json:"symbol"`
+			Quantity          decimal.Decimal `json:"quantity"`
+			AverageEntryPrice decimal.Decimal `json:"average_entry_price"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal position created event", "error", err)
+			return err
+		}
+		h.monitor.OnPositionChanged(payload.UserID, payload.Symbol, payload.Quantity, payload.AverageEntryPrice)
+	case positionUpdatedTopic:
+		var payload struct {
+			UserID          string          `json:"user_id"`
+			Symbol          string          `json:"symbol"`
+			NewQuantity     decimal.Decimal `json:"new_quantity"`
+			NewAveragePrice decimal.Decimal `json:"new_average_price"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal position updated event", "error", err)
+			return err
+		}
+		h.monitor.OnPositionChanged(payload.UserID, payload.Symbol, payload.NewQuantity, payload.NewAveragePrice)
+	case positionClosedTopic:
+		var payload struct {
+			UserID string `json:"user_id"`
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal position closed event", "error", err)
+			return err
+		}
+		h.monitor.OnPositionChanged(payload.UserID, payload.Symbol, decimal.Zero, decimal.Zero)
+	case accountCreatedTopic:
+		var payload struct {
+			AccountID string `json:"account_id"`
+			UserID    string `json:"user_id"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal account created event", "error", err)
+			return err
+		}
+		return h.monitor.OnAccountCreated(ctx, payload.AccountID, payload.UserID)
+	case accountDepositedTopic, accountWithdrawnTopic, accountFrozenTopic, accountUnfrozenTopic,
+		accountDeductedTopic, accountBorrowedTopic, accountRepaidTopic, accountInterestTopic:
+		var payload struct {
+			AccountID string `json:"account_id"`
+			Balance   string `json:"balance"`
+		}
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			h.logger.ErrorContext(ctx, "failed to unmarshal account event", "topic", msg.Topic, "error", err)
+			return err
+		}
+		// 携带最新余额的事件直接应用，其余（冻结、扣款等）回源刷新
+		if balance, err := decimal.NewFromString(payload.Balance); err == nil {
+			h.monitor.OnBalance(payload.AccountID, balance)
+		} else {
+			h.monitor.OnAccountChanged(payload.AccountID)
+		}
+	default:
+		h.logger.WarnContext(ctx, "unknown margin event topic", "topic", msg.Topic)
+	}
+	return nil
+}
+
+// quoteMark 优先使用最新成交价，缺失时取买卖中间价
+func quoteMark(last, bid, ask string) decimal.Decimal {
+	if p, err := decimal.NewFromString(last); err == nil && p.IsPositive() {
+		return p
+	}
+	b, errB := decimal.NewFromString(bid)
+	a, errA := decimal.NewFromString(ask)
+	if errB != nil || errA != nil {
+		return decimal.Zero
+	}
+	return b.Add(a).Div(decimal.NewFromInt(2))
+}
@@ -16,8 +16,9 @@ import (
 // 负责处理与风险管理相关的 gRPC 请求
 type Handler struct {
 	pb.UnimplementedRiskServiceServer
-	cmd   *application.RiskCommandService
-	query *application.RiskQueryService
+	cmd    *application.RiskCommandService
+	query  *application.RiskQueryService
+	stress *application.StressTestService
 }
 
 // NewHandler 创建 gRPC 处理器实例
@@ -25,6 +26,11 @@ func NewHandler(cmd *application.RiskCommandService, query *application.RiskQuer
 	return &Handler{cmd: cmd, query: query}
 }
 
+// SetStressTestService 注入压力场景库服务
+func (h *Handler) SetStressTestService(svc *application.StressTestService) {
+	h.stress = svc
+}
+
 // CheckRisk 检查交易风险 (Legacy, mapped to AssessRisk)
 func (h *Handler) CheckRisk(ctx context.Context, req *pb.CheckRiskRequest) (*pb.CheckRiskResponse, error) {
 	side := req.Side
@@ -181,6 +187,7 @@ func (h *Handler) CalculatePortfolioRisk(ctx context.Context, req *pb.CalculateP
 		TimeHorizon:     req.TimeHorizon,
 		Simulations:     int(req.Simulations),
 		ConfidenceLevel: req.ConfidenceLevel,
+		Seed:            req.Seed,
 	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to calculate portfolio risk: %v", err)
@@ -219,6 +226,7 @@ func (h *Handler) CalculateMonteCarloRisk(ctx context.Context, req *pb.MonteCarl
 		T:          req.T,
 		Iterations: int(req.Iterations),
 		Steps:      int(req.Steps),
+		Seed:       req.Seed,
 	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to calculate monte carlo risk: %v", err)
@@ -234,6 +242,68 @@ func (h *Handler) CalculateMonteCarloRisk(ctx context.Context, req *pb.MonteCarl
 	}, nil
 }
 
+// CalculateVaR 全量重估组合 VaR/ES
+func (h *Handler) CalculateVaR(ctx context.Context, req *pb.CalculateVaRRequest) (*pb.CalculateVaRResponse, error) {
+	positions := make([]application.VaRPositionDTO, 0, len(req.Positions))
+	for _, p := range req.Positions {
+		positions = append(positions, application.VaRPositionDTO{
+			PositionID:   p.PositionId,
+			Symbol:       p.Symbol,
+			Underlying:   p.Underlying,
+			Type:         p.Type,
+			Quantity:     p.Quantity,
+			Multiplier:   p.Multiplier,
+			Price:        p.Price,
+			OptionType:   p.OptionType,
+			Strike:       p.Strike,
+			ExpiryDate:   p.ExpiryDate,
+			ImpliedVol:   p.ImpliedVol,
+			RiskFreeRate: p.RiskFreeRate,
+		})
+	}
+
+	result, err := h.query.CalculateVaR(ctx, &application.CalculateVaRRequest{
+		Positions:       positions,
+		Method:          req.Method,
+		Filter:          req.Filter,
+		ConfidenceLevel: req.ConfidenceLevel,
+		HorizonDays:     int(req.HorizonDays),
+		LookbackDays:    int(req.LookbackDays),
+		Simulations:     int(req.Simulations),
+		Seed:            req.Seed,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to calculate var: %v", err)
+	}
+
+	contributions := make([]*pb.PositionRiskContribution, 0, len(result.Contributions))
+	for _, c := range result.Contributions {
+		contributions = append(contributions, &pb.PositionRiskContribution{
+			PositionId:     c.PositionID,
+			Symbol:         c.Symbol,
+			MarketValue:    c.MarketValue,
+			StandaloneVar:  c.StandaloneVaR,
+			ComponentVar:   c.ComponentVaR,
+			IncrementalVar: c.IncrementalVaR,
+			ComponentEs:    c.ComponentES,
+		})
+	}
+	return &pb.CalculateVaRResponse{
+		Method:           result.Method,
+		ConfidenceLevel:  result.ConfidenceLevel,
+		HorizonDays:      int32(result.HorizonDays),
+		Scenarios:        int32(result.Scenarios),
+		Seed:             result.Seed,
+		PortfolioValue:   result.PortfolioValue,
+		VarValue:         result.VaR,
+		EsValue:          result.ES,
+		UndiversifiedVar: result.UndiversifiedVaR,
+		Diversification:  result.Diversification,
+		Contributions:    contributions,
+		CalculatedAt:     result.CalculatedAt,
+	}, nil
+}
+
 // RunStressTest 运行压力测试
 func (h *Handler) RunStressTest(ctx context.Context, req *pb.RunStressTestRequest) (*pb.RunStressTestResponse, error) {
 	assets := make([]application.PortfolioAssetDTO, 0, len(req.Assets))
@@ -258,16 +328,167 @@ func (h *Handler) RunStressTest(ctx context.Context, req *pb.RunStressTestReques
 	resultsDTO := make([]*pb.StressTestResult, 0, len(result.Results))
 	for _, res := range result.Results {
 		resultsDTO = append(resultsDTO, &pb.StressTestResult{
-			ScenarioName:   res.ScenarioName,
-			PnlImpact:      res.PnLImpact,
-			PercentageDrop: res.PercentageDrop,
-			Survived:       res.Survived,
+			ScenarioName:    res.ScenarioName,
+			PnlImpact:       res.PnLImpact,
+			PercentageDrop:  res.PercentageDrop,
+			Survived:        res.Survived,
+			ScenarioVersion: int32(res.ScenarioVersion),
 		})
 	}
 
 	return &pb.RunStressTestResponse{Results: resultsDTO}, nil
 }
 
+// SaveStressScenario 保存压力场景（同名场景生成新版本）
+func (h *Handler) SaveStressScenario(ctx context.Context, req *pb.SaveStressScenarioRequest) (*pb.StressScenarioResponse, error) {
+	if h.stress == nil {
+		return nil, status.Error(codes.FailedPrecondition, "stress scenario library not configured")
+	}
+	dto, err := h.stress.SaveScenario(ctx, application.SaveStressScenarioCommand{
+		Name:         req.Name,
+		Description:  req.Description,
+		SymbolShocks: req.SymbolShocks,
+		SectorShocks: req.SectorShocks,
+		DefaultShock: req.DefaultShock,
+		VolShock:     req.VolShock,
+		RateShock:    req.RateShock,
+		FXShocks:     req.FxShocks,
+		CreatedBy:    req.CreatedBy,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to save stress scenario: %v", err)
+	}
+	return &pb.StressScenarioResponse{Scenario: toPBStressScenario(dto)}, nil
+}
+
+// BuildHistoricalScenario 由历史窗口回放生成压力场景
+func (h *Handler) BuildHistoricalScenario(ctx context.Context, req *pb.BuildHistoricalScenarioRequest) (*pb.StressScenarioResponse, error) {
+	if h.stress == nil {
+		return nil, status.Error(codes.FailedPrecondition, "stress scenario library not configured")
+	}
+	dto, err := h.stress.BuildHistoricalScenario(ctx, application.BuildHistoricalScenarioCommand{
+		Name:         req.Name,
+		Description:  req.Description,
+		Symbols:      req.Symbols,
+		Sectors:      req.Sectors,
+		FXSymbols:    req.FxSymbols,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+		WorstPoint:   req.WorstPoint,
+		BaselineDays: int(req.BaselineDays),
+		RateShock:    req.RateShock,
+		CreatedBy:    req.CreatedBy,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to build historical scenario: %v", err)
+	}
+	return &pb.StressScenarioResponse{Scenario: toPBStressScenario(dto)}, nil
+}
+
+// ListStressScenarios 列出场景库；指定名称时返回该场景的全部版本
+func (h *Handler) ListStressScenarios(ctx context.Context, req *pb.ListStressScenariosRequest) (*pb.ListStressScenariosResponse, error) {
+	if h.stress == nil {
+		return nil, status.Error(codes.FailedPrecondition, "stress scenario library not configured")
+	}
+	var (
+		dtos []*application.StressScenarioDTO
+		err  error
+	)
+	if req.Name != "" {
+		dtos, err = h.stress.ListScenarioVersions(ctx, req.Name)
+	} else {
+		dtos, err = h.stress.ListScenarios(ctx)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stress scenarios: %v", err)
+	}
+	scenarios := make([]*pb.StressScenario, 0, len(dtos))
+	for _, d := range dtos {
+		scenarios = append(scenarios, toPBStressScenario(d))
+	}
+	return &pb.ListStressScenariosResponse{Scenarios: scenarios}, nil
+}
+
+// RunAccountStressTest 对用户账户运行场景库
+func (h *Handler) RunAccountStressTest(ctx context.Context, req *pb.RunAccountStressTestRequest) (*pb.RunAccountStressTestResponse, error) {
+	if h.stress == nil {
+		return nil, status.Error(codes.FailedPrecondition, "stress scenario library not configured")
+	}
+	runs, err := h.stress.RunAccountStressTest(ctx, &application.RunAccountStressTestRequest{
+		UserID:        req.UserId,
+		ScenarioNames: req.ScenarioNames,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to run account stress test: %v", err)
+	}
+	pbRuns := make([]*pb.StressTestRun, 0, len(runs))
+	for _, r := range runs {
+		pbRuns = append(pbRuns, &pb.StressTestRun{
+			Id:              r.ID,
+			UserId:          r.UserID,
+			AccountId:       r.AccountID,
+			ScenarioName:    r.ScenarioName,
+			ScenarioVersion: int32(r.ScenarioVersion),
+			PnlImpact:       r.PnLImpact,
+			PercentageDrop:  r.PercentageDrop,
+			StressedEquity:  r.StressedEquity,
+			RequiredMargin:  r.RequiredMargin,
+			Survived:        r.Survived,
+			RunAt:           r.RunAt,
+		})
+	}
+	return &pb.RunAccountStressTestResponse{Runs: pbRuns}, nil
+}
+
+// ReverseStressTest 反向压力测试
+func (h *Handler) ReverseStressTest(ctx context.Context, req *pb.ReverseStressTestRequest) (*pb.ReverseStressTestResponse, error) {
+	if h.stress == nil {
+		return nil, status.Error(codes.FailedPrecondition, "stress scenario library not configured")
+	}
+	result, err := h.stress.ReverseStressTest(ctx, &application.ReverseStressTestRequest{
+		UserID:          req.UserId,
+		ScenarioName:    req.ScenarioName,
+		ScenarioVersion: int(req.ScenarioVersion),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to run reverse stress test: %v", err)
+	}
+	return &pb.ReverseStressTestResponse{
+		UserId:          result.UserID,
+		Breached:        result.Breached,
+		AlreadyBreached: result.AlreadyBreached,
+		Scale:           result.Scale,
+		Scenario:        toPBStressScenario(result.Scenario),
+		PnlImpact:       result.PnLImpact,
+		StressedEquity:  result.StressedEquity,
+		RequiredMargin:  result.RequiredMargin,
+		Iterations:      int32(result.Iterations),
+	}, nil
+}
+
+func toPBStressScenario(d *application.StressScenarioDTO) *pb.StressScenario {
+	if d == nil {
+		return nil
+	}
+	return &pb.StressScenario{
+		Id:           d.ID,
+		Name:         d.Name,
+		Version:      int32(d.Version),
+		Description:  d.Description,
+		Source:       d.Source,
+		SymbolShocks: d.SymbolShocks,
+		SectorShocks: d.SectorShocks,
+		DefaultShock: d.DefaultShock,
+		VolShock:     d.VolShock,
+		RateShock:    d.RateShock,
+		FxShocks:     d.FXShocks,
+		WindowStart:  d.WindowStart,
+		WindowEnd:    d.WindowEnd,
+		CreatedBy:    d.CreatedBy,
+		CreatedAt:    d.CreatedAt,
+	}
+}
+
 // GetAnomalyReport 获取异常检测报告
 func (h *Handler) GetAnomalyReport(ctx context.Context, req *pb.GetAnomalyReportRequest) (*pb.GetAnomalyReportResponse, error) {
 	result, err := h.query.GetAnomalyReport(ctx, &application.GetAnomalyReportRequest{
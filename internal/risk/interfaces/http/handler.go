@@ -8,13 +8,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wyfcoding/financialtrading/internal/risk/application"
+	"github.com/wyfcoding/financialtrading/internal/risk/domain"
 	"github.com/wyfcoding/pkg/logging"
 )
 
 // RiskHandler 负责处理与风险管理相关的 HTTP 请求
 type RiskHandler struct {
-	cmd   *application.RiskCommandService
-	query *application.RiskQueryService
+	cmd    *application.RiskCommandService
+	query  *application.RiskQueryService
+	stress *application.StressTestService
+	liq    *application.LiquidationEngine
+	margin *application.MarginMonitor
+	rules  *application.PreTradeRuleService
+	ctrl   *application.PreTradeControlService
 }
 
 // NewRiskHandler 创建 HTTP 处理器
@@ -22,6 +28,31 @@ func NewRiskHandler(cmd *application.RiskCommandService, query *application.Risk
 	return &RiskHandler{cmd: cmd, query: query}
 }
 
+// SetStressTestService 注入压力场景库服务，注入后注册场景库相关路由
+func (h *RiskHandler) SetStressTestService(svc *application.StressTestService) {
+	h.stress = svc
+}
+
+// SetLiquidationEngine 注入强平引擎，注入后注册强平案件查询路由
+func (h *RiskHandler) SetLiquidationEngine(engine *application.LiquidationEngine) {
+	h.liq = engine
+}
+
+// SetMarginMonitor 注入实时保证金监控，注入后注册保证金状态查询路由
+func (h *RiskHandler) SetMarginMonitor(monitor *application.MarginMonitor) {
+	h.margin = monitor
+}
+
+// SetPreTradeRuleService 注入事前风控规则服务，注入后注册规则管理路由
+func (h *RiskHandler) SetPreTradeRuleService(svc *application.PreTradeRuleService) {
+	h.rules = svc
+}
+
+// SetPreTradeControlService 注入订单级事前控制服务，注入后注册控制参数与熔断开关路由
+func (h *RiskHandler) SetPreTradeControlService(svc *application.PreTradeControlService) {
+	h.ctrl = svc
+}
+
 // RegisterRoutes 注册路由
 func (h *RiskHandler) RegisterRoutes(router *gin.RouterGroup) {
 	api := router.Group("/api/v1/risk")
@@ -32,6 +63,51 @@ func (h *RiskHandler) RegisterRoutes(router *gin.RouterGroup) {
 		api.GET("/alerts", h.GetRiskAlerts)
 		api.POST("/portfolio-risk", h.CalculatePortfolioRisk)
 		api.POST("/monte-carlo", h.CalculateMonteCarloRisk)
+		api.POST("/var", h.CalculateVaR)
+		api.POST("/stress-test", h.RunStressTest)
+	}
+
+	if h.stress != nil {
+		stress := api.Group("/stress")
+		stress.GET("/scenarios", h.ListStressScenarios)
+		stress.POST("/scenarios", h.SaveStressScenario)
+		stress.POST("/scenarios/historical", h.BuildHistoricalScenario)
+		stress.GET("/scenarios/:name/versions", h.ListStressScenarioVersions)
+		stress.POST("/runs", h.RunAccountStressTest)
+		stress.GET("/runs", h.ListStressRuns)
+		stress.POST("/reverse", h.ReverseStressTest)
+	}
+
+	if h.liq != nil {
+		api.GET("/liquidations", h.ListLiquidationCases)
+		api.GET("/liquidations/:id", h.GetLiquidationCase)
+	}
+
+	if h.margin != nil {
+		api.GET("/margin/:account_id", h.GetMarginState)
+	}
+
+	if h.rules != nil {
+		rules := api.Group("/rules")
+		rules.GET("", h.ListRiskRules)
+		rules.POST("", h.SaveRiskRule)
+		rules.DELETE("/:id", h.DeleteRiskRule)
+		rules.POST("/validate", h.ValidateRuleExpression)
+		rules.POST("/reload", h.ReloadRiskRules)
+		rules.GET("/stats", h.GetRuleStats)
+	}
+
+	if h.ctrl != nil {
+		controls := api.Group("/controls")
+		controls.GET("/limits", h.ListPreTradeLimits)
+		controls.PUT("/limits", h.SavePreTradeLimits)
+		controls.DELETE("/limits/:scope", h.RemovePreTradeLimits)
+		controls.PUT("/desks", h.AssignDesk)
+		controls.GET("/usage/:user_id", h.GetControlUsage)
+		controls.GET("/audits", h.ListControlAudits)
+		controls.GET("/kill-switches", h.ListKillSwitches)
+		controls.POST("/kill-switches", h.ActivateKillSwitch)
+		controls.POST("/kill-switches/:id/release", h.ReleaseKillSwitch)
 	}
 }
 
@@ -173,3 +249,422 @@ func (h *RiskHandler) CalculateMonteCarloRisk(c *gin.Context) {
 
 	response.Success(c, result)
 }
+
+// CalculateVaR 全量重估组合 VaR/ES
+func (h *RiskHandler) CalculateVaR(c *gin.Context) {
+	var req application.CalculateVaRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	result, err := h.query.CalculateVaR(c.Request.Context(), &req)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to calculate VaR", "method", req.Method, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RunStressTest 对给定资产组合运行压力场景
+func (h *RiskHandler) RunStressTest(c *gin.Context) {
+	var req application.RunStressTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	result, err := h.query.RunStressTest(c.Request.Context(), &req)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to run stress test", "scenario", req.ScenarioName, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListStressScenarios 列出场景库（各场景最新版本）
+func (h *RiskHandler) ListStressScenarios(c *gin.Context) {
+	scenarios, err := h.stress.ListScenarios(c.Request.Context())
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list stress scenarios", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, scenarios)
+}
+
+// SaveStressScenario 保存场景，同名场景生成新版本
+func (h *RiskHandler) SaveStressScenario(c *gin.Context) {
+	var cmd application.SaveStressScenarioCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	scenario, err := h.stress.SaveScenario(c.Request.Context(), cmd)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to save stress scenario", "name", cmd.Name, "error", err)
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	response.Success(c, scenario)
+}
+
+// BuildHistoricalScenario 由历史窗口回放生成场景
+func (h *RiskHandler) BuildHistoricalScenario(c *gin.Context) {
+	var cmd application.BuildHistoricalScenarioCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	scenario, err := h.stress.BuildHistoricalScenario(c.Request.Context(), cmd)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to build historical scenario", "name", cmd.Name, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, scenario)
+}
+
+// ListStressScenarioVersions 列出场景的全部版本
+func (h *RiskHandler) ListStressScenarioVersions(c *gin.Context) {
+	name := c.Param("name")
+	versions, err := h.stress.ListScenarioVersions(c.Request.Context(), name)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list stress scenario versions", "name", name, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, versions)
+}
+
+// RunAccountStressTest 对用户账户运行场景库
+func (h *RiskHandler) RunAccountStressTest(c *gin.Context) {
+	var req application.RunAccountStressTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	runs, err := h.stress.RunAccountStressTest(c.Request.Context(), &req)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to run account stress test", "user_id", req.UserID, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, runs)
+}
+
+// ListStressRuns 查询账户压力测试记录
+func (h *RiskHandler) ListStressRuns(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid limit", "")
+		return
+	}
+
+	runs, err := h.stress.ListStressRuns(c.Request.Context(), userID, limit)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list stress runs", "user_id", userID, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, runs)
+}
+
+// ReverseStressTest 反向压力测试：搜索击穿维持保证金的最小冲击
+func (h *RiskHandler) ReverseStressTest(c *gin.Context) {
+	var req application.ReverseStressTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	result, err := h.stress.ReverseStressTest(c.Request.Context(), &req)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to run reverse stress test", "user_id", req.UserID, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListLiquidationCases 查询用户强平案件
+func (h *RiskHandler) ListLiquidationCases(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "user_id is required", "")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid limit", "")
+		return
+	}
+
+	cases, err := h.liq.ListCases(c.Request.Context(), userID, limit)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list liquidation cases", "user_id", userID, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, cases)
+}
+
+// GetLiquidationCase 查询强平案件及完整审计轨迹
+func (h *RiskHandler) GetLiquidationCase(c *gin.Context) {
+	id := c.Param("id")
+	lc, err := h.liq.GetCase(c.Request.Context(), id)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to get liquidation case", "case_id", id, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	if lc == nil {
+		response.ErrorWithStatus(c, http.StatusNotFound, "liquidation case not found", "")
+		return
+	}
+
+	response.Success(c, lc)
+}
+
+// GetMarginState 查询内存中的实时账户保证金状态
+func (h *RiskHandler) GetMarginState(c *gin.Context) {
+	accountID := c.Param("account_id")
+	state, figures, ok := h.margin.GetAccountState(accountID)
+	if !ok {
+		response.ErrorWithStatus(c, http.StatusNotFound, "account not monitored", "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"account":      state,
+		"equity":       figures.Equity.String(),
+		"used_margin":  figures.UsedMargin.String(),
+		"margin_level": figures.MarginLevel.String(),
+	})
+}
+
+// ListRiskRules 列出全部事前风控规则
+func (h *RiskHandler) ListRiskRules(c *gin.Context) {
+	rules, err := h.rules.ListRules(c.Request.Context())
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list risk rules", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// SaveRiskRule 新建或更新规则，表达式无效时拒绝保存
+func (h *RiskHandler) SaveRiskRule(c *gin.Context) {
+	var cmd application.SaveRiskRuleCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	rule, err := h.rules.SaveRule(c.Request.Context(), cmd)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to save risk rule", "rule_id", cmd.RuleID, "error", err)
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	response.Success(c, rule)
+}
+
+// DeleteRiskRule 删除规则
+func (h *RiskHandler) DeleteRiskRule(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.rules.DeleteRule(c.Request.Context(), id); err != nil {
+		logging.Error(c.Request.Context(), "Failed to delete risk rule", "rule_id", id, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ValidateRuleExpression 编译校验表达式
+func (h *RiskHandler) ValidateRuleExpression(c *gin.Context) {
+	var req application.ValidateRuleExpressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	if err := h.rules.ValidateExpression(req.Expression); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	response.Success(c, gin.H{"valid": true})
+}
+
+// ReloadRiskRules 立即从仓储重新加载规则
+func (h *RiskHandler) ReloadRiskRules(c *gin.Context) {
+	if err := h.rules.Reload(c.Request.Context()); err != nil {
+		logging.Error(c.Request.Context(), "Failed to reload risk rules", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, h.rules.Engine().Rules())
+}
+
+// GetRuleStats 查询各规则求值统计
+func (h *RiskHandler) GetRuleStats(c *gin.Context) {
+	response.Success(c, h.rules.RuleStats())
+}
+
+// ListPreTradeLimits 查询生效中的订单级控制参数
+func (h *RiskHandler) ListPreTradeLimits(c *gin.Context) {
+	response.Success(c, h.ctrl.ListLimits())
+}
+
+// SavePreTradeLimits 新增或替换一组控制参数
+func (h *RiskHandler) SavePreTradeLimits(c *gin.Context) {
+	var cmd application.SavePreTradeLimitsCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	limits, err := h.ctrl.SaveLimits(c.Request.Context(), cmd)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to save pre-trade limits", "scope", cmd.Scope, "key", cmd.Key, "error", err)
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	response.Success(c, limits)
+}
+
+// RemovePreTradeLimits 删除一组控制参数，key 通过查询参数传入
+func (h *RiskHandler) RemovePreTradeLimits(c *gin.Context) {
+	scope := domain.ControlScope(c.Param("scope"))
+	key := c.Query("key")
+	if err := h.ctrl.RemoveLimits(c.Request.Context(), scope, key, c.Query("actor")); err != nil {
+		logging.Error(c.Request.Context(), "Failed to remove pre-trade limits", "scope", scope, "key", key, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// AssignDesk 设置用户所属交易台
+func (h *RiskHandler) AssignDesk(c *gin.Context) {
+	var cmd application.AssignDeskCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	if err := h.ctrl.AssignDesk(c.Request.Context(), cmd); err != nil {
+		logging.Error(c.Request.Context(), "Failed to assign desk", "user_id", cmd.UserID, "desk", cmd.Desk, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// GetControlUsage 查询用户的报单频率、报单成交比与生效中的熔断开关
+func (h *RiskHandler) GetControlUsage(c *gin.Context) {
+	response.Success(c, h.ctrl.Usage(c.Param("user_id")))
+}
+
+// ListControlAudits 查询事前控制审计记录
+func (h *RiskHandler) ListControlAudits(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, "invalid limit", "")
+		return
+	}
+
+	audits, err := h.ctrl.ListAudits(c.Request.Context(), c.Query("target"), limit)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list control audits", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, audits)
+}
+
+// ListKillSwitches 查询熔断开关，active=true 时仅返回生效中的开关
+func (h *RiskHandler) ListKillSwitches(c *gin.Context) {
+	switches, err := h.ctrl.ListKillSwitches(c.Request.Context(), c.Query("active") == "true")
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to list kill switches", "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, switches)
+}
+
+// ActivateKillSwitch 激活熔断开关并撤销范围内挂单
+func (h *RiskHandler) ActivateKillSwitch(c *gin.Context) {
+	var cmd application.ActivateKillSwitchCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	ks, err := h.ctrl.ActivateKillSwitch(c.Request.Context(), cmd)
+	if ks == nil {
+		logging.Error(c.Request.Context(), "Failed to activate kill switch", "scope", cmd.Scope, "target", cmd.Target, "error", err)
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	// 开关已生效，撤单未完成时一并返回错误信息
+	if err != nil {
+		response.Success(c, gin.H{"kill_switch": ks, "cancel_error": err.Error()})
+		return
+	}
+
+	response.Success(c, gin.H{"kill_switch": ks})
+}
+
+// ReleaseKillSwitch 解除熔断开关
+func (h *RiskHandler) ReleaseKillSwitch(c *gin.Context) {
+	var req application.ReleaseKillSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithStatus(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+
+	id := c.Param("id")
+	ks, err := h.ctrl.ReleaseKillSwitch(c.Request.Context(), id, req.Actor)
+	if err != nil {
+		logging.Error(c.Request.Context(), "Failed to release kill switch", "kill_switch_id", id, "error", err)
+		response.ErrorWithStatus(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+
+	response.Success(c, ks)
+}
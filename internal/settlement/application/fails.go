@@ -0,0 +1,404 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+const (
+	failsPageSize      = 200
+	failsSweepInterval = time.Hour
+	// failsCalendarLookback 加载节假日的回溯区间，更早的日期仅按周末判断
+	failsCalendarLookback = 366 * 24 * time.Hour
+)
+
+// FailsRunResult 一次结算失败处理的结果
+type FailsRunResult struct {
+	AsOf             time.Time                  `json:"as_of"`
+	Recycled         int                        `json:"recycled"`
+	Settled          int                        `json:"settled"`
+	PartiallySettled int                        `json:"partially_settled"`
+	PenaltiesCharged int                        `json:"penalties_charged"`
+	PenaltyAmount    map[string]decimal.Decimal `json:"penalty_amount"`
+	BuyInsInitiated  int                        `json:"buy_ins_initiated"`
+	BuyInsExecuted   int                        `json:"buy_ins_executed"`
+	BuyInsCharged    int                        `json:"buy_ins_charged"`
+}
+
+// ExecuteBuyInCommand 人工登记强制买入成交
+type ExecuteBuyInCommand struct {
+	BuyInID string
+	Price   decimal.Decimal
+	TradeID string
+}
+
+// FailsService 结算失败管理：循环重试失败指令（允许部分交收），
+// 按工作日计提罚金，超过延展期的证券不足失败发起强制买入并生成替代交易。
+type FailsService struct {
+	repo      domain.SettlementRepository
+	fails     domain.FailsRepository
+	app       *SettlementAppService
+	domainSvc *domain.SettlementDomainService
+	policy    *domain.FailsPolicy
+	prices    domain.ReferencePriceSource
+	executor  domain.BuyInExecutor
+	calendar  domain.SettlementCalendar
+	market    string
+	logger    *slog.Logger
+
+	mu sync.Mutex
+}
+
+// NewFailsService 创建结算失败管理服务
+func NewFailsService(
+	repo domain.SettlementRepository,
+	fails domain.FailsRepository,
+	app *SettlementAppService,
+	domainSvc *domain.SettlementDomainService,
+	logger *slog.Logger,
+) *FailsService {
+	return &FailsService{
+		repo:      repo,
+		fails:     fails,
+		app:       app,
+		domainSvc: domainSvc,
+		policy:    domain.DefaultFailsPolicy(),
+		logger:    logger,
+	}
+}
+
+// SetPolicy 设置罚金与强制买入参数
+func (s *FailsService) SetPolicy(policy *domain.FailsPolicy) {
+	if policy != nil {
+		s.policy = policy
+	}
+}
+
+// SetReferencePriceSource 设置罚金参考价来源，未设置时使用原成交价
+func (s *FailsService) SetReferencePriceSource(prices domain.ReferencePriceSource) {
+	s.prices = prices
+}
+
+// SetBuyInExecutor 设置强制买入代理，未设置时强制买入需人工登记成交
+func (s *FailsService) SetBuyInExecutor(executor domain.BuyInExecutor) {
+	s.executor = executor
+}
+
+// SetCalendar 设置结算日历，失败工作日按市场与结算币种的联合日历计数，
+// 未设置或查询失败时仅排除周末
+func (s *FailsService) SetCalendar(calendar domain.SettlementCalendar, market string) {
+	s.calendar = calendar
+	s.market = market
+}
+
+// businessCalendar 返回币种对应的工作日日历，同一次处理内按币种缓存
+func (s *FailsService) businessCalendar(ctx context.Context, cache map[string]domain.BusinessCalendar, currency string, asOf time.Time) domain.BusinessCalendar {
+	if cal, ok := cache[currency]; ok {
+		return cal
+	}
+	var cal domain.BusinessCalendar = domain.WeekdayCalendar{}
+	if s.calendar != nil {
+		loaded, err := s.calendar.BusinessCalendar(ctx, s.market, currency, asOf.Add(-failsCalendarLookback), asOf)
+		if err != nil {
+			s.logger.WarnContext(ctx, "business calendar unavailable, counting weekdays", "market", s.market, "currency", currency, "error", err)
+		} else {
+			cal = loaded
+		}
+	}
+	cache[currency] = cal
+	return cal
+}
+
+// Start 周期性处理结算失败，直到 ctx 结束
+func (s *FailsService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(failsSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.ProcessFails(ctx, time.Now()); err != nil {
+				s.logger.ErrorContext(ctx, "settlement fails processing failed", "error", err)
+			}
+		}
+	}
+}
+
+// ProcessFails 处理截至 asOf 的全部未了结失败指令
+func (s *FailsService) ProcessFails(ctx context.Context, asOf time.Time) (*FailsRunResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &FailsRunResult{AsOf: asOf, PenaltyAmount: make(map[string]decimal.Decimal)}
+	calendars := make(map[string]domain.BusinessCalendar)
+	var afterID uint
+	for {
+		page, err := s.fails.FindOpenFails(ctx, asOf, afterID, failsPageSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to find open fails: %w", err)
+		}
+		for _, ins := range page {
+			afterID = ins.ID
+			cal := s.businessCalendar(ctx, calendars, ins.Currency, asOf)
+			if err := s.processFail(ctx, cal, ins, asOf, result); err != nil {
+				s.logger.ErrorContext(ctx, "failed to process settlement fail", "instruction_id", ins.InstructionID, "error", err)
+			}
+		}
+		if len(page) < failsPageSize {
+			break
+		}
+	}
+
+	// 已发起但尚未成交的强制买入
+	if s.executor != nil {
+		pending, err := s.fails.ListBuyIns(ctx, domain.BuyInInitiated, failsPageSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to list pending buy-ins: %w", err)
+		}
+		for _, b := range pending {
+			if err := s.executeBuyIn(ctx, b); err != nil {
+				s.logger.WarnContext(ctx, "buy-in execution failed", "buy_in_id", b.BuyInID, "error", err)
+				continue
+			}
+			result.BuyInsExecuted++
+		}
+	}
+
+	// 已成交但差额尚未收取的强制买入，重试直至成功
+	if s.domainSvc != nil {
+		pending, err := s.fails.ListPendingBuyInCharges(ctx, failsPageSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to list pending buy-in charges: %w", err)
+		}
+		for _, b := range pending {
+			if err := s.chargeBuyIn(ctx, b); err != nil {
+				s.logger.WarnContext(ctx, "buy-in charge retry failed", "buy_in_id", b.BuyInID, "amount", b.ChargeAmount, "error", err)
+				continue
+			}
+			result.BuyInsCharged++
+		}
+	}
+
+	s.logger.InfoContext(ctx, "settlement fails processed",
+		"as_of", asOf,
+		"recycled", result.Recycled,
+		"settled", result.Settled,
+		"penalties", result.PenaltiesCharged,
+		"buy_ins", result.BuyInsInitiated,
+		"buy_ins_charged", result.BuyInsCharged,
+	)
+	return result, nil
+}
+
+func (s *FailsService) processFail(ctx context.Context, cal domain.BusinessCalendar, ins *domain.SettlementInstruction, asOf time.Time, result *FailsRunResult) error {
+	// 1. 未进入强制买入的失败指令先循环重试
+	if ins.BuyInID == "" {
+		result.Recycled++
+		before := ins.SettledQuantity
+		err := s.app.ProcessSettlement(ctx, ProcessSettlementCommand{
+			InstructionID: ins.InstructionID,
+			BatchID:       "RECYCLE-" + asOf.Format("20060102"),
+		})
+		reloaded, getErr := s.repo.Get(ctx, ins.InstructionID)
+		if getErr != nil {
+			return fmt.Errorf("failed to reload instruction: %w", getErr)
+		}
+		ins = reloaded
+		if err == nil && ins.IsSettled() {
+			result.Settled++
+			return nil
+		}
+		if ins.SettledQuantity.GreaterThan(before) {
+			result.PartiallySettled++
+		}
+	}
+	if !ins.IsOpenFail() {
+		return nil
+	}
+
+	// 2. 计提已结束工作日的罚金
+	if err := s.accruePenalties(ctx, cal, ins, asOf, result); err != nil {
+		return err
+	}
+
+	// 3. 超过延展期发起强制买入
+	if s.policy.BuyInDue(cal, ins, asOf) {
+		buyIn := domain.NewBuyIn(ins)
+		ins.MarkBuyInInitiated(buyIn.BuyInID)
+		if err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+			if err := s.fails.SaveBuyIn(txCtx, buyIn); err != nil {
+				return err
+			}
+			return s.repo.Update(txCtx, ins)
+		}); err != nil {
+			return fmt.Errorf("failed to initiate buy-in: %w", err)
+		}
+		result.BuyInsInitiated++
+		s.logger.InfoContext(ctx, "buy-in initiated",
+			"buy_in_id", buyIn.BuyInID,
+			"instruction_id", ins.InstructionID,
+			"quantity", buyIn.Quantity,
+			"days_failed", ins.BusinessDaysFailed(cal, asOf),
+		)
+	}
+	return nil
+}
+
+func (s *FailsService) accruePenalties(ctx context.Context, cal domain.BusinessCalendar, ins *domain.SettlementInstruction, asOf time.Time, result *FailsRunResult) error {
+	dates := s.policy.PenaltyDates(cal, ins, asOf)
+	if len(dates) == 0 {
+		return nil
+	}
+	refPrice := ins.Price
+	if s.prices != nil && ins.FailingParty == domain.FailingPartySeller {
+		if p, err := s.prices.ReferencePrice(ctx, ins.Symbol); err == nil && p.IsPositive() {
+			refPrice = p
+		} else if err != nil {
+			s.logger.WarnContext(ctx, "reference price unavailable, using trade price", "symbol", ins.Symbol, "error", err)
+		}
+	}
+
+	var penalties []*domain.SettlementPenalty
+	for _, d := range dates {
+		if p := s.policy.NewSettlementPenalty(ins, d, refPrice); p != nil {
+			penalties = append(penalties, p)
+			ins.ApplyPenalty(p)
+		}
+	}
+	if len(penalties) == 0 {
+		return nil
+	}
+	if err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.fails.SavePenalties(txCtx, penalties); err != nil {
+			return err
+		}
+		return s.repo.Update(txCtx, ins)
+	}); err != nil {
+		return fmt.Errorf("failed to save penalties: %w", err)
+	}
+	for _, p := range penalties {
+		result.PenaltiesCharged++
+		result.PenaltyAmount[p.Currency] = result.PenaltyAmount[p.Currency].Add(p.Amount)
+	}
+	return nil
+}
+
+// ExecuteBuyIn 登记强制买入成交（人工或代理回报）
+func (s *FailsService) ExecuteBuyIn(ctx context.Context, cmd ExecuteBuyInCommand) (*domain.BuyIn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buyIn, err := s.fails.GetBuyIn(ctx, cmd.BuyInID)
+	if err != nil {
+		return nil, err
+	}
+	tradeID := cmd.TradeID
+	if tradeID == "" {
+		tradeID = "BUYIN-" + buyIn.InstructionID
+	}
+	if err := s.completeBuyIn(ctx, buyIn, cmd.Price, tradeID); err != nil {
+		return nil, err
+	}
+	return buyIn, nil
+}
+
+func (s *FailsService) executeBuyIn(ctx context.Context, buyIn *domain.BuyIn) error {
+	price, tradeID, err := s.executor.ExecuteBuyIn(ctx, buyIn)
+	if err != nil {
+		return err
+	}
+	return s.completeBuyIn(ctx, buyIn, price, tradeID)
+}
+
+// completeBuyIn 记录成交、生成替代交易结算指令、了结原指令，并向失败方收取差额
+func (s *FailsService) completeBuyIn(ctx context.Context, buyIn *domain.BuyIn, price decimal.Decimal, tradeID string) error {
+	if err := buyIn.Execute(price, tradeID); err != nil {
+		return err
+	}
+	original, err := s.repo.Get(ctx, buyIn.InstructionID)
+	if err != nil {
+		return fmt.Errorf("failed to get original instruction: %w", err)
+	}
+	if err := original.MarkBoughtIn(); err != nil {
+		return err
+	}
+	replacement := buyIn.ReplacementInstruction(s.policy.BuyInAgentAccount, s.policy.BuyInSettlementDays)
+	replacement.SecurityType = original.SecurityType
+	buyIn.ReplacementInstructionID = replacement.InstructionID
+
+	if err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.repo.Save(txCtx, replacement); err != nil {
+			return err
+		}
+		if err := s.repo.Update(txCtx, original); err != nil {
+			return err
+		}
+		return s.fails.SaveBuyIn(txCtx, buyIn)
+	}); err != nil {
+		return fmt.Errorf("failed to complete buy-in: %w", err)
+	}
+
+	// 差额状态已随成交落库为 PENDING，此处失败由下一轮失败处理重试
+	if buyIn.ChargeStatus == domain.BuyInChargePending && s.domainSvc != nil {
+		if err := s.chargeBuyIn(ctx, buyIn); err != nil {
+			s.logger.WarnContext(ctx, "failed to charge buy-in difference, will retry", "buy_in_id", buyIn.BuyInID, "amount", buyIn.ChargeAmount, "error", err)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "buy-in executed",
+		"buy_in_id", buyIn.BuyInID,
+		"price", buyIn.ExecutionPrice,
+		"charge", buyIn.ChargeAmount,
+		"replacement_instruction_id", replacement.InstructionID,
+	)
+	return nil
+}
+
+// chargeBuyIn 向失败方收取差额并标记已收取
+func (s *FailsService) chargeBuyIn(ctx context.Context, buyIn *domain.BuyIn) error {
+	if err := s.domainSvc.ChargeBuyInDifference(ctx, buyIn); err != nil {
+		return err
+	}
+	buyIn.MarkCharged()
+	if err := s.fails.SaveBuyIn(ctx, buyIn); err != nil {
+		return fmt.Errorf("failed to mark buy-in charged: %w", err)
+	}
+	return nil
+}
+
+// GetAgeingReport 生成截至 asOf 的结算失败账龄报告
+func (s *FailsService) GetAgeingReport(ctx context.Context, asOf time.Time) (*domain.FailsAgeingReport, error) {
+	var fails []*domain.SettlementInstruction
+	var afterID uint
+	for {
+		page, err := s.fails.FindOpenFails(ctx, asOf, afterID, failsPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find open fails: %w", err)
+		}
+		fails = append(fails, page...)
+		if len(page) < failsPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	calendars := make(map[string]domain.BusinessCalendar)
+	return domain.BuildFailsAgeingReport(asOf, fails, func(ins *domain.SettlementInstruction) domain.BusinessCalendar {
+		return s.businessCalendar(ctx, calendars, ins.Currency, asOf)
+	}), nil
+}
+
+// ListPenalties 查询罚金明细
+func (s *FailsService) ListPenalties(ctx context.Context, instructionID, accountID string, from, to time.Time) ([]*domain.SettlementPenalty, error) {
+	if instructionID == "" && accountID == "" {
+		return nil, errors.New("instruction_id or account_id is required")
+	}
+	return s.fails.ListPenalties(ctx, instructionID, accountID, from, to)
+}
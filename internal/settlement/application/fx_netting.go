@@ -0,0 +1,228 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+const multiCurrencyNettingLimit = 10000
+
+// MultiCurrencyNettingCommand 多币种净额结算
+type MultiCurrencyNettingCommand struct {
+	SettlementDate time.Time // 纳入结算日不晚于该日的待结算指令，为空时取当前时间
+	// SettlementCurrency 非空时将其他币种的剩余净额兑换为该币种
+	SettlementCurrency string
+	SpreadBps          decimal.Decimal // 兑换点差（基点），在买卖价基础上向不利于会员的方向加收
+	RateSnapshotAt     time.Time       // 汇率快照时点，为空时取当前时间
+}
+
+// MultiCurrencyNettingResult 多币种净额结算结果
+type MultiCurrencyNettingResult struct {
+	RunID              string
+	SettlementCurrency string
+	RateSnapshotAt     time.Time
+	InstructionCount   int
+	Results            []*domain.NettingResult
+	FXInstructions     []*domain.FXInstruction
+}
+
+// SetFXInstructionRepository 启用多币种净额的兑换指令留存
+func (s *SettlementAppService) SetFXInstructionRepository(repo domain.FXInstructionRepository) {
+	s.fxInstructionRepo = repo
+}
+
+// PerformMultiCurrencyNetting 对到期待结算指令做多边净额：按会员、币种轧差，
+// 可选按快照汇率与点差把其他币种剩余净额兑换为结算币种并生成兑换指令。
+// 兑换指令按货币对的即期起息日交收；汇率或起息日缺失时整批不落库。
+func (s *SettlementAppService) PerformMultiCurrencyNetting(ctx context.Context, cmd MultiCurrencyNettingCommand) (*MultiCurrencyNettingResult, error) {
+	if cmd.SettlementDate.IsZero() {
+		cmd.SettlementDate = time.Now()
+	}
+	if cmd.RateSnapshotAt.IsZero() {
+		cmd.RateSnapshotAt = time.Now()
+	}
+	if cmd.SpreadBps.IsNegative() {
+		return nil, errors.New("spread must not be negative")
+	}
+	settleCcy := strings.ToUpper(cmd.SettlementCurrency)
+	if settleCcy != "" && s.fxInstructionRepo == nil {
+		return nil, errors.New("fx instruction repository is not configured")
+	}
+
+	instructions, err := s.repo.FindPendingByDate(ctx, cmd.SettlementDate, multiCurrencyNettingLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instructions: %w", err)
+	}
+
+	runID := "MCN-" + strconv.FormatUint(idgen.GenID(), 10)
+	netting := domain.NewMultiCurrencyNetting()
+	for _, ins := range instructions {
+		netting.Add(ins)
+	}
+
+	result := &MultiCurrencyNettingResult{
+		RunID:              runID,
+		SettlementCurrency: settleCcy,
+		RateSnapshotAt:     cmd.RateSnapshotAt,
+		InstructionCount:   len(instructions),
+	}
+	quotes := make(map[string]*domain.FXQuote)
+	valueDates := make(map[string]time.Time)
+	now := time.Now()
+	for _, member := range netting.Members() {
+		var settleRow *domain.NettingResult
+		converted := decimal.Zero
+		for _, pos := range netting.Positions(member) {
+			row := &domain.NettingResult{
+				NettingID:      fmt.Sprintf("%s-%d", runID, len(result.Results)+1),
+				RunID:          runID,
+				AccountID:      member,
+				Currency:       pos.Currency,
+				GrossAmount:    pos.Gross(),
+				NetAmount:      pos.Net(),
+				InstructionIDs: fmt.Sprintf("%v", pos.InstructionIDs),
+				Status:         domain.NettingStatusCompleted,
+				CreatedAt:      now,
+			}
+			result.Results = append(result.Results, row)
+			if pos.Currency == settleCcy {
+				settleRow = row
+			}
+			if settleCcy == "" || pos.Currency == settleCcy || pos.Net().IsZero() {
+				continue
+			}
+
+			quote, err := s.snapshotQuote(ctx, quotes, pos.Currency, settleCcy, cmd.RateSnapshotAt)
+			if err != nil {
+				return nil, err
+			}
+			valueDate, err := s.fxValueDate(ctx, valueDates, pos.Currency, settleCcy, cmd)
+			if err != nil {
+				return nil, err
+			}
+			fx := domain.NewFXInstruction("FX-"+strconv.FormatUint(idgen.GenID(), 10), runID, pos, quote,
+				cmd.SpreadBps, cmd.RateSnapshotAt, valueDate)
+			result.FXInstructions = append(result.FXInstructions, fx)
+			converted = converted.Add(fx.SignedSettlementAmount())
+			row.Status = domain.NettingStatusConverted
+		}
+
+		if converted.IsZero() {
+			continue
+		}
+		if settleRow == nil {
+			settleRow = &domain.NettingResult{
+				NettingID: fmt.Sprintf("%s-%d", runID, len(result.Results)+1),
+				RunID:     runID,
+				AccountID: member,
+				Currency:  settleCcy,
+				Status:    domain.NettingStatusCompleted,
+				CreatedAt: now,
+			}
+			result.Results = append(result.Results, settleRow)
+		}
+		settleRow.ConvertedAmount = converted
+		settleRow.NetAmount = settleRow.NetAmount.Add(converted)
+	}
+
+	err = s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		for _, ins := range instructions {
+			if err := ins.StartNetting(runID); err != nil {
+				return fmt.Errorf("instruction %s: %w", ins.InstructionID, err)
+			}
+			if err := ins.CompleteNetting(); err != nil {
+				return fmt.Errorf("instruction %s: %w", ins.InstructionID, err)
+			}
+			if err := s.repo.Update(txCtx, ins); err != nil {
+				return err
+			}
+		}
+		for _, row := range result.Results {
+			if err := s.nettingRepo.Save(txCtx, row); err != nil {
+				return err
+			}
+		}
+		if len(result.FXInstructions) == 0 {
+			return nil
+		}
+		return s.fxInstructionRepo.Save(txCtx, result.FXInstructions)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save netting run: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "multi-currency netting completed",
+		"run_id", runID,
+		"instructions", len(instructions),
+		"members", len(netting.Members()),
+		"settlement_currency", settleCcy,
+		"fx_instructions", len(result.FXInstructions),
+	)
+	return result, nil
+}
+
+// snapshotQuote 取快照时点 from→to 报价，无正向汇率时用反向汇率换算；同一批次内同一货币对只查询一次
+func (s *SettlementAppService) snapshotQuote(ctx context.Context, cache map[string]*domain.FXQuote, from, to string, at time.Time) (*domain.FXQuote, error) {
+	key := from + "/" + to
+	if q, ok := cache[key]; ok {
+		return q, nil
+	}
+	var quote *domain.FXQuote
+	if rate, err := s.fxRateRepo.GetRateAt(ctx, from, to, at); err == nil {
+		quote = domain.NewFXQuote(rate)
+	} else if rate, invErr := s.fxRateRepo.GetRateAt(ctx, to, from, at); invErr == nil {
+		if quote, err = domain.NewInvertedFXQuote(rate); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("%w: %s at %s", domain.ErrFXRateUnavailable, key, at.Format(time.RFC3339))
+	}
+	cache[key] = quote
+	return quote, nil
+}
+
+// fxValueDate 以汇率快照时点为交易日，按货币对两种货币的节假日计算即期起息日；
+// 未配置结算日历时沿用净额结算日。同一批次内同一货币对只查询一次
+func (s *SettlementAppService) fxValueDate(ctx context.Context, cache map[string]time.Time, from, to string, cmd MultiCurrencyNettingCommand) (time.Time, error) {
+	if s.calendar == nil {
+		return cmd.SettlementDate, nil
+	}
+	key := from + "/" + to
+	if d, ok := cache[key]; ok {
+		return d, nil
+	}
+	d, err := s.calendar.FXValueDate(ctx, from, to, cmd.RateSnapshotAt, 0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get fx value date for %s: %w", key, err)
+	}
+	cache[key] = d
+	return d, nil
+}
+
+// GetMultiCurrencyNetting 查询一次多币种净额结算的各币种结果与兑换指令
+func (s *SettlementAppService) GetMultiCurrencyNetting(ctx context.Context, runID string) (*MultiCurrencyNettingResult, error) {
+	results, err := s.nettingRepo.ListByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	result := &MultiCurrencyNettingResult{RunID: runID, Results: results}
+	if s.fxInstructionRepo == nil {
+		return result, nil
+	}
+	if result.FXInstructions, err = s.fxInstructionRepo.ListByRun(ctx, runID); err != nil {
+		return nil, err
+	}
+	if len(result.FXInstructions) > 0 {
+		result.SettlementCurrency = result.FXInstructions[0].SettlementCurrency
+		result.RateSnapshotAt = result.FXInstructions[0].RateSnapshotAt
+	}
+	return result, nil
+}
@@ -0,0 +1,326 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+const inboundPollInterval = 10 * time.Second
+
+// SendInstructionCommand 向托管商发送结算指令报文
+type SendInstructionCommand struct {
+	InstructionID string
+	Standard      domain.MessageStandard // 为空时使用 ISO 20022
+}
+
+// MessagingService 托管商结算报文：由结算指令生成 sese.023 / MT540-543 出站报文，
+// 接收 sese.024 / MT548 状态与 sese.025 / MT544-547 确认并驱动指令状态机。
+type MessagingService struct {
+	repo      domain.SettlementRepository
+	messages  domain.SettlementMessageRepository
+	codec     domain.SettlementMessageCodec
+	gateway   domain.SettlementMessageGateway
+	senderBIC string
+	logger    *slog.Logger
+
+	mu sync.Mutex
+}
+
+// NewMessagingService 创建结算报文服务，senderBIC 为本方 BIC
+func NewMessagingService(
+	repo domain.SettlementRepository,
+	messages domain.SettlementMessageRepository,
+	codec domain.SettlementMessageCodec,
+	gateway domain.SettlementMessageGateway,
+	senderBIC string,
+	logger *slog.Logger,
+) *MessagingService {
+	return &MessagingService{
+		repo:      repo,
+		messages:  messages,
+		codec:     codec,
+		gateway:   gateway,
+		senderBIC: senderBIC,
+		logger:    logger,
+	}
+}
+
+// Start 周期性拉取入站报文，直到 ctx 结束
+func (s *MessagingService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(inboundPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.PollInbound(ctx); err != nil {
+				s.logger.ErrorContext(ctx, "settlement message polling failed", "error", err)
+			}
+		}
+	}
+}
+
+// SendInstruction 为指令每个托管方向生成并发送一条指令报文。
+// 已发送且未被拒绝的方向不再重复生成；此前发送失败的报文重新发送。
+func (s *MessagingService) SendInstruction(ctx context.Context, cmd SendInstructionCommand) ([]*domain.SettlementMessage, error) {
+	standard := cmd.Standard
+	if standard == "" {
+		standard = domain.StandardISO20022
+	}
+	ins, err := s.repo.Get(ctx, cmd.InstructionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instruction: %w", err)
+	}
+	sides := ins.MessageSides()
+	if len(sides) == 0 {
+		return nil, domain.ErrNoCustodian
+	}
+	existing, err := s.messages.ListByInstruction(ctx, ins.InstructionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*domain.SettlementMessage
+	for _, side := range sides {
+		msg := activeOutbound(existing, side)
+		if msg == nil {
+			if msg, err = s.generate(ctx, ins, side, standard); err != nil {
+				return result, err
+			}
+		}
+		if msg.Status == domain.MessageGenerated {
+			if err := s.send(ctx, msg); err != nil {
+				return result, err
+			}
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
+
+func activeOutbound(messages []*domain.SettlementMessage, side domain.MessageSide) *domain.SettlementMessage {
+	for _, m := range messages {
+		if m.Direction == domain.MessageOutbound && m.Side == side && m.Status != domain.MessageRejected {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *MessagingService) generate(ctx context.Context, ins *domain.SettlementInstruction, side domain.MessageSide, standard domain.MessageStandard) (*domain.SettlementMessage, error) {
+	reference := newMessageReference()
+	view := ins.InstructionMessage(side, reference, s.senderBIC)
+	msgType, payload, err := s.codec.Encode(standard, view)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", standard, err)
+	}
+	msg := &domain.SettlementMessage{
+		MessageID:     "OUT-" + reference,
+		Reference:     reference,
+		InstructionID: ins.InstructionID,
+		Direction:     domain.MessageOutbound,
+		Standard:      standard,
+		MessageType:   msgType,
+		Side:          side,
+		Sender:        s.senderBIC,
+		Receiver:      view.Receiver,
+		Payload:       string(payload),
+		Status:        domain.MessageGenerated,
+	}
+	ins.RecordOutboundMessage(msg)
+	if err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.messages.Save(txCtx, msg); err != nil {
+			return err
+		}
+		return s.repo.Update(txCtx, ins)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save settlement message: %w", err)
+	}
+	return msg, nil
+}
+
+func (s *MessagingService) send(ctx context.Context, msg *domain.SettlementMessage) error {
+	if err := s.gateway.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s %s: %w", msg.MessageType, msg.Reference, err)
+	}
+	now := time.Now()
+	msg.Status = domain.MessageSent
+	msg.SentAt = &now
+	if err := s.messages.Update(ctx, msg); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "settlement message sent",
+		"instruction_id", msg.InstructionID,
+		"message_type", msg.MessageType,
+		"reference", msg.Reference,
+		"receiver", msg.Receiver,
+	)
+	return nil
+}
+
+// newMessageReference 生成不超过 16 位的报文引用（MT :20C::SEME 长度限制）
+func newMessageReference() string {
+	return "S" + strings.ToUpper(strconv.FormatUint(idgen.GenID(), 36))
+}
+
+// PollInbound 拉取并处理网关中的入站报文，返回处理成功的条数
+func (s *MessagingService) PollInbound(ctx context.Context) (int, error) {
+	inbound, err := s.gateway.Receive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to receive settlement messages: %w", err)
+	}
+	processed := 0
+	for _, in := range inbound {
+		_, procErr := s.Ingest(ctx, in.Payload)
+		if procErr != nil {
+			s.logger.WarnContext(ctx, "failed to ingest settlement message", "name", in.Name, "error", procErr)
+		} else {
+			processed++
+		}
+		if err := s.gateway.Ack(ctx, in, procErr); err != nil {
+			return processed, fmt.Errorf("failed to ack %s: %w", in.Name, err)
+		}
+	}
+	return processed, nil
+}
+
+// Ingest 解析一条入站状态或确认报文并应用到对应指令；无法解析或无法关联的报文也会留档
+func (s *MessagingService) Ingest(ctx context.Context, payload []byte) (*domain.SettlementMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 无法解析或缺少参考号的报文以报文编号占位，满足 (direction, reference) 唯一约束
+	messageID := "IN-" + strconv.FormatUint(idgen.GenID(), 10)
+	inbound := &domain.SettlementMessage{
+		MessageID:   messageID,
+		Reference:   messageID,
+		Direction:   domain.MessageInbound,
+		MessageType: "UNKNOWN",
+		Receiver:    s.senderBIC,
+		Payload:     string(payload),
+		Status:      domain.MessageProcessed,
+	}
+
+	report, err := s.codec.Decode(payload)
+	if err != nil {
+		return inbound, s.saveFailedInbound(ctx, inbound, err)
+	}
+	if report.Reference != "" {
+		inbound.Reference = report.Reference
+	}
+	inbound.RelatedReference = report.RelatedReference
+	inbound.Standard = report.Standard
+	inbound.MessageType = report.MessageType
+	inbound.Sender = report.Sender
+	inbound.StatusReason = truncate(report.Describe(), 255)
+
+	// 托管商重发的报文按参考号去重，已处理的直接返回，避免部分交收确认重复入账；
+	// 此前处理失败的复用原记录重新处理
+	existing, err := s.messages.GetByReference(ctx, domain.MessageInbound, inbound.Reference)
+	switch {
+	case err == nil && existing.Status == domain.MessageProcessed:
+		s.logger.InfoContext(ctx, "duplicate settlement message ignored",
+			"reference", existing.Reference,
+			"message_id", existing.MessageID,
+		)
+		return existing, nil
+	case err == nil:
+		inbound.Model = existing.Model
+		inbound.MessageID = existing.MessageID
+	case !errors.Is(err, domain.ErrMessageNotFound):
+		return inbound, fmt.Errorf("failed to check message reference: %w", err)
+	}
+
+	original, err := s.messages.GetOutboundByReference(ctx, report.RelatedReference)
+	if err != nil {
+		return inbound, s.saveFailedInbound(ctx, inbound, fmt.Errorf("%w: %s", err, report.RelatedReference))
+	}
+	inbound.InstructionID = original.InstructionID
+	inbound.Side = original.Side
+
+	ins, err := s.repo.Get(ctx, original.InstructionID)
+	if err != nil {
+		return inbound, s.saveFailedInbound(ctx, inbound, fmt.Errorf("failed to get instruction: %w", err))
+	}
+	messages, err := s.messages.ListByInstruction(ctx, original.InstructionID)
+	if err != nil {
+		return inbound, err
+	}
+	sides := make([]*domain.SettlementMessage, 0, len(messages))
+	for _, m := range messages {
+		switch {
+		case m.ID == original.ID:
+			sides = append(sides, original)
+		case m.Direction == domain.MessageOutbound && m.Status != domain.MessageRejected:
+			sides = append(sides, m)
+		}
+	}
+
+	if err := ins.ApplyStatusReport(original, sides, report); err != nil {
+		return inbound, s.saveFailedInbound(ctx, inbound, err)
+	}
+	now := time.Now()
+	inbound.ProcessedAt = &now
+
+	if err := s.repo.WithTx(ctx, func(txCtx context.Context) error {
+		if err := s.saveInbound(txCtx, inbound); err != nil {
+			return err
+		}
+		if err := s.messages.Update(txCtx, original); err != nil {
+			return err
+		}
+		return s.repo.Update(txCtx, ins)
+	}); err != nil {
+		return inbound, fmt.Errorf("failed to apply settlement message: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "settlement message ingested",
+		"instruction_id", ins.InstructionID,
+		"message_type", report.MessageType,
+		"status", report.Status,
+		"reason", report.ReasonCode,
+		"instruction_status", ins.Status.String(),
+	)
+	return inbound, nil
+}
+
+func (s *MessagingService) saveFailedInbound(ctx context.Context, inbound *domain.SettlementMessage, cause error) error {
+	now := time.Now()
+	inbound.Status = domain.MessageFailed
+	inbound.StatusReason = truncate(cause.Error(), 255)
+	inbound.ProcessedAt = &now
+	if err := s.saveInbound(ctx, inbound); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// saveInbound 新报文插入，重新处理的失败报文更新原记录
+func (s *MessagingService) saveInbound(ctx context.Context, inbound *domain.SettlementMessage) error {
+	if inbound.ID != 0 {
+		return s.messages.Update(ctx, inbound)
+	}
+	return s.messages.Save(ctx, inbound)
+}
+
+// ListMessages 查询指令的全部往来报文
+func (s *MessagingService) ListMessages(ctx context.Context, instructionID string) ([]*domain.SettlementMessage, error) {
+	return s.messages.ListByInstruction(ctx, instructionID)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -17,6 +17,11 @@ type SettlementAppService struct {
 	fxRateRepo    domain.FXRateRepository
 	domainSvc     *domain.SettlementDomainService
 	logger        *slog.Logger
+
+	calendar      domain.SettlementCalendar
+	defaultMarket string
+
+	fxInstructionRepo domain.FXInstructionRepository
 }
 
 func NewSettlementAppService(
@@ -37,6 +42,13 @@ func NewSettlementAppService(
 	}
 }
 
+// SetCalendar 设置结算日历，设置后按市场与币种工作日计算 T+N 结算日，
+// 未设置或查询失败时按自然日计算
+func (s *SettlementAppService) SetCalendar(calendar domain.SettlementCalendar, defaultMarket string) {
+	s.calendar = calendar
+	s.defaultMarket = defaultMarket
+}
+
 type CreateInstructionCommand struct {
 	TradeID        string
 	OrderID        string
@@ -51,6 +63,7 @@ type CreateInstructionCommand struct {
 	CycleDays      int
 	CCPFlag        bool
 	CCPAccount     string
+	Market         string // 市场日历代码，为空时使用默认市场
 }
 
 func (s *SettlementAppService) CreateInstruction(ctx context.Context, cmd CreateInstructionCommand) (*domain.SettlementInstruction, error) {
@@ -80,6 +93,7 @@ func (s *SettlementAppService) CreateInstruction(ctx context.Context, cmd Create
 	if cmd.CCPFlag {
 		instruction.SetCCP(cmd.CCPAccount)
 	}
+	s.applyCalendar(ctx, instruction, cmd.Market, cmd.CycleDays)
 	
 	if err := s.repo.Save(ctx, instruction); err != nil {
 		return nil, fmt.Errorf("failed to save instruction: %w", err)
@@ -94,6 +108,28 @@ func (s *SettlementAppService) CreateInstruction(ctx context.Context, cmd Create
 	return instruction, nil
 }
 
+// applyCalendar 按结算日历确定预定结算日与资金起息日
+func (s *SettlementAppService) applyCalendar(ctx context.Context, instruction *domain.SettlementInstruction, market string, days int) {
+	if s.calendar == nil {
+		return
+	}
+	if market == "" {
+		market = s.defaultMarket
+	}
+	date, err := s.calendar.SettlementDate(ctx, market, instruction.Currency, instruction.TradeDate, days)
+	if err != nil {
+		s.logger.WarnContext(ctx, "settlement calendar unavailable, using calendar days",
+			"trade_id", instruction.TradeID,
+			"market", market,
+			"currency", instruction.Currency,
+			"error", err,
+		)
+		return
+	}
+	instruction.SettlementDate = date
+	instruction.ValueDate = date
+}
+
 type SetCustodianCommand struct {
 	InstructionID    string
 	BuyerCustodian   string
@@ -141,7 +177,7 @@ func (s *SettlementAppService) ProcessSettlement(ctx context.Context, cmd Proces
 		if err := instruction.StartProcessing(cmd.BatchID); err != nil {
 			return err
 		}
-	} else if instruction.Status == domain.SettlementStatusPending {
+	} else if instruction.Status == domain.SettlementStatusPending || instruction.IsOpenFail() {
 		if err := instruction.StartProcessing(cmd.BatchID); err != nil {
 			return err
 		}
@@ -149,9 +185,7 @@ func (s *SettlementAppService) ProcessSettlement(ctx context.Context, cmd Proces
 	
 	if s.domainSvc != nil {
 		if err := s.domainSvc.ValidateBalance(ctx, instruction); err != nil {
-			instruction.Fail(fmt.Sprintf("balance validation failed: %v", err))
-			_ = s.repo.Update(ctx, instruction)
-			return err
+			return s.handleShortfall(ctx, instruction, err)
 		}
 		
 		if instruction.SettlementType == domain.SettlementTypeDVP {
@@ -179,6 +213,41 @@ func (s *SettlementAppService) ProcessSettlement(ctx context.Context, cmd Proces
 	return nil
 }
 
+// handleShortfall 余额不足时尽可能部分交收，剩余部分按失败处理并记录责任方
+func (s *SettlementAppService) handleShortfall(ctx context.Context, instruction *domain.SettlementInstruction, cause error) error {
+	party := domain.FailingPartyOf(cause)
+	reason := fmt.Sprintf("balance validation failed: %v", cause)
+
+	if party != "" && instruction.PartialAllowed && instruction.SettlementType == domain.SettlementTypeDVP {
+		qty, err := s.domainSvc.PartialQuantity(ctx, instruction)
+		if err == nil && qty.IsPositive() {
+			if err := s.domainSvc.ExecutePartialDVP(ctx, instruction, qty); err != nil {
+				s.logger.WarnContext(ctx, "partial settlement failed", "instruction_id", instruction.InstructionID, "error", err)
+			} else {
+				if err := instruction.PartialSettle(qty, party, reason); err != nil {
+					return err
+				}
+				if err := s.repo.Update(ctx, instruction); err != nil {
+					return fmt.Errorf("failed to update instruction: %w", err)
+				}
+				s.logger.InfoContext(ctx, "settlement partially settled",
+					"instruction_id", instruction.InstructionID,
+					"settled_quantity", instruction.SettledQuantity,
+					"remaining_quantity", instruction.RemainingQuantity(),
+				)
+				if instruction.IsSettled() {
+					return nil
+				}
+				return fmt.Errorf("%w: %v", domain.ErrPartiallySettled, cause)
+			}
+		}
+	}
+
+	_ = instruction.FailBy(reason, party)
+	_ = s.repo.Update(ctx, instruction)
+	return cause
+}
+
 type RetrySettlementCommand struct {
 	InstructionID string
 }
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SettlementCalendar 结算日历：由参考数据服务按交易所与币种节假日计算工作日
+type SettlementCalendar interface {
+	// SettlementDate 证券 T+N 预定结算日，按市场与结算币种的联合工作日计数
+	SettlementDate(ctx context.Context, market, currency string, tradeDate time.Time, days int) (time.Time, error)
+	// FXValueDate 外汇起息日，days 小于等于 0 时按货币对即期天数
+	FXValueDate(ctx context.Context, baseCurrency, quoteCurrency string, tradeDate time.Time, days int) (time.Time, error)
+	// BusinessCalendar 市场与结算币种的联合工作日日历，加载 [from, to] 区间内的节假日
+	BusinessCalendar(ctx context.Context, market, currency string, from, to time.Time) (BusinessCalendar, error)
+}
+
+// BusinessCalendar 工作日判断，结算失败账龄、罚金计提与强制买入延展期均按其计数
+type BusinessCalendar interface {
+	IsBusinessDay(d time.Time) bool
+}
+
+// WeekdayCalendar 仅排除周六、周日，结算日历未配置或不可用时使用
+type WeekdayCalendar struct{}
+
+func (WeekdayCalendar) IsBusinessDay(d time.Time) bool {
+	return d.Weekday() != time.Saturday && d.Weekday() != time.Sunday
+}
+
+// AddBusinessDays 从 d 起向后推 n 个工作日
+func AddBusinessDays(cal BusinessCalendar, d time.Time, n int) time.Time {
+	d = truncateDay(d)
+	for n > 0 {
+		d = d.AddDate(0, 0, 1)
+		if cal.IsBusinessDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// BusinessDaysBetween 统计 (from, to] 区间内的工作日数量
+func BusinessDaysBetween(cal BusinessCalendar, from, to time.Time) int {
+	from = truncateDay(from)
+	to = truncateDay(to)
+	days := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if cal.IsBusinessDay(d) {
+			days++
+		}
+	}
+	return days
+}
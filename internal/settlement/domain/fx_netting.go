@@ -0,0 +1,246 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrFXRateUnavailable 快照时点找不到可用汇率（正向与反向均无）
+var ErrFXRateUnavailable = errors.New("fx rate unavailable")
+
+// FX 兑换方向：会员对被兑换币种的买卖
+const (
+	FXSideBuy  = "BUY"  // 净应付币种：会员买入该币种、支付结算币种
+	FXSideSell = "SELL" // 净应收币种：会员卖出该币种、收取结算币种
+)
+
+// NettingResult 状态
+const (
+	NettingStatusCompleted = "COMPLETED"
+	// NettingStatusConverted 该币种净额已兑换为结算币种
+	NettingStatusConverted = "CONVERTED"
+)
+
+var bpsDivisor = decimal.NewFromInt(10000)
+
+// CurrencyPosition 会员在单一币种上的应付与应收
+type CurrencyPosition struct {
+	AccountID      string
+	Currency       string
+	Payable        decimal.Decimal
+	Receivable     decimal.Decimal
+	InstructionIDs []string
+}
+
+// Net 净额，正数为净应付、负数为净应收（与 PerformNetting 的买减卖口径一致）
+func (p *CurrencyPosition) Net() decimal.Decimal {
+	return p.Payable.Sub(p.Receivable)
+}
+
+// Gross 总额
+func (p *CurrencyPosition) Gross() decimal.Decimal {
+	return p.Payable.Add(p.Receivable)
+}
+
+// MultiCurrencyNetting 多边多币种净额：每笔指令计入买方应付与卖方应收，按会员、币种汇总
+type MultiCurrencyNetting struct {
+	positions map[string]map[string]*CurrencyPosition
+}
+
+// NewMultiCurrencyNetting 创建多币种净额汇总
+func NewMultiCurrencyNetting() *MultiCurrencyNetting {
+	return &MultiCurrencyNetting{positions: make(map[string]map[string]*CurrencyPosition)}
+}
+
+// Add 计入一笔结算指令的资金腿
+func (n *MultiCurrencyNetting) Add(ins *SettlementInstruction) {
+	buyer := n.position(ins.BuyerAccountID, ins.Currency)
+	buyer.Payable = buyer.Payable.Add(ins.Amount)
+	buyer.InstructionIDs = append(buyer.InstructionIDs, ins.InstructionID)
+
+	seller := n.position(ins.SellerAccountID, ins.Currency)
+	seller.Receivable = seller.Receivable.Add(ins.Amount)
+	seller.InstructionIDs = append(seller.InstructionIDs, ins.InstructionID)
+}
+
+func (n *MultiCurrencyNetting) position(accountID, currency string) *CurrencyPosition {
+	byCurrency, ok := n.positions[accountID]
+	if !ok {
+		byCurrency = make(map[string]*CurrencyPosition)
+		n.positions[accountID] = byCurrency
+	}
+	p, ok := byCurrency[currency]
+	if !ok {
+		p = &CurrencyPosition{AccountID: accountID, Currency: currency}
+		byCurrency[currency] = p
+	}
+	return p
+}
+
+// Members 按会员编号排序
+func (n *MultiCurrencyNetting) Members() []string {
+	members := make([]string, 0, len(n.positions))
+	for m := range n.positions {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Positions 会员各币种头寸，按币种排序
+func (n *MultiCurrencyNetting) Positions(accountID string) []*CurrencyPosition {
+	byCurrency := n.positions[accountID]
+	list := make([]*CurrencyPosition, 0, len(byCurrency))
+	for _, p := range byCurrency {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return list
+}
+
+// FXQuote 快照中 From→To 的报价，反向报价已换算为正向
+type FXQuote struct {
+	From        string
+	To          string
+	Mid         decimal.Decimal
+	Bid         decimal.Decimal
+	Ask         decimal.Decimal
+	Source      string
+	RateID      uint
+	Inverted    bool
+	EffectiveAt time.Time
+}
+
+// NewFXQuote 由汇率记录生成正向报价
+func NewFXQuote(rate *FXRate) *FXQuote {
+	return &FXQuote{
+		From:        rate.FromCurrency,
+		To:          rate.ToCurrency,
+		Mid:         rate.Rate,
+		Bid:         rate.BidRate,
+		Ask:         rate.AskRate,
+		Source:      rate.Source,
+		RateID:      rate.ID,
+		EffectiveAt: rate.EffectiveAt,
+	}
+}
+
+// NewInvertedFXQuote 由 To→From 的汇率记录换算 From→To 报价：中间价取倒数，买卖价互换后取倒数
+func NewInvertedFXQuote(rate *FXRate) (*FXQuote, error) {
+	if !rate.Rate.IsPositive() {
+		return nil, fmt.Errorf("%w: non-positive rate %s/%s", ErrFXRateUnavailable, rate.FromCurrency, rate.ToCurrency)
+	}
+	q := &FXQuote{
+		From:        rate.ToCurrency,
+		To:          rate.FromCurrency,
+		Mid:         decimal.NewFromInt(1).Div(rate.Rate),
+		Source:      rate.Source,
+		RateID:      rate.ID,
+		Inverted:    true,
+		EffectiveAt: rate.EffectiveAt,
+	}
+	if rate.AskRate.IsPositive() {
+		q.Bid = decimal.NewFromInt(1).Div(rate.AskRate)
+	}
+	if rate.BidRate.IsPositive() {
+		q.Ask = decimal.NewFromInt(1).Div(rate.BidRate)
+	}
+	return q, nil
+}
+
+// Price 会员兑换价（1 单位 From 折合多少 To）：会员买入 From 用卖出价加点差，
+// 卖出 From 用买入价减点差；无买卖价时以中间价为基准
+func (q *FXQuote) Price(side string, spreadBps decimal.Decimal) decimal.Decimal {
+	spread := spreadBps.Div(bpsDivisor)
+	if side == FXSideBuy {
+		base := q.Mid
+		if q.Ask.IsPositive() {
+			base = q.Ask
+		}
+		return base.Mul(decimal.NewFromInt(1).Add(spread)).Round(8)
+	}
+	base := q.Mid
+	if q.Bid.IsPositive() {
+		base = q.Bid
+	}
+	return base.Mul(decimal.NewFromInt(1).Sub(spread)).Round(8)
+}
+
+// FXInstruction 净额兑换指令：把会员某币种的剩余净额按快照汇率兑换为结算币种，
+// 同时留存所用汇率的来源、记录与时点供审计
+type FXInstruction struct {
+	gorm.Model
+	FXInstructionID    string          `gorm:"column:fx_instruction_id;type:varchar(64);uniqueIndex;not null" json:"fx_instruction_id"`
+	NettingRunID       string          `gorm:"column:netting_run_id;type:varchar(64);index;not null" json:"netting_run_id"`
+	AccountID          string          `gorm:"column:account_id;type:varchar(64);index;not null" json:"account_id"`
+	Side               string          `gorm:"column:side;type:varchar(4);not null" json:"side"`
+	Currency           string          `gorm:"column:currency;type:varchar(3);not null" json:"currency"`
+	Amount             decimal.Decimal `gorm:"column:amount;type:decimal(20,2);not null" json:"amount"`
+	SettlementCurrency string          `gorm:"column:settlement_currency;type:varchar(3);not null" json:"settlement_currency"`
+	SettlementAmount   decimal.Decimal `gorm:"column:settlement_amount;type:decimal(20,2);not null" json:"settlement_amount"`
+	MidRate            decimal.Decimal `gorm:"column:mid_rate;type:decimal(18,8);not null" json:"mid_rate"`
+	AppliedRate        decimal.Decimal `gorm:"column:applied_rate;type:decimal(18,8);not null" json:"applied_rate"`
+	SpreadBps          decimal.Decimal `gorm:"column:spread_bps;type:decimal(10,4)" json:"spread_bps"`
+	RateSource         string          `gorm:"column:rate_source;type:varchar(32)" json:"rate_source"`
+	RateID             uint            `gorm:"column:rate_id" json:"rate_id"`
+	RateInverted       bool            `gorm:"column:rate_inverted" json:"rate_inverted"`
+	RateEffectiveAt    time.Time       `gorm:"column:rate_effective_at" json:"rate_effective_at"`
+	RateSnapshotAt     time.Time       `gorm:"column:rate_snapshot_at;not null" json:"rate_snapshot_at"`
+	ValueDate          time.Time       `gorm:"column:value_date;not null" json:"value_date"`
+	Status             string          `gorm:"column:status;type:varchar(16);not null" json:"status"`
+}
+
+// TableName 表名
+func (FXInstruction) TableName() string {
+	return "settlement_fx_instructions"
+}
+
+// NewFXInstruction 为会员某币种的剩余净额生成兑换指令，quote 须为该币种→结算币种的报价
+func NewFXInstruction(id, runID string, pos *CurrencyPosition, quote *FXQuote, spreadBps decimal.Decimal, snapshotAt, valueDate time.Time) *FXInstruction {
+	side := FXSideSell
+	if pos.Net().IsPositive() {
+		side = FXSideBuy
+	}
+	amount := pos.Net().Abs()
+	applied := quote.Price(side, spreadBps)
+	return &FXInstruction{
+		FXInstructionID:    id,
+		NettingRunID:       runID,
+		AccountID:          pos.AccountID,
+		Side:               side,
+		Currency:           pos.Currency,
+		Amount:             amount,
+		SettlementCurrency: quote.To,
+		SettlementAmount:   amount.Mul(applied).Round(2),
+		MidRate:            quote.Mid.Round(8),
+		AppliedRate:        applied,
+		SpreadBps:          spreadBps,
+		RateSource:         quote.Source,
+		RateID:             quote.RateID,
+		RateInverted:       quote.Inverted,
+		RateEffectiveAt:    quote.EffectiveAt,
+		RateSnapshotAt:     snapshotAt,
+		ValueDate:          valueDate,
+		Status:             "PENDING",
+	}
+}
+
+// SignedSettlementAmount 兑换对结算币种净额的影响，口径同 CurrencyPosition.Net
+func (f *FXInstruction) SignedSettlementAmount() decimal.Decimal {
+	if f.Side == FXSideBuy {
+		return f.SettlementAmount
+	}
+	return f.SettlementAmount.Neg()
+}
+
+// FXInstructionRepository 兑换指令仓储
+type FXInstructionRepository interface {
+	Save(ctx context.Context, instructions []*FXInstruction) error
+	ListByRun(ctx context.Context, runID string) ([]*FXInstruction, error)
+}
@@ -22,6 +22,8 @@ const (
 	SettlementStatusSettled    SettlementStatus = 5 // 已交收
 	SettlementStatusFailed     SettlementStatus = 6 // 失败
 	SettlementStatusCancelled  SettlementStatus = 7 // 已取消
+	SettlementStatusPartial    SettlementStatus = 8 // 部分交收
+	SettlementStatusBoughtIn   SettlementStatus = 9 // 已强制买入了结
 )
 
 func (s SettlementStatus) String() string {
@@ -40,6 +42,10 @@ func (s SettlementStatus) String() string {
 		return "FAILED"
 	case SettlementStatusCancelled:
 		return "CANCELLED"
+	case SettlementStatusPartial:
+		return "PARTIALLY_SETTLED"
+	case SettlementStatusBoughtIn:
+		return "BOUGHT_IN"
 	default:
 		return "UNKNOWN"
 	}
@@ -100,6 +106,15 @@ type SettlementInstruction struct {
 	RetryCount       int              `gorm:"column:retry_count;default:0" json:"retry_count"`
 	MaxRetry         int              `gorm:"column:max_retry;default:3" json:"max_retry"`
 	
+	PartialAllowed   bool             `gorm:"column:partial_allowed;default:true" json:"partial_allowed"`
+	SettledQuantity  decimal.Decimal  `gorm:"column:settled_quantity;type:decimal(20,4)" json:"settled_quantity"`
+	SettledAmount    decimal.Decimal  `gorm:"column:settled_amount;type:decimal(20,2)" json:"settled_amount"`
+	FailingParty     FailingParty     `gorm:"column:failing_party;type:varchar(16)" json:"failing_party"`
+	FailedSince      *time.Time       `gorm:"column:failed_since;index" json:"failed_since"`
+	PenaltyAccrued   decimal.Decimal  `gorm:"column:penalty_accrued;type:decimal(20,2)" json:"penalty_accrued"`
+	LastPenaltyDate  *time.Time       `gorm:"column:last_penalty_date" json:"last_penalty_date"`
+	BuyInID          string           `gorm:"column:buy_in_id;type:varchar(64)" json:"buy_in_id"`
+	
 	CCPFlag          bool             `gorm:"column:ccp_flag;default:false" json:"ccp_flag"`
 	CCPAccount       string           `gorm:"column:ccp_account;type:varchar(64)" json:"ccp_account"`
 	
@@ -140,12 +155,15 @@ func (SettlementEvent) TableName() string {
 type NettingResult struct {
 	gorm.Model
 	NettingID      string          `gorm:"column:netting_id;type:varchar(64);uniqueIndex;not null" json:"netting_id"`
+	RunID          string          `gorm:"column:netting_run_id;type:varchar(64);index" json:"netting_run_id"`
 	AccountID      string          `gorm:"column:account_id;type:varchar(64);index;not null" json:"account_id"`
 	Currency       string          `gorm:"column:currency;type:varchar(3);not null" json:"currency"`
 	Symbol         string          `gorm:"column:symbol;type:varchar(32)" json:"symbol"`
 	GrossAmount    decimal.Decimal `gorm:"column:gross_amount;type:decimal(20,2)" json:"gross_amount"`
 	NetAmount      decimal.Decimal `gorm:"column:net_amount;type:decimal(20,2)" json:"net_amount"`
 	NetQuantity    decimal.Decimal `gorm:"column:net_quantity;type:decimal(20,4)" json:"net_quantity"`
+	// ConvertedAmount 由其他币种兑换计入本币种净额的部分（仅结算币种）
+	ConvertedAmount decimal.Decimal `gorm:"column:converted_amount;type:decimal(20,2)" json:"converted_amount"`
 	InstructionIDs string          `gorm:"column:instruction_ids;type:text" json:"instruction_ids"`
 	Status         string          `gorm:"column:status;type:varchar(32)" json:"status"`
 	CreatedAt      time.Time       `gorm:"column:created_at" json:"created_at"`
@@ -222,6 +240,7 @@ func NewSettlementInstruction(
 		SettlementDate: settlementDate,
 		Status:         SettlementStatusPending,
 		MaxRetry:       3,
+		PartialAllowed: true,
 		Events:         []SettlementEvent{},
 	}
 }
@@ -261,9 +280,9 @@ func (s *SettlementInstruction) CompleteNetting() error {
 	return nil
 }
 
-// StartProcessing 开始处理
+// StartProcessing 开始处理；失败与部分交收的指令可再次进入处理（日间循环重试）
 func (s *SettlementInstruction) StartProcessing(batchID string) error {
-	if s.Status != SettlementStatusCleared && s.Status != SettlementStatusPending {
+	if s.Status != SettlementStatusCleared && s.Status != SettlementStatusPending && !s.IsOpenFail() {
 		return errors.New("invalid status for processing")
 	}
 	s.Status = SettlementStatusProcessing
@@ -287,6 +306,8 @@ func (s *SettlementInstruction) Settle() error {
 	}
 	now := time.Now()
 	s.Status = SettlementStatusSettled
+	s.SettledQuantity = s.Quantity
+	s.SettledAmount = s.Amount
 	s.SettledAt = &now
 	s.addEvent("SETTLED", "结算完成", "SUCCESS")
 	return nil
@@ -294,8 +315,24 @@ func (s *SettlementInstruction) Settle() error {
 
 // Fail 结算失败
 func (s *SettlementInstruction) Fail(reason string) error {
-	s.Status = SettlementStatusFailed
+	return s.FailBy(reason, "")
+}
+
+// FailBy 结算失败并记录责任方，首次失败时记录失败起始时间用于罚金与账龄计算
+func (s *SettlementInstruction) FailBy(reason string, party FailingParty) error {
+	if s.SettledQuantity.IsPositive() {
+		s.Status = SettlementStatusPartial
+	} else {
+		s.Status = SettlementStatusFailed
+	}
 	s.FailReason = reason
+	if party != "" {
+		s.FailingParty = party
+	}
+	if s.FailedSince == nil {
+		now := time.Now()
+		s.FailedSince = &now
+	}
 	s.addEvent("FAILED", reason, "FAILED")
 	return nil
 }
@@ -366,6 +403,7 @@ type NettingRepository interface {
 	Save(ctx context.Context, result *NettingResult) error
 	Get(ctx context.Context, nettingID string) (*NettingResult, error)
 	GetByAccountAndCurrency(ctx context.Context, accountID, currency string) (*NettingResult, error)
+	ListByRun(ctx context.Context, runID string) ([]*NettingResult, error)
 }
 
 // BatchRepository 批次仓储接口
@@ -378,6 +416,8 @@ type BatchRepository interface {
 // FXRateRepository 汇率仓储接口
 type FXRateRepository interface {
 	GetRate(ctx context.Context, fromCurrency, toCurrency string) (*FXRate, error)
+	// GetRateAt 返回 at 时点有效的最新汇率
+	GetRateAt(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*FXRate, error)
 	SaveRate(ctx context.Context, rate *FXRate) error
 }
 
@@ -441,6 +481,21 @@ func (s *SettlementDomainService) ExecuteDVP(ctx context.Context, instruction *S
 		return nil
 	}
 
+	return s.deliver(ctx, instruction, instruction.RemainingQuantity(), instruction.RemainingAmount())
+}
+
+// ExecutePartialDVP 按部分数量执行券款对付
+func (s *SettlementDomainService) ExecutePartialDVP(ctx context.Context, instruction *SettlementInstruction, quantity decimal.Decimal) error {
+	if instruction.SettlementType != SettlementTypeDVP {
+		return errors.New("not a DVP instruction")
+	}
+	if s.custodianSvc == nil {
+		return nil
+	}
+	return s.deliver(ctx, instruction, quantity, quantity.Mul(instruction.Price).Round(2))
+}
+
+func (s *SettlementDomainService) deliver(ctx context.Context, instruction *SettlementInstruction, quantity, amount decimal.Decimal) error {
 	sellerAccount := instruction.SellerAccountID
 	buyerAccount := instruction.BuyerAccountID
 	if instruction.CCPFlag && instruction.CCPAccount != "" {
@@ -448,18 +503,50 @@ func (s *SettlementDomainService) ExecuteDVP(ctx context.Context, instruction *S
 		buyerAccount = instruction.CCPAccount
 	}
 
-	if err := s.custodianSvc.TransferSecurity(ctx, sellerAccount, buyerAccount, instruction.Symbol, instruction.Quantity); err != nil {
+	if err := s.custodianSvc.TransferSecurity(ctx, sellerAccount, buyerAccount, instruction.Symbol, quantity); err != nil {
 		return fmt.Errorf("security transfer failed: %w", err)
 	}
 
-	if err := s.custodianSvc.TransferCash(ctx, buyerAccount, sellerAccount, instruction.Amount, instruction.Currency); err != nil {
-		_ = s.custodianSvc.TransferSecurity(ctx, buyerAccount, sellerAccount, instruction.Symbol, instruction.Quantity)
+	if err := s.custodianSvc.TransferCash(ctx, buyerAccount, sellerAccount, amount, instruction.Currency); err != nil {
+		_ = s.custodianSvc.TransferSecurity(ctx, buyerAccount, sellerAccount, instruction.Symbol, quantity)
 		return fmt.Errorf("cash transfer failed: %w", err)
 	}
 
 	return nil
 }
 
+// PartialQuantity 计算当前可部分交收的整数数量：卖方可交付证券与买方可支付资金的较小者
+func (s *SettlementDomainService) PartialQuantity(ctx context.Context, instruction *SettlementInstruction) (decimal.Decimal, error) {
+	remaining := instruction.RemainingQuantity()
+	if s.custodianSvc == nil {
+		return remaining, nil
+	}
+	position, err := s.custodianSvc.GetSecurityPosition(ctx, instruction.SellerAccountID, instruction.Symbol)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get seller security position: %w", err)
+	}
+	qty := decimal.Min(position, remaining)
+	if instruction.Price.IsPositive() {
+		cash, err := s.custodianSvc.GetAccountBalance(ctx, instruction.BuyerAccountID, instruction.Currency)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("failed to get buyer cash balance: %w", err)
+		}
+		qty = decimal.Min(qty, cash.Div(instruction.Price))
+	}
+	return decimal.Max(qty.Floor(), decimal.Zero), nil
+}
+
+// ChargeBuyInDifference 向失败卖方收取强制买入差额并支付给原买方
+func (s *SettlementDomainService) ChargeBuyInDifference(ctx context.Context, buyIn *BuyIn) error {
+	if s.custodianSvc == nil || !buyIn.ChargeAmount.IsPositive() {
+		return nil
+	}
+	if err := s.custodianSvc.TransferCash(ctx, buyIn.FailingAccountID, buyIn.ReceivingAccountID, buyIn.ChargeAmount, buyIn.Currency); err != nil {
+		return fmt.Errorf("buy-in difference transfer failed: %w", err)
+	}
+	return nil
+}
+
 // ValidateBalance 验证余额
 func (s *SettlementDomainService) ValidateBalance(ctx context.Context, instruction *SettlementInstruction) error {
 	if s.custodianSvc == nil {
@@ -470,16 +557,16 @@ func (s *SettlementDomainService) ValidateBalance(ctx context.Context, instructi
 	if err != nil {
 		return fmt.Errorf("failed to get buyer cash balance: %w", err)
 	}
-	if cashBalance.LessThan(instruction.Amount) {
-		return errors.New("insufficient cash balance")
+	if cashBalance.LessThan(instruction.RemainingAmount()) {
+		return ErrInsufficientCash
 	}
 
 	securityPosition, err := s.custodianSvc.GetSecurityPosition(ctx, instruction.SellerAccountID, instruction.Symbol)
 	if err != nil {
 		return fmt.Errorf("failed to get seller security position: %w", err)
 	}
-	if securityPosition.LessThan(instruction.Quantity) {
-		return errors.New("insufficient security position")
+	if securityPosition.LessThan(instruction.RemainingQuantity()) {
+		return ErrInsufficientSecurities
 	}
 
 	return nil
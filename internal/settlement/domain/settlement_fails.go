@@ -0,0 +1,463 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInsufficientCash 买方资金不足
+	ErrInsufficientCash = errors.New("insufficient cash balance")
+	// ErrInsufficientSecurities 卖方证券不足
+	ErrInsufficientSecurities = errors.New("insufficient security position")
+	// ErrPartiallySettled 指令仅部分交收
+	ErrPartiallySettled = errors.New("instruction partially settled")
+	// ErrBuyInNotFound 强制买入记录不存在
+	ErrBuyInNotFound = errors.New("buy-in not found")
+)
+
+// FailingParty 结算失败责任方
+type FailingParty string
+
+const (
+	FailingPartyBuyer  FailingParty = "BUYER"  // 资金不足
+	FailingPartySeller FailingParty = "SELLER" // 证券不足
+)
+
+// FailingPartyOf 根据失败原因判断责任方，无法归责时返回空
+func FailingPartyOf(err error) FailingParty {
+	switch {
+	case errors.Is(err, ErrInsufficientSecurities):
+		return FailingPartySeller
+	case errors.Is(err, ErrInsufficientCash):
+		return FailingPartyBuyer
+	default:
+		return ""
+	}
+}
+
+// RemainingQuantity 尚未交收的数量
+func (s *SettlementInstruction) RemainingQuantity() decimal.Decimal {
+	return s.Quantity.Sub(s.SettledQuantity)
+}
+
+// RemainingAmount 尚未交收的金额
+func (s *SettlementInstruction) RemainingAmount() decimal.Decimal {
+	return s.Amount.Sub(s.SettledAmount)
+}
+
+// IsOpenFail 是否为尚未了结的失败指令（失败或部分交收）
+func (s *SettlementInstruction) IsOpenFail() bool {
+	return s.Status == SettlementStatusFailed || s.Status == SettlementStatusPartial
+}
+
+// FailingAccountID 责任方账户
+func (s *SettlementInstruction) FailingAccountID() string {
+	if s.FailingParty == FailingPartyBuyer {
+		return s.BuyerAccountID
+	}
+	return s.SellerAccountID
+}
+
+// ReceivingAccountID 受损方账户
+func (s *SettlementInstruction) ReceivingAccountID() string {
+	if s.FailingParty == FailingPartyBuyer {
+		return s.SellerAccountID
+	}
+	return s.BuyerAccountID
+}
+
+// PartialSettle 部分交收：累计已交收数量与金额，余量仍按失败处理直至全部交收或被强制买入
+func (s *SettlementInstruction) PartialSettle(quantity decimal.Decimal, party FailingParty, reason string) error {
+	if s.Status != SettlementStatusProcessing {
+		return errors.New("invalid status for partial settle")
+	}
+	if !quantity.IsPositive() || quantity.GreaterThan(s.RemainingQuantity()) {
+		return fmt.Errorf("invalid partial quantity %s", quantity)
+	}
+	s.SettledQuantity = s.SettledQuantity.Add(quantity)
+	s.SettledAmount = s.SettledAmount.Add(quantity.Mul(s.Price).Round(2))
+	s.addEvent("PARTIAL_SETTLED", fmt.Sprintf("部分交收 %s，剩余 %s", quantity, s.RemainingQuantity()), "PARTIAL")
+
+	if !s.RemainingQuantity().IsPositive() {
+		now := time.Now()
+		s.Status = SettlementStatusSettled
+		s.SettledAmount = s.Amount
+		s.SettledAt = &now
+		s.addEvent("SETTLED", "结算完成", "SUCCESS")
+		return nil
+	}
+	return s.FailBy(reason, party)
+}
+
+// BusinessDaysFailed 自预定结算日起至 asOf 已失败的工作日数
+func (s *SettlementInstruction) BusinessDaysFailed(cal BusinessCalendar, asOf time.Time) int {
+	return BusinessDaysBetween(cal, s.SettlementDate, asOf)
+}
+
+// MarkBuyInInitiated 记录已发起强制买入
+func (s *SettlementInstruction) MarkBuyInInitiated(buyInID string) {
+	s.BuyInID = buyInID
+	s.addEvent("BUY_IN_INITIATED", fmt.Sprintf("发起强制买入 %s", buyInID), "PROCESSING")
+}
+
+// MarkBoughtIn 剩余数量已通过强制买入了结
+func (s *SettlementInstruction) MarkBoughtIn() error {
+	if !s.IsOpenFail() {
+		return errors.New("invalid status for buy-in")
+	}
+	s.Status = SettlementStatusBoughtIn
+	s.addEvent("BOUGHT_IN", fmt.Sprintf("强制买入 %s 完成", s.BuyInID), "SUCCESS")
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// FailsPolicy 结算失败处理参数（参照 CSDR 结算纪律制度）
+type FailsPolicy struct {
+	// SecuritiesPenaltyRates 证券不足的日罚金费率，按证券类型配置，作用于未交收证券市值
+	SecuritiesPenaltyRates map[string]decimal.Decimal
+	DefaultSecuritiesRate  decimal.Decimal
+	// CashPenaltyRate 资金不足的年化罚息率，按日计提，作用于未交收金额
+	CashPenaltyRate decimal.Decimal
+	// BuyInExtensionDays 失败超过该工作日数后发起强制买入，按证券类型配置
+	BuyInExtensionDays  map[string]int
+	DefaultExtensionDay int
+	// BuyInAgentAccount 强制买入代理账户，作为替代交易的卖方
+	BuyInAgentAccount string
+	// BuyInSettlementDays 替代交易的结算周期
+	BuyInSettlementDays int
+}
+
+// DefaultFailsPolicy 默认参数：流动性股票 1bp、债券 0.2bp，资金罚息 4%，延展期 4 个工作日（非流动性品种 7 个）
+func DefaultFailsPolicy() *FailsPolicy {
+	return &FailsPolicy{
+		SecuritiesPenaltyRates: map[string]decimal.Decimal{
+			"EQUITY":          decimal.NewFromFloat(0.0001),
+			"ILLIQUID_EQUITY": decimal.NewFromFloat(0.00005),
+			"SOVEREIGN_BOND":  decimal.NewFromFloat(0.00001),
+			"BOND":            decimal.NewFromFloat(0.00002),
+			"ETF":             decimal.NewFromFloat(0.00005),
+		},
+		DefaultSecuritiesRate: decimal.NewFromFloat(0.0001),
+		CashPenaltyRate:       decimal.NewFromFloat(0.04),
+		BuyInExtensionDays: map[string]int{
+			"ILLIQUID_EQUITY": 7,
+			"BOND":            7,
+			"SOVEREIGN_BOND":  7,
+		},
+		DefaultExtensionDay: 4,
+		BuyInAgentAccount:   "BUYIN-AGENT",
+		BuyInSettlementDays: 1,
+	}
+}
+
+// ExtensionDays 证券类型对应的强制买入延展期
+func (p *FailsPolicy) ExtensionDays(securityType string) int {
+	if days, ok := p.BuyInExtensionDays[securityType]; ok {
+		return days
+	}
+	return p.DefaultExtensionDay
+}
+
+// BuyInDue 证券不足失败超过延展期且尚未发起强制买入
+func (p *FailsPolicy) BuyInDue(cal BusinessCalendar, ins *SettlementInstruction, asOf time.Time) bool {
+	return ins.IsOpenFail() &&
+		ins.FailingParty == FailingPartySeller &&
+		ins.BuyInID == "" &&
+		ins.BusinessDaysFailed(cal, asOf) > p.ExtensionDays(ins.SecurityType)
+}
+
+// SettlementPenalty 结算失败日罚金，每条指令每个工作日一条
+type SettlementPenalty struct {
+	gorm.Model
+	PenaltyID          string          `gorm:"column:penalty_id;type:varchar(96);uniqueIndex;not null" json:"penalty_id"`
+	InstructionID      string          `gorm:"column:instruction_id;type:varchar(64);index;not null" json:"instruction_id"`
+	PenaltyDate        time.Time       `gorm:"column:penalty_date;index;not null" json:"penalty_date"`
+	FailingParty       FailingParty    `gorm:"column:failing_party;type:varchar(16);not null" json:"failing_party"`
+	FailingAccountID   string          `gorm:"column:failing_account_id;type:varchar(64);index;not null" json:"failing_account_id"`
+	ReceivingAccountID string          `gorm:"column:receiving_account_id;type:varchar(64);index;not null" json:"receiving_account_id"`
+	Symbol             string          `gorm:"column:symbol;type:varchar(32)" json:"symbol"`
+	UnsettledQuantity  decimal.Decimal `gorm:"column:unsettled_quantity;type:decimal(20,4)" json:"unsettled_quantity"`
+	ReferencePrice     decimal.Decimal `gorm:"column:reference_price;type:decimal(18,8)" json:"reference_price"`
+	BaseAmount         decimal.Decimal `gorm:"column:base_amount;type:decimal(20,2)" json:"base_amount"`
+	Rate               decimal.Decimal `gorm:"column:rate;type:decimal(12,8)" json:"rate"`
+	Amount             decimal.Decimal `gorm:"column:amount;type:decimal(20,2);not null" json:"amount"`
+	Currency           string          `gorm:"column:currency;type:varchar(3);not null" json:"currency"`
+}
+
+// TableName 表名
+func (SettlementPenalty) TableName() string {
+	return "settlement_penalties"
+}
+
+// NewSettlementPenalty 计算指令在某工作日的罚金：
+// 证券不足按未交收数量 × 参考价 × 证券费率，资金不足按未交收金额 × 年化费率 / 365；无法归责时返回 nil
+func (p *FailsPolicy) NewSettlementPenalty(ins *SettlementInstruction, date time.Time, referencePrice decimal.Decimal) *SettlementPenalty {
+	if ins.FailingParty == "" || !ins.RemainingQuantity().IsPositive() {
+		return nil
+	}
+	if !referencePrice.IsPositive() {
+		referencePrice = ins.Price
+	}
+
+	var base, rate decimal.Decimal
+	switch ins.FailingParty {
+	case FailingPartySeller:
+		base = ins.RemainingQuantity().Mul(referencePrice).Round(2)
+		rate = p.DefaultSecuritiesRate
+		if r, ok := p.SecuritiesPenaltyRates[ins.SecurityType]; ok {
+			rate = r
+		}
+	case FailingPartyBuyer:
+		base = ins.RemainingAmount()
+		rate = p.CashPenaltyRate.Div(decimal.NewFromInt(365))
+	default:
+		return nil
+	}
+
+	day := truncateDay(date)
+	return &SettlementPenalty{
+		PenaltyID:          fmt.Sprintf("PEN-%s-%s", ins.InstructionID, day.Format("20060102")),
+		InstructionID:      ins.InstructionID,
+		PenaltyDate:        day,
+		FailingParty:       ins.FailingParty,
+		FailingAccountID:   ins.FailingAccountID(),
+		ReceivingAccountID: ins.ReceivingAccountID(),
+		Symbol:             ins.Symbol,
+		UnsettledQuantity:  ins.RemainingQuantity(),
+		ReferencePrice:     referencePrice,
+		BaseAmount:         base,
+		Rate:               rate,
+		Amount:             base.Mul(rate).Round(2),
+		Currency:           ins.Currency,
+	}
+}
+
+// PenaltyDates 返回需要计提罚金的工作日：从预定结算日（或上次计提日次日）到 asOf 前一天
+func (p *FailsPolicy) PenaltyDates(cal BusinessCalendar, ins *SettlementInstruction, asOf time.Time) []time.Time {
+	start := truncateDay(ins.SettlementDate)
+	if ins.LastPenaltyDate != nil {
+		start = truncateDay(*ins.LastPenaltyDate).AddDate(0, 0, 1)
+	}
+	if !cal.IsBusinessDay(start) {
+		start = AddBusinessDays(cal, start, 1)
+	}
+	end := truncateDay(asOf)
+	var dates []time.Time
+	for d := start; d.Before(end); d = AddBusinessDays(cal, d, 1) {
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// ApplyPenalty 累计罚金并推进计提日
+func (s *SettlementInstruction) ApplyPenalty(penalty *SettlementPenalty) {
+	s.PenaltyAccrued = s.PenaltyAccrued.Add(penalty.Amount)
+	date := penalty.PenaltyDate
+	s.LastPenaltyDate = &date
+}
+
+// BuyInStatus 强制买入状态
+type BuyInStatus string
+
+const (
+	BuyInInitiated BuyInStatus = "INITIATED"
+	BuyInExecuted  BuyInStatus = "EXECUTED"
+)
+
+// BuyInChargeStatus 强制买入差额收取状态
+type BuyInChargeStatus string
+
+const (
+	BuyInChargeNone    BuyInChargeStatus = ""
+	BuyInChargePending BuyInChargeStatus = "PENDING"
+	BuyInChargeCharged BuyInChargeStatus = "CHARGED"
+)
+
+// BuyIn 强制买入：代理在市场买入未交付证券，生成替代交易交付给买方，
+// 买入价高于原成交价的差额由失败卖方承担
+type BuyIn struct {
+	gorm.Model
+	BuyInID                  string          `gorm:"column:buy_in_id;type:varchar(64);uniqueIndex;not null" json:"buy_in_id"`
+	InstructionID            string          `gorm:"column:instruction_id;type:varchar(64);index;not null" json:"instruction_id"`
+	Symbol                   string          `gorm:"column:symbol;type:varchar(32);not null" json:"symbol"`
+	Quantity                 decimal.Decimal `gorm:"column:quantity;type:decimal(20,4);not null" json:"quantity"`
+	Currency                 string          `gorm:"column:currency;type:varchar(3);not null" json:"currency"`
+	OriginalPrice            decimal.Decimal `gorm:"column:original_price;type:decimal(18,8)" json:"original_price"`
+	ExecutionPrice           decimal.Decimal `gorm:"column:execution_price;type:decimal(18,8)" json:"execution_price"`
+	PriceDifference          decimal.Decimal `gorm:"column:price_difference;type:decimal(20,2)" json:"price_difference"`
+	ChargeAmount             decimal.Decimal `gorm:"column:charge_amount;type:decimal(20,2)" json:"charge_amount"`
+	FailingAccountID         string          `gorm:"column:failing_account_id;type:varchar(64);index" json:"failing_account_id"`
+	ReceivingAccountID       string          `gorm:"column:receiving_account_id;type:varchar(64)" json:"receiving_account_id"`
+	Status                   BuyInStatus     `gorm:"column:status;type:varchar(16);index;not null" json:"status"`
+	ReplacementTradeID       string          `gorm:"column:replacement_trade_id;type:varchar(64)" json:"replacement_trade_id"`
+	ReplacementInstructionID string          `gorm:"column:replacement_instruction_id;type:varchar(64)" json:"replacement_instruction_id"`
+	InitiatedAt              time.Time       `gorm:"column:initiated_at" json:"initiated_at"`
+	ExecutedAt               *time.Time      `gorm:"column:executed_at" json:"executed_at"`
+	// ChargeStatus 与成交在同一事务落库，PENDING 的差额由失败处理周期重试直至收取成功
+	ChargeStatus BuyInChargeStatus `gorm:"column:charge_status;type:varchar(16);index" json:"charge_status"`
+	ChargedAt    *time.Time        `gorm:"column:charged_at" json:"charged_at"`
+}
+
+// TableName 表名
+func (BuyIn) TableName() string {
+	return "settlement_buy_ins"
+}
+
+// NewBuyIn 为失败指令的剩余数量发起强制买入
+func NewBuyIn(ins *SettlementInstruction) *BuyIn {
+	return &BuyIn{
+		BuyInID:            "BI-" + ins.InstructionID,
+		InstructionID:      ins.InstructionID,
+		Symbol:             ins.Symbol,
+		Quantity:           ins.RemainingQuantity(),
+		Currency:           ins.Currency,
+		OriginalPrice:      ins.Price,
+		FailingAccountID:   ins.SellerAccountID,
+		ReceivingAccountID: ins.BuyerAccountID,
+		Status:             BuyInInitiated,
+		InitiatedAt:        time.Now(),
+	}
+}
+
+// Execute 记录买入成交：差额 = (买入价 - 原价) × 数量，仅当为正时向失败方收取
+func (b *BuyIn) Execute(price decimal.Decimal, tradeID string) error {
+	if b.Status != BuyInInitiated {
+		return fmt.Errorf("buy-in %s already %s", b.BuyInID, b.Status)
+	}
+	if !price.IsPositive() {
+		return errors.New("buy-in price must be positive")
+	}
+	now := time.Now()
+	b.ExecutionPrice = price
+	b.PriceDifference = price.Sub(b.OriginalPrice).Mul(b.Quantity).Round(2)
+	b.ChargeAmount = decimal.Max(b.PriceDifference, decimal.Zero)
+	b.ReplacementTradeID = tradeID
+	b.Status = BuyInExecuted
+	b.ExecutedAt = &now
+	if b.ChargeAmount.IsPositive() {
+		b.ChargeStatus = BuyInChargePending
+	}
+	return nil
+}
+
+// MarkCharged 记录差额已从失败方收取
+func (b *BuyIn) MarkCharged() {
+	now := time.Now()
+	b.ChargeStatus = BuyInChargeCharged
+	b.ChargedAt = &now
+}
+
+// ReplacementInstruction 生成替代交易的结算指令：代理账户按买入价向原买方交付
+func (b *BuyIn) ReplacementInstruction(agentAccount string, settlementDays int) *SettlementInstruction {
+	ins := NewSettlementInstruction(b.ReplacementTradeID, b.Symbol, b.Quantity, b.ExecutionPrice, b.Currency, b.ReceivingAccountID, agentAccount, settlementDays)
+	ins.PartialAllowed = false
+	return ins
+}
+
+// FailAgeingItem 账龄报告明细
+type FailAgeingItem struct {
+	InstructionID     string          `json:"instruction_id"`
+	Symbol            string          `json:"symbol"`
+	Currency          string          `json:"currency"`
+	FailingParty      FailingParty    `json:"failing_party"`
+	FailingAccountID  string          `json:"failing_account_id"`
+	DaysFailed        int             `json:"days_failed"`
+	UnsettledQuantity decimal.Decimal `json:"unsettled_quantity"`
+	UnsettledAmount   decimal.Decimal `json:"unsettled_amount"`
+	PenaltyAccrued    decimal.Decimal `json:"penalty_accrued"`
+	BuyInID           string          `json:"buy_in_id,omitempty"`
+}
+
+// FailAgeingBucket 账龄区间汇总，金额按币种
+type FailAgeingBucket struct {
+	Label           string                     `json:"label"`
+	MinDays         int                        `json:"min_days"`
+	MaxDays         int                        `json:"max_days"` // 0 表示无上限
+	Count           int                        `json:"count"`
+	UnsettledAmount map[string]decimal.Decimal `json:"unsettled_amount"`
+	PenaltyAccrued  map[string]decimal.Decimal `json:"penalty_accrued"`
+}
+
+// FailsAgeingReport 结算失败账龄报告
+type FailsAgeingReport struct {
+	AsOf    time.Time           `json:"as_of"`
+	Buckets []*FailAgeingBucket `json:"buckets"`
+	Items   []*FailAgeingItem   `json:"items"`
+}
+
+// BuildFailsAgeingReport 按失败工作日数分桶：0-3、4-7、8-15、16 天以上，明细按账龄降序。
+// calendarFor 返回指令结算市场与币种的工作日日历
+func BuildFailsAgeingReport(asOf time.Time, fails []*SettlementInstruction, calendarFor func(*SettlementInstruction) BusinessCalendar) *FailsAgeingReport {
+	report := &FailsAgeingReport{AsOf: asOf}
+	for _, b := range [][2]int{{0, 3}, {4, 7}, {8, 15}, {16, 0}} {
+		label := fmt.Sprintf("%d-%d", b[0], b[1])
+		if b[1] == 0 {
+			label = fmt.Sprintf("%d+", b[0])
+		}
+		report.Buckets = append(report.Buckets, &FailAgeingBucket{
+			Label:           label,
+			MinDays:         b[0],
+			MaxDays:         b[1],
+			UnsettledAmount: make(map[string]decimal.Decimal),
+			PenaltyAccrued:  make(map[string]decimal.Decimal),
+		})
+	}
+
+	for _, ins := range fails {
+		item := &FailAgeingItem{
+			InstructionID:     ins.InstructionID,
+			Symbol:            ins.Symbol,
+			Currency:          ins.Currency,
+			FailingParty:      ins.FailingParty,
+			FailingAccountID:  ins.FailingAccountID(),
+			DaysFailed:        ins.BusinessDaysFailed(calendarFor(ins), asOf),
+			UnsettledQuantity: ins.RemainingQuantity(),
+			UnsettledAmount:   ins.RemainingAmount(),
+			PenaltyAccrued:    ins.PenaltyAccrued,
+			BuyInID:           ins.BuyInID,
+		}
+		report.Items = append(report.Items, item)
+		for _, b := range report.Buckets {
+			if item.DaysFailed >= b.MinDays && (b.MaxDays == 0 || item.DaysFailed <= b.MaxDays) {
+				b.Count++
+				b.UnsettledAmount[ins.Currency] = b.UnsettledAmount[ins.Currency].Add(item.UnsettledAmount)
+				b.PenaltyAccrued[ins.Currency] = b.PenaltyAccrued[ins.Currency].Add(item.PenaltyAccrued)
+				break
+			}
+		}
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool { return report.Items[i].DaysFailed > report.Items[j].DaysFailed })
+	return report
+}
+
+// FailsRepository 结算失败、罚金与强制买入仓储接口
+type FailsRepository interface {
+	FindOpenFails(ctx context.Context, asOf time.Time, afterID uint, limit int) ([]*SettlementInstruction, error)
+	SavePenalties(ctx context.Context, penalties []*SettlementPenalty) error
+	ListPenalties(ctx context.Context, instructionID, accountID string, from, to time.Time) ([]*SettlementPenalty, error)
+	SaveBuyIn(ctx context.Context, buyIn *BuyIn) error
+	GetBuyIn(ctx context.Context, buyInID string) (*BuyIn, error)
+	ListBuyIns(ctx context.Context, status BuyInStatus, limit int) ([]*BuyIn, error)
+	ListPendingBuyInCharges(ctx context.Context, limit int) ([]*BuyIn, error)
+}
+
+// ReferencePriceSource 罚金计算使用的证券参考价
+type ReferencePriceSource interface {
+	ReferencePrice(ctx context.Context, symbol string) (decimal.Decimal, error)
+}
+
+// BuyInExecutor 强制买入代理：在市场买入证券并返回成交价与替代交易号
+type BuyInExecutor interface {
+	ExecuteBuyIn(ctx context.Context, buyIn *BuyIn) (price decimal.Decimal, tradeID string, err error)
+}
@@ -0,0 +1,333 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoCustodian 指令未设置任何托管商，无法生成报文
+	ErrNoCustodian = errors.New("instruction has no custodian")
+	// ErrUnknownMessage 无法识别的报文
+	ErrUnknownMessage = errors.New("unknown settlement message")
+	// ErrMessageNotFound 找不到报文引用对应的原始指令报文
+	ErrMessageNotFound = errors.New("settlement message not found")
+)
+
+// MessageStandard 报文标准
+type MessageStandard string
+
+const (
+	StandardISO20022 MessageStandard = "ISO20022" // sese.023 / sese.024 / sese.025
+	StandardMT       MessageStandard = "MT"       // MT540-543 / MT544-548
+)
+
+// MessageDirection 报文方向
+type MessageDirection string
+
+const (
+	MessageOutbound MessageDirection = "OUTBOUND"
+	MessageInbound  MessageDirection = "INBOUND"
+)
+
+// MessageSide 报文对应的交收方向：买方收券（RECE），卖方交券（DELI）
+type MessageSide string
+
+const (
+	SideReceive MessageSide = "RECE"
+	SideDeliver MessageSide = "DELI"
+)
+
+// MessageStatus 报文处理状态
+type MessageStatus string
+
+const (
+	MessageGenerated MessageStatus = "GENERATED" // 已生成未发送
+	MessageSent      MessageStatus = "SENT"      // 已发送
+	MessageAccepted  MessageStatus = "ACCEPTED"  // 托管商已受理
+	MessageMatched   MessageStatus = "MATCHED"   // 已与对手方指令匹配
+	MessageRejected  MessageStatus = "REJECTED"  // 被托管商拒绝
+	MessageSettled   MessageStatus = "SETTLED"   // 已确认全部交收
+	MessageProcessed MessageStatus = "PROCESSED" // 入站报文已处理
+	MessageFailed    MessageStatus = "FAILED"    // 入站报文处理失败
+)
+
+// SettlementMessage 与托管商往来的结算报文。出站报文为每个托管方向各一条，
+// 入站状态与确认报文通过 RelatedReference 关联到出站报文。
+type SettlementMessage struct {
+	gorm.Model
+	MessageID        string           `gorm:"column:message_id;type:varchar(64);uniqueIndex;not null" json:"message_id"`
+	Reference        string           `gorm:"column:reference;type:varchar(35);uniqueIndex:uk_settlement_message_ref,priority:2;not null" json:"reference"`
+	RelatedReference string           `gorm:"column:related_reference;type:varchar(35);index" json:"related_reference"`
+	InstructionID    string           `gorm:"column:instruction_id;type:varchar(64);index" json:"instruction_id"`
+	Direction        MessageDirection `gorm:"column:direction;type:varchar(16);uniqueIndex:uk_settlement_message_ref,priority:1;not null" json:"direction"`
+	Standard         MessageStandard  `gorm:"column:standard;type:varchar(16);not null" json:"standard"`
+	MessageType      string           `gorm:"column:message_type;type:varchar(16);not null" json:"message_type"`
+	Side             MessageSide      `gorm:"column:side;type:varchar(8)" json:"side"`
+	Sender           string           `gorm:"column:sender;type:varchar(64)" json:"sender"`
+	Receiver         string           `gorm:"column:receiver;type:varchar(64)" json:"receiver"`
+	Payload          string           `gorm:"column:payload;type:mediumtext" json:"payload"`
+	Status           MessageStatus    `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	StatusReason     string           `gorm:"column:status_reason;type:varchar(255)" json:"status_reason"`
+	// ConfirmedQuantity 出站报文已收到交收确认的累计数量
+	ConfirmedQuantity decimal.Decimal `gorm:"column:confirmed_quantity;type:decimal(20,4)" json:"confirmed_quantity"`
+	SentAt            *time.Time      `gorm:"column:sent_at" json:"sent_at"`
+	ProcessedAt       *time.Time      `gorm:"column:processed_at" json:"processed_at"`
+}
+
+// TableName 表名
+func (SettlementMessage) TableName() string {
+	return "settlement_messages"
+}
+
+// SettlementInstructionMessage 生成出站报文所需的单边指令视图
+type SettlementInstructionMessage struct {
+	Reference        string
+	Sender           string // 本方 BIC
+	Receiver         string // 托管商 BIC
+	Side             MessageSide
+	Payment          bool // 券款对付（APMT）或纯券（FREE）
+	Instruction      *SettlementInstruction
+	SafekeepingAcct  string // 本方在托管商的证券账户
+	CounterpartyBIC  string // 对手方托管商
+	CounterpartyAcct string
+}
+
+// MessageSides 指令需要向托管商发送的方向：设置了买方托管商则发送收券指令，设置了卖方托管商则发送交券指令
+func (s *SettlementInstruction) MessageSides() []MessageSide {
+	var sides []MessageSide
+	if s.BuyerCustodian != "" {
+		sides = append(sides, SideReceive)
+	}
+	if s.SellerCustodian != "" {
+		sides = append(sides, SideDeliver)
+	}
+	return sides
+}
+
+// InstructionMessage 构造单边报文视图
+func (s *SettlementInstruction) InstructionMessage(side MessageSide, reference, sender string) *SettlementInstructionMessage {
+	m := &SettlementInstructionMessage{
+		Reference:   reference,
+		Sender:      sender,
+		Side:        side,
+		Payment:     s.SettlementType == SettlementTypeDVP || s.SettlementType == SettlementTypeRVP,
+		Instruction: s,
+	}
+	if side == SideReceive {
+		m.Receiver, m.SafekeepingAcct = s.BuyerCustodian, s.BuyerSettleAcct
+		m.CounterpartyBIC, m.CounterpartyAcct = s.SellerCustodian, s.SellerSettleAcct
+	} else {
+		m.Receiver, m.SafekeepingAcct = s.SellerCustodian, s.SellerSettleAcct
+		m.CounterpartyBIC, m.CounterpartyAcct = s.BuyerCustodian, s.BuyerSettleAcct
+	}
+	return m
+}
+
+// MTInstructionType 对应的 MT 指令类型：MT540 收券免付、MT541 收券付款、MT542 交券免付、MT543 交券收款
+func (m *SettlementInstructionMessage) MTInstructionType() string {
+	switch {
+	case m.Side == SideReceive && !m.Payment:
+		return "540"
+	case m.Side == SideReceive:
+		return "541"
+	case !m.Payment:
+		return "542"
+	default:
+		return "543"
+	}
+}
+
+// RecordOutboundMessage 记录已生成出站指令报文
+func (s *SettlementInstruction) RecordOutboundMessage(msg *SettlementMessage) {
+	s.addEvent("MESSAGE_GENERATED", fmt.Sprintf("%s %s -> %s", msg.MessageType, msg.Reference, msg.Receiver), "PROCESSING")
+}
+
+// ReportKind 入站报文类别
+type ReportKind string
+
+const (
+	ReportStatusAdvice ReportKind = "STATUS"       // sese.024 / MT548
+	ReportConfirmation ReportKind = "CONFIRMATION" // sese.025 / MT544-547
+)
+
+// ReportStatus 入站报文表达的状态
+type ReportStatus string
+
+const (
+	ReportAccepted  ReportStatus = "ACCEPTED"  // 已受理（PACK）
+	ReportRejected  ReportStatus = "REJECTED"  // 拒绝（REJT）
+	ReportMatched   ReportStatus = "MATCHED"   // 已匹配（MACH）
+	ReportUnmatched ReportStatus = "UNMATCHED" // 未匹配（NMAT）
+	ReportPending   ReportStatus = "PENDING"   // 待交收（PEND）
+	ReportFailing   ReportStatus = "FAILING"   // 交收失败中（PENF）
+	ReportCancelled ReportStatus = "CANCELLED" // 已撤销（CAND）
+	ReportSettled   ReportStatus = "SETTLED"   // 交收确认
+)
+
+// 常用状态原因代码
+const (
+	ReasonLackOfSecurities = "LACK"
+	ReasonLackOfMoney      = "MONY"
+)
+
+// SettlementStatusReport 解析后的入站状态或确认报文
+type SettlementStatusReport struct {
+	Standard         MessageStandard
+	MessageType      string
+	Kind             ReportKind
+	Reference        string // 入站报文自身引用
+	RelatedReference string // 对应出站指令的引用
+	Sender           string
+	Status           ReportStatus
+	ReasonCode       string
+	Reason           string
+	SettledQuantity  decimal.Decimal // 确认报文本次交收数量
+	SettledAmount    decimal.Decimal
+	Currency         string
+	SettlementDate   time.Time
+	Partial          bool
+}
+
+// FailingParty 根据原因代码判断失败责任方
+func (r *SettlementStatusReport) FailingParty() FailingParty {
+	switch r.ReasonCode {
+	case ReasonLackOfSecurities:
+		return FailingPartySeller
+	case ReasonLackOfMoney:
+		return FailingPartyBuyer
+	default:
+		return ""
+	}
+}
+
+// Describe 状态描述，用于指令事件
+func (r *SettlementStatusReport) Describe() string {
+	desc := fmt.Sprintf("%s %s", r.MessageType, r.Status)
+	if r.ReasonCode != "" {
+		desc += " " + r.ReasonCode
+	}
+	if r.Reason != "" {
+		desc += " " + r.Reason
+	}
+	return desc
+}
+
+// ApplyStatusReport 将托管商状态与确认报文应用到出站报文与指令状态机：
+// 拒绝与交收失败使指令进入失败（按原因代码归责，纳入结算失败管理），
+// 交收确认按各方向已确认数量的最小值推进已交收数量，全部确认后指令完成交收。
+// sides 为该指令全部出站报文（含 msg）。
+func (s *SettlementInstruction) ApplyStatusReport(msg *SettlementMessage, sides []*SettlementMessage, report *SettlementStatusReport) error {
+	if s.Status == SettlementStatusSettled || s.Status == SettlementStatusCancelled || s.Status == SettlementStatusBoughtIn {
+		s.addEvent("CUSTODIAN_"+string(report.Status), report.Describe(), "IGNORED")
+		return nil
+	}
+
+	switch report.Status {
+	case ReportAccepted:
+		msg.Status = MessageAccepted
+		s.addEvent("CUSTODIAN_ACCEPTED", report.Describe(), "PROCESSING")
+	case ReportMatched:
+		msg.Status = MessageMatched
+		if s.ConfirmedAt == nil && allMatched(sides) {
+			return s.Confirm()
+		}
+		s.addEvent("CUSTODIAN_MATCHED", report.Describe(), "PROCESSING")
+	case ReportUnmatched, ReportPending:
+		msg.StatusReason = report.Describe()
+		s.addEvent("CUSTODIAN_"+string(report.Status), report.Describe(), "PROCESSING")
+	case ReportRejected:
+		msg.Status = MessageRejected
+		msg.StatusReason = report.Describe()
+		return s.Fail(report.Describe())
+	case ReportFailing:
+		msg.StatusReason = report.Describe()
+		return s.FailBy(report.Describe(), report.FailingParty())
+	case ReportCancelled:
+		msg.StatusReason = report.Describe()
+		return s.Cancel(report.Describe())
+	case ReportSettled:
+		return s.applyConfirmation(msg, sides, report)
+	default:
+		return fmt.Errorf("%w: status %s", ErrUnknownMessage, report.Status)
+	}
+	return nil
+}
+
+func (s *SettlementInstruction) applyConfirmation(msg *SettlementMessage, sides []*SettlementMessage, report *SettlementStatusReport) error {
+	qty := report.SettledQuantity
+	if !qty.IsPositive() {
+		qty = s.Quantity.Sub(msg.ConfirmedQuantity)
+	}
+	msg.ConfirmedQuantity = decimal.Min(msg.ConfirmedQuantity.Add(qty), s.Quantity)
+	if msg.ConfirmedQuantity.GreaterThanOrEqual(s.Quantity) {
+		msg.Status = MessageSettled
+	}
+
+	// 券款两端均确认的数量才视为已交收
+	confirmed := s.Quantity
+	for _, m := range sides {
+		confirmed = decimal.Min(confirmed, m.ConfirmedQuantity)
+	}
+	delta := confirmed.Sub(s.SettledQuantity)
+	if !delta.IsPositive() {
+		s.addEvent("CUSTODIAN_CONFIRMED", report.Describe()+" "+qty.String(), "PROCESSING")
+		return nil
+	}
+
+	s.Status = SettlementStatusProcessing
+	if delta.GreaterThanOrEqual(s.RemainingQuantity()) {
+		return s.Settle()
+	}
+	reason := s.FailReason
+	if reason == "" {
+		reason = "托管商部分交收确认，余量待交收"
+	}
+	return s.PartialSettle(delta, s.FailingParty, reason)
+}
+
+func allMatched(sides []*SettlementMessage) bool {
+	for _, m := range sides {
+		if m.Status != MessageMatched && m.Status != MessageSettled {
+			return false
+		}
+	}
+	return true
+}
+
+// SettlementMessageCodec 报文编解码
+type SettlementMessageCodec interface {
+	// Encode 按标准生成出站指令报文，返回报文类型与报文内容
+	Encode(standard MessageStandard, m *SettlementInstructionMessage) (messageType string, payload []byte, err error)
+	// Decode 解析入站状态或确认报文，自动识别 ISO 20022 与 MT
+	Decode(payload []byte) (*SettlementStatusReport, error)
+}
+
+// InboundMessage 网关收到的原始入站报文
+type InboundMessage struct {
+	Name    string
+	Payload []byte
+}
+
+// SettlementMessageGateway 报文网关（SWIFT 网络或测试用文件投递）
+type SettlementMessageGateway interface {
+	Send(ctx context.Context, msg *SettlementMessage) error
+	// Receive 取回待处理入站报文
+	Receive(ctx context.Context) ([]InboundMessage, error)
+	// Ack 标记入站报文已处理，err 非空表示处理失败
+	Ack(ctx context.Context, in InboundMessage, err error) error
+}
+
+// SettlementMessageRepository 结算报文仓储
+type SettlementMessageRepository interface {
+	Save(ctx context.Context, msg *SettlementMessage) error
+	Update(ctx context.Context, msg *SettlementMessage) error
+	GetOutboundByReference(ctx context.Context, reference string) (*SettlementMessage, error)
+	GetByReference(ctx context.Context, direction MessageDirection, reference string) (*SettlementMessage, error)
+	ListByInstruction(ctx context.Context, instructionID string) ([]*SettlementMessage, error)
+}
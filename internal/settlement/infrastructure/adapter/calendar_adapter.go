@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	referencedatav1 "github.com/wyfcoding/financialtrading/go-api/referencedata/v1"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+const calendarDateLayout = "2006-01-02"
+
+// ReferenceDataCalendarAdapter 通过参考数据服务计算结算日与起息日
+type ReferenceDataCalendarAdapter struct {
+	client referencedatav1.ReferenceDataServiceClient
+}
+
+func NewReferenceDataCalendarAdapter(client referencedatav1.ReferenceDataServiceClient) domain.SettlementCalendar {
+	return &ReferenceDataCalendarAdapter{client: client}
+}
+
+func (a *ReferenceDataCalendarAdapter) SettlementDate(ctx context.Context, market, currency string, tradeDate time.Time, days int) (time.Time, error) {
+	resp, err := a.client.GetSettlementDate(ctx, &referencedatav1.GetSettlementDateRequest{
+		Market:    market,
+		Currency:  currency,
+		TradeDate: tradeDate.Format(calendarDateLayout),
+		Days:      int32(days),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get settlement date: %w", err)
+	}
+	return time.Parse(calendarDateLayout, resp.SettlementDate)
+}
+
+func (a *ReferenceDataCalendarAdapter) FXValueDate(ctx context.Context, baseCurrency, quoteCurrency string, tradeDate time.Time, days int) (time.Time, error) {
+	resp, err := a.client.GetFXValueDate(ctx, &referencedatav1.GetFXValueDateRequest{
+		BaseCurrency:  baseCurrency,
+		QuoteCurrency: quoteCurrency,
+		TradeDate:     tradeDate.Format(calendarDateLayout),
+		Days:          int32(days),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get fx value date: %w", err)
+	}
+	return time.Parse(calendarDateLayout, resp.ValueDate)
+}
+
+// BusinessCalendar 按参考数据中市场与币种日历的周末规则和节假日构建联合工作日日历
+func (a *ReferenceDataCalendarAdapter) BusinessCalendar(ctx context.Context, market, currency string, from, to time.Time) (domain.BusinessCalendar, error) {
+	resp, err := a.client.ListCalendars(ctx, &referencedatav1.ListCalendarsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("list calendars: %w", err)
+	}
+	weekends := make(map[string][]int32, len(resp.Calendars))
+	for _, c := range resp.Calendars {
+		weekends[strings.ToUpper(c.Code)] = c.Weekend
+	}
+
+	var joint jointCalendar
+	for _, code := range []string{market, currency} {
+		if code == "" {
+			continue
+		}
+		code = strings.ToUpper(code)
+		weekend, ok := weekends[code]
+		if !ok {
+			return nil, fmt.Errorf("calendar %s not found", code)
+		}
+		holidays, err := a.client.ListHolidays(ctx, &referencedatav1.ListHolidaysRequest{
+			Calendar: code,
+			From:     from.Format(calendarDateLayout),
+			To:       to.Format(calendarDateLayout),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list holidays %s: %w", code, err)
+		}
+		cal := &holidayCalendar{weekend: make(map[time.Weekday]bool), holidays: make(map[string]bool)}
+		for _, w := range weekend {
+			cal.weekend[time.Weekday(w)] = true
+		}
+		for _, h := range holidays.Holidays {
+			cal.holidays[h.Date] = true
+		}
+		joint = append(joint, cal)
+	}
+	if len(joint) == 0 {
+		return nil, fmt.Errorf("market or currency is required")
+	}
+	return joint, nil
+}
+
+type holidayCalendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+func (c *holidayCalendar) IsBusinessDay(d time.Time) bool {
+	return !c.weekend[d.Weekday()] && !c.holidays[d.Format(calendarDateLayout)]
+}
+
+// jointCalendar 所有日历均为工作日时才是工作日
+type jointCalendar []domain.BusinessCalendar
+
+func (j jointCalendar) IsBusinessDay(d time.Time) bool {
+	for _, c := range j {
+		if !c.IsBusinessDay(d) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+	marketdatav1 "github.com/wyfcoding/financialtrading/go-api/marketdata/v1"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"github.com/wyfcoding/pkg/idgen"
+)
+
+// MarketReferencePriceAdapter 以最新成交价作为罚金参考价
+type MarketReferencePriceAdapter struct {
+	client marketdatav1.MarketDataServiceClient
+}
+
+func NewMarketReferencePriceAdapter(client marketdatav1.MarketDataServiceClient) domain.ReferencePriceSource {
+	return &MarketReferencePriceAdapter{client: client}
+}
+
+func (a *MarketReferencePriceAdapter) ReferencePrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	quote, err := a.client.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: symbol})
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("get latest quote: %w", err)
+	}
+	if quote.LastPrice <= 0 {
+		return decimal.Zero, fmt.Errorf("no last price for %s", symbol)
+	}
+	return decimal.NewFromFloat(quote.LastPrice), nil
+}
+
+// QuoteBuyInExecutor 按最新卖一价（缺失时为最新价）登记强制买入成交，
+// 用于尚未接入真实买入代理的环境
+type QuoteBuyInExecutor struct {
+	client marketdatav1.MarketDataServiceClient
+	logger *slog.Logger
+}
+
+func NewQuoteBuyInExecutor(client marketdatav1.MarketDataServiceClient, logger *slog.Logger) domain.BuyInExecutor {
+	return &QuoteBuyInExecutor{client: client, logger: logger}
+}
+
+func (e *QuoteBuyInExecutor) ExecuteBuyIn(ctx context.Context, buyIn *domain.BuyIn) (decimal.Decimal, string, error) {
+	quote, err := e.client.GetLatestQuote(ctx, &marketdatav1.GetLatestQuoteRequest{Symbol: buyIn.Symbol})
+	if err != nil {
+		return decimal.Zero, "", fmt.Errorf("get latest quote: %w", err)
+	}
+	price := quote.AskPrice
+	if price <= 0 {
+		price = quote.LastPrice
+	}
+	if price <= 0 {
+		return decimal.Zero, "", fmt.Errorf("no buy-in price for %s", buyIn.Symbol)
+	}
+
+	tradeID := fmt.Sprintf("BUYIN-%d", idgen.GenID())
+	e.logger.Info("buy-in executed at quote", "buy_in_id", buyIn.BuyInID, "symbol", buyIn.Symbol, "quantity", buyIn.Quantity, "price", price, "trade_id", tradeID)
+	return decimal.NewFromFloat(price), tradeID, nil
+}
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FailsRepo struct {
+	db *gorm.DB
+}
+
+func NewFailsRepo(db *gorm.DB) domain.FailsRepository {
+	return &FailsRepo{db: db}
+}
+
+func (r *FailsRepo) FindOpenFails(ctx context.Context, asOf time.Time, afterID uint, limit int) ([]*domain.SettlementInstruction, error) {
+	var instructions []*domain.SettlementInstruction
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("status IN ? AND settlement_date <= ? AND id > ?",
+			[]domain.SettlementStatus{domain.SettlementStatusFailed, domain.SettlementStatusPartial}, asOf, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&instructions).Error
+	return instructions, err
+}
+
+// SavePenalties 同一指令同一日的罚金只计提一次
+func (r *FailsRepo) SavePenalties(ctx context.Context, penalties []*domain.SettlementPenalty) error {
+	if len(penalties) == 0 {
+		return nil
+	}
+	return getDB(ctx, r.db).WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "penalty_id"}}, DoNothing: true}).
+		Create(&penalties).Error
+}
+
+func (r *FailsRepo) ListPenalties(ctx context.Context, instructionID, accountID string, from, to time.Time) ([]*domain.SettlementPenalty, error) {
+	db := getDB(ctx, r.db).WithContext(ctx)
+	if instructionID != "" {
+		db = db.Where("instruction_id = ?", instructionID)
+	}
+	if accountID != "" {
+		db = db.Where("failing_account_id = ? OR receiving_account_id = ?", accountID, accountID)
+	}
+	if !from.IsZero() {
+		db = db.Where("penalty_date >= ?", from)
+	}
+	if !to.IsZero() {
+		db = db.Where("penalty_date < ?", to)
+	}
+	var penalties []*domain.SettlementPenalty
+	err := db.Order("penalty_date ASC, id ASC").Find(&penalties).Error
+	return penalties, err
+}
+
+func (r *FailsRepo) SaveBuyIn(ctx context.Context, buyIn *domain.BuyIn) error {
+	return getDB(ctx, r.db).WithContext(ctx).Save(buyIn).Error
+}
+
+func (r *FailsRepo) GetBuyIn(ctx context.Context, buyInID string) (*domain.BuyIn, error) {
+	var buyIn domain.BuyIn
+	if err := getDB(ctx, r.db).WithContext(ctx).Where("buy_in_id = ?", buyInID).First(&buyIn).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrBuyInNotFound
+		}
+		return nil, err
+	}
+	return &buyIn, nil
+}
+
+func (r *FailsRepo) ListBuyIns(ctx context.Context, status domain.BuyInStatus, limit int) ([]*domain.BuyIn, error) {
+	db := getDB(ctx, r.db).WithContext(ctx)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var buyIns []*domain.BuyIn
+	err := db.Order("id ASC").Limit(limit).Find(&buyIns).Error
+	return buyIns, err
+}
+
+func (r *FailsRepo) ListPendingBuyInCharges(ctx context.Context, limit int) ([]*domain.BuyIn, error) {
+	var buyIns []*domain.BuyIn
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("charge_status = ?", domain.BuyInChargePending).
+		Order("id ASC").Limit(limit).Find(&buyIns).Error
+	return buyIns, err
+}
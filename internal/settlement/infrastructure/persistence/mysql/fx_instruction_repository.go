@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"gorm.io/gorm"
+)
+
+type FXInstructionRepo struct {
+	db *gorm.DB
+}
+
+func NewFXInstructionRepo(db *gorm.DB) domain.FXInstructionRepository {
+	return &FXInstructionRepo{db: db}
+}
+
+func (r *FXInstructionRepo) Save(ctx context.Context, instructions []*domain.FXInstruction) error {
+	if len(instructions) == 0 {
+		return nil
+	}
+	return getDB(ctx, r.db).WithContext(ctx).Create(instructions).Error
+}
+
+func (r *FXInstructionRepo) ListByRun(ctx context.Context, runID string) ([]*domain.FXInstruction, error) {
+	var instructions []*domain.FXInstruction
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("netting_run_id = ?", runID).
+		Order("account_id ASC, currency ASC").
+		Find(&instructions).Error
+	return instructions, err
+}
@@ -0,0 +1,53 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"gorm.io/gorm"
+)
+
+type MessageRepo struct {
+	db *gorm.DB
+}
+
+func NewMessageRepo(db *gorm.DB) domain.SettlementMessageRepository {
+	return &MessageRepo{db: db}
+}
+
+func (r *MessageRepo) Save(ctx context.Context, msg *domain.SettlementMessage) error {
+	return getDB(ctx, r.db).WithContext(ctx).Create(msg).Error
+}
+
+func (r *MessageRepo) Update(ctx context.Context, msg *domain.SettlementMessage) error {
+	return getDB(ctx, r.db).WithContext(ctx).Save(msg).Error
+}
+
+func (r *MessageRepo) GetOutboundByReference(ctx context.Context, reference string) (*domain.SettlementMessage, error) {
+	return r.GetByReference(ctx, domain.MessageOutbound, reference)
+}
+
+// GetByReference 按方向与参考号查询报文，(direction, reference) 唯一
+func (r *MessageRepo) GetByReference(ctx context.Context, direction domain.MessageDirection, reference string) (*domain.SettlementMessage, error) {
+	var msg domain.SettlementMessage
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("direction = ? AND reference = ?", direction, reference).
+		First(&msg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *MessageRepo) ListByInstruction(ctx context.Context, instructionID string) ([]*domain.SettlementMessage, error) {
+	var messages []*domain.SettlementMessage
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("instruction_id = ?", instructionID).
+		Order("id ASC").
+		Find(&messages).Error
+	return messages, err
+}
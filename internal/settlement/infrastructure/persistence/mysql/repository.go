@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"github.com/wyfcoding/pkg/contextx"
 	"gorm.io/gorm"
 )
 
@@ -17,11 +18,11 @@ func NewSettlementRepo(db *gorm.DB) domain.SettlementRepository {
 }
 
 func (r *SettlementRepo) Save(ctx context.Context, instruction *domain.SettlementInstruction) error {
-	return r.db.WithContext(ctx).Create(instruction).Error
+	return getDB(ctx, r.db).WithContext(ctx).Create(instruction).Error
 }
 
 func (r *SettlementRepo) Update(ctx context.Context, instruction *domain.SettlementInstruction) error {
-	return r.db.WithContext(ctx).Save(instruction).Error
+	return getDB(ctx, r.db).WithContext(ctx).Save(instruction).Error
 }
 
 func (r *SettlementRepo) Get(ctx context.Context, instructionID string) (*domain.SettlementInstruction, error) {
@@ -86,11 +87,19 @@ func (r *SettlementRepo) UpdateStatus(ctx context.Context, instructionID string,
 
 func (r *SettlementRepo) WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		txCtx := context.WithValue(ctx, "tx", tx)
+		txCtx := contextx.WithTx(ctx, tx)
 		return fn(txCtx)
 	})
 }
 
+// getDB 优先使用上下文中的事务
+func getDB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := contextx.GetTx(ctx).(*gorm.DB); ok {
+		return tx
+	}
+	return db
+}
+
 type NettingRepo struct {
 	db *gorm.DB
 }
@@ -100,7 +109,7 @@ func NewNettingRepo(db *gorm.DB) domain.NettingRepository {
 }
 
 func (r *NettingRepo) Save(ctx context.Context, result *domain.NettingResult) error {
-	return r.db.WithContext(ctx).Save(result).Error
+	return getDB(ctx, r.db).WithContext(ctx).Save(result).Error
 }
 
 func (r *NettingRepo) Get(ctx context.Context, nettingID string) (*domain.NettingResult, error) {
@@ -121,6 +130,15 @@ func (r *NettingRepo) GetByAccountAndCurrency(ctx context.Context, accountID, cu
 	return &result, nil
 }
 
+func (r *NettingRepo) ListByRun(ctx context.Context, runID string) ([]*domain.NettingResult, error) {
+	var results []*domain.NettingResult
+	err := getDB(ctx, r.db).WithContext(ctx).
+		Where("netting_run_id = ?", runID).
+		Order("account_id ASC, currency ASC").
+		Find(&results).Error
+	return results, err
+}
+
 type BatchRepo struct {
 	db *gorm.DB
 }
@@ -160,11 +178,14 @@ func NewFXRateRepo(db *gorm.DB) domain.FXRateRepository {
 }
 
 func (r *FXRateRepo) GetRate(ctx context.Context, fromCurrency, toCurrency string) (*domain.FXRate, error) {
+	return r.GetRateAt(ctx, fromCurrency, toCurrency, time.Now())
+}
+
+func (r *FXRateRepo) GetRateAt(ctx context.Context, fromCurrency, toCurrency string, at time.Time) (*domain.FXRate, error) {
 	var rate domain.FXRate
-	now := time.Now()
 	if err := r.db.WithContext(ctx).
 		Where("from_currency = ? AND to_currency = ? AND effective_at <= ? AND (expires_at IS NULL OR expires_at > ?)",
-			fromCurrency, toCurrency, now, now).
+			fromCurrency, toCurrency, at, at).
 		Order("effective_at DESC").
 		First(&rate).Error; err != nil {
 		return nil, err
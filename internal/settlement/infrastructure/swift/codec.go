@@ -0,0 +1,53 @@
+// Package swift 生成与解析托管商结算报文：ISO 20022 sese.023/024/025 与 MT540-548。
+package swift
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+var isinPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`)
+
+// Codec 同时支持 ISO 20022 与 MT 的报文编解码器
+type Codec struct{}
+
+// NewCodec 创建编解码器
+func NewCodec() domain.SettlementMessageCodec {
+	return &Codec{}
+}
+
+func (c *Codec) Encode(standard domain.MessageStandard, m *domain.SettlementInstructionMessage) (string, []byte, error) {
+	if m.Instruction == nil {
+		return "", nil, fmt.Errorf("%w: instruction is required", domain.ErrUnknownMessage)
+	}
+	switch standard {
+	case domain.StandardISO20022:
+		payload, err := encodeSese023(m)
+		return "sese.023", payload, err
+	case domain.StandardMT:
+		mt := m.MTInstructionType()
+		return "MT" + mt, encodeMT54x(mt, m), nil
+	default:
+		return "", nil, fmt.Errorf("%w: standard %q", domain.ErrUnknownMessage, standard)
+	}
+}
+
+func (c *Codec) Decode(payload []byte) (*domain.SettlementStatusReport, error) {
+	trimmed := bytes.TrimSpace(payload)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return decodeISO20022(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte(":")):
+		return decodeMT(trimmed)
+	default:
+		return nil, fmt.Errorf("%w: unrecognised payload", domain.ErrUnknownMessage)
+	}
+}
+
+// isISIN 证券代码符合 ISIN 格式时按 ISIN 标识，否则按代码标识
+func isISIN(symbol string) bool {
+	return isinPattern.MatchString(symbol)
+}
@@ -0,0 +1,89 @@
+package swift
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+// FileDropGateway 以目录投递代替 SWIFT 网络，用于联调与测试：
+// 出站报文写入 outbound/，入站报文从 inbound/ 读取，处理后移入 inbound/processed/ 或 inbound/error/
+type FileDropGateway struct {
+	outbound  string
+	inbound   string
+	processed string
+	failed    string
+}
+
+// NewFileDropGateway 创建文件投递网关并初始化目录
+func NewFileDropGateway(root string) (*FileDropGateway, error) {
+	g := &FileDropGateway{
+		outbound:  filepath.Join(root, "outbound"),
+		inbound:   filepath.Join(root, "inbound"),
+		processed: filepath.Join(root, "inbound", "processed"),
+		failed:    filepath.Join(root, "inbound", "error"),
+	}
+	for _, dir := range []string{g.outbound, g.inbound, g.processed, g.failed} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create %s: %w", dir, err)
+		}
+	}
+	return g, nil
+}
+
+// Send 先写临时文件再改名，避免对端读到半个报文
+func (g *FileDropGateway) Send(_ context.Context, msg *domain.SettlementMessage) error {
+	ext := ".fin"
+	if msg.Standard == domain.StandardISO20022 {
+		ext = ".xml"
+	}
+	name := fmt.Sprintf("%s_%s_%s%s", msg.Receiver, strings.ReplaceAll(msg.MessageType, ".", ""), msg.Reference, ext)
+	tmp := filepath.Join(g.outbound, "."+name+".tmp")
+	if err := os.WriteFile(tmp, []byte(msg.Payload), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(g.outbound, name))
+}
+
+// Receive 按文件名顺序返回 inbound/ 下的报文，忽略隐藏文件与子目录
+func (g *FileDropGateway) Receive(_ context.Context) ([]domain.InboundMessage, error) {
+	entries, err := os.ReadDir(g.inbound)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	messages := make([]domain.InboundMessage, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(g.inbound, name))
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, domain.InboundMessage{Name: name, Payload: data})
+	}
+	return messages, nil
+}
+
+// Ack 将已处理报文移出 inbound/，处理失败的报文附带 .err 说明文件
+func (g *FileDropGateway) Ack(_ context.Context, in domain.InboundMessage, procErr error) error {
+	target := g.processed
+	if procErr != nil {
+		target = g.failed
+		if err := os.WriteFile(filepath.Join(target, in.Name+".err"), []byte(procErr.Error()+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return os.Rename(filepath.Join(g.inbound, in.Name), filepath.Join(target, in.Name))
+}
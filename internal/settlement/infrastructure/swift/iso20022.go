@@ -0,0 +1,299 @@
+package swift
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+const (
+	sese023Namespace = "urn:iso:std:iso:20022:tech:xsd:sese.023.001.09"
+	isoDateLayout    = "2006-01-02"
+)
+
+// ---- sese.023 SecuritiesSettlementTransactionInstruction ----
+
+type sese023Document struct {
+	XMLName xml.Name        `xml:"Document"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Instr   sese023Instruct `xml:"SctiesSttlmTxInstr"`
+}
+
+type sese023Instruct struct {
+	TxID        string              `xml:"TxId"`
+	TpAndParams sese023TypeParams   `xml:"SttlmTpAndAddtlParams"`
+	TradDtls    sese023TradeDetails `xml:"TradDtls"`
+	FinInstrmID finInstrmID         `xml:"FinInstrmId"`
+	QtyAndAcct  sese023QtyAcct      `xml:"QtyAndAcctDtls"`
+	SttlmParams sese023SttlmParams  `xml:"SttlmParams"`
+	DlvrgPties  *settlementParties  `xml:"DlvrgSttlmPties,omitempty"`
+	RcvgPties   *settlementParties  `xml:"RcvgSttlmPties,omitempty"`
+	SttlmAmt    *amountAndDirection `xml:"SttlmAmt,omitempty"`
+}
+
+type sese023TypeParams struct {
+	MvmntTp string `xml:"SctiesMvmntTp"`
+	Pmt     string `xml:"Pmt"`
+}
+
+type dateChoice struct {
+	Dt string `xml:"Dt>Dt"`
+}
+
+type sese023TradeDetails struct {
+	TradID   string     `xml:"TradId,omitempty"`
+	TradDt   dateChoice `xml:"TradDt"`
+	SttlmDt  dateChoice `xml:"SttlmDt"`
+	DealPric *dealPrice `xml:"DealPric,omitempty"`
+}
+
+type dealPrice struct {
+	Yld bool         `xml:"Tp>Yldd"`
+	Amt activeAmount `xml:"Val>Amt"`
+}
+
+type activeAmount struct {
+	Ccy string `xml:"Ccy,attr"`
+	Val string `xml:",chardata"`
+}
+
+type finInstrmID struct {
+	ISIN string       `xml:"ISIN,omitempty"`
+	Othr *otherInstrm `xml:"OthrId,omitempty"`
+}
+
+type otherInstrm struct {
+	ID    string `xml:"Id"`
+	Prtry string `xml:"Tp>Prtry"`
+}
+
+type sese023QtyAcct struct {
+	Unit      string `xml:"SttlmQty>Qty>Unit"`
+	SfkpgAcct string `xml:"SfkpgAcct>Id,omitempty"`
+}
+
+type sese023SttlmParams struct {
+	TxTp         string `xml:"SctiesTxTp>Cd"`
+	PrtlSttlmInd string `xml:"PrtlSttlmInd,omitempty"`
+}
+
+type settlementParties struct {
+	BIC       string `xml:"Pty1>Id>AnyBIC"`
+	SfkpgAcct string `xml:"Pty1>SfkpgAcct>Id,omitempty"`
+}
+
+type amountAndDirection struct {
+	Amt       activeAmount `xml:"Amt"`
+	CdtDbtInd string       `xml:"CdtDbtInd"`
+}
+
+func encodeSese023(m *domain.SettlementInstructionMessage) ([]byte, error) {
+	ins := m.Instruction
+	doc := sese023Document{
+		Xmlns: sese023Namespace,
+		Instr: sese023Instruct{
+			TxID: m.Reference,
+			TpAndParams: sese023TypeParams{
+				MvmntTp: string(m.Side),
+				Pmt:     "FREE",
+			},
+			TradDtls: sese023TradeDetails{
+				TradID:  ins.TradeID,
+				TradDt:  dateChoice{Dt: ins.TradeDate.Format(isoDateLayout)},
+				SttlmDt: dateChoice{Dt: ins.SettlementDate.Format(isoDateLayout)},
+			},
+			QtyAndAcct: sese023QtyAcct{
+				Unit:      ins.Quantity.String(),
+				SfkpgAcct: m.SafekeepingAcct,
+			},
+			SttlmParams: sese023SttlmParams{TxTp: "TRAD", PrtlSttlmInd: partialIndicator(ins.PartialAllowed)},
+		},
+	}
+	if isISIN(ins.Symbol) {
+		doc.Instr.FinInstrmID.ISIN = ins.Symbol
+	} else {
+		doc.Instr.FinInstrmID.Othr = &otherInstrm{ID: ins.Symbol, Prtry: "TICKER"}
+	}
+
+	parties := &settlementParties{BIC: m.CounterpartyBIC, SfkpgAcct: m.CounterpartyAcct}
+	if m.CounterpartyBIC == "" {
+		parties = nil
+	}
+	if m.Side == domain.SideReceive {
+		doc.Instr.DlvrgPties = parties
+	} else {
+		doc.Instr.RcvgPties = parties
+	}
+
+	if m.Payment {
+		doc.Instr.TpAndParams.Pmt = "APMT"
+		doc.Instr.TradDtls.DealPric = &dealPrice{Amt: activeAmount{Ccy: ins.Currency, Val: ins.Price.String()}}
+		direction := "DBIT"
+		if m.Side == domain.SideDeliver {
+			direction = "CRDT"
+		}
+		doc.Instr.SttlmAmt = &amountAndDirection{
+			Amt:       activeAmount{Ccy: ins.Currency, Val: ins.Amount.StringFixed(2)},
+			CdtDbtInd: direction,
+		}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func partialIndicator(allowed bool) string {
+	if allowed {
+		return "PART"
+	}
+	return "NPAR"
+}
+
+// ---- sese.024 / sese.025 ----
+
+type reasonCode struct {
+	Cd    string `xml:"Cd>Cd"`
+	Prtry string `xml:"Cd>Prtry>Id"`
+	Info  string `xml:"AddtlRsnInf"`
+}
+
+type reasonedStatus struct {
+	NoSpcfdRsn string       `xml:"NoSpcfdRsn"`
+	Rsn        []reasonCode `xml:"Rsn"`
+}
+
+type iso20022Inbound struct {
+	XMLName xml.Name
+	Advice  *sese024Advice `xml:"SctiesSttlmTxStsAdvc"`
+	Confirm *sese025Conf   `xml:"SctiesSttlmTxConf"`
+}
+
+type sese024Advice struct {
+	OwnerTxID    string `xml:"TxId>AcctOwnrTxId"`
+	ServicerTxID string `xml:"TxId>AcctSvcrTxId"`
+	PrcgSts      *struct {
+		AckdAccptd *reasonedStatus `xml:"AckdAccptd"`
+		Rjctd      *reasonedStatus `xml:"Rjctd"`
+		Canc       *reasonedStatus `xml:"Canc"`
+		PdgPrcg    *reasonedStatus `xml:"PdgPrcg"`
+	} `xml:"PrcgSts"`
+	MtchgSts *struct {
+		Mtchd  *reasonedStatus `xml:"Mtchd"`
+		Umtchd *reasonedStatus `xml:"Umtchd"`
+	} `xml:"MtchgSts"`
+	SttlmSts *struct {
+		Pdg  *reasonedStatus `xml:"Pdg"`
+		Flng *reasonedStatus `xml:"Flng"`
+	} `xml:"SttlmSts"`
+}
+
+type sese025Conf struct {
+	OwnerTxID    string        `xml:"TxIdDtls>AcctOwnrTxId"`
+	ServicerTxID string        `xml:"TxIdDtls>AcctSvcrTxId"`
+	EfctvSttlmDt string        `xml:"TradDtls>EfctvSttlmDt>Dt>Dt"`
+	SttldUnit    string        `xml:"QtyAndAcctDtls>SttldQty>Qty>Unit"`
+	PrtlSttlmInd string        `xml:"SttlmParams>PrtlSttlmInd"`
+	SttldAmt     *activeAmount `xml:"SttldAmt>Amt"`
+}
+
+func decodeISO20022(payload []byte) (*domain.SettlementStatusReport, error) {
+	var doc iso20022Inbound
+	if err := xml.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrUnknownMessage, err)
+	}
+	switch {
+	case doc.Advice != nil:
+		return decodeSese024(doc.Advice), nil
+	case doc.Confirm != nil:
+		return decodeSese025(doc.Confirm)
+	default:
+		return nil, fmt.Errorf("%w: unsupported ISO 20022 document", domain.ErrUnknownMessage)
+	}
+}
+
+// decodeSese024 多个状态并存时按 拒绝 > 撤销 > 交收失败 > 匹配状态 > 待交收 > 受理 取最重要者
+func decodeSese024(a *sese024Advice) *domain.SettlementStatusReport {
+	r := &domain.SettlementStatusReport{
+		Standard:         domain.StandardISO20022,
+		MessageType:      "sese.024",
+		Kind:             domain.ReportStatusAdvice,
+		Reference:        a.ServicerTxID,
+		RelatedReference: a.OwnerTxID,
+	}
+	set := func(status domain.ReportStatus, rs *reasonedStatus) {
+		r.Status, r.ReasonCode, r.Reason = status, "", ""
+		if rs != nil && len(rs.Rsn) > 0 {
+			r.ReasonCode = rs.Rsn[0].Cd
+			if r.ReasonCode == "" {
+				r.ReasonCode = rs.Rsn[0].Prtry
+			}
+			r.Reason = rs.Rsn[0].Info
+		}
+	}
+
+	if a.PrcgSts != nil && a.PrcgSts.AckdAccptd != nil {
+		set(domain.ReportAccepted, a.PrcgSts.AckdAccptd)
+	}
+	if a.PrcgSts != nil && a.PrcgSts.PdgPrcg != nil {
+		set(domain.ReportPending, a.PrcgSts.PdgPrcg)
+	}
+	if a.SttlmSts != nil && a.SttlmSts.Pdg != nil {
+		set(domain.ReportPending, a.SttlmSts.Pdg)
+	}
+	if a.MtchgSts != nil {
+		if a.MtchgSts.Umtchd != nil {
+			set(domain.ReportUnmatched, a.MtchgSts.Umtchd)
+		}
+		if a.MtchgSts.Mtchd != nil {
+			set(domain.ReportMatched, a.MtchgSts.Mtchd)
+		}
+	}
+	if a.SttlmSts != nil && a.SttlmSts.Flng != nil {
+		set(domain.ReportFailing, a.SttlmSts.Flng)
+	}
+	if a.PrcgSts != nil && a.PrcgSts.Canc != nil {
+		set(domain.ReportCancelled, a.PrcgSts.Canc)
+	}
+	if a.PrcgSts != nil && a.PrcgSts.Rjctd != nil {
+		set(domain.ReportRejected, a.PrcgSts.Rjctd)
+	}
+	return r
+}
+
+func decodeSese025(c *sese025Conf) (*domain.SettlementStatusReport, error) {
+	r := &domain.SettlementStatusReport{
+		Standard:         domain.StandardISO20022,
+		MessageType:      "sese.025",
+		Kind:             domain.ReportConfirmation,
+		Reference:        c.ServicerTxID,
+		RelatedReference: c.OwnerTxID,
+		Status:           domain.ReportSettled,
+		Partial:          c.PrtlSttlmInd == "PAIN" || c.PrtlSttlmInd == "PARC",
+	}
+	if c.SttldUnit != "" {
+		qty, err := decimal.NewFromString(c.SttldUnit)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid settled quantity %q", domain.ErrUnknownMessage, c.SttldUnit)
+		}
+		r.SettledQuantity = qty
+	}
+	if c.SttldAmt != nil {
+		amt, err := decimal.NewFromString(c.SttldAmt.Val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid settled amount %q", domain.ErrUnknownMessage, c.SttldAmt.Val)
+		}
+		r.SettledAmount, r.Currency = amt, c.SttldAmt.Ccy
+	}
+	if c.EfctvSttlmDt != "" {
+		if d, err := time.Parse(isoDateLayout, c.EfctvSttlmDt); err == nil {
+			r.SettlementDate = d
+		}
+	}
+	return r, nil
+}
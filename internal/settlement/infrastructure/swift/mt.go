@@ -0,0 +1,346 @@
+package swift
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+)
+
+const mtDateLayout = "20060102"
+
+var (
+	mtBlockPattern = regexp.MustCompile(`\{([1-3]):([^{}]*)\}`)
+	mtQualified    = regexp.MustCompile(`^:([A-Z]{4})//(.*)$`) // :SEME//REF
+	mtQualifiedDSS = regexp.MustCompile(`^:([A-Z]{4})/[A-Z0-9]*/(.*)$`)
+)
+
+// encodeMT54x 生成 MT540-543 结算指令
+func encodeMT54x(mt string, m *domain.SettlementInstructionMessage) []byte {
+	ins := m.Instruction
+	var b strings.Builder
+	fmt.Fprintf(&b, "{1:F01%s0000000000}{2:I%s%sN}{4:\r\n", ltAddress(m.Sender), mt, ltAddress(m.Receiver))
+
+	field := func(tag, value string) {
+		b.WriteString(":" + tag + ":" + value + "\r\n")
+	}
+
+	field("16R", "GENL")
+	field("20C", ":SEME//"+m.Reference)
+	field("23G", "NEWM")
+	field("16S", "GENL")
+
+	field("16R", "TRADDET")
+	field("98A", ":SETT//"+ins.SettlementDate.Format(mtDateLayout))
+	field("98A", ":TRAD//"+ins.TradeDate.Format(mtDateLayout))
+	if m.Payment {
+		field("90B", ":DEAL//ACTU/"+ins.Currency+mtDecimal(ins.Price))
+	}
+	if isISIN(ins.Symbol) {
+		field("35B", "ISIN "+ins.Symbol)
+	} else {
+		field("35B", "/TS/"+ins.Symbol)
+	}
+	field("16S", "TRADDET")
+
+	field("16R", "FIAC")
+	field("36B", ":SETT//UNIT/"+mtDecimal(ins.Quantity))
+	if m.SafekeepingAcct != "" {
+		field("97A", ":SAFE//"+m.SafekeepingAcct)
+	}
+	field("16S", "FIAC")
+
+	field("16R", "SETDET")
+	field("22F", ":SETR//TRAD")
+	if ins.PartialAllowed {
+		field("22F", ":STCO//PART")
+	} else {
+		field("22F", ":STCO//NPAR")
+	}
+	if m.CounterpartyBIC != "" {
+		qualifier := "DEAG"
+		if m.Side == domain.SideDeliver {
+			qualifier = "REAG"
+		}
+		field("16R", "SETPRTY")
+		field("95P", ":"+qualifier+"//"+m.CounterpartyBIC)
+		if m.CounterpartyAcct != "" {
+			field("97A", ":SAFE//"+m.CounterpartyAcct)
+		}
+		field("16S", "SETPRTY")
+	}
+	if m.Payment {
+		field("16R", "AMT")
+		field("19A", ":SETT//"+ins.Currency+mtDecimal(ins.Amount.Round(2)))
+		field("16S", "AMT")
+	}
+	field("16S", "SETDET")
+	b.WriteString("-}")
+	return []byte(b.String())
+}
+
+// mtDecimal SWIFT 数字格式：逗号为小数点，整数以逗号结尾
+func mtDecimal(d decimal.Decimal) string {
+	s := d.String()
+	if !strings.Contains(s, ".") {
+		return s + ","
+	}
+	return strings.Replace(s, ".", ",", 1)
+}
+
+func parseMTDecimal(s string) (decimal.Decimal, error) {
+	s = strings.TrimSuffix(strings.Replace(s, ",", ".", 1), ".")
+	return decimal.NewFromString(s)
+}
+
+// ltAddress 12 位逻辑终端地址：BIC8 + 终端码 + 分行码
+func ltAddress(bic string) string {
+	bic = strings.ToUpper(bic)
+	switch {
+	case len(bic) >= 11:
+		return bic[:8] + "X" + bic[8:11]
+	case len(bic) == 8:
+		return bic + "XXXX"
+	default:
+		return (bic + "XXXXXXXXXXXX")[:12]
+	}
+}
+
+type mtField struct {
+	tag   string
+	value string
+}
+
+// mtMessage 解析后的 MT 报文
+type mtMessage struct {
+	msgType string
+	sender  string
+	fields  []mtField
+}
+
+func parseMT(payload []byte) (*mtMessage, error) {
+	text := strings.ReplaceAll(string(payload), "\r\n", "\n")
+	msg := &mtMessage{}
+
+	if i := strings.Index(text, "{4:"); i >= 0 {
+		for _, blk := range mtBlockPattern.FindAllStringSubmatch(text[:i], -1) {
+			if blk[1] == "2" {
+				msg.msgType, msg.sender = parseMTBlock2(blk[2])
+			}
+		}
+		text = text[i+3:]
+		if j := strings.Index(text, "-}"); j >= 0 {
+			text = text[:j]
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			if end := strings.Index(line[1:], ":"); end > 0 {
+				msg.fields = append(msg.fields, mtField{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if len(msg.fields) == 0 {
+			return nil, fmt.Errorf("%w: malformed MT line %q", domain.ErrUnknownMessage, line)
+		}
+		// 续行
+		last := &msg.fields[len(msg.fields)-1]
+		last.value += "\n" + line
+	}
+	if len(msg.fields) == 0 {
+		return nil, fmt.Errorf("%w: empty MT text block", domain.ErrUnknownMessage)
+	}
+	return msg, nil
+}
+
+// parseMTBlock2 输入报文 I548BIC...，输出报文 O548HHMMYYMMDD + LT地址 + ...
+func parseMTBlock2(v string) (string, string) {
+	if len(v) < 4 {
+		return "", ""
+	}
+	msgType := v[1:4]
+	switch {
+	case v[0] == 'O' && len(v) >= 26:
+		lt := v[14:26]
+		return msgType, lt[:8] + lt[9:12]
+	case v[0] == 'I' && len(v) >= 16:
+		lt := v[4:16]
+		return msgType, lt[:8] + lt[9:12]
+	}
+	return msgType, ""
+}
+
+// qualified 查找指定标签与限定符的字段值，如 20C + SEME
+func (m *mtMessage) qualified(tag, qualifier string) (string, bool) {
+	for _, f := range m.fields {
+		if f.tag != tag {
+			continue
+		}
+		if g := mtQualified.FindStringSubmatch(f.value); g != nil && g[1] == qualifier {
+			return g[2], true
+		}
+		if g := mtQualifiedDSS.FindStringSubmatch(f.value); g != nil && g[1] == qualifier {
+			return g[2], true
+		}
+	}
+	return "", false
+}
+
+// linkedReference 链接序列中的原指令引用（RELA 或 PREV）
+func (m *mtMessage) linkedReference() string {
+	if v, ok := m.qualified("20C", "RELA"); ok {
+		return v
+	}
+	v, _ := m.qualified("20C", "PREV")
+	return v
+}
+
+func decodeMT(payload []byte) (*domain.SettlementStatusReport, error) {
+	msg, err := parseMT(payload)
+	if err != nil {
+		return nil, err
+	}
+	if msg.msgType == "" {
+		// 无报头时根据内容判断：含状态字段视为 MT548
+		if _, ok := msg.firstTag("25D"); ok {
+			msg.msgType = "548"
+		}
+	}
+
+	r := &domain.SettlementStatusReport{
+		Standard:         domain.StandardMT,
+		MessageType:      "MT" + msg.msgType,
+		Sender:           msg.sender,
+		RelatedReference: msg.linkedReference(),
+	}
+	r.Reference, _ = msg.qualified("20C", "SEME")
+	if r.RelatedReference == "" {
+		return nil, fmt.Errorf("%w: MT%s without linked reference", domain.ErrUnknownMessage, msg.msgType)
+	}
+
+	switch msg.msgType {
+	case "548":
+		r.Kind = domain.ReportStatusAdvice
+		decodeMT548Status(msg, r)
+		if r.Status == "" {
+			return nil, fmt.Errorf("%w: MT548 without status", domain.ErrUnknownMessage)
+		}
+	case "544", "545", "546", "547":
+		r.Kind = domain.ReportConfirmation
+		r.Status = domain.ReportSettled
+		if err := decodeMTConfirmation(msg, r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: MT%s", domain.ErrUnknownMessage, msg.msgType)
+	}
+	return r, nil
+}
+
+func (m *mtMessage) firstTag(tag string) (string, bool) {
+	for _, f := range m.fields {
+		if f.tag == tag {
+			return f.value, true
+		}
+	}
+	return "", false
+}
+
+// decodeMT548Status 按 :25D: 状态（IPRC/MTCH/SETT/CPRC）与 :24B: 原因确定状态，
+// 多个状态并存时取最重要者
+func decodeMT548Status(msg *mtMessage, r *domain.SettlementStatusReport) {
+	rank := map[domain.ReportStatus]int{
+		domain.ReportAccepted:  1,
+		domain.ReportPending:   2,
+		domain.ReportUnmatched: 3,
+		domain.ReportMatched:   4,
+		domain.ReportFailing:   5,
+		domain.ReportCancelled: 6,
+		domain.ReportRejected:  7,
+	}
+	for _, f := range msg.fields {
+		switch f.tag {
+		case "25D":
+			g := mtQualified.FindStringSubmatch(f.value)
+			if g == nil {
+				g = mtQualifiedDSS.FindStringSubmatch(f.value)
+			}
+			if g == nil {
+				continue
+			}
+			status := mt548Status(g[1], g[2])
+			if status != "" && rank[status] > rank[r.Status] {
+				r.Status, r.ReasonCode, r.Reason = status, "", ""
+			}
+		case "24B":
+			if r.ReasonCode == "" {
+				if g := mtQualified.FindStringSubmatch(f.value); g != nil {
+					r.ReasonCode = g[2]
+				} else if g := mtQualifiedDSS.FindStringSubmatch(f.value); g != nil {
+					r.ReasonCode = g[2]
+				}
+			}
+		case "70D":
+			if g := mtQualified.FindStringSubmatch(f.value); g != nil && g[1] == "REAS" && r.Reason == "" {
+				r.Reason = strings.ReplaceAll(g[2], "\n", " ")
+			}
+		}
+	}
+}
+
+func mt548Status(qualifier, code string) domain.ReportStatus {
+	switch qualifier + "/" + code {
+	case "IPRC/PACK":
+		return domain.ReportAccepted
+	case "IPRC/REJT", "CPRC/REJT":
+		return domain.ReportRejected
+	case "IPRC/CAND", "CPRC/CAND":
+		return domain.ReportCancelled
+	case "IPRC/PPRC", "SETT/PEND":
+		return domain.ReportPending
+	case "MTCH/MACH":
+		return domain.ReportMatched
+	case "MTCH/NMAT":
+		return domain.ReportUnmatched
+	case "SETT/PENF":
+		return domain.ReportFailing
+	}
+	return ""
+}
+
+func decodeMTConfirmation(msg *mtMessage, r *domain.SettlementStatusReport) error {
+	if v, ok := msg.qualified("36B", "ESTT"); ok {
+		qty, err := parseMTDecimal(strings.TrimPrefix(strings.TrimPrefix(v, "UNIT/"), "FAMT/"))
+		if err != nil {
+			return fmt.Errorf("%w: invalid settled quantity %q", domain.ErrUnknownMessage, v)
+		}
+		r.SettledQuantity = qty
+	}
+	if v, ok := msg.qualified("19A", "ESTT"); ok {
+		v = strings.TrimPrefix(v, "N")
+		if len(v) > 3 {
+			amt, err := parseMTDecimal(v[3:])
+			if err != nil {
+				return fmt.Errorf("%w: invalid settled amount %q", domain.ErrUnknownMessage, v)
+			}
+			r.Currency, r.SettledAmount = v[:3], amt
+		}
+	}
+	if v, ok := msg.qualified("98A", "ESET"); ok {
+		if d, err := time.Parse(mtDateLayout, v); err == nil {
+			r.SettlementDate = d
+		}
+	}
+	if v, ok := msg.qualified("22F", "STCO"); ok {
+		r.Partial = v == "PAIN" || v == "PARC"
+	}
+	return nil
+}
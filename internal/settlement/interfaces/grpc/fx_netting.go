@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	pb "github.com/wyfcoding/financialtrading/go-api/settlement/v1"
+	"github.com/wyfcoding/financialtrading/internal/settlement/application"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) PerformMultiCurrencyNetting(ctx context.Context, req *pb.PerformMultiCurrencyNettingRequest) (*pb.MultiCurrencyNettingResponse, error) {
+	cmd := application.MultiCurrencyNettingCommand{SettlementCurrency: req.SettlementCurrency}
+	if req.SettlementDate > 0 {
+		cmd.SettlementDate = time.Unix(req.SettlementDate, 0)
+	}
+	if req.RateSnapshotAt > 0 {
+		cmd.RateSnapshotAt = time.Unix(req.RateSnapshotAt, 0)
+	}
+	if req.SpreadBps != "" {
+		spread, err := decimal.NewFromString(req.SpreadBps)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid spread_bps: %v", err)
+		}
+		cmd.SpreadBps = spread
+	}
+
+	result, err := s.app.PerformMultiCurrencyNetting(ctx, cmd)
+	if err != nil {
+		if errors.Is(err, domain.ErrFXRateUnavailable) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "multi-currency netting failed: %v", err)
+	}
+	return toProtoNettingRun(result), nil
+}
+
+func (s *Server) GetMultiCurrencyNetting(ctx context.Context, req *pb.GetMultiCurrencyNettingRequest) (*pb.MultiCurrencyNettingResponse, error) {
+	result, err := s.app.GetMultiCurrencyNetting(ctx, req.RunId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get netting run failed: %v", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, status.Errorf(codes.NotFound, "netting run %s not found", req.RunId)
+	}
+	return toProtoNettingRun(result), nil
+}
+
+func toProtoNettingRun(r *application.MultiCurrencyNettingResult) *pb.MultiCurrencyNettingResponse {
+	resp := &pb.MultiCurrencyNettingResponse{
+		RunId:              r.RunID,
+		SettlementCurrency: r.SettlementCurrency,
+		InstructionCount:   int32(r.InstructionCount),
+		Nets:               make([]*pb.CurrencyNet, 0, len(r.Results)),
+		FxInstructions:     make([]*pb.FXInstruction, 0, len(r.FXInstructions)),
+	}
+	if !r.RateSnapshotAt.IsZero() {
+		resp.RateSnapshotAt = r.RateSnapshotAt.Unix()
+	}
+	for _, n := range r.Results {
+		resp.Nets = append(resp.Nets, &pb.CurrencyNet{
+			NettingId:       n.NettingID,
+			AccountId:       n.AccountID,
+			Currency:        n.Currency,
+			GrossAmount:     n.GrossAmount.String(),
+			NetAmount:       n.NetAmount.String(),
+			ConvertedAmount: n.ConvertedAmount.String(),
+			Status:          n.Status,
+		})
+	}
+	for _, f := range r.FXInstructions {
+		resp.FxInstructions = append(resp.FxInstructions, &pb.FXInstruction{
+			FxInstructionId:    f.FXInstructionID,
+			AccountId:          f.AccountID,
+			Side:               f.Side,
+			Currency:           f.Currency,
+			Amount:             f.Amount.String(),
+			SettlementCurrency: f.SettlementCurrency,
+			SettlementAmount:   f.SettlementAmount.String(),
+			MidRate:            f.MidRate.String(),
+			AppliedRate:        f.AppliedRate.String(),
+			SpreadBps:          f.SpreadBps.String(),
+			RateSource:         f.RateSource,
+			RateId:             uint64(f.RateID),
+			RateInverted:       f.RateInverted,
+			RateEffectiveAt:    f.RateEffectiveAt.Unix(),
+			RateSnapshotAt:     f.RateSnapshotAt.Unix(),
+			ValueDate:          f.ValueDate.Unix(),
+			Status:             f.Status,
+		})
+	}
+	return resp
+}
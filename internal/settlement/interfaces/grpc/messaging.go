@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	pb "github.com/wyfcoding/financialtrading/go-api/settlement/v1"
+	"github.com/wyfcoding/financialtrading/internal/settlement/application"
+	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) SendSettlementMessages(ctx context.Context, req *pb.SendSettlementMessagesRequest) (*pb.SendSettlementMessagesResponse, error) {
+	if s.messaging == nil {
+		return nil, status.Error(codes.Unimplemented, "settlement messaging not enabled")
+	}
+	standard := domain.MessageStandard(strings.ToUpper(req.Standard))
+	if standard != "" && standard != domain.StandardISO20022 && standard != domain.StandardMT {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported standard %q", req.Standard)
+	}
+	messages, err := s.messaging.SendInstruction(ctx, application.SendInstructionCommand{
+		InstructionID: req.InstructionId,
+		Standard:      standard,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCustodian) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "send settlement messages failed: %v", err)
+	}
+	resp := &pb.SendSettlementMessagesResponse{Messages: make([]*pb.SettlementMessage, 0, len(messages))}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, toProtoMessage(m))
+	}
+	return resp, nil
+}
+
+func (s *Server) IngestSettlementMessage(ctx context.Context, req *pb.IngestSettlementMessageRequest) (*pb.IngestSettlementMessageResponse, error) {
+	if s.messaging == nil {
+		return nil, status.Error(codes.Unimplemented, "settlement messaging not enabled")
+	}
+	msg, err := s.messaging.Ingest(ctx, req.Payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownMessage):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, domain.ErrMessageNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		default:
+			return nil, status.Errorf(codes.Internal, "ingest settlement message failed: %v", err)
+		}
+	}
+	resp := &pb.IngestSettlementMessageResponse{Message: toProtoMessage(msg)}
+	if ins, err := s.app.GetInstruction(ctx, msg.InstructionID); err == nil {
+		resp.Instruction = toProtoInstruction(ins)
+	}
+	return resp, nil
+}
+
+func (s *Server) ListSettlementMessages(ctx context.Context, req *pb.ListSettlementMessagesRequest) (*pb.ListSettlementMessagesResponse, error) {
+	if s.messaging == nil {
+		return nil, status.Error(codes.Unimplemented, "settlement messaging not enabled")
+	}
+	messages, err := s.messaging.ListMessages(ctx, req.InstructionId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list settlement messages failed: %v", err)
+	}
+	resp := &pb.ListSettlementMessagesResponse{Messages: make([]*pb.SettlementMessage, 0, len(messages))}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, toProtoMessage(m))
+	}
+	return resp, nil
+}
+
+func toProtoMessage(m *domain.SettlementMessage) *pb.SettlementMessage {
+	return &pb.SettlementMessage{
+		MessageId:         m.MessageID,
+		Reference:         m.Reference,
+		RelatedReference:  m.RelatedReference,
+		InstructionId:     m.InstructionID,
+		Direction:         string(m.Direction),
+		Standard:          string(m.Standard),
+		MessageType:       m.MessageType,
+		Side:              string(m.Side),
+		Sender:            m.Sender,
+		Receiver:          m.Receiver,
+		Status:            string(m.Status),
+		StatusReason:      m.StatusReason,
+		ConfirmedQuantity: m.ConfirmedQuantity.String(),
+		Payload:           m.Payload,
+		CreatedAt:         m.CreatedAt.Unix(),
+	}
+}
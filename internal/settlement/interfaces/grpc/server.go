@@ -2,8 +2,10 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/shopspring/decimal"
 	pb "github.com/wyfcoding/financialtrading/go-api/settlement/v1"
 	"github.com/wyfcoding/financialtrading/internal/settlement/application"
 	"github.com/wyfcoding/financialtrading/internal/settlement/domain"
@@ -13,13 +15,26 @@ import (
 
 type Server struct {
 	pb.UnimplementedSettlementServiceServer
-	app *application.SettlementAppService
+	app   *application.SettlementAppService
+	fails *application.FailsService
+
+	messaging *application.MessagingService
 }
 
 func NewServer(app *application.SettlementAppService) *Server {
 	return &Server{app: app}
 }
 
+// SetFailsService 注入结算失败管理服务
+func (s *Server) SetFailsService(fails *application.FailsService) {
+	s.fails = fails
+}
+
+// SetMessagingService 注入托管商结算报文服务
+func (s *Server) SetMessagingService(messaging *application.MessagingService) {
+	s.messaging = messaging
+}
+
 func (s *Server) CreateInstruction(ctx context.Context, req *pb.CreateInstructionRequest) (*pb.CreateInstructionResponse, error) {
 	cmd := application.CreateInstructionCommand{
 		TradeID:         req.TradeId,
@@ -30,6 +45,7 @@ func (s *Server) CreateInstruction(ctx context.Context, req *pb.CreateInstructio
 		SellerAccountID: req.SellerAccountId,
 		Currency:        req.Currency,
 		CycleDays:       int(req.SettlementCycleDays),
+		Market:          req.Market,
 	}
 
 	ins, err := s.app.CreateInstruction(ctx, cmd)
@@ -72,6 +88,144 @@ func (s *Server) BatchSettle(ctx context.Context, req *pb.BatchSettleRequest) (*
 	}, nil
 }
 
+func (s *Server) ProcessFails(ctx context.Context, req *pb.ProcessFailsRequest) (*pb.ProcessFailsResponse, error) {
+	if s.fails == nil {
+		return nil, status.Error(codes.Unimplemented, "fails management not enabled")
+	}
+	result, err := s.fails.ProcessFails(ctx, asOfTime(req.AsOf))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "process fails failed: %v", err)
+	}
+
+	return &pb.ProcessFailsResponse{
+		Recycled:         int32(result.Recycled),
+		Settled:          int32(result.Settled),
+		PartiallySettled: int32(result.PartiallySettled),
+		PenaltiesCharged: int32(result.PenaltiesCharged),
+		PenaltyAmount:    decimalMap(result.PenaltyAmount),
+		BuyInsInitiated:  int32(result.BuyInsInitiated),
+		BuyInsExecuted:   int32(result.BuyInsExecuted),
+	}, nil
+}
+
+func (s *Server) GetFailsAgeingReport(ctx context.Context, req *pb.GetFailsAgeingReportRequest) (*pb.GetFailsAgeingReportResponse, error) {
+	if s.fails == nil {
+		return nil, status.Error(codes.Unimplemented, "fails management not enabled")
+	}
+	report, err := s.fails.GetAgeingReport(ctx, asOfTime(req.AsOf))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "ageing report failed: %v", err)
+	}
+
+	resp := &pb.GetFailsAgeingReportResponse{AsOf: report.AsOf.Unix()}
+	for _, b := range report.Buckets {
+		resp.Buckets = append(resp.Buckets, &pb.FailAgeingBucket{
+			Label:           b.Label,
+			MinDays:         int32(b.MinDays),
+			MaxDays:         int32(b.MaxDays),
+			Count:           int32(b.Count),
+			UnsettledAmount: decimalMap(b.UnsettledAmount),
+			PenaltyAccrued:  decimalMap(b.PenaltyAccrued),
+		})
+	}
+	for _, item := range report.Items {
+		resp.Items = append(resp.Items, &pb.FailAgeingItem{
+			InstructionId:     item.InstructionID,
+			Symbol:            item.Symbol,
+			Currency:          item.Currency,
+			FailingParty:      string(item.FailingParty),
+			FailingAccountId:  item.FailingAccountID,
+			DaysFailed:        int32(item.DaysFailed),
+			UnsettledQuantity: item.UnsettledQuantity.String(),
+			UnsettledAmount:   item.UnsettledAmount.String(),
+			PenaltyAccrued:    item.PenaltyAccrued.String(),
+			BuyInId:           item.BuyInID,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) ListSettlementPenalties(ctx context.Context, req *pb.ListSettlementPenaltiesRequest) (*pb.ListSettlementPenaltiesResponse, error) {
+	if s.fails == nil {
+		return nil, status.Error(codes.Unimplemented, "fails management not enabled")
+	}
+	var from, to time.Time
+	if req.From > 0 {
+		from = time.Unix(req.From, 0)
+	}
+	if req.To > 0 {
+		to = time.Unix(req.To, 0)
+	}
+	penalties, err := s.fails.ListPenalties(ctx, req.InstructionId, req.AccountId, from, to)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "list penalties failed: %v", err)
+	}
+
+	resp := &pb.ListSettlementPenaltiesResponse{}
+	for _, p := range penalties {
+		resp.Penalties = append(resp.Penalties, &pb.SettlementPenalty{
+			PenaltyId:          p.PenaltyID,
+			InstructionId:      p.InstructionID,
+			PenaltyDate:        p.PenaltyDate.Unix(),
+			FailingParty:       string(p.FailingParty),
+			FailingAccountId:   p.FailingAccountID,
+			ReceivingAccountId: p.ReceivingAccountID,
+			UnsettledQuantity:  p.UnsettledQuantity.String(),
+			ReferencePrice:     p.ReferencePrice.String(),
+			Rate:               p.Rate.String(),
+			Amount:             p.Amount.String(),
+			Currency:           p.Currency,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) ExecuteBuyIn(ctx context.Context, req *pb.ExecuteBuyInRequest) (*pb.ExecuteBuyInResponse, error) {
+	if s.fails == nil {
+		return nil, status.Error(codes.Unimplemented, "fails management not enabled")
+	}
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid price: %v", err)
+	}
+	buyIn, err := s.fails.ExecuteBuyIn(ctx, application.ExecuteBuyInCommand{BuyInID: req.BuyInId, Price: price, TradeID: req.TradeId})
+	if err != nil {
+		if errors.Is(err, domain.ErrBuyInNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "execute buy-in failed: %v", err)
+	}
+
+	return &pb.ExecuteBuyInResponse{BuyIn: &pb.BuyIn{
+		BuyInId:                  buyIn.BuyInID,
+		InstructionId:            buyIn.InstructionID,
+		Symbol:                   buyIn.Symbol,
+		Quantity:                 buyIn.Quantity.String(),
+		OriginalPrice:            buyIn.OriginalPrice.String(),
+		ExecutionPrice:           buyIn.ExecutionPrice.String(),
+		ChargeAmount:             buyIn.ChargeAmount.String(),
+		FailingAccountId:         buyIn.FailingAccountID,
+		Status:                   string(buyIn.Status),
+		ReplacementTradeId:       buyIn.ReplacementTradeID,
+		ReplacementInstructionId: buyIn.ReplacementInstructionID,
+	}}, nil
+}
+
+func asOfTime(unix int64) time.Time {
+	if unix <= 0 {
+		return time.Now()
+	}
+	return time.Unix(unix, 0)
+}
+
+func decimalMap(m map[string]decimal.Decimal) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v.String()
+	}
+	return out
+}
+
 func toProtoInstruction(ins *domain.SettlementInstruction) *pb.SettlementInstruction {
 	quantity, _ := ins.Quantity.Float64()
 	price, _ := ins.Price.Float64()
@@ -89,6 +243,10 @@ func toProtoInstruction(ins *domain.SettlementInstruction) *pb.SettlementInstruc
 		SettlementDate:  ins.SettlementDate.Unix(),
 		Status:          toProtoStatus(ins.Status),
 		Currency:        ins.Currency,
+		SettledQuantity: ins.SettledQuantity.String(),
+		FailingParty:    string(ins.FailingParty),
+		PenaltyAccrued:  ins.PenaltyAccrued.String(),
+		BuyInId:         ins.BuyInID,
 	}
 }
 
@@ -102,6 +260,10 @@ func toProtoStatus(status domain.SettlementStatus) pb.SettlementStatus {
 		return pb.SettlementStatus_SETTLEMENT_STATUS_SETTLED
 	case domain.SettlementStatusFailed:
 		return pb.SettlementStatus_SETTLEMENT_STATUS_FAILED
+	case domain.SettlementStatusPartial:
+		return pb.SettlementStatus_SETTLEMENT_STATUS_PARTIALLY_SETTLED
+	case domain.SettlementStatusBoughtIn:
+		return pb.SettlementStatus_SETTLEMENT_STATUS_BOUGHT_IN
 	default:
 		return pb.SettlementStatus_SETTLEMENT_STATUS_UNSPECIFIED
 	}